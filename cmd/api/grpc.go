@@ -0,0 +1,394 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gavinwade12/sendkey/pkg/api/sendkeyv1"
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// grpcConfig serves the API over gRPC too, for internal services that prefer
+// it. See api.Proto for its definitions.
+type grpcConfig struct {
+	// Addr is the host:port of the gRPC listener, e.g. ":50051". It's
+	// served over HTTP/2 without TLS, for a private network, unless the API
+	// is served over HTTPS, when it uses the same certificates. Empty
+	// doesn't serve it.
+	Addr string
+}
+
+// grpcChunkSize is how much of a value each message of a streamed claim
+// holds.
+const grpcChunkSize = 64 << 10
+
+// grpcGateway serves the gRPC API's generated services by making each call
+// to the JSON route its method's google.api.http option names, through the
+// API's handler, so calls go through the same middleware, controllers, and
+// services as the JSON API and get the same responses. Metadata is sent as
+// the route's headers, and the route's headers are sent back as metadata.
+type grpcGateway struct {
+	api http.Handler
+}
+
+// newGRPCServer returns a gRPC server for the API's handler, taking messages
+// up to maxBody bytes, over TLS with the API's config if it's served over
+// HTTPS.
+func newGRPCServer(h http.Handler, maxBody int64, tlsConfig *tls.Config) *grpc.Server {
+	opts := []grpc.ServerOption{grpc.MaxRecvMsgSize(int(maxBody))}
+	if tlsConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig.Clone())))
+	}
+	srv := grpc.NewServer(opts...)
+
+	gw := &grpcGateway{api: h}
+	sendkeyv1.RegisterUsersServer(srv, grpcUsers{gw: gw})
+	sendkeyv1.RegisterAuthServer(srv, grpcAuth{gw: gw})
+	sendkeyv1.RegisterEntriesServer(srv, grpcEntries{gw: gw})
+	return srv
+}
+
+type grpcUsers struct {
+	sendkeyv1.UnimplementedUsersServer
+	gw *grpcGateway
+}
+
+func (s grpcUsers) CreateUser(ctx context.Context, req *sendkeyv1.CreateUserRequest) (*sendkeyv1.CreateUserResponse, error) {
+	res := &sendkeyv1.CreateUserResponse{}
+	return res, s.gw.call(ctx, req, res)
+}
+
+type grpcAuth struct {
+	sendkeyv1.UnimplementedAuthServer
+	gw *grpcGateway
+}
+
+func (s grpcAuth) Login(ctx context.Context, req *sendkeyv1.LoginRequest) (*sendkeyv1.LoginResponse, error) {
+	res := &sendkeyv1.LoginResponse{}
+	return res, s.gw.call(ctx, req, res)
+}
+
+func (s grpcAuth) RefreshToken(ctx context.Context, req *sendkeyv1.RefreshTokenRequest) (*sendkeyv1.RefreshTokenResponse, error) {
+	res := &sendkeyv1.RefreshTokenResponse{}
+	return res, s.gw.call(ctx, req, res)
+}
+
+type grpcEntries struct {
+	sendkeyv1.UnimplementedEntriesServer
+	gw *grpcGateway
+}
+
+func (s grpcEntries) CreateEntry(ctx context.Context, req *sendkeyv1.CreateEntryRequest) (*sendkeyv1.CreateEntryResponse, error) {
+	res := &sendkeyv1.CreateEntryResponse{}
+	return res, s.gw.call(ctx, req, res)
+}
+
+func (s grpcEntries) FindEntry(ctx context.Context, req *sendkeyv1.FindEntryRequest) (*sendkeyv1.Entry, error) {
+	res := &sendkeyv1.Entry{}
+	return res, s.gw.call(ctx, req, res)
+}
+
+func (s grpcEntries) ClaimEntry(ctx context.Context, req *sendkeyv1.ClaimEntryRequest) (*sendkeyv1.ClaimEntryResponse, error) {
+	res := &sendkeyv1.ClaimEntryResponse{}
+	return res, s.gw.call(ctx, req, res)
+}
+
+// StreamClaimEntry sends the route's response first, without its value, and
+// then the value in chunks.
+func (s grpcEntries) StreamClaimEntry(req *sendkeyv1.ClaimEntryRequest, stream sendkeyv1.Entries_StreamClaimEntryServer) error {
+	body, err := s.gw.route(stream.Context(), req)
+	if err != nil {
+		return err
+	}
+
+	value, _ := body["value"].(string)
+	delete(body, "value")
+	res := &sendkeyv1.ClaimEntryResponse{}
+	if err = decodeRouteResponse(body, res); err != nil {
+		return err
+	}
+	if err = stream.Send(&sendkeyv1.ClaimEntryChunk{Response: res}); err != nil {
+		return err
+	}
+	for len(value) > 0 {
+		n := len(value)
+		if n > grpcChunkSize {
+			n = grpcChunkSize
+		}
+		if err = stream.Send(&sendkeyv1.ClaimEntryChunk{Data: []byte(value[:n])}); err != nil {
+			return err
+		}
+		value = value[n:]
+	}
+	return nil
+}
+
+// call makes the call to its method's route, and decodes the route's
+// response into res.
+func (g *grpcGateway) call(ctx context.Context, req, res proto.Message) error {
+	body, err := g.route(ctx, req)
+	if err != nil {
+		return err
+	}
+	return decodeRouteResponse(body, res)
+}
+
+// route makes the call to its method's route and returns the route's
+// response. A route that failed outside of validation fails the call.
+func (g *grpcGateway) route(ctx context.Context, req proto.Message) (map[string]interface{}, error) {
+	name, _ := grpc.Method(ctx)
+	d, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(strings.ReplaceAll(strings.TrimPrefix(name, "/"), "/", ".")))
+	if err != nil {
+		return nil, status.Errorf(codes.Unimplemented, "Unknown method %s.", name)
+	}
+	rule, _ := proto.GetExtension(d.Options(), annotations.E_Http).(*annotations.HttpRule)
+	if rule == nil {
+		return nil, status.Errorf(codes.Unimplemented, "%s has no google.api.http option.", name)
+	}
+
+	r, err := routeRequest(ctx, rule, req)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	res := &bufferedResponse{header: http.Header{}}
+	g.api.ServeHTTP(res, r)
+
+	md := metadata.MD{}
+	for k, v := range res.header {
+		switch k {
+		case "Content-Type", "Content-Length", "Connection":
+		default:
+			md.Set(k, v...)
+		}
+	}
+	grpc.SetHeader(ctx, md)
+
+	body, ok := res.object()
+	_, isEnvelope := body["success"]
+	if res.status >= 300 && !isEnvelope {
+		// the route failed outside of validation, with an api.Error
+		message, _ := body["message"].(string)
+		if message == "" {
+			message = http.StatusText(res.status)
+		}
+		if code, _ := body["code"].(string); code != "" {
+			grpc.SetTrailer(ctx, metadata.Pairs("sendkey-error-code", code))
+		}
+		return nil, status.Error(grpcCodeOf(res.status), message)
+	}
+	if !ok {
+		return nil, status.Error(codes.Internal, "The route's response isn't a JSON object.")
+	}
+	return body, nil
+}
+
+// decodeRouteResponse decodes the route's response into the method's
+// response, ignoring the fields the method's response doesn't have.
+func decodeRouteResponse(body map[string]interface{}, res proto.Message) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	if err = (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(b, res); err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	return nil
+}
+
+// routeRequest returns the request to the rule's route with the call's
+// fields, by their JSON names, as its path parameters, and its body or
+// query parameters. The call's metadata are its headers.
+func routeRequest(ctx context.Context, rule *annotations.HttpRule, msg proto.Message) (*http.Request, error) {
+	b, err := protojson.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string]interface{}{}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	if err = d.Decode(&fields); err != nil {
+		return nil, err
+	}
+
+	method, pattern := httpRulePattern(rule)
+	var path strings.Builder
+	rest := pattern
+	for {
+		start := strings.IndexByte(rest, '{')
+		end := strings.IndexByte(rest, '}')
+		if start < 0 || end < start {
+			path.WriteString(rest)
+			break
+		}
+		name := rest[start+1 : end]
+		var value interface{}
+		if f := msg.ProtoReflect().Descriptor().Fields().ByName(protoreflect.Name(name)); f != nil {
+			value = fields[f.JSONName()]
+			delete(fields, f.JSONName())
+		}
+		if value == nil || value == "" {
+			return nil, fmt.Errorf("%s is required.", name)
+		}
+		path.WriteString(rest[:start])
+		path.WriteString(url.PathEscape(fmt.Sprint(value)))
+		rest = rest[end+1:]
+	}
+
+	u := url.URL{Path: path.String()}
+	var body io.Reader = http.NoBody
+	if rule.Body == "*" {
+		b, err := json.Marshal(fields)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(b)
+	} else {
+		q := url.Values{}
+		for name, value := range fields {
+			values, ok := value.([]interface{})
+			if !ok {
+				values = []interface{}{value}
+			}
+			for _, v := range values {
+				q.Add(name, fmt.Sprint(v))
+			}
+		}
+		u.RawQuery = q.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	for k, v := range md {
+		switch {
+		case k == ":authority":
+			req.Host = v[0]
+		case k == "content-type", k == "te", strings.HasPrefix(k, ":"), strings.HasPrefix(k, "grpc-"):
+		default:
+			req.Header[http.CanonicalHeaderKey(k)] = v
+		}
+	}
+	if body != http.NoBody {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		req.RemoteAddr = p.Addr.String()
+		if info, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			req.TLS = &info.State
+		}
+	}
+	return req, nil
+}
+
+// httpRulePattern returns the rule's HTTP method and path.
+func httpRulePattern(rule *annotations.HttpRule) (string, string) {
+	switch p := rule.Pattern.(type) {
+	case *annotations.HttpRule_Get:
+		return http.MethodGet, p.Get
+	case *annotations.HttpRule_Put:
+		return http.MethodPut, p.Put
+	case *annotations.HttpRule_Post:
+		return http.MethodPost, p.Post
+	case *annotations.HttpRule_Delete:
+		return http.MethodDelete, p.Delete
+	case *annotations.HttpRule_Patch:
+		return http.MethodPatch, p.Patch
+	case *annotations.HttpRule_Custom:
+		return p.Custom.Kind, p.Custom.Path
+	}
+	return "", ""
+}
+
+// bufferedResponse keeps the route's response to send as the call's.
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *bufferedResponse) Header() http.Header {
+	return w.header
+}
+
+func (w *bufferedResponse) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *bufferedResponse) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.body.Write(b)
+}
+
+// object returns the response's JSON object. An empty response is an empty
+// object.
+func (w *bufferedResponse) object() (map[string]interface{}, bool) {
+	if w.body.Len() == 0 {
+		return map[string]interface{}{}, true
+	}
+	var v map[string]interface{}
+	d := json.NewDecoder(&w.body)
+	d.UseNumber()
+	if err := d.Decode(&v); err != nil || v == nil {
+		return nil, false
+	}
+	return v, true
+}
+
+// grpcCodeOf returns the gRPC status code for the route's HTTP status.
+func grpcCodeOf(status int) codes.Code {
+	switch status {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound, http.StatusGone:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.Aborted
+	case http.StatusPreconditionFailed, http.StatusLocked:
+		return codes.FailedPrecondition
+	case http.StatusRequestEntityTooLarge, http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusNotImplemented, http.StatusMethodNotAllowed:
+		return codes.Unimplemented
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	case http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	}
+	if status >= http.StatusInternalServerError {
+		return codes.Internal
+	}
+	return codes.Unknown
+}
+
+// serveGRPC serves the gRPC server on ln.
+func serveGRPC(srv *grpc.Server, ln net.Listener) error {
+	return srv.Serve(ln)
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gavinwade12/sendkey/pkg/api/sendkeyv1"
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// dialGateway serves a gateway in front of h and returns a client connected
+// to it.
+func dialGateway(t *testing.T, h http.HandlerFunc, maxBody int64) *grpc.ClientConn {
+	t.Helper()
+	srv := newGRPCServer(h, maxBody, nil)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go serveGRPC(srv, ln)
+
+	conn, err := grpc.NewClient("passthrough:///"+ln.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+		srv.Stop()
+	})
+	return conn
+}
+
+func writeJSON(w http.ResponseWriter, status int, body string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write([]byte(body))
+}
+
+func TestGRPCMethodsHaveRoutes(t *testing.T) {
+	services := sendkeyv1.File_sendkey_proto.Services()
+	for i := 0; i < services.Len(); i++ {
+		methods := services.Get(i).Methods()
+		for j := 0; j < methods.Len(); j++ {
+			m := methods.Get(j)
+			rule, _ := proto.GetExtension(m.Options(), annotations.E_Http).(*annotations.HttpRule)
+			if rule == nil {
+				t.Errorf("%s has no google.api.http option", m.FullName())
+				continue
+			}
+			if method, path := httpRulePattern(rule); method == "" || path == "" {
+				t.Errorf("%s has no route", m.FullName())
+			}
+		}
+	}
+}
+
+func TestGRPCUnary(t *testing.T) {
+	conn := dialGateway(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/v1/entries/e1" || r.URL.Query().Get("nonce") != "n1" {
+			t.Errorf("the route got %s %s", r.Method, r.URL)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer t1" {
+			t.Errorf("the route got Authorization %q", got)
+		}
+		w.Header().Set("X-Request-Id", "r1")
+		writeJSON(w, http.StatusOK, `{"id":"e1","name":"wifi","invalidAttempts":2,"createdAtUtc":"2026-10-15T12:00:00Z","unknown":true}`)
+	}, 1<<20)
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer t1")
+	var header metadata.MD
+	entry, err := sendkeyv1.NewEntriesClient(conn).FindEntry(ctx, &sendkeyv1.FindEntryRequest{EntryId: "e1", Nonce: "n1"}, grpc.Header(&header))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if entry.Id != "e1" || entry.Name != "wifi" || entry.InvalidAttempts != 2 {
+		t.Errorf("got the entry %v", entry)
+	}
+	if entry.CreatedAtUtc.GetSeconds() != 1792065600 {
+		t.Errorf("got createdAtUtc %v", entry.CreatedAtUtc)
+	}
+	if got := header.Get("x-request-id"); len(got) != 1 || got[0] != "r1" {
+		t.Errorf("got the x-request-id metadata %v", got)
+	}
+}
+
+func TestGRPCErrors(t *testing.T) {
+	tests := []struct {
+		status  int
+		body    string
+		code    codes.Code
+		message string
+	}{
+		{http.StatusNotFound, `{"code":"entry_not_found","message":"The entry wasn't found."}`, codes.NotFound, "The entry wasn't found."},
+		{http.StatusUnauthorized, `{"code":"unauthorized","message":"Log in first."}`, codes.Unauthenticated, "Log in first."},
+		{http.StatusTooManyRequests, `{"code":"rate_limited","message":"Slow down ✋"}`, codes.ResourceExhausted, "Slow down ✋"},
+		{http.StatusInternalServerError, ``, codes.Internal, "Internal Server Error"},
+	}
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			conn := dialGateway(t, func(w http.ResponseWriter, r *http.Request) {
+				writeJSON(w, tt.status, tt.body)
+			}, 1<<20)
+
+			var trailer metadata.MD
+			_, err := sendkeyv1.NewEntriesClient(conn).FindEntry(context.Background(), &sendkeyv1.FindEntryRequest{EntryId: "e1", Nonce: "n1"}, grpc.Trailer(&trailer))
+			s, _ := status.FromError(err)
+			if s.Code() != tt.code || s.Message() != tt.message {
+				t.Errorf("got %v, want %v: %s", err, tt.code, tt.message)
+			}
+			if tt.body != "" {
+				if got := trailer.Get("sendkey-error-code"); len(got) != 1 || !strings.Contains(tt.body, got[0]) {
+					t.Errorf("got the sendkey-error-code metadata %v", got)
+				}
+			}
+		})
+	}
+}
+
+func TestGRPCValidationErrors(t *testing.T) {
+	conn := dialGateway(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusBadRequest, `{"success":false,"errors":[{"field":"secret","code":"secret_invalid","message":"The secret is wrong."}]}`)
+	}, 1<<20)
+
+	res, err := sendkeyv1.NewEntriesClient(conn).ClaimEntry(context.Background(), &sendkeyv1.ClaimEntryRequest{EntryId: "e1", Nonce: "n1"})
+	if err != nil {
+		t.Fatalf("a response with errors failed the call: %v", err)
+	}
+	if res.Success || len(res.Errors) != 1 {
+		t.Fatalf("got the response %v", res)
+	}
+	if res.Errors[0].Field != "secret" || res.Errors[0].Code != "secret_invalid" {
+		t.Errorf("got the error %v", res.Errors[0])
+	}
+}
+
+func TestGRPCServerStreaming(t *testing.T) {
+	value := strings.Repeat("0123456789abcdef", 3*grpcChunkSize/16+100)
+	conn := dialGateway(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/entries/e1/value" || r.URL.Query().Get("secret") != "s1" {
+			t.Errorf("the route got %s %s", r.Method, r.URL)
+		}
+		writeJSON(w, http.StatusOK, `{"success":true,"value":"`+value+`"}`)
+	}, 1<<20)
+
+	stream, err := sendkeyv1.NewEntriesClient(conn).StreamClaimEntry(context.Background(), &sendkeyv1.ClaimEntryRequest{EntryId: "e1", Secret: "s1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var chunks []*sendkeyv1.ClaimEntryChunk
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		chunks = append(chunks, chunk)
+	}
+
+	if len(chunks) != 5 {
+		t.Fatalf("got %d messages, want the response and 4 chunks", len(chunks))
+	}
+	if res := chunks[0].Response; !res.GetSuccess() || res.Value != "" {
+		t.Errorf("the first message has the response %v, want it without its value", res)
+	}
+	var got strings.Builder
+	for _, chunk := range chunks[1:] {
+		if len(chunk.Data) > grpcChunkSize {
+			t.Errorf("a chunk has %d bytes", len(chunk.Data))
+		}
+		got.Write(chunk.Data)
+	}
+	if got.String() != value {
+		t.Errorf("the chunks have %d bytes of the value, want %d", got.Len(), len(value))
+	}
+}
+
+func TestGRPCRefusedCalls(t *testing.T) {
+	conn := dialGateway(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("the route got %s %s", r.Method, r.URL)
+	}, 16)
+
+	tests := []struct {
+		name   string
+		method string
+		req    proto.Message
+		code   codes.Code
+	}{
+		{"unknown method", "/sendkey.v1.Entries/DeleteEntry", &sendkeyv1.FindEntryRequest{EntryId: "e1", Nonce: "n1"}, codes.Unimplemented},
+		{"message too large", "/sendkey.v1.Entries/FindEntry", &sendkeyv1.FindEntryRequest{EntryId: "e1", Nonce: strings.Repeat("n", 16)}, codes.ResourceExhausted},
+		{"missing path parameter", "/sendkey.v1.Entries/FindEntry", &sendkeyv1.FindEntryRequest{}, codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := conn.Invoke(context.Background(), tt.method, tt.req, &sendkeyv1.Entry{})
+			if s, _ := status.FromError(err); s.Code() != tt.code {
+				t.Errorf("got %v, want %v", err, tt.code)
+			}
+		})
+	}
+
+	t.Run("malformed message", func(t *testing.T) {
+		// grpc-go fails calls with messages it can't unmarshal as internal
+		// errors
+		req, res := []byte{1<<3 | 2, 5}, []byte(nil)
+		err := conn.Invoke(context.Background(), "/sendkey.v1.Entries/FindEntry", &req, &res, grpc.ForceCodec(rawCodec{}))
+		if s, _ := status.FromError(err); s.Code() != codes.Internal {
+			t.Errorf("got %v, want %v", err, codes.Internal)
+		}
+	})
+}
+
+// rawCodec sends and receives messages as the bytes they're encoded as, to
+// send messages the generated code wouldn't.
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v interface{}) ([]byte, error) { return *v.(*[]byte), nil }
+
+func (rawCodec) Unmarshal(data []byte, v interface{}) error {
+	*v.(*[]byte) = append([]byte(nil), data...)
+	return nil
+}
+
+func (rawCodec) Name() string { return "proto" }
+
+func TestGRPCDeadline(t *testing.T) {
+	var deadline time.Time
+	conn := dialGateway(t, func(w http.ResponseWriter, r *http.Request) {
+		deadline, _ = r.Context().Deadline()
+		writeJSON(w, http.StatusOK, `{}`)
+	}, 1<<20)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if _, err := sendkeyv1.NewEntriesClient(conn).FindEntry(ctx, &sendkeyv1.FindEntryRequest{EntryId: "e1", Nonce: "n1"}); err != nil {
+		t.Fatal(err)
+	}
+	want, _ := ctx.Deadline()
+	// the server's deadline is from when it read the call's timeout
+	if d := deadline.Sub(want); deadline.IsZero() || d < -10*time.Second || d > 10*time.Second {
+		t.Errorf("the route's deadline is %v, want about %v", deadline, want)
+	}
+}
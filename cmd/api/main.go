@@ -1,167 +1,199 @@
 package main
 
 import (
-	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
+	"log/slog"
 	"net"
 	"net/http"
 	"os"
-	"strings"
-	"time"
 
-	"github.com/gavinwade12/sendkey"
-	"github.com/gavinwade12/sendkey/internal/app"
 	"github.com/gavinwade12/sendkey/internal/mysql"
-	"github.com/google/uuid"
-	"github.com/julienschmidt/httprouter"
-	"github.com/rs/cors"
+	"github.com/gavinwade12/sendkey/pkg/server"
 )
 
+// config is the server's config, along with where it listens.
 type config struct {
-	Key                string
-	MaxInvalidAttempts int
-	Host               string
-	Port               string
-	Cors               struct {
-		AllowedOrigins []string
-		AllowedMethods []string
-		AllowedHeaders []string
-	}
-	Auth struct {
-		SigningKey                string
-		AccessTokenDurationMins   int
-		RefreshTokenDurationHours int
-	}
-	MySQL struct {
-		DSN           string
-		MigrationsDir string
-	}
+	server.Config
+	Host string
+	Port string
+	TLS  serverTLSConfig
+	GRPC grpcConfig
 }
 
 func main() {
 	configPath := flag.String("config", "config.json", "the path to the config file")
+	rotateKeys := flag.Bool("rotate-keys", false, "re-encrypt entries with the current key version and exit")
+	exportPath := flag.String("export-entries", "", "write the unexpired entries, still encrypted, to the file and exit")
+	importPath := flag.String("import-entries", "", "import entries from a file written by -export-entries and exit")
+	verifyAudit := flag.Bool("verify-audit", false, "check the audit log for rewritten history and exit")
+	validateOnly := flag.Bool("validate-only", false, "run the startup checks, without changing the database, and exit")
 	flag.Parse()
 
+	st := newStartupTimer()
+	endConfig := st.phase("config")
 	cfg, err := readConfig(*configPath)
 	if err != nil {
+		endConfig(err)
 		log.Fatal(err)
 	}
-
-	opts := []mysql.Option{mysql.AutoCreateDB()}
-	if cfg.MySQL.MigrationsDir != "" {
+	logger, err := cfg.Logger(os.Stderr)
+	if err != nil {
+		endConfig(err)
+		log.Fatal(err)
+	}
+	// the standard logger's output goes through it too, at the info level
+	slog.SetDefault(logger)
+	st.logger = logger
+	endConfig(nil)
+
+	// a read-only replica can't be created or migrated; that's done through
+	// its source. Validating only checks which migrations are pending.
+	recordIDs, _, err := cfg.IDGenerators()
+	if err != nil {
+		log.Fatal(err)
+	}
+	opts := []mysql.Option{mysql.ObservePhases(st.observe), mysql.GenerateIDs(recordIDs)}
+	if !cfg.Replication.ReadOnly && !*validateOnly {
+		opts = append(opts, mysql.AutoCreateDB())
+	}
+	if cfg.MySQL.MigrationsDir != "" && !cfg.Replication.ReadOnly {
 		opts = append(opts, mysql.WithMigrations(cfg.MySQL.MigrationsDir))
+		if *validateOnly {
+			opts = append(opts, mysql.CheckMigrations())
+		}
 	}
 	db, err := mysql.NewDB(cfg.MySQL.DSN, opts...)
 	if err != nil {
 		log.Fatal(err)
 	}
 	defer db.Close()
-
-	// TODO: create a transaction for each request? allow services to request a transaction?
-
-	accessTokenLifetime := time.Minute * time.Duration(cfg.Auth.AccessTokenDurationMins)
-	refreshTokenLifetime := time.Hour * time.Duration(cfg.Auth.RefreshTokenDurationHours)
-	atm := newAuthTokenManager([]byte(cfg.Auth.SigningKey), accessTokenLifetime, refreshTokenLifetime)
-
-	r := httprouter.New()
-	setUserID := setUserID(atm)
-	pipeline := func(a action) httprouter.Handle {
-		return acceptJSON(cleanOutput(setUserID(a)))
+	st.migrated(db)
+	if pending := db.PendingMigrations(); len(pending) > 0 {
+		logger.Info("migrations pending", "migrations", pending)
 	}
 
-	bc := baseController{}
-
-	userSvc := app.NewUserService(db.Users)
-	uc := &UsersController{bc, userSvc, atm, db.RefreshTokens}
-
-	entrySvc := app.NewEntryService(db.Entries, []byte(cfg.Key), cfg.MaxInvalidAttempts)
-	ec := &EntriesController{bc, entrySvc}
-
-	r.POST("/users", pipeline(uc.CreateUser))
-	r.POST("/login", pipeline(uc.Login))
-	r.POST("/token", pipeline(uc.RefreshToken))
-
-	r.POST("/entries", pipeline(ec.CreateEntry))
-	r.GET("/entries/:entryID", pipeline(ec.FindEntry))
-	r.GET("/entries/:entryID/value", pipeline(ec.EntryValue))
-	r.GET("/users/:userID/entries", pipeline(ec.FindUserEntries))
-
-	c := cors.New(cors.Options{
-		AllowedOrigins: cfg.Cors.AllowedOrigins,
-		AllowedMethods: cfg.Cors.AllowedMethods,
-		AllowedHeaders: cfg.Cors.AllowedHeaders,
-	})
-
-	addr := net.JoinHostPort(cfg.Host, cfg.Port)
-	fmt.Printf("listening on %s\n", addr)
-	if err = http.ListenAndServe(addr, c.Handler(r)); err != nil {
+	srv, err := server.New(cfg.Config, server.MySQLStores(db), server.WithLogger(logger), server.ObservePhases(st.observe))
+	if err != nil {
 		log.Fatal(err)
 	}
-}
-
-func acceptJSON(h httprouter.Handle) httprouter.Handle {
-	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
-		ct := r.Header.Get("Content-Type")
-		if ct != "" && ct != "application/json" {
-			http.Error(w, "Invalid Content-Type", http.StatusBadRequest)
-			return
+	defer srv.Close()
+	if reg := srv.Metrics(); reg != nil {
+		st.record(reg)
+	}
+	if *verifyAudit {
+		v, err := srv.VerifyAudit()
+		if err != nil {
+			log.Fatal(err)
 		}
-
-		w.Header().Set("Content-Type", "application/json")
-
-		h(w, r, p)
+		fmt.Printf("verified %d audit events and %d anchors\n", v.Events, v.Anchors)
+		if v.Unanchored > 0 {
+			fmt.Printf("%d events after the last anchor are chained but not yet signed\n", v.Unanchored)
+		}
+		return
 	}
-}
-
-type action func(http.ResponseWriter, *http.Request, httprouter.Params) error
-
-func cleanOutput(a action) httprouter.Handle {
-	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
-		defer func() {
-			if r := recover(); r != nil {
-				err, ok := r.(error)
-				if !ok {
-					err = fmt.Errorf("%v", r)
-				}
-
-				e := Error{StatusCode: http.StatusInternalServerError, Message: fmt.Sprintf("panic recovery: %v", err)}
-				w.WriteHeader(e.StatusCode)
-				json.NewEncoder(w).Encode(e)
-				json.NewEncoder(log.Writer()).Encode(e)
-			}
-		}()
-
-		err := a(w, r, p)
-		if err == nil {
-			return
+	if *rotateKeys {
+		resp, err := srv.RotateKeys()
+		if err != nil {
+			log.Fatal(err)
 		}
-
-		var (
-			e  Error
-			ok bool
-		)
-		if e, ok = err.(Error); !ok {
-			e = Error{StatusCode: http.StatusInternalServerError, Message: err.Error()}
+		fmt.Printf("re-encrypted %d entries with key version %d\n", resp.Reencrypted, resp.KeyVersion)
+		if resp.Legacy > 0 {
+			fmt.Printf("%d legacy entries can't be re-encrypted; keep Key until they're claimed or expire\n", resp.Legacy)
 		}
+		return
+	}
+	if *exportPath != "" {
+		n, err := srv.ExportEntries(*exportPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("exported %d entries to %s\n", n, *exportPath)
+		return
+	}
+	if *importPath != "" {
+		resp, err := srv.ImportEntries(*importPath)
+		if resp != nil {
+			fmt.Printf("imported %d entries, skipped %d existing and %d expired\n", resp.Imported, resp.Existing, resp.Expired)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if !*validateOnly {
+		srv.Start()
+	}
 
-		w.WriteHeader(e.StatusCode)
-		json.NewEncoder(w).Encode(e)
-		json.NewEncoder(log.Writer()).Encode(e)
+	addr := net.JoinHostPort(cfg.Host, cfg.Port)
+	hs := srv.HTTPServer(addr)
+	var redirect http.Handler
+	if cfg.TLS.enabled() {
+		endTLS := st.phase("tls")
+		redirect, err = cfg.TLS.configure(hs)
+		endTLS(err)
+		if err != nil {
+			log.Fatal(err)
+		}
 	}
-}
 
-// Error is an error returned from the API.
-type Error struct {
-	UserID     uuid.UUID `json:"userId"`
-	StatusCode int       `json:"statusCode"`
-	Message    string    `json:"message"`
-}
+	endListen := st.phase("listen")
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		endListen(err)
+		log.Fatal(err)
+	}
+	var opsLn net.Listener
+	if cfg.Ops.Addr != "" {
+		if opsLn, err = net.Listen("tcp", cfg.Ops.Addr); err != nil {
+			endListen(err)
+			log.Fatal(err)
+		}
+	}
+	var grpcLn net.Listener
+	if cfg.GRPC.Addr != "" {
+		if grpcLn, err = net.Listen("tcp", cfg.GRPC.Addr); err != nil {
+			endListen(err)
+			log.Fatal(err)
+		}
+	}
+	endListen(nil)
+	if *validateOnly {
+		ln.Close()
+		if opsLn != nil {
+			opsLn.Close()
+		}
+		if grpcLn != nil {
+			grpcLn.Close()
+		}
+		fmt.Println("startup checks passed")
+		return
+	}
 
-func (e Error) Error() string {
-	return e.Message
+	st.ready()
+	fmt.Printf("listening on %s\n", addr)
+	if opsLn != nil {
+		fmt.Printf("serving operational endpoints on %s\n", cfg.Ops.Addr)
+		go func() {
+			if err := http.Serve(opsLn, srv.Ops()); err != nil {
+				log.Fatal(err)
+			}
+		}()
+	}
+	if grpcLn != nil {
+		fmt.Printf("serving grpc on %s\n", cfg.GRPC.Addr)
+		gs := newGRPCServer(srv, srv.MaxBodyBytes(), hs.TLSConfig)
+		go func() {
+			if err := serveGRPC(gs, grpcLn); err != nil {
+				log.Fatal(err)
+			}
+		}()
+	}
+	if err = serve(hs, ln, redirect, cfg.TLS.RedirectAddr); err != nil {
+		log.Fatal(err)
+	}
 }
 
 func readConfig(path string) (*config, error) {
@@ -178,51 +210,3 @@ func readConfig(path string) (*config, error) {
 
 	return cfg, nil
 }
-
-type userIDCtxKey string
-
-const userIDCtxKeyValue = userIDCtxKey("userID")
-
-func setUserID(atv AccessTokenVerifier) func(a action) action {
-	return func(a action) action {
-		return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
-			token := r.Header.Get("Authorization")
-			if token == "" {
-				return a(w, r, p)
-			}
-			token = strings.TrimPrefix(token, "Bearer ")
-
-			userID, err := atv.Verify(token)
-			if err != nil {
-				return Error{StatusCode: http.StatusUnauthorized, Message: err.Error()}
-			}
-
-			ctx := r.Context()
-			ctx = context.WithValue(ctx, userIDCtxKeyValue, userID)
-			r = r.WithContext(ctx)
-
-			return a(w, r, p)
-		}
-	}
-}
-
-type baseController struct {
-}
-
-func (c baseController) GetCurrentUserID(r *http.Request) (uuid.UUID, error) {
-	userID := r.Context().Value(userIDCtxKeyValue)
-	if userID == nil {
-		return uuid.Nil, fmt.Errorf("unable to get current user id")
-	}
-
-	return userID.(uuid.UUID), nil
-}
-
-func (c baseController) GetCurrentUser(r *http.Request, us *app.UserService) (*sendkey.User, error) {
-	id, err := c.GetCurrentUserID(r)
-	if err != nil {
-		return nil, err
-	}
-
-	return us.FindUser(id)
-}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/gavinwade12/sendkey/internal/metrics"
+	"github.com/gavinwade12/sendkey/internal/mysql"
+)
+
+type startupPhase struct {
+	name string
+	d    time.Duration
+	err  error
+}
+
+// startupTimer times the phases of starting the API, like loading the config
+// and migrating the database, so slow or failing deploys show where the time
+// went. Each phase is logged as it ends and, once metrics are set up,
+// recorded as a "startup." operation.
+type startupTimer struct {
+	start  time.Time
+	logger *slog.Logger
+	reg    *metrics.Registry
+	phases []startupPhase
+}
+
+func newStartupTimer() *startupTimer {
+	return &startupTimer{start: time.Now(), logger: slog.Default()}
+}
+
+// phase starts timing a phase, returning the func that ends it with the
+// phase's error.
+func (t *startupTimer) phase(name string) func(error) {
+	start := time.Now()
+	return func(err error) { t.observe(name, time.Since(start), err) }
+}
+
+func (t *startupTimer) observe(name string, d time.Duration, err error) {
+	t.phases = append(t.phases, startupPhase{name, d, err})
+	if err != nil {
+		t.logger.Error("startup phase failed", "phase", name, "duration", d, "error", err)
+	} else {
+		t.logger.Info("startup phase", "phase", name, "duration", d)
+	}
+	if t.reg != nil {
+		t.reg.Observe("startup."+name, d, err)
+	}
+}
+
+// migrated logs the migrations the database ran and how long each took.
+func (t *startupTimer) migrated(db *mysql.DB) {
+	for _, m := range db.Migrations() {
+		t.logger.Info("ran migration", "migration", m.Name, "duration", m.Duration)
+	}
+}
+
+// record records the phases timed so far in reg, along with the ones after.
+func (t *startupTimer) record(reg *metrics.Registry) {
+	t.reg = reg
+	for _, p := range t.phases {
+		reg.Observe("startup."+p.name, p.d, p.err)
+	}
+}
+
+// ready ends startup, recording how long it took in total.
+func (t *startupTimer) ready() {
+	t.observe("total", time.Since(t.start), nil)
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// serverTLSConfig serves the API over HTTPS directly, instead of behind a
+// reverse proxy that terminates TLS. It uses a certificate and key from files,
+// or gets certificates from Let's Encrypt (or another ACME CA) with
+// Autocert. It's off if neither is set.
+type serverTLSConfig struct {
+	CertFile string
+	KeyFile  string
+	Autocert struct {
+		Enabled bool
+		// Hosts are the only hostnames certificates are requested for, so
+		// a request for any other name can't make the server exhaust the
+		// CA's rate limits.
+		Hosts []string
+		// Email is given to the CA to contact about the certificates.
+		Email string
+		// CacheDir keeps the account key and certificates between
+		// restarts. It must be private, since it holds the keys.
+		CacheDir string
+		// DirectoryURL is the CA's ACME directory. Empty uses Let's
+		// Encrypt's production directory; use its staging one to test.
+		DirectoryURL string
+	}
+	// RedirectAddr is the address of a plain HTTP listener, e.g. ":80",
+	// redirecting to HTTPS. With Autocert, it also answers the CA's HTTP-01
+	// challenges. Empty doesn't listen.
+	RedirectAddr string
+}
+
+func (c serverTLSConfig) enabled() bool {
+	return c.CertFile != "" || c.Autocert.Enabled
+}
+
+// configure sets up the server for HTTPS and returns the handler for the
+// redirect listener.
+func (c serverTLSConfig) configure(srv *http.Server) (http.Handler, error) {
+	if !c.Autocert.Enabled {
+		if c.CertFile == "" || c.KeyFile == "" {
+			return nil, fmt.Errorf("tls: CertFile and KeyFile are both required")
+		}
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("tls: loading certificate: %w", err)
+		}
+		srv.TLSConfig = &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+		return redirectHTTPS(srv.Addr), nil
+	}
+
+	if c.CertFile != "" {
+		return nil, fmt.Errorf("tls: use either CertFile and KeyFile or Autocert, not both")
+	}
+	if len(c.Autocert.Hosts) == 0 {
+		return nil, fmt.Errorf("tls: Autocert needs the Hosts to request certificates for")
+	}
+	if c.Autocert.CacheDir == "" {
+		return nil, fmt.Errorf("tls: Autocert needs a CacheDir, or certificates are requested again on every restart")
+	}
+	m := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(c.Autocert.Hosts...),
+		Cache:      autocert.DirCache(c.Autocert.CacheDir),
+		Email:      c.Autocert.Email,
+	}
+	if c.Autocert.DirectoryURL != "" {
+		m.Client = &acme.Client{DirectoryURL: c.Autocert.DirectoryURL}
+	}
+	srv.TLSConfig = m.TLSConfig()
+	srv.TLSConfig.MinVersion = tls.VersionTLS12
+	// the manager's handler answers challenges, and redirects everything
+	// else to HTTPS on the default port
+	return m.HTTPHandler(redirectHTTPS(srv.Addr)), nil
+}
+
+// redirectHTTPS redirects requests to the same URL over HTTPS on the API's
+// port. Only GET and HEAD are redirected; anything else may already have sent
+// a secret in the clear, so it's refused rather than repeated.
+func redirectHTTPS(addr string) http.Handler {
+	_, port, _ := net.SplitHostPort(addr)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "Use HTTPS", http.StatusBadRequest)
+			return
+		}
+
+		host := r.Host
+		if h, _, err := net.SplitHostPort(r.Host); err == nil {
+			host = h
+		}
+		if port != "" && port != "443" {
+			host = net.JoinHostPort(host, port)
+		}
+		u := url.URL{Scheme: "https", Host: host, Path: r.URL.Path, RawQuery: r.URL.RawQuery}
+		http.Redirect(w, r, u.String(), http.StatusMovedPermanently)
+	})
+}
+
+// serve serves the API on ln, over HTTPS if the server was configured for it,
+// with the redirect listener alongside it.
+func serve(srv *http.Server, ln net.Listener, redirect http.Handler, redirectAddr string) error {
+	if srv.TLSConfig == nil {
+		return srv.Serve(ln)
+	}
+
+	if redirectAddr != "" {
+		fmt.Printf("redirecting http on %s to https\n", redirectAddr)
+		go func() {
+			if err := http.ListenAndServe(redirectAddr, redirect); err != nil {
+				log.Fatal(err)
+			}
+		}()
+	}
+	// the certificates are in the TLS config
+	return srv.ServeTLS(ln, "", "")
+}
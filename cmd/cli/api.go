@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/gavinwade12/sendkey/pkg/api"
+	"github.com/urfave/cli/v2"
+)
+
+func mountAPICommands(cliApp *cli.App) {
+	cliApp.Commands = append(cliApp.Commands, apiCommand)
+}
+
+var apiCommand = &cli.Command{
+	Name:  "api",
+	Usage: "Explore the sendkey API.",
+	Subcommands: []*cli.Command{
+		apiListCommand,
+		apiCallCommand,
+	},
+}
+
+var apiListCommand = &cli.Command{
+	Name:  "list",
+	Usage: "List the operations that can be called.",
+	Action: func(ctx *cli.Context) error {
+		for _, id := range api.OperationIDs() {
+			o, _ := api.FindOperation(id)
+			fmt.Printf("%-18s %-6s %-26s %s\n", o.ID, o.Method, o.Path, o.Summary)
+		}
+		return nil
+	},
+}
+
+var apiCallCommand = &cli.Command{
+	Name:      "call",
+	Usage:     "Call an operation and print the response.",
+	ArgsUsage: "<operation>",
+	Flags: []cli.Flag{
+		&cli.StringSliceFlag{
+			Name:    "param",
+			Aliases: []string{"p"},
+			Usage:   "A path parameter, like entryID=<id>.",
+		},
+		&cli.StringSliceFlag{
+			Name:    "query",
+			Aliases: []string{"q"},
+			Usage:   "A query parameter, like nonce=<nonce>.",
+		},
+		&cli.StringFlag{
+			Name:    "data",
+			Aliases: []string{"d"},
+			Usage:   "The JSON request body, @file to read it from a file, or - to read it from stdin.",
+		},
+		&cli.BoolFlag{
+			Name:  "example",
+			Usage: "Start from the operation's example parameters and body. Other flags override them.",
+		},
+	},
+	Action: func(ctx *cli.Context) error {
+		id := ctx.Args().First()
+		if id == "" {
+			return fmt.Errorf("an operation is required; see `sendkey api list`")
+		}
+		o, ok := api.FindOperation(id)
+		if !ok {
+			return fmt.Errorf("unknown operation %q; see `sendkey api list`", id)
+		}
+
+		params, query := map[string]string{}, url.Values{}
+		var body []byte
+		if ctx.Bool("example") {
+			for name, v := range o.Params {
+				params[name] = v
+			}
+			for name, v := range o.Query {
+				query.Set(name, v)
+			}
+			if o.Request != nil {
+				b, err := json.Marshal(o.Request)
+				if err != nil {
+					return err
+				}
+				body = b
+			}
+		}
+		for _, p := range ctx.StringSlice("param") {
+			name, v, ok := strings.Cut(p, "=")
+			if !ok {
+				return fmt.Errorf("invalid parameter %q; use name=value", p)
+			}
+			params[name] = v
+		}
+		for _, q := range ctx.StringSlice("query") {
+			name, v, ok := strings.Cut(q, "=")
+			if !ok {
+				return fmt.Errorf("invalid query parameter %q; use name=value", q)
+			}
+			query.Set(name, v)
+		}
+		if ctx.IsSet("data") {
+			b, err := readData(ctx.String("data"))
+			if err != nil {
+				return err
+			}
+			body = b
+		}
+
+		path, err := o.Expand(params)
+		if err != nil {
+			return err
+		}
+		if len(query) > 0 {
+			path += "?" + query.Encode()
+		}
+
+		if err = ensureClient(ctx); err != nil {
+			return err
+		}
+		var reader io.ReadSeeker
+		if body != nil {
+			reader = bytes.NewReader(body)
+		}
+		res, err := sendkeyClient.Do(o.Method, path, reader)
+		if err != nil {
+			return err
+		}
+		defer res.Body.Close()
+
+		fmt.Fprintf(os.Stderr, "%s %s\n", res.Proto, res.Status)
+		if reqID := res.Header.Get("X-Request-ID"); reqID != "" {
+			fmt.Fprintf(os.Stderr, "X-Request-ID: %s\n", reqID)
+		}
+		b, err := io.ReadAll(res.Body)
+		if err != nil {
+			return err
+		}
+		var out bytes.Buffer
+		if json.Indent(&out, b, "", "  ") != nil {
+			out.Reset()
+			out.Write(b)
+		}
+		fmt.Println(strings.TrimRight(out.String(), "\n"))
+		return nil
+	},
+}
+
+// readData reads a request body given as JSON, @file, or - for stdin.
+func readData(data string) ([]byte, error) {
+	switch {
+	case data == "-":
+		return io.ReadAll(os.Stdin)
+	case strings.HasPrefix(data, "@"):
+		return os.ReadFile(data[1:])
+	default:
+		return []byte(data), nil
+	}
+}
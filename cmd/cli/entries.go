@@ -1,17 +1,37 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/gavinwade12/sendkey"
+	"github.com/gavinwade12/sendkey/pkg/api"
 	"github.com/gavinwade12/sendkey/pkg/client"
+	"github.com/google/uuid"
+	"github.com/skip2/go-qrcode"
 	"github.com/urfave/cli/v2"
 )
 
 func mountEntryCommands(cliApp *cli.App) {
 	cliApp.Commands = append(cliApp.Commands,
 		createEntryCommand,
+		generateCommand,
 		listEntriesCommand,
+		watchCommand,
+		usageCommand,
+		claimEntryCommand,
+		deferEntryCommand,
+		resendLinkCommand,
+		delegateClaimCommand,
+		claimDelegatedCommand,
+		pinEntryCommand,
+		favoriteEntryCommand,
+		openEscrowCommand,
 	)
 }
 
@@ -19,19 +39,23 @@ var createEntryCommand = &cli.Command{
 	Name:    "create_entry",
 	Aliases: []string{"ce"},
 	Usage:   "Create a new sendkey entry.",
-	Flags: []cli.Flag{
+	Flags: append([]cli.Flag{
 		&cli.StringFlag{
 			Name:     "name",
 			Aliases:  []string{"n"},
 			Usage:    "The entry name.",
 			Required: true,
 		},
-		&cli.IntFlag{
+		&cli.StringFlag{
 			Name:     "duration",
 			Aliases:  []string{"d"},
-			Usage:    "The duration (in minutes) the entry is valid.",
+			Usage:    "How long the entry is valid, e.g. \"45m\" or \"2h\". A bare number is minutes.",
 			Required: true,
 		},
+		&cli.StringFlag{
+			Name:  "availableAt",
+			Usage: "The earliest the entry can be claimed, as an RFC 3339 time like \"2022-03-20T09:00:00Z\".",
+		},
 		&cli.StringFlag{
 			Name:     "sendTo",
 			Aliases:  []string{"st"},
@@ -39,10 +63,21 @@ var createEntryCommand = &cli.Command{
 			Required: true,
 		},
 		&cli.StringFlag{
-			Name:     "value",
-			Aliases:  []string{"v"},
-			Usage:    "The entry value.",
-			Required: true,
+			Name:    "value",
+			Aliases: []string{"v"},
+			Usage:   "The entry value. Required unless --generate is set.",
+		},
+		&cli.BoolFlag{
+			Name:  "qr",
+			Usage: "Print the claim link as a QR code for scanning with a phone.",
+		},
+		&cli.BoolFlag{
+			Name:  "generate",
+			Usage: "Have the server generate a random value for the entry and print it.",
+		},
+		&cli.BoolFlag{
+			Name:  "recipientOnly",
+			Usage: "With --generate, don't return or print the generated value, so only the recipient ever sees it.",
 		},
 		&cli.StringFlag{
 			Name:     "secret",
@@ -50,49 +85,286 @@ var createEntryCommand = &cli.Command{
 			Usage:    "The secret required to view the entry value.",
 			Required: true,
 		},
-	},
+		&cli.StringFlag{
+			Name:    "locale",
+			Aliases: []string{"l"},
+			Usage:   "The language the recipient's claim page should use if their browser doesn't request a supported one, e.g. \"es\".",
+		},
+		&cli.StringSliceFlag{
+			Name:    "question",
+			Aliases: []string{"q"},
+			Usage:   "A challenge question the recipient must answer. Can be repeated.",
+		},
+		&cli.StringSliceFlag{
+			Name:    "answer",
+			Aliases: []string{"a"},
+			Usage:   "The answer to the challenge question at the same position.",
+		},
+		&cli.StringFlag{
+			Name:    "type",
+			Aliases: []string{"t"},
+			Usage:   "The kind of value: \"password\", \"note\" (the default), \"ssh-key\", or \"env-file\".",
+		},
+		&cli.StringFlag{
+			Name:  "username",
+			Usage: "The account a password or SSH key is for.",
+		},
+		&cli.StringFlag{
+			Name:  "hostname",
+			Usage: "The host an SSH key is for.",
+		},
+		&cli.BoolFlag{
+			Name:  "e2e",
+			Usage: "Encrypt the value locally so the server never sees it or the secret. The recipient must claim it with the CLI.",
+		},
+		&cli.BoolFlag{
+			Name:  "requireLogin",
+			Usage: "Require the recipient to log in to sendkey with the email the entry is sent to before claiming it.",
+		},
+		&cli.BoolFlag{
+			Name:  "requireOtp",
+			Usage: "Email the recipient a one-time code when they claim the entry, which they must enter with the secret.",
+		},
+		&cli.StringFlag{
+			Name:  "acknowledgment",
+			Usage: "Text the recipient must acknowledge to claim the entry, like \"I will store this in the team vault\".",
+		},
+		&cli.StringFlag{
+			Name:  "cipher",
+			Usage: "The cipher for an end-to-end entry, either \"xchacha20-poly1305\" (the default) or \"aes-gcm\".",
+		},
+		&cli.StringFlag{
+			Name:  "replaces",
+			Usage: "The ID of an unclaimed entry this one rotates, like a password's previous value. It's revoked when this one is claimed.",
+		},
+	}, generateFlags...),
 	Action: func(ctx *cli.Context) error {
-		err := ensureClient(ctx.String("config"))
+		err := ensureClient(ctx)
 		if err != nil {
 			return err
 		}
 
-		req := client.CreateEntryRequest{
-			Name:            ctx.String("name"),
-			SendToEmail:     ctx.String("sendTo"),
-			Value:           ctx.String("value"),
-			Secret:          ctx.String("secret"),
-			DurationMinutes: ctx.Int("duration"),
+		generate := ctx.Bool("generate")
+		switch {
+		case generate && ctx.String("value") != "":
+			return fmt.Errorf("--value can't be used with --generate")
+		case generate && ctx.Bool("e2e"):
+			return fmt.Errorf("--generate can't be used with --e2e since the server would see the value")
+		case !generate && ctx.String("value") == "":
+			return fmt.Errorf("--value is required unless --generate is set")
+		case !generate && ctx.Bool("recipientOnly"):
+			return fmt.Errorf("--recipientOnly can only be used with --generate")
+		}
+
+		questions, answers := ctx.StringSlice("question"), ctx.StringSlice("answer")
+		if len(questions) != len(answers) {
+			return fmt.Errorf("each question requires exactly one answer")
 		}
 
-		res, e, err := sendkeyClient.Entries.CreateEntry(req)
+		duration, err := parseDuration(ctx.String("duration"))
 		if err != nil {
 			return err
 		}
-		if e != nil {
-			return fmt.Errorf("[%d]: %s", e.StatusCode, e.Message)
+
+		var replaces *uuid.UUID
+		if s := ctx.String("replaces"); s != "" {
+			id, err := sendkey.ParseID(s)
+			if err != nil {
+				return fmt.Errorf("invalid --replaces entry id: %w", err)
+			}
+			replaces = &id
 		}
-		if !res.Success {
-			return fmt.Errorf(strings.Join(res.Errors, "; "))
+
+		var availableAt *time.Time
+		if s := ctx.String("availableAt"); s != "" {
+			t, err := time.Parse(time.RFC3339, s)
+			if err != nil {
+				return fmt.Errorf("--availableAt must be an RFC 3339 time: %w", err)
+			}
+			availableAt = &t
+		}
+
+		req := api.CreateEntryRequest{
+			Name:           ctx.String("name"),
+			SendToEmail:    ctx.String("sendTo"),
+			Value:          ctx.String("value"),
+			Secret:         ctx.String("secret"),
+			Duration:       &api.EntryDuration{Duration: duration},
+			AvailableAtUTC: availableAt,
+			Locale:         ctx.String("locale"),
+			Type:           ctx.String("type"),
+			RequireLogin:   ctx.Bool("requireLogin"),
+			RequireOTP:     ctx.Bool("requireOtp"),
+			Acknowledgment: ctx.String("acknowledgment"),
+			Metadata: sendkey.EntryMetadata{
+				Username: ctx.String("username"),
+				Hostname: ctx.String("hostname"),
+			},
+			ReplacesEntryID: replaces,
+		}
+		if ctx.Bool("e2e") {
+			if req.EndToEnd, err = client.Seal(req.Value, req.Secret, ctx.String("cipher")); err != nil {
+				return fmt.Errorf("encrypting value: %w", err)
+			}
+			if req.Digest, err = client.NewDigest(req.Value, req.Secret); err != nil {
+				return fmt.Errorf("computing digest: %w", err)
+			}
+			req.Value, req.Secret = "", ""
+		}
+		for i := range questions {
+			req.Challenges = append(req.Challenges, api.EntryChallenge{
+				Question: questions[i],
+				Answer:   answers[i],
+			})
+		}
+
+		if ctx.Bool("recipientOnly") {
+			policy := generatePolicy(ctx)
+			req.Generate = &policy
+		}
+
+		var (
+			entry           *sendkey.Entry
+			value, claimURL string
+		)
+		if generate && req.Generate == nil {
+			gen := generateRequest(ctx)
+			gen.Entry = &req
+			res, e, err := sendkeyClient.Entries.Generate(gen)
+			if err != nil {
+				return err
+			}
+			if e != nil {
+				return apiError(e)
+			}
+			if !res.Success {
+				return errors.New(strings.Join(res.Messages(), "; "))
+			}
+			entry, value, claimURL = res.Entry, res.Value, res.ClaimURL
+		} else {
+			res, e, err := sendkeyClient.Entries.Create(ctx.Context, req, "")
+			if err != nil {
+				return err
+			}
+			if e != nil {
+				return apiError(e)
+			}
+			if !res.Success {
+				return errors.New(strings.Join(res.Messages(), "; "))
+			}
+			entry, claimURL = res.Entry, res.ClaimURL
+			for _, w := range res.Warnings {
+				fmt.Fprintf(os.Stderr, "Warning: %s\n", w)
+			}
 		}
 
 		fmt.Println("Successfully created entry:")
-		fmt.Printf("\tID: %s\n", res.Entry.ID.String())
-		fmt.Printf("\tName: %s\n", res.Entry.Name)
-		fmt.Printf("\tSentTo: %s\n", res.Entry.SentToEmail)
-		fmt.Printf("\tCreatedAtUtc: %s\n", res.Entry.CreatedAtUTC.String())
-		fmt.Printf("\tExpiresAtUtc: %s\n", res.Entry.ExpiresAtUTC.String())
+		fmt.Printf("\tID: %s\n", sendkey.FormatID(entry.ID))
+		fmt.Printf("\tName: %s\n", entry.Name)
+		fmt.Printf("\tType: %s\n", entry.Type)
+		fmt.Printf("\tSentTo: %s\n", entry.SentToEmail)
+		fmt.Printf("\tCreatedAtUtc: %s\n", entry.CreatedAtUTC.String())
+		if entry.AvailableAtUTC != nil {
+			fmt.Printf("\tAvailableAtUtc: %s\n", entry.AvailableAtUTC.String())
+		}
+		fmt.Printf("\tExpiresAtUtc: %s\n", entry.ExpiresAtUTC.String())
+		if entry.ReplacesEntryID != nil {
+			fmt.Printf("\tReplaces: %s\n", sendkey.FormatID(*entry.ReplacesEntryID))
+		}
+		if entry.Acknowledgment != "" {
+			fmt.Printf("\tAcknowledgment: %s\n", entry.Acknowledgment)
+		}
+		if value != "" {
+			fmt.Printf("\tValue: %s\n", value)
+		}
+		if claimURL != "" {
+			fmt.Printf("\tClaimURL: %s\n", claimURL)
+		}
+		if ctx.Bool("qr") && claimURL != "" {
+			qr, err := qrcode.New(claimURL, qrcode.Medium)
+			if err != nil {
+				return fmt.Errorf("rendering QR code: %w", err)
+			}
+			fmt.Println()
+			fmt.Print(qr.ToSmallString(false))
+		}
 
 		return nil
 	},
 }
 
+// generateFlags configure the value generated by the generate command and
+// create_entry --generate.
+var generateFlags = []cli.Flag{
+	&cli.IntFlag{
+		Name:  "length",
+		Usage: "The number of characters in a generated password. Defaults to 24.",
+	},
+	&cli.StringFlag{
+		Name:  "charset",
+		Usage: "The characters a generated password is drawn from: \"alphanumeric\" (the default), \"symbols\", \"digits\", or \"hex\".",
+	},
+	&cli.IntFlag{
+		Name:  "words",
+		Usage: "Generate a passphrase of this many words instead of a password.",
+	},
+	&cli.StringFlag{
+		Name:  "separator",
+		Usage: "The separator between a passphrase's words. Defaults to \"-\".",
+	},
+}
+
+func generateRequest(ctx *cli.Context) api.GenerateRequest {
+	return api.GenerateRequest{
+		Length:    ctx.Int("length"),
+		Charset:   ctx.String("charset"),
+		Words:     ctx.Int("words"),
+		Separator: ctx.String("separator"),
+	}
+}
+
+func generatePolicy(ctx *cli.Context) api.GeneratePolicy {
+	return api.GeneratePolicy{
+		Length:    ctx.Int("length"),
+		Charset:   ctx.String("charset"),
+		Words:     ctx.Int("words"),
+		Separator: ctx.String("separator"),
+	}
+}
+
+var generateCommand = &cli.Command{
+	Name:    "generate",
+	Aliases: []string{"g"},
+	Usage:   "Generate a random password or passphrase.",
+	Flags:   generateFlags,
+	Action: func(ctx *cli.Context) error {
+		err := ensureClient(ctx)
+		if err != nil {
+			return err
+		}
+
+		res, e, err := sendkeyClient.Entries.Generate(generateRequest(ctx))
+		if err != nil {
+			return err
+		}
+		if e != nil {
+			return apiError(e)
+		}
+		if !res.Success {
+			return errors.New(strings.Join(res.Messages(), "; "))
+		}
+
+		fmt.Println(res.Value)
+		return nil
+	},
+}
+
 var listEntriesCommand = &cli.Command{
 	Name:    "list_entries",
 	Aliases: []string{"le"},
 	Usage:   "Lists all unclaimed, unexpired entries.",
 	Action: func(ctx *cli.Context) error {
-		err := ensureClient(ctx.String("config"))
+		err := ensureClient(ctx)
 		if err != nil {
 			return err
 		}
@@ -102,18 +374,664 @@ var listEntriesCommand = &cli.Command{
 			return err
 		}
 		if e != nil {
-			return fmt.Errorf("[%d]: %s", e.StatusCode, e.Message)
+			return apiError(e)
 		}
 
 		for _, entry := range res {
-			fmt.Printf("ID: %s\n", entry.ID.String())
+			fmt.Printf("ID: %s%s\n", sendkey.FormatID(entry.ID), flagSuffix(entry.Flags))
 			fmt.Printf("\tName: %s\n", entry.Name)
+			fmt.Printf("\tType: %s\n", entryType(entry))
+			printMetadata(entry.Metadata)
 			fmt.Printf("\tSentTo: %s\n", entry.SentToEmail)
 			fmt.Printf("\tCreatedAtUtc: %s\n", entry.CreatedAtUTC.String())
+			if entry.AvailableAtUTC != nil {
+				fmt.Printf("\tAvailableAtUtc: %s\n", entry.AvailableAtUTC.String())
+			}
 			fmt.Printf("\tExpiresAtUtc: %s\n", entry.ExpiresAtUTC.String())
+			for _, d := range entry.Deferrals {
+				fmt.Printf("\tDeferredAtUtc: %s (reminder at %s)\n", d.DeferredAtUTC.String(), d.RemindAtUTC.String())
+			}
 			fmt.Println()
 		}
 
 		return nil
 	},
 }
+
+var watchCommand = &cli.Command{
+	Name:  "watch",
+	Usage: "Shows your entries being claimed, expiring, and being revoked as it happens.",
+	Action: func(ctx *cli.Context) error {
+		err := ensureClient(ctx)
+		if err != nil {
+			return err
+		}
+
+		for {
+			e, err := sendkeyClient.Entries.Watch(ctx.Context, printEntryEvent)
+			if e != nil {
+				return apiError(e)
+			}
+			// the server ends streams now and then, e.g. before the access
+			// token expires, so they're reopened
+			if !errors.Is(err, client.ErrStreamEnded) {
+				return err
+			}
+			time.Sleep(time.Second)
+		}
+	},
+}
+
+func printEntryEvent(e api.EntryEvent) error {
+	fmt.Printf("%s %s %s\n", e.AtUTC.Format(time.RFC3339), strings.TrimPrefix(e.Type, "entry."), sendkey.FormatID(e.EntryID))
+	for _, k := range []string{"claimerId", "claimMethod", "tooManyAttempts", "replacedByEntryId"} {
+		if v, ok := e.Data[k]; ok {
+			fmt.Printf("\t%s: %s\n", k, v)
+		}
+	}
+	return nil
+}
+
+// flagSuffix marks pinned and favorite entries in listings.
+func flagSuffix(f sendkey.EntryFlags) string {
+	var marks []string
+	if f.Pinned {
+		marks = append(marks, "pinned")
+	}
+	if f.Favorite {
+		marks = append(marks, "favorite")
+	}
+	if len(marks) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(marks, ", ") + ")"
+}
+
+var pinEntryCommand = &cli.Command{
+	Name:  "pin_entry",
+	Usage: "Pin an entry to the top of list_entries.",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "id",
+			Usage:    "The entry ID.",
+			Required: true,
+		},
+		&cli.BoolFlag{
+			Name:  "unpin",
+			Usage: "Unpin the entry instead.",
+		},
+	},
+	Action: func(ctx *cli.Context) error {
+		return setEntryFlag(ctx, true, !ctx.Bool("unpin"))
+	},
+}
+
+var favoriteEntryCommand = &cli.Command{
+	Name:  "favorite_entry",
+	Usage: "Mark an entry as a favorite, listed after pinned entries in list_entries.",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "id",
+			Usage:    "The entry ID.",
+			Required: true,
+		},
+		&cli.BoolFlag{
+			Name:  "remove",
+			Usage: "Remove the entry from your favorites instead.",
+		},
+	},
+	Action: func(ctx *cli.Context) error {
+		return setEntryFlag(ctx, false, !ctx.Bool("remove"))
+	},
+}
+
+// setEntryFlag pins the entry if pin is set and favorites it otherwise, or
+// undoes it if on isn't set.
+func setEntryFlag(ctx *cli.Context, pin, on bool) error {
+	err := ensureClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	id, err := sendkey.ParseID(ctx.String("id"))
+	if err != nil {
+		return fmt.Errorf("invalid entry id: %w", err)
+	}
+
+	set := sendkeyClient.Entries.SetFavorite
+	if pin {
+		set = sendkeyClient.Entries.SetPinned
+	}
+	res, e, err := set(id, on)
+	if err != nil {
+		return err
+	}
+	if e != nil {
+		return apiError(e)
+	}
+	if !res.Success {
+		return errors.New(strings.Join(res.Messages(), "; "))
+	}
+
+	fmt.Printf("%s%s\n", id, flagSuffix(res.Flags))
+	return nil
+}
+
+var usageCommand = &cli.Command{
+	Name:  "usage",
+	Usage: "Shows how much you've sent against your plan's limits.",
+	Action: func(ctx *cli.Context) error {
+		err := ensureClient(ctx)
+		if err != nil {
+			return err
+		}
+
+		res, e, err := sendkeyClient.Entries.Usage()
+		if err != nil {
+			return err
+		}
+		if e != nil {
+			return apiError(e)
+		}
+
+		fmt.Printf("Entries today: %s\n", usageLimit(res.EntriesToday, res.Quota.EntriesPerDay))
+		fmt.Printf("Live entries: %s\n", usageLimit(res.LiveEntries, res.Quota.MaxLiveEntries))
+		if res.Quota.MaxValueBytes > 0 {
+			fmt.Printf("Max value size: %d bytes\n", res.Quota.MaxValueBytes)
+		}
+		return nil
+	},
+}
+
+func usageLimit(used, limit int) string {
+	if limit == 0 {
+		return fmt.Sprintf("%d (unlimited)", used)
+	}
+	return fmt.Sprintf("%d of %d", used, limit)
+}
+
+var claimEntryCommand = &cli.Command{
+	Name:    "claim_entry",
+	Aliases: []string{"cle"},
+	Usage:   "Claim an entry and print its value.",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "id",
+			Usage:    "The entry ID.",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:     "nonce",
+			Aliases:  []string{"n"},
+			Usage:    "The entry nonce.",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:     "secret",
+			Aliases:  []string{"s"},
+			Usage:    "The secret required to view the entry value.",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:      "receipt",
+			Aliases:   []string{"r"},
+			Usage:     "A path to write the signed claim receipt to.",
+			TakesFile: true,
+		},
+		&cli.StringSliceFlag{
+			Name:    "answer",
+			Aliases: []string{"a"},
+			Usage:   "An answer to the entry's challenge questions, in order. Can be repeated.",
+		},
+		&cli.StringFlag{
+			Name:  "otp",
+			Usage: "The one-time code emailed to you, for entries that require one.",
+		},
+		&cli.BoolFlag{
+			Name:  "acknowledge",
+			Usage: "Acknowledge the text the sender requires you to, for entries that have it.",
+		},
+	},
+	Action: func(ctx *cli.Context) error {
+		err := ensureClient(ctx)
+		if err != nil {
+			return err
+		}
+
+		id, err := sendkey.ParseID(ctx.String("id"))
+		if err != nil {
+			return fmt.Errorf("invalid entry id: %w", err)
+		}
+
+		nonce, secret := ctx.String("nonce"), ctx.String("secret")
+		entry, e, err := sendkeyClient.Entries.FindEntry(id, nonce)
+		if err != nil {
+			return err
+		}
+		if e != nil {
+			return apiError(e)
+		}
+		if entry == nil {
+			return fmt.Errorf("the entry doesn't exist, has expired, or has already been claimed")
+		}
+
+		// end-to-end entries are decrypted here, so the secret isn't sent
+		claimSecret := secret
+		if entry.EndToEnd {
+			claimSecret = ""
+		}
+		claim := sendkeyClient.Entries.ClaimWithOTP
+		if entry.Acknowledgment != "" {
+			if !ctx.Bool("acknowledge") {
+				return fmt.Errorf("the sender requires you to acknowledge %q; claim the entry again with --acknowledge", entry.Acknowledgment)
+			}
+			claim = sendkeyClient.Entries.ClaimAcknowledged
+		}
+		// another process sharing the session may be claiming it too
+		res, e, err := claim(ctx.Context, id, nonce, claimSecret, ctx.String("otp"), ctx.StringSlice("answer")...)
+		if errors.Is(err, client.ErrDigestMismatch) {
+			return fmt.Errorf("the entry was claimed, but %w; ask the sender to send it again", err)
+		}
+		if errors.Is(err, client.ErrClaimedByYou) {
+			return fmt.Errorf("%w, e.g. by another process using this session; the value can't be shown again", err)
+		}
+		if err != nil {
+			return err
+		}
+		if e != nil {
+			return apiError(e)
+		}
+		if res.OTPSent {
+			return fmt.Errorf("a one-time code was emailed to you; claim the entry again with --otp")
+		}
+		if !res.Success {
+			return errors.New(strings.Join(res.Messages(), "; "))
+		}
+
+		// metadata goes to stderr so the value can be piped on its own
+		if entry.Metadata.Username != "" {
+			fmt.Fprintf(os.Stderr, "Username: %s\n", entry.Metadata.Username)
+		}
+		if entry.Metadata.Hostname != "" {
+			fmt.Fprintf(os.Stderr, "Hostname: %s\n", entry.Metadata.Hostname)
+		}
+		if res.Sealed != nil {
+			value, err := client.Open(*res.Sealed, secret)
+			if err != nil {
+				return fmt.Errorf("decrypting value: %w", err)
+			}
+			if res.Digest != nil {
+				if err = client.VerifyDigest(*res.Digest, value, secret); err != nil {
+					return fmt.Errorf("the entry was claimed, but %w; ask the sender to send it again", err)
+				}
+			}
+			fmt.Println(value)
+		} else {
+			fmt.Println(*res.Value)
+		}
+
+		receiptPath := ctx.String("receipt")
+		if receiptPath == "" || res.Receipt == nil {
+			return nil
+		}
+
+		b, err := json.MarshalIndent(res.Receipt, "", "    ")
+		if err != nil {
+			return err
+		}
+		if err = os.WriteFile(receiptPath, b, 0600); err != nil {
+			return fmt.Errorf("writing receipt: %w", err)
+		}
+
+		return nil
+	},
+}
+
+var deferEntryCommand = &cli.Command{
+	Name:    "defer_entry",
+	Aliases: []string{"de"},
+	Usage:   "Put off claiming an entry and get an email reminder before it expires.",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "id",
+			Usage:    "The entry ID.",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:     "nonce",
+			Aliases:  []string{"n"},
+			Usage:    "The entry nonce.",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:    "delay",
+			Aliases: []string{"d"},
+			Usage:   "How long until the reminder, with units like \"4h\". Defaults to a day.",
+		},
+	},
+	Action: func(ctx *cli.Context) error {
+		err := ensureClient(ctx)
+		if err != nil {
+			return err
+		}
+
+		id, err := sendkey.ParseID(ctx.String("id"))
+		if err != nil {
+			return fmt.Errorf("invalid entry id: %w", err)
+		}
+
+		var delay time.Duration
+		if s := ctx.String("delay"); s != "" {
+			if delay, err = parseDuration(s); err != nil {
+				return err
+			}
+		}
+
+		res, e, err := sendkeyClient.Entries.DeferClaim(id, api.DeferClaimRequest{
+			Nonce:        ctx.String("nonce"),
+			DelaySeconds: int(delay / time.Second),
+		})
+		if err != nil {
+			return err
+		}
+		if e != nil {
+			return apiError(e)
+		}
+		if !res.Success {
+			return errors.New(strings.Join(res.Messages(), "; "))
+		}
+
+		fmt.Printf("You'll be reminded at %s.\n", res.Deferral.RemindAtUTC.Local().Format(time.RFC1123))
+		return nil
+	},
+}
+
+var resendLinkCommand = &cli.Command{
+	Name:  "resend_link",
+	Usage: "Get a new claim link for one of your entries. The entry's earlier links stop working.",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "id",
+			Usage:    "The entry ID.",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:  "ttl",
+			Usage: "How long the link lasts, with units like \"24h\". Defaults to the server's link expiration.",
+		},
+	},
+	Action: func(ctx *cli.Context) error {
+		err := ensureClient(ctx)
+		if err != nil {
+			return err
+		}
+
+		id, err := sendkey.ParseID(ctx.String("id"))
+		if err != nil {
+			return fmt.Errorf("invalid entry id: %w", err)
+		}
+
+		var ttl time.Duration
+		if s := ctx.String("ttl"); s != "" {
+			if ttl, err = parseDuration(s); err != nil {
+				return err
+			}
+		}
+
+		res, e, err := sendkeyClient.Entries.ResendLink(id, ttl)
+		if err != nil {
+			return err
+		}
+		if e != nil {
+			return apiError(e)
+		}
+		if !res.Success {
+			return errors.New(strings.Join(res.Messages(), "; "))
+		}
+
+		// the expiry goes to stderr so the link can be piped on its own
+		fmt.Fprintf(os.Stderr, "Expires at %s.\n", res.Link.ExpiresAtUTC.Local().Format(time.RFC1123))
+		fmt.Println(res.ClaimURL)
+		return nil
+	},
+}
+
+var delegateClaimCommand = &cli.Command{
+	Name:  "delegate_claim",
+	Usage: "Make a short-lived token a bot or service can claim one of your entries with, without the secret.",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "id",
+			Usage:    "The entry ID.",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:     "secret",
+			Aliases:  []string{"s"},
+			Usage:    "The entry secret.",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:  "ttl",
+			Usage: "How long the token lasts, with units like \"5m\". Defaults to 15 minutes; at most an hour.",
+		},
+	},
+	Action: func(ctx *cli.Context) error {
+		err := ensureClient(ctx)
+		if err != nil {
+			return err
+		}
+
+		id, err := sendkey.ParseID(ctx.String("id"))
+		if err != nil {
+			return fmt.Errorf("invalid entry id: %w", err)
+		}
+
+		var ttl time.Duration
+		if s := ctx.String("ttl"); s != "" {
+			if ttl, err = parseDuration(s); err != nil {
+				return err
+			}
+		}
+
+		res, e, err := sendkeyClient.Entries.DelegateClaim(id, ctx.String("secret"), ttl)
+		if err != nil {
+			return err
+		}
+		if e != nil {
+			return apiError(e)
+		}
+		if !res.Success {
+			return errors.New(strings.Join(res.Messages(), "; "))
+		}
+
+		// the expiry goes to stderr so the token can be piped on its own
+		fmt.Fprintf(os.Stderr, "Expires at %s.\n", res.ExpiresAtUTC.Local().Format(time.RFC1123))
+		fmt.Println(res.Token)
+		return nil
+	},
+}
+
+var claimDelegatedCommand = &cli.Command{
+	Name:  "claim_delegated",
+	Usage: "Claim an entry with a delegated claim token.",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "id",
+			Usage:    "The entry ID.",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:     "token",
+			Aliases:  []string{"t"},
+			Usage:    "The delegated claim token.",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:      "receipt",
+			Aliases:   []string{"r"},
+			Usage:     "A path to write the signed claim receipt to.",
+			TakesFile: true,
+		},
+	},
+	Action: func(ctx *cli.Context) error {
+		err := ensureClient(ctx)
+		if err != nil {
+			return err
+		}
+
+		id, err := sendkey.ParseID(ctx.String("id"))
+		if err != nil {
+			return fmt.Errorf("invalid entry id: %w", err)
+		}
+
+		res, e, err := sendkeyClient.Entries.ClaimDelegated(id, ctx.String("token"))
+		if err != nil {
+			return err
+		}
+		if e != nil {
+			return apiError(e)
+		}
+		if !res.Success {
+			return errors.New(strings.Join(res.Messages(), "; "))
+		}
+		fmt.Println(*res.Value)
+
+		receiptPath := ctx.String("receipt")
+		if receiptPath == "" || res.Receipt == nil {
+			return nil
+		}
+
+		b, err := json.MarshalIndent(res.Receipt, "", "    ")
+		if err != nil {
+			return err
+		}
+		if err = os.WriteFile(receiptPath, b, 0600); err != nil {
+			return fmt.Errorf("writing receipt: %w", err)
+		}
+
+		return nil
+	},
+}
+
+var openEscrowCommand = &cli.Command{
+	Name:  "open_escrow",
+	Usage: "Release an entry's escrow as an admin, or decrypt a released escrow with the org's escrow private key.",
+	Description: "Escrow is break-glass: an admin releases the escrow with a reason, which is audited, and\n" +
+		"whoever holds the escrow private key decrypts it offline. Without --private-key, the released\n" +
+		"escrow is printed as JSON to hand over; with --escrow, a released escrow is decrypted without\n" +
+		"calling the server.",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "id",
+			Usage: "The ID of the entry whose escrow to release.",
+		},
+		&cli.StringFlag{
+			Name:  "reason",
+			Usage: "Why the escrow's being released, like a ticket number. It's recorded in the audit log.",
+		},
+		&cli.StringFlag{
+			Name:  "escrow",
+			Usage: "A released escrow's JSON, @file to read it from a file, or - to read it from stdin.",
+		},
+		&cli.StringFlag{
+			Name:      "private-key",
+			Usage:     "A path to the org's PEM-encoded RSA escrow private key.",
+			TakesFile: true,
+		},
+	},
+	Action: func(ctx *cli.Context) error {
+		var escrow *sendkey.EntryEscrow
+		if ctx.IsSet("escrow") {
+			b, err := readData(ctx.String("escrow"))
+			if err != nil {
+				return err
+			}
+			var res api.ReleaseEscrowResponse
+			if err = json.Unmarshal(b, &res); err != nil {
+				return fmt.Errorf("invalid escrow: %w", err)
+			}
+			escrow = res.Escrow
+			if escrow == nil {
+				escrow = &sendkey.EntryEscrow{}
+				if err = json.Unmarshal(b, escrow); err != nil {
+					return fmt.Errorf("invalid escrow: %w", err)
+				}
+			}
+		} else {
+			id, err := sendkey.ParseID(ctx.String("id"))
+			if err != nil {
+				return fmt.Errorf("invalid entry id: %w", err)
+			}
+			if err = ensureClient(ctx); err != nil {
+				return err
+			}
+			res, e, err := sendkeyClient.Entries.ReleaseEscrow(id, ctx.String("reason"))
+			if err != nil {
+				return err
+			}
+			if e != nil {
+				return apiError(e)
+			}
+			if !res.Success {
+				return errors.New(strings.Join(res.Messages(), "; "))
+			}
+			escrow = res.Escrow
+		}
+
+		if !ctx.IsSet("private-key") {
+			b, err := json.MarshalIndent(escrow, "", "    ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(b))
+			return nil
+		}
+		b, err := os.ReadFile(ctx.String("private-key"))
+		if err != nil {
+			return err
+		}
+		key, err := client.ParseEscrowPrivateKey(b)
+		if err != nil {
+			return err
+		}
+		value, err := client.OpenEscrow(*escrow, key)
+		if err != nil {
+			return err
+		}
+		fmt.Println(value)
+		return nil
+	},
+}
+
+// parseDuration parses a duration with units, or a bare number of minutes as
+// the --duration flag used to require.
+func parseDuration(s string) (time.Duration, error) {
+	if minutes, err := strconv.Atoi(s); err == nil {
+		return time.Duration(minutes) * time.Minute, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q; use units like \"45m\" or \"2h\"", s)
+	}
+	return d, nil
+}
+
+// entryType returns the entry's type, treating entries from before types
+// were added as notes.
+func entryType(e sendkey.Entry) string {
+	if e.Type == "" {
+		return sendkey.EntryTypeNote
+	}
+	return e.Type
+}
+
+func printMetadata(m sendkey.EntryMetadata) {
+	if m.Username != "" {
+		fmt.Printf("\tUsername: %s\n", m.Username)
+	}
+	if m.Hostname != "" {
+		fmt.Printf("\tHostname: %s\n", m.Hostname)
+	}
+}
@@ -6,7 +6,10 @@ import (
 	"log"
 	"os"
 	"path"
+	"strconv"
+	"time"
 
+	"github.com/gavinwade12/sendkey/pkg/api"
 	"github.com/gavinwade12/sendkey/pkg/client"
 	"github.com/google/uuid"
 	"github.com/urfave/cli/v2"
@@ -18,6 +21,9 @@ var sendkeyClient *client.Client
 
 type config struct {
 	BaseURL string
+	TLS     client.TLSOptions
+	// Proxy is the proxy URL to use instead of the environment's.
+	Proxy string
 }
 
 var defaultConfig = config{
@@ -30,7 +36,7 @@ func main() {
 		Version:     version,
 		Description: "A CLI tool for interfacing with the sendkey REST API.",
 		Usage:       "Inteface with the sendkey API from the commandline.",
-		Flags: []cli.Flag{
+		Flags: append([]cli.Flag{
 			&cli.StringFlag{
 				Name:      "config",
 				Aliases:   []string{"c"},
@@ -38,10 +44,27 @@ func main() {
 				TakesFile: true,
 				EnvVars:   []string{"SENDKEY_CLI_CONFIG", "SENDKEY_CONFIG"},
 			},
-		},
+			&cli.StringFlag{
+				Name:    "proxy",
+				Usage:   "An http, https, or socks5 proxy URL to use instead of HTTPS_PROXY or ALL_PROXY.",
+				EnvVars: []string{"SENDKEY_PROXY"},
+			},
+			&cli.BoolFlag{
+				Name:    "sandbox",
+				Usage:   "Use the server's sandbox, where entries expire faster and nothing is emailed.",
+				EnvVars: []string{"SENDKEY_SANDBOX"},
+			},
+			&cli.BoolFlag{
+				Name:    "strict-json",
+				Usage:   "Have the server reject request bodies with unknown fields, e.g. with api call.",
+				EnvVars: []string{"SENDKEY_STRICT_JSON"},
+			},
+		}, tlsFlags...),
 	}
 	mountUserCommands(cliApp)
 	mountEntryCommands(cliApp)
+	mountAPICommands(cliApp)
+	mountPluginCommands(cliApp)
 
 	cliApp.Setup()
 	if err := cliApp.Run(os.Args); err != nil {
@@ -49,22 +72,48 @@ func main() {
 	}
 }
 
-func ensureClient(configFile string) error {
+func ensureClient(ctx *cli.Context) error {
 	if sendkeyClient != nil {
 		return nil
 	}
 
-	var (
-		cfg *config
-		err error
-	)
-	if configFile != "" {
-		cfg, err = readConfig(configFile)
+	cfg, err := loadConfig(ctx.String("config"))
+	if err != nil {
+		return err
+	}
+
+	tlsOpts := tlsOptions(ctx, cfg)
+	if tlsOpts.InsecureSkipVerify {
+		warnInsecure()
+	}
+	tlsConfig, err := client.NewTLSConfig(tlsOpts)
+	if err != nil {
+		return err
+	}
+	opts := []client.Option{
+		client.WithDefaultHeaders(map[string][]string{
+			"User-Agent": {"sendkey-cli@" + version},
+		}),
+		client.WithTLSConfig(tlsConfig),
+		client.WithWarningHandler(printWarning),
+	}
+	proxy := cfg.Proxy
+	if ctx.IsSet("proxy") {
+		proxy = ctx.String("proxy")
+	}
+	if proxy != "" {
+		proxyURL, err := client.ParseProxy(proxy)
 		if err != nil {
 			return err
 		}
-	} else {
-		cfg = &defaultConfig
+		opts = append(opts, client.WithProxy(proxyURL))
+	}
+
+	if ctx.Bool("sandbox") {
+		opts = append(opts, client.WithSandbox())
+	}
+	if ctx.Bool("strict-json") {
+		opts = append(opts, client.WithStrictJSON())
 	}
 
 	session, err := loadSession()
@@ -72,17 +121,45 @@ func ensureClient(configFile string) error {
 		return err
 	}
 
-	sendkeyClient = client.NewClient(cfg.BaseURL,
-		client.WithDefaultHeaders(map[string][]string{
-			"User-Agent": {"sendkey-cli@" + version},
-		}),
+	sendkeyClient = client.NewClient(cfg.BaseURL, append(opts,
 		client.WithSession(session.UserID, session.RefreshToken.Token,
 			session.AccessToken.Token),
-	)
+	)...)
 
 	return nil
 }
 
+// printedWarnings keeps a warning repeated by several requests in one command
+// from being printed more than once.
+var printedWarnings = map[string]bool{}
+
+// printWarning prints a warning from the server, like a deprecated route the
+// CLI still calls, to stderr so output can still be piped.
+func printWarning(w api.Warning) {
+	if printedWarnings[w.Message] {
+		return
+	}
+	printedWarnings[w.Message] = true
+
+	msg := "WARNING: " + w.Message
+	if w.Sunset != nil {
+		msg += fmt.Sprintf(" It stops working on %s.", w.Sunset.Local().Format(time.RFC1123))
+	}
+	if w.Successor != "" {
+		msg += fmt.Sprintf(" Use %s instead; a newer CLI may already.", w.Successor)
+	}
+	fmt.Fprintln(os.Stderr, msg)
+}
+
+// loadConfig reads the config file, or returns the default config if there
+// isn't one.
+func loadConfig(configFile string) (*config, error) {
+	if configFile == "" {
+		return &defaultConfig, nil
+	}
+	return readConfig(configFile)
+}
+
 func readConfig(path string) (*config, error) {
 	f, err := os.Open(path)
 	if err != nil {
@@ -153,3 +230,16 @@ type Session struct {
 	AccessToken  Token
 	RefreshToken Token
 }
+
+// apiError formats an error returned by the API with its request ID, so it
+// can be quoted in a support request.
+func apiError(e *api.Error) error {
+	status := strconv.Itoa(e.StatusCode)
+	if e.Code != "" {
+		status += " " + e.Code
+	}
+	if e.RequestID == "" {
+		return fmt.Errorf("[%s]: %s", status, e.Message)
+	}
+	return fmt.Errorf("[%s]: %s (request ID: %s)", status, e.Message, e.RequestID)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+// pluginPrefix is the prefix of the executables on PATH that extend the CLI.
+// An executable named sendkey-jira is run for `sendkey jira`.
+const pluginPrefix = "sendkey-"
+
+// mountPluginCommands adds a command for each plugin on PATH. Built-in
+// commands can't be overridden, and the first plugin found for a name wins,
+// like the shell's lookup.
+func mountPluginCommands(cliApp *cli.App) {
+	taken := map[string]bool{}
+	for _, c := range cliApp.Commands {
+		for _, name := range c.Names() {
+			taken[name] = true
+		}
+	}
+
+	plugins := findPlugins()
+	names := make([]string, 0, len(plugins))
+	for name := range plugins {
+		if !taken[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		cliApp.Commands = append(cliApp.Commands, pluginCommand(name, plugins[name]))
+	}
+}
+
+// findPlugins returns the plugins' paths by their command names.
+func findPlugins() map[string]string {
+	plugins := map[string]string{}
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		if dir == "" {
+			continue
+		}
+		files, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+
+		for _, f := range files {
+			name := f.Name()
+			if !strings.HasPrefix(name, pluginPrefix) || f.IsDir() {
+				continue
+			}
+			if runtime.GOOS == "windows" {
+				if !strings.EqualFold(filepath.Ext(name), ".exe") {
+					continue
+				}
+				name = strings.TrimSuffix(name, filepath.Ext(name))
+			} else if info, err := f.Info(); err != nil || info.Mode()&0111 == 0 {
+				continue
+			}
+
+			name = strings.TrimPrefix(name, pluginPrefix)
+			if _, ok := plugins[name]; name != "" && !ok {
+				plugins[name] = filepath.Join(dir, f.Name())
+			}
+		}
+	}
+
+	return plugins
+}
+
+// pluginCommand runs the plugin with the command's arguments. The plugin gets
+// the CLI's session and config through the environment:
+//
+//	SENDKEY_BASE_URL       the API's base URL
+//	SENDKEY_CONFIG         the config file, if one was given
+//	SENDKEY_USER_ID        the logged in user's ID
+//	SENDKEY_ACCESS_TOKEN   the session's access token
+//	SENDKEY_REFRESH_TOKEN  the session's refresh token
+//	SENDKEY_CLI_VERSION    the CLI's version
+//
+// The CLI exits with the plugin's exit code.
+func pluginCommand(name, path string) *cli.Command {
+	return &cli.Command{
+		Name:            name,
+		Usage:           "Run the " + filepath.Base(path) + " plugin.",
+		Category:        "plugins",
+		SkipFlagParsing: true,
+		Action: func(ctx *cli.Context) error {
+			configFile := ctx.String("config")
+			cfg, err := loadConfig(configFile)
+			if err != nil {
+				return err
+			}
+			session, err := loadSession()
+			if err != nil {
+				return err
+			}
+
+			cmd := exec.Command(path, ctx.Args().Slice()...)
+			cmd.Stdin = os.Stdin
+			cmd.Stdout = os.Stdout
+			cmd.Stderr = os.Stderr
+			cmd.Env = append(os.Environ(),
+				"SENDKEY_BASE_URL="+cfg.BaseURL,
+				"SENDKEY_CONFIG="+configFile,
+				"SENDKEY_USER_ID="+session.UserID.String(),
+				"SENDKEY_ACCESS_TOKEN="+session.AccessToken.Token,
+				"SENDKEY_REFRESH_TOKEN="+session.RefreshToken.Token,
+				"SENDKEY_CLI_VERSION="+version,
+			)
+
+			err = cmd.Run()
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				return cli.Exit("", exitErr.ExitCode())
+			}
+			return err
+		},
+	}
+}
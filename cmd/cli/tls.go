@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/gavinwade12/sendkey/pkg/client"
+	"github.com/urfave/cli/v2"
+)
+
+// tlsFlags override the config's TLS options, for self-hosted servers using
+// private CAs or mutual TLS.
+var tlsFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:      "caCert",
+		Usage:     "A PEM bundle of CA certificates to trust in addition to the system's.",
+		TakesFile: true,
+		EnvVars:   []string{"SENDKEY_CA_CERT"},
+	},
+	&cli.StringFlag{
+		Name:      "clientCert",
+		Usage:     "A PEM client certificate for servers that require mutual TLS.",
+		TakesFile: true,
+		EnvVars:   []string{"SENDKEY_CLIENT_CERT"},
+	},
+	&cli.StringFlag{
+		Name:      "clientKey",
+		Usage:     "The PEM key for the client certificate.",
+		TakesFile: true,
+		EnvVars:   []string{"SENDKEY_CLIENT_KEY"},
+	},
+	&cli.StringFlag{
+		Name:    "tlsMinVersion",
+		Usage:   "The lowest TLS version allowed: 1.0, 1.1, 1.2, or 1.3. Defaults to 1.2.",
+		EnvVars: []string{"SENDKEY_TLS_MIN_VERSION"},
+	},
+	&cli.BoolFlag{
+		Name:    "insecureSkipVerify",
+		Usage:   "Don't verify the server's certificate. Anyone on the network can read your secrets. Only use this for testing.",
+		EnvVars: []string{"SENDKEY_INSECURE_SKIP_VERIFY"},
+	},
+}
+
+// tlsOptions returns the config's TLS options with the flags applied.
+func tlsOptions(ctx *cli.Context, cfg *config) client.TLSOptions {
+	o := cfg.TLS
+	if ctx.IsSet("caCert") {
+		o.CAFile = ctx.String("caCert")
+	}
+	if ctx.IsSet("clientCert") {
+		o.CertFile = ctx.String("clientCert")
+	}
+	if ctx.IsSet("clientKey") {
+		o.KeyFile = ctx.String("clientKey")
+	}
+	if ctx.IsSet("tlsMinVersion") {
+		o.MinVersion = ctx.String("tlsMinVersion")
+	}
+	if ctx.IsSet("insecureSkipVerify") {
+		o.InsecureSkipVerify = ctx.Bool("insecureSkipVerify")
+	}
+	return o
+}
+
+// warnInsecure tells the user, every time, that the server isn't verified.
+func warnInsecure() {
+	fmt.Fprintln(os.Stderr, "WARNING: TLS certificate verification is disabled. Anyone on the network can read and change")
+	fmt.Fprintln(os.Stderr, "WARNING: your requests, including secrets. Use --caCert to trust the server's CA instead.")
+}
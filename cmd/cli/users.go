@@ -1,11 +1,12 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
 
-	"github.com/gavinwade12/sendkey/pkg/client"
+	"github.com/gavinwade12/sendkey/pkg/api"
 	"github.com/urfave/cli/v2"
 )
 
@@ -13,6 +14,7 @@ func mountUserCommands(cliApp *cli.App) {
 	cliApp.Commands = append(cliApp.Commands,
 		createUserCommand,
 		loginCommand,
+		loginMagicCommand,
 	)
 }
 
@@ -45,12 +47,12 @@ var createUserCommand = &cli.Command{
 		},
 	},
 	Action: func(ctx *cli.Context) error {
-		err := ensureClient(ctx.String("config"))
+		err := ensureClient(ctx)
 		if err != nil {
 			return err
 		}
 
-		req := client.CreateUserRequest{
+		req := api.CreateUserRequest{
 			FirstName: ctx.String("firstName"),
 			LastName:  ctx.String("lastName"),
 			Email:     ctx.String("email"),
@@ -62,10 +64,10 @@ var createUserCommand = &cli.Command{
 			return err
 		}
 		if e != nil {
-			return fmt.Errorf("[%d]: %s", e.StatusCode, e.Message)
+			return apiError(e)
 		}
 		if !res.Success {
-			return fmt.Errorf(strings.Join(res.Errors, "; "))
+			return errors.New(strings.Join(res.Messages(), "; "))
 		}
 
 		fmt.Println("Successfully created user:")
@@ -96,38 +98,114 @@ var loginCommand = &cli.Command{
 			Usage:    "The user's password.",
 			Required: true,
 		},
+		&cli.StringFlag{
+			Name:    "mfaCode",
+			Aliases: []string{"m"},
+			Usage:   "The user's MFA or recovery code, if MFA is enabled.",
+		},
 	},
 	Action: func(ctx *cli.Context) error {
-		err := ensureClient(ctx.String("config"))
+		err := ensureClient(ctx)
 		if err != nil {
 			return err
 		}
 
-		res, e, err := sendkeyClient.Users.Login(ctx.String("email"), ctx.String("password"))
+		res, e, err := sendkeyClient.Users.LoginWithMFA(ctx.String("email"), ctx.String("password"), ctx.String("mfaCode"))
 		if err != nil {
 			return err
 		}
 		if e != nil {
-			return fmt.Errorf("[%d]: %s", e.StatusCode, e.Message)
+			return apiError(e)
 		}
 		if !res.Success {
-			return fmt.Errorf(strings.Join(res.Errors, "; "))
+			return errors.New(strings.Join(res.Messages(), "; "))
 		}
 
-		session, err := loadSession()
+		return saveLoginSession(res)
+	},
+}
+
+var loginMagicCommand = &cli.Command{
+	Name:  "login_magic",
+	Usage: "Login with an emailed link instead of a password. Request a link with --email, then paste its code with --code.",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:    "email",
+			Aliases: []string{"e"},
+			Usage:   "The user's email. A login link will be sent to it.",
+		},
+		&cli.StringFlag{
+			Name:  "code",
+			Usage: "The code from the login link.",
+		},
+		&cli.StringFlag{
+			Name:    "mfaCode",
+			Aliases: []string{"m"},
+			Usage:   "The user's MFA or recovery code, if MFA is enabled.",
+		},
+		&cli.StringFlag{
+			Name:  "newPassword",
+			Usage: "A new password for the user, required if an admin reset theirs.",
+		},
+	},
+	Action: func(ctx *cli.Context) error {
+		err := ensureClient(ctx)
 		if err != nil {
 			return err
 		}
 
-		session.UserID = res.User.ID
-		session.AccessToken = Token{
-			Token:   res.AccessToken.Token,
-			Expires: res.AccessToken.Expires,
+		if code := ctx.String("code"); code != "" {
+			// accept the whole link as well as just the code
+			code = code[strings.LastIndex(code, "/")+1:]
+
+			res, e, err := sendkeyClient.Users.RedeemMagicLink(code, ctx.String("mfaCode"), ctx.String("newPassword"))
+			if err != nil {
+				return err
+			}
+			if e != nil {
+				return apiError(e)
+			}
+			if !res.Success {
+				return errors.New(strings.Join(res.Messages(), "; "))
+			}
+
+			return saveLoginSession(res)
+		}
+
+		if ctx.String("email") == "" {
+			return fmt.Errorf("either --email or --code is required")
+		}
+
+		res, e, err := sendkeyClient.Users.RequestMagicLink(ctx.String("email"))
+		if err != nil {
+			return err
 		}
-		session.RefreshToken = Token{
-			Token:   res.RefreshToken.Token,
-			Expires: res.RefreshToken.Expires,
+		if e != nil {
+			return apiError(e)
+		}
+		if !res.Success {
+			return errors.New(strings.Join(res.Messages(), "; "))
 		}
-		return saveSession(*session)
+
+		fmt.Println("If an account exists for that email, a login link has been sent to it.")
+		return nil
 	},
 }
+
+func saveLoginSession(res *api.LoginResponse) error {
+	session, err := loadSession()
+	if err != nil {
+		return err
+	}
+
+	session.UserID = res.User.ID
+	session.AccessToken = Token{
+		Token:   res.AccessToken.Token,
+		Expires: res.AccessToken.Expires,
+	}
+	session.RefreshToken = Token{
+		Token:   res.RefreshToken.Token,
+		Expires: res.RefreshToken.Expires,
+	}
+	return saveSession(*session)
+}
@@ -0,0 +1,119 @@
+package sendkey
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// IDGenerator generates the IDs of new records. Every generator's IDs are
+// UUIDs, stored as their 16 bytes, so a deployment can switch generators
+// without migrating its records: the IDs it already has stay valid alongside
+// the new ones.
+type IDGenerator interface {
+	NewID() uuid.UUID
+}
+
+var (
+	// RandomIDs generates random (version 4) UUIDs. It's the default.
+	RandomIDs IDGenerator = randomIDs{}
+	// TimeOrderedIDs generates version 7 UUIDs, which start with the time
+	// they were generated in milliseconds. New rows are inserted at the end
+	// of the primary key's index rather than at random places in it, which
+	// keeps MySQL's inserts fast as tables grow.
+	TimeOrderedIDs IDGenerator = timeOrderedIDs{}
+	// ShortIDs generates UUIDs with 62 random bits, which FormatID writes
+	// with 11 characters rather than 36, for IDs people copy by hand, like
+	// entries'. They're version 8 UUIDs, with the bits before the random
+	// ones zero.
+	ShortIDs IDGenerator = shortIDs{}
+)
+
+type randomIDs struct{}
+
+func (randomIDs) NewID() uuid.UUID {
+	return uuid.New()
+}
+
+type timeOrderedIDs struct{}
+
+func (timeOrderedIDs) NewID() uuid.UUID {
+	var id uuid.UUID
+	if _, err := rand.Read(id[6:]); err != nil {
+		panic(err)
+	}
+	ms := uint64(time.Now().UnixMilli())
+	for i := 0; i < 6; i++ {
+		id[i] = byte(ms >> (40 - 8*i))
+	}
+	id[6] = 0x70 | id[6]&0x0f
+	id[8] = 0x80 | id[8]&0x3f
+	return id
+}
+
+type shortIDs struct{}
+
+func (shortIDs) NewID() uuid.UUID {
+	var id uuid.UUID
+	if _, err := rand.Read(id[8:]); err != nil {
+		panic(err)
+	}
+	id[6] = 0x80
+	id[8] = 0x80 | id[8]&0x3f
+	return id
+}
+
+const (
+	base62      = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	shortIDSize = 11
+)
+
+// IsShortID reports whether the ID is one ShortIDs generated.
+func IsShortID(id uuid.UUID) bool {
+	for _, b := range id[:6] {
+		if b != 0 {
+			return false
+		}
+	}
+	return id[6] == 0x80 && id[7] == 0 && id[8]&0xc0 == 0x80
+}
+
+// FormatID returns the ID's short form if it's a short ID, and its usual form
+// otherwise.
+func FormatID(id uuid.UUID) string {
+	if !IsShortID(id) {
+		return id.String()
+	}
+	x := binary.BigEndian.Uint64(id[8:]) &^ (3 << 62)
+	b := []byte(strings.Repeat("0", shortIDSize))
+	for i := len(b) - 1; x > 0; i-- {
+		b[i] = base62[x%62]
+		x /= 62
+	}
+	return string(b)
+}
+
+// ParseID parses an ID in either of the forms FormatID writes. Short IDs can
+// also be written in the usual form.
+func ParseID(s string) (uuid.UUID, error) {
+	if len(s) != shortIDSize {
+		return uuid.Parse(s)
+	}
+
+	var x uint64
+	for i := 0; i < len(s); i++ {
+		d := strings.IndexByte(base62, s[i])
+		if d < 0 || x > (1<<62-1-uint64(d))/62 {
+			return uuid.Nil, errors.New("invalid short ID")
+		}
+		x = x*62 + uint64(d)
+	}
+	var id uuid.UUID
+	id[6] = 0x80
+	binary.BigEndian.PutUint64(id[8:], x|1<<63)
+	return id, nil
+}
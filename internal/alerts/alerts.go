@@ -0,0 +1,93 @@
+// Package alerts tells operators about conditions that need their attention
+// before they turn into outages, like the database running out of disk.
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// Alert is a condition that's started or, if Resolved is set, cleared.
+type Alert struct {
+	// Name identifies the condition, like "storage.table".
+	Name    string            `json:"name"`
+	Message string            `json:"message"`
+	AtUTC   time.Time         `json:"atUtc"`
+	Fields  map[string]string `json:"fields,omitempty"`
+	// Resolved is set when the condition has cleared.
+	Resolved bool `json:"resolved"`
+}
+
+// Notifier tells operators about alerts.
+type Notifier interface {
+	Notify(Alert) error
+}
+
+// Log logs alerts: ones that started as errors, and resolved ones as info.
+type Log struct {
+	Logger *slog.Logger
+}
+
+func (l Log) Notify(a Alert) error {
+	level := slog.LevelError
+	if a.Resolved {
+		level = slog.LevelInfo
+	}
+	args := []interface{}{"name", a.Name}
+	for k, v := range a.Fields {
+		args = append(args, k, v)
+	}
+	l.Logger.Log(context.Background(), level, "alert: "+a.Message, args...)
+	return nil
+}
+
+// Webhook posts alerts as JSON to a URL, like a chat or paging service's
+// incoming webhook.
+type Webhook struct {
+	url string
+}
+
+// NewWebhook returns a notifier posting alerts to the URL.
+func NewWebhook(url string) *Webhook {
+	return &Webhook{url: url}
+}
+
+func (w *Webhook) Notify(a Alert) error {
+	body, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+
+	res, err := httpClient.Post(w.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("alert webhook: %w", err)
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, res.Body)
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("alert webhook: status %d", res.StatusCode)
+	}
+	return nil
+}
+
+// Notifiers notifies each of its notifiers, returning the first error.
+type Notifiers []Notifier
+
+func (ns Notifiers) Notify(a Alert) error {
+	var first error
+	for _, n := range ns {
+		if err := n.Notify(a); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
@@ -0,0 +1,48 @@
+package app
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/gavinwade12/sendkey"
+	"github.com/google/uuid"
+)
+
+type AnonymousSenderRepository interface {
+	// Record counts an entry created at the time by the sender with the
+	// address hash, creating the sender if it's the first, and returns it.
+	Record(addressHash string, at time.Time) (*sendkey.AnonymousSender, error)
+	Find(uuid.UUID) (*sendkey.AnonymousSender, error)
+}
+
+// recordAnonymousSender attributes a guest entry created from the address to
+// its anonymous sender, returning the sender's ID. It returns nil if guests'
+// senders aren't kept or the address is unknown.
+func (s *EntryService) recordAnonymousSender(address string, now time.Time) (*uuid.UUID, error) {
+	if s.anonymousSenders == nil || address == "" {
+		return nil, nil
+	}
+
+	sender, err := s.anonymousSenders.Record(s.addressHash(address), now)
+	if err != nil {
+		return nil, err
+	}
+	return &sender.ID, nil
+}
+
+// FindAnonymousSender returns the anonymous sender with the ID, or nil if
+// there isn't one.
+func (s *EntryService) FindAnonymousSender(id uuid.UUID) (*sendkey.AnonymousSender, error) {
+	if s.anonymousSenders == nil {
+		return nil, nil
+	}
+	return s.anonymousSenders.Find(id)
+}
+
+func (s *EntryService) addressHash(address string) string {
+	mac := hmac.New(sha256.New, s.addressKey)
+	mac.Write([]byte("anonymous-sender|" + address))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,275 @@
+package app
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gavinwade12/sendkey"
+	"github.com/gavinwade12/sendkey/internal/redact"
+)
+
+// AuditRepository keeps the audit log's chain head and anchors. The events
+// themselves are written to an AuditSink.
+type AuditRepository interface {
+	// Advance builds the next event from the head, writes it with write,
+	// and moves the head to it if the write succeeds. Advances must be
+	// serialized so each event chains to the one written before it.
+	Advance(build func(head sendkey.AuditHead) sendkey.AuditEvent, write func(sendkey.AuditEvent) error) error
+	Head() (sendkey.AuditHead, error)
+
+	CreateAnchor(sendkey.AuditAnchor) error
+	LastAnchor() (*sendkey.AuditAnchor, error)
+	// Anchors returns up to limit anchors after afterSeq, ordered by Seq.
+	Anchors(afterSeq int64, limit int) ([]sendkey.AuditAnchor, error)
+}
+
+// AuditSink stores audit events, e.g. in the database or in an external log
+// pipeline, so high-volume deployments can keep them out of the primary
+// database. Write is called once per event, in order. If the head can't be
+// moved after a write, the next event is written with the same Seq, and
+// sources should return the last event written for each Seq.
+type AuditSink interface {
+	Write(sendkey.AuditEvent) error
+}
+
+// AuditSource reads back the events written to a sink so they can be
+// verified.
+type AuditSource interface {
+	// Events returns up to limit events after afterSeq, ordered by Seq.
+	Events(afterSeq int64, limit int) ([]sendkey.AuditEvent, error)
+}
+
+// genesisHash is the previous hash of the first event.
+var genesisHash = strings.Repeat("0", 64)
+
+// AuditLog is a tamper-evident log of security events. Each event's hash
+// chains to the previous event, and the latest hash is periodically anchored
+// with a signature from the server key. Rewriting history breaks the chain,
+// and recomputing the chain to hide it breaks the anchors' signatures.
+type AuditLog struct {
+	heads AuditRepository
+	sink  AuditSink
+	key   []byte
+}
+
+// The key argument signs the anchors. Changing it makes earlier anchors fail
+// verification.
+func NewAuditLog(ar AuditRepository, sink AuditSink, key []byte) *AuditLog {
+	return &AuditLog{ar, sink, key}
+}
+
+// Record appends an event with the fields as its data. Sensitive fields are
+// masked; the log is kept for longer than any entry.
+func (l *AuditLog) Record(eventType string, fields map[string]string) error {
+	data, err := json.Marshal(redact.Fields(fields))
+	if err != nil {
+		return err
+	}
+
+	// the stored time only has seconds, and the hash has to match it
+	at := time.Now().UTC().Truncate(time.Second)
+	return l.heads.Advance(func(head sendkey.AuditHead) sendkey.AuditEvent {
+		e := sendkey.AuditEvent{
+			Seq:      head.Seq + 1,
+			Type:     eventType,
+			Data:     string(data),
+			AtUTC:    at,
+			PrevHash: head.Hash,
+		}
+		e.Hash = auditHash(e)
+		return e
+	}, l.sink.Write)
+}
+
+// Anchor signs the latest event's hash if it's newer than the last anchor.
+// It returns nil if there's nothing new to anchor.
+func (l *AuditLog) Anchor() (*sendkey.AuditAnchor, error) {
+	head, err := l.heads.Head()
+	if err != nil || head.Seq == 0 {
+		return nil, err
+	}
+	anchor, err := l.heads.LastAnchor()
+	if err != nil {
+		return nil, err
+	}
+	if anchor != nil && anchor.Seq >= head.Seq {
+		return nil, nil
+	}
+
+	a := sendkey.AuditAnchor{
+		Seq:          head.Seq,
+		Hash:         head.Hash,
+		Signature:    l.sign(head.Seq, head.Hash),
+		CreatedAtUTC: time.Now().UTC(),
+	}
+	if err = l.heads.CreateAnchor(a); err != nil {
+		return nil, err
+	}
+
+	return &a, nil
+}
+
+type AuditVerification struct {
+	Events  int64 `json:"events"`
+	Anchors int   `json:"anchors"`
+	// Unanchored is the number of events after the last anchor. They're
+	// chained, but could be removed or rewritten without detection until
+	// they're anchored.
+	Unanchored int64 `json:"unanchored"`
+}
+
+// AuditTamperedError is returned by Verify when the log doesn't match its
+// chain or anchors.
+type AuditTamperedError struct {
+	Seq    int64
+	Reason string
+}
+
+func (e *AuditTamperedError) Error() string {
+	return fmt.Sprintf("audit log tampered at event %d: %s", e.Seq, e.Reason)
+}
+
+// Verify checks every event read from the source's hash and chain, and that
+// every anchor is signed with the key and matches its event, reading
+// batchSize rows at a time. It returns an *AuditTamperedError for the first
+// problem found.
+func (l *AuditLog) Verify(events AuditSource, batchSize int) (*AuditVerification, error) {
+	v := &AuditVerification{}
+
+	anchors := map[int64]sendkey.AuditAnchor{}
+	var lastAnchored int64
+	for {
+		batch, err := l.heads.Anchors(lastAnchored, batchSize)
+		if err != nil {
+			return nil, err
+		}
+		if len(batch) == 0 {
+			break
+		}
+		for _, a := range batch {
+			if !hmac.Equal([]byte(a.Signature), []byte(l.sign(a.Seq, a.Hash))) {
+				return nil, &AuditTamperedError{a.Seq, "the anchor's signature is invalid"}
+			}
+			anchors[a.Seq] = a
+			lastAnchored = a.Seq
+		}
+	}
+	v.Anchors = len(anchors)
+
+	prev := sendkey.AuditEvent{Hash: genesisHash}
+	for {
+		batch, err := events.Events(prev.Seq, batchSize)
+		if err != nil {
+			return nil, err
+		}
+		if len(batch) == 0 {
+			break
+		}
+		for _, e := range batch {
+			if e.Seq != prev.Seq+1 {
+				return nil, &AuditTamperedError{prev.Seq + 1, "the event is missing"}
+			}
+			if e.PrevHash != prev.Hash {
+				return nil, &AuditTamperedError{e.Seq, "the event doesn't chain to the previous one"}
+			}
+			if e.Hash != auditHash(e) {
+				return nil, &AuditTamperedError{e.Seq, "the event's hash doesn't match its contents"}
+			}
+			if a, ok := anchors[e.Seq]; ok {
+				if a.Hash != e.Hash {
+					return nil, &AuditTamperedError{e.Seq, "the event doesn't match its anchor"}
+				}
+				delete(anchors, e.Seq)
+			}
+			prev = e
+		}
+	}
+	v.Events = prev.Seq
+
+	// anchors left over are for events that were removed from the end
+	if len(anchors) > 0 {
+		return nil, &AuditTamperedError{prev.Seq + 1, "anchored events are missing"}
+	}
+	if prev.Seq > lastAnchored {
+		v.Unanchored = prev.Seq - lastAnchored
+	}
+
+	return v, nil
+}
+
+func (l *AuditLog) sign(seq int64, hash string) string {
+	mac := hmac.New(sha256.New, l.key)
+	fmt.Fprintf(mac, "audit-anchor|%d|%s", seq, hash)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func auditHash(e sendkey.AuditEvent) string {
+	h := sha256.New()
+	for _, field := range []string{strconv.FormatInt(e.Seq, 10), e.Type, e.AtUTC.UTC().Format(time.RFC3339), e.Data, e.PrevHash} {
+		h.Write([]byte(field))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// RecordAudit records the service's entry events in the audit log.
+func (s *EntryService) RecordAudit(l *AuditLog) {
+	s.audit = l
+}
+
+// FromClient returns a copy of the service that records the client's IP with
+// the audit events it records. It's meant to be made for each request. The
+// IP isn't published with the events, since it's personal data.
+func (s *EntryService) FromClient(ip string) *EntryService {
+	c := *s
+	c.clientIP = ip
+	return &c
+}
+
+// record adds an event to the audit log, if there is one, publishes it, and
+// counts it. The event has already happened by the time it's recorded, so a
+// failure is logged rather than failing the request.
+func (s *EntryService) record(eventType string, fields map[string]string) {
+	s.count(eventType)
+	publish(s.log(), s.events, eventType, fields)
+	if s.audit == nil {
+		return
+	}
+	if err := s.audit.Record(eventType, s.withClientIP(fields)); err != nil {
+		s.log().Error("audit: recording an event", "type", eventType, "fields", fields, "error", err)
+	}
+}
+
+// recordFirst is record for events that mustn't happen unaudited: the event
+// is written to the audit log before it's published, and an error is
+// returned, so the caller can refuse it, if that fails or there's no log.
+func (s *EntryService) recordFirst(eventType string, fields map[string]string) error {
+	if s.audit == nil {
+		return fmt.Errorf("audit: no audit log to record %s in", eventType)
+	}
+	if err := s.audit.Record(eventType, s.withClientIP(fields)); err != nil {
+		return fmt.Errorf("audit: recording %s: %w", eventType, err)
+	}
+
+	s.count(eventType)
+	publish(s.log(), s.events, eventType, fields)
+	return nil
+}
+
+func (s *EntryService) withClientIP(fields map[string]string) map[string]string {
+	if s.clientIP == "" {
+		return fields
+	}
+	withIP := make(map[string]string, len(fields)+1)
+	for k, v := range fields {
+		withIP[k] = v
+	}
+	withIP["clientIp"] = s.clientIP
+	return withIP
+}
@@ -0,0 +1,97 @@
+package app
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gavinwade12/sendkey"
+	"github.com/gavinwade12/sendkey/internal/redact"
+)
+
+// memoryAudit keeps the audit log's head and events in memory.
+type memoryAudit struct {
+	head    sendkey.AuditHead
+	events  []sendkey.AuditEvent
+	anchors []sendkey.AuditAnchor
+}
+
+func (m *memoryAudit) Advance(build func(sendkey.AuditHead) sendkey.AuditEvent, write func(sendkey.AuditEvent) error) error {
+	e := build(m.head)
+	if err := write(e); err != nil {
+		return err
+	}
+	m.head = sendkey.AuditHead{Seq: e.Seq, Hash: e.Hash}
+	return nil
+}
+
+func (m *memoryAudit) Head() (sendkey.AuditHead, error) { return m.head, nil }
+
+func (m *memoryAudit) CreateAnchor(a sendkey.AuditAnchor) error {
+	m.anchors = append(m.anchors, a)
+	return nil
+}
+
+func (m *memoryAudit) LastAnchor() (*sendkey.AuditAnchor, error) {
+	if len(m.anchors) == 0 {
+		return nil, nil
+	}
+	return &m.anchors[len(m.anchors)-1], nil
+}
+
+func (m *memoryAudit) Anchors(afterSeq int64, limit int) ([]sendkey.AuditAnchor, error) {
+	var result []sendkey.AuditAnchor
+	for _, a := range m.anchors {
+		if a.Seq > afterSeq && len(result) < limit {
+			result = append(result, a)
+		}
+	}
+	return result, nil
+}
+
+func (m *memoryAudit) Write(e sendkey.AuditEvent) error {
+	m.events = append(m.events, e)
+	return nil
+}
+
+func (m *memoryAudit) Events(afterSeq int64, limit int) ([]sendkey.AuditEvent, error) {
+	var result []sendkey.AuditEvent
+	for _, e := range m.events {
+		if e.Seq > afterSeq && len(result) < limit {
+			result = append(result, e)
+		}
+	}
+	return result, nil
+}
+
+func TestAuditRecordRedacts(t *testing.T) {
+	const secret = "hunter2-s3cr3t"
+	// the head starts at the genesis hash, as the migration seeds it
+	m := &memoryAudit{head: sendkey.AuditHead{Hash: genesisHash}}
+	l := NewAuditLog(m, m, []byte("signing key"))
+
+	err := l.Record("entry.escrowReleased", map[string]string{
+		"entryId": "4f1c",
+		"secret":  secret,
+		"reason":  "the sender pasted it: password=" + secret,
+		"link":    "https://sendkey.example/claim/" + secret,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(m.events) != 1 {
+		t.Fatalf("recorded %d events, want 1", len(m.events))
+	}
+	data := m.events[0].Data
+	if strings.Contains(data, secret) {
+		t.Errorf("the event has the secret: %s", data)
+	}
+	if !strings.Contains(data, redact.Mask) || !strings.Contains(data, `"entryId":"4f1c"`) {
+		t.Errorf("the event's data is %s", data)
+	}
+
+	// the chain is over the redacted data, so it still verifies
+	if _, err = l.Verify(m, 10); err != nil {
+		t.Errorf("verifying the log: %v", err)
+	}
+}
@@ -0,0 +1,40 @@
+package app
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"fmt"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// The AEADs entries can be encrypted with. XChaCha20-Poly1305 is much faster
+// than AES-GCM on hardware without AES instructions. Entries with no cipher
+// recorded predate the choice and use AES-GCM.
+const (
+	CipherAESGCM            = "aes-gcm"
+	CipherXChaCha20Poly1305 = "xchacha20-poly1305"
+)
+
+// SupportedCipher reports whether entries can be encrypted with the cipher.
+func SupportedCipher(name string) bool {
+	return name == CipherAESGCM || name == CipherXChaCha20Poly1305
+}
+
+func newAEAD(name string, key []byte) (cipher.AEAD, error) {
+	switch name {
+	case "", CipherAESGCM:
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		return cipher.NewGCM(block)
+	case CipherXChaCha20Poly1305:
+		if len(key) != chacha20poly1305.KeySize {
+			return nil, fmt.Errorf("%s requires a %d byte key", name, chacha20poly1305.KeySize)
+		}
+		return chacha20poly1305.NewX(key)
+	default:
+		return nil, fmt.Errorf("unsupported cipher %q", name)
+	}
+}
@@ -0,0 +1,71 @@
+package app
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/gavinwade12/sendkey"
+	"github.com/google/uuid"
+)
+
+// ClaimLinks builds the links recipients open to claim entries. A link holds
+// the entry's ID and nonce signed with a server key, so it can't be altered
+// to point at another entry.
+type ClaimLinks struct {
+	key     []byte
+	baseURL string
+}
+
+// The baseURL argument is the public URL the links point at; "/claim/" and
+// the signed token are appended to it.
+func NewClaimLinks(key []byte, baseURL string) *ClaimLinks {
+	return &ClaimLinks{key, strings.TrimSuffix(baseURL, "/")}
+}
+
+// URL returns the signed claim link for the entry.
+func (l *ClaimLinks) URL(e sendkey.Entry) string {
+	return l.baseURL + "/claim/" + l.token(e.ID, e.Nonce)
+}
+
+// LinkURL returns the signed URL for one of an entry's claim links.
+func (l *ClaimLinks) LinkURL(link sendkey.ClaimLink) string {
+	return l.baseURL + "/claim/" + l.token(link.EntryID, link.Nonce)
+}
+
+// Verify returns the entry ID and hex encoded nonce held by a link's token if
+// its signature is valid.
+func (l *ClaimLinks) Verify(token string) (uuid.UUID, string, bool) {
+	parts := strings.SplitN(token, ".", 2)
+	if len(parts) != 2 {
+		return uuid.Nil, "", false
+	}
+
+	b, err := hex.DecodeString(parts[0])
+	if err != nil || len(b) <= len(uuid.Nil) {
+		return uuid.Nil, "", false
+	}
+	id, err := uuid.FromBytes(b[:len(uuid.Nil)])
+	if err != nil {
+		return uuid.Nil, "", false
+	}
+	nonce := b[len(uuid.Nil):]
+
+	if !hmac.Equal([]byte(parts[1]), []byte(l.signature(id, nonce))) {
+		return uuid.Nil, "", false
+	}
+	return id, hex.EncodeToString(nonce), true
+}
+
+func (l *ClaimLinks) token(id uuid.UUID, nonce []byte) string {
+	return hex.EncodeToString(append(id[:], nonce...)) + "." + l.signature(id, nonce)
+}
+
+func (l *ClaimLinks) signature(id uuid.UUID, nonce []byte) string {
+	mac := hmac.New(sha256.New, l.key)
+	mac.Write([]byte("claim-link:"))
+	mac.Write(id[:])
+	mac.Write(nonce)
+	return hex.EncodeToString(mac.Sum(nil))
+}
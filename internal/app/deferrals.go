@@ -0,0 +1,164 @@
+package app
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gavinwade12/sendkey"
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultClaimDeferral is how long a claim is deferred if the recipient
+	// doesn't say.
+	DefaultClaimDeferral = 24 * time.Hour
+	maxDeferrals         = 3
+	// reminderLead is how long before an entry expires its latest reminder
+	// is sent, so the recipient has time to claim it.
+	reminderLead = time.Hour
+)
+
+type DeferClaimRequest struct {
+	ID    uuid.UUID `json:"id"`
+	Nonce string    `json:"nonce"`
+	// Delay is how long until the recipient is reminded. It defaults to
+	// DefaultClaimDeferral, and is shortened if the entry would expire
+	// before the reminder.
+	Delay time.Duration `json:"delay"`
+}
+
+type DeferClaimResponse struct {
+	Success  bool                   `json:"success"`
+	Errors   []Problem              `json:"errors"`
+	Deferral *sendkey.EntryDeferral `json:"deferral"`
+}
+
+// DeferClaim records that the recipient wants to claim the entry later and
+// schedules a reminder for them. The sender can see the deferrals in their
+// list of entries.
+func (s *EntryService) DeferClaim(req DeferClaimRequest) (*DeferClaimResponse, error) {
+	resp := &DeferClaimResponse{}
+	if req.Delay < 0 {
+		resp.Errors = append(resp.Errors, problem("deferral_invalid"))
+		return resp, nil
+	}
+	if req.Delay == 0 {
+		req.Delay = DefaultClaimDeferral
+	}
+
+	entry, err := s.FindEntry(req.ID, req.Nonce)
+	if notAvailable, ok := err.(*NotAvailableError); ok {
+		resp.Errors = append(resp.Errors, notAvailable.Problem())
+		return resp, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		resp.Errors = append(resp.Errors, problem("invalid_entry_id"))
+		return resp, nil
+	}
+
+	deferrals, err := s.entries.FindDeferrals(entry.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(deferrals) >= maxDeferrals {
+		resp.Errors = append(resp.Errors, problem("too_many_deferrals", maxDeferrals))
+		return resp, nil
+	}
+
+	now := time.Now().UTC()
+	remindAt := now.Add(s.scale(req.Delay))
+	if latest := entry.ExpiresAtUTC.Add(-reminderLead); remindAt.After(latest) {
+		remindAt = latest
+	}
+	if !remindAt.After(now) {
+		resp.Errors = append(resp.Errors, problem("deferral_too_late"))
+		return resp, nil
+	}
+
+	d := sendkey.EntryDeferral{
+		ID:            s.newID(),
+		EntryID:       entry.ID,
+		DeferredAtUTC: now,
+		RemindAtUTC:   remindAt,
+	}
+	if err = s.entries.CreateDeferral(d); err != nil {
+		return nil, err
+	}
+
+	resp.Success = true
+	resp.Deferral = &d
+	return resp, nil
+}
+
+// SendDueReminders emails the recipients of deferred claims that are due,
+// batchSize deferrals at a time, with a new link to the entry. It returns the
+// number of reminders sent. Reminders for entries that are gone, in the
+// sandbox, or sent to recipients who opted out are skipped.
+func (s *EntryService) SendDueReminders(mailer Mailer, links *ClaimLinks, batchSize int) (int, error) {
+	now := time.Now().UTC()
+	sent := 0
+
+	for {
+		due, err := s.entries.FindDueDeferrals(now, batchSize)
+		if err != nil {
+			return sent, err
+		}
+		if len(due) == 0 {
+			return sent, nil
+		}
+
+		for _, d := range due {
+			entry, err := s.entries.Find(d.EntryID)
+			if err != nil {
+				return sent, err
+			}
+			// sandbox entries are never emailed, and recipients who opted
+			// out aren't reminded, so those are only marked
+			remind := entry != nil && !entry.Sandbox && entry.ExpiresAtUTC.After(now)
+			if remind {
+				optedOut, err := s.optedOut(entry.SentToEmail)
+				if err != nil {
+					return sent, err
+				}
+				remind = !optedOut
+			}
+			if remind {
+				url, err := s.reminderURL(links, *entry)
+				if err != nil {
+					return sent, err
+				}
+				body := fmt.Sprintf("You asked to be reminded about %q, which was sent to you with sendkey. It expires at %s.\n\n%s\n%s",
+					entry.Name, entry.ExpiresAtUTC.Format(time.RFC1123), url, s.optOutFooter(entry.SentToEmail))
+				if err = mailer.Send(entry.SentToEmail, "Reminder: an entry is waiting for you", body); err != nil {
+					return sent, err
+				}
+				sent++
+			}
+
+			if err = s.entries.MarkDeferralReminded(d.ID, now); err != nil {
+				return sent, err
+			}
+		}
+	}
+}
+
+// reminderURL returns the link for a reminder. An entry whose links expire
+// gets a new one, since the one the recipient has may have expired while they
+// put it off.
+func (s *EntryService) reminderURL(links *ClaimLinks, entry sendkey.Entry) (string, error) {
+	active, err := s.ActiveClaimLink(entry)
+	if err != nil {
+		return "", err
+	}
+	if active == nil && s.linkTTL == 0 {
+		return links.URL(entry), nil
+	}
+	l, err := s.issueClaimLink(entry, s.linkTTL)
+	if err != nil {
+		return "", err
+	}
+	return links.LinkURL(*l), nil
+}
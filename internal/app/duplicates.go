@@ -0,0 +1,61 @@
+package app
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SendLog remembers recently created entries by fingerprint.
+type SendLog interface {
+	// Record records the fingerprint and returns the number of times it's
+	// been recorded, forgetting it once the window passes without another.
+	Record(fingerprint string, window time.Duration) (int, error)
+}
+
+// DuplicateDetection catches a sender creating the same entry for the same
+// recipient twice within a short window, like a flaky client submitting a
+// request again. End-to-end entries are encrypted with a random salt, so
+// their duplicates can't be detected.
+type DuplicateDetection struct {
+	Log    SendLog
+	Window time.Duration
+	// Suppress rejects duplicates instead of only warning about them.
+	Suppress bool
+}
+
+// DetectDuplicates turns on duplicate detection for new entries.
+func (s *EntryService) DetectDuplicates(d DuplicateDetection) {
+	s.duplicates = &d
+}
+
+// checkDuplicate records the entry and returns a problem if it's a duplicate.
+// The fingerprint is keyed with the server key so recorded fingerprints can't
+// be used to guess values.
+func (s *EntryService) checkDuplicate(senderID uuid.UUID, sendTo, name string, value []byte) (*Problem, error) {
+	if s.duplicates == nil {
+		return nil, nil
+	}
+
+	serverKey, err := s.keys.key(s.keys.Current())
+	if err != nil {
+		return nil, err
+	}
+	key := sha256.Sum256(append([]byte("duplicate:"), serverKey...))
+	mac := hmac.New(sha256.New, key[:])
+	for _, field := range [][]byte{senderID[:], []byte(sendTo), []byte(name), value} {
+		sum := sha256.Sum256(field)
+		mac.Write(sum[:])
+	}
+
+	count, err := s.duplicates.Log.Record("entry:"+hex.EncodeToString(mac.Sum(nil)), s.duplicates.Window)
+	if err != nil || count <= 1 {
+		return nil, err
+	}
+
+	p := problem("duplicate_entry", int(s.duplicates.Window.Seconds()))
+	return &p, nil
+}
@@ -1,17 +1,20 @@
 package app
 
 import (
-	"crypto/aes"
-	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
-	"math/rand"
+	"log/slog"
+	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/gavinwade12/sendkey"
 	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
 )
 
 type EntryRepository interface {
@@ -22,20 +25,155 @@ type EntryRepository interface {
 	IncrementInvalidAttempts(uuid.UUID) (int, error)
 
 	CreateClaimedEntry(sendkey.ClaimedEntry) error
+	FindClaimedEntry(entryID uuid.UUID) (*sendkey.ClaimedEntry, error)
 	CreateExpiredEntry(sendkey.ExpiredEntry) error
+	CreateClaimReceipt(sendkey.ClaimReceipt) error
+
+	FindChallenges(uuid.UUID) ([]sendkey.EntryChallenge, error)
+
+	// FindStaleKeyVersion returns up to limit entries that are wrapped with a
+	// key version other than current. Legacy entries aren't included.
+	FindStaleKeyVersion(current, limit int) ([]sendkey.Entry, error)
+	CountByKeyVersion(int) (int, error)
+	UpdateEncryption(id uuid.UUID, value []byte, keyVersion int) error
+
+	// FindUnexpired returns up to limit entries that expire after now,
+	// ordered by ID and starting after the given ID.
+	FindUnexpired(now time.Time, after uuid.UUID, limit int) ([]sendkey.Entry, error)
+
+	CreateDeferral(sendkey.EntryDeferral) error
+	FindDeferrals(entryID uuid.UUID) ([]sendkey.EntryDeferral, error)
+	// FindDueDeferrals returns up to limit deferrals with reminders due by
+	// now that haven't been sent.
+	FindDueDeferrals(now time.Time, limit int) ([]sendkey.EntryDeferral, error)
+	MarkDeferralReminded(id uuid.UUID, at time.Time) error
+
+	// SaveOTP stores the entry's one-time code, replacing any earlier one.
+	SaveOTP(sendkey.EntryOTP) error
+	FindOTP(entryID uuid.UUID) (*sendkey.EntryOTP, error)
+
+	CreateDelegation(sendkey.EntryDelegation) error
+	FindDelegation(uuid.UUID) (*sendkey.EntryDelegation, error)
+	// CreateClaimLink stores the link as the entry's active link,
+	// superseding the others at its creation time.
+	CreateClaimLink(sendkey.ClaimLink) error
+	// FindClaimLinks returns the entry's links, newest first.
+	FindClaimLinks(entryID uuid.UUID) ([]sendkey.ClaimLink, error)
+
+	SaveFlags(userID, entryID uuid.UUID, f sendkey.EntryFlags) error
+	FindFlags(userID uuid.UUID) (map[uuid.UUID]sendkey.EntryFlags, error)
+
+	// CreateWithEscrow creates the entry along with its escrow, atomically.
+	CreateWithEscrow(sendkey.Entry, sendkey.EntryEscrow) error
+	FindEscrow(entryID uuid.UUID) (*sendkey.EntryEscrow, error)
+	// RetainEscrow marks the entry's escrow as claimed and keeps it until
+	// purgeAt.
+	RetainEscrow(entryID uuid.UUID, claimedAt, purgeAt time.Time) error
+	DeleteEscrow(entryID uuid.UUID) error
+	// PurgeEscrows deletes the escrows due to be purged before the time.
+	PurgeEscrows(before time.Time) (int64, error)
+
+	// Revoke expires the active entries matching the filter in one
+	// transaction, and returns the expired entries.
+	Revoke(filter sendkey.EntryFilter, at time.Time) ([]sendkey.ExpiredEntry, error)
+	// FindOutcomes returns up to limit claimed and expired entries matching
+	// the filter, newest first, that come before the outcome at the time
+	// with the entry ID. A zero time starts with the newest.
+	FindOutcomes(filter sendkey.OutcomeFilter, before time.Time, beforeID uuid.UUID, limit int) ([]sendkey.EntryOutcome, error)
+	// FindOutcomesBefore returns up to limit claimed and expired entries
+	// from before the time, oldest first.
+	FindOutcomesBefore(before time.Time, limit int) ([]sendkey.EntryOutcome, error)
+	// DeleteOutcomes deletes the claimed and expired entries with the IDs,
+	// returning how many there were.
+	DeleteOutcomes(entryIDs []uuid.UUID) (int64, error)
+
+	// SuppressRecipient adds the lowercased address to the recipients who
+	// opted out of receiving entries.
+	SuppressRecipient(email string, at time.Time) error
+	// UnsuppressRecipient removes it, returning whether it was there.
+	UnsuppressRecipient(email string) (bool, error)
+	RecipientSuppressed(email string) (bool, error)
 }
 
 type EntryService struct {
 	entries EntryRepository
 
-	aesKey      []byte
+	keys        *KeyRing
 	maxAttempts int
+	kdf         EntryKeyDerivation
+	cipher      string
+	maxValue    int
+	duplicates  *DuplicateDetection
+	frozen      bool
+	otpMailer   Mailer
+	audit       *AuditLog
+	// clientIP is recorded with audit events; see FromClient.
+	clientIP string
+	events   EventPublisher
+	quota    Quota
+	usage    UsageRepository
+	// usageCounter counts features for anonymous usage statistics.
+	usageCounter UsageCounter
+	logger       *slog.Logger
+	// guestMaxDuration is the longest entries created without a sender
+	// can last. Zero doesn't allow them.
+	guestMaxDuration time.Duration
+	anonymousSenders AnonymousSenderRepository
+	addressKey       []byte
+	// sandbox services keep their entries apart from real ones; see Sandbox.
+	sandbox          bool
+	sandboxTimeScale int
+	// escrow keeps copies of new entries' values; see EscrowValues.
+	escrow      *Escrow
+	escrowKeyID string
+	// claimedValueKey keys the hashes of claimed values in events; see
+	// HashClaimedValues.
+	claimedValueKey []byte
+	// history limits how long outcomes are kept; see RetainHistory.
+	history *HistoryRetention
+	// optOuts links recipients to opt out of entries; see HonorOptOuts.
+	optOuts *OptOutLinks
+	// linkTTL is how long new entries' claim links last; see
+	// ExpireClaimLinks.
+	linkTTL time.Duration
+	// entryIDs and ids generate the IDs of new entries and of the other
+	// records; see GenerateIDs.
+	entryIDs, ids sendkey.IDGenerator
 }
 
-// The key argument should be the AES key, either 16, 24, or 32 bytes to select AES-128, AES-192, or AES-256.
+// DefaultMaxValueBytes is the default limit on the size of an entry's value.
+const DefaultMaxValueBytes = 64 << 10
+
+// The keys argument holds the server keys entries are wrapped with.
 // The maxAttempts argument is the number of invalid attempts allowed before an entry is forcefully expired.
-func NewEntryService(er EntryRepository, key []byte, maxAttempts int) *EntryService {
-	return &EntryService{er, key, maxAttempts}
+// The kdf argument is the cost of deriving keys for new entries from their secrets.
+// The cipher argument is the AEAD new entries are encrypted with; see SupportedCipher.
+// The maxValue argument is the largest value, in bytes, an entry can hold.
+func NewEntryService(er EntryRepository, keys *KeyRing, maxAttempts int, kdf EntryKeyDerivation, cipher string, maxValue int) *EntryService {
+	return &EntryService{entries: er, keys: keys, maxAttempts: maxAttempts, kdf: kdf, cipher: cipher, maxValue: maxValue}
+}
+
+// GenerateIDs sets the generators of the IDs of new entries and of the
+// other records the service creates, like claim links. Without it, they're
+// random.
+func (s *EntryService) GenerateIDs(entries, records sendkey.IDGenerator) {
+	s.entryIDs, s.ids = entries, records
+}
+
+func (s *EntryService) newEntryID() uuid.UUID {
+	return newID(s.entryIDs)
+}
+
+func (s *EntryService) newID() uuid.UUID {
+	return newID(s.ids)
+}
+
+// newID generates an ID with the generator, or a random one if it's nil.
+func newID(g sendkey.IDGenerator) uuid.UUID {
+	if g == nil {
+		return sendkey.RandomIDs.NewID()
+	}
+	return g.NewID()
 }
 
 type CreateEntryRequest struct {
@@ -45,99 +183,368 @@ type CreateEntryRequest struct {
 	Value       string        `json:"value"`
 	Secret      string        `json:"secret"`
 	Duration    time.Duration `json:"duration"`
+	// AvailableAtUTC, if set, is the earliest the entry can be claimed. It
+	// must be before the entry expires.
+	AvailableAtUTC *time.Time `json:"availableAtUtc"`
+	Locale         string     `json:"locale"`
+	// Type is one of the sendkey.EntryType constants. It defaults to a note.
+	Type     string                `json:"type"`
+	Metadata sendkey.EntryMetadata `json:"metadata"`
+	// EndToEnd is set instead of Value and Secret when the sender's client
+	// encrypted the value itself.
+	EndToEnd *SealedValue `json:"endToEnd"`
+	// Generate, if set, has the value generated with the policy instead of
+	// sent. It's never returned, so once the recipient claims the entry
+	// they're the only one who's seen it. Value must be empty.
+	Generate *GeneratePolicy `json:"generate"`
+	// RequireLogin makes the recipient log in to claim the entry, so a
+	// leaked link and secret aren't enough.
+	RequireLogin bool `json:"requireLogin"`
+	// RequireOTP emails the recipient a one-time code when they claim the
+	// entry, so the link and secret alone aren't enough.
+	RequireOTP bool `json:"requireOtp"`
+	// Acknowledgment, if set, is text the recipient must acknowledge before
+	// the value is decrypted. What they acknowledged, and when, is recorded
+	// with the claim.
+	Acknowledgment string `json:"acknowledgment"`
+	// Digest is the sender's client's digest of the value, handed back to
+	// the recipient when they claim the entry.
+	Digest *sendkey.ValueDigest `json:"digest"`
+	// ReplacesEntryID, if set, is one of the sender's unclaimed entries this
+	// one rotates. Claiming this one revokes it.
+	ReplacesEntryID *uuid.UUID `json:"replacesEntryId"`
+	// SenderAddress is a guest's client address, which their entry is
+	// attributed to through an anonymous sender. It's ignored for entries
+	// with a sender.
+	SenderAddress string `json:"-"`
+
+	Challenges []ChallengeRequest `json:"challenges"`
 }
 
+type ChallengeRequest struct {
+	Question string `json:"question"`
+	Answer   string `json:"answer"`
+}
+
+const (
+	// maxChallenges is the most challenges an entry can have. Each answer
+	// is hashed with bcrypt, so it bounds the work one request can cause.
+	maxChallenges = 5
+	// maxChallengeQuestionLength is the most characters a question can have.
+	maxChallengeQuestionLength = 255
+	// maxChallengeAnswerBytes is the most bytes of a normalized answer
+	// bcrypt can hash.
+	maxChallengeAnswerBytes = 72
+)
+
 type CreateEntryResponse struct {
-	Success bool           `json:"success"`
-	Errors  []string       `json:"errors"`
-	Entry   *sendkey.Entry `json:"entry"`
+	Success bool      `json:"success"`
+	Errors  []Problem `json:"errors"`
+	// Warnings are problems that didn't stop the entry from being created.
+	Warnings []Problem      `json:"warnings"`
+	Entry    *sendkey.Entry `json:"entry"`
+	// Link is the entry's claim link if links expire; otherwise it's
+	// claimed with its original link.
+	Link *sendkey.ClaimLink `json:"link"`
 }
 
 func (s *EntryService) CreateEntry(req CreateEntryRequest) (*CreateEntryResponse, error) {
 	resp := &CreateEntryResponse{}
+	var v Validator
 	if req.SenderID == uuid.Nil {
-		resp.Errors = append(resp.Errors, "A sender ID is required.")
-	}
-	if strings.TrimSpace(req.Name) == "" {
-		resp.Errors = append(resp.Errors, "A name is required.")
+		if v.Check(s.guestMaxDuration != 0, "", "sender_id_required") {
+			v.Check(req.Duration <= s.guestMaxDuration, "duration", "guest_duration", s.guestMaxDuration.String())
+		}
 	}
+	v.Require("name", req.Name, "name_required")
 	req.SendToEmail = strings.TrimSpace(req.SendToEmail)
-	if req.SendToEmail == "" {
-		resp.Errors = append(resp.Errors, "A send to email is required.")
-	}
-	if strings.TrimSpace(req.Value) == "" {
-		resp.Errors = append(resp.Errors, "A value is required.")
+	v.Require("sendToEmail", req.SendToEmail, "send_to_email_required")
+	if req.Generate != nil {
+		problems, err := generateValue(&req)
+		if err != nil {
+			return nil, err
+		}
+		v.Add("", problems...)
+	}
+	if req.EndToEnd != nil {
+		v.Check(req.Value == "" && req.Secret == "", "endToEnd", "end_to_end_plaintext")
+		v.Add("endToEnd", s.validateSealed(*req.EndToEnd)...)
+	} else {
+		if req.Generate == nil {
+			v.Require("value", req.Value, "value_required")
+		}
+		if len(req.Value) > s.maxValue {
+			v.Add("value", s.valueTooLarge())
+		}
+		v.Require("secret", req.Secret, "secret_required")
+	}
+	if v.Check(req.Duration > 0, "duration", "duration_invalid") && req.AvailableAtUTC != nil {
+		v.Check(req.AvailableAtUTC.Before(time.Now().Add(req.Duration)), "availableAtUtc", "available_after_expiry")
+	}
+	v.Check(!req.RequireOTP || s.otpMailer != nil || s.sandbox, "requireOtp", "otp_unavailable")
+	req.Acknowledgment = strings.TrimSpace(req.Acknowledgment)
+	v.Check(utf8.RuneCountInString(req.Acknowledgment) <= maxAcknowledgmentLength,
+		"acknowledgment", "acknowledgment_too_long", maxAcknowledgmentLength)
+	req.Type = strings.TrimSpace(req.Type)
+	if req.Type == "" {
+		req.Type = sendkey.EntryTypeNote
+	}
+	v.Add("", validateEntryType(req.Type, req.Metadata)...)
+	v.Check(len(req.Challenges) <= maxChallenges, "challenges", "too_many_challenges", maxChallenges)
+	for i, c := range req.Challenges {
+		field := fmt.Sprintf("challenges[%d]", i)
+		if !v.Check(strings.TrimSpace(c.Question) != "" && normalizeAnswer(c.Answer) != "", field, "challenge_incomplete", i+1) {
+			continue
+		}
+		v.Check(utf8.RuneCountInString(strings.TrimSpace(c.Question)) <= maxChallengeQuestionLength,
+			field, "question_too_long", i+1, maxChallengeQuestionLength)
+		v.Check(len(normalizeAnswer(c.Answer)) <= maxChallengeAnswerBytes,
+			field, "answer_too_long", i+1, maxChallengeAnswerBytes)
+	}
+	if v.Valid() && req.Digest != nil {
+		if p := validateDigest(*req.Digest, req.Value, req.Secret, req.EndToEnd != nil); p != nil {
+			v.Add("digest", *p)
+		}
 	}
-	if strings.TrimSpace(req.Secret) == "" {
-		resp.Errors = append(resp.Errors, "A secret is required.")
+	if !v.Valid() {
+		resp.Errors = v.Problems()
+		resp.Success = false
+		return resp, nil
 	}
-	if req.Duration <= 0 {
-		resp.Errors = append(resp.Errors, "Duration must be greater than 0.")
+
+	optedOut, err := s.optedOut(req.SendToEmail)
+	if err != nil {
+		return nil, err
 	}
-	if len(resp.Errors) > 0 {
-		resp.Success = false
+	if optedOut {
+		resp.Errors = append(resp.Errors, problem("recipient_opted_out").at("sendToEmail"))
 		return resp, nil
 	}
+	if req.ReplacesEntryID != nil {
+		p, err := s.checkReplaces(req.SenderID, *req.ReplacesEntryID)
+		if err != nil {
+			return nil, err
+		}
+		if p != nil {
+			resp.Errors = append(resp.Errors, *p)
+			return resp, nil
+		}
+	}
 
-	nonce := s.nonce()
-	value, err := s.encrypt([]byte(req.Value), nonce, []byte(req.Secret))
+	sent := []byte(req.Value)
+	if req.EndToEnd != nil {
+		sent = req.EndToEnd.Ciphertext
+	}
+	duplicate, err := s.checkDuplicate(req.SenderID, req.SendToEmail, req.Name, sent)
 	if err != nil {
 		return nil, err
 	}
+	if duplicate != nil {
+		if s.duplicates.Suppress {
+			resp.Errors = append(resp.Errors, *duplicate)
+			return resp, nil
+		}
+		resp.Warnings = append(resp.Warnings, *duplicate)
+	}
+	// sealed values are measured by their ciphertext
+	overQuota, err := s.checkQuota(req.SenderID, len(sent))
+	if err != nil {
+		return nil, err
+	}
+	if overQuota != nil {
+		resp.Errors = append(resp.Errors, *overQuota)
+		return resp, nil
+	}
+
+	challenges := make([]sendkey.EntryChallenge, len(req.Challenges))
+	for i, c := range req.Challenges {
+		hash, err := bcrypt.GenerateFromPassword([]byte(normalizeAnswer(c.Answer)), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, err
+		}
+		challenges[i] = sendkey.EntryChallenge{
+			Question:   strings.TrimSpace(c.Question),
+			AnswerHash: string(hash),
+		}
+	}
+
+	var (
+		keyVersion = s.keys.Current()
+		cipher     = s.cipher
+		kdf        sendkey.EntryKDF
+		key        []byte
+		nonce      []byte
+		value      []byte
+	)
+	if req.EndToEnd != nil {
+		cipher, kdf = req.EndToEnd.Cipher, req.EndToEnd.KDF
+		nonce, value = req.EndToEnd.Nonce, req.EndToEnd.Ciphertext
+	} else {
+		if kdf, err = s.kdf.newKDF(); err != nil {
+			return nil, err
+		}
+		if key, err = s.deriveKey([]byte(req.Secret), kdf, keyVersion); err != nil {
+			return nil, err
+		}
+		if nonce, value, err = s.encrypt([]byte(req.Value), key); err != nil {
+			return nil, err
+		}
+	}
+	if value, err = s.keys.wrap(value, keyVersion, cipher); err != nil {
+		return nil, err
+	}
 
 	now := time.Now().UTC()
+	var anonymousSenderID *uuid.UUID
+	if req.SenderID == uuid.Nil {
+		if anonymousSenderID, err = s.recordAnonymousSender(req.SenderAddress, now); err != nil {
+			return nil, err
+		}
+	}
 	entry := sendkey.Entry{
-		ID:           uuid.New(),
-		Name:         req.Name,
-		SentByUserID: req.SenderID,
-		SentToEmail:  req.SendToEmail,
-		Nonce:        nonce,
-		Value:        value,
-		CreatedAtUTC: now,
-		ExpiresAtUTC: now.Add(req.Duration),
+		ID:             s.newEntryID(),
+		Name:           req.Name,
+		SentByUserID:   req.SenderID,
+		SentToEmail:    req.SendToEmail,
+		Nonce:          nonce,
+		Value:          value,
+		Locale:         strings.TrimSpace(req.Locale),
+		KDF:            kdf,
+		KeyVersion:     keyVersion,
+		Cipher:         cipher,
+		EndToEnd:       req.EndToEnd != nil,
+		RequireLogin:   req.RequireLogin,
+		RequireOTP:     req.RequireOTP,
+		Acknowledgment: req.Acknowledgment,
+		Type:           req.Type,
+		Metadata:       req.Metadata,
+		CreatedAtUTC:   now,
+		AvailableAtUTC: s.scaleAvailableAt(availableAt(req.AvailableAtUTC, now), now),
+		ExpiresAtUTC:   now.Add(s.scale(req.Duration)),
+		Digest:         req.Digest,
+		Sandbox:        s.sandbox,
+		Challenges:     challenges,
+
+		AnonymousSenderID: anonymousSenderID,
+		ReplacesEntryID:   req.ReplacesEntryID,
+	}
+
+	escrow, err := s.newEscrow(entry, []byte(req.Value))
+	if err != nil {
+		return nil, err
+	}
+	if escrow != nil {
+		err = s.entries.CreateWithEscrow(entry, *escrow)
+	} else {
+		err = s.entries.Create(entry)
 	}
-
-	err = s.entries.Create(entry)
 	if err != nil {
 		return nil, err
 	}
-	// TODO: remove
-	fmt.Println(hex.EncodeToString(entry.Nonce))
-
+	if s.usage != nil && entry.SentByUserID != uuid.Nil {
+		if err = s.usage.AddEntryCreated(entry.SentByUserID, now); err != nil {
+			return nil, err
+		}
+	}
+	if resp.Link, err = s.newClaimLink(entry); err != nil {
+		return nil, err
+	}
 	err = s.SendEntry(entry)
 	if err != nil {
 		// TODO: delete entry? attempt to resend?
 		return nil, err
 	}
 
+	s.record("entry.created", map[string]string{
+		"entryId":  entry.ID.String(),
+		"senderId": entry.SentByUserID.String(),
+		"sentTo":   entry.SentToEmail,
+	})
+	s.countFeatures(entry)
+
 	resp.Success = true
 	resp.Entry = &entry
 	return resp, nil
 }
 
+// FreezeWrites stops the service from expiring entries as they're read, so a
+// standby can serve reads from a read-only replica. The primary still expires
+// them. Requests that claim or create entries must be refused by the caller.
+func (s *EntryService) FreezeWrites() {
+	s.frozen = true
+}
+
+// AllowGuests lets entries be created without a sender, lasting up to
+// maxDuration. Guest entries aren't listed for anyone. Each is attributed to
+// an anonymous sender for its client address, stored in senders as an HMAC
+// with key.
+func (s *EntryService) AllowGuests(maxDuration time.Duration, senders AnonymousSenderRepository, key []byte) {
+	s.guestMaxDuration = maxDuration
+	s.anonymousSenders = senders
+	s.addressKey = key
+}
+
+func (s *EntryService) valueTooLarge() Problem {
+	return problem("value_too_large", s.maxValue)
+}
+
 func (s *EntryService) SendEntry(entry sendkey.Entry) error {
 	// TODO: add email client to service and send email
 	return nil
 }
 
+// FindEntry returns the unexpired entry if the nonce matches, or nil
+// otherwise. It returns a NotAvailableError if the entry can't be claimed yet.
 func (s *EntryService) FindEntry(id uuid.UUID, nonce string) (*sendkey.Entry, error) {
 	entry, err := s.entries.Find(id)
-	if err != nil || entry == nil {
-		return entry, err
+	if err != nil || entry == nil || !s.inNamespace(*entry) {
+		return nil, err
 	}
 	if !entry.ExpiresAtUTC.After(time.Now().UTC()) {
 		_, err = s.expireEntry(*entry, false)
 		return nil, err
 	}
 
-	if hex.EncodeToString(entry.Nonce) != nonce {
+	ok, err := s.checkLinkNonce(*entry, nonce)
+	if err != nil || !ok {
+		return nil, err
+	}
+	if err = checkAvailable(*entry, time.Now().UTC()); err != nil {
+		return nil, err
+	}
+
+	entry.Challenges, err = s.entries.FindChallenges(entry.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+// FindSentEntry returns the unexpired entry if it was sent by the user, or
+// nil otherwise. It doesn't need the nonce, so it's only for the sender.
+func (s *EntryService) FindSentEntry(id, senderID uuid.UUID) (*sendkey.Entry, error) {
+	entry, err := s.entries.Find(id)
+	if err != nil || entry == nil {
+		return nil, err
+	}
+	if entry.SentByUserID != senderID || senderID == uuid.Nil || !s.inNamespace(*entry) {
 		return nil, nil
 	}
+	if !entry.ExpiresAtUTC.After(time.Now().UTC()) {
+		_, err = s.expireEntry(*entry, false)
+		return nil, err
+	}
 
 	return entry, nil
 }
 
 func (s *EntryService) FindByUserID(userID uuid.UUID) ([]sendkey.Entry, error) {
+	// guest entries have no sender
+	if userID == uuid.Nil {
+		return []sendkey.Entry{}, nil
+	}
+
 	entries, err := s.entries.FindByUserID(userID)
 	if err != nil {
 		return nil, err
@@ -146,7 +553,13 @@ func (s *EntryService) FindByUserID(userID uuid.UUID) ([]sendkey.Entry, error) {
 	now := time.Now().UTC()
 	result := []sendkey.Entry{}
 	for _, entry := range entries {
+		if !s.inNamespace(entry) {
+			continue
+		}
 		if entry.ExpiresAtUTC.After(now) {
+			if entry.Deferrals, err = s.entries.FindDeferrals(entry.ID); err != nil {
+				return nil, err
+			}
 			result = append(result, entry)
 			continue
 		}
@@ -155,97 +568,284 @@ func (s *EntryService) FindByUserID(userID uuid.UUID) ([]sendkey.Entry, error) {
 			return nil, err
 		}
 	}
+	if err = s.sortFlagged(userID, result); err != nil {
+		return nil, err
+	}
 
 	return result, nil
 }
 
 type DecryptEntryRequest struct {
-	ID     uuid.UUID `json:"id"`
-	Nonce  string    `json:"nonce"`
-	Secret string    `json:"secret"`
+	ID      uuid.UUID `json:"id"`
+	Nonce   string    `json:"nonce"`
+	Secret  string    `json:"secret"`
+	Answers []string  `json:"answers"`
+	// Claimer is the logged-in user claiming the entry, if any. It's
+	// required for entries that require login.
+	Claimer *sendkey.User `json:"-"`
+	// OTP is the one-time code emailed to the recipient, for entries that
+	// require one. Claiming without it sends the code.
+	OTP string `json:"otp"`
+	// Acknowledged is set if the recipient acknowledged the entry's
+	// Acknowledgment text. Entries with one can't be claimed without it.
+	Acknowledged bool `json:"acknowledged"`
+	// ClaimKey identifies the claimer's client, e.g. its session. If it's
+	// set, claiming an entry that's already been claimed says whether it
+	// was claimed with the same key, so a client retrying a claim or racing
+	// another process on the same session knows the value was delivered.
+	ClaimKey string `json:"-"`
 }
 
 type DecryptEntryResponse struct {
-	Success bool           `json:"success"`
-	Errors  []string       `json:"errors"`
-	Expired bool           `json:"expired"`
-	Entry   *sendkey.Entry `json:"entry"`
+	Success bool                  `json:"success"`
+	Errors  []Problem             `json:"errors"`
+	Expired bool                  `json:"expired"`
+	OTPSent bool                  `json:"otpSent"`
+	Entry   *sendkey.Entry        `json:"entry"`
+	Sealed  *SealedValue          `json:"sealed"`
+	Receipt *sendkey.ClaimReceipt `json:"receipt"`
+	// Digest is the sender's digest of the value, if they sent one, for the
+	// recipient's client to check the value against.
+	Digest *sendkey.ValueDigest `json:"digest"`
 }
 
+// DecryptEntry claims the entry if the answers and secret are valid. End-to-end
+// entries don't need a secret; their ciphertext is returned for the client to
+// decrypt instead.
 func (s *EntryService) DecryptEntry(req DecryptEntryRequest) (*DecryptEntryResponse, error) {
 	resp := &DecryptEntryResponse{}
 
 	entry, err := s.FindEntry(req.ID, req.Nonce)
+	if notAvailable, ok := err.(*NotAvailableError); ok {
+		resp.Errors = append(resp.Errors, notAvailable.Problem())
+		return resp, nil
+	}
 	if err != nil {
 		return nil, err
 	}
 	if entry == nil {
-		resp.Errors = append(resp.Errors, "Invalid entry ID.")
-		return resp, nil
-	}
-
-	value, err := s.decrypt(entry.Value, entry.Nonce, []byte(req.Secret))
-	if err != nil {
-		resp.Errors = append(resp.Errors, "Invalid secret.")
-
-		ee, err := s.incrementInvalidAttempts(*entry)
+		p, err := s.alreadyClaimed(req.ID, req.ClaimKey)
 		if err != nil {
 			return nil, err
 		}
+		if p == nil {
+			p = &Problem{Code: "invalid_entry_id"}
+		}
+		resp.Errors = append(resp.Errors, *p)
+		return resp, nil
+	}
+	if p := checkClaimer(*entry, req.Claimer); p != nil {
+		resp.Errors = append(resp.Errors, *p)
+		return resp, nil
+	}
+	if p := checkAcknowledged(*entry, req.Acknowledged); p != nil {
+		resp.Errors = append(resp.Errors, *p)
+		return resp, nil
+	}
+	if !entry.EndToEnd && req.Secret == "" {
+		resp.Errors = append(resp.Errors, problem("secret_required"))
+		return resp, nil
+	}
 
-		if ee != nil {
-			resp.Expired = true
-			resp.Errors = append(resp.Errors, "Too many attempts have been made, and the entry has been expired.")
+	if !answeredChallenges(entry.Challenges, req.Answers) {
+		resp.Errors = append(resp.Errors, problem("challenge_answers_invalid"))
+		return s.failedAttempt(resp, *entry)
+	}
+	if entry.EndToEnd {
+		if stop, err := s.checkOTP(resp, *entry, req.OTP); stop != nil || err != nil {
+			return stop, err
 		}
+		return s.claimSealed(resp, *entry, claim{key: req.ClaimKey, claimer: req.Claimer, method: claimMethodEndToEnd, acknowledged: req.Acknowledged})
+	}
 
-		return resp, nil
+	ciphertext, err := s.entryCiphertext(*entry)
+	if err != nil {
+		return nil, err
+	}
+	key, err := s.deriveKey([]byte(req.Secret), entry.KDF, entry.KeyVersion)
+	if err != nil {
+		return nil, err
+	}
+	value, err := s.decrypt(ciphertext, entry.Nonce, key, entry.Cipher)
+	if err != nil {
+		resp.Errors = append(resp.Errors, problem("secret_invalid"))
+		return s.failedAttempt(resp, *entry)
+	}
+	// the code is checked after the secret, so only someone with the secret
+	// can have one sent
+	if stop, err := s.checkOTP(resp, *entry, req.OTP); stop != nil || err != nil {
+		return stop, err
 	}
 
-	_, err = s.claimEntry(*entry)
+	ce, err := s.claimEntry(*entry, claim{key: req.ClaimKey, claimer: req.Claimer, method: claimMethodSecret, value: value, acknowledged: req.Acknowledged})
 	if err != nil {
+		return s.claimFailed(resp, *entry, req.ClaimKey, err)
+	}
+
+	receipt := s.receipt(*ce, value)
+	if err = s.entries.CreateClaimReceipt(receipt); err != nil {
 		return nil, err
 	}
 
 	entry.Value = value
 	resp.Entry = entry
+	resp.Receipt = &receipt
+	resp.Digest = entry.Digest
 	resp.Success = true
 	return resp, nil
 }
 
-func (s *EntryService) encrypt(value, nonce, secret []byte) ([]byte, error) {
-	key := sha256.Sum256(append(s.aesKey, secret...))
-	block, err := aes.NewCipher(key[:])
-	if err != nil {
+type ReencryptEntriesResponse struct {
+	// KeyVersion is the version the entries were re-encrypted with.
+	KeyVersion  int `json:"keyVersion"`
+	Reencrypted int `json:"reencrypted"`
+	// Legacy is the number of entries that can't be re-encrypted. The legacy
+	// key must be kept until they're claimed or expire.
+	Legacy int `json:"legacy"`
+}
+
+// ReencryptEntries re-wraps every entry that isn't using the current key
+// version, batchSize entries at a time. Afterwards, only the current and
+// legacy keys are needed to read entries.
+func (s *EntryService) ReencryptEntries(batchSize int) (*ReencryptEntriesResponse, error) {
+	current := s.keys.Current()
+	resp := &ReencryptEntriesResponse{KeyVersion: current}
+
+	for {
+		entries, err := s.entries.FindStaleKeyVersion(current, batchSize)
+		if err != nil {
+			return nil, err
+		}
+		if len(entries) == 0 {
+			break
+		}
+
+		for _, e := range entries {
+			ciphertext, err := s.keys.unwrap(e.Value, e.KeyVersion, e.Cipher)
+			if err != nil {
+				return nil, fmt.Errorf("unwrapping entry %s: %w", e.ID, err)
+			}
+			value, err := s.keys.wrap(ciphertext, current, e.Cipher)
+			if err != nil {
+				return nil, err
+			}
+			if err = s.entries.UpdateEncryption(e.ID, value, current); err != nil {
+				return nil, err
+			}
+			resp.Reencrypted++
+		}
+	}
+
+	var err error
+	if resp.Legacy, err = s.entries.CountByKeyVersion(LegacyKeyVersion); err != nil {
 		return nil, err
 	}
 
-	aead, err := cipher.NewGCM(block)
+	return resp, nil
+}
+
+// VerifyReceipt reports whether the receipt's signature was issued by this
+// service with any of its keys.
+func (s *EntryService) VerifyReceipt(r sendkey.ClaimReceipt) bool {
+	for _, v := range s.keys.Versions() {
+		key, _ := s.keys.key(v)
+		expected := receiptSignature(key, r.EntryID, r.ClaimedAtUTC, r.ValueHash)
+		if hmac.Equal([]byte(expected), []byte(r.Signature)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *EntryService) receipt(ce sendkey.ClaimedEntry, value []byte) sendkey.ClaimReceipt {
+	key, _ := s.keys.key(s.keys.Current())
+	r := sendkey.ClaimReceipt{
+		EntryID:   ce.EntryID,
+		ValueHash: receiptValueHash(key, value),
+		// the receipt is stored with second precision, so sign it that way
+		ClaimedAtUTC: ce.ClaimedAtUTC.Truncate(time.Second),
+	}
+	r.Signature = receiptSignature(key, r.EntryID, r.ClaimedAtUTC, r.ValueHash)
+	return r
+}
+
+// receiptValueHash is keyed with the server key, so short values like PINs
+// can't be found from a receipt, or the stored receipts, by hashing guesses.
+func receiptValueHash(serverKey, value []byte) string {
+	key := sha256.Sum256(append([]byte("receipt-value:"), serverKey...))
+	mac := hmac.New(sha256.New, key[:])
+	mac.Write(value)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func receiptSignature(serverKey []byte, entryID uuid.UUID, claimedAt time.Time, valueHash string) string {
+	key := sha256.Sum256(append([]byte("receipt:"), serverKey...))
+	mac := hmac.New(sha256.New, key[:])
+	fmt.Fprintf(mac, "%s|%d|%s", entryID, claimedAt.Unix(), valueHash)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// encrypt seals the value with the service's cipher and a random nonce.
+func (s *EntryService) encrypt(value, key []byte) (nonce, ciphertext []byte, err error) {
+	aead, err := newAEAD(s.cipher, key)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	nonce = make([]byte, aead.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, nil, err
 	}
 
-	return aead.Seal(nil, nonce, value, nil), nil
+	return nonce, aead.Seal(nil, nonce, value, nil), nil
 }
 
-func (s *EntryService) decrypt(value, nonce, secret []byte) ([]byte, error) {
-	key := sha256.Sum256(append(s.aesKey, secret...))
-	block, err := aes.NewCipher(key[:])
+func (s *EntryService) decrypt(value, nonce, key []byte, cipherName string) ([]byte, error) {
+	aead, err := newAEAD(cipherName, key)
 	if err != nil {
 		return nil, err
 	}
+	if len(nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("invalid nonce size")
+	}
+
+	return aead.Open(nil, nonce, value, nil)
+}
 
-	aead, err := cipher.NewGCM(block)
+func (s *EntryService) failedAttempt(resp *DecryptEntryResponse, entry sendkey.Entry) (*DecryptEntryResponse, error) {
+	ee, err := s.incrementInvalidAttempts(entry)
 	if err != nil {
 		return nil, err
 	}
 
-	return aead.Open(nil, nonce, value, nil)
+	if ee != nil {
+		resp.Expired = true
+		resp.Errors = append(resp.Errors, problem("too_many_attempts"))
+	}
+
+	return resp, nil
+}
+
+// answeredChallenges reports whether every challenge has a matching answer
+// at the same position.
+func answeredChallenges(challenges []sendkey.EntryChallenge, answers []string) bool {
+	if len(answers) != len(challenges) {
+		return false
+	}
+
+	for i, c := range challenges {
+		err := bcrypt.CompareHashAndPassword([]byte(c.AnswerHash), []byte(normalizeAnswer(answers[i])))
+		if err != nil {
+			return false
+		}
+	}
+
+	return true
 }
 
-func (s *EntryService) nonce() []byte {
-	b := make([]byte, 12)
-	rand.Read(b)
-	return b
+func normalizeAnswer(answer string) string {
+	return strings.ToLower(strings.Join(strings.Fields(answer), " "))
 }
 
 func (s *EntryService) expireEntry(e sendkey.Entry, tooManyAttempts bool) (*sendkey.ExpiredEntry, error) {
@@ -257,6 +857,10 @@ func (s *EntryService) expireEntry(e sendkey.Entry, tooManyAttempts bool) (*send
 		TooManyAttempts: tooManyAttempts,
 		ExpiredAtUTC:    time.Now().UTC(),
 	}
+	if s.frozen {
+		return &ee, nil
+	}
+
 	err := s.entries.CreateExpiredEntry(ee)
 	if err != nil {
 		return nil, err
@@ -266,7 +870,18 @@ func (s *EntryService) expireEntry(e sendkey.Entry, tooManyAttempts bool) (*send
 	if err != nil {
 		return nil, err
 	}
+	if err = s.discardEscrow(e.ID); err != nil {
+		return nil, err
+	}
 
+	s.record("entry.expired", map[string]string{
+		"entryId":         e.ID.String(),
+		"sentBy":          e.SentByUserID.String(),
+		"tooManyAttempts": strconv.FormatBool(tooManyAttempts),
+	})
+	if tooManyAttempts {
+		s.count("entry.tooManyAttempts")
+	}
 	return &ee, nil
 }
 
@@ -283,13 +898,22 @@ func (s *EntryService) incrementInvalidAttempts(e sendkey.Entry) (*sendkey.Expir
 	return nil, nil
 }
 
-func (s *EntryService) claimEntry(e sendkey.Entry) (*sendkey.ClaimedEntry, error) {
+func (s *EntryService) claimEntry(e sendkey.Entry, c claim) (*sendkey.ClaimedEntry, error) {
 	ce := sendkey.ClaimedEntry{
 		EntryID:      e.ID,
 		Name:         e.Name,
 		SentByUserID: e.SentByUserID,
 		SentToEmail:  e.SentToEmail,
 		ClaimedAtUTC: time.Now().UTC(),
+
+		ReplacesEntryID: e.ReplacesEntryID,
+	}
+	if c.key != "" {
+		ce.ClaimKeyHash = claimKeyHash(c.key)
+	}
+	if c.acknowledged && e.Acknowledgment != "" {
+		at := ce.ClaimedAtUTC
+		ce.Acknowledgment, ce.AcknowledgedAtUTC = e.Acknowledgment, &at
 	}
 	err := s.entries.CreateClaimedEntry(ce)
 	if err != nil {
@@ -300,6 +924,35 @@ func (s *EntryService) claimEntry(e sendkey.Entry) (*sendkey.ClaimedEntry, error
 	if err != nil {
 		return nil, err
 	}
+	if err = s.retainEscrow(ce); err != nil {
+		return nil, err
+	}
 
+	s.record("entry.claimed", s.claimedFields(e, c))
+	if err = s.retireReplaced(e); err != nil {
+		return nil, err
+	}
 	return &ce, nil
 }
+
+// checkClaimer returns a problem if the entry requires login and the claimer
+// isn't the recipient. The email must be verified, since anyone could sign
+// up with the recipient's email otherwise.
+func checkClaimer(e sendkey.Entry, claimer *sendkey.User) *Problem {
+	if !e.RequireLogin {
+		return nil
+	}
+
+	var p Problem
+	switch {
+	case claimer == nil:
+		p = problem("login_required")
+	case !strings.EqualFold(strings.TrimSpace(claimer.Email), e.SentToEmail):
+		p = problem("recipient_mismatch")
+	case !claimer.EmailVerified:
+		p = problem("recipient_unverified")
+	default:
+		return nil
+	}
+	return &p
+}
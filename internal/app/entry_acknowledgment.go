@@ -0,0 +1,19 @@
+package app
+
+import "github.com/gavinwade12/sendkey"
+
+// maxAcknowledgmentLength is the most characters an entry's acknowledgment
+// text can have. It's meant for a sentence or two, shown on the claim page.
+const maxAcknowledgmentLength = 500
+
+// checkAcknowledged returns a problem if the entry has acknowledgment text
+// the recipient hasn't acknowledged. It's checked before anything is
+// decrypted, and doesn't count as a failed attempt. The problem carries the
+// text, so a client that didn't show it can.
+func checkAcknowledged(e sendkey.Entry, acknowledged bool) *Problem {
+	if e.Acknowledgment == "" || acknowledged {
+		return nil
+	}
+	p := problem("acknowledgment_required", e.Acknowledgment).at("acknowledged")
+	return &p
+}
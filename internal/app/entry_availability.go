@@ -0,0 +1,47 @@
+package app
+
+import (
+	"time"
+
+	"github.com/gavinwade12/sendkey"
+)
+
+// NotAvailableError is returned when an entry is found before the time it can
+// be claimed, or with a claim link that no longer works.
+type NotAvailableError struct {
+	AvailableAtUTC time.Time
+	// LinkExpired is set instead when the entry's found with a claim link
+	// that expired or was superseded by a newer one.
+	LinkExpired bool
+}
+
+func (e *NotAvailableError) Error() string {
+	return e.Problem().String()
+}
+
+// Problem returns the error as a problem to show the recipient.
+func (e *NotAvailableError) Problem() Problem {
+	if e.LinkExpired {
+		return problem("claim_link_expired")
+	}
+	return problem("entry_not_available", e.AvailableAtUTC.Format(time.RFC1123))
+}
+
+// checkAvailable returns a NotAvailableError if the entry can't be claimed
+// yet.
+func checkAvailable(entry sendkey.Entry, now time.Time) error {
+	if entry.AvailableAtUTC != nil && entry.AvailableAtUTC.After(now) {
+		return &NotAvailableError{AvailableAtUTC: *entry.AvailableAtUTC}
+	}
+	return nil
+}
+
+// availableAt returns the time an entry created at now becomes available, or
+// nil if it's available right away.
+func availableAt(requested *time.Time, now time.Time) *time.Time {
+	if requested == nil || !requested.After(now) {
+		return nil
+	}
+	t := requested.UTC()
+	return &t
+}
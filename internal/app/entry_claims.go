@@ -0,0 +1,47 @@
+package app
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+
+	"github.com/gavinwade12/sendkey"
+	"github.com/google/uuid"
+)
+
+// alreadyClaimed returns a problem saying whether the entry was claimed with
+// the claim key or by someone else, or nil if the entry hasn't been claimed
+// or no key was sent. Clients that don't send a key get the same response
+// as for an entry that doesn't exist.
+func (s *EntryService) alreadyClaimed(id uuid.UUID, claimKey string) (*Problem, error) {
+	if claimKey == "" {
+		return nil, nil
+	}
+	ce, err := s.entries.FindClaimedEntry(id)
+	if err != nil || ce == nil {
+		return nil, err
+	}
+
+	if ce.ClaimKeyHash != "" && subtle.ConstantTimeCompare([]byte(ce.ClaimKeyHash), []byte(claimKeyHash(claimKey))) == 1 {
+		return &Problem{Code: "entry_claimed_by_you"}, nil
+	}
+	return &Problem{Code: "entry_claimed"}, nil
+}
+
+// claimFailed handles an error claiming the entry. If it was claimed by a
+// concurrent request, the claimer is told who claimed it rather than getting
+// the error; the value was only delivered to the request that won.
+func (s *EntryService) claimFailed(resp *DecryptEntryResponse, e sendkey.Entry, claimKey string, err error) (*DecryptEntryResponse, error) {
+	p, findErr := s.alreadyClaimed(e.ID, claimKey)
+	if findErr != nil || p == nil {
+		return nil, err
+	}
+
+	resp.Errors = append(resp.Errors, *p)
+	return resp, nil
+}
+
+func claimKeyHash(claimKey string) string {
+	sum := sha256.Sum256([]byte(claimKey))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,222 @@
+package app
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+	"time"
+
+	"github.com/gavinwade12/sendkey"
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultDelegationTTL is how long a delegated claim token lasts if the
+	// sender doesn't say, and maxDelegationTTL the longest it can last.
+	// Tokens are for handing to a service that claims the entry right away,
+	// so they're kept short.
+	DefaultDelegationTTL = 15 * time.Minute
+	maxDelegationTTL     = time.Hour
+	delegationTokenBytes = 32
+)
+
+type DelegateClaimRequest struct {
+	EntryID  uuid.UUID `json:"entryId"`
+	SenderID uuid.UUID `json:"-"`
+	Secret   string    `json:"secret"`
+	// TTL is how long the token can be used. It defaults to
+	// DefaultDelegationTTL.
+	TTL time.Duration `json:"ttl"`
+}
+
+type DelegateClaimResponse struct {
+	Success bool      `json:"success"`
+	Errors  []Problem `json:"errors"`
+	// Token claims the entry with ClaimDelegated. It's only returned here;
+	// the service keeps the entry's key encrypted with it, so it can't be
+	// recovered later.
+	Token        string    `json:"token"`
+	ExpiresAtUTC time.Time `json:"expiresAtUtc"`
+}
+
+// DelegateClaim gives the sender a token scoped to one of their entries that
+// a bot or service can claim it with, without the secret. The secret is
+// needed to make the token, and the entry's key is stored encrypted with the
+// token, so the stored delegation alone can't decrypt the entry.
+func (s *EntryService) DelegateClaim(req DelegateClaimRequest) (*DelegateClaimResponse, error) {
+	resp := &DelegateClaimResponse{}
+	if req.TTL < 0 || req.TTL > maxDelegationTTL {
+		resp.Errors = append(resp.Errors, problem("delegation_ttl_invalid", int(maxDelegationTTL.Minutes())))
+		return resp, nil
+	}
+	if req.TTL == 0 {
+		req.TTL = DefaultDelegationTTL
+	}
+
+	entry, err := s.FindSentEntry(req.EntryID, req.SenderID)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		resp.Errors = append(resp.Errors, problem("invalid_entry_id"))
+		return resp, nil
+	}
+	if entry.EndToEnd {
+		resp.Errors = append(resp.Errors, problem("delegation_end_to_end"))
+		return resp, nil
+	}
+	if req.Secret == "" {
+		resp.Errors = append(resp.Errors, problem("secret_required"))
+		return resp, nil
+	}
+
+	ciphertext, err := s.entryCiphertext(*entry)
+	if err != nil {
+		return nil, err
+	}
+	key, err := s.deriveKey([]byte(req.Secret), entry.KDF, entry.KeyVersion)
+	if err != nil {
+		return nil, err
+	}
+	// the sender is signed in, so a wrong secret isn't counted as an attempt
+	// on the entry
+	if _, err = s.decrypt(ciphertext, entry.Nonce, key, entry.Cipher); err != nil {
+		resp.Errors = append(resp.Errors, problem("secret_invalid"))
+		return resp, nil
+	}
+
+	token := make([]byte, delegationTokenBytes)
+	if _, err = rand.Read(token); err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	d := sendkey.EntryDelegation{
+		ID:              s.newID(),
+		EntryID:         entry.ID,
+		CreatedByUserID: req.SenderID,
+		CreatedAtUTC:    now,
+		ExpiresAtUTC:    now.Add(s.scale(req.TTL)),
+	}
+	if d.ExpiresAtUTC.After(entry.ExpiresAtUTC) {
+		d.ExpiresAtUTC = entry.ExpiresAtUTC
+	}
+	aead, err := newAEAD(CipherAESGCM, token)
+	if err != nil {
+		return nil, err
+	}
+	d.KeyNonce = make([]byte, aead.NonceSize())
+	if _, err = rand.Read(d.KeyNonce); err != nil {
+		return nil, err
+	}
+	// the delegation's ID is sealed with the key, so a wrapped key can't be
+	// moved to another delegation
+	d.WrappedKey = aead.Seal(nil, d.KeyNonce, key, d.ID[:])
+	if err = s.entries.CreateDelegation(d); err != nil {
+		return nil, err
+	}
+
+	s.record("entry.delegated", map[string]string{"entryId": entry.ID.String()})
+	resp.Token = d.ID.String() + "." + base64.RawURLEncoding.EncodeToString(token)
+	resp.ExpiresAtUTC = d.ExpiresAtUTC
+	resp.Success = true
+	return resp, nil
+}
+
+// ClaimDelegated claims the entry with a token from DelegateClaim. The sender
+// vouched for the token's holder by making it with the secret, so the
+// entry's challenges, one-time code, acknowledgment, and login requirement
+// don't apply.
+func (s *EntryService) ClaimDelegated(entryID uuid.UUID, token string) (*DecryptEntryResponse, error) {
+	resp := &DecryptEntryResponse{}
+
+	d, tokenKey, err := s.findDelegation(token)
+	if err != nil {
+		return nil, err
+	}
+	if d == nil || d.EntryID != entryID || !d.ExpiresAtUTC.After(time.Now().UTC()) {
+		resp.Errors = append(resp.Errors, problem("delegation_invalid"))
+		return resp, nil
+	}
+
+	entry, err := s.FindSentEntry(d.EntryID, d.CreatedByUserID)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		resp.Errors = append(resp.Errors, problem("invalid_entry_id"))
+		return resp, nil
+	}
+	if err = checkAvailable(*entry, time.Now().UTC()); err != nil {
+		if notAvailable, ok := err.(*NotAvailableError); ok {
+			resp.Errors = append(resp.Errors, notAvailable.Problem())
+			return resp, nil
+		}
+		return nil, err
+	}
+
+	aead, err := newAEAD(CipherAESGCM, tokenKey)
+	if err != nil {
+		return nil, err
+	}
+	key, err := aead.Open(nil, d.KeyNonce, d.WrappedKey, d.ID[:])
+	if err != nil {
+		resp.Errors = append(resp.Errors, problem("delegation_invalid"))
+		return resp, nil
+	}
+	ciphertext, err := s.entryCiphertext(*entry)
+	if err != nil {
+		return nil, err
+	}
+	value, err := s.decrypt(ciphertext, entry.Nonce, key, entry.Cipher)
+	if err != nil {
+		return nil, err
+	}
+
+	ce, err := s.claimEntry(*entry, claim{method: claimMethodDelegation, value: value})
+	if err != nil {
+		return s.claimFailed(resp, *entry, "", err)
+	}
+	receipt := s.receipt(*ce, value)
+	if err = s.entries.CreateClaimReceipt(receipt); err != nil {
+		return nil, err
+	}
+
+	entry.Value = value
+	resp.Entry = entry
+	resp.Receipt = &receipt
+	resp.Digest = entry.Digest
+	resp.Success = true
+	return resp, nil
+}
+
+// findDelegation parses a delegated claim token and finds its delegation,
+// returning nil if the token is malformed or there isn't one.
+func (s *EntryService) findDelegation(token string) (*sendkey.EntryDelegation, []byte, error) {
+	idPart, keyPart, ok := strings.Cut(strings.TrimSpace(token), ".")
+	if !ok {
+		return nil, nil, nil
+	}
+	id, err := uuid.Parse(idPart)
+	if err != nil {
+		return nil, nil, nil
+	}
+	key, err := base64.RawURLEncoding.DecodeString(keyPart)
+	if err != nil || len(key) != delegationTokenBytes {
+		return nil, nil, nil
+	}
+
+	d, err := s.entries.FindDelegation(id)
+	if err != nil || d == nil {
+		return nil, nil, err
+	}
+	return d, key, nil
+}
+
+// entryCiphertext returns the entry's value unwrapped from the server key,
+// ready to decrypt with the key derived from its secret.
+func (s *EntryService) entryCiphertext(e sendkey.Entry) ([]byte, error) {
+	if e.KeyVersion == LegacyKeyVersion {
+		return e.Value, nil
+	}
+	return s.keys.unwrap(e.Value, e.KeyVersion, e.Cipher)
+}
@@ -0,0 +1,37 @@
+package app
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+
+	"github.com/gavinwade12/sendkey"
+	"golang.org/x/crypto/argon2"
+)
+
+// valueDigestArgon2idHMACSHA256 derives the digest key from the secret with
+// Argon2id (3 passes, 64 MiB, 4 threads) and MACs the value with
+// HMAC-SHA256. The cost keeps the digest from being a cheaper way to guess
+// the secret than the value's own encryption.
+const valueDigestArgon2idHMACSHA256 = "argon2id-hmac-sha256"
+
+// validateDigest returns a problem if the digest isn't one the recipient's
+// client can check. Values the server sees are also checked against it, so
+// one corrupted on the way to the server is refused instead of stored.
+func validateDigest(d sendkey.ValueDigest, value, secret string, endToEnd bool) *Problem {
+	if d.Algorithm != valueDigestArgon2idHMACSHA256 || len(d.Salt) < entryKDFSaltLength || len(d.MAC) != sha256.Size {
+		p := problem("value_digest_invalid")
+		return &p
+	}
+	if endToEnd {
+		return nil
+	}
+
+	key := argon2.IDKey([]byte(secret), d.Salt, 3, 64*1024, 4, entryKeyLength)
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(value))
+	if !hmac.Equal(mac.Sum(nil), d.MAC) {
+		p := problem("value_digest_mismatch")
+		return &p
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package app
+
+import (
+	"github.com/gavinwade12/sendkey"
+)
+
+// SealedValue is an entry value encrypted by the sender's client. The server
+// never sees the plaintext or the secret; it stores the ciphertext wrapped
+// with its own key and hands it back, unwrapped, to the recipient's client.
+//
+// The client derives the key from the secret with the KDF, then seals the
+// value with the cipher and nonce. Only the argon2id KDF is accepted.
+type SealedValue struct {
+	Ciphertext []byte           `json:"ciphertext"`
+	Nonce      []byte           `json:"nonce"`
+	Cipher     string           `json:"cipher"`
+	KDF        sendkey.EntryKDF `json:"kdf"`
+}
+
+// validateSealed returns the problems with a sealed value sent by a client.
+func (s *EntryService) validateSealed(v SealedValue) []Problem {
+	var errs []Problem
+
+	aead, err := newAEAD(v.Cipher, make([]byte, entryKeyLength))
+	switch {
+	case !SupportedCipher(v.Cipher) || err != nil:
+		errs = append(errs, problem("cipher_unsupported"))
+	case s.keys.CheckCipher(v.Cipher) != nil:
+		errs = append(errs, problem("cipher_unsupported_by_key"))
+	default:
+		if len(v.Nonce) != aead.NonceSize() {
+			errs = append(errs, problem("nonce_size_invalid"))
+		}
+		if len(v.Ciphertext) <= aead.Overhead() {
+			errs = append(errs, problem("ciphertext_required"))
+		} else if len(v.Ciphertext)-aead.Overhead() > s.maxValue {
+			errs = append(errs, s.valueTooLarge())
+		}
+	}
+
+	if v.KDF.Algorithm != entryKDFArgon2id || len(v.KDF.Salt) == 0 ||
+		v.KDF.Time == 0 || v.KDF.MemoryKiB == 0 || v.KDF.Threads == 0 {
+		errs = append(errs, problem("kdf_invalid"))
+	} else if v.KDF.MemoryKiB > maxKDFMemoryKiB || v.KDF.Time > maxKDFTime || v.KDF.Threads > maxKDFThreads {
+		errs = append(errs, problem("kdf_too_costly", maxKDFMemoryKiB, maxKDFTime, maxKDFThreads))
+	}
+
+	return errs
+}
+
+// claimSealed claims an end-to-end entry, returning its ciphertext for the
+// recipient's client to decrypt. The receipt's hash is of the ciphertext
+// since the server never has the value.
+func (s *EntryService) claimSealed(resp *DecryptEntryResponse, entry sendkey.Entry, c claim) (*DecryptEntryResponse, error) {
+	ciphertext, err := s.keys.unwrap(entry.Value, entry.KeyVersion, entry.Cipher)
+	if err != nil {
+		return nil, err
+	}
+
+	ce, err := s.claimEntry(entry, c)
+	if err != nil {
+		return s.claimFailed(resp, entry, c.key, err)
+	}
+
+	receipt := s.receipt(*ce, ciphertext)
+	if err = s.entries.CreateClaimReceipt(receipt); err != nil {
+		return nil, err
+	}
+
+	entry.Value = nil
+	resp.Entry = &entry
+	resp.Sealed = &SealedValue{
+		Ciphertext: ciphertext,
+		Nonce:      entry.Nonce,
+		Cipher:     entry.Cipher,
+		KDF:        entry.KDF,
+	}
+	resp.Receipt = &receipt
+	resp.Digest = entry.Digest
+	resp.Success = true
+	return resp, nil
+}
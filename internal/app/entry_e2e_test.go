@@ -0,0 +1,51 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/gavinwade12/sendkey"
+)
+
+func TestValidateSealedBoundsKDFCosts(t *testing.T) {
+	keys, err := NewKeyRing(1, map[int][]byte{1: make([]byte, 32)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &EntryService{keys: keys, maxValue: 1024}
+
+	tests := []struct {
+		name string
+		kdf  func(*sendkey.EntryKDF)
+		code string
+	}{
+		{"the default costs", func(*sendkey.EntryKDF) {}, ""},
+		{"the most memory", func(k *sendkey.EntryKDF) { k.MemoryKiB = 1024 * 1024 }, ""},
+		{"more than 1 GiB", func(k *sendkey.EntryKDF) { k.MemoryKiB = 1024*1024 + 1 }, "kdf_too_costly"},
+		{"more than 10 passes", func(k *sendkey.EntryKDF) { k.Time = 11 }, "kdf_too_costly"},
+		{"more than 16 threads", func(k *sendkey.EntryKDF) { k.Threads = 17 }, "kdf_too_costly"},
+		{"no memory", func(k *sendkey.EntryKDF) { k.MemoryKiB = 0 }, "kdf_invalid"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kdf, err := DefaultEntryKeyDerivation().newKDF()
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.kdf(&kdf)
+
+			problems := s.validateSealed(SealedValue{
+				Cipher:     CipherAESGCM,
+				Nonce:      make([]byte, 12),
+				Ciphertext: make([]byte, 32),
+				KDF:        kdf,
+			})
+			if tt.code == "" {
+				if len(problems) != 0 {
+					t.Errorf("got %v, want no problems", problems)
+				}
+			} else if !hasProblem(problems, tt.code) {
+				t.Errorf("got %v, want %s", problems, tt.code)
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package app
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gavinwade12/sendkey"
+	"github.com/google/uuid"
+)
+
+// escrowKeyBytes is the size of the AES-256-GCM key each escrowed value is
+// sealed with.
+const escrowKeyBytes = 32
+
+// Escrow keeps a copy of each new entry's value encrypted to an org's escrow
+// public key, for regulated orgs that have to be able to recover what was
+// transferred. Only the org holds the private key, so the copies are useless
+// to the server and anyone who takes its database.
+//
+// The break-glass procedure is: an admin releases an entry's escrow with a
+// reason, which is audited, and whoever holds the private key decrypts it
+// offline with `sendkey open_escrow`. End-to-end entries are never escrowed,
+// since the server never sees their values, and neither are sandbox entries.
+type Escrow struct {
+	Key *rsa.PublicKey
+	// Retention is how long an escrowed value is kept after its entry's
+	// claimed. An entry that expires or is revoked unclaimed was never
+	// transferred, so its escrow is deleted with it.
+	Retention time.Duration
+}
+
+// ParseEscrowKey parses a PEM-encoded RSA public key in PKIX form, like
+// `openssl rsa -pubout` writes.
+func ParseEscrowKey(b []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, errors.New("escrow key isn't PEM-encoded")
+	}
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := pub.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("escrow key isn't an RSA key")
+	}
+	if key.Size() < 256 {
+		return nil, errors.New("escrow key must be at least 2048 bits")
+	}
+	return key, nil
+}
+
+// EscrowValues turns on escrow for new entries.
+func (s *EntryService) EscrowValues(e Escrow) error {
+	der, err := x509.MarshalPKIXPublicKey(e.Key)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(der)
+	s.escrow = &e
+	s.escrowKeyID = hex.EncodeToString(sum[:])
+	return nil
+}
+
+// newEscrow seals the entry's value to the escrow key, to be stored with the
+// entry until it expires. It's nil if the entry isn't escrowed.
+func (s *EntryService) newEscrow(e sendkey.Entry, value []byte) (*sendkey.EntryEscrow, error) {
+	if s.escrow == nil || e.EndToEnd || e.Sandbox {
+		return nil, nil
+	}
+
+	key := make([]byte, escrowKeyBytes)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(CipherAESGCM, key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	wrapped, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, s.escrow.Key, key, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sendkey.EntryEscrow{
+		EntryID:      e.ID,
+		SentByUserID: e.SentByUserID,
+		SentToEmail:  e.SentToEmail,
+		KeyID:        s.escrowKeyID,
+		WrappedKey:   wrapped,
+		Nonce:        nonce,
+		Ciphertext:   aead.Seal(nil, nonce, value, e.ID[:]),
+		CreatedAtUTC: e.CreatedAtUTC,
+		PurgeAtUTC:   e.ExpiresAtUTC,
+	}, nil
+}
+
+// retainEscrow keeps a claimed entry's escrow for the retention period.
+func (s *EntryService) retainEscrow(ce sendkey.ClaimedEntry) error {
+	if s.escrow == nil {
+		return nil
+	}
+	return s.entries.RetainEscrow(ce.EntryID, ce.ClaimedAtUTC, ce.ClaimedAtUTC.Add(s.escrow.Retention))
+}
+
+// discardEscrow deletes the escrow of an entry that expired or was revoked
+// without being claimed.
+func (s *EntryService) discardEscrow(entryID uuid.UUID) error {
+	if s.escrow == nil {
+		return nil
+	}
+	return s.entries.DeleteEscrow(entryID)
+}
+
+// PurgeEscrows deletes the escrows past their retention, returning how many
+// there were. It runs even with escrow turned off, so the escrows kept from
+// before it was are still purged on time.
+func (s *EntryService) PurgeEscrows() (int64, error) {
+	return s.entries.PurgeEscrows(time.Now().UTC())
+}
+
+type ReleaseEscrowRequest struct {
+	EntryID uuid.UUID `json:"entryId"`
+	AdminID uuid.UUID `json:"-"`
+	// Reason is why the escrow's being released, like a ticket number,
+	// which is recorded in the audit log.
+	Reason string `json:"reason"`
+}
+
+type ReleaseEscrowResponse struct {
+	Success bool                 `json:"success"`
+	Errors  []Problem            `json:"errors"`
+	Escrow  *sendkey.EntryEscrow `json:"escrow"`
+}
+
+// ReleaseEscrow hands an entry's escrow to an admin as part of the break-glass
+// procedure; see Escrow. It's still encrypted to the escrow key, so the admin
+// also needs whoever holds the private key. Every release is audited, and
+// it's refused if the audit record can't be written.
+func (s *EntryService) ReleaseEscrow(req ReleaseEscrowRequest) (*ReleaseEscrowResponse, error) {
+	resp := &ReleaseEscrowResponse{}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" {
+		resp.Errors = append(resp.Errors, problem("escrow_reason_required"))
+		return resp, nil
+	}
+
+	e, err := s.entries.FindEscrow(req.EntryID)
+	if err != nil {
+		return nil, err
+	}
+	if e == nil || !e.PurgeAtUTC.After(time.Now().UTC()) {
+		resp.Errors = append(resp.Errors, problem("escrow_not_found"))
+		return resp, nil
+	}
+
+	err = s.recordFirst("entry.escrowReleased", map[string]string{
+		"entryId": e.EntryID.String(),
+		"adminId": req.AdminID.String(),
+		"reason":  req.Reason,
+		"keyId":   e.KeyID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp.Escrow = e
+	resp.Success = true
+	return resp, nil
+}
@@ -0,0 +1,215 @@
+package app
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/gavinwade12/sendkey"
+	"github.com/google/uuid"
+)
+
+// ExportedEntry is an entry as it's moved between deployments. Its value is
+// still encrypted and wrapped with its key version, so it can only be read by
+// a deployment with the same keys or key provider.
+type ExportedEntry struct {
+	ID              uuid.UUID             `json:"id"`
+	Name            string                `json:"name"`
+	SentByUserID    uuid.UUID             `json:"sentByUserId"`
+	SentToEmail     string                `json:"sentToEmail"`
+	Nonce           []byte                `json:"nonce"`
+	Value           []byte                `json:"value"`
+	InvalidAttempts int                   `json:"invalidAttempts"`
+	Locale          string                `json:"locale"`
+	KDF             sendkey.EntryKDF      `json:"kdf"`
+	KeyVersion      int                   `json:"keyVersion"`
+	Cipher          string                `json:"cipher"`
+	EndToEnd        bool                  `json:"endToEnd"`
+	RequireLogin    bool                  `json:"requireLogin"`
+	RequireOTP      bool                  `json:"requireOtp"`
+	Acknowledgment  string                `json:"acknowledgment,omitempty"`
+	Type            string                `json:"type"`
+	Metadata        sendkey.EntryMetadata `json:"metadata"`
+	CreatedAtUTC    time.Time             `json:"createdAtUtc"`
+	AvailableAtUTC  *time.Time            `json:"availableAtUtc"`
+	ExpiresAtUTC    time.Time             `json:"expiresAtUtc"`
+	Digest          *sendkey.ValueDigest  `json:"digest,omitempty"`
+
+	Challenges []ExportedChallenge `json:"challenges"`
+}
+
+type ExportedChallenge struct {
+	Question   string `json:"question"`
+	AnswerHash string `json:"answerHash"`
+}
+
+// ExportEntries writes every unexpired entry to w as a line of JSON, reading
+// batchSize entries at a time. It returns the number of entries written.
+// Sandbox entries aren't exported.
+func (s *EntryService) ExportEntries(w io.Writer, batchSize int) (int, error) {
+	enc := json.NewEncoder(w)
+	now := time.Now().UTC()
+	after := uuid.Nil
+	count := 0
+
+	for {
+		entries, err := s.entries.FindUnexpired(now, after, batchSize)
+		if err != nil {
+			return count, err
+		}
+		if len(entries) == 0 {
+			return count, nil
+		}
+
+		for _, e := range entries {
+			if e.Sandbox {
+				continue
+			}
+			if e.Challenges, err = s.entries.FindChallenges(e.ID); err != nil {
+				return count, err
+			}
+			if err = enc.Encode(exportEntry(e)); err != nil {
+				return count, err
+			}
+			count++
+		}
+		after = entries[len(entries)-1].ID
+	}
+}
+
+type ImportEntriesResponse struct {
+	Imported int `json:"imported"`
+	// Existing is the number of entries skipped because an entry with the
+	// same ID is already stored, e.g. from an earlier import.
+	Existing int `json:"existing"`
+	// Expired is the number of entries skipped because they expired after
+	// they were exported.
+	Expired int `json:"expired"`
+}
+
+// ImportEntries stores the entries written by ExportEntries. Each entry is
+// checked against this service's keys before it's stored, so an export from
+// a deployment with different keys fails instead of importing entries that
+// can't be claimed. The entries' senders must already exist. No emails are
+// sent; recipients use the links they were sent by the exporting deployment.
+func (s *EntryService) ImportEntries(r io.Reader) (*ImportEntriesResponse, error) {
+	resp := &ImportEntriesResponse{}
+	now := time.Now().UTC()
+
+	scanner := bufio.NewScanner(r)
+	// wrapped values can be larger than the scanner's default line limit
+	scanner.Buffer(nil, 4*s.maxValue+64<<10)
+	for line := 1; scanner.Scan(); line++ {
+		var exported ExportedEntry
+		if err := json.Unmarshal(scanner.Bytes(), &exported); err != nil {
+			return resp, fmt.Errorf("reading line %d: %w", line, err)
+		}
+		e := importEntry(exported)
+
+		if !e.ExpiresAtUTC.After(now) {
+			resp.Expired++
+			continue
+		}
+		if err := s.checkImportKeys(e); err != nil {
+			return resp, fmt.Errorf("entry %s can't be read with this deployment's keys: %w", e.ID, err)
+		}
+
+		existing, err := s.entries.Find(e.ID)
+		if err != nil {
+			return resp, err
+		}
+		if existing != nil {
+			resp.Existing++
+			continue
+		}
+
+		if err = s.entries.Create(e); err != nil {
+			return resp, fmt.Errorf("importing entry %s: %w", e.ID, err)
+		}
+		resp.Imported++
+	}
+	if err := scanner.Err(); err != nil {
+		return resp, err
+	}
+
+	return resp, nil
+}
+
+// checkImportKeys returns an error if the entry isn't wrapped with one of the
+// service's keys. Legacy entries aren't wrapped, so only the legacy key's
+// presence can be checked.
+func (s *EntryService) checkImportKeys(e sendkey.Entry) error {
+	if e.KeyVersion == LegacyKeyVersion {
+		_, err := s.keys.key(LegacyKeyVersion)
+		return err
+	}
+
+	_, err := s.keys.unwrap(e.Value, e.KeyVersion, e.Cipher)
+	return err
+}
+
+func exportEntry(e sendkey.Entry) ExportedEntry {
+	challenges := make([]ExportedChallenge, len(e.Challenges))
+	for i, c := range e.Challenges {
+		challenges[i] = ExportedChallenge(c)
+	}
+
+	return ExportedEntry{
+		ID:              e.ID,
+		Name:            e.Name,
+		SentByUserID:    e.SentByUserID,
+		SentToEmail:     e.SentToEmail,
+		Nonce:           e.Nonce,
+		Value:           e.Value,
+		InvalidAttempts: e.InvalidAttempts,
+		Locale:          e.Locale,
+		KDF:             e.KDF,
+		KeyVersion:      e.KeyVersion,
+		Cipher:          e.Cipher,
+		EndToEnd:        e.EndToEnd,
+		RequireLogin:    e.RequireLogin,
+		RequireOTP:      e.RequireOTP,
+		Acknowledgment:  e.Acknowledgment,
+		Type:            e.Type,
+		Metadata:        e.Metadata,
+		CreatedAtUTC:    e.CreatedAtUTC,
+		AvailableAtUTC:  e.AvailableAtUTC,
+		ExpiresAtUTC:    e.ExpiresAtUTC,
+		Digest:          e.Digest,
+		Challenges:      challenges,
+	}
+}
+
+func importEntry(e ExportedEntry) sendkey.Entry {
+	challenges := make([]sendkey.EntryChallenge, len(e.Challenges))
+	for i, c := range e.Challenges {
+		challenges[i] = sendkey.EntryChallenge(c)
+	}
+
+	return sendkey.Entry{
+		ID:              e.ID,
+		Name:            e.Name,
+		SentByUserID:    e.SentByUserID,
+		SentToEmail:     e.SentToEmail,
+		Nonce:           e.Nonce,
+		Value:           e.Value,
+		InvalidAttempts: e.InvalidAttempts,
+		Locale:          e.Locale,
+		KDF:             e.KDF,
+		KeyVersion:      e.KeyVersion,
+		Cipher:          e.Cipher,
+		EndToEnd:        e.EndToEnd,
+		RequireLogin:    e.RequireLogin,
+		RequireOTP:      e.RequireOTP,
+		Acknowledgment:  e.Acknowledgment,
+		Type:            e.Type,
+		Metadata:        e.Metadata,
+		CreatedAtUTC:    e.CreatedAtUTC,
+		AvailableAtUTC:  e.AvailableAtUTC,
+		ExpiresAtUTC:    e.ExpiresAtUTC,
+		Digest:          e.Digest,
+		Challenges:      challenges,
+	}
+}
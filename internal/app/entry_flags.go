@@ -0,0 +1,84 @@
+package app
+
+import (
+	"sort"
+
+	"github.com/gavinwade12/sendkey"
+	"github.com/google/uuid"
+)
+
+type SetEntryFlagsRequest struct {
+	EntryID uuid.UUID `json:"entryId"`
+	UserID  uuid.UUID `json:"-"`
+	// Pinned and Favorite change the flag if they're set, and leave it as
+	// it is if they're nil.
+	Pinned   *bool `json:"pinned"`
+	Favorite *bool `json:"favorite"`
+}
+
+type SetEntryFlagsResponse struct {
+	Success bool               `json:"success"`
+	Errors  []Problem          `json:"errors"`
+	Flags   sendkey.EntryFlags `json:"flags"`
+}
+
+// SetEntryFlags pins or favorites one of the user's entries, or undoes it.
+// The flags are the user's own and only change how their entries are listed.
+func (s *EntryService) SetEntryFlags(req SetEntryFlagsRequest) (*SetEntryFlagsResponse, error) {
+	resp := &SetEntryFlagsResponse{}
+
+	entry, err := s.FindSentEntry(req.EntryID, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		resp.Errors = append(resp.Errors, problem("invalid_entry_id"))
+		return resp, nil
+	}
+
+	all, err := s.entries.FindFlags(req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	flags := all[entry.ID]
+	if req.Pinned != nil {
+		flags.Pinned = *req.Pinned
+	}
+	if req.Favorite != nil {
+		flags.Favorite = *req.Favorite
+	}
+	if err = s.entries.SaveFlags(req.UserID, entry.ID, flags); err != nil {
+		return nil, err
+	}
+
+	resp.Flags = flags
+	resp.Success = true
+	return resp, nil
+}
+
+// sortFlagged sets the user's flags on their entries and moves the pinned
+// ones first, then the favorites. Otherwise the order is kept.
+func (s *EntryService) sortFlagged(userID uuid.UUID, entries []sendkey.Entry) error {
+	flags, err := s.entries.FindFlags(userID)
+	if err != nil {
+		return err
+	}
+	for i := range entries {
+		entries[i].Flags = flags[entries[i].ID]
+	}
+
+	rank := func(f sendkey.EntryFlags) int {
+		switch {
+		case f.Pinned:
+			return 0
+		case f.Favorite:
+			return 1
+		default:
+			return 2
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return rank(entries[i].Flags) < rank(entries[j].Flags)
+	})
+	return nil
+}
@@ -0,0 +1,72 @@
+package app
+
+import (
+	"time"
+
+	"github.com/gavinwade12/sendkey"
+	"github.com/google/uuid"
+)
+
+// historyPurgeBatch is how many outcomes are archived and deleted at a time.
+const historyPurgeBatch = 1000
+
+// Archiver writes entry outcomes to cold storage before they're purged from
+// the history, like an S3 bucket, so the primary database stays small while
+// the long-term record is kept.
+type Archiver interface {
+	// Archive writes the outcomes, oldest first. Archiving the same batch
+	// again, e.g. after the purge that followed it failed, must replace it
+	// rather than duplicate it.
+	Archive(outcomes []sendkey.EntryOutcome) error
+}
+
+// HistoryRetention limits how long claimed and expired entries stay in the
+// history.
+type HistoryRetention struct {
+	Keep time.Duration
+	// Archive writes the outcomes past Keep to cold storage before they're
+	// purged. Without it, they're deleted.
+	Archive Archiver
+}
+
+// RetainHistory purges outcomes older than the retention's Keep with
+// PurgeHistory.
+func (s *EntryService) RetainHistory(h HistoryRetention) {
+	s.history = &h
+}
+
+// PurgeHistory archives and deletes the outcomes past their retention,
+// returning how many were purged. A batch that can't be archived is left in
+// the history for the next purge, so nothing's deleted before it's archived.
+func (s *EntryService) PurgeHistory() (int64, error) {
+	if s.history == nil || s.history.Keep <= 0 {
+		return 0, nil
+	}
+
+	before := time.Now().UTC().Add(-s.history.Keep)
+	var purged int64
+	for {
+		outcomes, err := s.entries.FindOutcomesBefore(before, historyPurgeBatch)
+		if err != nil || len(outcomes) == 0 {
+			return purged, err
+		}
+		if s.history.Archive != nil {
+			if err = s.history.Archive.Archive(outcomes); err != nil {
+				return purged, err
+			}
+		}
+
+		ids := make([]uuid.UUID, len(outcomes))
+		for i, o := range outcomes {
+			ids[i] = o.EntryID
+		}
+		n, err := s.entries.DeleteOutcomes(ids)
+		purged += n
+		if err != nil {
+			return purged, err
+		}
+		if len(outcomes) < historyPurgeBatch {
+			return purged, nil
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package app
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/gavinwade12/sendkey"
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	entryKDFArgon2id   = "argon2id"
+	entryKDFSaltLength = 16
+	entryKeyLength     = 32
+)
+
+// The most the KDF sent with an end-to-end entry can cost, so a sender can't
+// make the recipient's client, or the server checking it, derive a key with
+// more memory or time than it can spare.
+const (
+	maxKDFMemoryKiB = 1024 * 1024
+	maxKDFTime      = 10
+	maxKDFThreads   = 16
+)
+
+// EntryKeyDerivation is the Argon2id cost used to derive the keys for new
+// entries. Existing entries keep the parameters they were created with.
+type EntryKeyDerivation struct {
+	Time      uint32
+	MemoryKiB uint32
+	Threads   uint8
+}
+
+// DefaultEntryKeyDerivation uses the parameters recommended by RFC 9106 for
+// memory-constrained environments.
+func DefaultEntryKeyDerivation() EntryKeyDerivation {
+	return EntryKeyDerivation{Time: 3, MemoryKiB: 64 * 1024, Threads: 4}
+}
+
+// newKDF returns the KDF parameters for a new entry with a random salt.
+func (d EntryKeyDerivation) newKDF() (sendkey.EntryKDF, error) {
+	salt := make([]byte, entryKDFSaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return sendkey.EntryKDF{}, err
+	}
+
+	return sendkey.EntryKDF{
+		Algorithm: entryKDFArgon2id,
+		Salt:      salt,
+		Time:      d.Time,
+		MemoryKiB: d.MemoryKiB,
+		Threads:   d.Threads,
+	}, nil
+}
+
+// deriveKey returns the AES key for an entry. Entries with no KDF algorithm
+// predate key derivation and use a single SHA-256. Legacy entries mix the
+// legacy server key into the derivation; newer entries are protected by the
+// server key through wrapping instead.
+func (s *EntryService) deriveKey(secret []byte, kdf sendkey.EntryKDF, keyVersion int) ([]byte, error) {
+	input := secret
+	if keyVersion == LegacyKeyVersion {
+		legacy, err := s.keys.key(LegacyKeyVersion)
+		if err != nil {
+			return nil, err
+		}
+		input = make([]byte, 0, len(legacy)+len(secret))
+		input = append(append(input, legacy...), secret...)
+	}
+
+	switch kdf.Algorithm {
+	case "":
+		key := sha256.Sum256(input)
+		return key[:], nil
+	case entryKDFArgon2id:
+		return argon2.IDKey(input, kdf.Salt, kdf.Time, kdf.MemoryKiB, kdf.Threads, entryKeyLength), nil
+	default:
+		return nil, fmt.Errorf("unsupported entry kdf %q", kdf.Algorithm)
+	}
+}
@@ -0,0 +1,164 @@
+package app
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/gavinwade12/sendkey"
+	"github.com/google/uuid"
+)
+
+// claimLinkNonceBytes is the size of each claim link's nonce.
+const claimLinkNonceBytes = 16
+
+// ExpireClaimLinks gives new entries claim links that expire after ttl, or
+// with the entry if it expires sooner. A sender can resend an entry to get a
+// new link, which supersedes the old ones. Entries created before links
+// expired keep being claimed with their original link until one is resent.
+func (s *EntryService) ExpireClaimLinks(ttl time.Duration) {
+	s.linkTTL = ttl
+}
+
+type ResendClaimLinkRequest struct {
+	EntryID  uuid.UUID `json:"entryId"`
+	SenderID uuid.UUID `json:"-"`
+	// TTL is how long the new link lasts. It defaults to the service's link
+	// expiration, or the entry's if links don't expire.
+	TTL time.Duration `json:"ttl"`
+}
+
+type ResendClaimLinkResponse struct {
+	Success bool               `json:"success"`
+	Errors  []Problem          `json:"errors"`
+	Entry   *sendkey.Entry     `json:"entry"`
+	Link    *sendkey.ClaimLink `json:"link"`
+}
+
+// ResendClaimLink issues a new claim link for one of the sender's entries,
+// like when the recipient didn't open the first one in time. Every earlier
+// link to the entry stops working, including its original one.
+func (s *EntryService) ResendClaimLink(req ResendClaimLinkRequest) (*ResendClaimLinkResponse, error) {
+	resp := &ResendClaimLinkResponse{}
+	if req.TTL < 0 {
+		resp.Errors = append(resp.Errors, problem("link_ttl_invalid"))
+		return resp, nil
+	}
+
+	entry, err := s.FindSentEntry(req.EntryID, req.SenderID)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		resp.Errors = append(resp.Errors, problem("invalid_entry_id"))
+		return resp, nil
+	}
+
+	ttl := req.TTL
+	if ttl == 0 {
+		ttl = s.linkTTL
+	}
+	link, err := s.issueClaimLink(*entry, ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	s.record("entry.linkResent", map[string]string{"entryId": entry.ID.String(), "linkId": link.ID.String()})
+	resp.Entry = entry
+	resp.Link = link
+	resp.Success = true
+	return resp, nil
+}
+
+// FindClaimLinks returns the links issued for the entry, newest first. It's
+// for the sender, after finding the entry with FindSentEntry.
+func (s *EntryService) FindClaimLinks(entry sendkey.Entry) ([]sendkey.ClaimLink, error) {
+	links, err := s.entries.FindClaimLinks(entry.ID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	for i, l := range links {
+		if l.Status == sendkey.ClaimLinkActive && !l.ExpiresAtUTC.After(now) {
+			links[i].Status = sendkey.ClaimLinkExpired
+		}
+	}
+	return links, nil
+}
+
+// ActiveClaimLink returns the entry's active link, or nil if it's claimed
+// with its original link.
+func (s *EntryService) ActiveClaimLink(entry sendkey.Entry) (*sendkey.ClaimLink, error) {
+	links, err := s.entries.FindClaimLinks(entry.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range links {
+		if l.Status == sendkey.ClaimLinkActive {
+			return &l, nil
+		}
+	}
+	return nil, nil
+}
+
+// newClaimLink issues the link for a new entry if links expire.
+func (s *EntryService) newClaimLink(entry sendkey.Entry) (*sendkey.ClaimLink, error) {
+	if s.linkTTL == 0 {
+		return nil, nil
+	}
+	return s.issueClaimLink(entry, s.linkTTL)
+}
+
+// issueClaimLink stores a new link for the entry that lasts ttl, superseding
+// its other links. A zero ttl lasts as long as the entry.
+func (s *EntryService) issueClaimLink(entry sendkey.Entry, ttl time.Duration) (*sendkey.ClaimLink, error) {
+	nonce := make([]byte, claimLinkNonceBytes)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	l := sendkey.ClaimLink{
+		ID:           s.newID(),
+		EntryID:      entry.ID,
+		Nonce:        nonce,
+		Status:       sendkey.ClaimLinkActive,
+		CreatedAtUTC: now,
+		ExpiresAtUTC: entry.ExpiresAtUTC,
+	}
+	if ttl > 0 && now.Add(s.scale(ttl)).Before(l.ExpiresAtUTC) {
+		l.ExpiresAtUTC = now.Add(s.scale(ttl))
+	}
+	if err := s.entries.CreateClaimLink(l); err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+// checkLinkNonce reports whether the nonce from a claim link can claim the
+// entry. Once an entry has links, only its active, unexpired link can; until
+// then, its original nonce can. A link that's expired or superseded returns
+// a NotAvailableError, so the recipient knows to ask for a new one.
+func (s *EntryService) checkLinkNonce(entry sendkey.Entry, nonce string) (bool, error) {
+	links, err := s.entries.FindClaimLinks(entry.ID)
+	if err != nil {
+		return false, err
+	}
+	if len(links) == 0 {
+		return hex.EncodeToString(entry.Nonce) == nonce, nil
+	}
+
+	for _, l := range links {
+		if hex.EncodeToString(l.Nonce) != nonce {
+			continue
+		}
+		if l.Status != sendkey.ClaimLinkActive || !l.ExpiresAtUTC.After(time.Now().UTC()) {
+			return false, &NotAvailableError{LinkExpired: true}
+		}
+		return true, nil
+	}
+	if hex.EncodeToString(entry.Nonce) == nonce {
+		return false, &NotAvailableError{LinkExpired: true}
+	}
+	return false, nil
+}
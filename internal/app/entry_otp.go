@@ -0,0 +1,106 @@
+package app
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/gavinwade12/sendkey"
+)
+
+const (
+	// otpLifetime is how long a one-time code can be used after it's sent.
+	otpLifetime = 10 * time.Minute
+	// otpResendAfter keeps repeated claims from flooding the recipient with
+	// codes. Until it passes, the code already sent has to be used.
+	otpResendAfter = time.Minute
+)
+
+// SendOTPs lets entries require a one-time code, emailed to the recipient
+// with the mailer, to be claimed. The code is a second channel alongside the
+// link and secret.
+func (s *EntryService) SendOTPs(mailer Mailer) {
+	s.otpMailer = mailer
+}
+
+// checkOTP verifies the one-time code for an entry that requires one. If no
+// code is given, one is emailed to the recipient. It returns a response if the
+// claim has to stop, and nil if it can go on.
+func (s *EntryService) checkOTP(resp *DecryptEntryResponse, e sendkey.Entry, code string) (*DecryptEntryResponse, error) {
+	if !e.RequireOTP {
+		return nil, nil
+	}
+
+	code = strings.TrimSpace(code)
+	if code == "" {
+		if err := s.sendOTP(e); err != nil {
+			return nil, err
+		}
+		resp.OTPSent = true
+		resp.Errors = append(resp.Errors, problem("otp_required"))
+		return resp, nil
+	}
+
+	otp, err := s.entries.FindOTP(e.ID)
+	if err != nil {
+		return nil, err
+	}
+	if otp == nil || !otp.ExpiresAtUTC.After(time.Now().UTC()) ||
+		subtle.ConstantTimeCompare([]byte(otp.CodeHash), []byte(hashOTP(code))) != 1 {
+		resp.Errors = append(resp.Errors, problem("otp_invalid"))
+		return s.failedAttempt(resp, e)
+	}
+
+	// the code is deleted with the entry when it's claimed
+	return nil, nil
+}
+
+// sendOTP emails a new one-time code to the entry's recipient, unless one was
+// just sent. Sandbox entries get SandboxOTP, and it isn't emailed.
+func (s *EntryService) sendOTP(e sendkey.Entry) error {
+	now := time.Now().UTC()
+	existing, err := s.entries.FindOTP(e.ID)
+	if err != nil {
+		return err
+	}
+	if existing != nil && existing.ExpiresAtUTC.After(now) && now.Sub(existing.CreatedAtUTC) < otpResendAfter {
+		return nil
+	}
+
+	code := SandboxOTP
+	if !s.sandbox {
+		n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+		if err != nil {
+			return err
+		}
+		code = fmt.Sprintf("%06d", n)
+	}
+
+	err = s.entries.SaveOTP(sendkey.EntryOTP{
+		EntryID:      e.ID,
+		CodeHash:     hashOTP(code),
+		CreatedAtUTC: now,
+		ExpiresAtUTC: now.Add(otpLifetime),
+	})
+	if err != nil {
+		return err
+	}
+
+	if s.sandbox {
+		return nil
+	}
+
+	body := fmt.Sprintf("Your code to claim %q with sendkey is %s. It expires in %d minutes.\n\nIf you didn't try to claim this entry, someone else may have its link. Don't share the code.\n",
+		e.Name, code, int(otpLifetime/time.Minute))
+	return s.otpMailer.Send(e.SentToEmail, "Your sendkey claim code", body)
+}
+
+func hashOTP(code string) string {
+	h := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(h[:])
+}
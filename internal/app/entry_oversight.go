@@ -0,0 +1,126 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gavinwade12/sendkey"
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultEntryPageSize is the number of entries or outcomes listed if
+	// the request doesn't say.
+	DefaultEntryPageSize = 100
+	maxEntryPageSize     = 1000
+)
+
+type ListEntriesResponse struct {
+	// Entries are the live entries' metadata. Their values are never
+	// included.
+	Entries []sendkey.Entry `json:"entries"`
+	// Next is the ID to list after for the next page, or nil on the last
+	// page.
+	Next *uuid.UUID `json:"next"`
+}
+
+// ListLiveEntries returns a page of every sender's unexpired entries, ordered
+// by ID, for admins.
+func (s *EntryService) ListLiveEntries(after uuid.UUID, limit int) (*ListEntriesResponse, error) {
+	limit = entryPageSize(limit)
+
+	// one extra entry shows whether there's another page
+	entries, err := s.entries.FindUnexpired(time.Now().UTC(), after, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &ListEntriesResponse{Entries: entries}
+	if len(entries) > limit {
+		resp.Entries = entries[:limit]
+		next := resp.Entries[limit-1].ID
+		resp.Next = &next
+	}
+	for i := range resp.Entries {
+		resp.Entries[i].Nonce = nil
+		resp.Entries[i].Value = nil
+	}
+	return resp, nil
+}
+
+type EntryHistoryRequest struct {
+	Filter sendkey.OutcomeFilter `json:"filter"`
+	// Cursor is the Next cursor from the previous page.
+	Cursor string `json:"cursor"`
+	Limit  int    `json:"limit"`
+}
+
+type EntryHistoryResponse struct {
+	Success  bool                   `json:"success"`
+	Errors   []Problem              `json:"errors"`
+	Outcomes []sendkey.EntryOutcome `json:"outcomes"`
+	// Next is the cursor for the next page, or empty on the last page.
+	Next string `json:"next"`
+}
+
+// EntryHistory returns a page of the entries that have been claimed or have
+// expired across every sender, newest first, for admins following up on a
+// compromised secret.
+func (s *EntryService) EntryHistory(req EntryHistoryRequest) (*EntryHistoryResponse, error) {
+	resp := &EntryHistoryResponse{}
+	limit := entryPageSize(req.Limit)
+	req.Filter.SentToEmail = strings.TrimSpace(req.Filter.SentToEmail)
+
+	var (
+		before   time.Time
+		beforeID uuid.UUID
+	)
+	if req.Cursor != "" {
+		var err error
+		if before, beforeID, err = parseOutcomeCursor(req.Cursor); err != nil {
+			resp.Errors = append(resp.Errors, problem("cursor_invalid"))
+			return resp, nil
+		}
+	}
+
+	outcomes, err := s.entries.FindOutcomes(req.Filter, before, beforeID, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Success = true
+	resp.Outcomes = outcomes
+	if len(outcomes) > limit {
+		resp.Outcomes = outcomes[:limit]
+		last := resp.Outcomes[limit-1]
+		resp.Next = fmt.Sprintf("%d_%s", last.AtUTC.Unix(), last.EntryID)
+	}
+	return resp, nil
+}
+
+func parseOutcomeCursor(cursor string) (time.Time, uuid.UUID, error) {
+	var (
+		unix int64
+		id   string
+	)
+	if _, err := fmt.Sscanf(strings.Replace(cursor, "_", " ", 1), "%d %s", &unix, &id); err != nil {
+		return time.Time{}, uuid.Nil, err
+	}
+	entryID, err := uuid.Parse(id)
+	if err != nil {
+		return time.Time{}, uuid.Nil, err
+	}
+
+	return time.Unix(unix, 0).UTC(), entryID, nil
+}
+
+func entryPageSize(limit int) int {
+	if limit <= 0 {
+		return DefaultEntryPageSize
+	}
+	if limit > maxEntryPageSize {
+		return maxEntryPageSize
+	}
+	return limit
+}
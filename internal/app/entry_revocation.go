@@ -0,0 +1,155 @@
+package app
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gavinwade12/sendkey"
+	"github.com/google/uuid"
+)
+
+type RevokeEntriesResponse struct {
+	Success bool      `json:"success"`
+	Errors  []Problem `json:"errors"`
+	Revoked int       `json:"revoked"`
+	// Notified is the number of recipients emailed about their revoked
+	// entries, and NotifyFailed the number that couldn't be.
+	Notified     int `json:"notified"`
+	NotifyFailed int `json:"notifyFailed"`
+	// OptedOut is the number of recipients who weren't emailed because
+	// they opted out of receiving entries.
+	OptedOut int `json:"optedOut"`
+}
+
+// RevokeEntries expires every active entry matching the filter at once, such
+// as all the entries sent by a compromised account, and emails each affected
+// recipient one notice listing their revoked entries. At least one filter is
+// required so a mistake can't revoke everything. Without a mailer, every
+// notice counts as failed.
+func (s *EntryService) RevokeEntries(filter sendkey.EntryFilter, mailer Mailer) (*RevokeEntriesResponse, error) {
+	resp := &RevokeEntriesResponse{}
+	filter.Type = strings.TrimSpace(filter.Type)
+	filter.RecipientDomain = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(filter.RecipientDomain), "@"))
+
+	if filter == (sendkey.EntryFilter{}) {
+		resp.Errors = append(resp.Errors, problem("revoke_filter_required"))
+		return resp, nil
+	}
+	if filter.Type != "" {
+		resp.Errors = append(resp.Errors, validateEntryType(filter.Type, sendkey.EntryMetadata{})...)
+		if len(resp.Errors) > 0 {
+			return resp, nil
+		}
+	}
+
+	revoked, err := s.entries.Revoke(filter, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	resp.Success = true
+	resp.Revoked = len(revoked)
+	for _, ee := range revoked {
+		if err = s.discardEscrow(ee.EntryID); err != nil {
+			return nil, err
+		}
+		s.record("entry.revoked", map[string]string{"entryId": ee.EntryID.String(), "sentBy": ee.SentByUserID.String()})
+	}
+
+	// the entries are already revoked, so a failed notice doesn't fail the
+	// request; it's counted for the caller to follow up on
+	byRecipient := map[string][]string{}
+	for _, ee := range revoked {
+		to := strings.ToLower(ee.SentToEmail)
+		byRecipient[to] = append(byRecipient[to], ee.Name)
+	}
+	for to, names := range byRecipient {
+		optedOut, err := s.optedOut(to)
+		if err != nil {
+			return nil, err
+		}
+		if optedOut {
+			resp.OptedOut++
+			continue
+		}
+		if mailer == nil {
+			resp.NotifyFailed++
+			continue
+		}
+		sort.Strings(names)
+		if err = mailer.Send(to, "Entries sent to you were revoked", revokedNotice(names)+s.optOutFooter(to)); err != nil {
+			resp.NotifyFailed++
+			continue
+		}
+		resp.Notified++
+	}
+
+	return resp, nil
+}
+
+func revokedNotice(names []string) string {
+	var b strings.Builder
+	b.WriteString("These entries sent to you with sendkey were revoked and can no longer be claimed:\n\n")
+	for _, name := range names {
+		fmt.Fprintf(&b, "  - %s\n", name)
+	}
+	b.WriteString("\nIf you still need them, ask the sender to send them again.\n")
+	return b.String()
+}
+
+// FindAnyEntry returns the entry whatever its sender, for admins. Like
+// FindSentEntry, an entry that's past its expiration is expired instead.
+func (s *EntryService) FindAnyEntry(id uuid.UUID) (*sendkey.Entry, error) {
+	entry, err := s.entries.Find(id)
+	if err != nil || entry == nil {
+		return nil, err
+	}
+	if !entry.ExpiresAtUTC.After(time.Now().UTC()) {
+		_, err = s.expireEntry(*entry, false)
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+// ExpireEntry revokes the entry now so it can't be claimed, e.g. when an
+// admin learns its secret is compromised. It returns nil if there's no active
+// entry with the ID. Unlike RevokeEntries, the recipient isn't notified.
+func (s *EntryService) ExpireEntry(id uuid.UUID) (*sendkey.ExpiredEntry, error) {
+	entry, err := s.FindAnyEntry(id)
+	if err != nil || entry == nil {
+		return nil, err
+	}
+	return s.revokeEntry(*entry, nil)
+}
+
+// revokeEntry moves the entry to the expired entries as revoked, noting the
+// entry that replaced it if that's why.
+func (s *EntryService) revokeEntry(entry sendkey.Entry, replacedBy *uuid.UUID) (*sendkey.ExpiredEntry, error) {
+	ee := sendkey.ExpiredEntry{
+		EntryID:           entry.ID,
+		Name:              entry.Name,
+		SentByUserID:      entry.SentByUserID,
+		SentToEmail:       entry.SentToEmail,
+		Revoked:           true,
+		ExpiredAtUTC:      time.Now().UTC(),
+		ReplacedByEntryID: replacedBy,
+	}
+	if err := s.entries.CreateExpiredEntry(ee); err != nil {
+		return nil, err
+	}
+	if err := s.entries.Delete(entry.ID); err != nil {
+		return nil, err
+	}
+	if err := s.discardEscrow(entry.ID); err != nil {
+		return nil, err
+	}
+
+	if replacedBy != nil {
+		s.record("entry.replaced", map[string]string{"entryId": entry.ID.String(), "sentBy": entry.SentByUserID.String(), "replacedByEntryId": replacedBy.String()})
+	} else {
+		s.record("entry.revoked", map[string]string{"entryId": entry.ID.String(), "sentBy": entry.SentByUserID.String()})
+	}
+	return &ee, nil
+}
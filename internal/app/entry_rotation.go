@@ -0,0 +1,32 @@
+package app
+
+import (
+	"github.com/gavinwade12/sendkey"
+	"github.com/google/uuid"
+)
+
+// checkReplaces returns a problem unless the entry a new one replaces is one
+// of the sender's entries that's still waiting to be claimed.
+func (s *EntryService) checkReplaces(senderID, replacesID uuid.UUID) (*Problem, error) {
+	entry, err := s.FindSentEntry(replacesID, senderID)
+	if err != nil || entry != nil {
+		return nil, err
+	}
+	p := problem("replaced_entry_invalid").at("replacesEntryId")
+	return &p, nil
+}
+
+// retireReplaced revokes the entry the claimed one replaces, so a rotated
+// credential's old value can't be claimed once the new one has been. It's
+// left alone if it was already claimed or has expired.
+func (s *EntryService) retireReplaced(e sendkey.Entry) error {
+	if e.ReplacesEntryID == nil {
+		return nil
+	}
+	old, err := s.entries.Find(*e.ReplacesEntryID)
+	if err != nil || old == nil {
+		return err
+	}
+	_, err = s.revokeEntry(*old, &e.ID)
+	return err
+}
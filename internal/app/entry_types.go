@@ -0,0 +1,32 @@
+package app
+
+import (
+	"github.com/gavinwade12/sendkey"
+)
+
+const maxEntryMetadataLength = 255
+
+// validateEntryType returns the problems with an entry's type and metadata.
+// Each metadata field is only allowed for the types it describes.
+func validateEntryType(entryType string, m sendkey.EntryMetadata) []Problem {
+	var errs []Problem
+
+	switch entryType {
+	case sendkey.EntryTypePassword, sendkey.EntryTypeNote, sendkey.EntryTypeSSHKey, sendkey.EntryTypeEnvFile:
+	default:
+		return append(errs, problem("type_invalid",
+			sendkey.EntryTypePassword, sendkey.EntryTypeNote, sendkey.EntryTypeSSHKey, sendkey.EntryTypeEnvFile).at("type"))
+	}
+
+	if m.Username != "" && entryType != sendkey.EntryTypePassword && entryType != sendkey.EntryTypeSSHKey {
+		errs = append(errs, problem("username_not_allowed").at("metadata.username"))
+	}
+	if m.Hostname != "" && entryType != sendkey.EntryTypeSSHKey {
+		errs = append(errs, problem("hostname_not_allowed").at("metadata.hostname"))
+	}
+	if len(m.Username) > maxEntryMetadataLength || len(m.Hostname) > maxEntryMetadataLength {
+		errs = append(errs, problem("metadata_too_long", maxEntryMetadataLength).at("metadata"))
+	}
+
+	return errs
+}
@@ -0,0 +1,109 @@
+package app
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/gavinwade12/sendkey"
+	"github.com/gavinwade12/sendkey/internal/redact"
+	"github.com/google/uuid"
+)
+
+// Event is a domain event, such as "entry.created" or "user.created",
+// published for downstream pipelines. Its data never holds entries' values
+// or secrets, at most a keyed hash of a claimed value; see HashClaimedValues.
+type Event struct {
+	ID    uuid.UUID         `json:"id"`
+	Type  string            `json:"type"`
+	AtUTC time.Time         `json:"atUtc"`
+	Data  map[string]string `json:"data"`
+}
+
+// EventPublisher publishes events, e.g. to a Kafka topic or NATS subjects.
+type EventPublisher interface {
+	Publish(Event) error
+}
+
+// PublishEvents publishes the service's entry events.
+func (s *EntryService) PublishEvents(p EventPublisher) {
+	s.events = p
+}
+
+// HashClaimedValues adds a hash of each claimed value, keyed with key, to its
+// entry.claimed event. A downstream system that's given the key can tell
+// which credential was handed over by hashing its own copy, without the
+// event ever holding the value, and anyone reading the events without the
+// key can't test guesses against it.
+func (s *EntryService) HashClaimedValues(key []byte) {
+	s.claimedValueKey = key
+}
+
+// PublishEvents publishes the service's user events.
+func (s *UserService) PublishEvents(p EventPublisher) {
+	s.events = p
+}
+
+// publish sends an event to the publisher, if there is one. Like the audit
+// log, the event has already happened, so a failure is only logged.
+func publish(l *slog.Logger, p EventPublisher, eventType string, data map[string]string) {
+	if p == nil {
+		return
+	}
+
+	e := Event{ID: uuid.New(), Type: eventType, AtUTC: time.Now().UTC(), Data: redact.Fields(data)}
+	if err := p.Publish(e); err != nil {
+		l.Error("events: publishing an event", "type", eventType, "data", e.Data, "error", err)
+	}
+}
+
+// The ways an entry can be claimed, as its entry.claimed event's claimMethod.
+const (
+	claimMethodSecret     = "secret"
+	claimMethodDelegation = "delegation"
+	claimMethodEndToEnd   = "endToEnd"
+)
+
+// claim is who claimed an entry and how, for its entry.claimed event.
+type claim struct {
+	// key is the claimer's claim key; see DecryptEntryRequest.ClaimKey.
+	key     string
+	claimer *sendkey.User
+	method  string
+	// value is the claimed value, hashed into the event. It's nil for
+	// end-to-end entries, whose values the server never has.
+	value []byte
+	// acknowledged is set if the claimer acknowledged the entry's
+	// acknowledgment text.
+	acknowledged bool
+}
+
+// claimedFields are the entry.claimed event's fields, describing the claimer
+// and, if HashClaimedValues is on, the value's hash, so downstream systems
+// can reconcile what was handed over to whom.
+func (s *EntryService) claimedFields(e sendkey.Entry, c claim) map[string]string {
+	fields := map[string]string{
+		"entryId":     e.ID.String(),
+		"sentBy":      e.SentByUserID.String(),
+		"sentTo":      e.SentToEmail,
+		"type":        e.Type,
+		"claimMethod": c.method,
+		"otpVerified": strconv.FormatBool(e.RequireOTP),
+	}
+	if c.claimer != nil {
+		fields["claimerId"] = c.claimer.ID.String()
+	}
+	if c.acknowledged && e.Acknowledgment != "" {
+		fields["acknowledgment"] = e.Acknowledgment
+	}
+	if c.value != nil && s.claimedValueKey != nil {
+		mac := hmac.New(sha256.New, s.claimedValueKey)
+		mac.Write(c.value)
+		fields["valueHash"] = hex.EncodeToString(mac.Sum(nil))
+		fields["valueHashAlgorithm"] = "hmac-sha256"
+	}
+	return fields
+}
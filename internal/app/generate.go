@@ -0,0 +1,205 @@
+package app
+
+import (
+	"crypto/rand"
+	_ "embed"
+	"math/big"
+	"strings"
+
+	"github.com/gavinwade12/sendkey"
+)
+
+// The character sets generated passwords can be drawn from.
+const (
+	CharsetAlphanumeric = "alphanumeric"
+	CharsetSymbols      = "symbols"
+	CharsetDigits       = "digits"
+	CharsetHex          = "hex"
+)
+
+var charsets = map[string]string{
+	CharsetAlphanumeric: "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789",
+	CharsetSymbols:      "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789!#$%&()*+,-./:;<=>?@[]^_{|}~",
+	CharsetDigits:       "0123456789",
+	CharsetHex:          "0123456789abcdef",
+}
+
+const (
+	defaultSecretLength = 24
+	minSecretLength     = 8
+	maxSecretLength     = 1024
+	minPassphraseWords  = 3
+	maxPassphraseWords  = 64
+)
+
+//go:embed wordlist.txt
+var wordlistText string
+
+// wordlist holds the words passphrases are made of, one per line.
+var wordlist = strings.Fields(wordlistText)
+
+// GeneratePolicy describes a random password or passphrase.
+type GeneratePolicy struct {
+	// Length is the number of characters in a password. It defaults to 24.
+	Length int `json:"length"`
+	// Charset is the set of characters a password is drawn from; see the
+	// Charset constants. It defaults to alphanumeric.
+	Charset string `json:"charset"`
+	// Words is the number of words in a passphrase. If it's set, a
+	// passphrase is generated instead of a password.
+	Words int `json:"words"`
+	// Separator joins the words of a passphrase. It defaults to "-".
+	Separator string `json:"separator"`
+}
+
+type GenerateSecretRequest struct {
+	GeneratePolicy
+
+	// Entry, if set, is created with the generated value in the same call.
+	// Its Value must be empty.
+	Entry *CreateEntryRequest `json:"entry"`
+}
+
+type GenerateSecretResponse struct {
+	Success bool               `json:"success"`
+	Errors  []Problem          `json:"errors"`
+	Value   string             `json:"value"`
+	Entry   *sendkey.Entry     `json:"entry"`
+	Link    *sendkey.ClaimLink `json:"link"`
+}
+
+// GenerateSecret returns a cryptographically random password or passphrase,
+// optionally creating an entry with it.
+func (s *EntryService) GenerateSecret(req GenerateSecretRequest) (*GenerateSecretResponse, error) {
+	resp := &GenerateSecretResponse{}
+	var v Validator
+	v.Add("", req.GeneratePolicy.validate("")...)
+	if req.Entry != nil {
+		v.Check(req.Entry.Value == "" && req.Entry.Generate == nil, "entry.value", "generated_value_conflict")
+		v.Check(req.Entry.EndToEnd == nil, "entry.endToEnd", "end_to_end_generated")
+	}
+	if !v.Valid() {
+		resp.Errors = v.Problems()
+		return resp, nil
+	}
+
+	var err error
+	if resp.Value, err = req.GeneratePolicy.generate(); err != nil {
+		return nil, err
+	}
+
+	if req.Entry == nil {
+		resp.Success = true
+		return resp, nil
+	}
+
+	entryReq := *req.Entry
+	entryReq.Value = resp.Value
+	created, err := s.CreateEntry(entryReq)
+	if err != nil {
+		return nil, err
+	}
+	if !created.Success {
+		resp.Value = ""
+		for _, p := range created.Errors {
+			if p.Field != "" {
+				p.Field = "entry." + p.Field
+			}
+			resp.Errors = append(resp.Errors, p)
+		}
+		return resp, nil
+	}
+
+	resp.Success = true
+	resp.Entry = created.Entry
+	resp.Link = created.Link
+	return resp, nil
+}
+
+// generateValue sets the request's value to one generated with its policy,
+// unless the request has problems.
+func generateValue(req *CreateEntryRequest) ([]Problem, error) {
+	var v Validator
+	v.Add("", req.Generate.validate("generate.")...)
+	v.Check(req.Value == "", "value", "generated_value_conflict")
+	v.Check(req.EndToEnd == nil, "endToEnd", "end_to_end_generated")
+	// the sender's client never has the value to digest
+	v.Check(req.Digest == nil, "digest", "digest_generated")
+	if !v.Valid() {
+		return v.Problems(), nil
+	}
+
+	var err error
+	req.Value, err = req.Generate.generate()
+	return nil, err
+}
+
+// validate returns the policy's problems, filling in its defaults. Their
+// fields are prefixed with the policy's field in the request.
+func (p *GeneratePolicy) validate(prefix string) []Problem {
+	if p.Length == 0 && p.Words == 0 {
+		p.Length = defaultSecretLength
+	}
+	if p.Charset == "" {
+		p.Charset = CharsetAlphanumeric
+	}
+	if p.Separator == "" {
+		p.Separator = "-"
+	}
+
+	var v Validator
+	switch {
+	case p.Length != 0 && p.Words != 0:
+		v.Add(prefix+"words", problem("length_and_words"))
+	case p.Words != 0:
+		v.Check(p.Words >= minPassphraseWords && p.Words <= maxPassphraseWords, prefix+"words", "words_invalid", minPassphraseWords, maxPassphraseWords)
+	default:
+		v.Check(p.Length >= minSecretLength && p.Length <= maxSecretLength, prefix+"length", "length_invalid", minSecretLength, maxSecretLength)
+	}
+	_, ok := charsets[p.Charset]
+	v.Check(ok, prefix+"charset", "charset_invalid", CharsetAlphanumeric, CharsetSymbols, CharsetDigits, CharsetHex)
+	return v.Problems()
+}
+
+// generate returns a value following the policy, which must be valid.
+func (p GeneratePolicy) generate() (string, error) {
+	if p.Words > 0 {
+		return randomPassphrase(p.Words, p.Separator)
+	}
+	return randomPassword(p.Length, charsets[p.Charset])
+}
+
+func randomPassword(length int, chars string) (string, error) {
+	b := make([]byte, length)
+	for i := range b {
+		n, err := randomIndex(len(chars))
+		if err != nil {
+			return "", err
+		}
+		b[i] = chars[n]
+	}
+
+	return string(b), nil
+}
+
+func randomPassphrase(words int, separator string) (string, error) {
+	ws := make([]string, words)
+	for i := range ws {
+		n, err := randomIndex(len(wordlist))
+		if err != nil {
+			return "", err
+		}
+		ws[i] = wordlist[n]
+	}
+
+	return strings.Join(ws, separator), nil
+}
+
+// randomIndex returns a uniformly random index into a list of n items.
+func randomIndex(n int) (int, error) {
+	i, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, err
+	}
+	return int(i.Int64()), nil
+}
@@ -0,0 +1,142 @@
+package app
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"fmt"
+	"sort"
+)
+
+// LegacyKeyVersion identifies entries from before key versioning. Their
+// server key is mixed into the key derivation rather than wrapping the
+// ciphertext, so they can't be re-encrypted and must be left to expire.
+const LegacyKeyVersion = 0
+
+// KeyRing holds the server's AES keys by version. New entries are wrapped with
+// the current key; older versions are kept so existing entries can still be
+// read until they're re-encrypted.
+type KeyRing struct {
+	keys    map[int][]byte
+	current int
+}
+
+// NewKeyRing returns a key ring using the current version for new entries.
+// Every key must be 16, 24, or 32 bytes to select AES-128, AES-192, or AES-256,
+// except the LegacyKeyVersion key. It's only mixed into the legacy entries'
+// key derivation, so it can be any length, and it can't be current.
+func NewKeyRing(current int, keys map[int][]byte) (*KeyRing, error) {
+	if current == LegacyKeyVersion {
+		return nil, fmt.Errorf("key version %d is reserved for legacy entries", LegacyKeyVersion)
+	}
+	if _, ok := keys[current]; !ok {
+		return nil, fmt.Errorf("no key for the current key version %d", current)
+	}
+	for v, k := range keys {
+		if v == LegacyKeyVersion {
+			continue
+		}
+		if _, err := aes.NewCipher(k); err != nil {
+			return nil, fmt.Errorf("key version %d: %w", v, err)
+		}
+	}
+
+	return &KeyRing{keys, current}, nil
+}
+
+// KeyProvider unwraps keys that are stored encrypted, e.g. by a KMS, so the
+// plaintext keys never have to be written to config.
+type KeyProvider interface {
+	UnwrapKey(wrapped string) ([]byte, error)
+}
+
+// LoadKeyRing unwraps every key with the provider and returns a key ring using
+// the current version for new entries. The legacy key, if there is one, was
+// never wrapped, so it's added as it is.
+func LoadKeyRing(p KeyProvider, current int, wrapped map[int]string, legacy []byte) (*KeyRing, error) {
+	keys := make(map[int][]byte, len(wrapped)+1)
+	if len(legacy) > 0 {
+		keys[LegacyKeyVersion] = legacy
+	}
+	for v, w := range wrapped {
+		if v == LegacyKeyVersion {
+			return nil, fmt.Errorf("key version %d is reserved for legacy entries", LegacyKeyVersion)
+		}
+		k, err := p.UnwrapKey(w)
+		if err != nil {
+			return nil, fmt.Errorf("unwrapping key version %d: %w", v, err)
+		}
+		keys[v] = k
+	}
+
+	return NewKeyRing(current, keys)
+}
+
+// Current returns the version used for new entries.
+func (k *KeyRing) Current() int {
+	return k.current
+}
+
+// CheckCipher returns an error if new entries can't be wrapped with the cipher
+// using the current key, e.g. because the key is too short.
+func (k *KeyRing) CheckCipher(cipherName string) error {
+	_, err := k.aead(k.current, cipherName)
+	return err
+}
+
+// Versions returns every version in the ring in ascending order.
+func (k *KeyRing) Versions() []int {
+	versions := make([]int, 0, len(k.keys))
+	for v := range k.keys {
+		versions = append(versions, v)
+	}
+	sort.Ints(versions)
+	return versions
+}
+
+func (k *KeyRing) key(version int) ([]byte, error) {
+	key, ok := k.keys[version]
+	if !ok {
+		return nil, fmt.Errorf("no key for key version %d", version)
+	}
+	return key, nil
+}
+
+// wrap encrypts the entry ciphertext with the key version, using the same
+// cipher as the entry. The result is the random nonce followed by the sealed
+// ciphertext.
+func (k *KeyRing) wrap(ciphertext []byte, version int, cipherName string) ([]byte, error) {
+	aead, err := k.aead(version, cipherName)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(ciphertext)+aead.Overhead())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, ciphertext, nil), nil
+}
+
+func (k *KeyRing) unwrap(wrapped []byte, version int, cipherName string) ([]byte, error) {
+	aead, err := k.aead(version, cipherName)
+	if err != nil {
+		return nil, err
+	}
+	if len(wrapped) < aead.NonceSize() {
+		return nil, fmt.Errorf("wrapped value is too short")
+	}
+
+	n := aead.NonceSize()
+	return aead.Open(nil, wrapped[:n], wrapped[n:], nil)
+}
+
+func (k *KeyRing) aead(version int, cipherName string) (cipher.AEAD, error) {
+	key, err := k.key(version)
+	if err != nil {
+		return nil, err
+	}
+
+	return newAEAD(cipherName, key)
+}
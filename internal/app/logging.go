@@ -0,0 +1,29 @@
+package app
+
+import "log/slog"
+
+// LogTo sets the logger for the service's background failures, like an event
+// that couldn't be published. It defaults to slog's default logger.
+func (s *EntryService) LogTo(l *slog.Logger) {
+	s.logger = l
+}
+
+// LogTo sets the logger for the service's background failures. It defaults
+// to slog's default logger.
+func (s *UserService) LogTo(l *slog.Logger) {
+	s.logger = l
+}
+
+func (s *EntryService) log() *slog.Logger {
+	if s.logger == nil {
+		return slog.Default()
+	}
+	return s.logger
+}
+
+func (s *UserService) log() *slog.Logger {
+	if s.logger == nil {
+		return slog.Default()
+	}
+	return s.logger
+}
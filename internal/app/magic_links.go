@@ -0,0 +1,264 @@
+package app
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gavinwade12/sendkey"
+	"github.com/google/uuid"
+)
+
+type MagicLinkRepository interface {
+	Create(sendkey.MagicLink) error
+	Find(uuid.UUID) (*sendkey.MagicLink, error)
+	// Delete deletes the link, reporting whether it was there to delete, so
+	// only one of two redemptions racing for a link can use it.
+	Delete(uuid.UUID) (bool, error)
+}
+
+// Mailer sends plain text email.
+type Mailer interface {
+	Send(to, subject, body string) error
+}
+
+type MagicLinkService struct {
+	users    *UserService
+	links    MagicLinkRepository
+	mailer   Mailer
+	key      []byte
+	baseURL  string
+	lifetime time.Duration
+}
+
+// The key argument is used to sign the link codes. The baseURL argument is the
+// public URL the emailed link should point at; the code is appended to it.
+// Without a mailer, magic links are disabled.
+func NewMagicLinkService(users *UserService, links MagicLinkRepository, mailer Mailer, key []byte, baseURL string, lifetime time.Duration) *MagicLinkService {
+	return &MagicLinkService{users, links, mailer, key, strings.TrimSuffix(baseURL, "/"), lifetime}
+}
+
+type SendMagicLinkResponse struct {
+	Success bool      `json:"success"`
+	Errors  []Problem `json:"errors"`
+}
+
+// Send emails a login link to the user with the email. To avoid revealing
+// which emails have accounts, it succeeds whether or not there's a user.
+func (s *MagicLinkService) Send(email string) (*SendMagicLinkResponse, error) {
+	resp := &SendMagicLinkResponse{}
+	if s.mailer == nil {
+		resp.Errors = append(resp.Errors, problem("magic_links_unavailable"))
+		return resp, nil
+	}
+
+	email = strings.TrimSpace(email)
+	if email == "" {
+		resp.Errors = append(resp.Errors, problem("email_required"))
+		return resp, nil
+	}
+	// users in an org that requires SSO can't log in without it. This only
+	// depends on the email's domain, so it doesn't reveal whether there's a
+	// user with the email.
+	if p := s.users.ssoRequired(email); p != nil {
+		resp.Errors = append(resp.Errors, *p)
+		return resp, nil
+	}
+
+	user, err := s.users.users.FindByEmail(email)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil || user.DeactivatedAtUTC != nil || user.DisabledAtUTC != nil {
+		resp.Success = true
+		return resp, nil
+	}
+
+	now := time.Now().UTC()
+	link := sendkey.MagicLink{
+		ID:           newID(s.users.ids),
+		UserID:       user.ID,
+		CreatedAtUTC: now,
+		ExpiresAtUTC: now.Add(s.lifetime),
+	}
+	if err = s.links.Create(link); err != nil {
+		return nil, err
+	}
+
+	code := s.code(link.ID)
+	body := fmt.Sprintf("Use the link below to log in to sendkey. It can only be used once and expires in %d minutes.\n\n%s/login/magic/%s\n\n"+
+		"If you're using the CLI, run:\n\n    sendkey login_magic --code %s\n\nIf you didn't request this, you can ignore this email.\n",
+		int(s.lifetime.Minutes()), s.baseURL, code, code)
+	if err = s.mailer.Send(user.Email, "Your sendkey login link", body); err != nil {
+		return nil, err
+	}
+
+	resp.Success = true
+	return resp, nil
+}
+
+// Valid reports whether the code is one the service signed, without using up
+// its link, so a page can be shown for it before it's redeemed.
+func (s *MagicLinkService) Valid(code string) bool {
+	_, ok := s.verifyCode(code)
+	return ok
+}
+
+type RedeemMagicLinkRequest struct {
+	Code    string
+	MFACode string
+	// NewPassword sets the user's password, which they must do if an admin
+	// reset it.
+	NewPassword string
+	// Link is an external identity to link to the user once they've logged
+	// in; see UserLoginRequest.Link.
+	Link *ExternalLoginRequest
+}
+
+// Redeem exchanges the code from a magic link for a login. Links are single
+// use; the link is deleted whether or not the login succeeds, unless it's
+// refused for a missing or invalid new password, so the user can try again.
+// Users with MFA enabled must still provide an MFA code, and users in an org
+// that requires SSO are refused.
+func (s *MagicLinkService) Redeem(req RedeemMagicLinkRequest) (*UserLoginResponse, error) {
+	resp := &UserLoginResponse{}
+
+	id, ok := s.verifyCode(req.Code)
+	if !ok {
+		resp.Errors = append(resp.Errors, problem("magic_link_invalid"))
+		return resp, nil
+	}
+
+	var newPassword string
+	if req.NewPassword != "" {
+		var violations []PasswordViolation
+		var err error
+		newPassword, violations, err = s.users.hashNewPassword(req.NewPassword)
+		if err != nil {
+			return nil, err
+		}
+		if len(violations) > 0 {
+			for _, v := range violations {
+				resp.Errors = append(resp.Errors, v.Problem.at("newPassword"))
+			}
+			return resp, nil
+		}
+	}
+
+	link, err := s.links.Find(id)
+	if err != nil {
+		return nil, err
+	}
+	if link == nil {
+		resp.Errors = append(resp.Errors, problem("magic_link_invalid"))
+		return resp, nil
+	}
+	user, err := s.users.FindUser(link.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user != nil && passwordResetPending(*user) && newPassword == "" {
+		resp.Errors = append(resp.Errors, problem("password_reset_required").at("newPassword"))
+		return resp, nil
+	}
+
+	deleted, err := s.links.Delete(link.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !deleted {
+		// another redemption used the link first
+		resp.Errors = append(resp.Errors, problem("magic_link_invalid"))
+		return resp, nil
+	}
+	if !link.ExpiresAtUTC.After(time.Now().UTC()) {
+		resp.Errors = append(resp.Errors, problem("magic_link_expired"))
+		return resp, nil
+	}
+	if user == nil {
+		resp.Errors = append(resp.Errors, problem("magic_link_invalid"))
+		return resp, nil
+	}
+	if user.DeactivatedAtUTC != nil {
+		resp.Errors = append(resp.Errors, problem(errDeactivated))
+		return resp, nil
+	}
+	if user.DisabledAtUTC != nil {
+		resp.Errors = append(resp.Errors, problem(errDisabled))
+		return resp, nil
+	}
+	// the user may have been sent the link before their org required SSO.
+	// Linking an identity from the SSO provider is allowed, as in Login.
+	if p := s.users.ssoRequired(user.Email); p != nil && req.Link == nil {
+		resp.Errors = append(resp.Errors, *p)
+		return resp, nil
+	}
+
+	if user.MFAEnabled {
+		ok, err := s.users.verifyMFA(*user, req.MFACode)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			resp.Errors = append(resp.Errors, problem("mfa_code_invalid"))
+			return resp, nil
+		}
+	}
+	if req.Link != nil {
+		if p, err := s.users.linkIdentity(*user, *req.Link); err != nil {
+			return nil, err
+		} else if p != nil {
+			resp.Errors = append(resp.Errors, *p)
+			return resp, nil
+		}
+	}
+
+	// redeeming the link proves the user controls the email, which is also
+	// the proof needed to set a password without the current one
+	if !user.EmailVerified || newPassword != "" {
+		user.EmailVerified = true
+		if newPassword != "" {
+			user.Password = newPassword
+		}
+		if err = s.users.users.Update(*user); err != nil {
+			return nil, err
+		}
+	}
+
+	resp.User = user
+	resp.Success = true
+	return resp, nil
+}
+
+// code returns the link ID along with its signature.
+func (s *MagicLinkService) code(id uuid.UUID) string {
+	return hex.EncodeToString(id[:]) + "." + s.signature(id)
+}
+
+func (s *MagicLinkService) verifyCode(code string) (uuid.UUID, bool) {
+	parts := strings.SplitN(strings.TrimSpace(code), ".", 2)
+	if len(parts) != 2 {
+		return uuid.Nil, false
+	}
+
+	b, err := hex.DecodeString(parts[0])
+	if err != nil {
+		return uuid.Nil, false
+	}
+	id, err := uuid.FromBytes(b)
+	if err != nil {
+		return uuid.Nil, false
+	}
+
+	return id, hmac.Equal([]byte(parts[1]), []byte(s.signature(id)))
+}
+
+func (s *MagicLinkService) signature(id uuid.UUID) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte("magic-link:"))
+	mac.Write(id[:])
+	return hex.EncodeToString(mac.Sum(nil))
+}
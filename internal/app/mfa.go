@@ -0,0 +1,242 @@
+package app
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gavinwade12/sendkey"
+	"github.com/google/uuid"
+)
+
+const (
+	recoveryCodeCount = 10
+	totpPeriod        = 30 * time.Second
+	totpDigits        = 6
+)
+
+type RecoveryCodeRepository interface {
+	Create(sendkey.RecoveryCode) error
+	// DeleteByUserAndHash deletes the user's code with the hash and reports
+	// whether there was one, so concurrent logins can't both use a code.
+	DeleteByUserAndHash(userID uuid.UUID, codeHash string) (bool, error)
+	DeleteByUserID(uuid.UUID) error
+}
+
+type EnableMFAResponse struct {
+	Success bool      `json:"success"`
+	Errors  []Problem `json:"errors"`
+	Secret  string    `json:"secret"`
+}
+
+// EnableMFA generates a new TOTP secret for the user to add to their
+// authenticator. MFA isn't enabled until ConfirmMFA checks a code from it,
+// so a secret that didn't make it into the authenticator can't lock the
+// user out. Calling it again before then replaces the secret.
+func (s *UserService) EnableMFA(userID uuid.UUID) (*EnableMFAResponse, error) {
+	resp := &EnableMFAResponse{}
+
+	user, err := s.users.Find(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		resp.Errors = append(resp.Errors, problem("invalid_user_id"))
+		return resp, nil
+	}
+	if user.MFAEnabled {
+		resp.Errors = append(resp.Errors, problem("mfa_enabled"))
+		return resp, nil
+	}
+
+	secret := make([]byte, 20)
+	if _, err = rand.Read(secret); err != nil {
+		return nil, err
+	}
+	user.MFASecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(secret)
+	if err = s.users.Update(*user); err != nil {
+		return nil, err
+	}
+
+	resp.Success = true
+	resp.Secret = user.MFASecret
+	return resp, nil
+}
+
+type ConfirmMFARequest struct {
+	UserID uuid.UUID `json:"-"`
+	Code   string    `json:"code"`
+}
+
+type ConfirmMFAResponse struct {
+	Success       bool      `json:"success"`
+	Errors        []Problem `json:"errors"`
+	RecoveryCodes []string  `json:"recoveryCodes"`
+}
+
+// ConfirmMFA enables MFA once the user proves they have the secret from
+// EnableMFA with a code from it, and returns a fresh set of recovery codes.
+// The plaintext codes are only ever returned here and from
+// RegenerateRecoveryCodes; only their hashes are stored.
+func (s *UserService) ConfirmMFA(req ConfirmMFARequest) (*ConfirmMFAResponse, error) {
+	resp := &ConfirmMFAResponse{}
+
+	user, err := s.users.Find(req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		resp.Errors = append(resp.Errors, problem("invalid_user_id"))
+		return resp, nil
+	}
+	if user.MFAEnabled {
+		resp.Errors = append(resp.Errors, problem("mfa_enabled"))
+		return resp, nil
+	}
+	if user.MFASecret == "" {
+		resp.Errors = append(resp.Errors, problem("mfa_not_started"))
+		return resp, nil
+	}
+
+	ok, err := s.verifyTOTP(*user, req.Code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		resp.Errors = append(resp.Errors, problem("mfa_code_invalid").at("code"))
+		return resp, nil
+	}
+
+	user.MFAEnabled = true
+	if err = s.users.Update(*user); err != nil {
+		return nil, err
+	}
+	if resp.RecoveryCodes, err = s.RegenerateRecoveryCodes(user.ID); err != nil {
+		return nil, err
+	}
+
+	resp.Success = true
+	return resp, nil
+}
+
+// RegenerateRecoveryCodes invalidates any existing recovery codes for the user
+// and returns a new set.
+func (s *UserService) RegenerateRecoveryCodes(userID uuid.UUID) ([]string, error) {
+	if err := s.recoveryCodes.DeleteByUserID(userID); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	codes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		code := hex.EncodeToString(b)
+		codes[i] = code[:5] + "-" + code[5:]
+
+		err := s.recoveryCodes.Create(sendkey.RecoveryCode{
+			ID:           newID(s.ids),
+			UserID:       userID,
+			CodeHash:     hashRecoveryCode(codes[i]),
+			CreatedAtUTC: now,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return codes, nil
+}
+
+// checkMFA returns what's wrong with the MFA code if the user has MFA
+// enabled, or nil if they can log in.
+func (s *UserService) checkMFA(user sendkey.User, code string) (*Problem, error) {
+	if !user.MFAEnabled {
+		return nil, nil
+	}
+	if strings.TrimSpace(code) == "" {
+		p := problem("mfa_code_required").at("mfaCode")
+		return &p, nil
+	}
+	ok, err := s.verifyMFA(user, code)
+	if err != nil || ok {
+		return nil, err
+	}
+	p := problem("mfa_code_invalid").at("mfaCode")
+	return &p, nil
+}
+
+// verifyMFA checks the code against the user's TOTP secret, falling back to
+// their recovery codes. A matching recovery code is consumed.
+func (s *UserService) verifyMFA(user sendkey.User, code string) (bool, error) {
+	code = strings.TrimSpace(code)
+	if len(code) == totpDigits {
+		return s.verifyTOTP(user, code)
+	}
+
+	return s.recoveryCodes.DeleteByUserAndHash(user.ID, hashRecoveryCode(code))
+}
+
+// verifyTOTP checks the code against the user's TOTP secret. A code is only
+// accepted once: its time step has to be later than the last one accepted,
+// which also rules out an earlier code that's still within the drift
+// allowed.
+func (s *UserService) verifyTOTP(user sendkey.User, code string) (bool, error) {
+	step, ok := totpStep(user.MFASecret, strings.TrimSpace(code), time.Now())
+	if !ok {
+		return false, nil
+	}
+	return s.users.AdvanceMFAStep(user.ID, step)
+}
+
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	h := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(h[:])
+}
+
+// totpStep checks the code against the RFC 6238 value for the current period,
+// allowing one period of clock drift in either direction, and returns the
+// time step it matched.
+func totpStep(secret, code string, now time.Time) (int64, bool) {
+	if len(code) != totpDigits {
+		return 0, false
+	}
+
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(strings.ToUpper(secret))
+	if err != nil || len(key) == 0 {
+		return 0, false
+	}
+
+	counter := now.Unix() / int64(totpPeriod/time.Second)
+	for _, c := range []int64{counter - 1, counter, counter + 1} {
+		if hmac.Equal([]byte(totp(key, uint64(c))), []byte(code)) {
+			return c, true
+		}
+	}
+
+	return 0, false
+}
+
+func totp(key []byte, counter uint64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	return fmt.Sprintf("%06d", value%1000000)
+}
@@ -0,0 +1,108 @@
+package app
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// OptOutLinks builds the links in notification emails that recipients open
+// to stop receiving entries. A link holds the address signed with a server
+// key, so it can't be altered to opt out someone else.
+type OptOutLinks struct {
+	key     []byte
+	baseURL string
+}
+
+// The baseURL argument is the public URL the links point at; "/unsubscribe/"
+// and the signed token are appended to it.
+func NewOptOutLinks(key []byte, baseURL string) *OptOutLinks {
+	return &OptOutLinks{key, strings.TrimSuffix(baseURL, "/")}
+}
+
+// URL returns the signed opt-out link for the address.
+func (l *OptOutLinks) URL(email string) string {
+	email = normalizeEmail(email)
+	return l.baseURL + "/unsubscribe/" + base64.RawURLEncoding.EncodeToString([]byte(email)) + "." + l.signature(email)
+}
+
+// Verify returns the address held by a link's token if its signature is
+// valid.
+func (l *OptOutLinks) Verify(token string) (string, bool) {
+	encoded, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return "", false
+	}
+	b, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil || len(b) == 0 {
+		return "", false
+	}
+	email := string(b)
+	if !hmac.Equal([]byte(sig), []byte(l.signature(email))) {
+		return "", false
+	}
+	return email, true
+}
+
+func (l *OptOutLinks) signature(email string) string {
+	mac := hmac.New(sha256.New, l.key)
+	mac.Write([]byte("opt-out:"))
+	mac.Write([]byte(email))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// HonorOptOuts lets recipients opt out of receiving entries. The notification
+// emails sent to recipients get a link to opt out, and once they have, new
+// entries to them are refused and their reminders and notices aren't sent.
+func (s *EntryService) HonorOptOuts(links *OptOutLinks) {
+	s.optOuts = links
+}
+
+// OptOut adds the address to the recipients who opted out. It's also used by
+// admins to act on opt-outs that came in some other way, like a reply.
+func (s *EntryService) OptOut(email string) error {
+	email = normalizeEmail(email)
+	if err := s.entries.SuppressRecipient(email, time.Now().UTC()); err != nil {
+		return err
+	}
+	s.record("recipient.optedOut", map[string]string{"email": email})
+	return nil
+}
+
+// OptIn removes the address from the recipients who opted out, returning
+// false if it wasn't one of them.
+func (s *EntryService) OptIn(email string) (bool, error) {
+	email = normalizeEmail(email)
+	ok, err := s.entries.UnsuppressRecipient(email)
+	if err != nil || !ok {
+		return false, err
+	}
+	s.record("recipient.optedIn", map[string]string{"email": email})
+	return true, nil
+}
+
+// optedOut reports whether the recipient opted out. It's always false if
+// opt-outs aren't honored.
+func (s *EntryService) optedOut(email string) (bool, error) {
+	if s.optOuts == nil {
+		return false, nil
+	}
+	return s.entries.RecipientSuppressed(normalizeEmail(email))
+}
+
+// optOutFooter returns the footer for a notification email to the
+// recipient with the link to opt out, if they can.
+func (s *EntryService) optOutFooter(email string) string {
+	if s.optOuts == nil {
+		return ""
+	}
+	return fmt.Sprintf("\n--\nTo stop receiving entries sent with sendkey, open %s\n", s.optOuts.URL(email))
+}
@@ -0,0 +1,193 @@
+package app
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// PasswordHasher is a password hashing algorithm.
+type PasswordHasher interface {
+	// Hash returns the encoded hash of the password, including everything
+	// needed to verify it later.
+	Hash(password string) (string, error)
+	// Compare reports whether the password matches the hash.
+	Compare(hash, password string) (bool, error)
+	// Recognizes reports whether the hash was produced by the algorithm.
+	Recognizes(hash string) bool
+	// NeedsRehash reports whether a recognized hash was produced with weaker
+	// parameters than the hasher is configured with.
+	NeedsRehash(hash string) bool
+}
+
+// PasswordHashers hashes new passwords with Default and verifies existing
+// hashes with whichever hasher recognizes them. Hashes that weren't produced
+// by Default with its current parameters are re-hashed on the next login.
+type PasswordHashers struct {
+	Default PasswordHasher
+	Legacy  []PasswordHasher
+}
+
+// DefaultPasswordHashers hashes with Argon2id and accepts bcrypt hashes from
+// before Argon2id was introduced.
+func DefaultPasswordHashers() PasswordHashers {
+	return PasswordHashers{
+		Default: DefaultArgon2idHasher(),
+		Legacy:  []PasswordHasher{BcryptHasher{Cost: bcrypt.DefaultCost}},
+	}
+}
+
+func (h PasswordHashers) Hash(password string) (string, error) {
+	return h.Default.Hash(password)
+}
+
+// Compare reports whether the password matches the hash and whether the hash
+// should be replaced with a new one from Default.
+func (h PasswordHashers) Compare(hash, password string) (ok, rehash bool, err error) {
+	hasher, legacy := h.Default, false
+	if !hasher.Recognizes(hash) {
+		hasher, legacy = nil, true
+		for _, l := range h.Legacy {
+			if l.Recognizes(hash) {
+				hasher = l
+				break
+			}
+		}
+		if hasher == nil {
+			return false, false, fmt.Errorf("unrecognized password hash format")
+		}
+	}
+
+	ok, err = hasher.Compare(hash, password)
+	if err != nil || !ok {
+		return false, false, err
+	}
+
+	return true, legacy || hasher.NeedsRehash(hash), nil
+}
+
+// Argon2idHasher hashes passwords with Argon2id. Hashes are encoded in the
+// PHC string format used by the reference implementation:
+// $argon2id$v=19$m=65536,t=1,p=4$<salt>$<key>
+type Argon2idHasher struct {
+	Time       uint32
+	MemoryKiB  uint32
+	Threads    uint8
+	KeyLength  uint32
+	SaltLength uint32
+}
+
+// DefaultArgon2idHasher uses the parameters recommended by RFC 9106 for
+// memory-constrained environments.
+func DefaultArgon2idHasher() Argon2idHasher {
+	return Argon2idHasher{
+		Time:       3,
+		MemoryKiB:  64 * 1024,
+		Threads:    4,
+		KeyLength:  32,
+		SaltLength: 16,
+	}
+}
+
+const argon2idPrefix = "$argon2id$"
+
+func (h Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, h.Time, h.MemoryKiB, h.Threads, h.KeyLength)
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s", argon2idPrefix, argon2.Version,
+		h.MemoryKiB, h.Time, h.Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func (h Argon2idHasher) Compare(hash, password string) (bool, error) {
+	params, salt, key, err := decodeArgon2id(hash)
+	if err != nil {
+		return false, err
+	}
+
+	actual := argon2.IDKey([]byte(password), salt, params.Time, params.MemoryKiB, params.Threads, uint32(len(key)))
+	return subtle.ConstantTimeCompare(key, actual) == 1, nil
+}
+
+func (h Argon2idHasher) Recognizes(hash string) bool {
+	return strings.HasPrefix(hash, argon2idPrefix)
+}
+
+func (h Argon2idHasher) NeedsRehash(hash string) bool {
+	params, salt, key, err := decodeArgon2id(hash)
+	if err != nil {
+		return true
+	}
+
+	return params.Time < h.Time || params.MemoryKiB < h.MemoryKiB || params.Threads < h.Threads ||
+		uint32(len(key)) < h.KeyLength || uint32(len(salt)) < h.SaltLength
+}
+
+func decodeArgon2id(hash string) (params Argon2idHasher, salt, key []byte, err error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return params, nil, nil, fmt.Errorf("invalid argon2id hash")
+	}
+
+	var version int
+	if _, err = fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return params, nil, nil, fmt.Errorf("invalid argon2id hash version: %w", err)
+	}
+	if version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("unsupported argon2id version %d", version)
+	}
+
+	if _, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.MemoryKiB, &params.Time, &params.Threads); err != nil {
+		return params, nil, nil, fmt.Errorf("invalid argon2id hash parameters: %w", err)
+	}
+
+	if salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return params, nil, nil, fmt.Errorf("invalid argon2id hash salt: %w", err)
+	}
+	if key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil {
+		return params, nil, nil, fmt.Errorf("invalid argon2id hash key: %w", err)
+	}
+	params.KeyLength = uint32(len(key))
+	params.SaltLength = uint32(len(salt))
+
+	return params, salt, key, nil
+}
+
+// BcryptHasher hashes passwords with bcrypt. It's kept for verifying hashes
+// created before Argon2id became the default.
+type BcryptHasher struct {
+	Cost int
+}
+
+func (h BcryptHasher) Hash(password string) (string, error) {
+	b, err := bcrypt.GenerateFromPassword([]byte(password), h.Cost)
+	return string(b), err
+}
+
+func (h BcryptHasher) Compare(hash, password string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if err == bcrypt.ErrMismatchedHashAndPassword {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (h BcryptHasher) Recognizes(hash string) bool {
+	_, err := bcrypt.Cost([]byte(hash))
+	return err == nil
+}
+
+func (h BcryptHasher) NeedsRehash(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost < h.Cost
+}
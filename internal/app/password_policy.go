@@ -0,0 +1,86 @@
+package app
+
+import (
+	"unicode"
+)
+
+// BreachChecker reports whether a password is known to have been breached.
+type BreachChecker interface {
+	Breached(password string) (bool, error)
+}
+
+// PasswordPolicy is the set of rules new passwords must satisfy.
+type PasswordPolicy struct {
+	MinLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+
+	// Breaches is optional. If set, passwords found in a breach are rejected.
+	Breaches BreachChecker
+}
+
+// PasswordViolation is a rule the password failed to satisfy. Its problem's
+// code is the violation's code prefixed with "password_".
+type PasswordViolation struct {
+	Code    string  `json:"code"`
+	Problem Problem `json:"-"`
+}
+
+func violation(code string, args ...interface{}) PasswordViolation {
+	return PasswordViolation{code, problem("password_"+code, args...)}
+}
+
+// Validate returns every rule the password violates.
+func (p PasswordPolicy) Validate(password string) ([]PasswordViolation, error) {
+	var (
+		violations                     []PasswordViolation
+		length                         int
+		upper, lower, digit, hasSymbol bool
+	)
+	for _, r := range password {
+		length++
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			hasSymbol = true
+		}
+	}
+
+	if length < p.MinLength {
+		violations = append(violations, violation("min_length", p.MinLength))
+	}
+	if p.RequireUpper && !upper {
+		violations = append(violations, violation("upper"))
+	}
+	if p.RequireLower && !lower {
+		violations = append(violations, violation("lower"))
+	}
+	if p.RequireDigit && !digit {
+		violations = append(violations, violation("digit"))
+	}
+	if p.RequireSymbol && !hasSymbol {
+		violations = append(violations, violation("symbol"))
+	}
+
+	// there's no need to check for breaches if the password fails anyway
+	if len(violations) > 0 || p.Breaches == nil {
+		return violations, nil
+	}
+
+	breached, err := p.Breaches.Breached(password)
+	if err != nil {
+		return nil, err
+	}
+	if breached {
+		violations = append(violations, violation("breached"))
+	}
+
+	return violations, nil
+}
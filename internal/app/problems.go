@@ -0,0 +1,33 @@
+package app
+
+import "github.com/gavinwade12/sendkey/internal/i18n"
+
+// Problem is a validation error. Its code is stable for API clients, and its
+// message comes from the i18n catalog so it can be shown in any language.
+type Problem struct {
+	// Field is the request field the problem is with, by its JSON name, like
+	// "sendToEmail" or "challenges[1]". It's empty for a problem with the
+	// request as a whole.
+	Field string
+	Code  string
+	Args  []interface{}
+}
+
+func problem(code string, args ...interface{}) Problem {
+	return Problem{Code: code, Args: args}
+}
+
+// at returns the problem pointing at the field.
+func (p Problem) at(field string) Problem {
+	p.Field = field
+	return p
+}
+
+// Message returns the problem's message in the language.
+func (p Problem) Message(lang string) string {
+	return i18n.Message(lang, p.Code, p.Args...)
+}
+
+func (p Problem) String() string {
+	return p.Message(i18n.DefaultLanguage)
+}
@@ -0,0 +1,89 @@
+package app
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UsageRepository tracks how much each user has sent, for enforcing quotas.
+type UsageRepository interface {
+	// AddEntryCreated counts an entry created by the user on the UTC day.
+	AddEntryCreated(userID uuid.UUID, day time.Time) error
+	EntriesCreated(userID uuid.UUID, day time.Time) (int, error)
+	// CountLiveEntries returns the number of the user's entries that
+	// haven't been claimed or expired.
+	CountLiveEntries(userID uuid.UUID, now time.Time) (int, error)
+}
+
+// Quota limits what each user can send. Zero fields are unlimited. Entries
+// created without a sender aren't counted; they're limited separately.
+type Quota struct {
+	// EntriesPerDay is the most entries a user can create each UTC day.
+	EntriesPerDay int `json:"entriesPerDay"`
+	// MaxLiveEntries is the most entries a user can have waiting to be
+	// claimed at once.
+	MaxLiveEntries int `json:"maxLiveEntries"`
+	// MaxValueBytes is the largest value a user can send. It can only be
+	// lower than the service's limit.
+	MaxValueBytes int `json:"maxValueBytes"`
+}
+
+// EnforceQuota limits what each user can send, tracking their usage in the
+// repository.
+func (s *EntryService) EnforceQuota(q Quota, usage UsageRepository) {
+	s.quota = q
+	s.usage = usage
+}
+
+type Usage struct {
+	EntriesToday int   `json:"entriesToday"`
+	LiveEntries  int   `json:"liveEntries"`
+	Quota        Quota `json:"quota"`
+}
+
+// Usage returns what the user has sent against their quota. It's nil if
+// quotas aren't enforced.
+func (s *EntryService) Usage(userID uuid.UUID) (*Usage, error) {
+	if s.usage == nil {
+		return nil, nil
+	}
+
+	now := time.Now().UTC()
+	today, err := s.usage.EntriesCreated(userID, now)
+	if err != nil {
+		return nil, err
+	}
+	live, err := s.usage.CountLiveEntries(userID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Usage{EntriesToday: today, LiveEntries: live, Quota: s.quota}, nil
+}
+
+// checkQuota returns a problem if creating an entry with a value of size
+// bytes would put the sender over their quota.
+func (s *EntryService) checkQuota(senderID uuid.UUID, size int) (*Problem, error) {
+	if s.usage == nil || senderID == uuid.Nil {
+		return nil, nil
+	}
+	if s.quota.MaxValueBytes > 0 && size > s.quota.MaxValueBytes {
+		p := problem("quota_value_size", s.quota.MaxValueBytes)
+		return &p, nil
+	}
+
+	usage, err := s.Usage(senderID)
+	if err != nil {
+		return nil, err
+	}
+	if s.quota.EntriesPerDay > 0 && usage.EntriesToday >= s.quota.EntriesPerDay {
+		p := problem("quota_entries_per_day", s.quota.EntriesPerDay)
+		return &p, nil
+	}
+	if s.quota.MaxLiveEntries > 0 && usage.LiveEntries >= s.quota.MaxLiveEntries {
+		p := problem("quota_live_entries", s.quota.MaxLiveEntries)
+		return &p, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,61 @@
+package app
+
+import (
+	"time"
+
+	"github.com/gavinwade12/sendkey"
+)
+
+const (
+	// DefaultSandboxTimeScale is how many times faster time passes for
+	// sandbox entries if the deployment doesn't say.
+	DefaultSandboxTimeScale = 60
+	// SandboxOTP is the one-time code for every sandbox entry that requires
+	// one. It's never emailed.
+	SandboxOTP = "000000"
+)
+
+// Sandbox returns a copy of the service for integrators to test against.
+// Its entries are kept apart from real ones: they're only found through a
+// sandbox service, and never emailed, published, reminded about, exported,
+// or counted towards quotas or usage statistics. Their durations and
+// availability delays are divided by timeScale so whole lifecycles can be
+// tested quickly.
+func (s *EntryService) Sandbox(timeScale int) *EntryService {
+	if timeScale < 1 {
+		timeScale = 1
+	}
+
+	sandbox := *s
+	sandbox.sandbox = true
+	sandbox.sandboxTimeScale = timeScale
+	sandbox.duplicates = nil
+	sandbox.events = nil
+	sandbox.usage = nil
+	sandbox.usageCounter = nil
+	sandbox.logger = s.log().With("sandbox", true)
+	return &sandbox
+}
+
+// scale shortens d for sandbox entries.
+func (s *EntryService) scale(d time.Duration) time.Duration {
+	if !s.sandbox {
+		return d
+	}
+	return d / time.Duration(s.sandboxTimeScale)
+}
+
+// inNamespace reports whether the entry belongs to the service's namespace:
+// sandbox entries to sandbox services, and the rest to the others.
+func (s *EntryService) inNamespace(e sendkey.Entry) bool {
+	return e.Sandbox == s.sandbox
+}
+
+// scaleAvailableAt shortens the wait until a sandbox entry is available.
+func (s *EntryService) scaleAvailableAt(at *time.Time, now time.Time) *time.Time {
+	if at == nil || !s.sandbox {
+		return at
+	}
+	t := now.Add(s.scale(at.Sub(now)))
+	return &t
+}
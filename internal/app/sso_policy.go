@@ -0,0 +1,53 @@
+package app
+
+import "strings"
+
+// SSOOrg is an organization that requires its members to log in through
+// single sign-on instead of with a password. Members are identified by their
+// email domain.
+type SSOOrg struct {
+	Name    string
+	Domains []string
+	// Exempt are members' emails that can still use a password, such as
+	// break-glass admin accounts for when the identity provider is down.
+	Exempt []string
+}
+
+// RequireSSO refuses password logins and sign-ups for members of the orgs.
+// They can still log in through OIDC or SAML.
+func (s *UserService) RequireSSO(orgs ...SSOOrg) {
+	s.ssoOrgs = append(s.ssoOrgs, orgs...)
+}
+
+// ssoRequired returns a problem if the email belongs to an org that requires
+// SSO and isn't exempt.
+func (s *UserService) ssoRequired(email string) *Problem {
+	email = strings.ToLower(strings.TrimSpace(email))
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return nil
+	}
+	domain := email[at+1:]
+
+	for _, org := range s.ssoOrgs {
+		if !containsFold(org.Domains, domain) {
+			continue
+		}
+		if containsFold(org.Exempt, email) {
+			return nil
+		}
+		p := problem("sso_required", org.Name)
+		return &p
+	}
+
+	return nil
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(strings.TrimSpace(v), s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+package app
+
+import (
+	"github.com/gavinwade12/sendkey"
+	"github.com/google/uuid"
+)
+
+// UsageCounter counts how often features are used, for anonymous usage
+// statistics. Counts are by name only; nothing about the user or entry is
+// passed.
+type UsageCounter interface {
+	Count(name string)
+}
+
+// CountUsage counts the service's events and the features new entries use.
+func (s *EntryService) CountUsage(c UsageCounter) {
+	s.usageCounter = c
+}
+
+func (s *EntryService) count(name string) {
+	if s.usageCounter != nil {
+		s.usageCounter.Count(name)
+	}
+}
+
+// countFeatures counts the features a new entry uses.
+func (s *EntryService) countFeatures(e sendkey.Entry) {
+	if s.usageCounter == nil {
+		return
+	}
+
+	s.count("entry.type." + e.Type)
+	features := map[string]bool{
+		"entry.endToEnd":     e.EndToEnd,
+		"entry.requireLogin": e.RequireLogin,
+		"entry.requireOtp":   e.RequireOTP,
+		"entry.challenges":   len(e.Challenges) > 0,
+		"entry.scheduled":    e.AvailableAtUTC != nil,
+		"entry.digest":       e.Digest != nil,
+		"entry.guest":        e.SentByUserID == uuid.Nil,
+		"entry.rotation":     e.ReplacesEntryID != nil,
+	}
+	for name, used := range features {
+		if used {
+			s.count(name)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package app
+
+// Tracer wraps a service's repositories and mailer so the calls made while
+// serving one request are traced as part of it. See EntryService.Traced.
+type Tracer interface {
+	EntryRepository(EntryRepository) EntryRepository
+	UsageRepository(UsageRepository) UsageRepository
+	UserRepository(UserRepository) UserRepository
+	UserIdentityRepository(UserIdentityRepository) UserIdentityRepository
+	RecoveryCodeRepository(RecoveryCodeRepository) RecoveryCodeRepository
+	Mailer(Mailer) Mailer
+}
+
+// Traced returns a copy of the service whose repository calls and emails go
+// through the tracer. It's meant to be made for each request, since the
+// tracer holds the request's span.
+func (s *EntryService) Traced(t Tracer) *EntryService {
+	traced := *s
+	traced.entries = t.EntryRepository(s.entries)
+	if s.usage != nil {
+		traced.usage = t.UsageRepository(s.usage)
+	}
+	if s.otpMailer != nil {
+		traced.otpMailer = t.Mailer(s.otpMailer)
+	}
+	return &traced
+}
+
+// Traced returns a copy of the service whose repository calls go through
+// the tracer. See EntryService.Traced.
+func (s *UserService) Traced(t Tracer) *UserService {
+	traced := *s
+	traced.users = t.UserRepository(s.users)
+	traced.identities = t.UserIdentityRepository(s.identities)
+	traced.recoveryCodes = t.RecoveryCodeRepository(s.recoveryCodes)
+	return &traced
+}
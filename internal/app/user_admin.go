@@ -0,0 +1,122 @@
+package app
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gavinwade12/sendkey"
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultUserPageSize is the number of users listed if the request
+	// doesn't say.
+	DefaultUserPageSize = 50
+	maxUserPageSize     = 500
+)
+
+type ListUsersRequest struct {
+	// Search matches users whose email or name contains it.
+	Search string `json:"search"`
+	// After is the Next cursor from the previous page.
+	After string `json:"after"`
+	Limit int    `json:"limit"`
+}
+
+type ListUsersResponse struct {
+	Users []sendkey.User `json:"users"`
+	// Next is the cursor for the next page, or empty on the last page.
+	Next string `json:"next"`
+}
+
+// ListUsers returns a page of users, ordered by email, for admins.
+func (s *UserService) ListUsers(req ListUsersRequest) (*ListUsersResponse, error) {
+	if req.Limit <= 0 {
+		req.Limit = DefaultUserPageSize
+	}
+	if req.Limit > maxUserPageSize {
+		req.Limit = maxUserPageSize
+	}
+
+	// one extra user shows whether there's another page
+	users, err := s.users.Search(strings.TrimSpace(req.Search), req.After, req.Limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &ListUsersResponse{Users: users}
+	if len(users) > req.Limit {
+		resp.Users = users[:req.Limit]
+		resp.Next = resp.Users[req.Limit-1].Email
+	}
+	return resp, nil
+}
+
+// DisableUser stops the user from logging in until EnableUser is called. It
+// returns nil if there's no such user. The caller must revoke the user's
+// sessions.
+func (s *UserService) DisableUser(id uuid.UUID) (*sendkey.User, error) {
+	user, err := s.users.Find(id)
+	if err != nil || user == nil || user.DisabledAtUTC != nil {
+		return user, err
+	}
+
+	now := time.Now().UTC()
+	user.DisabledAtUTC = &now
+	if err = s.users.Update(*user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// EnableUser lets a disabled user log in again. It returns nil if there's no
+// such user.
+func (s *UserService) EnableUser(id uuid.UUID) (*sendkey.User, error) {
+	user, err := s.users.Find(id)
+	if err != nil || user == nil || user.DisabledAtUTC == nil {
+		return user, err
+	}
+
+	user.DisabledAtUTC = nil
+	if err = s.users.Update(*user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// ForcePasswordReset removes the user's password, e.g. after it's leaked, and
+// revokes their access tokens. They have to set a new one when they redeem a
+// magic link, since whoever leaked it may have a session too. It returns nil
+// if there's no such user. The caller must revoke the user's refresh tokens.
+func (s *UserService) ForcePasswordReset(id uuid.UUID) (*sendkey.User, error) {
+	user, err := s.users.Find(id)
+	if err != nil || user == nil {
+		return user, err
+	}
+
+	now := time.Now().UTC()
+	user.Password = ""
+	user.PasswordResetAtUTC = &now
+	if err = s.users.Update(*user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// passwordResetPending reports whether an admin reset the user's password and
+// they haven't set a new one.
+func passwordResetPending(user sendkey.User) bool {
+	return user.Password == "" && user.PasswordResetAtUTC != nil
+}
+
+// DeleteUser permanently deletes the user and the entries they've sent right
+// away, unlike DeactivateUser's grace period. It returns false if there's no
+// such user.
+func (s *UserService) DeleteUser(id uuid.UUID) (bool, error) {
+	user, err := s.users.Find(id)
+	if err != nil || user == nil {
+		return false, err
+	}
+
+	return true, s.users.Delete(id)
+}
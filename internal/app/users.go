@@ -1,12 +1,12 @@
 package app
 
 import (
+	"log/slog"
 	"strings"
 	"time"
 
 	"github.com/gavinwade12/sendkey"
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
 )
 
 type UserRepository interface {
@@ -15,14 +15,46 @@ type UserRepository interface {
 	Create(sendkey.User) error
 	Update(sendkey.User) error
 	Delete(uuid.UUID) error
+	DeleteDeactivatedBefore(time.Time) (int64, error)
+	// AdvanceMFAStep records the time step of a TOTP code the user logged in
+	// with if it's later than the last one recorded, and reports whether it
+	// was, so each code can only be used once.
+	AdvanceMFAStep(id uuid.UUID, step int64) (bool, error)
+	// Search returns up to limit users whose email or name contains the
+	// query, ordered by email, after the afterEmail cursor.
+	Search(query, afterEmail string, limit int) ([]sendkey.User, error)
+}
+
+type UserIdentityRepository interface {
+	Create(sendkey.UserIdentity) error
+	Find(provider, subject string) (*sendkey.UserIdentity, error)
 }
 
 type UserService struct {
-	users UserRepository
+	users         UserRepository
+	recoveryCodes RecoveryCodeRepository
+	identities    UserIdentityRepository
+
+	passwordPolicy PasswordPolicy
+	passwords      PasswordHashers
+	ssoOrgs        []SSOOrg
+	events         EventPublisher
+	logger         *slog.Logger
+	// ids generates the IDs of new records; see GenerateIDs.
+	ids sendkey.IDGenerator
 }
 
-func NewUserService(users UserRepository) *UserService {
-	return &UserService{users}
+// The policy argument is enforced for passwords set when creating a user
+// or changing their password. The hashers argument hashes new passwords and
+// verifies existing ones; see DefaultPasswordHashers.
+func NewUserService(users UserRepository, recoveryCodes RecoveryCodeRepository, identities UserIdentityRepository, policy PasswordPolicy, hashers PasswordHashers) *UserService {
+	return &UserService{users: users, recoveryCodes: recoveryCodes, identities: identities, passwordPolicy: policy, passwords: hashers}
+}
+
+// GenerateIDs sets the generator of the IDs of new users and their records,
+// like recovery codes. Without it, they're random.
+func (s *UserService) GenerateIDs(g sendkey.IDGenerator) {
+	s.ids = g
 }
 
 type CreateUserRequest struct {
@@ -33,22 +65,34 @@ type CreateUserRequest struct {
 }
 
 type CreateUserResponse struct {
-	Success bool          `json:"success"`
-	Errors  []string      `json:"errors"`
-	User    *sendkey.User `json:"user"`
+	Success        bool                `json:"success"`
+	Errors         []Problem           `json:"errors"`
+	PasswordErrors []PasswordViolation `json:"passwordErrors"`
+	User           *sendkey.User       `json:"user"`
 }
 
 func (s *UserService) CreateUser(req CreateUserRequest) (*CreateUserResponse, error) {
 	resp := &CreateUserResponse{}
 
+	var v Validator
 	req.Email = strings.TrimSpace(req.Email)
-	if req.Email == "" {
-		resp.Errors = append(resp.Errors, "An email is required.")
+	if v.Require("email", req.Email, "email_required") {
+		if p := s.ssoRequired(req.Email); p != nil {
+			v.Add("email", *p)
+		}
 	}
-	if req.Password == "" {
-		resp.Errors = append(resp.Errors, "A password is required.")
+	if v.Check(req.Password != "", "password", "password_required") {
+		violations, err := s.passwordPolicy.Validate(req.Password)
+		if err != nil {
+			return nil, err
+		}
+		for _, violation := range violations {
+			v.Add("password", violation.Problem)
+		}
+		resp.PasswordErrors = violations
 	}
-	if len(resp.Errors) > 0 {
+	if !v.Valid() {
+		resp.Errors = v.Problems()
 		resp.Success = false
 		return resp, nil
 	}
@@ -58,28 +102,30 @@ func (s *UserService) CreateUser(req CreateUserRequest) (*CreateUserResponse, er
 		return nil, err
 	}
 	if u != nil {
-		resp.Errors = append(resp.Errors, "An account with the specified email already exists.")
+		resp.Errors = append(resp.Errors, problem("email_taken").at("email"))
 		resp.Success = false
 		return resp, nil
 	}
 
-	pass, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	pass, err := s.passwords.Hash(req.Password)
 	if err != nil {
 		return nil, err
 	}
 
 	user := sendkey.User{
-		ID:           uuid.New(),
+		ID:           newID(s.ids),
 		Email:        req.Email,
 		FirstName:    req.FirstName,
 		LastName:     req.LastName,
-		Password:     string(pass),
+		Password:     pass,
 		CreatedAtUTC: time.Now().UTC(),
+		Role:         sendkey.RoleUser,
 	}
 	err = s.users.Create(user)
 	if err != nil {
 		return nil, err
 	}
+	publish(s.log(), s.events, "user.created", map[string]string{"userId": user.ID.String()})
 
 	resp.Success = true
 	resp.User = &user
@@ -89,23 +135,25 @@ func (s *UserService) CreateUser(req CreateUserRequest) (*CreateUserResponse, er
 type UserLoginRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
+	MFACode  string `json:"mfaCode"`
+	// Link is an external identity to link to the user once they've logged
+	// in, which ExternalLogin wouldn't link on its own.
+	Link *ExternalLoginRequest `json:"-"`
 }
 
 type UserLoginResponse struct {
 	Success bool          `json:"success"`
-	Errors  []string      `json:"errors"`
+	Errors  []Problem     `json:"errors"`
 	User    *sendkey.User `json:"user"`
 }
 
 func (s *UserService) Login(req UserLoginRequest) (*UserLoginResponse, error) {
 	resp := &UserLoginResponse{}
-	if req.Email == "" {
-		resp.Errors = append(resp.Errors, "An email is required.")
-	}
-	if req.Password == "" {
-		resp.Errors = append(resp.Errors, "A password is required.")
-	}
-	if len(resp.Errors) > 0 {
+	var v Validator
+	v.Check(req.Email != "", "email", "email_required")
+	v.Check(req.Password != "", "password", "password_required")
+	if !v.Valid() {
+		resp.Errors = v.Problems()
 		resp.Success = false
 		return resp, nil
 	}
@@ -115,27 +163,371 @@ func (s *UserService) Login(req UserLoginRequest) (*UserLoginResponse, error) {
 		return nil, err
 	}
 	if user == nil {
-		resp.Errors = append(resp.Errors, "No user could be found with the specified email.")
+		resp.Errors = append(resp.Errors, problem("user_not_found").at("email"))
+		resp.Success = false
+		return resp, nil
+	}
+	if user.DeactivatedAtUTC != nil {
+		resp.Errors = append(resp.Errors, problem(errDeactivated))
+		resp.Success = false
+		return resp, nil
+	}
+	if user.DisabledAtUTC != nil {
+		resp.Errors = append(resp.Errors, problem(errDisabled))
+		resp.Success = false
+		return resp, nil
+	}
+	// checked before the password so it can't be guessed through a login
+	// that would be refused anyway. Linking an identity from the SSO
+	// provider is how those users prove they own an account.
+	if p := s.ssoRequired(user.Email); p != nil && req.Link == nil {
+		resp.Errors = append(resp.Errors, *p)
+		resp.Success = false
+		return resp, nil
+	}
+	// passwordless users signed up through SSO, or had their password
+	// reset by an admin
+	if user.Password == "" {
+		resp.Errors = append(resp.Errors, problem("password_not_set").at("password"))
 		resp.Success = false
 		return resp, nil
 	}
 
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password))
+	ok, rehash, err := s.passwords.Compare(user.Password, req.Password)
 	if err != nil {
-		if err != bcrypt.ErrMismatchedHashAndPassword {
-			return nil, err
-		}
+		return nil, err
+	}
+	if !ok {
+		resp.Errors = append(resp.Errors, problem("password_invalid").at("password"))
+		resp.Success = false
+		return resp, nil
+	}
 
-		resp.Errors = append(resp.Errors, "The specified password is invalid.")
+	if p, err := s.checkMFA(*user, req.MFACode); err != nil {
+		return nil, err
+	} else if p != nil {
+		resp.Errors = append(resp.Errors, *p)
 		resp.Success = false
 		return resp, nil
 	}
+	if req.Link != nil {
+		if p, err := s.linkIdentity(*user, *req.Link); err != nil {
+			return nil, err
+		} else if p != nil {
+			resp.Errors = append(resp.Errors, *p)
+			resp.Success = false
+			return resp, nil
+		}
+	}
+
+	// migrate hashes from older algorithms or parameters now that we have
+	// the plaintext password
+	if rehash {
+		pass, err := s.passwords.Hash(req.Password)
+		if err != nil {
+			return nil, err
+		}
+		user.Password = pass
+		if err = s.users.Update(*user); err != nil {
+			return nil, err
+		}
+	}
 
 	resp.User = user
 	resp.Success = true
 	return resp, nil
 }
 
+type ChangePasswordRequest struct {
+	UserID          uuid.UUID `json:"-"`
+	CurrentPassword string    `json:"currentPassword"`
+	NewPassword     string    `json:"newPassword"`
+}
+
+type ChangePasswordResponse struct {
+	Success        bool                `json:"success"`
+	Errors         []Problem           `json:"errors"`
+	PasswordErrors []PasswordViolation `json:"passwordErrors"`
+}
+
+// ChangePassword sets a new password for the user. The current password is
+// required unless the user doesn't have one, e.g. they signed up through SSO.
+// A user whose password an admin reset has to set one with a magic link
+// instead; see MagicLinkService.Redeem.
+func (s *UserService) ChangePassword(req ChangePasswordRequest) (*ChangePasswordResponse, error) {
+	resp := &ChangePasswordResponse{}
+
+	user, err := s.users.Find(req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		resp.Errors = append(resp.Errors, problem("invalid_user_id"))
+		return resp, nil
+	}
+
+	if passwordResetPending(*user) {
+		resp.Errors = append(resp.Errors, problem("reset_link_required"))
+		return resp, nil
+	}
+	if user.Password != "" {
+		ok, _, err := s.passwords.Compare(user.Password, req.CurrentPassword)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			resp.Errors = append(resp.Errors, problem("current_password_invalid"))
+			return resp, nil
+		}
+	}
+
+	if req.NewPassword == "" {
+		resp.Errors = append(resp.Errors, problem("new_password_required"))
+		return resp, nil
+	}
+	pass, violations, err := s.hashNewPassword(req.NewPassword)
+	if err != nil {
+		return nil, err
+	}
+	if len(violations) > 0 {
+		resp.PasswordErrors = violations
+		for _, v := range violations {
+			resp.Errors = append(resp.Errors, v.Problem)
+		}
+		return resp, nil
+	}
+
+	user.Password = pass
+	if err = s.users.Update(*user); err != nil {
+		return nil, err
+	}
+
+	resp.Success = true
+	return resp, nil
+}
+
+// hashNewPassword hashes the password if it meets the policy, and otherwise
+// returns how it doesn't.
+func (s *UserService) hashNewPassword(password string) (string, []PasswordViolation, error) {
+	violations, err := s.passwordPolicy.Validate(password)
+	if err != nil || len(violations) > 0 {
+		return "", violations, err
+	}
+	hash, err := s.passwords.Hash(password)
+	return hash, nil, err
+}
+
 func (s *UserService) FindUser(id uuid.UUID) (*sendkey.User, error) {
 	return s.users.Find(id)
 }
+
+type ExternalLoginRequest struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	// TrustEmail treats the email as verified when signing up a new user,
+	// for providers that only issue verified emails but don't say so. It
+	// never links the identity to an existing user.
+	TrustEmail bool
+	FirstName  string
+	LastName   string
+	MFACode    string
+}
+
+// ExternalLogin finds the user linked to the external identity, asking for
+// an MFA code if they've enabled MFA. If there isn't one, a new passwordless
+// user is created for the identity. An identity with the email of an
+// existing user isn't linked to them here: the provider might hand out
+// emails its users don't own, so the user has to log in to the account to
+// link it; see UserLoginRequest.Link.
+func (s *UserService) ExternalLogin(req ExternalLoginRequest) (*UserLoginResponse, error) {
+	resp := &UserLoginResponse{}
+	if req.Provider == "" || req.Subject == "" {
+		resp.Errors = append(resp.Errors, problem("identity_required"))
+		return resp, nil
+	}
+
+	identity, err := s.identities.Find(req.Provider, req.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if identity != nil {
+		user, err := s.users.Find(identity.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if user == nil {
+			return resp, nil
+		}
+		if user.DeactivatedAtUTC != nil {
+			resp.Errors = append(resp.Errors, problem(errDeactivated))
+			return resp, nil
+		}
+		if user.DisabledAtUTC != nil {
+			resp.Errors = append(resp.Errors, problem(errDisabled))
+			return resp, nil
+		}
+		if p, err := s.checkMFA(*user, req.MFACode); err != nil || p != nil {
+			if p != nil {
+				resp.Errors = append(resp.Errors, *p)
+			}
+			return resp, err
+		}
+		resp.User = user
+		resp.Success = true
+		return resp, nil
+	}
+
+	req.Email = strings.TrimSpace(req.Email)
+	if req.Email == "" || !(req.EmailVerified || req.TrustEmail) {
+		resp.Errors = append(resp.Errors, problem("identity_email_unverified"))
+		return resp, nil
+	}
+
+	user, err := s.users.FindByEmail(req.Email)
+	if err != nil {
+		return nil, err
+	}
+	if user != nil {
+		resp.Errors = append(resp.Errors, problem("identity_link_required"))
+		return resp, nil
+	}
+
+	now := time.Now().UTC()
+	user = &sendkey.User{
+		ID:            newID(s.ids),
+		Email:         req.Email,
+		EmailVerified: req.EmailVerified,
+		FirstName:     req.FirstName,
+		LastName:      req.LastName,
+		CreatedAtUTC:  now,
+		Role:          sendkey.RoleUser,
+	}
+	if err = s.users.Create(*user); err != nil {
+		return nil, err
+	}
+	publish(s.log(), s.events, "user.created", map[string]string{"userId": user.ID.String(), "provider": req.Provider})
+
+	err = s.identities.Create(sendkey.UserIdentity{
+		Provider:     req.Provider,
+		Subject:      req.Subject,
+		UserID:       user.ID,
+		CreatedAtUTC: now,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp.User = user
+	resp.Success = true
+	return resp, nil
+}
+
+// linkIdentity links the external identity to the user, who has just logged
+// in to the account to prove it's theirs. The identity must have the
+// account's email, so someone else's pending link can't be slipped into a
+// login.
+func (s *UserService) linkIdentity(user sendkey.User, id ExternalLoginRequest) (*Problem, error) {
+	if id.Provider == "" || id.Subject == "" {
+		p := problem("identity_required")
+		return &p, nil
+	}
+	if !strings.EqualFold(strings.TrimSpace(id.Email), user.Email) {
+		p := problem("identity_email_mismatch")
+		return &p, nil
+	}
+
+	existing, err := s.identities.Find(id.Provider, id.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		if existing.UserID == user.ID {
+			return nil, nil
+		}
+		p := problem("identity_linked_elsewhere")
+		return &p, nil
+	}
+	return nil, s.identities.Create(sendkey.UserIdentity{
+		Provider:     id.Provider,
+		Subject:      id.Subject,
+		UserID:       user.ID,
+		CreatedAtUTC: time.Now().UTC(),
+	})
+}
+
+const (
+	errDeactivated = "account_deleted"
+	errDisabled    = "account_disabled"
+)
+
+// DeactivateUser marks the user as deleted. They can no longer log in, but
+// their data is kept until PurgeDeactivatedUsers removes it, so an admin can
+// still restore the account in the meantime.
+func (s *UserService) DeactivateUser(id uuid.UUID) error {
+	user, err := s.users.Find(id)
+	if err != nil || user == nil || user.DeactivatedAtUTC != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	user.DeactivatedAtUTC = &now
+	return s.users.Update(*user)
+}
+
+type SetUserRoleResponse struct {
+	Success bool          `json:"success"`
+	Errors  []Problem     `json:"errors"`
+	User    *sendkey.User `json:"user"`
+}
+
+// SetUserRole changes the user's role, e.g. to make them an admin.
+func (s *UserService) SetUserRole(id uuid.UUID, role string) (*SetUserRoleResponse, error) {
+	resp := &SetUserRoleResponse{}
+	if role != sendkey.RoleUser && role != sendkey.RoleAdmin {
+		resp.Errors = append(resp.Errors, problem("role_invalid", sendkey.RoleUser, sendkey.RoleAdmin))
+		return resp, nil
+	}
+
+	user, err := s.users.Find(id)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		resp.Errors = append(resp.Errors, problem("invalid_user_id"))
+		return resp, nil
+	}
+
+	if user.Role != role {
+		user.Role = role
+		if err = s.users.Update(*user); err != nil {
+			return nil, err
+		}
+	}
+
+	resp.Success = true
+	resp.User = user
+	return resp, nil
+}
+
+// RestoreUser reactivates a user that's been deactivated but not yet purged.
+func (s *UserService) RestoreUser(id uuid.UUID) (*sendkey.User, error) {
+	user, err := s.users.Find(id)
+	if err != nil || user == nil || user.DeactivatedAtUTC == nil {
+		return user, err
+	}
+
+	user.DeactivatedAtUTC = nil
+	if err = s.users.Update(*user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// PurgeDeactivatedUsers permanently deletes users that were deactivated
+// longer ago than the grace period, returning how many were deleted.
+func (s *UserService) PurgeDeactivatedUsers(gracePeriod time.Duration) (int64, error) {
+	return s.users.DeleteDeactivatedBefore(time.Now().UTC().Add(-gracePeriod))
+}
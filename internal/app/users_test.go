@@ -0,0 +1,522 @@
+package app
+
+import (
+	"encoding/base32"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gavinwade12/sendkey"
+	"github.com/google/uuid"
+)
+
+// memoryUsers keeps users in memory.
+type memoryUsers struct {
+	users map[uuid.UUID]sendkey.User
+	steps map[uuid.UUID]int64
+}
+
+func (m *memoryUsers) Find(id uuid.UUID) (*sendkey.User, error) {
+	u, ok := m.users[id]
+	if !ok {
+		return nil, nil
+	}
+	return &u, nil
+}
+
+func (m *memoryUsers) FindByEmail(email string) (*sendkey.User, error) {
+	for _, u := range m.users {
+		if strings.EqualFold(u.Email, email) {
+			return &u, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *memoryUsers) Create(u sendkey.User) error {
+	m.users[u.ID] = u
+	return nil
+}
+
+func (m *memoryUsers) Update(u sendkey.User) error {
+	m.users[u.ID] = u
+	return nil
+}
+
+func (m *memoryUsers) Delete(id uuid.UUID) error {
+	delete(m.users, id)
+	return nil
+}
+
+func (m *memoryUsers) DeleteDeactivatedBefore(time.Time) (int64, error) { return 0, nil }
+
+func (m *memoryUsers) AdvanceMFAStep(id uuid.UUID, step int64) (bool, error) {
+	if step <= m.steps[id] {
+		return false, nil
+	}
+	m.steps[id] = step
+	return true, nil
+}
+
+func (m *memoryUsers) Search(query, afterEmail string, limit int) ([]sendkey.User, error) {
+	return nil, nil
+}
+
+// memoryMagicLinks keeps magic links in memory.
+type memoryMagicLinks struct {
+	links map[uuid.UUID]sendkey.MagicLink
+}
+
+func (m *memoryMagicLinks) Create(l sendkey.MagicLink) error {
+	m.links[l.ID] = l
+	return nil
+}
+
+func (m *memoryMagicLinks) Find(id uuid.UUID) (*sendkey.MagicLink, error) {
+	l, ok := m.links[id]
+	if !ok {
+		return nil, nil
+	}
+	return &l, nil
+}
+
+func (m *memoryMagicLinks) Delete(id uuid.UUID) (bool, error) {
+	_, ok := m.links[id]
+	delete(m.links, id)
+	return ok, nil
+}
+
+// memoryIdentities keeps external identities in memory.
+type memoryIdentities struct {
+	identities []sendkey.UserIdentity
+}
+
+func (m *memoryIdentities) Create(i sendkey.UserIdentity) error {
+	m.identities = append(m.identities, i)
+	return nil
+}
+
+func (m *memoryIdentities) Find(provider, subject string) (*sendkey.UserIdentity, error) {
+	for _, i := range m.identities {
+		if i.Provider == provider && i.Subject == subject {
+			return &i, nil
+		}
+	}
+	return nil, nil
+}
+
+// memoryRecoveryCodes keeps recovery codes in memory.
+type memoryRecoveryCodes struct {
+	codes []sendkey.RecoveryCode
+}
+
+func (m *memoryRecoveryCodes) Create(c sendkey.RecoveryCode) error {
+	m.codes = append(m.codes, c)
+	return nil
+}
+
+func (m *memoryRecoveryCodes) DeleteByUserAndHash(userID uuid.UUID, codeHash string) (bool, error) {
+	for i, c := range m.codes {
+		if c.UserID == userID && c.CodeHash == codeHash {
+			m.codes = append(m.codes[:i], m.codes[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *memoryRecoveryCodes) DeleteByUserID(userID uuid.UUID) error {
+	kept := m.codes[:0]
+	for _, c := range m.codes {
+		if c.UserID != userID {
+			kept = append(kept, c)
+		}
+	}
+	m.codes = kept
+	return nil
+}
+
+// memoryMailer keeps the mail it's sent.
+type memoryMailer struct {
+	sent []string
+}
+
+func (m *memoryMailer) Send(to, subject, body string) error {
+	m.sent = append(m.sent, body)
+	return nil
+}
+
+var magicLinkCode = regexp.MustCompile(`/login/magic/(\S+)`)
+
+// lastCode returns the code from the last magic link sent.
+func (m *memoryMailer) lastCode(t *testing.T) string {
+	t.Helper()
+	if len(m.sent) == 0 {
+		t.Fatal("no magic link was sent")
+	}
+	match := magicLinkCode.FindStringSubmatch(m.sent[len(m.sent)-1])
+	if match == nil {
+		t.Fatalf("the mail has no magic link: %s", m.sent[len(m.sent)-1])
+	}
+	return match[1]
+}
+
+// testHashers hash passwords cheaply, for tests.
+var testHashers = PasswordHashers{Default: Argon2idHasher{Time: 1, MemoryKiB: 64, Threads: 1, KeyLength: 32, SaltLength: 16}}
+
+type userFixture struct {
+	users      *memoryUsers
+	links      *memoryMagicLinks
+	identities *memoryIdentities
+	codes      *memoryRecoveryCodes
+	mailer     *memoryMailer
+	svc        *UserService
+	magic      *MagicLinkService
+	user       sendkey.User
+}
+
+// newUserFixture returns the services with one user, whose password is
+// "old password".
+func newUserFixture(t *testing.T) *userFixture {
+	t.Helper()
+	f := &userFixture{
+		users:      &memoryUsers{users: map[uuid.UUID]sendkey.User{}, steps: map[uuid.UUID]int64{}},
+		links:      &memoryMagicLinks{links: map[uuid.UUID]sendkey.MagicLink{}},
+		identities: &memoryIdentities{},
+		codes:      &memoryRecoveryCodes{},
+		mailer:     &memoryMailer{},
+	}
+	f.svc = NewUserService(f.users, f.codes, f.identities, PasswordPolicy{MinLength: 8}, testHashers)
+	f.magic = NewMagicLinkService(f.svc, f.links, f.mailer, []byte("signing key"), "https://sendkey.example", time.Minute)
+
+	hash, err := testHashers.Hash("old password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.user = sendkey.User{ID: uuid.New(), Email: "ada@example.com", Password: hash, Role: sendkey.RoleUser}
+	f.users.users[f.user.ID] = f.user
+	return f
+}
+
+func hasProblem(problems []Problem, code string) bool {
+	for _, p := range problems {
+		if p.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+func TestForcedPasswordResetNeedsMagicLink(t *testing.T) {
+	f := newUserFixture(t)
+	if _, err := f.svc.ForcePasswordReset(f.user.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// a session alone can't set the password, since it may be the one that
+	// leaked
+	resp, err := f.svc.ChangePassword(ChangePasswordRequest{UserID: f.user.ID, NewPassword: "attacker's password"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Success || !hasProblem(resp.Errors, "reset_link_required") {
+		t.Fatalf("ChangePassword() = %+v, want reset_link_required", resp)
+	}
+
+	if _, err = f.magic.Send(f.user.Email); err != nil {
+		t.Fatal(err)
+	}
+	code := f.mailer.lastCode(t)
+
+	// the link isn't used up by a redemption without the new password
+	login, err := f.magic.Redeem(RedeemMagicLinkRequest{Code: code})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if login.Success || !hasProblem(login.Errors, "password_reset_required") {
+		t.Fatalf("Redeem() = %+v, want password_reset_required", login)
+	}
+
+	login, err = f.magic.Redeem(RedeemMagicLinkRequest{Code: code, NewPassword: "new password"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !login.Success {
+		t.Fatalf("Redeem() = %+v, want success", login)
+	}
+	user := f.users.users[f.user.ID]
+	if ok, _, _ := testHashers.Compare(user.Password, "new password"); !ok {
+		t.Error("the new password wasn't set")
+	}
+	if user.PasswordResetAtUTC == nil {
+		t.Error("the reset time was cleared, which would let older tokens back in")
+	}
+}
+
+func TestForcePasswordResetRevokesTokens(t *testing.T) {
+	f := newUserFixture(t)
+	before := time.Now().UTC()
+	user, err := f.svc.ForcePasswordReset(f.user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Password != "" || user.PasswordResetAtUTC == nil || user.PasswordResetAtUTC.Before(before) {
+		t.Errorf("ForcePasswordReset() = %+v", user)
+	}
+
+	// resetting again, e.g. for another leak, moves the time forward
+	first := *user.PasswordResetAtUTC
+	if user, err = f.svc.ForcePasswordReset(f.user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if user.PasswordResetAtUTC.Before(first) {
+		t.Errorf("the second reset is at %v, before the first at %v", user.PasswordResetAtUTC, first)
+	}
+}
+
+func TestRedeemMagicLinkChecksNewPassword(t *testing.T) {
+	f := newUserFixture(t)
+	if _, err := f.magic.Send(f.user.Email); err != nil {
+		t.Fatal(err)
+	}
+	code := f.mailer.lastCode(t)
+
+	login, err := f.magic.Redeem(RedeemMagicLinkRequest{Code: code, NewPassword: "short"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if login.Success || !hasProblem(login.Errors, "password_min_length") || login.Errors[0].Field != "newPassword" {
+		t.Fatalf("Redeem() = %+v, want password_min_length at newPassword", login)
+	}
+	if len(f.links.links) != 1 {
+		t.Error("the link was used up by a password that doesn't meet the policy")
+	}
+}
+
+func TestExternalLoginNeedsLoginToLink(t *testing.T) {
+	f := newUserFixture(t)
+	identity := ExternalLoginRequest{Provider: "google", Subject: "s1", Email: "ADA@example.com", EmailVerified: true, TrustEmail: true}
+
+	// the provider's word for the email isn't enough to take over the
+	// account
+	resp, err := f.svc.ExternalLogin(identity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Success || !hasProblem(resp.Errors, "identity_link_required") {
+		t.Fatalf("ExternalLogin() = %+v, want identity_link_required", resp)
+	}
+	if len(f.identities.identities) != 0 {
+		t.Fatal("the identity was linked without a login")
+	}
+
+	other := identity
+	other.Email = "mallory@example.com"
+	login, err := f.svc.Login(UserLoginRequest{Email: f.user.Email, Password: "old password", Link: &other})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if login.Success || !hasProblem(login.Errors, "identity_email_mismatch") {
+		t.Fatalf("Login() = %+v, want identity_email_mismatch", login)
+	}
+
+	login, err = f.svc.Login(UserLoginRequest{Email: f.user.Email, Password: "old password", Link: &identity})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !login.Success {
+		t.Fatalf("Login() = %+v, want success", login)
+	}
+	if resp, err = f.svc.ExternalLogin(identity); err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Success || resp.User.ID != f.user.ID {
+		t.Fatalf("ExternalLogin() = %+v, want the linked user", resp)
+	}
+}
+
+func TestExternalLoginTrustedEmail(t *testing.T) {
+	f := newUserFixture(t)
+
+	resp, err := f.svc.ExternalLogin(ExternalLoginRequest{Provider: "azure", Subject: "s1", Email: "grace@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Success || !hasProblem(resp.Errors, "identity_email_unverified") {
+		t.Fatalf("ExternalLogin() = %+v, want identity_email_unverified", resp)
+	}
+
+	resp, err = f.svc.ExternalLogin(ExternalLoginRequest{Provider: "azure", Subject: "s1", Email: "grace@example.com", TrustEmail: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Success {
+		t.Fatalf("ExternalLogin() = %+v, want a new user", resp)
+	}
+	if resp.User.EmailVerified {
+		t.Error("the new user's email is verified, though the provider didn't say so")
+	}
+}
+
+func TestExternalLoginChecksMFA(t *testing.T) {
+	f := newUserFixture(t)
+	key := []byte("12345678901234567890")
+	f.user.MFAEnabled = true
+	f.user.MFASecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(key)
+	f.users.users[f.user.ID] = f.user
+	f.identities.identities = append(f.identities.identities, sendkey.UserIdentity{Provider: "google", Subject: "s1", UserID: f.user.ID})
+
+	identity := ExternalLoginRequest{Provider: "google", Subject: "s1", Email: f.user.Email, EmailVerified: true}
+	resp, err := f.svc.ExternalLogin(identity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Success || !hasProblem(resp.Errors, "mfa_code_required") {
+		t.Fatalf("ExternalLogin() = %+v, want mfa_code_required", resp)
+	}
+
+	identity.MFACode = currentTOTP(t, f.user.MFASecret)
+	if resp, err = f.svc.ExternalLogin(identity); err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Success {
+		t.Fatalf("ExternalLogin() = %+v, want success", resp)
+	}
+}
+
+func TestMagicLinksNeedAMailer(t *testing.T) {
+	f := newUserFixture(t)
+	magic := NewMagicLinkService(f.svc, f.links, nil, []byte("signing key"), "https://sendkey.example", time.Minute)
+
+	resp, err := magic.Send(f.user.Email)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Success || !hasProblem(resp.Errors, "magic_links_unavailable") {
+		t.Fatalf("Send() = %+v, want magic_links_unavailable", resp)
+	}
+	if len(f.links.links) != 0 {
+		t.Error("a link was created that can't be sent")
+	}
+}
+
+func TestMagicLinksRequireSSO(t *testing.T) {
+	f := newUserFixture(t)
+	if _, err := f.magic.Send(f.user.Email); err != nil {
+		t.Fatal(err)
+	}
+	code := f.mailer.lastCode(t)
+	f.svc.RequireSSO(SSOOrg{Name: "Example", Domains: []string{"example.com"}})
+
+	resp, err := f.magic.Send(f.user.Email)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Success || !hasProblem(resp.Errors, "sso_required") {
+		t.Fatalf("Send() = %+v, want sso_required", resp)
+	}
+
+	// a link sent before the org required SSO can't be used either
+	login, err := f.magic.Redeem(RedeemMagicLinkRequest{Code: code})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if login.Success || !hasProblem(login.Errors, "sso_required") {
+		t.Fatalf("Redeem() = %+v, want sso_required", login)
+	}
+}
+
+// racedMagicLinks finds links another redemption deletes first.
+type racedMagicLinks struct {
+	*memoryMagicLinks
+}
+
+func (m racedMagicLinks) Find(id uuid.UUID) (*sendkey.MagicLink, error) {
+	l, err := m.memoryMagicLinks.Find(id)
+	delete(m.links, id)
+	return l, err
+}
+
+func TestMagicLinkRedeemedOnce(t *testing.T) {
+	f := newUserFixture(t)
+	magic := NewMagicLinkService(f.svc, racedMagicLinks{f.links}, f.mailer, []byte("signing key"), "https://sendkey.example", time.Minute)
+	if _, err := magic.Send(f.user.Email); err != nil {
+		t.Fatal(err)
+	}
+
+	login, err := magic.Redeem(RedeemMagicLinkRequest{Code: f.mailer.lastCode(t)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if login.Success || !hasProblem(login.Errors, "magic_link_invalid") {
+		t.Fatalf("Redeem() = %+v, want magic_link_invalid", login)
+	}
+}
+
+// currentTOTP returns the code for the secret's current time step.
+func currentTOTP(t *testing.T, secret string) string {
+	t.Helper()
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return totp(key, uint64(time.Now().Unix()/int64(totpPeriod/time.Second)))
+}
+
+func TestMFANeedsConfirming(t *testing.T) {
+	f := newUserFixture(t)
+	enabled, err := f.svc.EnableMFA(f.user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a secret that never made it into the authenticator doesn't lock the
+	// user out
+	login, err := f.svc.Login(UserLoginRequest{Email: f.user.Email, Password: "old password"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !login.Success {
+		t.Fatalf("Login() = %+v, want MFA not required yet", login)
+	}
+
+	resp, err := f.svc.ConfirmMFA(ConfirmMFARequest{UserID: f.user.ID, Code: "000000"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Success || !hasProblem(resp.Errors, "mfa_code_invalid") {
+		t.Fatalf("ConfirmMFA() = %+v, want mfa_code_invalid", resp)
+	}
+
+	code := currentTOTP(t, enabled.Secret)
+	if resp, err = f.svc.ConfirmMFA(ConfirmMFARequest{UserID: f.user.ID, Code: code}); err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Success || len(resp.RecoveryCodes) != recoveryCodeCount {
+		t.Fatalf("ConfirmMFA() = %+v, want MFA enabled with recovery codes", resp)
+	}
+
+	// the code used to confirm can't be replayed to log in
+	if login, err = f.svc.Login(UserLoginRequest{Email: f.user.Email, Password: "old password", MFACode: code}); err != nil {
+		t.Fatal(err)
+	}
+	if login.Success || !hasProblem(login.Errors, "mfa_code_invalid") {
+		t.Fatalf("Login() = %+v, want the replayed code refused", login)
+	}
+
+	// recovery codes can only be used once
+	recovery := resp.RecoveryCodes[0]
+	if login, err = f.svc.Login(UserLoginRequest{Email: f.user.Email, Password: "old password", MFACode: recovery}); err != nil {
+		t.Fatal(err)
+	}
+	if !login.Success {
+		t.Fatalf("Login() = %+v, want the recovery code accepted", login)
+	}
+	if login, err = f.svc.Login(UserLoginRequest{Email: f.user.Email, Password: "old password", MFACode: recovery}); err != nil {
+		t.Fatal(err)
+	}
+	if login.Success {
+		t.Fatal("a recovery code was accepted twice")
+	}
+}
@@ -0,0 +1,45 @@
+package app
+
+import "strings"
+
+// Validator collects the problems with a request, each pointing at the field
+// it's about, so they're all reported at once and a client can show each one
+// next to its input.
+type Validator struct {
+	problems []Problem
+}
+
+// Require adds the code's problem for the field if the value is blank,
+// returning whether it wasn't.
+func (v *Validator) Require(field, value, code string) bool {
+	return v.Check(strings.TrimSpace(value) != "", field, code)
+}
+
+// Check adds the code's problem for the field unless ok, returning ok.
+func (v *Validator) Check(ok bool, field, code string, args ...interface{}) bool {
+	if !ok {
+		v.problems = append(v.problems, problem(code, args...).at(field))
+	}
+	return ok
+}
+
+// Add adds problems found by another check, pointing the ones that aren't
+// about a field yet at the field.
+func (v *Validator) Add(field string, problems ...Problem) {
+	for _, p := range problems {
+		if p.Field == "" {
+			p.Field = field
+		}
+		v.problems = append(v.problems, p)
+	}
+}
+
+// Valid reports whether no problems were found.
+func (v *Validator) Valid() bool {
+	return len(v.problems) == 0
+}
+
+// Problems returns the problems found, in the order they were.
+func (v *Validator) Problems() []Problem {
+	return v.problems
+}
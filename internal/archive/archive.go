@@ -0,0 +1,74 @@
+// Package archive writes entry outcomes purged from the history to cold
+// storage. Each archiver implements app.Archiver.
+//
+// Batches are written as gzipped JSON lines, one outcome per line, which
+// Athena, BigQuery, and Spark read directly. Parquet isn't built in; convert
+// the archive with those tools if it's needed.
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/gavinwade12/sendkey"
+)
+
+// objectKey names a batch by its first outcome, partitioned by day, e.g.
+// "outcomes/2022/04/06/20220406T120000Z-<entry ID>.jsonl.gz". Archiving a
+// batch again after its purge failed starts with the same outcome, so it
+// replaces the batch instead of duplicating it.
+func objectKey(outcomes []sendkey.EntryOutcome) string {
+	first := outcomes[0]
+	at := first.AtUTC.UTC()
+	return "outcomes/" + at.Format("2006/01/02") + "/" + at.Format("20060102T150405Z") + "-" + first.EntryID.String() + ".jsonl.gz"
+}
+
+func encode(outcomes []sendkey.EntryOutcome) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	enc := json.NewEncoder(zw)
+	for _, o := range outcomes {
+		if err := enc.Encode(o); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Dir archives batches as files under a directory, e.g. a mounted network
+// share, or one synced to object storage by another tool.
+type Dir struct {
+	path string
+}
+
+func NewDir(path string) *Dir {
+	return &Dir{path}
+}
+
+func (d *Dir) Archive(outcomes []sendkey.EntryOutcome) error {
+	if len(outcomes) == 0 {
+		return nil
+	}
+	b, err := encode(outcomes)
+	if err != nil {
+		return err
+	}
+
+	p := filepath.Join(d.path, filepath.FromSlash(objectKey(outcomes)))
+	if err = os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+		return err
+	}
+	// written under another name and renamed, so a partial batch is never
+	// left where the complete one goes
+	tmp := p + ".tmp"
+	if err = os.WriteFile(tmp, b, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, p)
+}
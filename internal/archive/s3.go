@@ -0,0 +1,158 @@
+package archive
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gavinwade12/sendkey"
+	"github.com/gavinwade12/sendkey/internal/sigv4"
+)
+
+var httpClient = &http.Client{Timeout: time.Minute}
+
+// S3 archives batches as objects in an S3 bucket, or a bucket in any storage
+// with S3's API, like MinIO or R2, through its endpoint.
+//
+// Credentials are read from the AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, and
+// AWS_SESSION_TOKEN environment variables.
+type S3 struct {
+	endpoint string
+	region   string
+	bucket   string
+	prefix   string
+}
+
+// NewS3 returns an archiver for the bucket, with the objects' keys starting
+// with prefix. An empty endpoint uses AWS's for the region.
+func NewS3(endpoint, region, bucket, prefix string) *S3 {
+	if endpoint == "" {
+		endpoint = "https://s3." + region + ".amazonaws.com"
+	}
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	return &S3{strings.TrimSuffix(endpoint, "/"), region, bucket, prefix}
+}
+
+func (s *S3) Archive(outcomes []sendkey.EntryOutcome) error {
+	if len(outcomes) == 0 {
+		return nil
+	}
+	body, err := encode(outcomes)
+	if err != nil {
+		return err
+	}
+	return s.Put(objectKey(outcomes), "application/gzip", body)
+}
+
+// Put stores the body as the object at the key, after the prefix.
+func (s *S3) Put(key, contentType string, body []byte) error {
+	res, err := s.do(http.MethodPut, key, nil, contentType, body)
+	if err != nil {
+		return fmt.Errorf("s3 put: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("s3 put: unexpected status %d: %s", res.StatusCode, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// Get returns the object at the key, after the prefix.
+func (s *S3) Get(key string) ([]byte, error) {
+	res, err := s.do(http.MethodGet, key, nil, "", nil)
+	if err != nil {
+		return nil, fmt.Errorf("s3 get: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return nil, fmt.Errorf("s3 get: unexpected status %d: %s", res.StatusCode, bytes.TrimSpace(msg))
+	}
+	return io.ReadAll(res.Body)
+}
+
+// List returns up to limit keys, without the prefix, that sort after the
+// key after. An empty after lists from the first key.
+func (s *S3) List(after string, limit int) ([]string, error) {
+	query := url.Values{
+		"list-type": {"2"},
+		"prefix":    {s.prefix},
+		"max-keys":  {strconv.Itoa(limit)},
+	}
+	if after != "" {
+		query.Set("start-after", s.prefix+after)
+	}
+	res, err := s.do(http.MethodGet, "", query, "", nil)
+	if err != nil {
+		return nil, fmt.Errorf("s3 list: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return nil, fmt.Errorf("s3 list: unexpected status %d: %s", res.StatusCode, bytes.TrimSpace(msg))
+	}
+
+	var result struct {
+		Contents []struct{ Key string }
+	}
+	if err = xml.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("s3 list: %w", err)
+	}
+	keys := make([]string, len(result.Contents))
+	for i, c := range result.Contents {
+		keys[i] = strings.TrimPrefix(c.Key, s.prefix)
+	}
+	return keys, nil
+}
+
+// do sends a signed request for the object at the key, after the prefix, or
+// for the bucket if the key is empty.
+func (s *S3) do(method, key string, query url.Values, contentType string, body []byte) (*http.Response, error) {
+	// path-style, so buckets with dots in their names and other storage
+	// work the same
+	path := "/" + s.bucket
+	if key != "" {
+		path += "/" + s.prefix + key
+	}
+	u, err := url.Parse(s.endpoint + path)
+	if err != nil {
+		return nil, err
+	}
+	u.RawQuery = canonicalQuery(query)
+
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	creds, err := sigv4.EnvCredentials()
+	if err != nil {
+		return nil, err
+	}
+	bodyHash := sha256.Sum256(body)
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(bodyHash[:]))
+	sigv4.Sign(req, body, "s3", s.region, creds, time.Now())
+	return httpClient.Do(req)
+}
+
+// canonicalQuery encodes the query the way SigV4 signs it: sorted by name,
+// with spaces as %20 rather than +.
+func canonicalQuery(query url.Values) string {
+	return strings.ReplaceAll(query.Encode(), "+", "%20")
+}
@@ -0,0 +1,112 @@
+// Package audit writes sendkey's audit events to sinks outside the primary
+// database: a file, Loki, a Kafka topic, or an S3 bucket. Each sink
+// implements app.AuditSink.
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/gavinwade12/sendkey"
+)
+
+// File appends events to a file as lines of JSON, for log shippers to tail.
+// It can also read the events back for verification.
+type File struct {
+	path string
+
+	mu sync.Mutex
+	f  *os.File
+	// cursorSeq and cursorOffset are where the last read stopped, so
+	// reading the file in batches doesn't start over each time.
+	cursorSeq    int64
+	cursorOffset int64
+}
+
+// NewFile opens the file for appending, creating it if it doesn't exist.
+func NewFile(path string) (*File, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	return &File{path: path, f: f}, nil
+}
+
+func (s *File) Write(e sendkey.AuditEvent) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.f.Write(append(line, '\n'))
+	return err
+}
+
+func (s *File) Events(afterSeq int64, limit int) ([]sendkey.AuditEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.Open(s.path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var offset int64
+	if afterSeq > 0 && afterSeq == s.cursorSeq {
+		if offset, err = f.Seek(s.cursorOffset, io.SeekStart); err != nil {
+			return nil, err
+		}
+	}
+
+	result := []sendkey.AuditEvent{}
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			// a partial line is still being written
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var e sendkey.AuditEvent
+		if err = json.Unmarshal(bytes.TrimSpace(line), &e); err != nil {
+			return nil, fmt.Errorf("reading %s at offset %d: %w", s.path, offset, err)
+		}
+		if e.Seq <= afterSeq {
+			offset += int64(len(line))
+			continue
+		}
+
+		// a rewritten event replaces the one before it
+		if n := len(result); n > 0 && result[n-1].Seq == e.Seq {
+			result[n-1] = e
+		} else if n == limit {
+			break
+		} else {
+			result = append(result, e)
+		}
+		offset += int64(len(line))
+	}
+
+	if len(result) > 0 {
+		s.cursorSeq = result[len(result)-1].Seq
+		s.cursorOffset = offset
+	}
+
+	return result, nil
+}
+
+func (s *File) Close() error {
+	return s.f.Close()
+}
@@ -0,0 +1,26 @@
+package audit
+
+import (
+	"github.com/gavinwade12/sendkey"
+	"github.com/gavinwade12/sendkey/internal/events"
+)
+
+// kafkaKey keys every event the same, so they all land on one partition and
+// consumers read them in the chain's order.
+const kafkaKey = "sendkey-audit"
+
+// Kafka produces events to a Kafka topic through the REST proxy that entry
+// events are published through. Events can't be read back for verification;
+// consume the topic for them instead.
+type Kafka struct {
+	producer *events.Kafka
+}
+
+// NewKafka returns a sink for the topic on the REST proxy at proxyURL.
+func NewKafka(proxyURL, topic string) *Kafka {
+	return &Kafka{events.NewKafka(proxyURL, topic)}
+}
+
+func (s *Kafka) Write(e sendkey.AuditEvent) error {
+	return s.producer.Produce(kafkaKey, e)
+}
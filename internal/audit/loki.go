@@ -0,0 +1,68 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gavinwade12/sendkey"
+)
+
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// Loki pushes events to Grafana Loki as JSON log lines. Events can't be read
+// back for verification; query Loki for them instead.
+type Loki struct {
+	url    string
+	labels map[string]string
+}
+
+// NewLoki returns a sink for the Loki server at url. The events' stream is
+// labeled with the labels and an "app" label of "sendkey".
+func NewLoki(url string, labels map[string]string) *Loki {
+	stream := map[string]string{"app": "sendkey"}
+	for k, v := range labels {
+		stream[k] = v
+	}
+
+	return &Loki{strings.TrimSuffix(url, "/"), stream}
+}
+
+func (s *Loki) Write(e sendkey.AuditEvent) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	type stream struct {
+		Stream map[string]string `json:"stream"`
+		Values [][2]string       `json:"values"`
+	}
+	body, err := json.Marshal(map[string][]stream{
+		"streams": {{
+			Stream: s.labels,
+			Values: [][2]string{{strconv.FormatInt(e.AtUTC.UnixNano(), 10), string(line)}},
+		}},
+	})
+	if err != nil {
+		return err
+	}
+
+	res, err := httpClient.Post(s.url+"/loki/api/v1/push", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("loki push: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusNoContent && res.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("loki push: unexpected status %d: %s", res.StatusCode, bytes.TrimSpace(msg))
+	}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/gavinwade12/sendkey"
+	"github.com/gavinwade12/sendkey/internal/archive"
+)
+
+// S3 writes each event as a JSON object in an S3 bucket, keyed by its
+// sequence number so the bucket lists in the chain's order. A rewritten event
+// replaces the object before it. It can also read the events back for
+// verification.
+type S3 struct {
+	bucket *archive.S3
+}
+
+// NewS3 returns a sink for the bucket, with the objects' keys starting with
+// prefix. An empty endpoint uses AWS's for the region.
+func NewS3(endpoint, region, bucket, prefix string) *S3 {
+	return &S3{archive.NewS3(endpoint, region, bucket, prefix)}
+}
+
+func (s *S3) Write(e sendkey.AuditEvent) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return s.bucket.Put(eventKey(e.Seq), "application/json", body)
+}
+
+func (s *S3) Events(afterSeq int64, limit int) ([]sendkey.AuditEvent, error) {
+	after := ""
+	if afterSeq > 0 {
+		after = eventKey(afterSeq)
+	}
+	keys, err := s.bucket.List(after, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]sendkey.AuditEvent, 0, len(keys))
+	for _, key := range keys {
+		body, err := s.bucket.Get(key)
+		if err != nil {
+			return nil, err
+		}
+		var e sendkey.AuditEvent
+		if err = json.Unmarshal(body, &e); err != nil {
+			return nil, fmt.Errorf("audit: decoding %s: %w", key, err)
+		}
+		events = append(events, e)
+	}
+	return events, nil
+}
+
+// eventKey zero-pads the sequence number, so keys sort like the numbers do.
+func eventKey(seq int64) string {
+	return fmt.Sprintf("%020d.json", seq)
+}
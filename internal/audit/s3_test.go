@@ -0,0 +1,94 @@
+package audit
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gavinwade12/sendkey"
+)
+
+// fakeS3 is a bucket with just enough of S3's API for the sink.
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (b *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 ") {
+		http.Error(w, "unsigned", http.StatusForbidden)
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	key := strings.TrimPrefix(r.URL.Path, "/audit-bucket/")
+	switch {
+	case r.Method == http.MethodPut:
+		body, _ := io.ReadAll(r.Body)
+		b.objects[key] = body
+	case r.URL.Path == "/audit-bucket" && r.URL.Query().Get("list-type") == "2":
+		q := r.URL.Query()
+		var keys []string
+		for k := range b.objects {
+			if strings.HasPrefix(k, q.Get("prefix")) && k > q.Get("start-after") {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		if max, _ := strconv.Atoi(q.Get("max-keys")); len(keys) > max {
+			keys = keys[:max]
+		}
+		var result struct {
+			XMLName  xml.Name `xml:"ListBucketResult"`
+			Contents []struct{ Key string }
+		}
+		for _, k := range keys {
+			result.Contents = append(result.Contents, struct{ Key string }{k})
+		}
+		xml.NewEncoder(w).Encode(result)
+	default:
+		body, ok := b.objects[key]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(body)
+	}
+}
+
+func TestS3ReadsEventsBackInOrder(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	bucket := &fakeS3{objects: map[string][]byte{}}
+	srv := httptest.NewServer(bucket)
+	defer srv.Close()
+
+	s := NewS3(srv.URL, "us-east-1", "audit-bucket", "audit")
+	// seq 10 sorts before seq 9 unless the keys are padded
+	for _, seq := range []int64{1, 9, 10, 9} {
+		if err := s.Write(sendkey.AuditEvent{Seq: seq, Hash: strconv.FormatInt(seq, 10)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(bucket.objects) != 3 {
+		t.Fatalf("got %d objects, want the rewritten event to replace its object", len(bucket.objects))
+	}
+
+	events, err := s.Events(1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[0].Seq != 9 || events[1].Seq != 10 {
+		t.Fatalf("Events(1, 10) = %+v, want seqs 9 and 10", events)
+	}
+	if events, err = s.Events(0, 1); err != nil || len(events) != 1 || events[0].Seq != 1 {
+		t.Fatalf("Events(0, 1) = %+v, %v, want seq 1", events, err)
+	}
+}
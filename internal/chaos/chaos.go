@@ -0,0 +1,58 @@
+// Package chaos injects latency and errors into HTTP requests and store calls
+// so retries, timeouts, and circuit breakers can be exercised end to end. It's
+// for testing environments only and does nothing unless explicitly configured.
+package chaos
+
+import (
+	"errors"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// ErrInjected is returned by store calls that were chosen to fail.
+var ErrInjected = errors.New("chaos: injected fault")
+
+// Faults configures what's injected. Rates are probabilities from 0 to 1 and
+// are applied independently to each request or call.
+type Faults struct {
+	Latency     time.Duration
+	LatencyRate float64
+	ErrorRate   float64
+}
+
+// Enabled reports whether any faults will ever be injected.
+func (f Faults) Enabled() bool {
+	return (f.Latency > 0 && f.LatencyRate > 0) || f.ErrorRate > 0
+}
+
+// inject sleeps and returns ErrInjected according to the configured rates.
+func (f Faults) inject() error {
+	if f.Latency > 0 && rand.Float64() < f.LatencyRate {
+		time.Sleep(f.Latency)
+	}
+	if rand.Float64() < f.ErrorRate {
+		return ErrInjected
+	}
+
+	return nil
+}
+
+// Middleware delays requests and fails them with a 503 before they reach h.
+func Middleware(h http.Handler, f Faults) http.Handler {
+	if !f.Enabled() {
+		return h
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := f.inject(); err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"statusCode":503,"message":"` + err.Error() + `"}` + "\n"))
+			return
+		}
+
+		h.ServeHTTP(w, r)
+	})
+}
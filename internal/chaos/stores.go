@@ -0,0 +1,648 @@
+package chaos
+
+import (
+	"time"
+
+	"github.com/gavinwade12/sendkey"
+	"github.com/gavinwade12/sendkey/internal/app"
+	"github.com/google/uuid"
+)
+
+// The store decorators below inject faults before every repository call. A
+// failed call never reaches the wrapped store.
+
+type UserStore struct {
+	next app.UserRepository
+	f    Faults
+}
+
+func NewUserStore(next app.UserRepository, f Faults) *UserStore {
+	return &UserStore{next, f}
+}
+
+func (s *UserStore) Find(id uuid.UUID) (*sendkey.User, error) {
+	if err := s.f.inject(); err != nil {
+		return nil, err
+	}
+	return s.next.Find(id)
+}
+
+func (s *UserStore) FindByEmail(email string) (*sendkey.User, error) {
+	if err := s.f.inject(); err != nil {
+		return nil, err
+	}
+	return s.next.FindByEmail(email)
+}
+
+func (s *UserStore) Create(u sendkey.User) error {
+	if err := s.f.inject(); err != nil {
+		return err
+	}
+	return s.next.Create(u)
+}
+
+func (s *UserStore) Update(u sendkey.User) error {
+	if err := s.f.inject(); err != nil {
+		return err
+	}
+	return s.next.Update(u)
+}
+
+func (s *UserStore) Delete(id uuid.UUID) error {
+	if err := s.f.inject(); err != nil {
+		return err
+	}
+	return s.next.Delete(id)
+}
+
+func (s *UserStore) DeleteDeactivatedBefore(t time.Time) (int64, error) {
+	if err := s.f.inject(); err != nil {
+		return 0, err
+	}
+	return s.next.DeleteDeactivatedBefore(t)
+}
+
+func (s *UserStore) AdvanceMFAStep(id uuid.UUID, step int64) (bool, error) {
+	if err := s.f.inject(); err != nil {
+		return false, err
+	}
+	return s.next.AdvanceMFAStep(id, step)
+}
+
+func (s *UserStore) Search(query, afterEmail string, limit int) ([]sendkey.User, error) {
+	if err := s.f.inject(); err != nil {
+		return nil, err
+	}
+	return s.next.Search(query, afterEmail, limit)
+}
+
+type UserIdentityStore struct {
+	next app.UserIdentityRepository
+	f    Faults
+}
+
+func NewUserIdentityStore(next app.UserIdentityRepository, f Faults) *UserIdentityStore {
+	return &UserIdentityStore{next, f}
+}
+
+func (s *UserIdentityStore) Create(i sendkey.UserIdentity) error {
+	if err := s.f.inject(); err != nil {
+		return err
+	}
+	return s.next.Create(i)
+}
+
+func (s *UserIdentityStore) Find(provider, subject string) (*sendkey.UserIdentity, error) {
+	if err := s.f.inject(); err != nil {
+		return nil, err
+	}
+	return s.next.Find(provider, subject)
+}
+
+type RecoveryCodeStore struct {
+	next app.RecoveryCodeRepository
+	f    Faults
+}
+
+func NewRecoveryCodeStore(next app.RecoveryCodeRepository, f Faults) *RecoveryCodeStore {
+	return &RecoveryCodeStore{next, f}
+}
+
+func (s *RecoveryCodeStore) Create(c sendkey.RecoveryCode) error {
+	if err := s.f.inject(); err != nil {
+		return err
+	}
+	return s.next.Create(c)
+}
+
+func (s *RecoveryCodeStore) DeleteByUserAndHash(userID uuid.UUID, codeHash string) (bool, error) {
+	if err := s.f.inject(); err != nil {
+		return false, err
+	}
+	return s.next.DeleteByUserAndHash(userID, codeHash)
+}
+
+func (s *RecoveryCodeStore) DeleteByUserID(userID uuid.UUID) error {
+	if err := s.f.inject(); err != nil {
+		return err
+	}
+	return s.next.DeleteByUserID(userID)
+}
+
+type MagicLinkStore struct {
+	next app.MagicLinkRepository
+	f    Faults
+}
+
+func NewMagicLinkStore(next app.MagicLinkRepository, f Faults) *MagicLinkStore {
+	return &MagicLinkStore{next, f}
+}
+
+func (s *MagicLinkStore) Create(l sendkey.MagicLink) error {
+	if err := s.f.inject(); err != nil {
+		return err
+	}
+	return s.next.Create(l)
+}
+
+func (s *MagicLinkStore) Find(id uuid.UUID) (*sendkey.MagicLink, error) {
+	if err := s.f.inject(); err != nil {
+		return nil, err
+	}
+	return s.next.Find(id)
+}
+
+func (s *MagicLinkStore) Delete(id uuid.UUID) (bool, error) {
+	if err := s.f.inject(); err != nil {
+		return false, err
+	}
+	return s.next.Delete(id)
+}
+
+type AuditStore struct {
+	next app.AuditRepository
+	f    Faults
+}
+
+func NewAuditStore(next app.AuditRepository, f Faults) *AuditStore {
+	return &AuditStore{next, f}
+}
+
+func (s *AuditStore) Advance(build func(head sendkey.AuditHead) sendkey.AuditEvent, write func(sendkey.AuditEvent) error) error {
+	if err := s.f.inject(); err != nil {
+		return err
+	}
+	return s.next.Advance(build, write)
+}
+
+func (s *AuditStore) Head() (sendkey.AuditHead, error) {
+	if err := s.f.inject(); err != nil {
+		return sendkey.AuditHead{}, err
+	}
+	return s.next.Head()
+}
+
+func (s *AuditStore) CreateAnchor(a sendkey.AuditAnchor) error {
+	if err := s.f.inject(); err != nil {
+		return err
+	}
+	return s.next.CreateAnchor(a)
+}
+
+func (s *AuditStore) LastAnchor() (*sendkey.AuditAnchor, error) {
+	if err := s.f.inject(); err != nil {
+		return nil, err
+	}
+	return s.next.LastAnchor()
+}
+
+func (s *AuditStore) Anchors(afterSeq int64, limit int) ([]sendkey.AuditAnchor, error) {
+	if err := s.f.inject(); err != nil {
+		return nil, err
+	}
+	return s.next.Anchors(afterSeq, limit)
+}
+
+type AuditSink struct {
+	next app.AuditSink
+	f    Faults
+}
+
+func NewAuditSink(next app.AuditSink, f Faults) *AuditSink {
+	return &AuditSink{next, f}
+}
+
+func (s *AuditSink) Write(e sendkey.AuditEvent) error {
+	if err := s.f.inject(); err != nil {
+		return err
+	}
+	return s.next.Write(e)
+}
+
+type EntryStore struct {
+	next app.EntryRepository
+	f    Faults
+}
+
+func NewEntryStore(next app.EntryRepository, f Faults) *EntryStore {
+	return &EntryStore{next, f}
+}
+
+func (s *EntryStore) Find(id uuid.UUID) (*sendkey.Entry, error) {
+	if err := s.f.inject(); err != nil {
+		return nil, err
+	}
+	return s.next.Find(id)
+}
+
+func (s *EntryStore) FindByUserID(userID uuid.UUID) ([]sendkey.Entry, error) {
+	if err := s.f.inject(); err != nil {
+		return nil, err
+	}
+	return s.next.FindByUserID(userID)
+}
+
+func (s *EntryStore) Create(e sendkey.Entry) error {
+	if err := s.f.inject(); err != nil {
+		return err
+	}
+	return s.next.Create(e)
+}
+
+func (s *EntryStore) Delete(id uuid.UUID) error {
+	if err := s.f.inject(); err != nil {
+		return err
+	}
+	return s.next.Delete(id)
+}
+
+func (s *EntryStore) IncrementInvalidAttempts(id uuid.UUID) (int, error) {
+	if err := s.f.inject(); err != nil {
+		return 0, err
+	}
+	return s.next.IncrementInvalidAttempts(id)
+}
+
+func (s *EntryStore) CreateClaimedEntry(e sendkey.ClaimedEntry) error {
+	if err := s.f.inject(); err != nil {
+		return err
+	}
+	return s.next.CreateClaimedEntry(e)
+}
+
+func (s *EntryStore) FindClaimedEntry(entryID uuid.UUID) (*sendkey.ClaimedEntry, error) {
+	if err := s.f.inject(); err != nil {
+		return nil, err
+	}
+	return s.next.FindClaimedEntry(entryID)
+}
+
+func (s *EntryStore) CreateExpiredEntry(e sendkey.ExpiredEntry) error {
+	if err := s.f.inject(); err != nil {
+		return err
+	}
+	return s.next.CreateExpiredEntry(e)
+}
+
+func (s *EntryStore) CreateClaimReceipt(r sendkey.ClaimReceipt) error {
+	if err := s.f.inject(); err != nil {
+		return err
+	}
+	return s.next.CreateClaimReceipt(r)
+}
+
+func (s *EntryStore) FindChallenges(entryID uuid.UUID) ([]sendkey.EntryChallenge, error) {
+	if err := s.f.inject(); err != nil {
+		return nil, err
+	}
+	return s.next.FindChallenges(entryID)
+}
+
+func (s *EntryStore) FindStaleKeyVersion(current, limit int) ([]sendkey.Entry, error) {
+	if err := s.f.inject(); err != nil {
+		return nil, err
+	}
+	return s.next.FindStaleKeyVersion(current, limit)
+}
+
+func (s *EntryStore) CountByKeyVersion(version int) (int, error) {
+	if err := s.f.inject(); err != nil {
+		return 0, err
+	}
+	return s.next.CountByKeyVersion(version)
+}
+
+func (s *EntryStore) UpdateEncryption(id uuid.UUID, value []byte, keyVersion int) error {
+	if err := s.f.inject(); err != nil {
+		return err
+	}
+	return s.next.UpdateEncryption(id, value, keyVersion)
+}
+
+func (s *EntryStore) FindUnexpired(now time.Time, after uuid.UUID, limit int) ([]sendkey.Entry, error) {
+	if err := s.f.inject(); err != nil {
+		return nil, err
+	}
+	return s.next.FindUnexpired(now, after, limit)
+}
+
+func (s *EntryStore) CreateDeferral(d sendkey.EntryDeferral) error {
+	if err := s.f.inject(); err != nil {
+		return err
+	}
+	return s.next.CreateDeferral(d)
+}
+
+func (s *EntryStore) FindDeferrals(entryID uuid.UUID) ([]sendkey.EntryDeferral, error) {
+	if err := s.f.inject(); err != nil {
+		return nil, err
+	}
+	return s.next.FindDeferrals(entryID)
+}
+
+func (s *EntryStore) FindDueDeferrals(now time.Time, limit int) ([]sendkey.EntryDeferral, error) {
+	if err := s.f.inject(); err != nil {
+		return nil, err
+	}
+	return s.next.FindDueDeferrals(now, limit)
+}
+
+func (s *EntryStore) MarkDeferralReminded(id uuid.UUID, at time.Time) error {
+	if err := s.f.inject(); err != nil {
+		return err
+	}
+	return s.next.MarkDeferralReminded(id, at)
+}
+
+func (s *EntryStore) SaveOTP(o sendkey.EntryOTP) error {
+	if err := s.f.inject(); err != nil {
+		return err
+	}
+	return s.next.SaveOTP(o)
+}
+
+func (s *EntryStore) FindOTP(entryID uuid.UUID) (*sendkey.EntryOTP, error) {
+	if err := s.f.inject(); err != nil {
+		return nil, err
+	}
+	return s.next.FindOTP(entryID)
+}
+
+func (s *EntryStore) CreateDelegation(d sendkey.EntryDelegation) error {
+	if err := s.f.inject(); err != nil {
+		return err
+	}
+	return s.next.CreateDelegation(d)
+}
+
+func (s *EntryStore) FindDelegation(id uuid.UUID) (*sendkey.EntryDelegation, error) {
+	if err := s.f.inject(); err != nil {
+		return nil, err
+	}
+	return s.next.FindDelegation(id)
+}
+
+func (s *EntryStore) CreateClaimLink(l sendkey.ClaimLink) error {
+	if err := s.f.inject(); err != nil {
+		return err
+	}
+	return s.next.CreateClaimLink(l)
+}
+
+func (s *EntryStore) FindClaimLinks(entryID uuid.UUID) ([]sendkey.ClaimLink, error) {
+	if err := s.f.inject(); err != nil {
+		return nil, err
+	}
+	return s.next.FindClaimLinks(entryID)
+}
+
+func (s *EntryStore) CreateWithEscrow(e sendkey.Entry, escrow sendkey.EntryEscrow) error {
+	if err := s.f.inject(); err != nil {
+		return err
+	}
+	return s.next.CreateWithEscrow(e, escrow)
+}
+
+func (s *EntryStore) FindEscrow(entryID uuid.UUID) (*sendkey.EntryEscrow, error) {
+	if err := s.f.inject(); err != nil {
+		return nil, err
+	}
+	return s.next.FindEscrow(entryID)
+}
+
+func (s *EntryStore) RetainEscrow(entryID uuid.UUID, claimedAt, purgeAt time.Time) error {
+	if err := s.f.inject(); err != nil {
+		return err
+	}
+	return s.next.RetainEscrow(entryID, claimedAt, purgeAt)
+}
+
+func (s *EntryStore) DeleteEscrow(entryID uuid.UUID) error {
+	if err := s.f.inject(); err != nil {
+		return err
+	}
+	return s.next.DeleteEscrow(entryID)
+}
+
+func (s *EntryStore) PurgeEscrows(before time.Time) (int64, error) {
+	if err := s.f.inject(); err != nil {
+		return 0, err
+	}
+	return s.next.PurgeEscrows(before)
+}
+
+func (s *EntryStore) FindOutcomesBefore(before time.Time, limit int) ([]sendkey.EntryOutcome, error) {
+	if err := s.f.inject(); err != nil {
+		return nil, err
+	}
+	return s.next.FindOutcomesBefore(before, limit)
+}
+
+func (s *EntryStore) DeleteOutcomes(entryIDs []uuid.UUID) (int64, error) {
+	if err := s.f.inject(); err != nil {
+		return 0, err
+	}
+	return s.next.DeleteOutcomes(entryIDs)
+}
+
+func (s *EntryStore) SuppressRecipient(email string, at time.Time) error {
+	if err := s.f.inject(); err != nil {
+		return err
+	}
+	return s.next.SuppressRecipient(email, at)
+}
+
+func (s *EntryStore) UnsuppressRecipient(email string) (bool, error) {
+	if err := s.f.inject(); err != nil {
+		return false, err
+	}
+	return s.next.UnsuppressRecipient(email)
+}
+
+func (s *EntryStore) RecipientSuppressed(email string) (bool, error) {
+	if err := s.f.inject(); err != nil {
+		return false, err
+	}
+	return s.next.RecipientSuppressed(email)
+}
+
+func (s *EntryStore) SaveFlags(userID, entryID uuid.UUID, f sendkey.EntryFlags) error {
+	if err := s.f.inject(); err != nil {
+		return err
+	}
+	return s.next.SaveFlags(userID, entryID, f)
+}
+
+func (s *EntryStore) FindFlags(userID uuid.UUID) (map[uuid.UUID]sendkey.EntryFlags, error) {
+	if err := s.f.inject(); err != nil {
+		return nil, err
+	}
+	return s.next.FindFlags(userID)
+}
+
+func (s *EntryStore) Revoke(filter sendkey.EntryFilter, at time.Time) ([]sendkey.ExpiredEntry, error) {
+	if err := s.f.inject(); err != nil {
+		return nil, err
+	}
+	return s.next.Revoke(filter, at)
+}
+
+func (s *EntryStore) FindOutcomes(filter sendkey.OutcomeFilter, before time.Time, beforeID uuid.UUID, limit int) ([]sendkey.EntryOutcome, error) {
+	if err := s.f.inject(); err != nil {
+		return nil, err
+	}
+	return s.next.FindOutcomes(filter, before, beforeID, limit)
+}
+
+// refreshTokenRepository matches the API's refresh token repository, which
+// isn't part of the app package.
+type refreshTokenRepository interface {
+	Create(sendkey.RefreshToken) error
+	FindByTokenAndUser(token string, userID uuid.UUID) (*sendkey.RefreshToken, error)
+	Delete(uuid.UUID) error
+	DeleteByUserID(uuid.UUID) error
+}
+
+type RefreshTokenStore struct {
+	next refreshTokenRepository
+	f    Faults
+}
+
+func NewRefreshTokenStore(next refreshTokenRepository, f Faults) *RefreshTokenStore {
+	return &RefreshTokenStore{next, f}
+}
+
+func (s *RefreshTokenStore) Create(t sendkey.RefreshToken) error {
+	if err := s.f.inject(); err != nil {
+		return err
+	}
+	return s.next.Create(t)
+}
+
+func (s *RefreshTokenStore) FindByTokenAndUser(token string, userID uuid.UUID) (*sendkey.RefreshToken, error) {
+	if err := s.f.inject(); err != nil {
+		return nil, err
+	}
+	return s.next.FindByTokenAndUser(token, userID)
+}
+
+func (s *RefreshTokenStore) Delete(id uuid.UUID) error {
+	if err := s.f.inject(); err != nil {
+		return err
+	}
+	return s.next.Delete(id)
+}
+
+func (s *RefreshTokenStore) DeleteByUserID(userID uuid.UUID) error {
+	if err := s.f.inject(); err != nil {
+		return err
+	}
+	return s.next.DeleteByUserID(userID)
+}
+
+// idempotencyRepository matches the API's idempotency key repository, which
+// isn't part of the app package.
+type idempotencyRepository interface {
+	Reserve(sendkey.IdempotencyRecord) (bool, error)
+	Find(keyHash string) (*sendkey.IdempotencyRecord, error)
+	Complete(keyHash string, statusCode int, response []byte) error
+	Delete(keyHash string) error
+	Purge(before time.Time) (int64, error)
+}
+
+type IdempotencyStore struct {
+	next idempotencyRepository
+	f    Faults
+}
+
+func NewIdempotencyStore(next idempotencyRepository, f Faults) *IdempotencyStore {
+	return &IdempotencyStore{next, f}
+}
+
+func (s *IdempotencyStore) Reserve(rec sendkey.IdempotencyRecord) (bool, error) {
+	if err := s.f.inject(); err != nil {
+		return false, err
+	}
+	return s.next.Reserve(rec)
+}
+
+func (s *IdempotencyStore) Find(keyHash string) (*sendkey.IdempotencyRecord, error) {
+	if err := s.f.inject(); err != nil {
+		return nil, err
+	}
+	return s.next.Find(keyHash)
+}
+
+func (s *IdempotencyStore) Complete(keyHash string, statusCode int, response []byte) error {
+	if err := s.f.inject(); err != nil {
+		return err
+	}
+	return s.next.Complete(keyHash, statusCode, response)
+}
+
+func (s *IdempotencyStore) Delete(keyHash string) error {
+	if err := s.f.inject(); err != nil {
+		return err
+	}
+	return s.next.Delete(keyHash)
+}
+
+func (s *IdempotencyStore) Purge(before time.Time) (int64, error) {
+	if err := s.f.inject(); err != nil {
+		return 0, err
+	}
+	return s.next.Purge(before)
+}
+
+type UsageStore struct {
+	next app.UsageRepository
+	f    Faults
+}
+
+func NewUsageStore(next app.UsageRepository, f Faults) *UsageStore {
+	return &UsageStore{next, f}
+}
+
+func (s *UsageStore) AddEntryCreated(userID uuid.UUID, day time.Time) error {
+	if err := s.f.inject(); err != nil {
+		return err
+	}
+	return s.next.AddEntryCreated(userID, day)
+}
+
+func (s *UsageStore) EntriesCreated(userID uuid.UUID, day time.Time) (int, error) {
+	if err := s.f.inject(); err != nil {
+		return 0, err
+	}
+	return s.next.EntriesCreated(userID, day)
+}
+
+func (s *UsageStore) CountLiveEntries(userID uuid.UUID, now time.Time) (int, error) {
+	if err := s.f.inject(); err != nil {
+		return 0, err
+	}
+	return s.next.CountLiveEntries(userID, now)
+}
+
+type AnonymousSenderStore struct {
+	next app.AnonymousSenderRepository
+	f    Faults
+}
+
+func NewAnonymousSenderStore(next app.AnonymousSenderRepository, f Faults) *AnonymousSenderStore {
+	return &AnonymousSenderStore{next, f}
+}
+
+func (s *AnonymousSenderStore) Record(addressHash string, at time.Time) (*sendkey.AnonymousSender, error) {
+	if err := s.f.inject(); err != nil {
+		return nil, err
+	}
+	return s.next.Record(addressHash, at)
+}
+
+func (s *AnonymousSenderStore) Find(id uuid.UUID) (*sendkey.AnonymousSender, error) {
+	if err := s.f.inject(); err != nil {
+		return nil, err
+	}
+	return s.next.Find(id)
+}
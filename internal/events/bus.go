@@ -0,0 +1,87 @@
+package events
+
+import (
+	"sync"
+
+	"github.com/gavinwade12/sendkey/internal/app"
+)
+
+// Bus delivers events to the process's subscribers, such as the API's event
+// streams, then publishes them through the next publisher, if there is one.
+// Subscribers only get the events published in their own process, so behind
+// a load balancer, a subscriber only hears about what its instance did.
+type Bus struct {
+	next app.EventPublisher
+
+	mu   sync.Mutex
+	subs map[*Subscription]struct{}
+}
+
+// NewBus returns a bus publishing events through next after delivering them.
+// next can be nil.
+func NewBus(next app.EventPublisher) *Bus {
+	return &Bus{next: next, subs: make(map[*Subscription]struct{})}
+}
+
+// Publish delivers the event to the subscribers it matches and publishes it
+// through the next publisher. Delivery never waits on a subscriber: one
+// whose buffer is full has fallen behind, and is closed.
+func (b *Bus) Publish(e app.Event) error {
+	b.mu.Lock()
+	for s := range b.subs {
+		if !s.match(e) {
+			continue
+		}
+		select {
+		case s.events <- e:
+		default:
+			b.remove(s)
+		}
+	}
+	b.mu.Unlock()
+
+	if b.next == nil {
+		return nil
+	}
+	return b.next.Publish(e)
+}
+
+// Subscribe returns a subscription to the events match accepts, buffering up
+// to buffer of them.
+func (b *Bus) Subscribe(buffer int, match func(app.Event) bool) *Subscription {
+	s := &Subscription{bus: b, match: match, events: make(chan app.Event, buffer)}
+
+	b.mu.Lock()
+	b.subs[s] = struct{}{}
+	b.mu.Unlock()
+
+	return s
+}
+
+// remove closes the subscription. b.mu must be held.
+func (b *Bus) remove(s *Subscription) {
+	if _, ok := b.subs[s]; ok {
+		delete(b.subs, s)
+		close(s.events)
+	}
+}
+
+// Subscription is a subscriber's events on a bus.
+type Subscription struct {
+	bus    *Bus
+	match  func(app.Event) bool
+	events chan app.Event
+}
+
+// Events returns the subscription's events. The channel's closed when the
+// subscription is, including when the subscriber falls behind.
+func (s *Subscription) Events() <-chan app.Event {
+	return s.events
+}
+
+// Close stops the subscription. It's safe to call more than once.
+func (s *Subscription) Close() {
+	s.bus.mu.Lock()
+	s.bus.remove(s)
+	s.bus.mu.Unlock()
+}
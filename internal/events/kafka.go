@@ -0,0 +1,63 @@
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gavinwade12/sendkey/internal/app"
+)
+
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// Kafka publishes events to a Kafka topic through a Kafka REST proxy, such as
+// Confluent's, so no broker client is needed. Events are keyed by their type.
+type Kafka struct {
+	url string
+}
+
+// NewKafka returns an exporter for the topic on the REST proxy at proxyURL.
+func NewKafka(proxyURL, topic string) *Kafka {
+	return &Kafka{fmt.Sprintf("%s/topics/%s", strings.TrimSuffix(proxyURL, "/"), url.PathEscape(topic))}
+}
+
+func (k *Kafka) Publish(e app.Event) error {
+	return k.Produce(e.Type, e)
+}
+
+// Produce sends one record with the key, and the value as JSON, to the topic.
+func (k *Kafka) Produce(key string, value interface{}) error {
+	type record struct {
+		Key   string      `json:"key"`
+		Value interface{} `json:"value"`
+	}
+	body, err := json.Marshal(map[string][]record{"records": {{key, value}}})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, k.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/vnd.kafka.json.v2+json")
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("kafka publish: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("kafka publish: unexpected status %d: %s", res.StatusCode, bytes.TrimSpace(msg))
+	}
+
+	return nil
+}
@@ -0,0 +1,72 @@
+package events
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/gavinwade12/sendkey/internal/app"
+)
+
+// fakeNATS accepts one connection, and rejects publishes to subjects other
+// than allowed the way a server enforcing permissions does.
+func fakeNATS(t *testing.T, allowed string, connects chan<- string) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		fmt.Fprint(conn, "INFO {\"server_id\":\"fake\",\"auth_required\":true}\r\n")
+		r := bufio.NewReader(conn)
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			switch fields := strings.Fields(line); fields[0] {
+			case "CONNECT":
+				connects <- strings.TrimSpace(strings.TrimPrefix(line, "CONNECT "))
+			case "PUB":
+				r.ReadString('\n')
+				if fields[1] != allowed {
+					fmt.Fprintf(conn, "-ERR 'Permissions Violation for Publish to %s'\r\n", fields[1])
+				}
+			case "PING":
+				fmt.Fprint(conn, "PONG\r\n")
+			}
+		}
+	}()
+
+	return ln.Addr().String()
+}
+
+func TestNATSReturnsRejectedPublishes(t *testing.T) {
+	connects := make(chan string, 1)
+	addr := fakeNATS(t, "sendkey.entry.created", connects)
+
+	n, err := NewNATS("nats://"+addr, "", NATSOptions{Token: "s3cret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = n.Publish(app.Event{Type: "entry.created"}); err != nil {
+		t.Fatalf("Publish() = %v, want it accepted", err)
+	}
+	if connect := <-connects; !strings.Contains(connect, `"auth_token":"s3cret"`) {
+		t.Errorf("got CONNECT %s, want the token", connect)
+	}
+
+	err = n.Publish(app.Event{Type: "entry.claimed"})
+	if err == nil || !strings.Contains(err.Error(), "Permissions Violation") {
+		t.Fatalf("Publish() = %v, want the server's error", err)
+	}
+}
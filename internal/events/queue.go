@@ -0,0 +1,102 @@
+// Package events exports sendkey's domain events to streaming platforms so
+// downstream pipelines don't have to poll the API. Each exporter implements
+// app.EventPublisher.
+package events
+
+import (
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gavinwade12/sendkey/internal/app"
+)
+
+// Queue publishes events in the background through another publisher so
+// requests don't wait on the broker. Failed publishes are retried with
+// backoff. When the queue is full, new events are dropped and logged; the
+// audit log is the durable record.
+type Queue struct {
+	// dropped is first so it's 64-bit aligned for atomic access
+	dropped int64
+	next    app.EventPublisher
+	events  chan app.Event
+	wg      sync.WaitGroup
+
+	// MaxAttempts is how many times an event is published before it's
+	// dropped.
+	MaxAttempts int
+	// RetryDelay is the wait after the first failed publish. It doubles
+	// with each failure up to a minute.
+	RetryDelay time.Duration
+}
+
+// NewQueue returns a queue holding up to depth events and starts a worker
+// publishing them in order.
+func NewQueue(next app.EventPublisher, depth int) *Queue {
+	q := &Queue{
+		next:        next,
+		events:      make(chan app.Event, depth),
+		MaxAttempts: 5,
+		RetryDelay:  time.Second,
+	}
+	q.wg.Add(1)
+	go q.work()
+
+	return q
+}
+
+// Publish queues the event. It never fails; events that don't fit are
+// dropped.
+func (q *Queue) Publish(e app.Event) error {
+	select {
+	case q.events <- e:
+	default:
+		q.drop(e, "the queue is full")
+	}
+	return nil
+}
+
+// Dropped returns the number of events that have been dropped.
+func (q *Queue) Dropped() int64 {
+	return atomic.LoadInt64(&q.dropped)
+}
+
+// Close waits for the queued events to be published. The queue can't be
+// published to after it's closed.
+func (q *Queue) Close() {
+	close(q.events)
+	q.wg.Wait()
+}
+
+func (q *Queue) work() {
+	defer q.wg.Done()
+
+	for e := range q.events {
+		q.deliver(e)
+	}
+}
+
+func (q *Queue) deliver(e app.Event) {
+	delay := q.RetryDelay
+	for attempt := 1; ; attempt++ {
+		err := q.next.Publish(e)
+		if err == nil {
+			return
+		}
+		if attempt >= q.MaxAttempts {
+			q.drop(e, err.Error())
+			return
+		}
+
+		time.Sleep(delay)
+		if delay *= 2; delay > time.Minute {
+			delay = time.Minute
+		}
+	}
+}
+
+func (q *Queue) drop(e app.Event, reason string) {
+	atomic.AddInt64(&q.dropped, 1)
+	slog.Warn("events: dropped an event", "type", e.Type, "id", e.ID, "reason", reason)
+}
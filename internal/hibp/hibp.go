@@ -0,0 +1,65 @@
+// Package hibp checks passwords against the Have I Been Pwned Pwned Passwords
+// range API. Only the first five characters of the password's SHA-1 hash are
+// sent, so the password itself never leaves the server.
+package hibp
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const defaultBaseURL = "https://api.pwnedpasswords.com"
+
+type Client struct {
+	baseURL string
+	client  *http.Client
+}
+
+func NewClient() *Client {
+	return &Client{
+		baseURL: defaultBaseURL,
+		client:  &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// Breached reports whether the password appears in a known breach.
+func (c *Client) Breached(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:5], hash[5:]
+
+	req, err := http.NewRequest(http.MethodGet, c.baseURL+"/range/"+prefix, nil)
+	if err != nil {
+		return false, err
+	}
+	// padding hides the number of matching suffixes from observers
+	req.Header.Set("Add-Padding", "true")
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("querying pwned passwords: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("querying pwned passwords: unexpected status %d", res.StatusCode)
+	}
+
+	s := bufio.NewScanner(res.Body)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		i := strings.IndexByte(line, ':')
+		if i == -1 || line[:i] != suffix {
+			continue
+		}
+
+		// padded entries have a count of zero
+		return strings.TrimSpace(line[i+1:]) != "0", nil
+	}
+
+	return false, s.Err()
+}
@@ -0,0 +1,472 @@
+package i18n
+
+var spanish = Catalog{
+	"There was a problem": "Hubo un problema",
+	"This value is shown only once. Copy it now; it can't be viewed again.": "Este valor solo se muestra una vez. Cópielo ahora; no podrá volver a verlo.",
+	"This entry doesn't exist, has expired, or has already been claimed.":   "Esta entrada no existe, ha caducado o ya ha sido reclamada.",
+	"Secret":                      "Secreto",
+	"Show value":                  "Mostrar valor",
+	"Entry value":                 "Valor de la entrada",
+	"The form could not be read.": "No se pudo leer el formulario.",
+	"This page has expired. Please reopen the link you were sent.": "Esta página ha caducado. Vuelva a abrir el enlace que recibió.",
+	"Invalid entry ID.":          "ID de entrada no válido.",
+	"Invalid secret.":            "Secreto no válido.",
+	"Invalid challenge answers.": "Respuestas de verificación no válidas.",
+	"Too many attempts have been made, and the entry has been expired.": "Se han realizado demasiados intentos y la entrada ha caducado.",
+
+	"A secret is required.": "Se requiere un secreto.",
+	"This entry is end-to-end encrypted and can only be claimed with the sendkey CLI.": "Esta entrada está cifrada de extremo a extremo y solo puede reclamarse con la CLI de sendkey.",
+	"Username": "Usuario",
+	"Hostname": "Nombre de host",
+
+	"Open this link in a browser to view the entry.": "Abra este enlace en un navegador para ver la entrada.",
+	"Too many requests. Try again later.":            "Demasiadas solicitudes. Inténtelo de nuevo más tarde.",
+
+	"The request body is invalid: %s":                                            "El cuerpo de la solicitud no es válido: %s",
+	"An email is required.":                                                      "Se requiere un correo electrónico.",
+	"An account with the specified email already exists.":                        "Ya existe una cuenta con el correo electrónico especificado.",
+	"No user could be found with the specified email.":                           "No se encontró ningún usuario con el correo electrónico especificado.",
+	"Invalid user ID.":                                                           "ID de usuario no válido.",
+	"This account has been deleted.":                                             "Esta cuenta ha sido eliminada.",
+	"A password is required.":                                                    "Se requiere una contraseña.",
+	"The specified password is invalid.":                                         "La contraseña especificada no es válida.",
+	"The current password is invalid.":                                           "La contraseña actual no es válida.",
+	"A new password is required.":                                                "Se requiere una nueva contraseña.",
+	"Your password was reset. Choose a new one to log in.":                       "Su contraseña fue restablecida. Elija una nueva para iniciar sesión.",
+	"Your password was reset. Set a new one with a login link.":                  "Su contraseña fue restablecida. Establezca una nueva con un enlace de inicio de sesión.",
+	"The password must be at least %d characters.":                               "La contraseña debe tener al menos %d caracteres.",
+	"The password must contain an uppercase letter.":                             "La contraseña debe contener una letra mayúscula.",
+	"The password must contain a lowercase letter.":                              "La contraseña debe contener una letra minúscula.",
+	"The password must contain a digit.":                                         "La contraseña debe contener un dígito.",
+	"The password must contain a symbol.":                                        "La contraseña debe contener un símbolo.",
+	"The password has appeared in a data breach. Please choose a different one.": "La contraseña ha aparecido en una filtración de datos. Elija otra.",
+	"An MFA code is required.":                                                   "Se requiere un código MFA.",
+	"The specified MFA code is invalid.":                                         "El código MFA especificado no es válido.",
+	"Enable MFA to get a secret before confirming it.":                           "Active MFA para obtener un secreto antes de confirmarlo.",
+	"MFA is already enabled.":                                                    "MFA ya está activado.",
+	"Invalid login link.":                                                        "Enlace de inicio de sesión no válido.",
+	"The login link has expired.":                                                "El enlace de inicio de sesión ha caducado.",
+	"An identity provider and subject are required.":                             "Se requieren un proveedor de identidad y un sujeto.",
+	"The identity provider did not supply a verified email.":                     "El proveedor de identidad no proporcionó un correo electrónico verificado.",
+	"An account with this email exists. Log in to it to link the identity.":      "Ya existe una cuenta con este correo electrónico. Inicie sesión en ella para vincular la identidad.",
+	"The identity's email doesn't match the account's.":                          "El correo electrónico de la identidad no coincide con el de la cuenta.",
+	"The identity is linked to another account.":                                 "La identidad está vinculada a otra cuenta.",
+	"The external login is invalid or expired. Log in again.":                    "El inicio de sesión externo no es válido o ha caducado. Inicie sesión de nuevo.",
+	"A refresh token is required.":                                               "Se requiere un token de actualización.",
+	"Invalid refresh token.":                                                     "Token de actualización no válido.",
+	"A sender ID is required.":                                                   "Se requiere un ID de remitente.",
+	"A name is required.":                                                        "Se requiere un nombre.",
+	"A send to email is required.":                                               "Se requiere un correo electrónico de destino.",
+	"A value is required.":                                                       "Se requiere un valor.",
+	"The value can't be larger than %d bytes.":                                   "El valor no puede superar los %d bytes.",
+	"Duration must be greater than 0.":                                           "La duración debe ser mayor que 0.",
+	"Only one of duration and durationSeconds can be set.":                       "Solo se puede establecer duration o durationSeconds.",
+	"Challenge %d requires a question and an answer.":                            "La verificación %d requiere una pregunta y una respuesta.",
+	"An entry can't have more than %d challenges.":                               "Una entrada no puede tener más de %d verificaciones.",
+	"Challenge %d's question can't be longer than %d characters.":                "La pregunta de la verificación %d no puede superar los %d caracteres.",
+	"Challenge %d's answer can't be longer than %d bytes.":                       "La respuesta de la verificación %d no puede superar los %d bytes.",
+	"The type must be one of %q, %q, %q, or %q.":                                 "El tipo debe ser %q, %q, %q o %q.",
+	"A username can only be set for a password or SSH key.":                      "Solo se puede establecer un usuario para una contraseña o una clave SSH.",
+	"A hostname can only be set for an SSH key.":                                 "Solo se puede establecer un nombre de host para una clave SSH.",
+	"The username and hostname can't be longer than %d characters.":              "El usuario y el nombre de host no pueden superar los %d caracteres.",
+	"A value and secret can't be sent with an end-to-end entry.":                 "No se pueden enviar un valor y un secreto con una entrada cifrada de extremo a extremo.",
+	"The cipher isn't supported.":                                                "El cifrado no es compatible.",
+	"The cipher isn't supported by the server's key.":                            "La clave del servidor no admite el cifrado.",
+	"The nonce is the wrong size for the cipher.":                                "El nonce no tiene el tamaño correcto para el cifrado.",
+	"A ciphertext is required.":                                                  "Se requiere un texto cifrado.",
+	"The KDF must be argon2id with a salt and non-zero costs.":                   "La KDF debe ser argon2id con una sal y costes distintos de cero.",
+	"The KDF can't use more than %d KiB, %d passes, or %d threads.":              "La KDF no puede usar más de %d KiB, %d pasadas o %d hilos.",
+	"Only one of length and words can be set.":                                   "Solo se puede establecer length o words.",
+	"The length must be between %d and %d.":                                      "La longitud debe estar entre %d y %d.",
+	"The number of words must be between %d and %d.":                             "El número de palabras debe estar entre %d y %d.",
+	"The charset must be one of %q, %q, %q, or %q.":                              "El conjunto de caracteres debe ser %q, %q, %q o %q.",
+	"A value can't be sent with a generated one.":                                "No se puede enviar un valor junto con uno generado.",
+	"An end-to-end entry can't use a generated value.":                           "Una entrada cifrada de extremo a extremo no puede usar un valor generado.",
+	"An identical entry was sent to this recipient in the last %d seconds.":      "Se envió una entrada idéntica a este destinatario en los últimos %d segundos.",
+	"The entry must become available before it expires.":                         "La entrada debe estar disponible antes de caducar.",
+	"This entry can't be claimed until %s.":                                      "Esta entrada no se puede reclamar hasta el %s.",
+	"%s requires signing in with single sign-on.":                                "%s requiere iniciar sesión con inicio de sesión único.",
+	"You must log in as the recipient to claim this entry.":                      "Debe iniciar sesión como destinatario para reclamar esta entrada.",
+	"This entry was sent to a different email address.":                          "Esta entrada se envió a otra dirección de correo electrónico.",
+	"Your email must be verified to claim this entry. Log in with single sign-on or a login link to verify it.": "Su correo electrónico debe estar verificado para reclamar esta entrada. Inicie sesión con inicio de sesión único o un enlace de inicio de sesión para verificarlo.",
+	"This entry requires logging in as the recipient and can only be claimed with the sendkey CLI.":             "Esta entrada requiere iniciar sesión como destinatario y solo se puede reclamar con la CLI de sendkey.",
+	"The delay can't be negative.":               "El retraso no puede ser negativo.",
+	"An entry can only be deferred %d times.":    "Una entrada solo se puede aplazar %d veces.",
+	"The entry expires too soon to be deferred.": "La entrada caduca demasiado pronto para aplazarla.",
+	"Remind me tomorrow":                         "Recordármelo mañana",
+	"We'll email you a reminder at %s.":          "Le enviaremos un recordatorio por correo electrónico el %s.",
+
+	"At least one filter is required to revoke entries.": "Se requiere al menos un filtro para revocar entradas.",
+
+	"One-time codes aren't enabled on this server.":                              "Los códigos de un solo uso no están habilitados en este servidor.",
+	"Login links aren't enabled on this server.":                                 "Los enlaces de inicio de sesión no están habilitados en este servidor.",
+	"A one-time code was emailed to the recipient. Enter it to claim the entry.": "Se envió un código de un solo uso al destinatario por correo electrónico. Introdúzcalo para reclamar la entrada.",
+	"The one-time code is invalid or has expired.":                               "El código de un solo uso no es válido o ha caducado.",
+	"Code from your email":                                                       "Código de su correo electrónico",
+
+	"Entries created without logging in can't last longer than %s.": "Las entradas creadas sin iniciar sesión no pueden durar más de %s.",
+
+	"The role must be %q or %q.":                                                                      "El rol debe ser %q o %q.",
+	"This account has been disabled. Contact your administrator.":                                     "Esta cuenta ha sido deshabilitada. Póngase en contacto con su administrador.",
+	"This account doesn't have a password. Log in with a magic link or single sign-on, then set one.": "Esta cuenta no tiene contraseña. Inicie sesión con un enlace mágico o con inicio de sesión único y luego establezca una.",
+	"The cursor is invalid.":                                                                          "El cursor no es válido.",
+
+	"The value digest is invalid.": "El resumen del valor no es válido.",
+	"The value doesn't match its digest. It may have been corrupted in transit.": "El valor no coincide con su resumen. Es posible que se haya dañado durante el envío.",
+
+	"The value can't be larger than %d bytes on your plan.":                                             "El valor no puede superar los %d bytes en su plan.",
+	"Your plan allows %d entries a day. Try again tomorrow.":                                            "Su plan permite %d entradas al día. Inténtelo de nuevo mañana.",
+	"Your plan allows %d entries waiting to be claimed at once. Wait for some to be claimed or expire.": "Su plan permite %d entradas pendientes de reclamar a la vez. Espere a que se reclamen o caduquen algunas.",
+
+	"You already claimed the entry. Its value can't be shown again.": "Usted ya reclamó la entrada. Su valor no se puede volver a mostrar.",
+	"The entry was already claimed by someone else.":                 "Otra persona ya reclamó la entrada.",
+
+	"A delegated claim token can last at most %d minutes.":                  "Un token de reclamación delegada puede durar como máximo %d minutos.",
+	"End-to-end encrypted entries can't be claimed with a delegated token.": "Las entradas cifradas de extremo a extremo no se pueden reclamar con un token delegado.",
+	"The delegated claim token is invalid or has expired.":                  "El token de reclamación delegada no es válido o ha caducado.",
+
+	"A reason is required to release an escrow.":    "Se requiere un motivo para liberar una custodia.",
+	"The entry has no escrow, or it's been purged.": "La entrada no tiene custodia o ya se ha eliminado.",
+
+	"The recipient has opted out of receiving entries sent with sendkey, so this entry can't be sent to them. Share it with them another way.": "El destinatario ha optado por no recibir entradas enviadas con sendkey, por lo que no se le puede enviar esta entrada. Compártala con él de otra manera.",
+
+	"Stop receiving entries": "Dejar de recibir entradas",
+	"This link is invalid.":  "Este enlace no es válido.",
+	"You've opted out. Entries can no longer be sent to this address with sendkey.":                            "Ha optado por no recibir entradas. Ya no se pueden enviar entradas a esta dirección con sendkey.",
+	"Opt this address out of receiving entries sent with sendkey? Senders will be told it can't receive them.": "¿Desea que esta dirección deje de recibir entradas enviadas con sendkey? Se informará a los remitentes de que no puede recibirlas.",
+	"Opt out": "Darse de baja",
+
+	"Log in to sendkey": "Iniciar sesión en sendkey",
+	"Log in with this link? It can only be used once.": "¿Desea iniciar sesión con este enlace? Solo se puede usar una vez.",
+	"New password, if you were asked to choose one":    "Nueva contraseña, si le pidieron que elija una",
+	"MFA code, if you've turned on MFA":                "Código MFA, si ha activado MFA",
+	"Log in":                                           "Iniciar sesión",
+
+	"This link has expired or was replaced by a newer one. Ask the sender to resend the entry.": "Este enlace ha caducado o ha sido sustituido por uno más reciente. Pida al remitente que vuelva a enviar la entrada.",
+	"The link's lifetime can't be negative.":                                                    "La duración del enlace no puede ser negativa.",
+
+	"A digest can't be sent with a generated value.": "No se puede enviar un resumen con un valor generado.",
+
+	"The entry to replace must be one of your entries that hasn't been claimed or expired.": "La entrada que se va a sustituir debe ser una de sus entradas que no se haya reclamado ni haya caducado.",
+
+	"The field must be of type %s.":                "El campo debe ser de tipo %s.",
+	"The field must be a string in the %s format.": "El campo debe ser una cadena con el formato %s.",
+
+	"The sender requires you to acknowledge the following to claim this entry: %s": "El remitente requiere que reconozca lo siguiente para reclamar esta entrada: %s",
+	"The acknowledgment can't be longer than %d characters.":                       "El texto de reconocimiento no puede superar los %d caracteres.",
+	"The sender asks you to acknowledge this before the value is shown:":           "El remitente le pide que reconozca esto antes de mostrar el valor:",
+}
+
+var french = Catalog{
+	"There was a problem": "Un problème est survenu",
+	"This value is shown only once. Copy it now; it can't be viewed again.": "Cette valeur n'est affichée qu'une seule fois. Copiez-la maintenant ; elle ne pourra plus être consultée.",
+	"This entry doesn't exist, has expired, or has already been claimed.":   "Cette entrée n'existe pas, a expiré ou a déjà été récupérée.",
+	"Secret":                      "Secret",
+	"Show value":                  "Afficher la valeur",
+	"Entry value":                 "Valeur de l'entrée",
+	"The form could not be read.": "Le formulaire n'a pas pu être lu.",
+	"This page has expired. Please reopen the link you were sent.": "Cette page a expiré. Veuillez rouvrir le lien qui vous a été envoyé.",
+	"Invalid entry ID.":          "Identifiant d'entrée invalide.",
+	"Invalid secret.":            "Secret invalide.",
+	"Invalid challenge answers.": "Réponses aux questions invalides.",
+	"Too many attempts have been made, and the entry has been expired.": "Trop de tentatives ont été effectuées et l'entrée a expiré.",
+
+	"A secret is required.": "Un secret est requis.",
+	"This entry is end-to-end encrypted and can only be claimed with the sendkey CLI.": "Cette entrée est chiffrée de bout en bout et ne peut être récupérée qu'avec la CLI sendkey.",
+	"Username": "Nom d'utilisateur",
+	"Hostname": "Nom d'hôte",
+
+	"Open this link in a browser to view the entry.": "Ouvrez ce lien dans un navigateur pour afficher l'entrée.",
+	"Too many requests. Try again later.":            "Trop de requêtes. Veuillez réessayer plus tard.",
+
+	"The request body is invalid: %s":                                            "Le corps de la requête est invalide : %s",
+	"An email is required.":                                                      "Une adresse e-mail est requise.",
+	"An account with the specified email already exists.":                        "Un compte avec cette adresse e-mail existe déjà.",
+	"No user could be found with the specified email.":                           "Aucun utilisateur n'a été trouvé avec cette adresse e-mail.",
+	"Invalid user ID.":                                                           "Identifiant d'utilisateur invalide.",
+	"This account has been deleted.":                                             "Ce compte a été supprimé.",
+	"A password is required.":                                                    "Un mot de passe est requis.",
+	"The specified password is invalid.":                                         "Le mot de passe indiqué est invalide.",
+	"The current password is invalid.":                                           "Le mot de passe actuel est invalide.",
+	"A new password is required.":                                                "Un nouveau mot de passe est requis.",
+	"Your password was reset. Choose a new one to log in.":                       "Votre mot de passe a été réinitialisé. Choisissez-en un nouveau pour vous connecter.",
+	"Your password was reset. Set a new one with a login link.":                  "Votre mot de passe a été réinitialisé. Définissez-en un nouveau avec un lien de connexion.",
+	"The password must be at least %d characters.":                               "Le mot de passe doit contenir au moins %d caractères.",
+	"The password must contain an uppercase letter.":                             "Le mot de passe doit contenir une lettre majuscule.",
+	"The password must contain a lowercase letter.":                              "Le mot de passe doit contenir une lettre minuscule.",
+	"The password must contain a digit.":                                         "Le mot de passe doit contenir un chiffre.",
+	"The password must contain a symbol.":                                        "Le mot de passe doit contenir un symbole.",
+	"The password has appeared in a data breach. Please choose a different one.": "Le mot de passe est apparu dans une fuite de données. Veuillez en choisir un autre.",
+	"An MFA code is required.":                                                   "Un code MFA est requis.",
+	"The specified MFA code is invalid.":                                         "Le code MFA indiqué est invalide.",
+	"Enable MFA to get a secret before confirming it.":                           "Activez la MFA pour obtenir un secret avant de le confirmer.",
+	"MFA is already enabled.":                                                    "La MFA est déjà activée.",
+	"Invalid login link.":                                                        "Lien de connexion invalide.",
+	"The login link has expired.":                                                "Le lien de connexion a expiré.",
+	"An identity provider and subject are required.":                             "Un fournisseur d'identité et un sujet sont requis.",
+	"The identity provider did not supply a verified email.":                     "Le fournisseur d'identité n'a pas fourni d'adresse e-mail vérifiée.",
+	"An account with this email exists. Log in to it to link the identity.":      "Un compte avec cette adresse e-mail existe déjà. Connectez-vous à celui-ci pour lier l'identité.",
+	"The identity's email doesn't match the account's.":                          "L'adresse e-mail de l'identité ne correspond pas à celle du compte.",
+	"The identity is linked to another account.":                                 "L'identité est liée à un autre compte.",
+	"The external login is invalid or expired. Log in again.":                    "La connexion externe est invalide ou a expiré. Reconnectez-vous.",
+	"A refresh token is required.":                                               "Un jeton d'actualisation est requis.",
+	"Invalid refresh token.":                                                     "Jeton d'actualisation invalide.",
+	"A sender ID is required.":                                                   "Un identifiant d'expéditeur est requis.",
+	"A name is required.":                                                        "Un nom est requis.",
+	"A send to email is required.":                                               "Une adresse e-mail de destination est requise.",
+	"A value is required.":                                                       "Une valeur est requise.",
+	"The value can't be larger than %d bytes.":                                   "La valeur ne peut pas dépasser %d octets.",
+	"Duration must be greater than 0.":                                           "La durée doit être supérieure à 0.",
+	"Only one of duration and durationSeconds can be set.":                       "Seul l'un de duration et durationSeconds peut être défini.",
+	"Challenge %d requires a question and an answer.":                            "La question %d nécessite une question et une réponse.",
+	"An entry can't have more than %d challenges.":                               "Une entrée ne peut pas avoir plus de %d questions.",
+	"Challenge %d's question can't be longer than %d characters.":                "La question %d ne peut pas dépasser %d caractères.",
+	"Challenge %d's answer can't be longer than %d bytes.":                       "La réponse à la question %d ne peut pas dépasser %d octets.",
+	"The type must be one of %q, %q, %q, or %q.":                                 "Le type doit être %q, %q, %q ou %q.",
+	"A username can only be set for a password or SSH key.":                      "Un nom d'utilisateur ne peut être défini que pour un mot de passe ou une clé SSH.",
+	"A hostname can only be set for an SSH key.":                                 "Un nom d'hôte ne peut être défini que pour une clé SSH.",
+	"The username and hostname can't be longer than %d characters.":              "Le nom d'utilisateur et le nom d'hôte ne peuvent pas dépasser %d caractères.",
+	"A value and secret can't be sent with an end-to-end entry.":                 "Une valeur et un secret ne peuvent pas être envoyés avec une entrée chiffrée de bout en bout.",
+	"The cipher isn't supported.":                                                "L'algorithme de chiffrement n'est pas pris en charge.",
+	"The cipher isn't supported by the server's key.":                            "La clé du serveur ne prend pas en charge l'algorithme de chiffrement.",
+	"The nonce is the wrong size for the cipher.":                                "Le nonce n'a pas la bonne taille pour l'algorithme de chiffrement.",
+	"A ciphertext is required.":                                                  "Un texte chiffré est requis.",
+	"The KDF must be argon2id with a salt and non-zero costs.":                   "La KDF doit être argon2id avec un sel et des coûts non nuls.",
+	"The KDF can't use more than %d KiB, %d passes, or %d threads.":              "La KDF ne peut pas utiliser plus de %d Kio, %d passes ou %d threads.",
+	"Only one of length and words can be set.":                                   "Seul l'un de length et words peut être défini.",
+	"The length must be between %d and %d.":                                      "La longueur doit être comprise entre %d et %d.",
+	"The number of words must be between %d and %d.":                             "Le nombre de mots doit être compris entre %d et %d.",
+	"The charset must be one of %q, %q, %q, or %q.":                              "Le jeu de caractères doit être %q, %q, %q ou %q.",
+	"A value can't be sent with a generated one.":                                "Une valeur ne peut pas être envoyée avec une valeur générée.",
+	"An end-to-end entry can't use a generated value.":                           "Une entrée chiffrée de bout en bout ne peut pas utiliser une valeur générée.",
+	"An identical entry was sent to this recipient in the last %d seconds.":      "Une entrée identique a été envoyée à ce destinataire au cours des %d dernières secondes.",
+	"The entry must become available before it expires.":                         "L'entrée doit devenir disponible avant d'expirer.",
+	"This entry can't be claimed until %s.":                                      "Cette entrée ne peut pas être récupérée avant le %s.",
+	"%s requires signing in with single sign-on.":                                "%s exige une connexion par authentification unique.",
+	"You must log in as the recipient to claim this entry.":                      "Vous devez vous connecter en tant que destinataire pour récupérer cette entrée.",
+	"This entry was sent to a different email address.":                          "Cette entrée a été envoyée à une autre adresse e-mail.",
+	"Your email must be verified to claim this entry. Log in with single sign-on or a login link to verify it.": "Votre e-mail doit être vérifié pour récupérer cette entrée. Connectez-vous par authentification unique ou avec un lien de connexion pour le vérifier.",
+	"This entry requires logging in as the recipient and can only be claimed with the sendkey CLI.":             "Cette entrée nécessite une connexion en tant que destinataire et ne peut être récupérée qu'avec la CLI sendkey.",
+	"The delay can't be negative.":               "Le délai ne peut pas être négatif.",
+	"An entry can only be deferred %d times.":    "Une entrée ne peut être reportée que %d fois.",
+	"The entry expires too soon to be deferred.": "L'entrée expire trop tôt pour être reportée.",
+	"Remind me tomorrow":                         "Me le rappeler demain",
+	"We'll email you a reminder at %s.":          "Nous vous enverrons un rappel par e-mail le %s.",
+
+	"At least one filter is required to revoke entries.": "Au moins un filtre est requis pour révoquer des entrées.",
+
+	"One-time codes aren't enabled on this server.":                              "Les codes à usage unique ne sont pas activés sur ce serveur.",
+	"Login links aren't enabled on this server.":                                 "Les liens de connexion ne sont pas activés sur ce serveur.",
+	"A one-time code was emailed to the recipient. Enter it to claim the entry.": "Un code à usage unique a été envoyé au destinataire par e-mail. Saisissez-le pour récupérer l'entrée.",
+	"The one-time code is invalid or has expired.":                               "Le code à usage unique est invalide ou a expiré.",
+	"Code from your email":                                                       "Code reçu par e-mail",
+
+	"Entries created without logging in can't last longer than %s.": "Les entrées créées sans connexion ne peuvent pas durer plus de %s.",
+
+	"The role must be %q or %q.":                                                                      "Le rôle doit être %q ou %q.",
+	"This account has been disabled. Contact your administrator.":                                     "Ce compte a été désactivé. Contactez votre administrateur.",
+	"This account doesn't have a password. Log in with a magic link or single sign-on, then set one.": "Ce compte n'a pas de mot de passe. Connectez-vous avec un lien magique ou l'authentification unique, puis définissez-en un.",
+	"The cursor is invalid.":                                                                          "Le curseur est invalide.",
+
+	"The value digest is invalid.": "Le condensé de la valeur est invalide.",
+	"The value doesn't match its digest. It may have been corrupted in transit.": "La valeur ne correspond pas à son condensé. Elle a peut-être été corrompue pendant le transfert.",
+
+	"The value can't be larger than %d bytes on your plan.":                                             "La valeur ne peut pas dépasser %d octets avec votre forfait.",
+	"Your plan allows %d entries a day. Try again tomorrow.":                                            "Votre forfait autorise %d entrées par jour. Réessayez demain.",
+	"Your plan allows %d entries waiting to be claimed at once. Wait for some to be claimed or expire.": "Votre forfait autorise %d entrées en attente de récupération à la fois. Attendez que certaines soient récupérées ou expirent.",
+
+	"You already claimed the entry. Its value can't be shown again.": "Vous avez déjà réclamé l'entrée. Sa valeur ne peut pas être affichée à nouveau.",
+	"The entry was already claimed by someone else.":                 "L'entrée a déjà été réclamée par quelqu'un d'autre.",
+
+	"A delegated claim token can last at most %d minutes.":                  "Un jeton de réclamation délégué peut durer au plus %d minutes.",
+	"End-to-end encrypted entries can't be claimed with a delegated token.": "Les entrées chiffrées de bout en bout ne peuvent pas être réclamées avec un jeton délégué.",
+	"The delegated claim token is invalid or has expired.":                  "Le jeton de réclamation délégué est invalide ou a expiré.",
+
+	"A reason is required to release an escrow.":    "Un motif est requis pour libérer un séquestre.",
+	"The entry has no escrow, or it's been purged.": "L'entrée n'a pas de séquestre, ou il a été purgé.",
+
+	"The recipient has opted out of receiving entries sent with sendkey, so this entry can't be sent to them. Share it with them another way.": "Le destinataire a choisi de ne plus recevoir d'entrées envoyées avec sendkey ; cette entrée ne peut donc pas lui être envoyée. Partagez-la avec lui d'une autre manière.",
+
+	"Stop receiving entries": "Ne plus recevoir d'entrées",
+	"This link is invalid.":  "Ce lien n'est pas valide.",
+	"You've opted out. Entries can no longer be sent to this address with sendkey.":                            "Votre désinscription a été prise en compte. Des entrées ne peuvent plus être envoyées à cette adresse avec sendkey.",
+	"Opt this address out of receiving entries sent with sendkey? Senders will be told it can't receive them.": "Désinscrire cette adresse des entrées envoyées avec sendkey ? Les expéditeurs seront informés qu'elle ne peut pas les recevoir.",
+	"Opt out": "Se désinscrire",
+
+	"Log in to sendkey": "Se connecter à sendkey",
+	"Log in with this link? It can only be used once.": "Se connecter avec ce lien ? Il ne peut être utilisé qu'une seule fois.",
+	"New password, if you were asked to choose one":    "Nouveau mot de passe, si l'on vous a demandé d'en choisir un",
+	"MFA code, if you've turned on MFA":                "Code MFA, si vous avez activé la MFA",
+	"Log in":                                           "Se connecter",
+
+	"This link has expired or was replaced by a newer one. Ask the sender to resend the entry.": "Ce lien a expiré ou a été remplacé par un lien plus récent. Demandez à l'expéditeur de renvoyer l'entrée.",
+	"The link's lifetime can't be negative.":                                                    "La durée de validité du lien ne peut pas être négative.",
+
+	"A digest can't be sent with a generated value.": "Un condensé ne peut pas être envoyé avec une valeur générée.",
+
+	"The entry to replace must be one of your entries that hasn't been claimed or expired.": "L'entrée à remplacer doit être l'une de vos entrées qui n'a été ni récupérée ni expirée.",
+
+	"The field must be of type %s.":                "Le champ doit être de type %s.",
+	"The field must be a string in the %s format.": "Le champ doit être une chaîne au format %s.",
+
+	"The sender requires you to acknowledge the following to claim this entry: %s": "L'expéditeur exige que vous reconnaissiez ce qui suit pour récupérer cette entrée : %s",
+	"The acknowledgment can't be longer than %d characters.":                       "Le texte de reconnaissance ne peut pas dépasser %d caractères.",
+	"The sender asks you to acknowledge this before the value is shown:":           "L'expéditeur vous demande de reconnaître ceci avant que la valeur ne soit affichée :",
+}
+
+var german = Catalog{
+	"There was a problem": "Es ist ein Problem aufgetreten",
+	"This value is shown only once. Copy it now; it can't be viewed again.": "Dieser Wert wird nur einmal angezeigt. Kopieren Sie ihn jetzt; er kann nicht erneut angezeigt werden.",
+	"This entry doesn't exist, has expired, or has already been claimed.":   "Dieser Eintrag existiert nicht, ist abgelaufen oder wurde bereits abgerufen.",
+	"Secret":                      "Geheimnis",
+	"Show value":                  "Wert anzeigen",
+	"Entry value":                 "Wert des Eintrags",
+	"The form could not be read.": "Das Formular konnte nicht gelesen werden.",
+	"This page has expired. Please reopen the link you were sent.": "Diese Seite ist abgelaufen. Bitte öffnen Sie den erhaltenen Link erneut.",
+	"Invalid entry ID.":          "Ungültige Eintrags-ID.",
+	"Invalid secret.":            "Ungültiges Geheimnis.",
+	"Invalid challenge answers.": "Ungültige Antworten auf die Sicherheitsfragen.",
+	"Too many attempts have been made, and the entry has been expired.": "Es wurden zu viele Versuche unternommen, und der Eintrag ist abgelaufen.",
+
+	"A secret is required.": "Ein Geheimnis ist erforderlich.",
+	"This entry is end-to-end encrypted and can only be claimed with the sendkey CLI.": "Dieser Eintrag ist Ende-zu-Ende-verschlüsselt und kann nur mit der sendkey-CLI abgerufen werden.",
+	"Username": "Benutzername",
+	"Hostname": "Hostname",
+
+	"Open this link in a browser to view the entry.": "Öffnen Sie diesen Link in einem Browser, um den Eintrag anzuzeigen.",
+	"Too many requests. Try again later.":            "Zu viele Anfragen. Versuchen Sie es später erneut.",
+
+	"The request body is invalid: %s":                                            "Der Anfragetext ist ungültig: %s",
+	"An email is required.":                                                      "Eine E-Mail-Adresse ist erforderlich.",
+	"An account with the specified email already exists.":                        "Ein Konto mit dieser E-Mail-Adresse existiert bereits.",
+	"No user could be found with the specified email.":                           "Es wurde kein Benutzer mit dieser E-Mail-Adresse gefunden.",
+	"Invalid user ID.":                                                           "Ungültige Benutzer-ID.",
+	"This account has been deleted.":                                             "Dieses Konto wurde gelöscht.",
+	"A password is required.":                                                    "Ein Passwort ist erforderlich.",
+	"The specified password is invalid.":                                         "Das angegebene Passwort ist ungültig.",
+	"The current password is invalid.":                                           "Das aktuelle Passwort ist ungültig.",
+	"A new password is required.":                                                "Ein neues Passwort ist erforderlich.",
+	"Your password was reset. Choose a new one to log in.":                       "Ihr Passwort wurde zurückgesetzt. Wählen Sie ein neues, um sich anzumelden.",
+	"Your password was reset. Set a new one with a login link.":                  "Ihr Passwort wurde zurückgesetzt. Legen Sie mit einem Anmeldelink ein neues fest.",
+	"The password must be at least %d characters.":                               "Das Passwort muss mindestens %d Zeichen lang sein.",
+	"The password must contain an uppercase letter.":                             "Das Passwort muss einen Großbuchstaben enthalten.",
+	"The password must contain a lowercase letter.":                              "Das Passwort muss einen Kleinbuchstaben enthalten.",
+	"The password must contain a digit.":                                         "Das Passwort muss eine Ziffer enthalten.",
+	"The password must contain a symbol.":                                        "Das Passwort muss ein Sonderzeichen enthalten.",
+	"The password has appeared in a data breach. Please choose a different one.": "Das Passwort ist in einem Datenleck aufgetaucht. Bitte wählen Sie ein anderes.",
+	"An MFA code is required.":                                                   "Ein MFA-Code ist erforderlich.",
+	"The specified MFA code is invalid.":                                         "Der angegebene MFA-Code ist ungültig.",
+	"Enable MFA to get a secret before confirming it.":                           "Aktivieren Sie MFA, um ein Geheimnis zu erhalten, bevor Sie es bestätigen.",
+	"MFA is already enabled.":                                                    "MFA ist bereits aktiviert.",
+	"Invalid login link.":                                                        "Ungültiger Anmeldelink.",
+	"The login link has expired.":                                                "Der Anmeldelink ist abgelaufen.",
+	"An identity provider and subject are required.":                             "Ein Identitätsanbieter und ein Subjekt sind erforderlich.",
+	"The identity provider did not supply a verified email.":                     "Der Identitätsanbieter hat keine bestätigte E-Mail-Adresse übermittelt.",
+	"An account with this email exists. Log in to it to link the identity.":      "Ein Konto mit dieser E-Mail-Adresse existiert bereits. Melden Sie sich dort an, um die Identität zu verknüpfen.",
+	"The identity's email doesn't match the account's.":                          "Die E-Mail-Adresse der Identität stimmt nicht mit der des Kontos überein.",
+	"The identity is linked to another account.":                                 "Die Identität ist mit einem anderen Konto verknüpft.",
+	"The external login is invalid or expired. Log in again.":                    "Die externe Anmeldung ist ungültig oder abgelaufen. Melden Sie sich erneut an.",
+	"A refresh token is required.":                                               "Ein Aktualisierungstoken ist erforderlich.",
+	"Invalid refresh token.":                                                     "Ungültiges Aktualisierungstoken.",
+	"A sender ID is required.":                                                   "Eine Absender-ID ist erforderlich.",
+	"A name is required.":                                                        "Ein Name ist erforderlich.",
+	"A send to email is required.":                                               "Eine Empfänger-E-Mail-Adresse ist erforderlich.",
+	"A value is required.":                                                       "Ein Wert ist erforderlich.",
+	"The value can't be larger than %d bytes.":                                   "Der Wert darf nicht größer als %d Bytes sein.",
+	"Duration must be greater than 0.":                                           "Die Dauer muss größer als 0 sein.",
+	"Only one of duration and durationSeconds can be set.":                       "Nur eines von duration und durationSeconds darf gesetzt sein.",
+	"Challenge %d requires a question and an answer.":                            "Sicherheitsfrage %d erfordert eine Frage und eine Antwort.",
+	"An entry can't have more than %d challenges.":                               "Ein Eintrag kann nicht mehr als %d Sicherheitsfragen haben.",
+	"Challenge %d's question can't be longer than %d characters.":                "Sicherheitsfrage %d darf nicht länger als %d Zeichen sein.",
+	"Challenge %d's answer can't be longer than %d bytes.":                       "Die Antwort auf Sicherheitsfrage %d darf nicht länger als %d Bytes sein.",
+	"The type must be one of %q, %q, %q, or %q.":                                 "Der Typ muss %q, %q, %q oder %q sein.",
+	"A username can only be set for a password or SSH key.":                      "Ein Benutzername kann nur für ein Passwort oder einen SSH-Schlüssel gesetzt werden.",
+	"A hostname can only be set for an SSH key.":                                 "Ein Hostname kann nur für einen SSH-Schlüssel gesetzt werden.",
+	"The username and hostname can't be longer than %d characters.":              "Benutzername und Hostname dürfen nicht länger als %d Zeichen sein.",
+	"A value and secret can't be sent with an end-to-end entry.":                 "Mit einem Ende-zu-Ende-verschlüsselten Eintrag können kein Wert und kein Geheimnis gesendet werden.",
+	"The cipher isn't supported.":                                                "Das Verschlüsselungsverfahren wird nicht unterstützt.",
+	"The cipher isn't supported by the server's key.":                            "Der Schlüssel des Servers unterstützt das Verschlüsselungsverfahren nicht.",
+	"The nonce is the wrong size for the cipher.":                                "Die Nonce hat die falsche Größe für das Verschlüsselungsverfahren.",
+	"A ciphertext is required.":                                                  "Ein Geheimtext ist erforderlich.",
+	"The KDF must be argon2id with a salt and non-zero costs.":                   "Die KDF muss argon2id mit einem Salt und Kosten ungleich null sein.",
+	"The KDF can't use more than %d KiB, %d passes, or %d threads.":              "Die KDF darf nicht mehr als %d KiB, %d Durchläufe oder %d Threads nutzen.",
+	"Only one of length and words can be set.":                                   "Nur eines von length und words darf gesetzt sein.",
+	"The length must be between %d and %d.":                                      "Die Länge muss zwischen %d und %d liegen.",
+	"The number of words must be between %d and %d.":                             "Die Anzahl der Wörter muss zwischen %d und %d liegen.",
+	"The charset must be one of %q, %q, %q, or %q.":                              "Der Zeichensatz muss %q, %q, %q oder %q sein.",
+	"A value can't be sent with a generated one.":                                "Ein Wert kann nicht zusammen mit einem generierten Wert gesendet werden.",
+	"An end-to-end entry can't use a generated value.":                           "Ein Ende-zu-Ende-verschlüsselter Eintrag kann keinen generierten Wert verwenden.",
+	"An identical entry was sent to this recipient in the last %d seconds.":      "Ein identischer Eintrag wurde in den letzten %d Sekunden an diesen Empfänger gesendet.",
+	"The entry must become available before it expires.":                         "Der Eintrag muss verfügbar werden, bevor er abläuft.",
+	"This entry can't be claimed until %s.":                                      "Dieser Eintrag kann erst ab %s abgerufen werden.",
+	"%s requires signing in with single sign-on.":                                "%s erfordert die Anmeldung per Single Sign-On.",
+	"You must log in as the recipient to claim this entry.":                      "Sie müssen sich als Empfänger anmelden, um diesen Eintrag abzurufen.",
+	"This entry was sent to a different email address.":                          "Dieser Eintrag wurde an eine andere E-Mail-Adresse gesendet.",
+	"Your email must be verified to claim this entry. Log in with single sign-on or a login link to verify it.": "Ihre E-Mail-Adresse muss bestätigt sein, um diesen Eintrag abzurufen. Melden Sie sich per Single Sign-On oder mit einem Anmeldelink an, um sie zu bestätigen.",
+	"This entry requires logging in as the recipient and can only be claimed with the sendkey CLI.":             "Dieser Eintrag erfordert die Anmeldung als Empfänger und kann nur mit der sendkey-CLI abgerufen werden.",
+	"The delay can't be negative.":               "Die Verzögerung darf nicht negativ sein.",
+	"An entry can only be deferred %d times.":    "Ein Eintrag kann nur %d-mal aufgeschoben werden.",
+	"The entry expires too soon to be deferred.": "Der Eintrag läuft zu bald ab, um ihn aufzuschieben.",
+	"Remind me tomorrow":                         "Morgen erinnern",
+	"We'll email you a reminder at %s.":          "Wir senden Ihnen am %s eine Erinnerung per E-Mail.",
+
+	"At least one filter is required to revoke entries.": "Zum Widerrufen von Einträgen ist mindestens ein Filter erforderlich.",
+
+	"One-time codes aren't enabled on this server.":                              "Einmalcodes sind auf diesem Server nicht aktiviert.",
+	"Login links aren't enabled on this server.":                                 "Anmeldelinks sind auf diesem Server nicht aktiviert.",
+	"A one-time code was emailed to the recipient. Enter it to claim the entry.": "Ein Einmalcode wurde per E-Mail an den Empfänger gesendet. Geben Sie ihn ein, um den Eintrag abzurufen.",
+	"The one-time code is invalid or has expired.":                               "Der Einmalcode ist ungültig oder abgelaufen.",
+	"Code from your email":                                                       "Code aus Ihrer E-Mail",
+
+	"Entries created without logging in can't last longer than %s.": "Ohne Anmeldung erstellte Einträge können nicht länger als %s gültig sein.",
+
+	"The role must be %q or %q.":                                                                      "Die Rolle muss %q oder %q sein.",
+	"This account has been disabled. Contact your administrator.":                                     "Dieses Konto wurde deaktiviert. Wenden Sie sich an Ihren Administrator.",
+	"This account doesn't have a password. Log in with a magic link or single sign-on, then set one.": "Dieses Konto hat kein Passwort. Melden Sie sich mit einem Magic Link oder Single Sign-on an und legen Sie dann eines fest.",
+	"The cursor is invalid.":                                                                          "Der Cursor ist ungültig.",
+
+	"The value digest is invalid.": "Der Wert-Digest ist ungültig.",
+	"The value doesn't match its digest. It may have been corrupted in transit.": "Der Wert stimmt nicht mit seinem Digest überein. Er wurde möglicherweise bei der Übertragung beschädigt.",
+
+	"The value can't be larger than %d bytes on your plan.":                                             "Der Wert darf in Ihrem Tarif nicht größer als %d Bytes sein.",
+	"Your plan allows %d entries a day. Try again tomorrow.":                                            "Ihr Tarif erlaubt %d Einträge pro Tag. Versuchen Sie es morgen erneut.",
+	"Your plan allows %d entries waiting to be claimed at once. Wait for some to be claimed or expire.": "Ihr Tarif erlaubt %d gleichzeitig auf Abruf wartende Einträge. Warten Sie, bis einige abgerufen werden oder ablaufen.",
+
+	"You already claimed the entry. Its value can't be shown again.": "Sie haben den Eintrag bereits abgerufen. Sein Wert kann nicht erneut angezeigt werden.",
+	"The entry was already claimed by someone else.":                 "Der Eintrag wurde bereits von jemand anderem abgerufen.",
+
+	"A delegated claim token can last at most %d minutes.":                  "Ein delegiertes Abruf-Token kann höchstens %d Minuten gültig sein.",
+	"End-to-end encrypted entries can't be claimed with a delegated token.": "Ende-zu-Ende-verschlüsselte Einträge können nicht mit einem delegierten Token abgerufen werden.",
+	"The delegated claim token is invalid or has expired.":                  "Das delegierte Abruf-Token ist ungültig oder abgelaufen.",
+
+	"A reason is required to release an escrow.":    "Für die Freigabe einer Hinterlegung ist ein Grund erforderlich.",
+	"The entry has no escrow, or it's been purged.": "Der Eintrag hat keine Hinterlegung, oder sie wurde bereits gelöscht.",
+
+	"The recipient has opted out of receiving entries sent with sendkey, so this entry can't be sent to them. Share it with them another way.": "Der Empfänger hat den Empfang von mit sendkey gesendeten Einträgen abgelehnt, daher kann dieser Eintrag nicht an ihn gesendet werden. Teilen Sie ihn auf andere Weise.",
+
+	"Stop receiving entries": "Keine Einträge mehr empfangen",
+	"This link is invalid.":  "Dieser Link ist ungültig.",
+	"You've opted out. Entries can no longer be sent to this address with sendkey.":                            "Sie haben sich abgemeldet. An diese Adresse können mit sendkey keine Einträge mehr gesendet werden.",
+	"Opt this address out of receiving entries sent with sendkey? Senders will be told it can't receive them.": "Soll diese Adresse keine mit sendkey gesendeten Einträge mehr empfangen? Absender werden darüber informiert, dass sie keine empfangen kann.",
+	"Opt out": "Abmelden",
+
+	"Log in to sendkey": "Bei sendkey anmelden",
+	"Log in with this link? It can only be used once.": "Mit diesem Link anmelden? Er kann nur einmal verwendet werden.",
+	"New password, if you were asked to choose one":    "Neues Passwort, falls Sie aufgefordert wurden, eines zu wählen",
+	"MFA code, if you've turned on MFA":                "MFA-Code, falls Sie MFA aktiviert haben",
+	"Log in":                                           "Anmelden",
+
+	"This link has expired or was replaced by a newer one. Ask the sender to resend the entry.": "Dieser Link ist abgelaufen oder wurde durch einen neueren ersetzt. Bitten Sie den Absender, den Eintrag erneut zu senden.",
+	"The link's lifetime can't be negative.":                                                    "Die Gültigkeitsdauer des Links darf nicht negativ sein.",
+
+	"A digest can't be sent with a generated value.": "Mit einem generierten Wert kann kein Digest gesendet werden.",
+
+	"The entry to replace must be one of your entries that hasn't been claimed or expired.": "Der zu ersetzende Eintrag muss einer Ihrer Einträge sein, der weder abgerufen wurde noch abgelaufen ist.",
+
+	"The field must be of type %s.":                "Das Feld muss vom Typ %s sein.",
+	"The field must be a string in the %s format.": "Das Feld muss eine Zeichenkette im Format %s sein.",
+
+	"The sender requires you to acknowledge the following to claim this entry: %s": "Der Absender verlangt, dass Sie Folgendes bestätigen, um diesen Eintrag abzurufen: %s",
+	"The acknowledgment can't be longer than %d characters.":                       "Der Bestätigungstext darf nicht länger als %d Zeichen sein.",
+	"The sender asks you to acknowledge this before the value is shown:":           "Der Absender bittet Sie, dies zu bestätigen, bevor der Wert angezeigt wird:",
+}
@@ -0,0 +1,98 @@
+// Package i18n holds the translation catalogs used for anything shown to
+// recipients and API users. Catalogs are keyed by the English source string,
+// so untranslated strings fall back to English as-is.
+package i18n
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultLanguage is the language of the source strings.
+const DefaultLanguage = "en"
+
+// Catalog maps English source strings to their translation.
+type Catalog map[string]string
+
+var catalogs = map[string]Catalog{
+	"es": spanish,
+	"fr": french,
+	"de": german,
+}
+
+// Supported reports whether there's a catalog for the language. Region
+// subtags are ignored, so "fr-CA" is supported if "fr" is.
+func Supported(lang string) bool {
+	lang = Base(lang)
+	if lang == DefaultLanguage {
+		return true
+	}
+
+	_, ok := catalogs[lang]
+	return ok
+}
+
+// Base returns the lowercased primary subtag of the language tag.
+func Base(lang string) string {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if i := strings.IndexAny(lang, "-_"); i != -1 {
+		lang = lang[:i]
+	}
+	return lang
+}
+
+// Negotiate returns the supported language the Accept-Language header
+// prefers most, or an empty string if it doesn't list any supported language.
+func Negotiate(acceptLanguage string) string {
+	type pref struct {
+		lang string
+		q    float64
+	}
+
+	var prefs []pref
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		fields := strings.Split(part, ";")
+		lang := strings.TrimSpace(fields[0])
+		if lang == "" || lang == "*" {
+			continue
+		}
+
+		q := 1.0
+		for _, f := range fields[1:] {
+			f = strings.TrimSpace(f)
+			if strings.HasPrefix(f, "q=") {
+				if v, err := strconv.ParseFloat(f[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if q > 0 {
+			prefs = append(prefs, pref{lang, q})
+		}
+	}
+	sort.SliceStable(prefs, func(i, j int) bool { return prefs[i].q > prefs[j].q })
+
+	for _, p := range prefs {
+		if Supported(p.lang) {
+			return Base(p.lang)
+		}
+	}
+
+	return ""
+}
+
+// Translate returns s in the language, or s itself if there's no translation.
+func Translate(lang, s string) string {
+	if t, ok := catalogs[Base(lang)][s]; ok {
+		return t
+	}
+	return s
+}
+
+// Translator returns a function that translates strings to the language.
+func Translator(lang string) func(string) string {
+	return func(s string) string {
+		return Translate(lang, s)
+	}
+}
@@ -0,0 +1,148 @@
+package i18n
+
+import "fmt"
+
+// messages maps the stable codes of validation errors to their English source
+// strings. A source string can have fmt verbs for the error's arguments, and
+// its translations must have the same verbs in the same order.
+var messages = map[string]string{
+	"invalid_body": "The request body is invalid: %s",
+
+	"email_required":            "An email is required.",
+	"email_taken":               "An account with the specified email already exists.",
+	"user_not_found":            "No user could be found with the specified email.",
+	"invalid_user_id":           "Invalid user ID.",
+	"account_deleted":           "This account has been deleted.",
+	"password_required":         "A password is required.",
+	"password_invalid":          "The specified password is invalid.",
+	"current_password_invalid":  "The current password is invalid.",
+	"new_password_required":     "A new password is required.",
+	"password_reset_required":   "Your password was reset. Choose a new one to log in.",
+	"reset_link_required":       "Your password was reset. Set a new one with a login link.",
+	"password_min_length":       "The password must be at least %d characters.",
+	"password_upper":            "The password must contain an uppercase letter.",
+	"password_lower":            "The password must contain a lowercase letter.",
+	"password_digit":            "The password must contain a digit.",
+	"password_symbol":           "The password must contain a symbol.",
+	"password_breached":         "The password has appeared in a data breach. Please choose a different one.",
+	"mfa_code_required":         "An MFA code is required.",
+	"mfa_code_invalid":          "The specified MFA code is invalid.",
+	"mfa_not_started":           "Enable MFA to get a secret before confirming it.",
+	"mfa_enabled":               "MFA is already enabled.",
+	"magic_link_invalid":        "Invalid login link.",
+	"magic_link_expired":        "The login link has expired.",
+	"identity_required":         "An identity provider and subject are required.",
+	"identity_email_unverified": "The identity provider did not supply a verified email.",
+	"identity_link_required":    "An account with this email exists. Log in to it to link the identity.",
+	"identity_email_mismatch":   "The identity's email doesn't match the account's.",
+	"identity_linked_elsewhere": "The identity is linked to another account.",
+	"external_login_invalid":    "The external login is invalid or expired. Log in again.",
+	"refresh_token_required":    "A refresh token is required.",
+	"refresh_token_invalid":     "Invalid refresh token.",
+	"sso_required":              "%s requires signing in with single sign-on.",
+
+	"sender_id_required":        "A sender ID is required.",
+	"guest_duration":            "Entries created without logging in can't last longer than %s.",
+	"name_required":             "A name is required.",
+	"send_to_email_required":    "A send to email is required.",
+	"value_required":            "A value is required.",
+	"value_too_large":           "The value can't be larger than %d bytes.",
+	"secret_required":           "A secret is required.",
+	"duration_invalid":          "Duration must be greater than 0.",
+	"duration_conflict":         "Only one of duration and durationSeconds can be set.",
+	"available_after_expiry":    "The entry must become available before it expires.",
+	"challenge_incomplete":      "Challenge %d requires a question and an answer.",
+	"too_many_challenges":       "An entry can't have more than %d challenges.",
+	"question_too_long":         "Challenge %d's question can't be longer than %d characters.",
+	"answer_too_long":           "Challenge %d's answer can't be longer than %d bytes.",
+	"type_invalid":              "The type must be one of %q, %q, %q, or %q.",
+	"username_not_allowed":      "A username can only be set for a password or SSH key.",
+	"hostname_not_allowed":      "A hostname can only be set for an SSH key.",
+	"metadata_too_long":         "The username and hostname can't be longer than %d characters.",
+	"end_to_end_plaintext":      "A value and secret can't be sent with an end-to-end entry.",
+	"cipher_unsupported":        "The cipher isn't supported.",
+	"cipher_unsupported_by_key": "The cipher isn't supported by the server's key.",
+	"nonce_size_invalid":        "The nonce is the wrong size for the cipher.",
+	"ciphertext_required":       "A ciphertext is required.",
+	"kdf_invalid":               "The KDF must be argon2id with a salt and non-zero costs.",
+	"kdf_too_costly":            "The KDF can't use more than %d KiB, %d passes, or %d threads.",
+	"invalid_entry_id":          "Invalid entry ID.",
+	"secret_invalid":            "Invalid secret.",
+	"challenge_answers_invalid": "Invalid challenge answers.",
+	"too_many_attempts":         "Too many attempts have been made, and the entry has been expired.",
+	"login_required":            "You must log in as the recipient to claim this entry.",
+	"recipient_mismatch":        "This entry was sent to a different email address.",
+	"recipient_unverified":      "Your email must be verified to claim this entry. Log in with single sign-on or a login link to verify it.",
+	"entry_not_available":       "This entry can't be claimed until %s.",
+	"duplicate_entry":           "An identical entry was sent to this recipient in the last %d seconds.",
+	"deferral_invalid":          "The delay can't be negative.",
+	"too_many_deferrals":        "An entry can only be deferred %d times.",
+	"deferral_too_late":         "The entry expires too soon to be deferred.",
+	"revoke_filter_required":    "At least one filter is required to revoke entries.",
+	"otp_unavailable":           "One-time codes aren't enabled on this server.",
+	"magic_links_unavailable":   "Login links aren't enabled on this server.",
+	"otp_required":              "A one-time code was emailed to the recipient. Enter it to claim the entry.",
+	"otp_invalid":               "The one-time code is invalid or has expired.",
+
+	"length_and_words":         "Only one of length and words can be set.",
+	"length_invalid":           "The length must be between %d and %d.",
+	"words_invalid":            "The number of words must be between %d and %d.",
+	"charset_invalid":          "The charset must be one of %q, %q, %q, or %q.",
+	"generated_value_conflict": "A value can't be sent with a generated one.",
+	"end_to_end_generated":     "An end-to-end entry can't use a generated value.",
+
+	"form_unreadable":   "The form could not be read.",
+	"page_expired":      "This page has expired. Please reopen the link you were sent.",
+	"too_many_requests": "Too many requests. Try again later.",
+	"claim_deferred":    "We'll email you a reminder at %s.",
+
+	"role_invalid":     "The role must be %q or %q.",
+	"account_disabled": "This account has been disabled. Contact your administrator.",
+	"password_not_set": "This account doesn't have a password. Log in with a magic link or single sign-on, then set one.",
+	"cursor_invalid":   "The cursor is invalid.",
+
+	"value_digest_invalid":  "The value digest is invalid.",
+	"value_digest_mismatch": "The value doesn't match its digest. It may have been corrupted in transit.",
+
+	"quota_value_size":      "The value can't be larger than %d bytes on your plan.",
+	"quota_entries_per_day": "Your plan allows %d entries a day. Try again tomorrow.",
+	"quota_live_entries":    "Your plan allows %d entries waiting to be claimed at once. Wait for some to be claimed or expire.",
+
+	"entry_claimed_by_you": "You already claimed the entry. Its value can't be shown again.",
+	"entry_claimed":        "The entry was already claimed by someone else.",
+
+	"delegation_ttl_invalid": "A delegated claim token can last at most %d minutes.",
+	"delegation_end_to_end":  "End-to-end encrypted entries can't be claimed with a delegated token.",
+	"delegation_invalid":     "The delegated claim token is invalid or has expired.",
+
+	"escrow_reason_required": "A reason is required to release an escrow.",
+	"escrow_not_found":       "The entry has no escrow, or it's been purged.",
+
+	"recipient_opted_out": "The recipient has opted out of receiving entries sent with sendkey, so this entry can't be sent to them. Share it with them another way.",
+
+	"claim_link_expired": "This link has expired or was replaced by a newer one. Ask the sender to resend the entry.",
+	"link_ttl_invalid":   "The link's lifetime can't be negative.",
+
+	"digest_generated": "A digest can't be sent with a generated value.",
+
+	"replaced_entry_invalid": "The entry to replace must be one of your entries that hasn't been claimed or expired.",
+
+	"field_type_invalid":   "The field must be of type %s.",
+	"field_format_invalid": "The field must be a string in the %s format.",
+
+	"acknowledgment_required": "The sender requires you to acknowledge the following to claim this entry: %s",
+	"acknowledgment_too_long": "The acknowledgment can't be longer than %d characters.",
+}
+
+// Message returns the message for the error code in the language, with the
+// arguments formatted into it. Unknown codes are returned as-is.
+func Message(lang, code string, args ...interface{}) string {
+	s, ok := messages[code]
+	if !ok {
+		return code
+	}
+	if len(args) == 0 {
+		return Translate(lang, s)
+	}
+	return fmt.Sprintf(Translate(lang, s), args...)
+}
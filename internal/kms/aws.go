@@ -0,0 +1,60 @@
+package kms
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gavinwade12/sendkey/internal/sigv4"
+)
+
+// AWS unwraps keys with AWS KMS. Wrapped keys are the base64 ciphertext blobs
+// returned by the KMS Encrypt API.
+//
+// Credentials are read from the AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, and
+// AWS_SESSION_TOKEN environment variables.
+type AWS struct {
+	endpoint string
+	region   string
+	keyID    string
+}
+
+// NewAWS returns a provider for the region. The keyID is optional for
+// symmetric keys, but setting it ensures only that key is used.
+func NewAWS(region, keyID string) *AWS {
+	return &AWS{"https://kms." + region + ".amazonaws.com/", region, keyID}
+}
+
+func (a *AWS) UnwrapKey(wrapped string) ([]byte, error) {
+	in := map[string]string{"CiphertextBlob": wrapped}
+	if a.keyID != "" {
+		in["KeyId"] = a.keyID
+	}
+	body, err := json.Marshal(in)
+	if err != nil {
+		return nil, err
+	}
+
+	creds, err := sigv4.EnvCredentials()
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, a.endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "TrentService.Decrypt")
+	sigv4.Sign(req, body, "kms", a.region, creds, time.Now())
+
+	var resp struct {
+		// Plaintext is base64 in the JSON, which decodes straight into []byte
+		Plaintext []byte `json:"Plaintext"`
+	}
+	if err = postJSON(req, body, &resp); err != nil {
+		return nil, fmt.Errorf("aws kms decrypt: %w", err)
+	}
+
+	return resp.Plaintext, nil
+}
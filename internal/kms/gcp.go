@@ -0,0 +1,88 @@
+package kms
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+const (
+	gcpKMSEndpoint   = "https://cloudkms.googleapis.com/v1/"
+	gcpMetadataToken = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+)
+
+// GCP unwraps keys with Google Cloud KMS. Wrapped keys are the base64
+// ciphertexts returned by the KMS encrypt API.
+//
+// The access token is taken from GOOGLE_OAUTH_ACCESS_TOKEN if it's set, and
+// otherwise from the metadata server of the instance the API runs on.
+type GCP struct {
+	endpoint      string
+	metadataToken string
+	keyName       string
+}
+
+// NewGCP returns a provider for the key, named like
+// "projects/p/locations/l/keyRings/r/cryptoKeys/k".
+func NewGCP(keyName string) *GCP {
+	return &GCP{gcpKMSEndpoint, gcpMetadataToken, keyName}
+}
+
+func (g *GCP) UnwrapKey(wrapped string) ([]byte, error) {
+	token, err := g.accessToken()
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := json.Marshal(map[string]string{"ciphertext": wrapped})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, g.endpoint+g.keyName+":decrypt", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	var resp struct {
+		Plaintext string `json:"plaintext"`
+	}
+	if err = postJSON(req, body, &resp); err != nil {
+		return nil, fmt.Errorf("gcp kms decrypt: %w", err)
+	}
+
+	return base64.StdEncoding.DecodeString(resp.Plaintext)
+}
+
+func (g *GCP) accessToken() (string, error) {
+	if t := os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN"); t != "" {
+		return t, nil
+	}
+
+	req, err := http.NewRequest(http.MethodGet, g.metadataToken, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("fetching gcp access token: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("fetching gcp access token: unexpected status %d", res.StatusCode)
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err = json.NewDecoder(res.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("decoding gcp access token: %w", err)
+	}
+
+	return token.AccessToken, nil
+}
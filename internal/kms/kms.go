@@ -0,0 +1,53 @@
+// Package kms unwraps sendkey's master keys with an external key management
+// service, so only encrypted keys are stored in config. Each provider
+// implements app.KeyProvider.
+//
+// Keys are unwrapped once, at startup, and aren't reloaded. Rotating the KMS
+// key doesn't need a restart: AWS KMS and Cloud KMS decrypt ciphertexts from
+// any of the key's versions that haven't been destroyed, and Vault from any
+// version at or above the key's min_decryption_version. Before retiring a
+// version, rewrap the config's keys with the new one and restart. Rotating
+// sendkey's own keys is adding one to Keys, making it the KeyVersion, and
+// running with -rotate-keys once the servers have restarted with it.
+package kms
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Plaintext is the provider used when no KMS is configured. Keys are stored
+// in config as-is.
+type Plaintext struct{}
+
+func (Plaintext) UnwrapKey(wrapped string) ([]byte, error) {
+	return []byte(wrapped), nil
+}
+
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// postJSON posts the body as JSON and decodes the JSON response into v.
+func postJSON(req *http.Request, body []byte, v interface{}) error {
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.ContentLength = int64(len(body))
+	if req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("unexpected status %d: %s", res.StatusCode, bytes.TrimSpace(msg))
+	}
+
+	return json.NewDecoder(res.Body).Decode(v)
+}
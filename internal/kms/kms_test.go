@@ -0,0 +1,116 @@
+package kms
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fakeKMS serves a decrypt API at path, checking the request with check and
+// answering with resp.
+func fakeKMS(t *testing.T, path string, check func(r *http.Request, body map[string]string), resp interface{}) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != path {
+			t.Errorf("got %s %s, want POST %s", r.Method, r.URL.Path, path)
+			http.NotFound(w, r)
+			return
+		}
+		var body map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decoding the request: %v", err)
+		}
+		check(r, body)
+		json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+var (
+	plaintext = []byte("0123456789abcdef0123456789abcdef")
+	encoded   = base64.StdEncoding.EncodeToString(plaintext)
+)
+
+func TestAWS(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	srv := fakeKMS(t, "/", func(r *http.Request, body map[string]string) {
+		if r.Header.Get("X-Amz-Target") != "TrentService.Decrypt" || r.Header.Get("Content-Type") != "application/x-amz-json-1.1" {
+			t.Errorf("got headers %v, want a Decrypt call", r.Header)
+		}
+		if auth := r.Header.Get("Authorization"); !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/") || !strings.Contains(auth, "/eu-west-1/kms/aws4_request") {
+			t.Errorf("got Authorization %q, want it signed for kms in eu-west-1", auth)
+		}
+		if body["CiphertextBlob"] != "blob" || body["KeyId"] != "alias/sendkey" {
+			t.Errorf("got the body %v", body)
+		}
+	}, map[string]string{"Plaintext": encoded})
+
+	a := NewAWS("eu-west-1", "alias/sendkey")
+	a.endpoint = srv.URL + "/"
+	key, err := a.UnwrapKey("blob")
+	if err != nil || string(key) != string(plaintext) {
+		t.Fatalf("UnwrapKey() = %q, %v", key, err)
+	}
+}
+
+func TestGCP(t *testing.T) {
+	metadata := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata-Flavor") != "Google" {
+			http.Error(w, "missing Metadata-Flavor", http.StatusForbidden)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "ya29.token"})
+	}))
+	defer metadata.Close()
+
+	name := "projects/p/locations/l/keyRings/r/cryptoKeys/k"
+	srv := fakeKMS(t, "/v1/"+name+":decrypt", func(r *http.Request, body map[string]string) {
+		if r.Header.Get("Authorization") != "Bearer ya29.token" {
+			t.Errorf("got Authorization %q, want the metadata server's token", r.Header.Get("Authorization"))
+		}
+		if body["ciphertext"] != "blob" {
+			t.Errorf("got the body %v", body)
+		}
+	}, map[string]string{"plaintext": encoded})
+
+	t.Setenv("GOOGLE_OAUTH_ACCESS_TOKEN", "")
+	g := NewGCP(name)
+	g.endpoint, g.metadataToken = srv.URL+"/v1/", metadata.URL
+	key, err := g.UnwrapKey("blob")
+	if err != nil || string(key) != string(plaintext) {
+		t.Fatalf("UnwrapKey() = %q, %v", key, err)
+	}
+}
+
+func TestVault(t *testing.T) {
+	srv := fakeKMS(t, "/v1/secrets/transit/decrypt/sendkey", func(r *http.Request, body map[string]string) {
+		if r.Header.Get("X-Vault-Token") != "hvs.token" {
+			t.Errorf("got X-Vault-Token %q", r.Header.Get("X-Vault-Token"))
+		}
+		if body["ciphertext"] != "vault:v2:blob" {
+			t.Errorf("got the body %v", body)
+		}
+	}, map[string]map[string]string{"data": {"plaintext": encoded}})
+
+	v := NewVault(srv.URL+"/", "hvs.token", "/secrets/transit/", "sendkey")
+	key, err := v.UnwrapKey("vault:v2:blob")
+	if err != nil || string(key) != string(plaintext) {
+		t.Fatalf("UnwrapKey() = %q, %v", key, err)
+	}
+}
+
+func TestUnwrapKeyErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"errors":["permission denied"]}`, http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	_, err := NewVault(srv.URL, "hvs.token", "", "sendkey").UnwrapKey("vault:v1:blob")
+	if err == nil || !strings.Contains(err.Error(), "unexpected status 403") {
+		t.Fatalf("UnwrapKey() = %v, want the status", err)
+	}
+}
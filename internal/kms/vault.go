@@ -0,0 +1,56 @@
+package kms
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// Vault unwraps keys with HashiCorp Vault's transit secrets engine. Wrapped
+// keys are transit ciphertexts, e.g. "vault:v1:...".
+type Vault struct {
+	addr  string
+	token string
+	mount string
+	key   string
+}
+
+// NewVault returns a provider for the transit key. If token is empty, the
+// VAULT_TOKEN environment variable is used. The mount defaults to "transit".
+func NewVault(addr, token, mount, key string) *Vault {
+	if token == "" {
+		token = os.Getenv("VAULT_TOKEN")
+	}
+	if mount == "" {
+		mount = "transit"
+	}
+
+	return &Vault{strings.TrimSuffix(addr, "/"), token, strings.Trim(mount, "/"), key}
+}
+
+func (v *Vault) UnwrapKey(wrapped string) ([]byte, error) {
+	body, err := json.Marshal(map[string]string{"ciphertext": wrapped})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/v1/%s/decrypt/%s", v.addr, v.mount, v.key), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", v.token)
+
+	var resp struct {
+		Data struct {
+			Plaintext string `json:"plaintext"`
+		} `json:"data"`
+	}
+	if err = postJSON(req, body, &resp); err != nil {
+		return nil, fmt.Errorf("vault transit decrypt: %w", err)
+	}
+
+	return base64.StdEncoding.DecodeString(resp.Data.Plaintext)
+}
@@ -0,0 +1,67 @@
+// Package mail sends the emails sendkey needs, e.g. login links.
+package mail
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"net/smtp"
+	"strings"
+)
+
+// SMTPMailer sends plain text email through an SMTP server.
+type SMTPMailer struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+// NewSMTPMailer returns a mailer for the server. If username is empty, no
+// authentication is attempted.
+func NewSMTPMailer(host, port, username, password, from string) *SMTPMailer {
+	m := &SMTPMailer{
+		addr: net.JoinHostPort(host, port),
+		from: from,
+	}
+	if username != "" {
+		m.auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return m
+}
+
+func (m *SMTPMailer) Send(to, subject, body string) error {
+	if strings.ContainsAny(to+subject, "\r\n") {
+		return fmt.Errorf("invalid header value")
+	}
+
+	msg := "From: " + m.from + "\r\n" +
+		"To: " + to + "\r\n" +
+		"Subject: " + subject + "\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=utf-8\r\n" +
+		"\r\n" + body
+
+	if err := smtp.SendMail(m.addr, m.auth, m.from, []string{to}, []byte(msg)); err != nil {
+		return fmt.Errorf("sending mail: %w", err)
+	}
+
+	return nil
+}
+
+// LogMailer logs emails' recipients and subjects instead of sending them.
+// Their bodies aren't logged, since they hold login links and claim codes.
+// It's meant for local development only.
+type LogMailer struct {
+	Logger *slog.Logger
+}
+
+func (m LogMailer) Send(to, subject, body string) error {
+	l := m.Logger
+	if l == nil {
+		l = slog.Default()
+	}
+
+	l.Info("mail: not sent", "to", to, "subject", subject)
+	return nil
+}
@@ -0,0 +1,24 @@
+package mail
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestLogMailerOmitsBodies(t *testing.T) {
+	var buf bytes.Buffer
+	m := LogMailer{Logger: slog.New(slog.NewTextHandler(&buf, nil))}
+
+	if err := m.Send("ada@example.com", "Your sendkey login link", "https://sendkey.example/login/magic/0123.abcd"); err != nil {
+		t.Fatal(err)
+	}
+	logged := buf.String()
+	if !strings.Contains(logged, "ada@example.com") || !strings.Contains(logged, "Your sendkey login link") {
+		t.Errorf("the log is missing the recipient or subject: %s", logged)
+	}
+	if strings.Contains(logged, "0123.abcd") {
+		t.Errorf("the log has the body: %s", logged)
+	}
+}
@@ -0,0 +1,187 @@
+package mail
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// The policies for a Queue that's full.
+const (
+	// OverflowDrop drops the new message and writes it, without its body,
+	// to the audit log. Send still succeeds.
+	OverflowDrop = "drop"
+	// OverflowBlock makes Send wait for room in the queue, up to the
+	// queue's BlockTimeout.
+	OverflowBlock = "block"
+)
+
+// ErrQueueFull is returned by Send when the queue stays full for the whole
+// BlockTimeout.
+var ErrQueueFull = errors.New("mail queue is full")
+
+// Mailer sends a single email.
+type Mailer interface {
+	Send(to, subject, body string) error
+}
+
+type message struct {
+	to, subject, body string
+}
+
+// Queue sends mail in the background through another mailer so requests don't
+// wait on the mail server. Failed sends are retried with backoff. The queue
+// holds a limited number of messages, so an outage can't make it grow without
+// bound; what happens when it's full is up to its overflow policy.
+type Queue struct {
+	// dropped is first so it's 64-bit aligned for atomic access
+	dropped  int64
+	alerting int32
+	next     Mailer
+	messages chan message
+	overflow string
+	wg       sync.WaitGroup
+
+	// BlockTimeout is how long Send waits for room with OverflowBlock.
+	BlockTimeout time.Duration
+	// MaxAttempts is how many times a message is sent before it's dropped.
+	MaxAttempts int
+	// RetryDelay is the wait after the first failed send. It doubles with
+	// each failure up to a minute.
+	RetryDelay time.Duration
+	// AlertDepth is the backlog that logs an alert. Zero disables alerts.
+	AlertDepth int
+	// Audit records dropped messages. It defaults to the standard logger.
+	Audit *log.Logger
+}
+
+// NewQueue returns a queue holding up to depth messages and starts the number
+// of workers sending them.
+func NewQueue(next Mailer, depth, workers int, overflow string) (*Queue, error) {
+	if overflow != OverflowDrop && overflow != OverflowBlock {
+		return nil, fmt.Errorf("unsupported mail queue overflow policy %q", overflow)
+	}
+	if depth <= 0 || workers <= 0 {
+		return nil, fmt.Errorf("the mail queue depth and workers must be positive")
+	}
+
+	q := &Queue{
+		next:         next,
+		messages:     make(chan message, depth),
+		overflow:     overflow,
+		BlockTimeout: 5 * time.Second,
+		MaxAttempts:  5,
+		RetryDelay:   time.Second,
+	}
+	q.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go q.work()
+	}
+
+	return q, nil
+}
+
+// Send queues the message. It only returns an error if the queue is full
+// with OverflowBlock.
+func (q *Queue) Send(to, subject, body string) error {
+	m := message{to, subject, body}
+	defer q.checkBacklog()
+
+	select {
+	case q.messages <- m:
+		return nil
+	default:
+	}
+
+	if q.overflow == OverflowDrop {
+		q.drop(m, "the queue is full")
+		return nil
+	}
+
+	t := time.NewTimer(q.BlockTimeout)
+	defer t.Stop()
+	select {
+	case q.messages <- m:
+		return nil
+	case <-t.C:
+		return ErrQueueFull
+	}
+}
+
+// Depth returns the number of messages waiting to be sent.
+func (q *Queue) Depth() int {
+	return len(q.messages)
+}
+
+// Dropped returns the number of messages that have been dropped.
+func (q *Queue) Dropped() int64 {
+	return atomic.LoadInt64(&q.dropped)
+}
+
+// Close waits for the queued messages to be sent. The queue can't be sent to
+// after it's closed.
+func (q *Queue) Close() {
+	close(q.messages)
+	q.wg.Wait()
+}
+
+func (q *Queue) work() {
+	defer q.wg.Done()
+
+	for m := range q.messages {
+		q.checkBacklog()
+		q.deliver(m)
+	}
+}
+
+func (q *Queue) deliver(m message) {
+	delay := q.RetryDelay
+	for attempt := 1; ; attempt++ {
+		err := q.next.Send(m.to, m.subject, m.body)
+		if err == nil {
+			return
+		}
+		if attempt >= q.MaxAttempts {
+			q.drop(m, err.Error())
+			return
+		}
+
+		time.Sleep(delay)
+		if delay *= 2; delay > time.Minute {
+			delay = time.Minute
+		}
+	}
+}
+
+// drop audits the message without its body, which can hold secrets like
+// login links.
+func (q *Queue) drop(m message, reason string) {
+	atomic.AddInt64(&q.dropped, 1)
+	q.audit().Printf("mail queue: dropped message to %s with subject %q: %s", m.to, m.subject, reason)
+}
+
+// checkBacklog logs an alert when the backlog reaches AlertDepth, and again
+// after it's recovered to below half of it.
+func (q *Queue) checkBacklog() {
+	if q.AlertDepth <= 0 {
+		return
+	}
+
+	depth := q.Depth()
+	switch {
+	case depth >= q.AlertDepth && atomic.CompareAndSwapInt32(&q.alerting, 0, 1):
+		q.audit().Printf("mail queue: backlog of %d messages has reached the alert depth of %d", depth, q.AlertDepth)
+	case depth < q.AlertDepth/2 && atomic.CompareAndSwapInt32(&q.alerting, 1, 0):
+		q.audit().Printf("mail queue: backlog has recovered to %d messages", depth)
+	}
+}
+
+func (q *Queue) audit() *log.Logger {
+	if q.Audit != nil {
+		return q.Audit
+	}
+	return log.Default()
+}
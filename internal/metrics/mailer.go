@@ -0,0 +1,44 @@
+package metrics
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/gavinwade12/sendkey/internal/app"
+)
+
+// Mailer records sends as "mailer.Send" and reports the number of sends in
+// progress as the "mailer.backlog" gauge. A growing backlog means the mail
+// server is slow or hanging.
+type Mailer struct {
+	next     app.Mailer
+	r        *Registry
+	inFlight int64
+}
+
+func NewMailer(next app.Mailer, r *Registry) *Mailer {
+	m := &Mailer{next: next, r: r}
+	r.Gauge("mailer.backlog", func() int64 { return atomic.LoadInt64(&m.inFlight) })
+	return m
+}
+
+func (m *Mailer) Send(to, subject, body string) (err error) {
+	atomic.AddInt64(&m.inFlight, 1)
+	defer atomic.AddInt64(&m.inFlight, -1)
+	defer m.r.observe("mailer.Send", time.Now(), &err)
+
+	return m.next.Send(to, subject, body)
+}
+
+// MailQueue is a queue of mail waiting to be sent.
+type MailQueue interface {
+	Depth() int
+	Dropped() int64
+}
+
+// WatchMailQueue reports the number of queued messages as the "mailer.queue"
+// gauge and the number dropped as the "mailer.dropped" gauge.
+func WatchMailQueue(q MailQueue, r *Registry) {
+	r.Gauge("mailer.queue", func() int64 { return int64(q.Depth()) })
+	r.Gauge("mailer.dropped", q.Dropped)
+}
@@ -0,0 +1,150 @@
+// Package metrics records call counts, errors, and latency for named
+// operations and publishes them through expvar.
+package metrics
+
+import (
+	"expvar"
+	"sort"
+	"sync"
+	"time"
+)
+
+// LatencyBuckets are the upper bounds of the latency histogram buckets. Calls
+// slower than the last bound are only counted in the totals.
+var LatencyBuckets = []time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+}
+
+// Registry holds the stats for every operation that's been observed, along
+// with gauges for resources that can leak, like open streams or queued jobs,
+// and counters for events like entries being claimed.
+type Registry struct {
+	mu       sync.Mutex
+	ops      map[string]*OpStats
+	gauges   map[string]func() int64
+	counters map[string]uint64
+}
+
+// OpStats are the stats for a single operation. Buckets holds cumulative
+// counts for each of LatencyBuckets.
+type OpStats struct {
+	Calls        uint64   `json:"calls"`
+	Errors       uint64   `json:"errors"`
+	TotalSeconds float64  `json:"totalSeconds"`
+	MaxSeconds   float64  `json:"maxSeconds"`
+	Buckets      []uint64 `json:"buckets"`
+}
+
+func NewRegistry() *Registry {
+	return &Registry{ops: map[string]*OpStats{}, gauges: map[string]func() int64{}, counters: map[string]uint64{}}
+}
+
+// Observe records a call to the operation that took d and returned err.
+func (r *Registry) Observe(op string, d time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s, ok := r.ops[op]
+	if !ok {
+		s = &OpStats{Buckets: make([]uint64, len(LatencyBuckets))}
+		r.ops[op] = s
+	}
+
+	s.Calls++
+	if err != nil {
+		s.Errors++
+	}
+	secs := d.Seconds()
+	s.TotalSeconds += secs
+	if secs > s.MaxSeconds {
+		s.MaxSeconds = secs
+	}
+	for i := sort.Search(len(LatencyBuckets), func(i int) bool { return d <= LatencyBuckets[i] }); i < len(LatencyBuckets); i++ {
+		s.Buckets[i]++
+	}
+}
+
+// observe is meant to be deferred at the start of a call, with err pointing
+// at the call's named error result.
+func (r *Registry) observe(op string, start time.Time, err *error) {
+	r.Observe(op, time.Since(start), *err)
+}
+
+// Snapshot returns a copy of the stats for every operation.
+func (r *Registry) Snapshot() map[string]OpStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	snap := make(map[string]OpStats, len(r.ops))
+	for op, s := range r.ops {
+		c := *s
+		c.Buckets = append([]uint64(nil), s.Buckets...)
+		snap[op] = c
+	}
+
+	return snap
+}
+
+// Gauge registers a function reporting the current value of a resource. It
+// replaces any gauge already registered with the name.
+func (r *Registry) Gauge(name string, f func() int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.gauges[name] = f
+}
+
+// Gauges returns the current value of every gauge.
+func (r *Registry) Gauges() map[string]int64 {
+	r.mu.Lock()
+	fs := make(map[string]func() int64, len(r.gauges))
+	for name, f := range r.gauges {
+		fs[name] = f
+	}
+	r.mu.Unlock()
+
+	// the gauges are called without the lock so they're free to observe
+	values := make(map[string]int64, len(fs))
+	for name, f := range fs {
+		values[name] = f()
+	}
+
+	return values
+}
+
+// Count adds one to the named counter. With it, a registry can count an
+// entry service's events; see app.EntryService.CountUsage.
+func (r *Registry) Count(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.counters[name]++
+}
+
+// Counters returns the value of every counter.
+func (r *Registry) Counters() map[string]uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	values := make(map[string]uint64, len(r.counters))
+	for name, n := range r.counters {
+		values[name] = n
+	}
+	return values
+}
+
+// Publish exposes the registry's snapshot as an expvar variable with the
+// given name. Like expvar.Publish, it panics if the name is already in use.
+func (r *Registry) Publish(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} { return r.Snapshot() }))
+}
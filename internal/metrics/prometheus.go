@@ -0,0 +1,121 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// HTTPPrefix starts the names of operations that are HTTP routes, like
+// "http.GET /entries/:entryID". They're exported as their own metrics.
+const HTTPPrefix = "http."
+
+// PrometheusHandler serves the registry in the Prometheus text format.
+func (r *Registry) PrometheusHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		r.WritePrometheus(w)
+	})
+}
+
+// WritePrometheus writes the registry in the Prometheus text format. HTTP
+// routes are sendkey_http_request_duration_seconds histograms with a route
+// label, and other operations, like store and mailer calls,
+// sendkey_operation_duration_seconds histograms with an op label. Each has an
+// errors counter; for routes, errors are responses with a 5xx status.
+// Counters are sendkey_events_total with an event label, and gauges are
+// named after themselves, e.g. sendkey_mailer_backlog.
+func (r *Registry) WritePrometheus(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	snap := r.Snapshot()
+	ops := make([]string, 0, len(snap))
+	for op := range snap {
+		ops = append(ops, op)
+	}
+	sort.Strings(ops)
+
+	var routes, others []string
+	for _, op := range ops {
+		if strings.HasPrefix(op, HTTPPrefix) {
+			routes = append(routes, op)
+		} else {
+			others = append(others, op)
+		}
+	}
+	writeOps(bw, "sendkey_http_request_duration_seconds", "sendkey_http_server_errors_total", "route",
+		"Latency of HTTP requests by route.", "HTTP requests answered with a 5xx status by route.", routes, snap)
+	writeOps(bw, "sendkey_operation_duration_seconds", "sendkey_operation_errors_total", "op",
+		"Latency of store, mailer, and other operations.", "Operations that returned an error.", others, snap)
+
+	counters := r.Counters()
+	if len(counters) > 0 {
+		names := make([]string, 0, len(counters))
+		for name := range counters {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		fmt.Fprintln(bw, "# HELP sendkey_events_total Entry lifecycle events and features used, by event.")
+		fmt.Fprintln(bw, "# TYPE sendkey_events_total counter")
+		for _, name := range names {
+			fmt.Fprintf(bw, "sendkey_events_total{event=%s} %d\n", labelValue(name), counters[name])
+		}
+	}
+
+	gauges := r.Gauges()
+	names := make([]string, 0, len(gauges))
+	for name := range gauges {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		metric := "sendkey_" + metricName(name)
+		fmt.Fprintf(bw, "# TYPE %s gauge\n%s %d\n", metric, metric, gauges[name])
+	}
+
+	return bw.Flush()
+}
+
+func writeOps(w io.Writer, histogram, errors, label, histogramHelp, errorsHelp string, ops []string, snap map[string]OpStats) {
+	if len(ops) == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", histogram, histogramHelp, histogram)
+	for _, op := range ops {
+		s := snap[op]
+		l := label + "=" + labelValue(strings.TrimPrefix(op, HTTPPrefix))
+		for i, bound := range LatencyBuckets {
+			fmt.Fprintf(w, "%s_bucket{%s,le=\"%g\"} %d\n", histogram, l, bound.Seconds(), s.Buckets[i])
+		}
+		fmt.Fprintf(w, "%s_bucket{%s,le=\"+Inf\"} %d\n", histogram, l, s.Calls)
+		fmt.Fprintf(w, "%s_sum{%s} %g\n", histogram, l, s.TotalSeconds)
+		fmt.Fprintf(w, "%s_count{%s} %d\n", histogram, l, s.Calls)
+	}
+
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", errors, errorsHelp, errors)
+	for _, op := range ops {
+		fmt.Fprintf(w, "%s{%s=%s} %d\n", errors, label, labelValue(strings.TrimPrefix(op, HTTPPrefix)), snap[op].Errors)
+	}
+}
+
+// labelValue quotes a label value, escaping it as the format requires.
+func labelValue(v string) string {
+	v = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+	return `"` + v + `"`
+}
+
+// metricName replaces the characters metric names can't have, like the dots
+// in "mailer.backlog".
+func metricName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+}
@@ -0,0 +1,500 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/gavinwade12/sendkey"
+	"github.com/gavinwade12/sendkey/internal/app"
+	"github.com/google/uuid"
+)
+
+// The store decorators below record every repository call in a registry as
+// "<store>.<Method>", e.g. "entrystore.Find".
+
+type UserStore struct {
+	next app.UserRepository
+	r    *Registry
+}
+
+func NewUserStore(next app.UserRepository, r *Registry) *UserStore {
+	return &UserStore{next, r}
+}
+
+func (s *UserStore) Find(id uuid.UUID) (u *sendkey.User, err error) {
+	defer s.r.observe("userstore.Find", time.Now(), &err)
+	return s.next.Find(id)
+}
+
+func (s *UserStore) FindByEmail(email string) (u *sendkey.User, err error) {
+	defer s.r.observe("userstore.FindByEmail", time.Now(), &err)
+	return s.next.FindByEmail(email)
+}
+
+func (s *UserStore) Create(u sendkey.User) (err error) {
+	defer s.r.observe("userstore.Create", time.Now(), &err)
+	return s.next.Create(u)
+}
+
+func (s *UserStore) Update(u sendkey.User) (err error) {
+	defer s.r.observe("userstore.Update", time.Now(), &err)
+	return s.next.Update(u)
+}
+
+func (s *UserStore) Delete(id uuid.UUID) (err error) {
+	defer s.r.observe("userstore.Delete", time.Now(), &err)
+	return s.next.Delete(id)
+}
+
+func (s *UserStore) DeleteDeactivatedBefore(t time.Time) (n int64, err error) {
+	defer s.r.observe("userstore.DeleteDeactivatedBefore", time.Now(), &err)
+	return s.next.DeleteDeactivatedBefore(t)
+}
+
+func (s *UserStore) AdvanceMFAStep(id uuid.UUID, step int64) (advanced bool, err error) {
+	defer s.r.observe("userstore.AdvanceMFAStep", time.Now(), &err)
+	return s.next.AdvanceMFAStep(id, step)
+}
+
+func (s *UserStore) Search(query, afterEmail string, limit int) (u []sendkey.User, err error) {
+	defer s.r.observe("userstore.Search", time.Now(), &err)
+	return s.next.Search(query, afterEmail, limit)
+}
+
+type UserIdentityStore struct {
+	next app.UserIdentityRepository
+	r    *Registry
+}
+
+func NewUserIdentityStore(next app.UserIdentityRepository, r *Registry) *UserIdentityStore {
+	return &UserIdentityStore{next, r}
+}
+
+func (s *UserIdentityStore) Create(i sendkey.UserIdentity) (err error) {
+	defer s.r.observe("useridentitystore.Create", time.Now(), &err)
+	return s.next.Create(i)
+}
+
+func (s *UserIdentityStore) Find(provider, subject string) (i *sendkey.UserIdentity, err error) {
+	defer s.r.observe("useridentitystore.Find", time.Now(), &err)
+	return s.next.Find(provider, subject)
+}
+
+type RecoveryCodeStore struct {
+	next app.RecoveryCodeRepository
+	r    *Registry
+}
+
+func NewRecoveryCodeStore(next app.RecoveryCodeRepository, r *Registry) *RecoveryCodeStore {
+	return &RecoveryCodeStore{next, r}
+}
+
+func (s *RecoveryCodeStore) Create(c sendkey.RecoveryCode) (err error) {
+	defer s.r.observe("recoverycodestore.Create", time.Now(), &err)
+	return s.next.Create(c)
+}
+
+func (s *RecoveryCodeStore) DeleteByUserAndHash(userID uuid.UUID, codeHash string) (deleted bool, err error) {
+	defer s.r.observe("recoverycodestore.DeleteByUserAndHash", time.Now(), &err)
+	return s.next.DeleteByUserAndHash(userID, codeHash)
+}
+
+func (s *RecoveryCodeStore) DeleteByUserID(userID uuid.UUID) (err error) {
+	defer s.r.observe("recoverycodestore.DeleteByUserID", time.Now(), &err)
+	return s.next.DeleteByUserID(userID)
+}
+
+type MagicLinkStore struct {
+	next app.MagicLinkRepository
+	r    *Registry
+}
+
+func NewMagicLinkStore(next app.MagicLinkRepository, r *Registry) *MagicLinkStore {
+	return &MagicLinkStore{next, r}
+}
+
+func (s *MagicLinkStore) Create(l sendkey.MagicLink) (err error) {
+	defer s.r.observe("magiclinkstore.Create", time.Now(), &err)
+	return s.next.Create(l)
+}
+
+func (s *MagicLinkStore) Find(id uuid.UUID) (l *sendkey.MagicLink, err error) {
+	defer s.r.observe("magiclinkstore.Find", time.Now(), &err)
+	return s.next.Find(id)
+}
+
+func (s *MagicLinkStore) Delete(id uuid.UUID) (deleted bool, err error) {
+	defer s.r.observe("magiclinkstore.Delete", time.Now(), &err)
+	return s.next.Delete(id)
+}
+
+type AuditStore struct {
+	next app.AuditRepository
+	r    *Registry
+}
+
+func NewAuditStore(next app.AuditRepository, r *Registry) *AuditStore {
+	return &AuditStore{next, r}
+}
+
+func (s *AuditStore) Advance(build func(head sendkey.AuditHead) sendkey.AuditEvent, write func(sendkey.AuditEvent) error) (err error) {
+	defer s.r.observe("auditstore.Advance", time.Now(), &err)
+	return s.next.Advance(build, write)
+}
+
+func (s *AuditStore) Head() (h sendkey.AuditHead, err error) {
+	defer s.r.observe("auditstore.Head", time.Now(), &err)
+	return s.next.Head()
+}
+
+func (s *AuditStore) CreateAnchor(a sendkey.AuditAnchor) (err error) {
+	defer s.r.observe("auditstore.CreateAnchor", time.Now(), &err)
+	return s.next.CreateAnchor(a)
+}
+
+func (s *AuditStore) LastAnchor() (a *sendkey.AuditAnchor, err error) {
+	defer s.r.observe("auditstore.LastAnchor", time.Now(), &err)
+	return s.next.LastAnchor()
+}
+
+func (s *AuditStore) Anchors(afterSeq int64, limit int) (a []sendkey.AuditAnchor, err error) {
+	defer s.r.observe("auditstore.Anchors", time.Now(), &err)
+	return s.next.Anchors(afterSeq, limit)
+}
+
+type AuditSink struct {
+	next app.AuditSink
+	r    *Registry
+}
+
+func NewAuditSink(next app.AuditSink, r *Registry) *AuditSink {
+	return &AuditSink{next, r}
+}
+
+func (s *AuditSink) Write(e sendkey.AuditEvent) (err error) {
+	defer s.r.observe("auditsink.Write", time.Now(), &err)
+	return s.next.Write(e)
+}
+
+type EntryStore struct {
+	next app.EntryRepository
+	r    *Registry
+}
+
+func NewEntryStore(next app.EntryRepository, r *Registry) *EntryStore {
+	return &EntryStore{next, r}
+}
+
+func (s *EntryStore) Find(id uuid.UUID) (e *sendkey.Entry, err error) {
+	defer s.r.observe("entrystore.Find", time.Now(), &err)
+	return s.next.Find(id)
+}
+
+func (s *EntryStore) FindByUserID(userID uuid.UUID) (e []sendkey.Entry, err error) {
+	defer s.r.observe("entrystore.FindByUserID", time.Now(), &err)
+	return s.next.FindByUserID(userID)
+}
+
+func (s *EntryStore) Create(e sendkey.Entry) (err error) {
+	defer s.r.observe("entrystore.Create", time.Now(), &err)
+	return s.next.Create(e)
+}
+
+func (s *EntryStore) Delete(id uuid.UUID) (err error) {
+	defer s.r.observe("entrystore.Delete", time.Now(), &err)
+	return s.next.Delete(id)
+}
+
+func (s *EntryStore) IncrementInvalidAttempts(id uuid.UUID) (n int, err error) {
+	defer s.r.observe("entrystore.IncrementInvalidAttempts", time.Now(), &err)
+	return s.next.IncrementInvalidAttempts(id)
+}
+
+func (s *EntryStore) CreateClaimedEntry(e sendkey.ClaimedEntry) (err error) {
+	defer s.r.observe("entrystore.CreateClaimedEntry", time.Now(), &err)
+	return s.next.CreateClaimedEntry(e)
+}
+
+func (s *EntryStore) FindClaimedEntry(entryID uuid.UUID) (e *sendkey.ClaimedEntry, err error) {
+	defer s.r.observe("entrystore.FindClaimedEntry", time.Now(), &err)
+	return s.next.FindClaimedEntry(entryID)
+}
+
+func (s *EntryStore) CreateExpiredEntry(e sendkey.ExpiredEntry) (err error) {
+	defer s.r.observe("entrystore.CreateExpiredEntry", time.Now(), &err)
+	return s.next.CreateExpiredEntry(e)
+}
+
+func (s *EntryStore) CreateClaimReceipt(r sendkey.ClaimReceipt) (err error) {
+	defer s.r.observe("entrystore.CreateClaimReceipt", time.Now(), &err)
+	return s.next.CreateClaimReceipt(r)
+}
+
+func (s *EntryStore) FindChallenges(entryID uuid.UUID) (c []sendkey.EntryChallenge, err error) {
+	defer s.r.observe("entrystore.FindChallenges", time.Now(), &err)
+	return s.next.FindChallenges(entryID)
+}
+
+func (s *EntryStore) FindStaleKeyVersion(current, limit int) (e []sendkey.Entry, err error) {
+	defer s.r.observe("entrystore.FindStaleKeyVersion", time.Now(), &err)
+	return s.next.FindStaleKeyVersion(current, limit)
+}
+
+func (s *EntryStore) CountByKeyVersion(version int) (n int, err error) {
+	defer s.r.observe("entrystore.CountByKeyVersion", time.Now(), &err)
+	return s.next.CountByKeyVersion(version)
+}
+
+func (s *EntryStore) UpdateEncryption(id uuid.UUID, value []byte, keyVersion int) (err error) {
+	defer s.r.observe("entrystore.UpdateEncryption", time.Now(), &err)
+	return s.next.UpdateEncryption(id, value, keyVersion)
+}
+
+func (s *EntryStore) FindUnexpired(now time.Time, after uuid.UUID, limit int) (e []sendkey.Entry, err error) {
+	defer s.r.observe("entrystore.FindUnexpired", time.Now(), &err)
+	return s.next.FindUnexpired(now, after, limit)
+}
+
+func (s *EntryStore) CreateDeferral(d sendkey.EntryDeferral) (err error) {
+	defer s.r.observe("entrystore.CreateDeferral", time.Now(), &err)
+	return s.next.CreateDeferral(d)
+}
+
+func (s *EntryStore) FindDeferrals(entryID uuid.UUID) (d []sendkey.EntryDeferral, err error) {
+	defer s.r.observe("entrystore.FindDeferrals", time.Now(), &err)
+	return s.next.FindDeferrals(entryID)
+}
+
+func (s *EntryStore) FindDueDeferrals(now time.Time, limit int) (d []sendkey.EntryDeferral, err error) {
+	defer s.r.observe("entrystore.FindDueDeferrals", time.Now(), &err)
+	return s.next.FindDueDeferrals(now, limit)
+}
+
+func (s *EntryStore) MarkDeferralReminded(id uuid.UUID, at time.Time) (err error) {
+	defer s.r.observe("entrystore.MarkDeferralReminded", time.Now(), &err)
+	return s.next.MarkDeferralReminded(id, at)
+}
+
+func (s *EntryStore) SaveOTP(o sendkey.EntryOTP) (err error) {
+	defer s.r.observe("entrystore.SaveOTP", time.Now(), &err)
+	return s.next.SaveOTP(o)
+}
+
+func (s *EntryStore) FindOTP(entryID uuid.UUID) (o *sendkey.EntryOTP, err error) {
+	defer s.r.observe("entrystore.FindOTP", time.Now(), &err)
+	return s.next.FindOTP(entryID)
+}
+
+func (s *EntryStore) CreateDelegation(d sendkey.EntryDelegation) (err error) {
+	defer s.r.observe("entrystore.CreateDelegation", time.Now(), &err)
+	return s.next.CreateDelegation(d)
+}
+
+func (s *EntryStore) FindDelegation(id uuid.UUID) (d *sendkey.EntryDelegation, err error) {
+	defer s.r.observe("entrystore.FindDelegation", time.Now(), &err)
+	return s.next.FindDelegation(id)
+}
+
+func (s *EntryStore) CreateClaimLink(l sendkey.ClaimLink) (err error) {
+	defer s.r.observe("entrystore.CreateClaimLink", time.Now(), &err)
+	return s.next.CreateClaimLink(l)
+}
+
+func (s *EntryStore) FindClaimLinks(entryID uuid.UUID) (links []sendkey.ClaimLink, err error) {
+	defer s.r.observe("entrystore.FindClaimLinks", time.Now(), &err)
+	return s.next.FindClaimLinks(entryID)
+}
+
+func (s *EntryStore) CreateWithEscrow(e sendkey.Entry, escrow sendkey.EntryEscrow) (err error) {
+	defer s.r.observe("entrystore.CreateWithEscrow", time.Now(), &err)
+	return s.next.CreateWithEscrow(e, escrow)
+}
+
+func (s *EntryStore) FindEscrow(entryID uuid.UUID) (e *sendkey.EntryEscrow, err error) {
+	defer s.r.observe("entrystore.FindEscrow", time.Now(), &err)
+	return s.next.FindEscrow(entryID)
+}
+
+func (s *EntryStore) RetainEscrow(entryID uuid.UUID, claimedAt, purgeAt time.Time) (err error) {
+	defer s.r.observe("entrystore.RetainEscrow", time.Now(), &err)
+	return s.next.RetainEscrow(entryID, claimedAt, purgeAt)
+}
+
+func (s *EntryStore) DeleteEscrow(entryID uuid.UUID) (err error) {
+	defer s.r.observe("entrystore.DeleteEscrow", time.Now(), &err)
+	return s.next.DeleteEscrow(entryID)
+}
+
+func (s *EntryStore) PurgeEscrows(before time.Time) (n int64, err error) {
+	defer s.r.observe("entrystore.PurgeEscrows", time.Now(), &err)
+	return s.next.PurgeEscrows(before)
+}
+
+func (s *EntryStore) FindOutcomesBefore(before time.Time, limit int) (outcomes []sendkey.EntryOutcome, err error) {
+	defer s.r.observe("entrystore.FindOutcomesBefore", time.Now(), &err)
+	return s.next.FindOutcomesBefore(before, limit)
+}
+
+func (s *EntryStore) DeleteOutcomes(entryIDs []uuid.UUID) (n int64, err error) {
+	defer s.r.observe("entrystore.DeleteOutcomes", time.Now(), &err)
+	return s.next.DeleteOutcomes(entryIDs)
+}
+
+func (s *EntryStore) SuppressRecipient(email string, at time.Time) (err error) {
+	defer s.r.observe("entrystore.SuppressRecipient", time.Now(), &err)
+	return s.next.SuppressRecipient(email, at)
+}
+
+func (s *EntryStore) UnsuppressRecipient(email string) (ok bool, err error) {
+	defer s.r.observe("entrystore.UnsuppressRecipient", time.Now(), &err)
+	return s.next.UnsuppressRecipient(email)
+}
+
+func (s *EntryStore) RecipientSuppressed(email string) (ok bool, err error) {
+	defer s.r.observe("entrystore.RecipientSuppressed", time.Now(), &err)
+	return s.next.RecipientSuppressed(email)
+}
+
+func (s *EntryStore) SaveFlags(userID, entryID uuid.UUID, f sendkey.EntryFlags) (err error) {
+	defer s.r.observe("entrystore.SaveFlags", time.Now(), &err)
+	return s.next.SaveFlags(userID, entryID, f)
+}
+
+func (s *EntryStore) FindFlags(userID uuid.UUID) (flags map[uuid.UUID]sendkey.EntryFlags, err error) {
+	defer s.r.observe("entrystore.FindFlags", time.Now(), &err)
+	return s.next.FindFlags(userID)
+}
+
+func (s *EntryStore) Revoke(filter sendkey.EntryFilter, at time.Time) (e []sendkey.ExpiredEntry, err error) {
+	defer s.r.observe("entrystore.Revoke", time.Now(), &err)
+	return s.next.Revoke(filter, at)
+}
+
+func (s *EntryStore) FindOutcomes(filter sendkey.OutcomeFilter, before time.Time, beforeID uuid.UUID, limit int) (o []sendkey.EntryOutcome, err error) {
+	defer s.r.observe("entrystore.FindOutcomes", time.Now(), &err)
+	return s.next.FindOutcomes(filter, before, beforeID, limit)
+}
+
+// refreshTokenRepository matches the API's refresh token repository, which
+// isn't part of the app package.
+type refreshTokenRepository interface {
+	Create(sendkey.RefreshToken) error
+	FindByTokenAndUser(token string, userID uuid.UUID) (*sendkey.RefreshToken, error)
+	Delete(uuid.UUID) error
+	DeleteByUserID(uuid.UUID) error
+}
+
+type RefreshTokenStore struct {
+	next refreshTokenRepository
+	r    *Registry
+}
+
+func NewRefreshTokenStore(next refreshTokenRepository, r *Registry) *RefreshTokenStore {
+	return &RefreshTokenStore{next, r}
+}
+
+func (s *RefreshTokenStore) Create(t sendkey.RefreshToken) (err error) {
+	defer s.r.observe("refreshtokenstore.Create", time.Now(), &err)
+	return s.next.Create(t)
+}
+
+func (s *RefreshTokenStore) FindByTokenAndUser(token string, userID uuid.UUID) (t *sendkey.RefreshToken, err error) {
+	defer s.r.observe("refreshtokenstore.FindByTokenAndUser", time.Now(), &err)
+	return s.next.FindByTokenAndUser(token, userID)
+}
+
+func (s *RefreshTokenStore) Delete(id uuid.UUID) (err error) {
+	defer s.r.observe("refreshtokenstore.Delete", time.Now(), &err)
+	return s.next.Delete(id)
+}
+
+func (s *RefreshTokenStore) DeleteByUserID(userID uuid.UUID) (err error) {
+	defer s.r.observe("refreshtokenstore.DeleteByUserID", time.Now(), &err)
+	return s.next.DeleteByUserID(userID)
+}
+
+// idempotencyRepository matches the API's idempotency key repository, which
+// isn't part of the app package.
+type idempotencyRepository interface {
+	Reserve(sendkey.IdempotencyRecord) (bool, error)
+	Find(keyHash string) (*sendkey.IdempotencyRecord, error)
+	Complete(keyHash string, statusCode int, response []byte) error
+	Delete(keyHash string) error
+	Purge(before time.Time) (int64, error)
+}
+
+type IdempotencyStore struct {
+	next idempotencyRepository
+	r    *Registry
+}
+
+func NewIdempotencyStore(next idempotencyRepository, r *Registry) *IdempotencyStore {
+	return &IdempotencyStore{next, r}
+}
+
+func (s *IdempotencyStore) Reserve(rec sendkey.IdempotencyRecord) (ok bool, err error) {
+	defer s.r.observe("idempotencystore.Reserve", time.Now(), &err)
+	return s.next.Reserve(rec)
+}
+
+func (s *IdempotencyStore) Find(keyHash string) (rec *sendkey.IdempotencyRecord, err error) {
+	defer s.r.observe("idempotencystore.Find", time.Now(), &err)
+	return s.next.Find(keyHash)
+}
+
+func (s *IdempotencyStore) Complete(keyHash string, statusCode int, response []byte) (err error) {
+	defer s.r.observe("idempotencystore.Complete", time.Now(), &err)
+	return s.next.Complete(keyHash, statusCode, response)
+}
+
+func (s *IdempotencyStore) Delete(keyHash string) (err error) {
+	defer s.r.observe("idempotencystore.Delete", time.Now(), &err)
+	return s.next.Delete(keyHash)
+}
+
+func (s *IdempotencyStore) Purge(before time.Time) (n int64, err error) {
+	defer s.r.observe("idempotencystore.Purge", time.Now(), &err)
+	return s.next.Purge(before)
+}
+
+type UsageStore struct {
+	next app.UsageRepository
+	r    *Registry
+}
+
+func NewUsageStore(next app.UsageRepository, r *Registry) *UsageStore {
+	return &UsageStore{next, r}
+}
+
+func (s *UsageStore) AddEntryCreated(userID uuid.UUID, day time.Time) (err error) {
+	defer s.r.observe("usagestore.AddEntryCreated", time.Now(), &err)
+	return s.next.AddEntryCreated(userID, day)
+}
+
+func (s *UsageStore) EntriesCreated(userID uuid.UUID, day time.Time) (n int, err error) {
+	defer s.r.observe("usagestore.EntriesCreated", time.Now(), &err)
+	return s.next.EntriesCreated(userID, day)
+}
+
+func (s *UsageStore) CountLiveEntries(userID uuid.UUID, now time.Time) (n int, err error) {
+	defer s.r.observe("usagestore.CountLiveEntries", time.Now(), &err)
+	return s.next.CountLiveEntries(userID, now)
+}
+
+type AnonymousSenderStore struct {
+	next app.AnonymousSenderRepository
+	r    *Registry
+}
+
+func NewAnonymousSenderStore(next app.AnonymousSenderRepository, r *Registry) *AnonymousSenderStore {
+	return &AnonymousSenderStore{next, r}
+}
+
+func (s *AnonymousSenderStore) Record(addressHash string, at time.Time) (a *sendkey.AnonymousSender, err error) {
+	defer s.r.observe("anonymoussenderstore.Record", time.Now(), &err)
+	return s.next.Record(addressHash, at)
+}
+
+func (s *AnonymousSenderStore) Find(id uuid.UUID) (a *sendkey.AnonymousSender, err error) {
+	defer s.r.observe("anonymoussenderstore.Find", time.Now(), &err)
+	return s.next.Find(id)
+}
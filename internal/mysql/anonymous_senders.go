@@ -0,0 +1,56 @@
+package mysql
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/gavinwade12/sendkey"
+	"github.com/google/uuid"
+)
+
+type anonymousSenderStore struct {
+	conn Conn
+	ids  sendkey.IDGenerator
+}
+
+func (s *anonymousSenderStore) Record(addressHash string, at time.Time) (*sendkey.AnonymousSender, error) {
+	id := sendkey.RandomIDs.NewID()
+	if s.ids != nil {
+		id = s.ids.NewID()
+	}
+	_, err := s.conn.Exec(`
+	INSERT INTO anonymous_senders(id, addressHash, entriesCreated, firstSeenAtUtc, lastSeenAtUtc)
+	VALUES (?, ?, 1, ?, ?)
+	ON DUPLICATE KEY UPDATE entriesCreated = entriesCreated + 1, lastSeenAtUtc = VALUES(lastSeenAtUtc);`,
+		mysqlUUID(id[:]), addressHash, at, at)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.find(`addressHash = ?`, addressHash)
+}
+
+func (s *anonymousSenderStore) Find(id uuid.UUID) (*sendkey.AnonymousSender, error) {
+	return s.find(`id = ?`, mysqlUUID(id[:]))
+}
+
+func (s *anonymousSenderStore) find(where string, arg interface{}) (*sendkey.AnonymousSender, error) {
+	row := s.conn.QueryRow(`
+	SELECT id, addressHash, entriesCreated, firstSeenAtUtc, lastSeenAtUtc
+	FROM anonymous_senders WHERE `+where+`;`, arg)
+	var (
+		id     mysqlUUID
+		sender sendkey.AnonymousSender
+	)
+
+	err := row.Scan(&id, &sender.AddressHash, &sender.EntriesCreated, &sender.FirstSeenAtUTC, &sender.LastSeenAtUTC)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	sender.ID = id.UUID()
+	return &sender, nil
+}
@@ -0,0 +1,140 @@
+package mysql
+
+import (
+	"database/sql"
+
+	"github.com/gavinwade12/sendkey"
+)
+
+// auditStore keeps the audit log's chain head and anchors. The events are
+// written to a sink, which is auditEventStore by default.
+type auditStore struct {
+	conn Conn
+}
+
+func (s *auditStore) Advance(build func(head sendkey.AuditHead) sendkey.AuditEvent, write func(sendkey.AuditEvent) error) error {
+	return inTx(s.conn, func(conn Conn) error {
+		// locking the head makes concurrent appends take turns, so the
+		// sink gets the events in order
+		var head sendkey.AuditHead
+		err := conn.QueryRow(`SELECT seq, hash FROM audit_head WHERE id = 1 FOR UPDATE;`).Scan(&head.Seq, &head.Hash)
+		if err != nil {
+			return err
+		}
+
+		e := build(head)
+		if err = write(e); err != nil {
+			return err
+		}
+
+		_, err = conn.Exec(`UPDATE audit_head SET seq = ?, hash = ? WHERE id = 1;`, e.Seq, e.Hash)
+		return err
+	})
+}
+
+func (s *auditStore) Head() (sendkey.AuditHead, error) {
+	var head sendkey.AuditHead
+	err := s.conn.QueryRow(`SELECT seq, hash FROM audit_head WHERE id = 1;`).Scan(&head.Seq, &head.Hash)
+	return head, err
+}
+
+func (s *auditStore) CreateAnchor(a sendkey.AuditAnchor) error {
+	_, err := s.conn.Exec(`
+	INSERT INTO audit_anchors(seq, hash, signature, createdAtUtc)
+	VALUES (?, ?, ?, ?);`,
+		a.Seq, a.Hash, a.Signature, a.CreatedAtUTC)
+	return err
+}
+
+func (s *auditStore) LastAnchor() (*sendkey.AuditAnchor, error) {
+	row := s.conn.QueryRow(`
+SELECT seq, hash, signature, createdAtUtc
+FROM audit_anchors
+ORDER BY seq DESC
+LIMIT 1;`)
+
+	a := &sendkey.AuditAnchor{}
+	err := row.Scan(&a.Seq, &a.Hash, &a.Signature, &a.CreatedAtUTC)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+func (s *auditStore) Anchors(afterSeq int64, limit int) ([]sendkey.AuditAnchor, error) {
+	rows, err := s.conn.Query(`
+SELECT seq, hash, signature, createdAtUtc
+FROM audit_anchors
+WHERE seq > ?
+ORDER BY seq
+LIMIT ?;`,
+		afterSeq, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []sendkey.AuditAnchor{}
+	for rows.Next() {
+		var a sendkey.AuditAnchor
+		if err = rows.Scan(&a.Seq, &a.Hash, &a.Signature, &a.CreatedAtUTC); err != nil {
+			return nil, err
+		}
+		result = append(result, a)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// auditEventStore is the default audit sink, storing events in the database.
+// An event whose head wasn't moved is replaced by the next one with its Seq.
+type auditEventStore struct {
+	conn Conn
+}
+
+func (s *auditEventStore) Write(e sendkey.AuditEvent) error {
+	_, err := s.conn.Exec(`
+	INSERT INTO audit_events(seq, type, data, atUtc, prevHash, hash)
+	VALUES (?, ?, ?, ?, ?, ?)
+	ON DUPLICATE KEY UPDATE type = VALUES(type), data = VALUES(data), atUtc = VALUES(atUtc),
+		prevHash = VALUES(prevHash), hash = VALUES(hash);`,
+		e.Seq, e.Type, e.Data, e.AtUTC, e.PrevHash, e.Hash)
+	return err
+}
+
+func (s *auditEventStore) Events(afterSeq int64, limit int) ([]sendkey.AuditEvent, error) {
+	rows, err := s.conn.Query(`
+SELECT seq, type, data, atUtc, prevHash, hash
+FROM audit_events
+WHERE seq > ?
+ORDER BY seq
+LIMIT ?;`,
+		afterSeq, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []sendkey.AuditEvent{}
+	for rows.Next() {
+		var e sendkey.AuditEvent
+		if err = rows.Scan(&e.Seq, &e.Type, &e.Data, &e.AtUTC, &e.PrevHash, &e.Hash); err != nil {
+			return nil, err
+		}
+		result = append(result, e)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
@@ -5,11 +5,12 @@ import (
 	"encoding/hex"
 	"fmt"
 	"io/ioutil"
-	"os"
 	"path"
 	"sort"
 	"strings"
+	"time"
 
+	"github.com/gavinwade12/sendkey"
 	// mysql driver
 	"github.com/go-sql-driver/mysql"
 	"github.com/google/uuid"
@@ -24,11 +25,24 @@ type DB struct {
 	dropExisting  bool
 	migrationsDir string
 	migrations    []string
+	checkOnly     bool
+	ran           []Migration
+	pending       []string
+	observe       func(phase string, d time.Duration, err error)
 	dropOnClose   bool
+	ids           sendkey.IDGenerator
 
 	Users         *userStore
 	Entries       *entryStore
 	RefreshTokens *refreshTokenStore
+	RecoveryCodes *recoveryCodeStore
+	Identities    *userIdentityStore
+	MagicLinks    *magicLinkStore
+	Audit         *auditStore
+	AuditEvents   *auditEventStore
+	Usage         *usageStore
+	Anonymous     *anonymousSenderStore
+	Idempotency   *idempotencyStore
 }
 
 // DBWithTx wraps a DB with a sql Tx.
@@ -59,6 +73,29 @@ type Tx interface {
 	Commit() error
 }
 
+// inTx runs f in a transaction. If conn is already a transaction, f runs in
+// it and the caller commits.
+func inTx(conn Conn, f func(Conn) error) error {
+	fc, ok := conn.(*failoverConn)
+	if !ok {
+		return f(conn)
+	}
+
+	tx, err := fc.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err = f(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// likeEscaper escapes the wildcards in a LIKE pattern, using ! as the escape
+// character.
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
 func (db *DB) WithTx() (*DBWithTx, error) {
 	tx, err := db.db.Begin()
 	if err != nil {
@@ -75,9 +112,18 @@ func (db *DB) WithTx() (*DBWithTx, error) {
 			migrationsDir: db.migrationsDir,
 			migrations:    db.migrations,
 			dropOnClose:   db.dropOnClose,
+			ids:           db.ids,
 			Users:         &userStore{tx},
 			Entries:       &entryStore{tx},
 			RefreshTokens: &refreshTokenStore{tx},
+			RecoveryCodes: &recoveryCodeStore{tx},
+			Identities:    &userIdentityStore{tx},
+			MagicLinks:    &magicLinkStore{tx},
+			Audit:         &auditStore{tx},
+			AuditEvents:   &auditEventStore{tx},
+			Usage:         &usageStore{tx},
+			Anonymous:     &anonymousSenderStore{tx, db.ids},
+			Idempotency:   &idempotencyStore{tx},
 		},
 		tx: tx,
 	}, nil
@@ -114,6 +160,24 @@ func WithMigrations(migrationsDir string) Option {
 	}
 }
 
+// CheckMigrations returns an option that will configure the DB to only
+// check which of the migrations from WithMigrations haven't been run,
+// leaving the database unchanged. They're returned by PendingMigrations.
+func CheckMigrations() Option {
+	return func(db *DB) {
+		db.checkOnly = true
+	}
+}
+
+// ObservePhases returns an option that will configure the DB to call f with
+// how long each phase of setting it up took: "db.create" with AutoCreateDB,
+// "db.connect", and "db.migrate" with WithMigrations.
+func ObservePhases(f func(phase string, d time.Duration, err error)) Option {
+	return func(db *DB) {
+		db.observe = f
+	}
+}
+
 // DropDBOnClose returns an option that will configure the DB to
 // drop the underlying database when the DB is closed. This is useful
 // if the database is only needed temporarily e.g. for testing.
@@ -123,6 +187,15 @@ func DropDBOnClose() Option {
 	}
 }
 
+// GenerateIDs returns an option that will configure the DB to generate the
+// IDs of the records its stores create, like anonymous senders, with g.
+// Without it, they're random.
+func GenerateIDs(g sendkey.IDGenerator) Option {
+	return func(db *DB) {
+		db.ids = g
+	}
+}
+
 // NewDB returns a new DB with any necessary actions from the given options performed.
 func NewDB(dsn string, options ...Option) (*DB, error) {
 	cfg, err := mysql.ParseDSN(dsn)
@@ -145,39 +218,91 @@ func NewDB(dsn string, options ...Option) (*DB, error) {
 
 	if d.autoCreate {
 		cfg.DBName = ""
-		if err = createDatabaseIfNotExist(cfg.FormatDSN(), d.name); err != nil {
+		err = d.phase("db.create", func() error { return createDatabaseIfNotExist(cfg.FormatDSN(), d.name) })
+		if err != nil {
 			return nil, fmt.Errorf("auto-creating database: %w", err)
 		}
 		cfg.DBName = d.name
 	}
 
-	d.db, err = sql.Open("mysql", dsn)
+	err = d.phase("db.connect", func() error {
+		d.db, err = sql.Open("mysql", dsn)
+		if err != nil {
+			return fmt.Errorf("opening database: %w", err)
+		}
+		if err = d.db.Ping(); err != nil {
+			d.db.Close()
+			return err
+		}
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("opening database: %w", err)
-	}
-
-	if err = d.db.Ping(); err != nil {
-		d.db.Close()
 		return nil, err
 	}
 
 	if d.migrationsDir != "" {
-		if err = d.runMigrations(); err != nil {
+		if err = d.phase("db.migrate", d.runMigrations); err != nil {
 			d.db.Close()
 			return nil, fmt.Errorf("running migrations: %w", err)
 		}
 	}
 
-	d.Users = &userStore{d.db}
-	d.Entries = &entryStore{d.db}
-	d.RefreshTokens = &refreshTokenStore{d.db}
+	conn := &failoverConn{d.db}
+	d.Users = &userStore{conn}
+	d.Entries = &entryStore{conn}
+	d.RefreshTokens = &refreshTokenStore{conn}
+	d.RecoveryCodes = &recoveryCodeStore{conn}
+	d.Identities = &userIdentityStore{conn}
+	d.MagicLinks = &magicLinkStore{conn}
+	d.Audit = &auditStore{conn}
+	d.AuditEvents = &auditEventStore{conn}
+	d.Usage = &usageStore{conn}
+	d.Anonymous = &anonymousSenderStore{conn, d.ids}
+	d.Idempotency = &idempotencyStore{conn}
 
 	return d, nil
 }
 
+// phase runs f, passing how long it took to the ObservePhases func.
+func (db *DB) phase(name string, f func() error) error {
+	start := time.Now()
+	err := f()
+	if db.observe != nil {
+		db.observe(name, time.Since(start), err)
+	}
+	return err
+}
+
+// Migration is a migration run by NewDB.
+type Migration struct {
+	Name     string
+	Duration time.Duration
+}
+
+// Migrations returns the migrations NewDB ran, in the order they were run.
+func (db *DB) Migrations() []Migration {
+	return db.ran
+}
+
+// PendingMigrations returns the migrations that haven't been run, with
+// CheckMigrations.
+func (db *DB) PendingMigrations() []string {
+	return db.pending
+}
+
 // Close handles closing any underlying resources for the database. It also
 // runs any of the specified options that are required at the end of the database's
 // use, such as DropDBOnClose.
+// Stats returns the connection pool statistics.
+func (db *DB) Stats() sql.DBStats {
+	return db.db.Stats()
+}
+
+// Ping checks that the database can be reached.
+func (db *DB) Ping() error {
+	return db.db.Ping()
+}
+
 func (db *DB) Close() error {
 	err := db.db.Close()
 	if err != nil {
@@ -236,6 +361,10 @@ func dropExistingDatabaseIfExist(dsn, dbName string) error {
 }
 
 func (db *DB) runMigrations() error {
+	if db.checkOnly {
+		return db.checkMigrations()
+	}
+
 	_, err := db.db.Exec(`
 CREATE TABLE IF NOT EXISTS __Migrations (
 	ID INT NOT NULL AUTO_INCREMENT,
@@ -247,34 +376,20 @@ CREATE TABLE IF NOT EXISTS __Migrations (
 		return err
 	}
 
-	var fi []os.FileInfo
-	fi, err = ioutil.ReadDir(db.migrationsDir)
-	if err != nil {
-		return fmt.Errorf("reading migrations directory: %w", err)
-	}
-
-	db.migrations = make([]string, 0)
-	for _, f := range fi {
-		if f.IsDir() || strings.ToLower(path.Ext(f.Name())) != ".sql" {
-			continue
-		}
-
-		db.migrations = append(db.migrations, f.Name())
+	if err = db.readMigrations(); err != nil {
+		return err
 	}
 
-	sort.Strings(db.migrations)
-
 	for _, migration := range db.migrations {
-		var exists mysqlBool
-		row := db.db.QueryRow("SELECT COALESCE((SELECT b'1' FROM __Migrations WHERE `Name` = ?), b'0');", migration)
-		if err = row.Scan(&exists); err != nil {
-			return fmt.Errorf("querying for migration: %w", err)
+		exists, err := db.migrationRun(migration)
+		if err != nil {
+			return err
 		}
-
-		if bool(exists) {
+		if exists {
 			continue
 		}
 
+		start := time.Now()
 		p := path.Join(db.migrationsDir, migration)
 		s, err := ioutil.ReadFile(p)
 		if err != nil {
@@ -334,11 +449,78 @@ CREATE TABLE IF NOT EXISTS __Migrations (
 		if err != nil {
 			return fmt.Errorf("inserting migration record '%s': %w", migration, err)
 		}
+		db.ran = append(db.ran, Migration{Name: migration, Duration: time.Since(start)})
+	}
+
+	return nil
+}
+
+// checkMigrations finds the migrations that haven't been run without running
+// them, checking that each can be read.
+func (db *DB) checkMigrations() error {
+	if err := db.readMigrations(); err != nil {
+		return err
+	}
+
+	var tables int
+	err := db.db.QueryRow("SELECT COUNT(*) FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = '__Migrations';").Scan(&tables)
+	if err != nil {
+		return fmt.Errorf("querying for migrations table: %w", err)
+	}
+
+	db.pending = make([]string, 0)
+	for _, migration := range db.migrations {
+		if tables > 0 {
+			exists, err := db.migrationRun(migration)
+			if err != nil {
+				return err
+			}
+			if exists {
+				continue
+			}
+		}
+
+		p := path.Join(db.migrationsDir, migration)
+		if _, err = ioutil.ReadFile(p); err != nil {
+			return fmt.Errorf("reading file %s: %w", p, err)
+		}
+		db.pending = append(db.pending, migration)
 	}
 
 	return nil
 }
 
+// readMigrations lists the migrations in the migrations directory in the
+// order they're run.
+func (db *DB) readMigrations() error {
+	fi, err := ioutil.ReadDir(db.migrationsDir)
+	if err != nil {
+		return fmt.Errorf("reading migrations directory: %w", err)
+	}
+
+	db.migrations = make([]string, 0)
+	for _, f := range fi {
+		if f.IsDir() || strings.ToLower(path.Ext(f.Name())) != ".sql" {
+			continue
+		}
+
+		db.migrations = append(db.migrations, f.Name())
+	}
+
+	sort.Strings(db.migrations)
+	return nil
+}
+
+// migrationRun returns whether the migration's been run.
+func (db *DB) migrationRun(migration string) (bool, error) {
+	var exists mysqlBool
+	row := db.db.QueryRow("SELECT COALESCE((SELECT b'1' FROM __Migrations WHERE `Name` = ?), b'0');", migration)
+	if err := row.Scan(&exists); err != nil {
+		return false, fmt.Errorf("querying for migration: %w", err)
+	}
+	return bool(exists), nil
+}
+
 type mysqlBool bool
 
 func (b *mysqlBool) Scan(src interface{}) error {
@@ -364,9 +546,31 @@ func (b mysqlBool) Value() interface{} {
 	return []uint8("\x00")
 }
 
+// nullUUID stores uuid.Nil as NULL.
+func nullUUID(id uuid.UUID) interface{} {
+	if id == uuid.Nil {
+		return nil
+	}
+	return mysqlUUID(id[:])
+}
+
+// optionalUUID is nullUUID for an ID that may not be set.
+func optionalUUID(id *uuid.UUID) interface{} {
+	if id == nil {
+		return nil
+	}
+	return nullUUID(*id)
+}
+
 type mysqlUUID string
 
 func (u *mysqlUUID) Scan(src interface{}) error {
+	// a NULL ID, like a guest entry's sender, is uuid.Nil
+	if src == nil {
+		*u = ""
+		return nil
+	}
+
 	tmp, ok := src.([]byte)
 	if !ok {
 		return fmt.Errorf("unexpected type for mysqlUUID: %T", src)
@@ -381,5 +585,17 @@ func (u mysqlUUID) Value() interface{} {
 }
 
 func (u mysqlUUID) UUID() uuid.UUID {
+	if u == "" {
+		return uuid.Nil
+	}
 	return uuid.MustParse(hex.EncodeToString([]byte(u)))
 }
+
+// optional returns the ID, or nil if it's NULL.
+func (u mysqlUUID) optional() *uuid.UUID {
+	if u == "" {
+		return nil
+	}
+	id := u.UUID()
+	return &id
+}
@@ -2,6 +2,7 @@ package mysql
 
 import (
 	"database/sql"
+	"strings"
 	"time"
 
 	"github.com/gavinwade12/sendkey"
@@ -12,18 +13,56 @@ type entryStore struct {
 	conn Conn
 }
 
+// Create inserts the entry along with its challenges, in one transaction so
+// an entry is never left without the challenges that gate it.
 func (s *entryStore) Create(e sendkey.Entry) error {
-	_, err := s.conn.Exec(`
-	INSERT INTO entries(id, name, sentByUserId, sentToEmail, nonce, value, invalidAttempts, createdAtUtc, expiresAtUtc)
-	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?);`,
-		mysqlUUID(e.ID[:]), e.Name, mysqlUUID(e.SentByUserID[:]), e.SentToEmail,
-		string(e.Nonce), string(e.Value), e.InvalidAttempts, e.CreatedAtUTC, e.ExpiresAtUTC)
-	return err
+	return inTx(s.conn, func(conn Conn) error {
+		return createEntry(conn, e)
+	})
+}
+
+func createEntry(conn Conn, e sendkey.Entry) error {
+	var digest sendkey.ValueDigest
+	if e.Digest != nil {
+		digest = *e.Digest
+	}
+	var anonymousSenderID uuid.UUID
+	if e.AnonymousSenderID != nil {
+		anonymousSenderID = *e.AnonymousSenderID
+	}
+	_, err := conn.Exec(`
+	INSERT INTO entries(id, name, sentByUserId, sentToEmail, nonce, value, invalidAttempts, locale,
+		kdf, kdfSalt, kdfTime, kdfMemoryKiB, kdfThreads, keyVersion, cipher, endToEnd, requireLogin, requireOtp, type, username, hostname, createdAtUtc, availableAtUtc, expiresAtUtc,
+		digestAlgorithm, digestSalt, digestMac, sandbox, anonymousSenderId, replacesEntryId, acknowledgment)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`,
+		mysqlUUID(e.ID[:]), e.Name, nullUUID(e.SentByUserID), e.SentToEmail,
+		string(e.Nonce), string(e.Value), e.InvalidAttempts, e.Locale,
+		e.KDF.Algorithm, string(e.KDF.Salt), e.KDF.Time, e.KDF.MemoryKiB, e.KDF.Threads, e.KeyVersion, e.Cipher,
+		mysqlBool(e.EndToEnd), mysqlBool(e.RequireLogin), mysqlBool(e.RequireOTP), e.Type, e.Metadata.Username, e.Metadata.Hostname, e.CreatedAtUTC, e.AvailableAtUTC, e.ExpiresAtUTC,
+		digest.Algorithm, string(digest.Salt), string(digest.MAC), mysqlBool(e.Sandbox), nullUUID(anonymousSenderID), optionalUUID(e.ReplacesEntryID),
+		sql.NullString{String: e.Acknowledgment, Valid: e.Acknowledgment != ""})
+	if err != nil {
+		return err
+	}
+
+	for i, c := range e.Challenges {
+		_, err = conn.Exec(`
+	INSERT INTO entry_challenges(entryId, position, question, answerHash)
+	VALUES (?, ?, ?, ?);`,
+			mysqlUUID(e.ID[:]), i, c.Question, c.AnswerHash)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (s *entryStore) Find(id uuid.UUID) (*sendkey.Entry, error) {
 	row := s.conn.QueryRow(
-		`SELECT name, sentByUserId, sentToEmail, nonce, value, invalidAttempts, createdAtUtc, expiresAtUtc FROM entries WHERE id = ?;`,
+		`SELECT name, sentByUserId, sentToEmail, nonce, value, invalidAttempts, locale,
+		kdf, kdfSalt, kdfTime, kdfMemoryKiB, kdfThreads, keyVersion, cipher, endToEnd, requireLogin, requireOtp, sandbox, type, username, hostname, createdAtUtc, availableAtUtc, expiresAtUtc,
+		digestAlgorithm, digestSalt, digestMac, anonymousSenderId, replacesEntryId, acknowledgment
+		FROM entries WHERE id = ?;`,
 		mysqlUUID(string(id[:])))
 	var (
 		name            string
@@ -32,19 +71,40 @@ func (s *entryStore) Find(id uuid.UUID) (*sendkey.Entry, error) {
 		nonce           string
 		value           string
 		invalidAttempts int
+		locale          string
+		kdf             sendkey.EntryKDF
+		kdfSalt         string
+		keyVersion      int
+		cipher          string
+		endToEnd        mysqlBool
+		requireLogin    mysqlBool
+		requireOtp      mysqlBool
+		sandbox         mysqlBool
+		entryType       string
+		metadata        sendkey.EntryMetadata
 		createdAtUtc    time.Time
+		availableAtUtc  sql.NullTime
 		expiresAtUtc    time.Time
+		digest          sendkey.ValueDigest
+		digestSalt      string
+		digestMac       string
+		anonymousSender mysqlUUID
+		replacesEntryId mysqlUUID
+		acknowledgment  sql.NullString
 	)
 
-	err := row.Scan(&name, &sentByUserId, &sentToEmail, &nonce, &value, &invalidAttempts, &createdAtUtc, &expiresAtUtc)
+	err := row.Scan(&name, &sentByUserId, &sentToEmail, &nonce, &value, &invalidAttempts, &locale,
+		&kdf.Algorithm, &kdfSalt, &kdf.Time, &kdf.MemoryKiB, &kdf.Threads, &keyVersion, &cipher, &endToEnd, &requireLogin, &requireOtp, &sandbox, &entryType, &metadata.Username, &metadata.Hostname, &createdAtUtc, &availableAtUtc, &expiresAtUtc,
+		&digest.Algorithm, &digestSalt, &digestMac, &anonymousSender, &replacesEntryId, &acknowledgment)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
 		return nil, err
 	}
+	kdf.Salt = []byte(kdfSalt)
 
-	return &sendkey.Entry{
+	e := &sendkey.Entry{
 		ID:              id,
 		Name:            name,
 		SentByUserID:    sentByUserId.UUID(),
@@ -52,14 +112,40 @@ func (s *entryStore) Find(id uuid.UUID) (*sendkey.Entry, error) {
 		Nonce:           []byte(nonce),
 		Value:           []byte(value),
 		InvalidAttempts: invalidAttempts,
+		Locale:          locale,
+		KDF:             kdf,
+		KeyVersion:      keyVersion,
+		Cipher:          cipher,
+		EndToEnd:        bool(endToEnd),
+		RequireLogin:    bool(requireLogin),
+		RequireOTP:      bool(requireOtp),
+		Acknowledgment:  acknowledgment.String,
+		Sandbox:         bool(sandbox),
+		Type:            entryType,
+		Metadata:        metadata,
 		CreatedAtUTC:    createdAtUtc,
 		ExpiresAtUTC:    expiresAtUtc,
-	}, nil
+		ReplacesEntryID: replacesEntryId.optional(),
+	}
+	if availableAtUtc.Valid {
+		e.AvailableAtUTC = &availableAtUtc.Time
+	}
+	if digest.Algorithm != "" {
+		digest.Salt, digest.MAC = []byte(digestSalt), []byte(digestMac)
+		e.Digest = &digest
+	}
+	if id := anonymousSender.UUID(); id != uuid.Nil {
+		e.AnonymousSenderID = &id
+	}
+
+	return e, nil
 }
 
 func (s *entryStore) FindByUserID(userID uuid.UUID) ([]sendkey.Entry, error) {
 	rows, err := s.conn.Query(`
-SELECT id, name, sentToEmail, nonce, value, invalidAttempts, createdAtUtc, expiresAtUtc
+SELECT id, name, sentToEmail, nonce, value, invalidAttempts, locale,
+	kdf, kdfSalt, kdfTime, kdfMemoryKiB, kdfThreads, keyVersion, cipher, endToEnd, requireLogin, requireOtp, sandbox, type, username, hostname, createdAtUtc, availableAtUtc, expiresAtUtc,
+	replacesEntryId, acknowledgment
 FROM entries
 WHERE sentByUserId = ?
 ORDER BY createdAtUtc;`,
@@ -77,18 +163,35 @@ ORDER BY createdAtUtc;`,
 		nonce           string
 		value           string
 		invalidAttempts int
+		locale          string
+		kdf             sendkey.EntryKDF
+		kdfSalt         string
+		keyVersion      int
+		cipher          string
+		endToEnd        mysqlBool
+		requireLogin    mysqlBool
+		requireOtp      mysqlBool
+		sandbox         mysqlBool
+		entryType       string
+		metadata        sendkey.EntryMetadata
 		createdAtUtc    time.Time
+		availableAtUtc  sql.NullTime
 		expiresAtUtc    time.Time
+		replacesEntryId mysqlUUID
+		acknowledgment  sql.NullString
 
 		result = []sendkey.Entry{}
 	)
 	for rows.Next() {
-		err = rows.Scan(&id, &name, &sentToEmail, &nonce, &value, &invalidAttempts, &createdAtUtc, &expiresAtUtc)
+		err = rows.Scan(&id, &name, &sentToEmail, &nonce, &value, &invalidAttempts, &locale,
+			&kdf.Algorithm, &kdfSalt, &kdf.Time, &kdf.MemoryKiB, &kdf.Threads, &keyVersion, &cipher, &endToEnd, &requireLogin, &requireOtp, &sandbox, &entryType, &metadata.Username, &metadata.Hostname, &createdAtUtc, &availableAtUtc, &expiresAtUtc,
+			&replacesEntryId, &acknowledgment)
 		if err != nil {
 			return nil, err
 		}
+		kdf.Salt = []byte(kdfSalt)
 
-		result = append(result, sendkey.Entry{
+		e := sendkey.Entry{
 			ID:              id.UUID(),
 			Name:            name,
 			SentByUserID:    userID,
@@ -96,8 +199,158 @@ ORDER BY createdAtUtc;`,
 			Nonce:           []byte(nonce),
 			Value:           []byte(value),
 			InvalidAttempts: invalidAttempts,
+			Locale:          locale,
+			KDF:             kdf,
+			KeyVersion:      keyVersion,
+			Cipher:          cipher,
+			EndToEnd:        bool(endToEnd),
+			RequireLogin:    bool(requireLogin),
+			RequireOTP:      bool(requireOtp),
+			Acknowledgment:  acknowledgment.String,
+			Sandbox:         bool(sandbox),
+			Type:            entryType,
+			Metadata:        metadata,
+			CreatedAtUTC:    createdAtUtc,
+			ExpiresAtUTC:    expiresAtUtc,
+			ReplacesEntryID: replacesEntryId.optional(),
+		}
+		if availableAtUtc.Valid {
+			availableAt := availableAtUtc.Time
+			e.AvailableAtUTC = &availableAt
+		}
+		result = append(result, e)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (s *entryStore) FindUnexpired(now time.Time, after uuid.UUID, limit int) ([]sendkey.Entry, error) {
+	rows, err := s.conn.Query(`
+SELECT id, name, sentByUserId, sentToEmail, nonce, value, invalidAttempts, locale,
+	kdf, kdfSalt, kdfTime, kdfMemoryKiB, kdfThreads, keyVersion, cipher, endToEnd, requireLogin, requireOtp, sandbox, type, username, hostname, createdAtUtc, availableAtUtc, expiresAtUtc,
+	digestAlgorithm, digestSalt, digestMac, acknowledgment
+FROM entries
+WHERE expiresAtUtc > ? AND id > ?
+ORDER BY id
+LIMIT ?;`,
+		now, mysqlUUID(after[:]), limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var (
+		id              mysqlUUID
+		name            string
+		sentByUserId    mysqlUUID
+		sentToEmail     string
+		nonce           string
+		value           string
+		invalidAttempts int
+		locale          string
+		kdf             sendkey.EntryKDF
+		kdfSalt         string
+		keyVersion      int
+		cipher          string
+		endToEnd        mysqlBool
+		requireLogin    mysqlBool
+		requireOtp      mysqlBool
+		sandbox         mysqlBool
+		entryType       string
+		metadata        sendkey.EntryMetadata
+		createdAtUtc    time.Time
+		availableAtUtc  sql.NullTime
+		expiresAtUtc    time.Time
+		digest          sendkey.ValueDigest
+		digestSalt      string
+		digestMac       string
+		acknowledgment  sql.NullString
+
+		result = []sendkey.Entry{}
+	)
+	for rows.Next() {
+		err = rows.Scan(&id, &name, &sentByUserId, &sentToEmail, &nonce, &value, &invalidAttempts, &locale,
+			&kdf.Algorithm, &kdfSalt, &kdf.Time, &kdf.MemoryKiB, &kdf.Threads, &keyVersion, &cipher, &endToEnd, &requireLogin, &requireOtp, &sandbox, &entryType, &metadata.Username, &metadata.Hostname, &createdAtUtc, &availableAtUtc, &expiresAtUtc,
+			&digest.Algorithm, &digestSalt, &digestMac, &acknowledgment)
+		if err != nil {
+			return nil, err
+		}
+		kdf.Salt = []byte(kdfSalt)
+
+		e := sendkey.Entry{
+			ID:              id.UUID(),
+			Name:            name,
+			SentByUserID:    sentByUserId.UUID(),
+			SentToEmail:     sentToEmail,
+			Nonce:           []byte(nonce),
+			Value:           []byte(value),
+			InvalidAttempts: invalidAttempts,
+			Locale:          locale,
+			KDF:             kdf,
+			KeyVersion:      keyVersion,
+			Cipher:          cipher,
+			EndToEnd:        bool(endToEnd),
+			RequireLogin:    bool(requireLogin),
+			RequireOTP:      bool(requireOtp),
+			Acknowledgment:  acknowledgment.String,
+			Sandbox:         bool(sandbox),
+			Type:            entryType,
+			Metadata:        metadata,
 			CreatedAtUTC:    createdAtUtc,
 			ExpiresAtUTC:    expiresAtUtc,
+		}
+		if availableAtUtc.Valid {
+			availableAt := availableAtUtc.Time
+			e.AvailableAtUTC = &availableAt
+		}
+		if digest.Algorithm != "" {
+			d := sendkey.ValueDigest{Algorithm: digest.Algorithm, Salt: []byte(digestSalt), MAC: []byte(digestMac)}
+			e.Digest = &d
+		}
+		result = append(result, e)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (s *entryStore) FindStaleKeyVersion(current, limit int) ([]sendkey.Entry, error) {
+	rows, err := s.conn.Query(`
+SELECT id, value, keyVersion, cipher
+FROM entries
+WHERE keyVersion <> 0 AND keyVersion <> ?
+LIMIT ?;`,
+		current, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var (
+		id         mysqlUUID
+		value      string
+		keyVersion int
+		cipher     string
+
+		result = []sendkey.Entry{}
+	)
+	for rows.Next() {
+		if err = rows.Scan(&id, &value, &keyVersion, &cipher); err != nil {
+			return nil, err
+		}
+
+		result = append(result, sendkey.Entry{
+			ID:         id.UUID(),
+			Value:      []byte(value),
+			KeyVersion: keyVersion,
+			Cipher:     cipher,
 		})
 	}
 	if err = rows.Err(); err != nil {
@@ -107,6 +360,18 @@ ORDER BY createdAtUtc;`,
 	return result, nil
 }
 
+func (s *entryStore) CountByKeyVersion(version int) (int, error) {
+	var count int
+	err := s.conn.QueryRow(`SELECT COUNT(*) FROM entries WHERE keyVersion = ?;`, version).Scan(&count)
+	return count, err
+}
+
+func (s *entryStore) UpdateEncryption(id uuid.UUID, value []byte, keyVersion int) error {
+	_, err := s.conn.Exec(`UPDATE entries SET value = ?, keyVersion = ? WHERE id = ?;`,
+		string(value), keyVersion, mysqlUUID(id[:]))
+	return err
+}
+
 func (s *entryStore) Delete(id uuid.UUID) error {
 	_, err := s.conn.Exec(`DELETE FROM entries WHERE id = ?;`, mysqlUUID(id[:]))
 	return err
@@ -127,18 +392,628 @@ func (s *entryStore) IncrementInvalidAttempts(id uuid.UUID) (int, error) {
 
 func (s *entryStore) CreateClaimedEntry(ce sendkey.ClaimedEntry) error {
 	_, err := s.conn.Exec(`
-	INSERT INTO claimed_entries(entryId, name, sentByUserId, sentToEmail, claimedAtUtc)
-	VALUES (?, ?, ?, ?, ?);`,
-		mysqlUUID(ce.EntryID[:]), ce.Name, mysqlUUID(ce.SentByUserID[:]), ce.SentToEmail,
-		ce.ClaimedAtUTC)
+	INSERT INTO claimed_entries(entryId, name, sentByUserId, sentToEmail, claimedAtUtc, claimKeyHash, replacesEntryId,
+		acknowledgment, acknowledgedAtUtc)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?);`,
+		mysqlUUID(ce.EntryID[:]), ce.Name, nullUUID(ce.SentByUserID), ce.SentToEmail,
+		ce.ClaimedAtUTC, sql.NullString{String: ce.ClaimKeyHash, Valid: ce.ClaimKeyHash != ""}, optionalUUID(ce.ReplacesEntryID),
+		sql.NullString{String: ce.Acknowledgment, Valid: ce.Acknowledgment != ""}, ce.AcknowledgedAtUTC)
 	return err
 }
 
+func (s *entryStore) FindClaimedEntry(entryID uuid.UUID) (*sendkey.ClaimedEntry, error) {
+	row := s.conn.QueryRow(`
+	SELECT name, sentByUserId, sentToEmail, claimedAtUtc, claimKeyHash
+	FROM claimed_entries WHERE entryId = ?;`,
+		mysqlUUID(entryID[:]))
+	var (
+		ce           = sendkey.ClaimedEntry{EntryID: entryID}
+		sentByUserId mysqlUUID
+		claimKeyHash sql.NullString
+	)
+
+	err := row.Scan(&ce.Name, &sentByUserId, &ce.SentToEmail, &ce.ClaimedAtUTC, &claimKeyHash)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	ce.SentByUserID = sentByUserId.UUID()
+	ce.ClaimKeyHash = claimKeyHash.String
+	return &ce, nil
+}
+
 func (s *entryStore) CreateExpiredEntry(ee sendkey.ExpiredEntry) error {
 	_, err := s.conn.Exec(`
-	INSERT INTO expired_entries(entryId, name, sentByUserId, sentToEmail, tooManyAttempts, expiredAtUtc)
-	VALUES (?, ?, ?, ?, ?, ?);`,
-		mysqlUUID(ee.EntryID[:]), ee.Name, mysqlUUID(ee.SentByUserID[:]), ee.SentToEmail,
-		ee.TooManyAttempts, ee.ExpiredAtUTC)
+	INSERT INTO expired_entries(entryId, name, sentByUserId, sentToEmail, tooManyAttempts, revoked, expiredAtUtc, replacedByEntryId)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?);`,
+		mysqlUUID(ee.EntryID[:]), ee.Name, nullUUID(ee.SentByUserID), ee.SentToEmail,
+		ee.TooManyAttempts, ee.Revoked, ee.ExpiredAtUTC, optionalUUID(ee.ReplacedByEntryID))
+	return err
+}
+
+func (s *entryStore) Revoke(filter sendkey.EntryFilter, at time.Time) ([]sendkey.ExpiredEntry, error) {
+	query := `
+SELECT id, name, sentByUserId, sentToEmail
+FROM entries
+WHERE expiresAtUtc > ?`
+	args := []interface{}{at}
+	if filter.SentByUserID != nil {
+		query += ` AND sentByUserId = ?`
+		args = append(args, mysqlUUID(filter.SentByUserID[:]))
+	}
+	if filter.Type != "" {
+		query += ` AND type = ?`
+		args = append(args, filter.Type)
+	}
+	if filter.CreatedAfterUTC != nil {
+		query += ` AND createdAtUtc > ?`
+		args = append(args, *filter.CreatedAfterUTC)
+	}
+	if filter.RecipientDomain != "" {
+		query += ` AND sentToEmail LIKE ? ESCAPE '!'`
+		args = append(args, "%@"+likeEscaper.Replace(filter.RecipientDomain))
+	}
+	query += `
+FOR UPDATE;`
+
+	var result []sendkey.ExpiredEntry
+	err := inTx(s.conn, func(conn Conn) error {
+		rows, err := conn.Query(query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		var (
+			id           mysqlUUID
+			name         string
+			sentByUserId mysqlUUID
+			sentToEmail  string
+		)
+		result = []sendkey.ExpiredEntry{}
+		for rows.Next() {
+			if err = rows.Scan(&id, &name, &sentByUserId, &sentToEmail); err != nil {
+				return err
+			}
+
+			result = append(result, sendkey.ExpiredEntry{
+				EntryID:      id.UUID(),
+				Name:         name,
+				SentByUserID: sentByUserId.UUID(),
+				SentToEmail:  sentToEmail,
+				Revoked:      true,
+				ExpiredAtUTC: at,
+			})
+		}
+		if err = rows.Err(); err != nil {
+			return err
+		}
+
+		store := &entryStore{conn}
+		for _, ee := range result {
+			if err = store.CreateExpiredEntry(ee); err != nil {
+				return err
+			}
+			if err = store.Delete(ee.EntryID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (s *entryStore) FindOutcomes(filter sendkey.OutcomeFilter, before time.Time, beforeID uuid.UUID, limit int) ([]sendkey.EntryOutcome, error) {
+	query := `
+SELECT entryId, name, sentByUserId, sentToEmail, outcome, atUtc, replacesEntryId, replacedByEntryId,
+	acknowledgment, acknowledgedAtUtc
+FROM (
+	SELECT entryId, name, sentByUserId, sentToEmail, 'claimed' AS outcome, claimedAtUtc AS atUtc,
+		replacesEntryId, NULL AS replacedByEntryId, acknowledgment, acknowledgedAtUtc
+	FROM claimed_entries
+	UNION ALL
+	SELECT entryId, name, sentByUserId, sentToEmail,
+		CASE WHEN replacedByEntryId IS NOT NULL THEN 'replaced' WHEN revoked = 1 THEN 'revoked' WHEN tooManyAttempts = 1 THEN 'too_many_attempts' ELSE 'expired' END,
+		expiredAtUtc, NULL, replacedByEntryId, NULL, NULL
+	FROM expired_entries
+) outcomes
+WHERE 1 = 1`
+	var args []interface{}
+	if !before.IsZero() {
+		query += ` AND (atUtc < ? OR (atUtc = ? AND entryId < ?))`
+		args = append(args, before, before, mysqlUUID(beforeID[:]))
+	}
+	if filter.SentByUserID != nil {
+		query += ` AND sentByUserId = ?`
+		args = append(args, mysqlUUID(filter.SentByUserID[:]))
+	}
+	if filter.SentToEmail != "" {
+		query += ` AND sentToEmail = ?`
+		args = append(args, filter.SentToEmail)
+	}
+	if filter.SinceUTC != nil {
+		query += ` AND atUtc >= ?`
+		args = append(args, *filter.SinceUTC)
+	}
+	if filter.Claimed != nil {
+		query += ` AND (outcome = 'claimed') = ?`
+		args = append(args, *filter.Claimed)
+	}
+	query += `
+ORDER BY atUtc DESC, entryId DESC
+LIMIT ?;`
+	args = append(args, limit)
+
+	rows, err := s.conn.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanOutcomes(rows)
+}
+
+func (s *entryStore) FindOutcomesBefore(before time.Time, limit int) ([]sendkey.EntryOutcome, error) {
+	rows, err := s.conn.Query(`
+SELECT entryId, name, sentByUserId, sentToEmail, outcome, atUtc, replacesEntryId, replacedByEntryId,
+	acknowledgment, acknowledgedAtUtc
+FROM (
+	SELECT entryId, name, sentByUserId, sentToEmail, 'claimed' AS outcome, claimedAtUtc AS atUtc,
+		replacesEntryId, NULL AS replacedByEntryId, acknowledgment, acknowledgedAtUtc
+	FROM claimed_entries
+	WHERE claimedAtUtc < ?
+	UNION ALL
+	SELECT entryId, name, sentByUserId, sentToEmail,
+		CASE WHEN replacedByEntryId IS NOT NULL THEN 'replaced' WHEN revoked = 1 THEN 'revoked' WHEN tooManyAttempts = 1 THEN 'too_many_attempts' ELSE 'expired' END,
+		expiredAtUtc, NULL, replacedByEntryId, NULL, NULL
+	FROM expired_entries
+	WHERE expiredAtUtc < ?
+) outcomes
+ORDER BY atUtc, entryId
+LIMIT ?;`, before, before, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanOutcomes(rows)
+}
+
+func scanOutcomes(rows *sql.Rows) ([]sendkey.EntryOutcome, error) {
+	defer rows.Close()
+
+	var (
+		err          error
+		entryID      mysqlUUID
+		sentByUserID mysqlUUID
+		replaces     mysqlUUID
+		replacedBy   mysqlUUID
+		ack          sql.NullString
+		ackAt        sql.NullTime
+
+		result = []sendkey.EntryOutcome{}
+	)
+	for rows.Next() {
+		var o sendkey.EntryOutcome
+		if err = rows.Scan(&entryID, &o.Name, &sentByUserID, &o.SentToEmail, &o.Outcome, &o.AtUTC, &replaces, &replacedBy, &ack, &ackAt); err != nil {
+			return nil, err
+		}
+		o.EntryID = entryID.UUID()
+		o.SentByUserID = sentByUserID.UUID()
+		o.ReplacesEntryID, o.ReplacedByEntryID = replaces.optional(), replacedBy.optional()
+		o.Acknowledgment = ack.String
+		if ackAt.Valid {
+			at := ackAt.Time
+			o.AcknowledgedAtUTC = &at
+		}
+		result = append(result, o)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// DeleteOutcomes deletes the claimed and expired entries in one transaction.
+// Claimed entries' receipts are deleted with them.
+func (s *entryStore) DeleteOutcomes(entryIDs []uuid.UUID) (int64, error) {
+	if len(entryIDs) == 0 {
+		return 0, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(entryIDs)), ", ")
+	args := make([]interface{}, len(entryIDs))
+	for i, id := range entryIDs {
+		args[i] = mysqlUUID(id[:])
+	}
+
+	var deleted int64
+	err := inTx(s.conn, func(conn Conn) error {
+		for _, table := range []string{"claimed_entries", "expired_entries"} {
+			res, err := conn.Exec(`DELETE FROM `+table+` WHERE entryId IN (`+placeholders+`);`, args...)
+			if err != nil {
+				return err
+			}
+			n, err := res.RowsAffected()
+			if err != nil {
+				return err
+			}
+			deleted += n
+		}
+		return nil
+	})
+	return deleted, err
+}
+
+func (s *entryStore) CreateClaimReceipt(r sendkey.ClaimReceipt) error {
+	_, err := s.conn.Exec(`
+	INSERT INTO claim_receipts(entryId, valueHash, signature, claimedAtUtc)
+	VALUES (?, ?, ?, ?);`,
+		mysqlUUID(r.EntryID[:]), r.ValueHash, r.Signature, r.ClaimedAtUTC)
+	return err
+}
+
+func (s *entryStore) FindChallenges(entryID uuid.UUID) ([]sendkey.EntryChallenge, error) {
+	rows, err := s.conn.Query(`
+SELECT question, answerHash
+FROM entry_challenges
+WHERE entryId = ?
+ORDER BY position;`,
+		mysqlUUID(entryID[:]),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var (
+		question   string
+		answerHash string
+
+		result = []sendkey.EntryChallenge{}
+	)
+	for rows.Next() {
+		if err = rows.Scan(&question, &answerHash); err != nil {
+			return nil, err
+		}
+
+		result = append(result, sendkey.EntryChallenge{
+			Question:   question,
+			AnswerHash: answerHash,
+		})
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (s *entryStore) CreateDeferral(d sendkey.EntryDeferral) error {
+	_, err := s.conn.Exec(`
+	INSERT INTO entry_deferrals(id, entryId, deferredAtUtc, remindAtUtc, remindedAtUtc)
+	VALUES (?, ?, ?, ?, ?);`,
+		mysqlUUID(d.ID[:]), mysqlUUID(d.EntryID[:]), d.DeferredAtUTC, d.RemindAtUTC, d.RemindedAtUTC)
+	return err
+}
+
+func (s *entryStore) FindDeferrals(entryID uuid.UUID) ([]sendkey.EntryDeferral, error) {
+	rows, err := s.conn.Query(`
+SELECT id, entryId, deferredAtUtc, remindAtUtc, remindedAtUtc
+FROM entry_deferrals
+WHERE entryId = ?
+ORDER BY deferredAtUtc;`,
+		mysqlUUID(entryID[:]),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanDeferrals(rows)
+}
+
+func (s *entryStore) FindDueDeferrals(now time.Time, limit int) ([]sendkey.EntryDeferral, error) {
+	rows, err := s.conn.Query(`
+SELECT id, entryId, deferredAtUtc, remindAtUtc, remindedAtUtc
+FROM entry_deferrals
+WHERE remindedAtUtc IS NULL AND remindAtUtc <= ?
+ORDER BY remindAtUtc
+LIMIT ?;`,
+		now, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanDeferrals(rows)
+}
+
+func (s *entryStore) MarkDeferralReminded(id uuid.UUID, at time.Time) error {
+	_, err := s.conn.Exec(`UPDATE entry_deferrals SET remindedAtUtc = ? WHERE id = ?;`, at, mysqlUUID(id[:]))
+	return err
+}
+
+func (s *entryStore) SaveOTP(o sendkey.EntryOTP) error {
+	_, err := s.conn.Exec(`
+	INSERT INTO entry_otps(entryId, codeHash, createdAtUtc, expiresAtUtc)
+	VALUES (?, ?, ?, ?)
+	ON DUPLICATE KEY UPDATE codeHash = VALUES(codeHash), createdAtUtc = VALUES(createdAtUtc), expiresAtUtc = VALUES(expiresAtUtc);`,
+		mysqlUUID(o.EntryID[:]), o.CodeHash, o.CreatedAtUTC, o.ExpiresAtUTC)
+	return err
+}
+
+func (s *entryStore) FindOTP(entryID uuid.UUID) (*sendkey.EntryOTP, error) {
+	row := s.conn.QueryRow(`SELECT codeHash, createdAtUtc, expiresAtUtc FROM entry_otps WHERE entryId = ?;`,
+		mysqlUUID(entryID[:]))
+
+	o := &sendkey.EntryOTP{EntryID: entryID}
+	err := row.Scan(&o.CodeHash, &o.CreatedAtUTC, &o.ExpiresAtUTC)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return o, nil
+}
+
+func (s *entryStore) CreateDelegation(d sendkey.EntryDelegation) error {
+	_, err := s.conn.Exec(`
+	INSERT INTO entry_delegations(id, entryId, createdByUserId, wrappedKey, keyNonce, createdAtUtc, expiresAtUtc)
+	VALUES (?, ?, ?, ?, ?, ?, ?);`,
+		mysqlUUID(d.ID[:]), mysqlUUID(d.EntryID[:]), mysqlUUID(d.CreatedByUserID[:]), string(d.WrappedKey), string(d.KeyNonce), d.CreatedAtUTC, d.ExpiresAtUTC)
 	return err
 }
+
+func (s *entryStore) FindDelegation(id uuid.UUID) (*sendkey.EntryDelegation, error) {
+	row := s.conn.QueryRow(`
+	SELECT entryId, createdByUserId, wrappedKey, keyNonce, createdAtUtc, expiresAtUtc
+	FROM entry_delegations WHERE id = ?;`,
+		mysqlUUID(id[:]))
+	var (
+		entryID         mysqlUUID
+		createdByUserID mysqlUUID
+		wrappedKey      string
+		keyNonce        string
+		d               = &sendkey.EntryDelegation{ID: id}
+	)
+
+	err := row.Scan(&entryID, &createdByUserID, &wrappedKey, &keyNonce, &d.CreatedAtUTC, &d.ExpiresAtUTC)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	d.EntryID, d.CreatedByUserID = entryID.UUID(), createdByUserID.UUID()
+	d.WrappedKey, d.KeyNonce = []byte(wrappedKey), []byte(keyNonce)
+	return d, nil
+}
+
+func (s *entryStore) CreateClaimLink(l sendkey.ClaimLink) error {
+	return inTx(s.conn, func(conn Conn) error {
+		_, err := conn.Exec(`
+		UPDATE claim_links SET status = ?, supersededAtUtc = ?
+		WHERE entryId = ? AND status = ?;`,
+			sendkey.ClaimLinkSuperseded, l.CreatedAtUTC, mysqlUUID(l.EntryID[:]), sendkey.ClaimLinkActive)
+		if err != nil {
+			return err
+		}
+
+		_, err = conn.Exec(`
+		INSERT INTO claim_links(id, entryId, nonce, status, createdAtUtc, expiresAtUtc)
+		VALUES (?, ?, ?, ?, ?, ?);`,
+			mysqlUUID(l.ID[:]), mysqlUUID(l.EntryID[:]), string(l.Nonce), sendkey.ClaimLinkActive, l.CreatedAtUTC, l.ExpiresAtUTC)
+		return err
+	})
+}
+
+func (s *entryStore) FindClaimLinks(entryID uuid.UUID) ([]sendkey.ClaimLink, error) {
+	rows, err := s.conn.Query(`
+	SELECT id, nonce, status, createdAtUtc, expiresAtUtc, supersededAtUtc
+	FROM claim_links WHERE entryId = ?
+	ORDER BY status = ? DESC, createdAtUtc DESC;`,
+		mysqlUUID(entryID[:]), sendkey.ClaimLinkActive)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var links []sendkey.ClaimLink
+	for rows.Next() {
+		var (
+			id           mysqlUUID
+			nonce        string
+			supersededAt sql.NullTime
+			l            = sendkey.ClaimLink{EntryID: entryID}
+		)
+		if err = rows.Scan(&id, &nonce, &l.Status, &l.CreatedAtUTC, &l.ExpiresAtUTC, &supersededAt); err != nil {
+			return nil, err
+		}
+		l.ID, l.Nonce = id.UUID(), []byte(nonce)
+		if supersededAt.Valid {
+			l.SupersededAtUTC = &supersededAt.Time
+		}
+		links = append(links, l)
+	}
+	return links, rows.Err()
+}
+
+// CreateWithEscrow inserts the entry and its escrow in one transaction, so
+// neither is left without the other.
+func (s *entryStore) CreateWithEscrow(e sendkey.Entry, escrow sendkey.EntryEscrow) error {
+	return inTx(s.conn, func(conn Conn) error {
+		if err := createEntry(conn, e); err != nil {
+			return err
+		}
+		return createEscrow(conn, escrow)
+	})
+}
+
+func createEscrow(conn Conn, e sendkey.EntryEscrow) error {
+	_, err := conn.Exec(`
+	INSERT INTO entry_escrows(entryId, sentByUserId, sentToEmail, keyId, wrappedKey, nonce, ciphertext, createdAtUtc, purgeAtUtc)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?);`,
+		mysqlUUID(e.EntryID[:]), mysqlUUID(e.SentByUserID[:]), e.SentToEmail, e.KeyID, string(e.WrappedKey), string(e.Nonce), string(e.Ciphertext), e.CreatedAtUTC, e.PurgeAtUTC)
+	return err
+}
+
+func (s *entryStore) FindEscrow(entryID uuid.UUID) (*sendkey.EntryEscrow, error) {
+	row := s.conn.QueryRow(`
+	SELECT sentByUserId, sentToEmail, keyId, wrappedKey, nonce, ciphertext, createdAtUtc, claimedAtUtc, purgeAtUtc
+	FROM entry_escrows WHERE entryId = ?;`,
+		mysqlUUID(entryID[:]))
+	var (
+		sentByUserID mysqlUUID
+		wrappedKey   string
+		nonce        string
+		ciphertext   string
+		claimedAtUtc sql.NullTime
+		e            = &sendkey.EntryEscrow{EntryID: entryID}
+	)
+
+	err := row.Scan(&sentByUserID, &e.SentToEmail, &e.KeyID, &wrappedKey, &nonce, &ciphertext, &e.CreatedAtUTC, &claimedAtUtc, &e.PurgeAtUTC)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	e.SentByUserID = sentByUserID.UUID()
+	e.WrappedKey, e.Nonce, e.Ciphertext = []byte(wrappedKey), []byte(nonce), []byte(ciphertext)
+	if claimedAtUtc.Valid {
+		e.ClaimedAtUTC = &claimedAtUtc.Time
+	}
+	return e, nil
+}
+
+func (s *entryStore) RetainEscrow(entryID uuid.UUID, claimedAt, purgeAt time.Time) error {
+	_, err := s.conn.Exec(`UPDATE entry_escrows SET claimedAtUtc = ?, purgeAtUtc = ? WHERE entryId = ?;`,
+		claimedAt, purgeAt, mysqlUUID(entryID[:]))
+	return err
+}
+
+func (s *entryStore) DeleteEscrow(entryID uuid.UUID) error {
+	_, err := s.conn.Exec(`DELETE FROM entry_escrows WHERE entryId = ?;`, mysqlUUID(entryID[:]))
+	return err
+}
+
+func (s *entryStore) PurgeEscrows(before time.Time) (int64, error) {
+	res, err := s.conn.Exec(`DELETE FROM entry_escrows WHERE purgeAtUtc < ?;`, before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// SaveFlags sets the user's flags on the entry. Clearing them all deletes
+// the row, so the table only holds entries someone has flagged.
+func (s *entryStore) SaveFlags(userID, entryID uuid.UUID, f sendkey.EntryFlags) error {
+	if f == (sendkey.EntryFlags{}) {
+		_, err := s.conn.Exec(`DELETE FROM entry_flags WHERE userId = ? AND entryId = ?;`,
+			mysqlUUID(userID[:]), mysqlUUID(entryID[:]))
+		return err
+	}
+
+	_, err := s.conn.Exec(`
+	INSERT INTO entry_flags(userId, entryId, pinned, favorite, updatedAtUtc)
+	VALUES (?, ?, ?, ?, ?)
+	ON DUPLICATE KEY UPDATE pinned = VALUES(pinned), favorite = VALUES(favorite), updatedAtUtc = VALUES(updatedAtUtc);`,
+		mysqlUUID(userID[:]), mysqlUUID(entryID[:]), mysqlBool(f.Pinned), mysqlBool(f.Favorite), time.Now().UTC())
+	return err
+}
+
+func (s *entryStore) FindFlags(userID uuid.UUID) (map[uuid.UUID]sendkey.EntryFlags, error) {
+	rows, err := s.conn.Query(`
+SELECT entryId, pinned, favorite
+FROM entry_flags
+WHERE userId = ?;`,
+		mysqlUUID(userID[:]),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var (
+		entryID  mysqlUUID
+		pinned   mysqlBool
+		favorite mysqlBool
+		result   = map[uuid.UUID]sendkey.EntryFlags{}
+	)
+	for rows.Next() {
+		if err = rows.Scan(&entryID, &pinned, &favorite); err != nil {
+			return nil, err
+		}
+		result[entryID.UUID()] = sendkey.EntryFlags{Pinned: bool(pinned), Favorite: bool(favorite)}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func scanDeferrals(rows *sql.Rows) ([]sendkey.EntryDeferral, error) {
+	var (
+		id            mysqlUUID
+		entryID       mysqlUUID
+		deferredAtUtc time.Time
+		remindAtUtc   time.Time
+		remindedAtUtc sql.NullTime
+
+		result = []sendkey.EntryDeferral{}
+	)
+	for rows.Next() {
+		if err := rows.Scan(&id, &entryID, &deferredAtUtc, &remindAtUtc, &remindedAtUtc); err != nil {
+			return nil, err
+		}
+
+		d := sendkey.EntryDeferral{
+			ID:            id.UUID(),
+			EntryID:       entryID.UUID(),
+			DeferredAtUTC: deferredAtUtc,
+			RemindAtUTC:   remindAtUtc,
+		}
+		if remindedAtUtc.Valid {
+			remindedAt := remindedAtUtc.Time
+			d.RemindedAtUTC = &remindedAt
+		}
+		result = append(result, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (s *entryStore) SuppressRecipient(email string, at time.Time) error {
+	// opting out again keeps the time of the first
+	_, err := s.conn.Exec(`INSERT IGNORE INTO recipient_suppressions(email, createdAtUtc) VALUES (?, ?);`, email, at)
+	return err
+}
+
+func (s *entryStore) UnsuppressRecipient(email string) (bool, error) {
+	res, err := s.conn.Exec(`DELETE FROM recipient_suppressions WHERE email = ?;`, email)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (s *entryStore) RecipientSuppressed(email string) (bool, error) {
+	var n int
+	err := s.conn.QueryRow(`SELECT COUNT(*) FROM recipient_suppressions WHERE email = ?;`, email).Scan(&n)
+	return n > 0, err
+}
@@ -0,0 +1,95 @@
+package mysql
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+const (
+	// readRetries is how many times a read that lost its connection is
+	// retried, waiting readRetryDelay before the first retry and twice as
+	// long before each one after. A failover that takes longer than that
+	// fails the request instead of holding it.
+	readRetries    = 2
+	readRetryDelay = 100 * time.Millisecond
+)
+
+// MySQL errors that mean the server can't take writes: it's read-only, like a
+// demoted primary during a failover, or it's shutting down.
+const (
+	errOptionPreventsStatement = 1290
+	errServerShutdown          = 1053
+	errReadOnlyMode            = 1836
+	errReadOnlyTransaction     = 1792
+	errConnectionKilled        = 1927
+)
+
+// failoverConn is the stores' Conn outside a transaction. Reads that lose
+// their connection, as happens to every pooled connection when MySQL fails
+// over, are retried, and the pool replaces the dead connections. Writes
+// aren't retried, since a write that lost its connection may have been
+// committed; see Unavailable.
+type failoverConn struct {
+	db *sql.DB
+}
+
+func (c *failoverConn) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return c.db.Exec(query, args...)
+}
+
+func (c *failoverConn) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	rows, err := c.db.Query(query, args...)
+	for i, delay := 0, readRetryDelay; i < readRetries && connLost(err); i, delay = i+1, delay*2 {
+		time.Sleep(delay)
+		rows, err = c.db.Query(query, args...)
+	}
+	return rows, err
+}
+
+func (c *failoverConn) QueryRow(query string, args ...interface{}) *sql.Row {
+	row := c.db.QueryRow(query, args...)
+	for i, delay := 0, readRetryDelay; i < readRetries && connLost(row.Err()); i, delay = i+1, delay*2 {
+		time.Sleep(delay)
+		row = c.db.QueryRow(query, args...)
+	}
+	return row
+}
+
+// connLost reports whether the error is from a connection that was lost or
+// couldn't be made.
+func connLost(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, mysql.ErrInvalidConn) || errors.Is(err, sql.ErrConnDone) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// Unavailable reports whether the error means the database can't be reached
+// or can't take writes right now, e.g. during a failover, rather than that
+// the query failed. The request can be tried again shortly.
+func Unavailable(err error) bool {
+	if connLost(err) {
+		return true
+	}
+
+	var myErr *mysql.MySQLError
+	if !errors.As(err, &myErr) {
+		return false
+	}
+	switch myErr.Number {
+	case errOptionPreventsStatement, errServerShutdown, errReadOnlyMode, errReadOnlyTransaction, errConnectionKilled:
+		return true
+	}
+	return false
+}
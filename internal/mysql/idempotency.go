@@ -0,0 +1,78 @@
+package mysql
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/gavinwade12/sendkey"
+)
+
+type idempotencyStore struct {
+	conn Conn
+}
+
+// Reserve stores the record for a request that's starting, unless its key is
+// already stored and hasn't expired, returning whether it was stored.
+func (s *idempotencyStore) Reserve(rec sendkey.IdempotencyRecord) (bool, error) {
+	var reserved bool
+	err := inTx(s.conn, func(conn Conn) error {
+		_, err := conn.Exec(`DELETE FROM idempotency_keys WHERE keyHash = ? AND expiresAtUtc <= ?;`,
+			rec.KeyHash, rec.CreatedAtUTC)
+		if err != nil {
+			return err
+		}
+
+		res, err := conn.Exec(`
+		INSERT IGNORE INTO idempotency_keys(keyHash, requestHash, statusCode, createdAtUtc, expiresAtUtc)
+		VALUES (?, ?, 0, ?, ?);`,
+			rec.KeyHash, rec.RequestHash, rec.CreatedAtUTC, rec.ExpiresAtUTC)
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		reserved = n == 1
+		return err
+	})
+	return reserved, err
+}
+
+func (s *idempotencyStore) Find(keyHash string) (*sendkey.IdempotencyRecord, error) {
+	row := s.conn.QueryRow(`
+	SELECT requestHash, statusCode, response, createdAtUtc, expiresAtUtc
+	FROM idempotency_keys WHERE keyHash = ?;`,
+		keyHash)
+	var (
+		response sql.NullString
+		rec      = &sendkey.IdempotencyRecord{KeyHash: keyHash}
+	)
+
+	err := row.Scan(&rec.RequestHash, &rec.StatusCode, &response, &rec.CreatedAtUTC, &rec.ExpiresAtUTC)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rec.Response = []byte(response.String)
+	return rec, nil
+}
+
+func (s *idempotencyStore) Complete(keyHash string, statusCode int, response []byte) error {
+	_, err := s.conn.Exec(`UPDATE idempotency_keys SET statusCode = ?, response = ? WHERE keyHash = ?;`,
+		statusCode, string(response), keyHash)
+	return err
+}
+
+func (s *idempotencyStore) Delete(keyHash string) error {
+	_, err := s.conn.Exec(`DELETE FROM idempotency_keys WHERE keyHash = ?;`, keyHash)
+	return err
+}
+
+func (s *idempotencyStore) Purge(before time.Time) (int64, error) {
+	res, err := s.conn.Exec(`DELETE FROM idempotency_keys WHERE expiresAtUtc < ?;`, before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
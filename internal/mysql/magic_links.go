@@ -0,0 +1,56 @@
+package mysql
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/gavinwade12/sendkey"
+	"github.com/google/uuid"
+)
+
+type magicLinkStore struct {
+	conn Conn
+}
+
+func (s *magicLinkStore) Create(l sendkey.MagicLink) error {
+	_, err := s.conn.Exec(`
+	INSERT INTO magic_links(id, userId, createdAtUtc, expiresAtUtc)
+	VALUES (?, ?, ?, ?);`,
+		mysqlUUID(l.ID[:]), mysqlUUID(l.UserID[:]), l.CreatedAtUTC, l.ExpiresAtUTC)
+	return err
+}
+
+func (s *magicLinkStore) Find(id uuid.UUID) (*sendkey.MagicLink, error) {
+	row := s.conn.QueryRow(
+		`SELECT userId, createdAtUtc, expiresAtUtc FROM magic_links WHERE id = ?;`,
+		mysqlUUID(id[:]))
+	var (
+		userId       mysqlUUID
+		createdAtUtc time.Time
+		expiresAtUtc time.Time
+	)
+
+	err := row.Scan(&userId, &createdAtUtc, &expiresAtUtc)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &sendkey.MagicLink{
+		ID:           id,
+		UserID:       userId.UUID(),
+		CreatedAtUTC: createdAtUtc,
+		ExpiresAtUTC: expiresAtUtc,
+	}, nil
+}
+
+func (s *magicLinkStore) Delete(id uuid.UUID) (bool, error) {
+	res, err := s.conn.Exec(`DELETE FROM magic_links WHERE id = ?;`, mysqlUUID(id[:]))
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
@@ -0,0 +1,13 @@
+ALTER TABLE users
+    ADD COLUMN mfaEnabled BIT NOT NULL DEFAULT b'0',
+    ADD COLUMN mfaSecret VARCHAR(64) NOT NULL DEFAULT '';
+
+CREATE TABLE recovery_codes(
+    id BINARY(16) NOT NULL,
+    userId BINARY(16) NOT NULL,
+    codeHash CHAR(64) NOT NULL,
+    createdAtUtc DATETIME NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE(userId, codeHash),
+    FOREIGN KEY (userId) REFERENCES users(id) ON DELETE CASCADE
+);
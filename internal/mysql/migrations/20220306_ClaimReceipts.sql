@@ -0,0 +1,8 @@
+CREATE TABLE claim_receipts(
+    entryId BINARY(16) NOT NULL,
+    valueHash CHAR(64) NOT NULL,
+    signature CHAR(64) NOT NULL,
+    claimedAtUtc DATETIME NOT NULL,
+    PRIMARY KEY (entryId),
+    FOREIGN KEY (entryId) REFERENCES claimed_entries(entryId) ON DELETE CASCADE
+);
@@ -0,0 +1,8 @@
+CREATE TABLE entry_challenges(
+    entryId BINARY(16) NOT NULL,
+    position INT NOT NULL,
+    question VARCHAR(255) NOT NULL,
+    answerHash VARCHAR(255) NOT NULL,
+    PRIMARY KEY (entryId, position),
+    FOREIGN KEY (entryId) REFERENCES entries(id) ON DELETE CASCADE
+);
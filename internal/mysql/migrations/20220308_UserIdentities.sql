@@ -0,0 +1,8 @@
+CREATE TABLE user_identities(
+    provider VARCHAR(50) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    userId BINARY(16) NOT NULL,
+    createdAtUtc DATETIME NOT NULL,
+    PRIMARY KEY (provider, subject),
+    FOREIGN KEY (userId) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,2 @@
+ALTER TABLE entries
+    ADD COLUMN locale VARCHAR(35) NOT NULL DEFAULT '';
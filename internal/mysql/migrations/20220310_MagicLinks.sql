@@ -0,0 +1,8 @@
+CREATE TABLE magic_links(
+    id BINARY(16) NOT NULL,
+    userId BINARY(16) NOT NULL,
+    createdAtUtc DATETIME NOT NULL,
+    expiresAtUtc DATETIME NOT NULL,
+    PRIMARY KEY (id),
+    FOREIGN KEY (userId) REFERENCES users(id) ON DELETE CASCADE
+);
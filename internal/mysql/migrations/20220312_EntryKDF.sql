@@ -0,0 +1,6 @@
+ALTER TABLE entries
+    ADD COLUMN kdf VARCHAR(16) NOT NULL DEFAULT '',
+    ADD COLUMN kdfSalt VARBINARY(32) NOT NULL DEFAULT '',
+    ADD COLUMN kdfTime INT UNSIGNED NOT NULL DEFAULT 0,
+    ADD COLUMN kdfMemoryKiB INT UNSIGNED NOT NULL DEFAULT 0,
+    ADD COLUMN kdfThreads TINYINT UNSIGNED NOT NULL DEFAULT 0;
@@ -0,0 +1,3 @@
+ALTER TABLE entries
+    ADD COLUMN keyVersion INT NOT NULL DEFAULT 0,
+    ADD INDEX (keyVersion);
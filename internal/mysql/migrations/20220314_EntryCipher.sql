@@ -0,0 +1,3 @@
+ALTER TABLE entries
+    MODIFY nonce VARBINARY(24) NOT NULL,
+    ADD COLUMN cipher VARCHAR(32) NOT NULL DEFAULT '';
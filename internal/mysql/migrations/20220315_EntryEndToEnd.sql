@@ -0,0 +1,2 @@
+ALTER TABLE entries
+    ADD COLUMN endToEnd BIT NOT NULL DEFAULT b'0';
@@ -0,0 +1,4 @@
+ALTER TABLE entries
+    ADD COLUMN type VARCHAR(16) NOT NULL DEFAULT '',
+    ADD COLUMN username VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN hostname VARCHAR(255) NOT NULL DEFAULT '';
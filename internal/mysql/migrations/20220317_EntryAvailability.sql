@@ -0,0 +1,2 @@
+ALTER TABLE entries
+    ADD COLUMN availableAtUtc DATETIME NULL;
@@ -0,0 +1,2 @@
+ALTER TABLE entries
+    ADD COLUMN requireLogin BIT NOT NULL DEFAULT b'0';
@@ -0,0 +1,10 @@
+CREATE TABLE entry_deferrals(
+    id BINARY(16) NOT NULL,
+    entryId BINARY(16) NOT NULL,
+    deferredAtUtc DATETIME NOT NULL,
+    remindAtUtc DATETIME NOT NULL,
+    remindedAtUtc DATETIME NULL,
+    PRIMARY KEY (id),
+    INDEX (remindAtUtc),
+    FOREIGN KEY (entryId) REFERENCES entries(id) ON DELETE CASCADE
+);
@@ -0,0 +1,2 @@
+ALTER TABLE expired_entries
+    ADD COLUMN revoked BIT NOT NULL DEFAULT b'0';
@@ -0,0 +1,11 @@
+ALTER TABLE entries
+    ADD COLUMN requireOtp BIT NOT NULL DEFAULT b'0';
+
+CREATE TABLE entry_otps(
+    entryId BINARY(16) NOT NULL,
+    codeHash CHAR(64) NOT NULL,
+    createdAtUtc DATETIME NOT NULL,
+    expiresAtUtc DATETIME NOT NULL,
+    PRIMARY KEY (entryId),
+    FOREIGN KEY (entryId) REFERENCES entries(id) ON DELETE CASCADE
+);
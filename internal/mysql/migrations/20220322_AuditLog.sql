@@ -0,0 +1,17 @@
+CREATE TABLE audit_events(
+    seq BIGINT NOT NULL,
+    `type` VARCHAR(50) NOT NULL,
+    `data` TEXT NOT NULL,
+    atUtc DATETIME NOT NULL,
+    prevHash CHAR(64) NOT NULL,
+    hash CHAR(64) NOT NULL,
+    PRIMARY KEY (seq)
+);
+
+CREATE TABLE audit_anchors(
+    seq BIGINT NOT NULL,
+    hash CHAR(64) NOT NULL,
+    signature CHAR(64) NOT NULL,
+    createdAtUtc DATETIME NOT NULL,
+    PRIMARY KEY (seq)
+);
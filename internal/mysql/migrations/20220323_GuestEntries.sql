@@ -0,0 +1,8 @@
+ALTER TABLE entries
+    MODIFY COLUMN sentByUserId BINARY(16) NULL;
+
+ALTER TABLE claimed_entries
+    MODIFY COLUMN sentByUserId BINARY(16) NULL;
+
+ALTER TABLE expired_entries
+    MODIFY COLUMN sentByUserId BINARY(16) NULL;
@@ -0,0 +1,12 @@
+CREATE TABLE audit_head(
+    id TINYINT NOT NULL,
+    seq BIGINT NOT NULL,
+    hash CHAR(64) NOT NULL,
+    PRIMARY KEY (id)
+);
+
+INSERT INTO audit_head(id, seq, hash)
+SELECT 1, seq, hash FROM audit_events ORDER BY seq DESC LIMIT 1;
+
+INSERT IGNORE INTO audit_head(id, seq, hash)
+VALUES (1, 0, REPEAT('0', 64));
@@ -0,0 +1,4 @@
+ALTER TABLE entries
+    ADD COLUMN digestAlgorithm VARCHAR(32) NOT NULL DEFAULT '',
+    ADD COLUMN digestSalt VARBINARY(32) NOT NULL DEFAULT '',
+    ADD COLUMN digestMac VARBINARY(64) NOT NULL DEFAULT '';
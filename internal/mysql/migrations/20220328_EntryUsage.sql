@@ -0,0 +1,7 @@
+CREATE TABLE entry_usage(
+    userId BINARY(16) NOT NULL,
+    day DATE NOT NULL,
+    entriesCreated INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (userId, day),
+    FOREIGN KEY (userId) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,11 @@
+CREATE TABLE anonymous_senders(
+    id BINARY(16) NOT NULL PRIMARY KEY,
+    addressHash CHAR(64) NOT NULL UNIQUE,
+    entriesCreated INT NOT NULL DEFAULT 0,
+    firstSeenAtUtc DATETIME NOT NULL,
+    lastSeenAtUtc DATETIME NOT NULL
+);
+
+ALTER TABLE entries
+    ADD COLUMN anonymousSenderId BINARY(16) NULL,
+    ADD FOREIGN KEY (anonymousSenderId) REFERENCES anonymous_senders(id) ON DELETE SET NULL;
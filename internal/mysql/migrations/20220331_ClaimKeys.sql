@@ -0,0 +1,2 @@
+ALTER TABLE claimed_entries
+    ADD COLUMN claimKeyHash CHAR(64) NULL;
@@ -0,0 +1,12 @@
+CREATE TABLE entry_delegations(
+    id BINARY(16) NOT NULL,
+    entryId BINARY(16) NOT NULL,
+    createdByUserId BINARY(16) NOT NULL,
+    wrappedKey VARBINARY(128) NOT NULL,
+    keyNonce VARBINARY(32) NOT NULL,
+    createdAtUtc DATETIME NOT NULL,
+    expiresAtUtc DATETIME NOT NULL,
+    PRIMARY KEY (id),
+    FOREIGN KEY (entryId) REFERENCES entries(id) ON DELETE CASCADE,
+    FOREIGN KEY (createdByUserId) REFERENCES users(id) ON DELETE CASCADE
+);
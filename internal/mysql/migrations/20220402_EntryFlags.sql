@@ -0,0 +1,10 @@
+CREATE TABLE entry_flags(
+    userId BINARY(16) NOT NULL,
+    entryId BINARY(16) NOT NULL,
+    pinned BOOL NOT NULL DEFAULT FALSE,
+    favorite BOOL NOT NULL DEFAULT FALSE,
+    updatedAtUtc DATETIME NOT NULL,
+    PRIMARY KEY (userId, entryId),
+    FOREIGN KEY (userId) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (entryId) REFERENCES entries(id) ON DELETE CASCADE
+);
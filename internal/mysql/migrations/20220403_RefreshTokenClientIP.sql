@@ -0,0 +1,2 @@
+ALTER TABLE refresh_tokens
+    ADD COLUMN clientIp VARCHAR(45) NOT NULL DEFAULT '';
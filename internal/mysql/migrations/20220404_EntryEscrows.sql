@@ -0,0 +1,14 @@
+CREATE TABLE entry_escrows(
+    entryId BINARY(16) NOT NULL,
+    sentByUserId BINARY(16) NOT NULL,
+    sentToEmail VARCHAR(100) NOT NULL,
+    keyId CHAR(64) NOT NULL,
+    wrappedKey VARBINARY(1024) NOT NULL,
+    nonce VARBINARY(32) NOT NULL,
+    ciphertext MEDIUMBLOB NOT NULL,
+    createdAtUtc DATETIME NOT NULL,
+    claimedAtUtc DATETIME NULL,
+    purgeAtUtc DATETIME NOT NULL,
+    PRIMARY KEY (entryId),
+    INDEX (purgeAtUtc)
+);
@@ -0,0 +1,5 @@
+ALTER TABLE claimed_entries
+    ADD INDEX (claimedAtUtc);
+
+ALTER TABLE expired_entries
+    ADD INDEX (expiredAtUtc);
@@ -0,0 +1,5 @@
+CREATE TABLE recipient_suppressions(
+    email VARCHAR(100) NOT NULL,
+    createdAtUtc DATETIME NOT NULL,
+    PRIMARY KEY (email)
+);
@@ -0,0 +1,12 @@
+CREATE TABLE claim_links(
+    id BINARY(16) NOT NULL,
+    entryId BINARY(16) NOT NULL,
+    nonce VARBINARY(32) NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    createdAtUtc DATETIME NOT NULL,
+    expiresAtUtc DATETIME NOT NULL,
+    supersededAtUtc DATETIME NULL,
+    PRIMARY KEY (id),
+    INDEX (entryId, createdAtUtc),
+    FOREIGN KEY (entryId) REFERENCES entries(id) ON DELETE CASCADE
+);
@@ -0,0 +1,10 @@
+CREATE TABLE idempotency_keys(
+    keyHash CHAR(64) NOT NULL,
+    requestHash CHAR(64) NOT NULL,
+    statusCode INT NOT NULL,
+    response MEDIUMBLOB NULL,
+    createdAtUtc DATETIME NOT NULL,
+    expiresAtUtc DATETIME NOT NULL,
+    PRIMARY KEY (keyHash),
+    INDEX (expiresAtUtc)
+);
@@ -0,0 +1,8 @@
+ALTER TABLE entries
+    ADD COLUMN replacesEntryId BINARY(16) NULL;
+
+ALTER TABLE claimed_entries
+    ADD COLUMN replacesEntryId BINARY(16) NULL;
+
+ALTER TABLE expired_entries
+    ADD COLUMN replacedByEntryId BINARY(16) NULL;
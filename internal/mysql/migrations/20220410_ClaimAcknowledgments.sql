@@ -0,0 +1,6 @@
+ALTER TABLE entries
+    ADD COLUMN acknowledgment VARCHAR(500) NULL;
+
+ALTER TABLE claimed_entries
+    ADD COLUMN acknowledgment VARCHAR(500) NULL,
+    ADD COLUMN acknowledgedAtUtc DATETIME NULL;
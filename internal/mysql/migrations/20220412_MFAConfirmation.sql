@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN mfaLastStep BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,32 @@
+package mysql
+
+import (
+	"github.com/gavinwade12/sendkey"
+	"github.com/google/uuid"
+)
+
+type recoveryCodeStore struct {
+	conn Conn
+}
+
+func (s *recoveryCodeStore) Create(code sendkey.RecoveryCode) error {
+	_, err := s.conn.Exec(`
+	INSERT INTO recovery_codes(id, userId, codeHash, createdAtUtc)
+	VALUES (?, ?, ?, ?);`,
+		mysqlUUID(code.ID[:]), mysqlUUID(code.UserID[:]), code.CodeHash, code.CreatedAtUTC)
+	return err
+}
+
+func (s *recoveryCodeStore) DeleteByUserAndHash(userID uuid.UUID, codeHash string) (bool, error) {
+	res, err := s.conn.Exec(`DELETE FROM recovery_codes WHERE userId = ? AND codeHash = ?;`, mysqlUUID(userID[:]), codeHash)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (s *recoveryCodeStore) DeleteByUserID(userID uuid.UUID) error {
+	_, err := s.conn.Exec(`DELETE FROM recovery_codes WHERE userId = ?;`, mysqlUUID(userID[:]))
+	return err
+}
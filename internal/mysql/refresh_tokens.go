@@ -14,23 +14,24 @@ type refreshTokenStore struct {
 
 func (s *refreshTokenStore) Create(token sendkey.RefreshToken) error {
 	_, err := s.conn.Exec(`
-	INSERT INTO refresh_tokens(id, userId, token, createdAtUtc, expiresAtUtc)
-	VALUES (?, ?, ?, ?, ?);`,
-		mysqlUUID(string(token.ID[:])), mysqlUUID(string(token.UserID[:])), token.Token, token.CreatedAtUTC, token.ExpiresAtUTC)
+	INSERT INTO refresh_tokens(id, userId, token, createdAtUtc, expiresAtUtc, clientIp)
+	VALUES (?, ?, ?, ?, ?, ?);`,
+		mysqlUUID(string(token.ID[:])), mysqlUUID(string(token.UserID[:])), token.Token, token.CreatedAtUTC, token.ExpiresAtUTC, token.ClientIP)
 	return err
 }
 
 func (s *refreshTokenStore) FindByTokenAndUser(token string, userID uuid.UUID) (*sendkey.RefreshToken, error) {
 	row := s.conn.QueryRow(
-		`SELECT id, createdAtUtc, expiresAtUtc FROM refresh_tokens WHERE token = ? AND userId = ?`,
+		`SELECT id, createdAtUtc, expiresAtUtc, clientIp FROM refresh_tokens WHERE token = ? AND userId = ?`,
 		token, mysqlUUID(userID[:]))
 	var (
 		id           mysqlUUID
 		createdAtUtc time.Time
 		expiresAtUtc time.Time
+		clientIP     string
 	)
 
-	err := row.Scan(&id, &createdAtUtc, &expiresAtUtc)
+	err := row.Scan(&id, &createdAtUtc, &expiresAtUtc, &clientIP)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -44,6 +45,7 @@ func (s *refreshTokenStore) FindByTokenAndUser(token string, userID uuid.UUID) (
 		Token:        token,
 		CreatedAtUTC: createdAtUtc,
 		ExpiresAtUTC: expiresAtUtc,
+		ClientIP:     clientIP,
 	}, nil
 }
 
@@ -51,3 +53,8 @@ func (s *refreshTokenStore) Delete(id uuid.UUID) error {
 	_, err := s.conn.Exec(`DELETE FROM refresh_tokens WHERE id = ?;`, mysqlUUID(id[:]))
 	return err
 }
+
+func (s *refreshTokenStore) DeleteByUserID(userID uuid.UUID) error {
+	_, err := s.conn.Exec(`DELETE FROM refresh_tokens WHERE userId = ?;`, mysqlUUID(userID[:]))
+	return err
+}
@@ -0,0 +1,83 @@
+package mysql
+
+import (
+	"database/sql"
+	"strconv"
+	"time"
+)
+
+// ReplicationStatus is the database server's view of replication.
+type ReplicationStatus struct {
+	// Replica is set if the server replicates from a source.
+	Replica    bool
+	SourceHost string
+	IORunning  bool
+	SQLRunning bool
+	// Lag is how far the replica is behind its source. It's nil on a
+	// primary, or if it's unknown because replication is stopped.
+	Lag *time.Duration
+	// ReadOnly is set if the server rejects writes.
+	ReadOnly bool
+}
+
+// ReplicationStatus returns the server's replication status. Replica status
+// needs the REPLICATION CLIENT privilege.
+func (db *DB) ReplicationStatus() (*ReplicationStatus, error) {
+	s := &ReplicationStatus{}
+	if err := db.db.QueryRow(`SELECT @@global.read_only;`).Scan(&s.ReadOnly); err != nil {
+		return nil, err
+	}
+
+	// SHOW REPLICA STATUS replaced SHOW SLAVE STATUS in MySQL 8.0.22
+	rows, err := db.db.Query(`SHOW REPLICA STATUS;`)
+	if err != nil {
+		if rows, err = db.db.Query(`SHOW SLAVE STATUS;`); err != nil {
+			return nil, err
+		}
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	if !rows.Next() {
+		// a server that isn't replicating has no status row
+		return s, rows.Err()
+	}
+	values := make([]sql.RawBytes, len(cols))
+	dest := make([]interface{}, len(cols))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err = rows.Scan(dest...); err != nil {
+		return nil, err
+	}
+
+	// the columns were renamed along with the statement
+	field := func(names ...string) sql.RawBytes {
+		for i, c := range cols {
+			for _, n := range names {
+				if c == n {
+					return values[i]
+				}
+			}
+		}
+		return nil
+	}
+
+	s.Replica = true
+	s.SourceHost = string(field("Source_Host", "Master_Host"))
+	s.IORunning = string(field("Replica_IO_Running", "Slave_IO_Running")) == "Yes"
+	s.SQLRunning = string(field("Replica_SQL_Running", "Slave_SQL_Running")) == "Yes"
+	if lag := field("Seconds_Behind_Source", "Seconds_Behind_Master"); lag != nil {
+		secs, err := strconv.ParseInt(string(lag), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		d := time.Duration(secs) * time.Second
+		s.Lag = &d
+	}
+
+	return s, rows.Err()
+}
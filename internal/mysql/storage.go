@@ -0,0 +1,93 @@
+package mysql
+
+import "github.com/google/uuid"
+
+// TableSize is how much disk one of the database's tables takes.
+type TableSize struct {
+	Name string
+	// Rows is InnoDB's estimate of the table's rows.
+	Rows       int64
+	DataBytes  int64
+	IndexBytes int64
+}
+
+// SenderStorage is how much the values of a sender's live entries take.
+// Guest entries, which have no sender, are counted together under uuid.Nil.
+type SenderStorage struct {
+	UserID     uuid.UUID
+	Entries    int64
+	ValueBytes int64
+}
+
+// StorageStats is how much disk the database takes, and who's using it.
+type StorageStats struct {
+	// Tables are the database's tables, largest first.
+	Tables []TableSize
+	// ValueBytes is the size of every live entry's value.
+	ValueBytes int64
+	// Senders are the senders whose live entries take the most, most first.
+	Senders []SenderStorage
+}
+
+// TotalBytes is the size of every table's data and indexes.
+func (s *StorageStats) TotalBytes() int64 {
+	var total int64
+	for _, t := range s.Tables {
+		total += t.DataBytes + t.IndexBytes
+	}
+	return total
+}
+
+// StorageStats returns the database's table sizes and the top senders using
+// the most of it. Table sizes come from information_schema, which MySQL
+// updates now and then rather than with every write, so they lag a little.
+func (db *DB) StorageStats(top int) (*StorageStats, error) {
+	s := &StorageStats{Tables: []TableSize{}, Senders: []SenderStorage{}}
+	rows, err := db.db.Query(`
+SELECT TABLE_NAME, COALESCE(TABLE_ROWS, 0), COALESCE(DATA_LENGTH, 0), COALESCE(INDEX_LENGTH, 0)
+FROM information_schema.TABLES
+WHERE TABLE_SCHEMA = DATABASE()
+ORDER BY COALESCE(DATA_LENGTH, 0) + COALESCE(INDEX_LENGTH, 0) DESC;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var t TableSize
+		if err = rows.Scan(&t.Name, &t.Rows, &t.DataBytes, &t.IndexBytes); err != nil {
+			return nil, err
+		}
+		s.Tables = append(s.Tables, t)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	err = db.db.QueryRow(`SELECT COALESCE(SUM(LENGTH(value)), 0) FROM entries;`).Scan(&s.ValueBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	senders, err := db.db.Query(`
+SELECT sentByUserId, COUNT(*), SUM(LENGTH(value)) AS valueBytes
+FROM entries
+GROUP BY sentByUserId
+ORDER BY valueBytes DESC
+LIMIT ?;`, top)
+	if err != nil {
+		return nil, err
+	}
+	defer senders.Close()
+	for senders.Next() {
+		var (
+			id mysqlUUID
+			ss SenderStorage
+		)
+		if err = senders.Scan(&id, &ss.Entries, &ss.ValueBytes); err != nil {
+			return nil, err
+		}
+		ss.UserID = id.UUID()
+		s.Senders = append(s.Senders, ss)
+	}
+	return s, senders.Err()
+}
@@ -0,0 +1,36 @@
+package mysql
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type usageStore struct {
+	conn Conn
+}
+
+func (s *usageStore) AddEntryCreated(userID uuid.UUID, day time.Time) error {
+	_, err := s.conn.Exec(`
+	INSERT INTO entry_usage(userId, day, entriesCreated)
+	VALUES (?, ?, 1)
+	ON DUPLICATE KEY UPDATE entriesCreated = entriesCreated + 1;`,
+		mysqlUUID(userID[:]), day.Format("2006-01-02"))
+	return err
+}
+
+func (s *usageStore) EntriesCreated(userID uuid.UUID, day time.Time) (int, error) {
+	var count int
+	err := s.conn.QueryRow(`
+	SELECT COALESCE(SUM(entriesCreated), 0) FROM entry_usage WHERE userId = ? AND day = ?;`,
+		mysqlUUID(userID[:]), day.Format("2006-01-02")).Scan(&count)
+	return count, err
+}
+
+func (s *usageStore) CountLiveEntries(userID uuid.UUID, now time.Time) (int, error) {
+	var count int
+	err := s.conn.QueryRow(`
+	SELECT COUNT(*) FROM entries WHERE sentByUserId = ? AND expiresAtUtc > ?;`,
+		mysqlUUID(userID[:]), now).Scan(&count)
+	return count, err
+}
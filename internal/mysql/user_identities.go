@@ -0,0 +1,45 @@
+package mysql
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/gavinwade12/sendkey"
+)
+
+type userIdentityStore struct {
+	conn Conn
+}
+
+func (s *userIdentityStore) Create(i sendkey.UserIdentity) error {
+	_, err := s.conn.Exec(`
+	INSERT INTO user_identities(provider, subject, userId, createdAtUtc)
+	VALUES (?, ?, ?, ?);`,
+		i.Provider, i.Subject, mysqlUUID(i.UserID[:]), i.CreatedAtUTC)
+	return err
+}
+
+func (s *userIdentityStore) Find(provider, subject string) (*sendkey.UserIdentity, error) {
+	row := s.conn.QueryRow(
+		`SELECT userId, createdAtUtc FROM user_identities WHERE provider = ? AND subject = ?;`,
+		provider, subject)
+	var (
+		userId       mysqlUUID
+		createdAtUtc time.Time
+	)
+
+	err := row.Scan(&userId, &createdAtUtc)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &sendkey.UserIdentity{
+		Provider:     provider,
+		Subject:      subject,
+		UserID:       userId.UUID(),
+		CreatedAtUTC: createdAtUtc,
+	}, nil
+}
@@ -12,7 +12,7 @@ type userStore struct {
 	conn Conn
 }
 
-const userSelectFrom = `SELECT id, email, emailVerified, firstName, lastName, password, createdAtUtc FROM users`
+const userSelectFrom = `SELECT id, email, emailVerified, firstName, lastName, password, mfaEnabled, mfaSecret, createdAtUtc, deactivatedAtUtc, role, disabledAtUtc, passwordResetAtUtc FROM users`
 
 func (s *userStore) Find(id uuid.UUID) (*sendkey.User, error) {
 	row := s.conn.QueryRow(userSelectFrom+` WHERE ID = ?;`, mysqlUUID(id[:]))
@@ -26,18 +26,21 @@ func (s *userStore) FindByEmail(email string) (*sendkey.User, error) {
 
 func (s *userStore) Create(u sendkey.User) error {
 	_, err := s.conn.Exec(`
-	INSERT INTO users(id, email, emailVerified, firstName, lastName, password, createdAtUtc)
-	VALUES (?, ?, ?, ?, ?, ?, ?);`,
-		mysqlUUID(string(u.ID[:])), u.Email, mysqlBool(u.EmailVerified), u.FirstName, u.LastName, u.Password, u.CreatedAtUTC)
+	INSERT INTO users(id, email, emailVerified, firstName, lastName, password, mfaEnabled, mfaSecret, createdAtUtc, role)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`,
+		mysqlUUID(string(u.ID[:])), u.Email, mysqlBool(u.EmailVerified), u.FirstName, u.LastName, u.Password,
+		mysqlBool(u.MFAEnabled), u.MFASecret, u.CreatedAtUTC, userRole(u.Role))
 	return err
 }
 
 func (s *userStore) Update(u sendkey.User) error {
 	_, err := s.conn.Exec(`
 	UPDATE users
-	SET email = ?, emailVerified = ?, firstName = ?, lastName = ?, password = ?
+	SET email = ?, emailVerified = ?, firstName = ?, lastName = ?, password = ?, mfaEnabled = ?, mfaSecret = ?, deactivatedAtUtc = ?, role = ?,
+		disabledAtUtc = ?, passwordResetAtUtc = ?
 	WHERE id = ?;`,
-		u.Email, u.EmailVerified, u.FirstName, u.LastName, u.Password, mysqlUUID(u.ID[:]))
+		u.Email, u.EmailVerified, u.FirstName, u.LastName, u.Password, u.MFAEnabled, u.MFASecret, u.DeactivatedAtUTC, userRole(u.Role),
+		u.DisabledAtUTC, u.PasswordResetAtUTC, mysqlUUID(u.ID[:]))
 	return err
 }
 
@@ -46,7 +49,59 @@ func (s *userStore) Delete(id uuid.UUID) error {
 	return err
 }
 
-func (s *userStore) scanUser(row *sql.Row) (*sendkey.User, error) {
+// AdvanceMFAStep records the TOTP time step if it's later than the user's
+// last one, in a single statement so concurrent logins can't both use a code.
+func (s *userStore) AdvanceMFAStep(id uuid.UUID, step int64) (bool, error) {
+	res, err := s.conn.Exec(`UPDATE users SET mfaLastStep = ? WHERE id = ? AND mfaLastStep < ?;`, step, mysqlUUID(id[:]), step)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// DeleteDeactivatedBefore permanently deletes users deactivated before the time.
+func (s *userStore) DeleteDeactivatedBefore(t time.Time) (int64, error) {
+	res, err := s.conn.Exec(`DELETE FROM users WHERE deactivatedAtUtc IS NOT NULL AND deactivatedAtUtc < ?;`, t)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+// Search returns up to limit users whose email or name contains the query,
+// ordered by email, after the afterEmail cursor. An empty query matches every
+// user.
+func (s *userStore) Search(query, afterEmail string, limit int) ([]sendkey.User, error) {
+	pattern := "%" + likeEscaper.Replace(query) + "%"
+	rows, err := s.conn.Query(userSelectFrom+`
+WHERE email > ? AND (email LIKE ? ESCAPE '!' OR firstName LIKE ? ESCAPE '!' OR lastName LIKE ? ESCAPE '!')
+ORDER BY email
+LIMIT ?;`,
+		afterEmail, pattern, pattern, pattern, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []sendkey.User{}
+	for rows.Next() {
+		u, err := s.scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *u)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (s *userStore) scanUser(row interface{ Scan(...interface{}) error }) (*sendkey.User, error) {
 	var (
 		id            mysqlUUID
 		email         string
@@ -54,10 +109,16 @@ func (s *userStore) scanUser(row *sql.Row) (*sendkey.User, error) {
 		firstName     string
 		lastName      string
 		password      string
+		mfaEnabled    mysqlBool
+		mfaSecret     string
 		createdAtUtc  time.Time
+		deactivatedAt sql.NullTime
+		role          string
+		disabledAt    sql.NullTime
+		resetAt       sql.NullTime
 	)
 
-	err := row.Scan(&id, &email, &emailVerified, &firstName, &lastName, &password, &createdAtUtc)
+	err := row.Scan(&id, &email, &emailVerified, &firstName, &lastName, &password, &mfaEnabled, &mfaSecret, &createdAtUtc, &deactivatedAt, &role, &disabledAt, &resetAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -72,8 +133,28 @@ func (s *userStore) scanUser(row *sql.Row) (*sendkey.User, error) {
 		FirstName:     firstName,
 		LastName:      lastName,
 		Password:      password,
+		MFAEnabled:    bool(mfaEnabled),
+		MFASecret:     mfaSecret,
 		CreatedAtUTC:  createdAtUtc,
+		Role:          role,
+	}
+	if deactivatedAt.Valid {
+		u.DeactivatedAtUTC = &deactivatedAt.Time
+	}
+	if disabledAt.Valid {
+		u.DisabledAtUTC = &disabledAt.Time
+	}
+	if resetAt.Valid {
+		u.PasswordResetAtUTC = &resetAt.Time
 	}
 
 	return u, nil
 }
+
+// userRole defaults users created without a role to regular users.
+func userRole(role string) string {
+	if role == "" {
+		return sendkey.RoleUser
+	}
+	return role
+}
@@ -0,0 +1,58 @@
+package ratelimit
+
+import (
+	"math"
+	"time"
+)
+
+// BucketStore keeps a token bucket per key.
+type BucketStore interface {
+	// Take takes a token from the key's bucket, which refills at rate tokens
+	// per second up to burst, and returns the tokens left and, if it's
+	// empty, how long until there's one to take. A bucket starts full.
+	Take(key string, rate float64, burst int) (tokens float64, wait time.Duration, err error)
+}
+
+// TokenBucket allows a steady rate of requests per key with bursts of up to
+// Burst requests, unlike Limiter, which makes a key that hits its limit wait
+// out the whole window.
+type TokenBucket struct {
+	store BucketStore
+
+	// Rate is how many requests per second the bucket refills with.
+	Rate  float64
+	Burst int
+}
+
+// NewTokenBucket returns a bucket allowing rate requests per second per key
+// in bursts of up to burst.
+func NewTokenBucket(store BucketStore, rate float64, burst int) *TokenBucket {
+	return &TokenBucket{store, rate, burst}
+}
+
+// Quota is a key's standing with a bucket after a request.
+type Quota struct {
+	// Limit is the bucket's burst, the most requests that can be made at
+	// once.
+	Limit     int
+	Remaining int
+	// Reset is how long until the bucket has refilled.
+	Reset time.Duration
+	// Wait is how long until the request can be made, or zero if it was
+	// allowed.
+	Wait time.Duration
+}
+
+// Allow takes a token for the key if it has one, returning the key's quota.
+func (b *TokenBucket) Allow(key string) (Quota, error) {
+	tokens, wait, err := b.store.Take(key, b.Rate, b.Burst)
+	if err != nil {
+		return Quota{}, err
+	}
+	return Quota{
+		Limit:     b.Burst,
+		Remaining: int(math.Max(0, math.Floor(tokens))),
+		Reset:     time.Duration((float64(b.Burst) - tokens) / b.Rate * float64(time.Second)),
+		Wait:      wait,
+	}, nil
+}
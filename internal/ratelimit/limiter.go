@@ -0,0 +1,32 @@
+package ratelimit
+
+import (
+	"time"
+)
+
+// Limiter allows a fixed number of requests per key. The count is kept in a
+// FailureStore, so the window restarts with each request and a key that hits
+// the limit has to wait out the window since its latest one.
+type Limiter struct {
+	store FailureStore
+
+	Limit  int
+	Window time.Duration
+}
+
+// NewLimiter returns a limiter that allows limit requests per key in window.
+func NewLimiter(store FailureStore, limit int, window time.Duration) *Limiter {
+	return &Limiter{store, limit, window}
+}
+
+// Allow records a request for the key and returns how long to wait if it's
+// over the limit. A zero duration means the request can be made now. The
+// request is recorded before it's checked, so concurrent requests can't all
+// be allowed past the limit; refused requests count too.
+func (l *Limiter) Allow(key string) (time.Duration, error) {
+	count, _, err := l.store.RecordFailure(key, l.Window)
+	if err != nil || count <= l.Limit {
+		return 0, err
+	}
+	return l.Window, nil
+}
@@ -0,0 +1,131 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+type memoryRecord struct {
+	count   int
+	last    time.Time
+	expires time.Time
+}
+
+type memoryBucket struct {
+	tokens float64
+	at     time.Time
+	// full is when the bucket will have refilled, after which it's the same
+	// as not having one.
+	full time.Time
+}
+
+// sweepInterval is how often expired failures and buckets that have refilled
+// are removed. Sweeping walks every key, so it isn't done on every call.
+const sweepInterval = time.Minute
+
+// MemoryStore is a FailureStore and BucketStore for a single API instance.
+type MemoryStore struct {
+	mu      sync.Mutex
+	records map[string]*memoryRecord
+	buckets map[string]*memoryBucket
+	// recordsSwept and bucketsSwept are when the records and buckets were
+	// last swept.
+	recordsSwept time.Time
+	bucketsSwept time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: map[string]*memoryRecord{}, buckets: map[string]*memoryBucket{}}
+}
+
+func (s *MemoryStore) Failures(key string) (int, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.records[key]
+	if !ok || time.Now().After(r.expires) {
+		return 0, time.Time{}, nil
+	}
+
+	return r.count, r.last, nil
+}
+
+func (s *MemoryStore) RecordFailure(key string, window time.Duration) (int, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.recordsSwept) >= sweepInterval {
+		s.removeExpired(now)
+		s.recordsSwept = now
+	}
+
+	r, ok := s.records[key]
+	if !ok || now.After(r.expires) {
+		r = &memoryRecord{}
+		s.records[key] = r
+	}
+	previous := r.last
+	r.count++
+	r.last = now
+	r.expires = now.Add(window)
+
+	return r.count, previous, nil
+}
+
+func (s *MemoryStore) Release(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r, ok := s.records[key]; ok && r.count > 0 {
+		r.count--
+	}
+	return nil
+}
+
+func (s *MemoryStore) Reset(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.records, key)
+	return nil
+}
+
+func (s *MemoryStore) removeExpired(now time.Time) {
+	for key, r := range s.records {
+		if now.After(r.expires) {
+			delete(s.records, key)
+		}
+	}
+}
+
+func (s *MemoryStore) Take(key string, rate float64, burst int) (float64, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.bucketsSwept) >= sweepInterval {
+		for k, b := range s.buckets {
+			if now.After(b.full) {
+				delete(s.buckets, k)
+			}
+		}
+		s.bucketsSwept = now
+	}
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &memoryBucket{tokens: float64(burst), at: now}
+		s.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(burst), b.tokens+now.Sub(b.at).Seconds()*rate)
+	b.at = now
+	if b.tokens < 1 {
+		return b.tokens, time.Duration((1 - b.tokens) / rate * float64(time.Second)), nil
+	}
+
+	b.tokens--
+	b.full = now.Add(time.Duration((float64(burst) - b.tokens) / rate * float64(time.Second)))
+	return b.tokens, 0, nil
+}
@@ -0,0 +1,118 @@
+// Package ratelimit throttles repeated failures, like bad login attempts,
+// with exponential backoff and a temporary lockout, and limits how often
+// requests can be made.
+package ratelimit
+
+import (
+	"time"
+)
+
+// FailureStore counts failures per key. A key's count is forgotten once the
+// window passes without another failure.
+type FailureStore interface {
+	// Failures returns the number of failures recorded for the key and
+	// when the latest one happened.
+	Failures(key string) (count int, last time.Time, err error)
+	// RecordFailure records a failure for the key and returns the new count
+	// and when the failure before it happened, atomically, so concurrent
+	// callers each see their own count.
+	RecordFailure(key string, window time.Duration) (count int, previous time.Time, err error)
+	// Release forgets the key's latest failure, for an attempt that turned
+	// out not to be one.
+	Release(key string) error
+	// Reset forgets the key's failures.
+	Reset(key string) error
+}
+
+// Throttler applies a backoff policy to the failures in a store.
+type Throttler struct {
+	store FailureStore
+
+	// BaseDelay is the wait required after the first failure. It doubles
+	// with each consecutive failure up to MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+
+	// After LockoutThreshold failures, the key is locked out for
+	// LockoutDuration since the latest failure.
+	LockoutThreshold int
+	LockoutDuration  time.Duration
+}
+
+// NewThrottler returns a throttler with the default policy: a one second base
+// delay capped at one minute and a 15 minute lockout after 10 failures.
+func NewThrottler(store FailureStore) *Throttler {
+	return &Throttler{
+		store:            store,
+		BaseDelay:        time.Second,
+		MaxDelay:         time.Minute,
+		LockoutThreshold: 10,
+		LockoutDuration:  15 * time.Minute,
+	}
+}
+
+// Attempt reserves an attempt for all the keys and returns how long to wait
+// before one is allowed. A zero duration means the attempt can be made now.
+// The attempt is recorded as a failure before it's made, so concurrent
+// attempts can't all slip through before any of them fails; Succeed or
+// Release take it back. Attempts refused with a wait count as failures
+// too, so a key that keeps trying is only locked out sooner.
+func (t *Throttler) Attempt(keys ...string) (time.Duration, error) {
+	now := time.Now()
+
+	var wait time.Duration
+	for _, key := range keys {
+		count, previous, err := t.store.RecordFailure(key, t.LockoutDuration)
+		if err != nil {
+			return 0, err
+		}
+		if count <= 1 {
+			continue
+		}
+
+		if w := previous.Add(t.delay(count - 1)).Sub(now); w > wait {
+			wait = w
+		}
+	}
+
+	return wait, nil
+}
+
+// Succeed clears the failures for each of the keys.
+func (t *Throttler) Succeed(keys ...string) error {
+	for _, key := range keys {
+		if err := t.store.Reset(key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Release takes back the attempt reserved for each of the keys, without
+// forgiving their earlier failures.
+func (t *Throttler) Release(keys ...string) error {
+	for _, key := range keys {
+		if err := t.store.Release(key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (t *Throttler) delay(failures int) time.Duration {
+	if t.LockoutThreshold > 0 && failures >= t.LockoutThreshold {
+		return t.LockoutDuration
+	}
+
+	d := t.BaseDelay
+	for i := 1; i < failures && d < t.MaxDelay; i++ {
+		d *= 2
+	}
+	if d > t.MaxDelay {
+		d = t.MaxDelay
+	}
+
+	return d
+}
@@ -0,0 +1,74 @@
+package ratelimit
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAttemptsAreReserved(t *testing.T) {
+	th := NewThrottler(NewMemoryStore())
+
+	// concurrent attempts can't all get in before any of them fails
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		allowed int
+	)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			wait, err := th.Attempt("login:email:ada@example.com")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if wait == 0 {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if allowed != 1 {
+		t.Errorf("%d concurrent attempts were allowed, want 1", allowed)
+	}
+}
+
+func TestAttemptRelease(t *testing.T) {
+	th := NewThrottler(NewMemoryStore())
+
+	for i := 0; i < 3; i++ {
+		if wait, err := th.Attempt("ip"); err != nil || wait != 0 {
+			t.Fatalf("attempt %d: Attempt() = %v, %v, want it allowed", i, wait, err)
+		}
+		if err := th.Release("ip"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := th.Attempt("ip"); err != nil {
+		t.Fatal(err)
+	}
+	wait, err := th.Attempt("ip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wait <= 0 || wait > time.Second {
+		t.Errorf("the attempt after a failure waits %v, want up to the base delay", wait)
+	}
+}
+
+func TestLimiterReserves(t *testing.T) {
+	l := NewLimiter(NewMemoryStore(), 2, time.Minute)
+	for i := 0; i < 2; i++ {
+		if wait, err := l.Allow("guest"); err != nil || wait != 0 {
+			t.Fatalf("request %d: Allow() = %v, %v, want it allowed", i, wait, err)
+		}
+	}
+	if wait, err := l.Allow("guest"); err != nil || wait == 0 {
+		t.Errorf("Allow() = %v, %v, want the request over the limit refused", wait, err)
+	}
+}
@@ -0,0 +1,134 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// RedisStore is a FailureStore and BucketStore shared by every API instance using the same
+// Redis server.
+type RedisStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisStore returns a store that namespaces its keys with the prefix.
+func NewRedisStore(client *redis.Client, prefix string) *RedisStore {
+	return &RedisStore{client, prefix}
+}
+
+func (s *RedisStore) Failures(key string) (int, time.Time, error) {
+	vals, err := s.client.HMGet(context.Background(), s.prefix+key, "count", "last").Result()
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	if vals[0] == nil || vals[1] == nil {
+		return 0, time.Time{}, nil
+	}
+
+	count, err := strconv.Atoi(vals[0].(string))
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	last, err := strconv.ParseInt(vals[1].(string), 10, 64)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+
+	return count, time.Unix(0, last), nil
+}
+
+func (s *RedisStore) RecordFailure(key string, window time.Duration) (int, time.Time, error) {
+	ctx := context.Background()
+	key = s.prefix + key
+
+	var (
+		previous *redis.StringCmd
+		count    *redis.IntCmd
+	)
+	_, err := s.client.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		previous = p.HGet(ctx, key, "last")
+		count = p.HIncrBy(ctx, key, "count", 1)
+		p.HSet(ctx, key, "last", time.Now().UnixNano())
+		p.Expire(ctx, key, window)
+		return nil
+	})
+	// the key's first failure has no previous one
+	if err != nil && err != redis.Nil {
+		return 0, time.Time{}, err
+	}
+
+	var last time.Time
+	if previous.Val() != "" {
+		ns, err := strconv.ParseInt(previous.Val(), 10, 64)
+		if err != nil {
+			return 0, time.Time{}, err
+		}
+		last = time.Unix(0, ns)
+	}
+	return int(count.Val()), last, nil
+}
+
+// releaseScript decrements the count without creating the key if it's
+// expired since.
+var releaseScript = redis.NewScript(`
+if redis.call('HGET', KEYS[1], 'count') then
+	redis.call('HINCRBY', KEYS[1], 'count', -1)
+end
+return 0
+`)
+
+func (s *RedisStore) Release(key string) error {
+	return releaseScript.Run(context.Background(), s.client, []string{s.prefix + key}).Err()
+}
+
+func (s *RedisStore) Reset(key string) error {
+	return s.client.Del(context.Background(), s.prefix+key).Err()
+}
+
+// takeScript refills and takes from a bucket atomically, using the Redis
+// server's clock so API instances with skewed clocks agree. The tokens and
+// wait are returned as strings since Redis truncates Lua numbers to integers.
+var takeScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) + tonumber(t[2]) / 1000000
+local b = redis.call('HMGET', KEYS[1], 'tokens', 'at')
+local tokens = tonumber(b[1]) or burst
+local at = tonumber(b[2]) or now
+tokens = math.min(burst, tokens + (now - at) * rate)
+local wait = 0
+if tokens < 1 then
+	wait = (1 - tokens) / rate
+else
+	tokens = tokens - 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'at', tostring(now))
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst / rate * 1000))
+return {tostring(tokens), tostring(wait)}
+`)
+
+func (s *RedisStore) Take(key string, rate float64, burst int) (float64, time.Duration, error) {
+	res, err := takeScript.Run(context.Background(), s.client, []string{s.prefix + key}, rate, burst).StringSlice()
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(res) != 2 {
+		return 0, 0, fmt.Errorf("ratelimit: unexpected bucket reply %q", res)
+	}
+	tokens, err := strconv.ParseFloat(res[0], 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	wait, err := strconv.ParseFloat(res[1], 64)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return tokens, time.Duration(wait * float64(time.Second)), nil
+}
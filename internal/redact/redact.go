@@ -0,0 +1,111 @@
+// Package redact masks entry values, secrets, nonces, passwords, and tokens
+// in text before it's logged or recorded. It matches them by where they
+// appear, like a query parameter or JSON field, rather than by their values,
+// so nothing sensitive has to be kept around to find it.
+package redact
+
+import (
+	"io"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// Mask replaces redacted text.
+const Mask = "[REDACTED]"
+
+// sensitive are the names of fields and parameters whose values are masked,
+// in lower case.
+var sensitive = []string{
+	"value", "secret", "nonce", "password", "currentpassword", "newpassword",
+	"otp", "answer", "answers", "mfacode", "token", "accesstoken",
+	"refreshtoken", "signingkey", "key", "ciphertext",
+}
+
+var (
+	names = strings.Join(sensitive, "|")
+	// login and OAuth codes are only masked in URLs, since error codes
+	// are logged in fields named code
+	params = names + "|code"
+	// quoted matches a double-quoted string with escapes
+	quoted = `"(?:[^"\\]|\\.)*"`
+)
+
+var rules = []struct {
+	re   *regexp.Regexp
+	repl string
+}{
+	// "secret": "..." in JSON
+	{regexp.MustCompile(`(?i)("(?:` + names + `)"\s*:\s*)"(?:[^"\\]|\\.)*"`), `${1}"` + Mask + `"`},
+	// ?secret=... or &nonce=... in URLs and forms
+	{regexp.MustCompile(`(?i)([?&](?:` + params + `)=)[^&\s"']*`), "${1}" + Mask},
+	// secret=... in messages, and secret="..." in text logs
+	{regexp.MustCompile(`(?i)(\b(?:` + names + `)=)(?:` + quoted + `|[^&\s"']*)`), "${1}" + Mask},
+	// Secret:... in structs printed with %+v, quoted if they were printed
+	// with %#v or %q
+	{regexp.MustCompile(`(?i)(\b(?:` + names + `):)(?:` + quoted + `|\[[^\]]*\]|[^\s}\]]*)`), "${1}" + Mask},
+	// Authorization: Bearer ...
+	{regexp.MustCompile(`(?i)(\bbearer\s+)[A-Za-z0-9._~+/=-]+`), "${1}" + Mask},
+	// claim and magic links carry the entry's nonce or a login code
+	{regexp.MustCompile(`(/claim/|/login/magic/)[^\s/?"']+`), "${1}" + Mask},
+}
+
+// String returns s with anything that looks sensitive masked.
+func String(s string) string {
+	for _, r := range rules {
+		s = r.re.ReplaceAllString(s, r.repl)
+	}
+	return s
+}
+
+// Fields returns a copy of the fields with sensitive ones masked, for
+// structured records like audit events.
+func Fields(fields map[string]string) map[string]string {
+	result := make(map[string]string, len(fields))
+	for k, v := range fields {
+		if isSensitive(k) {
+			v = Mask
+		} else {
+			v = String(v)
+		}
+		result[k] = v
+	}
+	return result
+}
+
+// ReplaceAttr masks sensitive attributes for a slog handler's options, so
+// they're masked however the handler formats them.
+func ReplaceAttr(_ []string, a slog.Attr) slog.Attr {
+	if isSensitive(a.Key) {
+		return slog.String(a.Key, Mask)
+	}
+	return a
+}
+
+func isSensitive(name string) bool {
+	name = strings.ToLower(name)
+	for _, s := range sensitive {
+		if name == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Writer masks sensitive text written through it. Each Write is redacted on
+// its own, so it suits writers like a log.Logger's output that are given a
+// whole line at a time.
+type Writer struct {
+	w io.Writer
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w}
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(w.w, String(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
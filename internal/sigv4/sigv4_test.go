@@ -0,0 +1,62 @@
+package sigv4
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The test vectors are from AWS's Signature Version 4 test suite, and the
+// IAM example in its documentation.
+func TestSign(t *testing.T) {
+	creds := Credentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+	now := time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		method, url string
+		headers     map[string]string
+		service     string
+		want        string
+	}{
+		{
+			"get-vanilla", http.MethodGet, "https://example.amazonaws.com/", nil, "service",
+			"AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		},
+		{
+			"post-vanilla", http.MethodPost, "https://example.amazonaws.com/", nil, "service",
+			"AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=5da7c1a2acd57cee7505fc6676e4e544621c30862966e37dddb68e92efbe5d6b",
+		},
+		{
+			"iam-list-users", http.MethodGet, "https://iam.amazonaws.com/?Action=ListUsers&Version=2010-05-08",
+			map[string]string{"Content-Type": "application/x-www-form-urlencoded; charset=utf-8"}, "iam",
+			"AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/iam/aws4_request, SignedHeaders=content-type;host;x-amz-date, Signature=5d672d79c15b13162d9279b0855cfba6789a8edb4c82c400e06b5924a6f2b5d7",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, tt.url, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+
+			Sign(req, nil, tt.service, "us-east-1", creds, now)
+			if got := req.Header.Get("Authorization"); got != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSignSessionToken(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	Sign(req, nil, "service", "us-east-1", Credentials{AccessKeyID: "a", SecretAccessKey: "s", SessionToken: "t"}, time.Now())
+
+	if req.Header.Get("X-Amz-Security-Token") != "t" || !strings.Contains(req.Header.Get("Authorization"), "x-amz-security-token") {
+		t.Errorf("got headers %v, want the session token sent and signed", req.Header)
+	}
+}
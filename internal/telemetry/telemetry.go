@@ -0,0 +1,143 @@
+// Package telemetry reports anonymous usage statistics for deployments that
+// opt in. Only counts of events and features are kept, with no IDs, emails,
+// or names, and each count is reported with random noise so no single user's
+// activity can be told from a report.
+package telemetry
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// Report is what's sent to the telemetry endpoint.
+type Report struct {
+	Schema int `json:"schema"`
+	// The period is rounded to the hour so reports can't be matched to
+	// activity by their timing.
+	PeriodStartUTC time.Time `json:"periodStartUtc"`
+	PeriodEndUTC   time.Time `json:"periodEndUtc"`
+	// Epsilon is the privacy budget spent on each count. Smaller values add
+	// more noise.
+	Epsilon float64          `json:"epsilon"`
+	Counts  map[string]int64 `json:"counts"`
+}
+
+// Reporter counts usage and periodically reports it to an endpoint.
+type Reporter struct {
+	endpoint string
+	epsilon  float64
+
+	mu     sync.Mutex
+	counts map[string]int64
+	since  time.Time
+}
+
+// NewReporter returns a reporter posting to endpoint with noise scaled to
+// epsilon.
+func NewReporter(endpoint string, epsilon float64) *Reporter {
+	return &Reporter{
+		endpoint: endpoint,
+		epsilon:  epsilon,
+		counts:   map[string]int64{},
+		since:    time.Now().UTC(),
+	}
+}
+
+func (r *Reporter) Count(name string) {
+	r.mu.Lock()
+	r.counts[name]++
+	r.mu.Unlock()
+}
+
+// Report sends the counts since the last report and resets them. If it
+// fails, the counts are kept for the next report.
+func (r *Reporter) Report() error {
+	r.mu.Lock()
+	counts, since := r.counts, r.since
+	r.counts, r.since = map[string]int64{}, time.Now().UTC()
+	r.mu.Unlock()
+	if len(counts) == 0 {
+		return nil
+	}
+
+	report := Report{
+		Schema:         1,
+		PeriodStartUTC: since.Truncate(time.Hour),
+		PeriodEndUTC:   time.Now().UTC().Truncate(time.Hour),
+		Epsilon:        r.epsilon,
+		Counts:         make(map[string]int64, len(counts)),
+	}
+	for name, n := range counts {
+		noisy, err := r.noisy(n)
+		if err != nil {
+			r.restore(counts, since)
+			return err
+		}
+		report.Counts[name] = noisy
+	}
+
+	if err := r.send(report); err != nil {
+		r.restore(counts, since)
+		return err
+	}
+	return nil
+}
+
+// restore adds counts that failed to be reported back to the current ones.
+func (r *Reporter) restore(counts map[string]int64, since time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for name, n := range counts {
+		r.counts[name] += n
+	}
+	r.since = since
+}
+
+func (r *Reporter) send(report Report) error {
+	body, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+
+	res, err := httpClient.Post(r.endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("telemetry report: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("telemetry report: unexpected status %d: %s", res.StatusCode, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// noisy adds Laplace noise with a scale of 1/epsilon to the count, so any one
+// event changes the odds of a reported count by at most a factor of
+// e^epsilon. It's rounded and kept from going negative afterwards, which
+// doesn't weaken that.
+func (r *Reporter) noisy(n int64) (int64, error) {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return 0, err
+	}
+	// uniform in (-0.5, 0.5)
+	u := (float64(binary.BigEndian.Uint64(b[:])>>11)+0.5)/(1<<53) - 0.5
+	noise := -math.Copysign(1/r.epsilon, u) * math.Log(1-2*math.Abs(u))
+
+	noisy := int64(math.Round(float64(n) + noise))
+	if noisy < 0 {
+		noisy = 0
+	}
+	return noisy, nil
+}
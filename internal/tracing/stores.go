@@ -0,0 +1,391 @@
+package tracing
+
+import (
+	"time"
+
+	"github.com/gavinwade12/sendkey"
+	"github.com/gavinwade12/sendkey/internal/app"
+	"github.com/google/uuid"
+)
+
+// The store decorators below record every repository call as a child span
+// of the request's, named "<store>.<Method>" like the metrics, e.g.
+// "entrystore.Find".
+
+type UserStore struct {
+	next app.UserRepository
+	sp   *Span
+}
+
+func NewUserStore(next app.UserRepository, sp *Span) *UserStore {
+	return &UserStore{next, sp}
+}
+
+func (s *UserStore) Find(id uuid.UUID) (u *sendkey.User, err error) {
+	defer s.sp.store("userstore.Find")(&err)
+	return s.next.Find(id)
+}
+
+func (s *UserStore) FindByEmail(email string) (u *sendkey.User, err error) {
+	defer s.sp.store("userstore.FindByEmail")(&err)
+	return s.next.FindByEmail(email)
+}
+
+func (s *UserStore) Create(u sendkey.User) (err error) {
+	defer s.sp.store("userstore.Create")(&err)
+	return s.next.Create(u)
+}
+
+func (s *UserStore) Update(u sendkey.User) (err error) {
+	defer s.sp.store("userstore.Update")(&err)
+	return s.next.Update(u)
+}
+
+func (s *UserStore) Delete(id uuid.UUID) (err error) {
+	defer s.sp.store("userstore.Delete")(&err)
+	return s.next.Delete(id)
+}
+
+func (s *UserStore) DeleteDeactivatedBefore(t time.Time) (n int64, err error) {
+	defer s.sp.store("userstore.DeleteDeactivatedBefore")(&err)
+	return s.next.DeleteDeactivatedBefore(t)
+}
+
+func (s *UserStore) AdvanceMFAStep(id uuid.UUID, step int64) (advanced bool, err error) {
+	defer s.sp.store("userstore.AdvanceMFAStep")(&err)
+	return s.next.AdvanceMFAStep(id, step)
+}
+
+func (s *UserStore) Search(query, afterEmail string, limit int) (u []sendkey.User, err error) {
+	defer s.sp.store("userstore.Search")(&err)
+	return s.next.Search(query, afterEmail, limit)
+}
+
+type UserIdentityStore struct {
+	next app.UserIdentityRepository
+	sp   *Span
+}
+
+func NewUserIdentityStore(next app.UserIdentityRepository, sp *Span) *UserIdentityStore {
+	return &UserIdentityStore{next, sp}
+}
+
+func (s *UserIdentityStore) Create(i sendkey.UserIdentity) (err error) {
+	defer s.sp.store("useridentitystore.Create")(&err)
+	return s.next.Create(i)
+}
+
+func (s *UserIdentityStore) Find(provider, subject string) (i *sendkey.UserIdentity, err error) {
+	defer s.sp.store("useridentitystore.Find")(&err)
+	return s.next.Find(provider, subject)
+}
+
+type RecoveryCodeStore struct {
+	next app.RecoveryCodeRepository
+	sp   *Span
+}
+
+func NewRecoveryCodeStore(next app.RecoveryCodeRepository, sp *Span) *RecoveryCodeStore {
+	return &RecoveryCodeStore{next, sp}
+}
+
+func (s *RecoveryCodeStore) Create(c sendkey.RecoveryCode) (err error) {
+	defer s.sp.store("recoverycodestore.Create")(&err)
+	return s.next.Create(c)
+}
+
+func (s *RecoveryCodeStore) DeleteByUserAndHash(userID uuid.UUID, codeHash string) (deleted bool, err error) {
+	defer s.sp.store("recoverycodestore.DeleteByUserAndHash")(&err)
+	return s.next.DeleteByUserAndHash(userID, codeHash)
+}
+
+func (s *RecoveryCodeStore) DeleteByUserID(userID uuid.UUID) (err error) {
+	defer s.sp.store("recoverycodestore.DeleteByUserID")(&err)
+	return s.next.DeleteByUserID(userID)
+}
+
+type EntryStore struct {
+	next app.EntryRepository
+	sp   *Span
+}
+
+func NewEntryStore(next app.EntryRepository, sp *Span) *EntryStore {
+	return &EntryStore{next, sp}
+}
+
+func (s *EntryStore) Find(id uuid.UUID) (e *sendkey.Entry, err error) {
+	defer s.sp.store("entrystore.Find")(&err)
+	return s.next.Find(id)
+}
+
+func (s *EntryStore) FindByUserID(userID uuid.UUID) (e []sendkey.Entry, err error) {
+	defer s.sp.store("entrystore.FindByUserID")(&err)
+	return s.next.FindByUserID(userID)
+}
+
+func (s *EntryStore) Create(e sendkey.Entry) (err error) {
+	defer s.sp.store("entrystore.Create")(&err)
+	return s.next.Create(e)
+}
+
+func (s *EntryStore) Delete(id uuid.UUID) (err error) {
+	defer s.sp.store("entrystore.Delete")(&err)
+	return s.next.Delete(id)
+}
+
+func (s *EntryStore) IncrementInvalidAttempts(id uuid.UUID) (n int, err error) {
+	defer s.sp.store("entrystore.IncrementInvalidAttempts")(&err)
+	return s.next.IncrementInvalidAttempts(id)
+}
+
+func (s *EntryStore) CreateClaimedEntry(e sendkey.ClaimedEntry) (err error) {
+	defer s.sp.store("entrystore.CreateClaimedEntry")(&err)
+	return s.next.CreateClaimedEntry(e)
+}
+
+func (s *EntryStore) FindClaimedEntry(entryID uuid.UUID) (e *sendkey.ClaimedEntry, err error) {
+	defer s.sp.store("entrystore.FindClaimedEntry")(&err)
+	return s.next.FindClaimedEntry(entryID)
+}
+
+func (s *EntryStore) CreateExpiredEntry(e sendkey.ExpiredEntry) (err error) {
+	defer s.sp.store("entrystore.CreateExpiredEntry")(&err)
+	return s.next.CreateExpiredEntry(e)
+}
+
+func (s *EntryStore) CreateClaimReceipt(r sendkey.ClaimReceipt) (err error) {
+	defer s.sp.store("entrystore.CreateClaimReceipt")(&err)
+	return s.next.CreateClaimReceipt(r)
+}
+
+func (s *EntryStore) FindChallenges(entryID uuid.UUID) (c []sendkey.EntryChallenge, err error) {
+	defer s.sp.store("entrystore.FindChallenges")(&err)
+	return s.next.FindChallenges(entryID)
+}
+
+func (s *EntryStore) FindStaleKeyVersion(current, limit int) (e []sendkey.Entry, err error) {
+	defer s.sp.store("entrystore.FindStaleKeyVersion")(&err)
+	return s.next.FindStaleKeyVersion(current, limit)
+}
+
+func (s *EntryStore) CountByKeyVersion(version int) (n int, err error) {
+	defer s.sp.store("entrystore.CountByKeyVersion")(&err)
+	return s.next.CountByKeyVersion(version)
+}
+
+func (s *EntryStore) UpdateEncryption(id uuid.UUID, value []byte, keyVersion int) (err error) {
+	defer s.sp.store("entrystore.UpdateEncryption")(&err)
+	return s.next.UpdateEncryption(id, value, keyVersion)
+}
+
+func (s *EntryStore) FindUnexpired(now time.Time, after uuid.UUID, limit int) (e []sendkey.Entry, err error) {
+	defer s.sp.store("entrystore.FindUnexpired")(&err)
+	return s.next.FindUnexpired(now, after, limit)
+}
+
+func (s *EntryStore) CreateDeferral(d sendkey.EntryDeferral) (err error) {
+	defer s.sp.store("entrystore.CreateDeferral")(&err)
+	return s.next.CreateDeferral(d)
+}
+
+func (s *EntryStore) FindDeferrals(entryID uuid.UUID) (d []sendkey.EntryDeferral, err error) {
+	defer s.sp.store("entrystore.FindDeferrals")(&err)
+	return s.next.FindDeferrals(entryID)
+}
+
+func (s *EntryStore) FindDueDeferrals(now time.Time, limit int) (d []sendkey.EntryDeferral, err error) {
+	defer s.sp.store("entrystore.FindDueDeferrals")(&err)
+	return s.next.FindDueDeferrals(now, limit)
+}
+
+func (s *EntryStore) MarkDeferralReminded(id uuid.UUID, at time.Time) (err error) {
+	defer s.sp.store("entrystore.MarkDeferralReminded")(&err)
+	return s.next.MarkDeferralReminded(id, at)
+}
+
+func (s *EntryStore) SaveOTP(o sendkey.EntryOTP) (err error) {
+	defer s.sp.store("entrystore.SaveOTP")(&err)
+	return s.next.SaveOTP(o)
+}
+
+func (s *EntryStore) FindOTP(entryID uuid.UUID) (o *sendkey.EntryOTP, err error) {
+	defer s.sp.store("entrystore.FindOTP")(&err)
+	return s.next.FindOTP(entryID)
+}
+
+func (s *EntryStore) CreateDelegation(d sendkey.EntryDelegation) (err error) {
+	defer s.sp.store("entrystore.CreateDelegation")(&err)
+	return s.next.CreateDelegation(d)
+}
+
+func (s *EntryStore) FindDelegation(id uuid.UUID) (d *sendkey.EntryDelegation, err error) {
+	defer s.sp.store("entrystore.FindDelegation")(&err)
+	return s.next.FindDelegation(id)
+}
+
+func (s *EntryStore) CreateClaimLink(l sendkey.ClaimLink) (err error) {
+	defer s.sp.store("entrystore.CreateClaimLink")(&err)
+	return s.next.CreateClaimLink(l)
+}
+
+func (s *EntryStore) FindClaimLinks(entryID uuid.UUID) (links []sendkey.ClaimLink, err error) {
+	defer s.sp.store("entrystore.FindClaimLinks")(&err)
+	return s.next.FindClaimLinks(entryID)
+}
+
+func (s *EntryStore) CreateWithEscrow(e sendkey.Entry, escrow sendkey.EntryEscrow) (err error) {
+	defer s.sp.store("entrystore.CreateWithEscrow")(&err)
+	return s.next.CreateWithEscrow(e, escrow)
+}
+
+func (s *EntryStore) FindEscrow(entryID uuid.UUID) (e *sendkey.EntryEscrow, err error) {
+	defer s.sp.store("entrystore.FindEscrow")(&err)
+	return s.next.FindEscrow(entryID)
+}
+
+func (s *EntryStore) RetainEscrow(entryID uuid.UUID, claimedAt, purgeAt time.Time) (err error) {
+	defer s.sp.store("entrystore.RetainEscrow")(&err)
+	return s.next.RetainEscrow(entryID, claimedAt, purgeAt)
+}
+
+func (s *EntryStore) DeleteEscrow(entryID uuid.UUID) (err error) {
+	defer s.sp.store("entrystore.DeleteEscrow")(&err)
+	return s.next.DeleteEscrow(entryID)
+}
+
+func (s *EntryStore) PurgeEscrows(before time.Time) (n int64, err error) {
+	defer s.sp.store("entrystore.PurgeEscrows")(&err)
+	return s.next.PurgeEscrows(before)
+}
+
+func (s *EntryStore) FindOutcomesBefore(before time.Time, limit int) (outcomes []sendkey.EntryOutcome, err error) {
+	defer s.sp.store("entrystore.FindOutcomesBefore")(&err)
+	return s.next.FindOutcomesBefore(before, limit)
+}
+
+func (s *EntryStore) DeleteOutcomes(entryIDs []uuid.UUID) (n int64, err error) {
+	defer s.sp.store("entrystore.DeleteOutcomes")(&err)
+	return s.next.DeleteOutcomes(entryIDs)
+}
+
+func (s *EntryStore) SuppressRecipient(email string, at time.Time) (err error) {
+	defer s.sp.store("entrystore.SuppressRecipient")(&err)
+	return s.next.SuppressRecipient(email, at)
+}
+
+func (s *EntryStore) UnsuppressRecipient(email string) (ok bool, err error) {
+	defer s.sp.store("entrystore.UnsuppressRecipient")(&err)
+	return s.next.UnsuppressRecipient(email)
+}
+
+func (s *EntryStore) RecipientSuppressed(email string) (ok bool, err error) {
+	defer s.sp.store("entrystore.RecipientSuppressed")(&err)
+	return s.next.RecipientSuppressed(email)
+}
+
+func (s *EntryStore) SaveFlags(userID, entryID uuid.UUID, f sendkey.EntryFlags) (err error) {
+	defer s.sp.store("entrystore.SaveFlags")(&err)
+	return s.next.SaveFlags(userID, entryID, f)
+}
+
+func (s *EntryStore) FindFlags(userID uuid.UUID) (flags map[uuid.UUID]sendkey.EntryFlags, err error) {
+	defer s.sp.store("entrystore.FindFlags")(&err)
+	return s.next.FindFlags(userID)
+}
+
+func (s *EntryStore) Revoke(filter sendkey.EntryFilter, at time.Time) (e []sendkey.ExpiredEntry, err error) {
+	defer s.sp.store("entrystore.Revoke")(&err)
+	return s.next.Revoke(filter, at)
+}
+
+func (s *EntryStore) FindOutcomes(filter sendkey.OutcomeFilter, before time.Time, beforeID uuid.UUID, limit int) (o []sendkey.EntryOutcome, err error) {
+	defer s.sp.store("entrystore.FindOutcomes")(&err)
+	return s.next.FindOutcomes(filter, before, beforeID, limit)
+}
+
+// refreshTokenRepository matches the API's refresh token repository, which
+// isn't part of the app package.
+type refreshTokenRepository interface {
+	Create(sendkey.RefreshToken) error
+	FindByTokenAndUser(token string, userID uuid.UUID) (*sendkey.RefreshToken, error)
+	Delete(uuid.UUID) error
+	DeleteByUserID(uuid.UUID) error
+}
+
+type UsageStore struct {
+	next app.UsageRepository
+	sp   *Span
+}
+
+func NewUsageStore(next app.UsageRepository, sp *Span) *UsageStore {
+	return &UsageStore{next, sp}
+}
+
+func (s *UsageStore) AddEntryCreated(userID uuid.UUID, day time.Time) (err error) {
+	defer s.sp.store("usagestore.AddEntryCreated")(&err)
+	return s.next.AddEntryCreated(userID, day)
+}
+
+func (s *UsageStore) EntriesCreated(userID uuid.UUID, day time.Time) (n int, err error) {
+	defer s.sp.store("usagestore.EntriesCreated")(&err)
+	return s.next.EntriesCreated(userID, day)
+}
+
+func (s *UsageStore) CountLiveEntries(userID uuid.UUID, now time.Time) (n int, err error) {
+	defer s.sp.store("usagestore.CountLiveEntries")(&err)
+	return s.next.CountLiveEntries(userID, now)
+}
+
+// Mailer records every email sent as a child span of the request's.
+type Mailer struct {
+	next app.Mailer
+	sp   *Span
+}
+
+func NewMailer(next app.Mailer, sp *Span) *Mailer {
+	return &Mailer{next, sp}
+}
+
+func (m *Mailer) Send(to, subject, body string) (err error) {
+	sp := m.sp.Child("mail.Send", KindClient)
+	defer func() { sp.End(err) }()
+	return m.next.Send(to, subject, body)
+}
+
+// store starts a span for a repository call, returning a func that ends it
+// with the call's error.
+func (sp *Span) store(op string) func(*error) {
+	child := sp.Child(op, KindClient)
+	child.SetAttr("db.system", "mysql")
+	return func(err *error) {
+		child.End(*err)
+	}
+}
+
+// The methods below make a span an app.Tracer, so a service can be copied
+// for a request with its repositories and mailer traced under the request's
+// span.
+
+func (sp *Span) EntryRepository(er app.EntryRepository) app.EntryRepository {
+	return NewEntryStore(er, sp)
+}
+
+func (sp *Span) UsageRepository(ur app.UsageRepository) app.UsageRepository {
+	return NewUsageStore(ur, sp)
+}
+
+func (sp *Span) UserRepository(ur app.UserRepository) app.UserRepository {
+	return NewUserStore(ur, sp)
+}
+
+func (sp *Span) UserIdentityRepository(ir app.UserIdentityRepository) app.UserIdentityRepository {
+	return NewUserIdentityStore(ir, sp)
+}
+
+func (sp *Span) RecoveryCodeRepository(rr app.RecoveryCodeRepository) app.RecoveryCodeRepository {
+	return NewRecoveryCodeStore(rr, sp)
+}
+
+func (sp *Span) Mailer(m app.Mailer) app.Mailer {
+	return NewMailer(m, sp)
+}
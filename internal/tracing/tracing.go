@@ -0,0 +1,150 @@
+// Package tracing records spans for requests and the work done to serve them
+// with the OpenTelemetry SDK, and exports them to a collector over
+// OTLP/HTTP. Traces are continued from a W3C traceparent header, so a
+// request's spans join the trace of whatever called it.
+package tracing
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.25.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SpanKind says how a span relates to the rest of the trace.
+type SpanKind = trace.SpanKind
+
+const (
+	KindInternal = trace.SpanKindInternal
+	KindServer   = trace.SpanKindServer
+	KindClient   = trace.SpanKindClient
+)
+
+// Tracer starts spans and batches the sampled ones to an OTLP exporter.
+type Tracer struct {
+	provider   *sdktrace.TracerProvider
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+}
+
+// NewTracer returns a tracer exporting to the collector at endpoint, e.g.
+// "http://localhost:4318", every interval. It samples ratio of the traces
+// it starts, between 0 and 1; traces continued from a caller keep the
+// caller's decision. Export errors are logged to logger.
+func NewTracer(endpoint string, headers map[string]string, serviceName string, ratio float64, interval time.Duration, logger *slog.Logger) (*Tracer, error) {
+	exporter, err := otlptracehttp.New(context.Background(),
+		otlptracehttp.WithEndpointURL(endpoint+"/v1/traces"),
+		otlptracehttp.WithHeaders(headers),
+	)
+	if err != nil {
+		return nil, err
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(semconv.ServiceName(serviceName)))
+	if err != nil {
+		return nil, err
+	}
+
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		logger.Warn("tracing: export failed", "error", err)
+	}))
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter, sdktrace.WithBatchTimeout(interval)),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+		sdktrace.WithResource(res),
+	)
+	return &Tracer{
+		provider:   provider,
+		tracer:     provider.Tracer("github.com/gavinwade12/sendkey"),
+		propagator: propagation.TraceContext{},
+	}, nil
+}
+
+// Shutdown exports the spans still queued and stops exporting.
+func (t *Tracer) Shutdown() {
+	if t == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	t.provider.Shutdown(ctx)
+}
+
+// Span is an operation in a trace. A nil *Span is valid and does nothing, so
+// callers don't have to check whether tracing is enabled.
+type Span struct {
+	tracer *Tracer
+	ctx    context.Context
+	span   trace.Span
+}
+
+// Start starts a root span, or continues the trace in the request's
+// traceparent header if it has a valid one.
+func (t *Tracer) Start(name string, kind SpanKind, h http.Header) *Span {
+	if t == nil {
+		return nil
+	}
+	ctx := t.propagator.Extract(context.Background(), propagation.HeaderCarrier(h))
+	ctx, span := t.tracer.Start(ctx, name, trace.WithSpanKind(kind))
+	return &Span{t, ctx, span}
+}
+
+// Child starts a span for work done as part of this one.
+func (sp *Span) Child(name string, kind SpanKind) *Span {
+	if sp == nil {
+		return nil
+	}
+	ctx, span := sp.tracer.tracer.Start(sp.ctx, name, trace.WithSpanKind(kind))
+	return &Span{sp.tracer, ctx, span}
+}
+
+// SetAttr sets an attribute describing the span, e.g. "http.status_code".
+func (sp *Span) SetAttr(key, value string) {
+	if sp == nil {
+		return
+	}
+	sp.span.SetAttributes(attribute.String(key, value))
+}
+
+// End ends the span, marking it as failed if err isn't nil. Only the first
+// call has any effect.
+func (sp *Span) End(err error) {
+	if sp == nil || !sp.span.IsRecording() {
+		return
+	}
+	if err != nil {
+		sp.span.RecordError(err)
+		sp.span.SetStatus(codes.Error, err.Error())
+	}
+	sp.span.End()
+}
+
+// TraceID returns the ID of the span's trace, e.g. to log with the request.
+func (sp *Span) TraceID() string {
+	if sp == nil {
+		return ""
+	}
+	return sp.span.SpanContext().TraceID().String()
+}
+
+type spanCtxKey struct{}
+
+// NewContext returns a copy of ctx carrying the span.
+func NewContext(ctx context.Context, sp *Span) context.Context {
+	return context.WithValue(ctx, spanCtxKey{}, sp)
+}
+
+// FromContext returns the span ctx carries, or nil if it doesn't have one.
+func FromContext(ctx context.Context) *Span {
+	sp, _ := ctx.Value(spanCtxKey{}).(*Span)
+	return sp
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"time"
+
+	"github.com/gavinwade12/sendkey"
+	"github.com/google/uuid"
+)
+
+// RevokeEntriesRequest selects the active entries to revoke. At least one
+// filter must be set.
+type RevokeEntriesRequest struct {
+	SentByUserID    *uuid.UUID `json:"sentByUserId,omitempty"`
+	Type            string     `json:"type,omitempty"`
+	CreatedAfterUTC *time.Time `json:"createdAfterUtc,omitempty"`
+	// RecipientDomain matches entries sent to emails at the domain.
+	RecipientDomain string `json:"recipientDomain,omitempty"`
+}
+
+type RevokeEntriesResponse struct {
+	Envelope
+	Revoked int `json:"revoked"`
+	// Notified is the number of recipients emailed about their revoked
+	// entries, and NotifyFailed the number that couldn't be.
+	Notified     int `json:"notified"`
+	NotifyFailed int `json:"notifyFailed"`
+	// OptedOut is the number of recipients who weren't emailed because
+	// they opted out of receiving entries.
+	OptedOut int `json:"optedOut"`
+}
+
+// ReleaseEscrowRequest releases an entry's escrow for the break-glass
+// procedure.
+type ReleaseEscrowRequest struct {
+	// Reason is why, like a ticket number. It's recorded in the audit log.
+	Reason string `json:"reason"`
+}
+
+type ReleaseEscrowResponse struct {
+	Envelope
+	// Escrow is still encrypted to the org's escrow key; it's opened offline
+	// with the private key, e.g. by `sendkey open_escrow`.
+	Escrow *sendkey.EntryEscrow `json:"escrow,omitempty"`
+}
+
+type SetUserRoleRequest struct {
+	// Role is "user" or "admin".
+	Role string `json:"role"`
+}
+
+type SetUserRoleResponse struct {
+	Envelope
+	User *sendkey.User `json:"user"`
+}
+
+type ListUsersResponse struct {
+	Users []sendkey.User `json:"users"`
+	// Next is the after query parameter for the next page, or empty on the
+	// last page.
+	Next string `json:"next"`
+}
+
+type ListEntriesResponse struct {
+	// Entries are the live entries' metadata, never their values.
+	Entries []sendkey.Entry `json:"entries"`
+	// Next is the after query parameter for the next page, or null on the
+	// last page.
+	Next *uuid.UUID `json:"next"`
+}
+
+type EntryHistoryResponse struct {
+	Envelope
+	Outcomes []sendkey.EntryOutcome `json:"outcomes"`
+	// Next is the cursor query parameter for the next page, or empty on the
+	// last page.
+	Next string `json:"next"`
+}
+
+// AdminStatsResponse has the numbers operators keep an eye on.
+type AdminStatsResponse struct {
+	Storage StorageStats `json:"storage"`
+}
+
+// StorageStats is how much disk the database takes, and who's using it.
+// Warnings describe the storage thresholds it's past, if any are set.
+type StorageStats struct {
+	TotalBytes int64 `json:"totalBytes"`
+	// ValueBytes is the size of every live entry's value.
+	ValueBytes int64        `json:"valueBytes"`
+	Tables     []TableSize  `json:"tables"`
+	Senders    []SenderSize `json:"senders"`
+	Warnings   []string     `json:"warnings"`
+}
+
+// TableSize is how much disk one of the database's tables takes. Rows is an
+// estimate.
+type TableSize struct {
+	Name       string `json:"name"`
+	Rows       int64  `json:"rows"`
+	DataBytes  int64  `json:"dataBytes"`
+	IndexBytes int64  `json:"indexBytes"`
+}
+
+// SenderSize is how much the values of a sender's live entries take. Guest
+// entries are counted together under the nil user ID.
+type SenderSize struct {
+	UserID     uuid.UUID `json:"userId"`
+	Entries    int64     `json:"entries"`
+	ValueBytes int64     `json:"valueBytes"`
+}
@@ -0,0 +1,128 @@
+// Package api defines the JSON models exchanged with the sendkey API. The
+// server and pkg/client share them so the wire format is only defined once.
+package api
+
+import (
+	"encoding/json"
+
+	"github.com/google/uuid"
+)
+
+// BasePath is the path the API's current version is served under, so its
+// base URL is the server's URL with BasePath, e.g.
+// https://api.sendkey.me/v1. The same routes are still served at the root
+// for older clients, but they're deprecated.
+const BasePath = "/v1"
+
+// Error is the body of every response that failed for a reason other than
+// validation, e.g. a missing or invalid access token.
+type Error struct {
+	UserID     uuid.UUID `json:"userId"`
+	StatusCode int       `json:"statusCode"`
+	// Code is a stable, machine-readable identifier for the error, like
+	// "entry_not_found" or "rate_limited", for clients to branch on rather
+	// than matching the message, which can change.
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message"`
+	// RequestID identifies the request in the server's logs. Quote it when
+	// asking for support.
+	RequestID string `json:"requestId,omitempty"`
+}
+
+func (e Error) Error() string {
+	return e.Message
+}
+
+// Envelope starts every response that can fail validation. When Success is
+// false, Errors describes what was wrong with the request in the language the
+// request's Accept-Language prefers.
+type Envelope struct {
+	Success bool         `json:"success"`
+	Errors  []FieldError `json:"errors"`
+	// Codes has the code for each error in the legacy format, where Errors
+	// are only their messages; see ErrorFormatHeader.
+	Codes []string `json:"codes,omitempty"`
+	// Warnings are about the request rather than its result, e.g. that the
+	// route is deprecated. They're also sent as headers on every response;
+	// see ParseWarnings.
+	Warnings []Warning `json:"warnings,omitempty"`
+}
+
+// HasCode reports whether the request failed validation with the code, e.g.
+// "secret_invalid".
+func (e Envelope) HasCode(code string) bool {
+	for _, c := range e.ErrorCodes() {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrorCodes returns the code of each of the errors.
+func (e Envelope) ErrorCodes() []string {
+	if len(e.Codes) > 0 {
+		return e.Codes
+	}
+	var codes []string
+	for _, fe := range e.Errors {
+		codes = append(codes, fe.Code)
+	}
+	return codes
+}
+
+// Messages returns the message of each of the errors.
+func (e Envelope) Messages() []string {
+	var messages []string
+	for _, fe := range e.Errors {
+		messages = append(messages, fe.Message)
+	}
+	return messages
+}
+
+// ErrorFormatHeader is the request header that picks the format of an
+// envelope's errors. Routes under BasePath default to structured errors;
+// sending ErrorFormatLegacy gets them as a list of messages, with their codes
+// in Codes, as they were before. Routes without a version always use the
+// legacy format.
+const ErrorFormatHeader = "X-Error-Format"
+
+const ErrorFormatLegacy = "legacy"
+
+// FieldError is a problem with a request.
+type FieldError struct {
+	// Field is the request field the problem is with, by its JSON name, like
+	// "sendToEmail" or "challenges[1]". It's empty for a problem with the
+	// request as a whole.
+	Field string `json:"field,omitempty"`
+	// Code is a stable identifier for the problem, like "name_required".
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// Legacy marshals the error as just its message.
+	Legacy bool `json:"-"`
+}
+
+type fieldError FieldError
+
+func (e FieldError) MarshalJSON() ([]byte, error) {
+	if e.Legacy {
+		return json.Marshal(e.Message)
+	}
+	return json.Marshal(fieldError(e))
+}
+
+// UnmarshalJSON reads an error in either format. A legacy error only has its
+// message; its code is in the envelope's Codes.
+func (e *FieldError) UnmarshalJSON(b []byte) error {
+	if len(b) > 0 && b[0] == '"' {
+		*e = FieldError{Legacy: true}
+		return json.Unmarshal(b, &e.Message)
+	}
+	return json.Unmarshal(b, (*fieldError)(e))
+}
+
+// Token is a token, used for authentication, with a Unix time expiration date
+type Token struct {
+	Token   string `json:"token"`
+	Expires int64  `json:"expires"`
+}
@@ -0,0 +1,248 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gavinwade12/sendkey"
+	"github.com/google/uuid"
+)
+
+// CreateEntryRequest creates an entry sent by the current user. Either Value
+// or Generate and a Secret, or EndToEnd must be set, and either Duration or
+// DurationSeconds.
+type CreateEntryRequest struct {
+	Name            string         `json:"name"`
+	SendToEmail     string         `json:"sendToEmail"`
+	Value           string         `json:"value"`
+	Secret          string         `json:"secret"`
+	Duration        *EntryDuration `json:"duration,omitempty"`
+	DurationSeconds int            `json:"durationSeconds,omitempty"`
+	// AvailableAtUTC, if set, is the earliest the entry can be claimed.
+	AvailableAtUTC *time.Time   `json:"availableAtUtc,omitempty"`
+	Locale         string       `json:"locale"`
+	EndToEnd       *SealedValue `json:"endToEnd,omitempty"`
+	// Generate has the server generate the value instead, which isn't
+	// returned, so the recipient is the only one who sees it.
+	Generate *GeneratePolicy `json:"generate,omitempty"`
+	// Type is one of the sendkey.EntryType constants. It defaults to a note.
+	Type     string                `json:"type,omitempty"`
+	Metadata sendkey.EntryMetadata `json:"metadata"`
+	// RequireLogin makes the recipient log in with a verified email
+	// matching SendToEmail to claim the entry.
+	RequireLogin bool `json:"requireLogin,omitempty"`
+	// RequireOTP emails the recipient a one-time code when they claim the
+	// entry, which they must send along with the secret.
+	RequireOTP bool `json:"requireOtp,omitempty"`
+	// Acknowledgment is text the recipient must acknowledge, like "I'll
+	// store this in the team vault", to claim the entry. It's recorded with
+	// the claim, along with when they acknowledged it.
+	Acknowledgment string `json:"acknowledgment,omitempty"`
+	// Digest is a keyed digest of the value, returned to the recipient so
+	// their client can check the value arrived intact. The client sets it
+	// automatically for values it sends; see client.NewDigest.
+	Digest *sendkey.ValueDigest `json:"digest,omitempty"`
+	// ReplacesEntryID rotates one of the sender's unclaimed entries, like a
+	// credential's previous value: it's revoked when this entry is claimed.
+	ReplacesEntryID *uuid.UUID `json:"replacesEntryId,omitempty"`
+	// CaptchaToken is the CAPTCHA widget's response. Guests need it when
+	// the server requires a CAPTCHA for guest entries.
+	CaptchaToken string `json:"captchaToken,omitempty"`
+
+	Challenges []EntryChallenge `json:"challenges"`
+}
+
+// EntryDuration is how long an entry is valid, written as a duration string
+// with units like "45m" or "1h30m". A bare number is read as minutes, but
+// that form is deprecated.
+type EntryDuration struct {
+	time.Duration
+	// Minutes is set when the duration was sent as a bare number of minutes.
+	Minutes bool
+}
+
+func (d EntryDuration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.Duration.String())
+}
+
+func (d *EntryDuration) UnmarshalJSON(b []byte) error {
+	if len(b) > 0 && b[0] != '"' {
+		minutes, err := strconv.Atoi(string(bytes.TrimSpace(b)))
+		if err != nil {
+			return fmt.Errorf(`duration must be a string with units, like "45m"`)
+		}
+		*d = EntryDuration{time.Duration(minutes) * time.Minute, true}
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf(`invalid duration %q; use units like "45m" or "1h30m"`, s)
+	}
+
+	*d = EntryDuration{Duration: v}
+	return nil
+}
+
+// EntryChallenge is a question the recipient must answer before claiming
+// the entry.
+type EntryChallenge struct {
+	Question string `json:"question"`
+	Answer   string `json:"answer"`
+}
+
+// CreateEntryResponse has the created entry and the signed link the
+// recipient opens to claim it. Warnings, like a possible duplicate, didn't
+// stop the entry from being created.
+type CreateEntryResponse struct {
+	Envelope
+	Warnings []string       `json:"warnings,omitempty"`
+	Entry    *sendkey.Entry `json:"entry"`
+	ClaimURL string         `json:"claimUrl,omitempty"`
+	// Link is the claim link's status and expiration, if the server's
+	// links expire before their entries.
+	Link *sendkey.ClaimLink `json:"link,omitempty"`
+}
+
+// ClaimEntryResponse holds the claimed value. End-to-end entries have a
+// Sealed value for the client to decrypt instead. OTPSent is set when the
+// entry requires a one-time code and one was emailed to the recipient; claim
+// again with it.
+type ClaimEntryResponse struct {
+	Envelope
+	Value   *string               `json:"value"`
+	Sealed  *SealedValue          `json:"sealed,omitempty"`
+	Receipt *sendkey.ClaimReceipt `json:"receipt"`
+	OTPSent bool                  `json:"otpSent,omitempty"`
+	// Digest is the sender's digest of the value, if they sent one.
+	Digest *sendkey.ValueDigest `json:"digest,omitempty"`
+}
+
+// DeferClaimRequest puts off claiming an entry. The recipient is reminded
+// after DelaySeconds, which defaults to a day.
+type DeferClaimRequest struct {
+	Nonce        string `json:"nonce"`
+	DelaySeconds int    `json:"delaySeconds,omitempty"`
+}
+
+type DeferClaimResponse struct {
+	Envelope
+	Deferral *sendkey.EntryDeferral `json:"deferral"`
+}
+
+// ResendLinkRequest issues a new claim link for an entry, which stops its
+// earlier links from working. The link lasts TTLSeconds, which defaults to
+// the server's link expiration, and never past the entry's expiration.
+type ResendLinkRequest struct {
+	TTLSeconds int `json:"ttlSeconds,omitempty"`
+}
+
+type ResendLinkResponse struct {
+	Envelope
+	Link     *sendkey.ClaimLink `json:"link,omitempty"`
+	ClaimURL string             `json:"claimUrl,omitempty"`
+}
+
+// DelegateClaimRequest makes a token a bot or service can claim the entry
+// with instead of the secret. It lasts TTLSeconds, which defaults to 15
+// minutes and can be at most an hour.
+type DelegateClaimRequest struct {
+	Secret     string `json:"secret"`
+	TTLSeconds int    `json:"ttlSeconds,omitempty"`
+}
+
+type DelegateClaimResponse struct {
+	Envelope
+	Token        string     `json:"token,omitempty"`
+	ExpiresAtUTC *time.Time `json:"expiresAtUtc,omitempty"`
+}
+
+// DelegatedClaimRequest claims an entry with a token from DelegateClaim. The
+// response is a ClaimEntryResponse.
+type DelegatedClaimRequest struct {
+	Token string `json:"token"`
+}
+
+// EntryFlagsResponse holds the user's flags on an entry after pinning or
+// favoriting it.
+type EntryFlagsResponse struct {
+	Envelope
+	Flags sendkey.EntryFlags `json:"flags"`
+}
+
+// SealedValue is an entry value encrypted by the sender's client. The key is
+// derived from the secret with the KDF, and the value is sealed with the
+// cipher and nonce.
+type SealedValue struct {
+	Ciphertext []byte           `json:"ciphertext"`
+	Nonce      []byte           `json:"nonce"`
+	Cipher     string           `json:"cipher"`
+	KDF        sendkey.EntryKDF `json:"kdf"`
+}
+
+// GeneratePolicy describes a random password, or a passphrase of Words
+// words; see GenerateRequest.
+type GeneratePolicy struct {
+	Length    int    `json:"length,omitempty"`
+	Charset   string `json:"charset,omitempty"`
+	Words     int    `json:"words,omitempty"`
+	Separator string `json:"separator,omitempty"`
+}
+
+// GenerateRequest generates a random password, or a passphrase of Words
+// words. If Entry is set, an entry is created with the generated value, so
+// its Value must be empty.
+type GenerateRequest struct {
+	Length    int                 `json:"length,omitempty"`
+	Charset   string              `json:"charset,omitempty"`
+	Words     int                 `json:"words,omitempty"`
+	Separator string              `json:"separator,omitempty"`
+	Entry     *CreateEntryRequest `json:"entry,omitempty"`
+}
+
+type GenerateResponse struct {
+	Envelope
+	Value    string             `json:"value"`
+	Entry    *sendkey.Entry     `json:"entry"`
+	ClaimURL string             `json:"claimUrl,omitempty"`
+	Link     *sendkey.ClaimLink `json:"link,omitempty"`
+}
+
+type VerifyReceiptResponse struct {
+	Valid bool `json:"valid"`
+}
+
+// Usage is what a user has sent against their quota.
+type Usage struct {
+	// EntriesToday is the number of entries created since midnight UTC.
+	EntriesToday int `json:"entriesToday"`
+	// LiveEntries is the number of entries waiting to be claimed.
+	LiveEntries int   `json:"liveEntries"`
+	Quota       Quota `json:"quota"`
+}
+
+// Quota limits what a user can send. Zero fields are unlimited.
+type Quota struct {
+	EntriesPerDay  int `json:"entriesPerDay"`
+	MaxLiveEntries int `json:"maxLiveEntries"`
+	MaxValueBytes  int `json:"maxValueBytes"`
+}
+
+// EntryEvent is sent on the event stream when one of the user's entries is
+// claimed, expires, or is revoked or replaced. Type is "entry.claimed",
+// "entry.expired", "entry.revoked", or "entry.replaced", and Data has the
+// event's other fields, like the claimer's ID or the replacing entry's.
+type EntryEvent struct {
+	ID      uuid.UUID         `json:"id"`
+	Type    string            `json:"type"`
+	EntryID uuid.UUID         `json:"entryId"`
+	AtUTC   time.Time         `json:"atUtc"`
+	Data    map[string]string `json:"data,omitempty"`
+}
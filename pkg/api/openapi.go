@@ -0,0 +1,328 @@
+package api
+
+import (
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// The subset of OpenAPI 3.0 needed to describe the operations, so clients in
+// other languages can be generated from it. See NewOpenAPIDocument.
+type (
+	OpenAPIDocument struct {
+		OpenAPI    string                     `json:"openapi"`
+		Info       OpenAPIInfo                `json:"info"`
+		Servers    []OpenAPIServer            `json:"servers,omitempty"`
+		Paths      map[string]OpenAPIPathItem `json:"paths"`
+		Components OpenAPIComponents          `json:"components"`
+	}
+	OpenAPIInfo struct {
+		Title   string `json:"title"`
+		Version string `json:"version"`
+	}
+	OpenAPIServer struct {
+		URL string `json:"url"`
+	}
+	// OpenAPIPathItem has a path's operations by their lowercase method.
+	OpenAPIPathItem  map[string]*OpenAPIOperation
+	OpenAPIOperation struct {
+		OperationID string                     `json:"operationId"`
+		Summary     string                     `json:"summary,omitempty"`
+		Parameters  []OpenAPIParameter         `json:"parameters,omitempty"`
+		RequestBody *OpenAPIRequestBody        `json:"requestBody,omitempty"`
+		Responses   map[string]OpenAPIResponse `json:"responses"`
+		Security    []map[string][]string      `json:"security,omitempty"`
+	}
+	OpenAPIParameter struct {
+		Name     string  `json:"name"`
+		In       string  `json:"in"`
+		Required bool    `json:"required,omitempty"`
+		Schema   *Schema `json:"schema"`
+		Example  string  `json:"example,omitempty"`
+	}
+	OpenAPIRequestBody struct {
+		Required bool                        `json:"required"`
+		Content  map[string]OpenAPIMediaType `json:"content"`
+	}
+	OpenAPIResponse struct {
+		Description string                      `json:"description"`
+		Content     map[string]OpenAPIMediaType `json:"content,omitempty"`
+	}
+	OpenAPIMediaType struct {
+		Schema  *Schema     `json:"schema"`
+		Example interface{} `json:"example,omitempty"`
+	}
+	OpenAPIComponents struct {
+		Schemas         map[string]*Schema               `json:"schemas"`
+		SecuritySchemes map[string]OpenAPISecurityScheme `json:"securitySchemes"`
+	}
+	OpenAPISecurityScheme struct {
+		Type   string `json:"type"`
+		Scheme string `json:"scheme"`
+	}
+)
+
+// Schema is an OpenAPI schema for a JSON value. A schema with Ref refers to
+// one of the document's component schemas; see OpenAPIDocument.Resolve.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Deprecated           bool               `json:"deprecated,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+	OneOf                []*Schema          `json:"oneOf,omitempty"`
+}
+
+const schemaRefPrefix = "#/components/schemas/"
+
+// bearerAuth is the security scheme of the operations that need an access
+// token.
+const bearerAuth = "bearerAuth"
+
+// NewOpenAPIDocument describes Operations as an OpenAPI document for the API
+// at baseURL, like https://api.sendkey.me/v1. The schemas are generated from
+// the operations' request and response models, so the document can't drift
+// from what the server sends, and the examples are the operations' own.
+func NewOpenAPIDocument(baseURL string) *OpenAPIDocument {
+	d := &OpenAPIDocument{
+		OpenAPI: "3.0.3",
+		Info:    OpenAPIInfo{Title: "sendkey", Version: strings.TrimPrefix(BasePath, "/")},
+		Paths:   map[string]OpenAPIPathItem{},
+		Components: OpenAPIComponents{
+			Schemas:         map[string]*Schema{},
+			SecuritySchemes: map[string]OpenAPISecurityScheme{bearerAuth: {Type: "http", Scheme: "bearer"}},
+		},
+	}
+	if baseURL != "" {
+		d.Servers = []OpenAPIServer{{URL: baseURL}}
+	}
+
+	g := schemaGenerator{doc: d, names: map[reflect.Type]string{}}
+	errorSchema := g.schema(reflect.TypeOf(Error{}))
+	for _, o := range Operations {
+		op := &OpenAPIOperation{
+			OperationID: o.ID,
+			Summary:     o.Summary,
+			Responses: map[string]OpenAPIResponse{
+				"default": {Description: "The request failed.", Content: jsonContent(errorSchema, nil)},
+			},
+		}
+
+		var path []string
+		for _, s := range strings.Split(o.Path, "/") {
+			if strings.HasPrefix(s, ":") {
+				name := s[1:]
+				op.Parameters = append(op.Parameters, OpenAPIParameter{
+					Name: name, In: "path", Required: true, Schema: &Schema{Type: "string"}, Example: o.Params[name],
+				})
+				s = "{" + name + "}"
+			}
+			path = append(path, s)
+		}
+		query := make([]string, 0, len(o.Query))
+		for name := range o.Query {
+			query = append(query, name)
+		}
+		sort.Strings(query)
+		for _, name := range query {
+			op.Parameters = append(op.Parameters, OpenAPIParameter{
+				Name: name, In: "query", Schema: &Schema{Type: "string"}, Example: o.Query[name],
+			})
+		}
+		if o.Auth {
+			op.Security = []map[string][]string{{bearerAuth: {}}}
+		}
+		if o.Request != nil {
+			op.RequestBody = &OpenAPIRequestBody{
+				Required: true,
+				Content:  jsonContent(g.schema(reflect.TypeOf(o.Request)), o.Request),
+			}
+		}
+		res := OpenAPIResponse{Description: http.StatusText(o.Status)}
+		if o.Response != nil {
+			res.Content = jsonContent(g.schema(reflect.TypeOf(o.Response)), o.Response)
+		}
+		op.Responses[strconv.Itoa(o.Status)] = res
+
+		p := strings.Join(path, "/")
+		if d.Paths[p] == nil {
+			d.Paths[p] = OpenAPIPathItem{}
+		}
+		d.Paths[p][strings.ToLower(o.Method)] = op
+	}
+	return d
+}
+
+// RequestSchema returns the schema of the request body the operation at the
+// method and path takes, with the path as it's routed, like
+// "/entries/:entryID". It's nil if the operation isn't described or doesn't
+// take a body.
+func (d *OpenAPIDocument) RequestSchema(method, path string) *Schema {
+	var segments []string
+	for _, s := range strings.Split(path, "/") {
+		if strings.HasPrefix(s, ":") {
+			s = "{" + s[1:] + "}"
+		}
+		segments = append(segments, s)
+	}
+	op := d.Paths[strings.Join(segments, "/")][strings.ToLower(method)]
+	if op == nil || op.RequestBody == nil {
+		return nil
+	}
+	return op.RequestBody.Content["application/json"].Schema
+}
+
+// Resolve returns the component schema s refers to, or s if it isn't a
+// reference.
+func (d *OpenAPIDocument) Resolve(s *Schema) *Schema {
+	for s != nil && s.Ref != "" {
+		s = d.Components.Schemas[strings.TrimPrefix(s.Ref, schemaRefPrefix)]
+	}
+	return s
+}
+
+func jsonContent(s *Schema, example interface{}) map[string]OpenAPIMediaType {
+	return map[string]OpenAPIMediaType{"application/json": {Schema: s, Example: example}}
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	uuidType          = reflect.TypeOf(uuid.UUID{})
+	entryDurationType = reflect.TypeOf(EntryDuration{})
+	fieldErrorType    = reflect.TypeOf(FieldError{})
+)
+
+// schemaGenerator generates the schemas for Go types the way encoding/json
+// marshals them. Named structs become component schemas, named after their
+// type.
+type schemaGenerator struct {
+	doc   *OpenAPIDocument
+	names map[reflect.Type]string
+}
+
+func (g schemaGenerator) schema(t reflect.Type) *Schema {
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case uuidType:
+		return &Schema{Type: "string", Format: "uuid"}
+	case entryDurationType:
+		return &Schema{
+			Description: `A duration with units, like "45m" or "1h30m".`,
+			OneOf:       []*Schema{{Type: "string"}, {Type: "integer", Description: "Minutes.", Deprecated: true}},
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		s := g.schema(t.Elem())
+		if s.Ref != "" {
+			// siblings of $ref are ignored, so the nullable reference is
+			// wrapped
+			return &Schema{Nullable: true, AllOf: []*Schema{s}}
+		}
+		s.Nullable = true
+		return s
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schema(t.Elem())}
+	case reflect.Struct:
+		return g.structSchema(t)
+	}
+	// an interface{} can hold anything
+	return &Schema{}
+}
+
+// structSchema returns a reference to the struct's component schema,
+// generating it the first time. Anonymous structs are inlined.
+func (g schemaGenerator) structSchema(t reflect.Type) *Schema {
+	if t.Name() == "" {
+		return g.properties(t)
+	}
+	if name, ok := g.names[t]; ok {
+		return &Schema{Ref: schemaRefPrefix + name}
+	}
+
+	name := t.Name()
+	if _, taken := g.doc.Components.Schemas[name]; taken {
+		// another package's type has the name
+		name = pkgName(t) + name
+	}
+	g.names[t] = name
+	// the name is taken before the properties are generated, so a type
+	// that refers to itself refers to its component
+	g.doc.Components.Schemas[name] = &Schema{}
+	fields := t
+	if t == fieldErrorType {
+		// FieldError marshals itself as fieldError, or a string in the
+		// legacy format the document doesn't describe
+		fields = reflect.TypeOf(fieldError{})
+	}
+	*g.doc.Components.Schemas[name] = *g.properties(fields)
+	return &Schema{Ref: schemaRefPrefix + name}
+}
+
+// properties returns the object schema of the struct's fields, with the
+// fields of embedded structs promoted like encoding/json does.
+func (g schemaGenerator) properties(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" || (!f.IsExported() && !f.Anonymous) {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				for n, p := range g.properties(ft).Properties {
+					if _, ok := s.Properties[n]; !ok {
+						s.Properties[n] = p
+					}
+				}
+				continue
+			}
+		}
+		if name == "" {
+			name = f.Name
+		}
+		s.Properties[name] = g.schema(f.Type)
+	}
+	return s
+}
+
+// pkgName returns the name of the type's package, capitalized like a type
+// name.
+func pkgName(t reflect.Type) string {
+	path := t.PkgPath()
+	name := path[strings.LastIndex(path, "/")+1:]
+	return strings.ToUpper(name[:1]) + name[1:]
+}
@@ -0,0 +1,376 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gavinwade12/sendkey"
+	"github.com/google/uuid"
+)
+
+// Operation is an API endpoint with an example request and response, for
+// exploring the API: the server serves them from /examples, and the CLI can
+// call any of them by ID.
+type Operation struct {
+	ID     string `json:"id"`
+	Method string `json:"method"`
+	// Path is relative to the API's base URL, with parameters like
+	// :entryID. See Expand.
+	Path    string `json:"path"`
+	Summary string `json:"summary"`
+	// Auth is set if the operation needs an access token.
+	Auth bool `json:"auth"`
+	// Params are example values for the path parameters, and Query for the
+	// query parameters.
+	Params map[string]string `json:"params,omitempty"`
+	Query  map[string]string `json:"query,omitempty"`
+	// Request is the example body, if the operation takes one.
+	Request interface{} `json:"request,omitempty"`
+	// Status and Response are the example response.
+	Status   int         `json:"status"`
+	Response interface{} `json:"response,omitempty"`
+}
+
+// Expand replaces the path's parameters with the values, returning an
+// error if one is missing.
+func (o Operation) Expand(params map[string]string) (string, error) {
+	segments := strings.Split(o.Path, "/")
+	for i, s := range segments {
+		if !strings.HasPrefix(s, ":") {
+			continue
+		}
+		v, ok := params[s[1:]]
+		if !ok || v == "" {
+			return "", fmt.Errorf("%s needs the %s parameter", o.ID, s[1:])
+		}
+		segments[i] = v
+	}
+	return strings.Join(segments, "/"), nil
+}
+
+// FindOperation returns the operation with the ID.
+func FindOperation(id string) (Operation, bool) {
+	for _, o := range Operations {
+		if o.ID == id {
+			return o, true
+		}
+	}
+	return Operation{}, false
+}
+
+// OperationIDs returns every operation's ID, sorted.
+func OperationIDs() []string {
+	ids := make([]string, len(Operations))
+	for i, o := range Operations {
+		ids[i] = o.ID
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// The values the examples share, so they read as one session.
+var (
+	exampleUserID  = uuid.MustParse("6f1c1a0e-3b7d-4c55-9a8e-2d4f0b9e7c31")
+	exampleEntryID = uuid.MustParse("b2e4d7a9-58c1-4f0e-8d3a-71c6e9f2a405")
+	exampleNonce   = "4c1d8e2f9a7b3c6d5e0f1a2b"
+	exampleNow     = time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC)
+	exampleUser    = sendkey.User{
+		ID:            exampleUserID,
+		Email:         "alex@example.com",
+		EmailVerified: true,
+		FirstName:     "Alex",
+		LastName:      "Rivera",
+		CreatedAtUTC:  exampleNow.AddDate(0, -1, 0),
+		Role:          sendkey.RoleUser,
+	}
+	exampleEntry = sendkey.Entry{
+		ID:           exampleEntryID,
+		Name:         "Staging database password",
+		SentByUserID: exampleUserID,
+		SentToEmail:  "sam@example.com",
+		Locale:       "en",
+		Type:         sendkey.EntryTypePassword,
+		CreatedAtUTC: exampleNow,
+		ExpiresAtUTC: exampleNow.Add(24 * time.Hour),
+		Challenges:   []sendkey.EntryChallenge{},
+	}
+	exampleClaimURL = "https://app.sendkey.me/claim/" + exampleEntryID.String() + "?nonce=" + exampleNonce
+	exampleReceipt  = sendkey.ClaimReceipt{
+		EntryID:      exampleEntryID,
+		ValueHash:    "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+		Signature:    "3a7bd3e2360a3d29eea436fcfb7e44c735d117c42d1c1835420b6b9942dd4f1b",
+		ClaimedAtUTC: exampleNow.Add(time.Hour),
+	}
+	exampleValue            = "correct-horse-battery-staple"
+	exampleDelegationToken  = "5a0c9e3b-7d21-4f86-a4b9-c13e8f60d2a7.q3Jx9vT0bW2nLc8sYk4pHd7fRz1mAe6uGo5iNj0tXwE"
+	exampleDelegationExpiry = exampleNow.Add(5 * time.Minute)
+	exampleOK               = Envelope{Success: true, Errors: []FieldError{}}
+	exampleClaimedAt        = exampleReceipt.ClaimedAtUTC
+	exampleEscrow           = sendkey.EntryEscrow{
+		EntryID:      exampleEntryID,
+		SentByUserID: exampleUserID,
+		SentToEmail:  "sam@example.com",
+		KeyID:        "5d41402abc4b2a76b9719d911017c592a7c2e5f0d1b3e9c8f6a4d2b0e1c3f5a7",
+		WrappedKey:   []byte("<RSA-OAEP ciphertext>"),
+		Nonce:        []byte("<nonce>"),
+		Ciphertext:   []byte("<AES-GCM ciphertext>"),
+		CreatedAtUTC: exampleNow,
+		ClaimedAtUTC: &exampleClaimedAt,
+		PurgeAtUTC:   exampleClaimedAt.AddDate(1, 0, 0),
+	}
+	exampleClaimLink = sendkey.ClaimLink{
+		ID:           uuid.MustParse("8e4f2a61-3c7b-4d09-b5e2-7a1c9f0d6b38"),
+		EntryID:      exampleEntryID,
+		Status:       sendkey.ClaimLinkActive,
+		CreatedAtUTC: exampleNow.Add(time.Hour),
+		ExpiresAtUTC: exampleNow.Add(24 * time.Hour),
+	}
+)
+
+// Operations are the API's operations that have examples.
+var Operations = []Operation{
+	{
+		ID:      "createUser",
+		Method:  http.MethodPost,
+		Path:    "/users",
+		Summary: "Register a user.",
+		Request: CreateUserRequest{
+			Email:     exampleUser.Email,
+			Password:  "a long and unguessable passphrase",
+			FirstName: exampleUser.FirstName,
+			LastName:  exampleUser.LastName,
+		},
+		Status:   http.StatusOK,
+		Response: CreateUserResponse{Envelope: exampleOK, PasswordErrors: []PasswordViolation{}, User: &exampleUser},
+	},
+	{
+		ID:       "login",
+		Method:   http.MethodPost,
+		Path:     "/login",
+		Summary:  "Log in with a password, and an MFA code if MFA is enabled.",
+		Request:  LoginRequest{Email: exampleUser.Email, Password: "a long and unguessable passphrase"},
+		Status:   http.StatusOK,
+		Response: LoginResponse{Envelope: exampleOK, User: &exampleUser, AccessToken: &Token{Token: "eyJhbGciOiJIUzI1NiJ9.example", Expires: exampleNow.Add(15 * time.Minute).Unix()}, RefreshToken: &Token{Token: "8d1f6c0e2b4a9d7f", Expires: exampleNow.AddDate(0, 0, 30).Unix()}},
+	},
+	{
+		ID:       "completeExternalLogin",
+		Method:   http.MethodPost,
+		Path:     "/auth/external",
+		Summary:  "Finish a login with an identity provider that needs an MFA code.",
+		Request:  ExternalLoginRequest{ExternalLogin: "eyJhbGciOiJIUzI1NiJ9.example", MFACode: "123456"},
+		Status:   http.StatusOK,
+		Response: LoginResponse{Envelope: exampleOK, User: &exampleUser, AccessToken: &Token{Token: "eyJhbGciOiJIUzI1NiJ9.example", Expires: exampleNow.Add(15 * time.Minute).Unix()}, RefreshToken: &Token{Token: "8d1f6c0e2b4a9d7f", Expires: exampleNow.AddDate(0, 0, 30).Unix()}},
+	},
+	{
+		ID:       "requestMagicLink",
+		Method:   http.MethodPost,
+		Path:     "/login/magic",
+		Summary:  "Email a link that logs the user in.",
+		Request:  MagicLinkRequest{Email: exampleUser.Email},
+		Status:   http.StatusOK,
+		Response: MagicLinkResponse{Envelope: exampleOK},
+	},
+	{
+		ID:       "redeemMagicLink",
+		Method:   http.MethodPost,
+		Path:     "/login/magic/:code",
+		Summary:  "Log in with the code from a magic link, and an MFA code if MFA is enabled. A user whose password an admin reset must set a new one.",
+		Request:  RedeemMagicLinkRequest{MFACode: "123456"},
+		Status:   http.StatusOK,
+		Response: LoginResponse{Envelope: exampleOK, User: &exampleUser, AccessToken: &Token{Token: "eyJhbGciOiJIUzI1NiJ9.example", Expires: exampleNow.Add(15 * time.Minute).Unix()}, RefreshToken: &Token{Token: "8d1f6c0e2b4a9d7f", Expires: exampleNow.AddDate(0, 0, 30).Unix()}},
+	},
+	{
+		ID:       "refreshToken",
+		Method:   http.MethodPost,
+		Path:     "/token",
+		Summary:  "Get a new access token with a refresh token.",
+		Request:  RefreshTokenRequest{UserID: exampleUserID, RefreshToken: "8d1f6c0e2b4a9d7f"},
+		Status:   http.StatusOK,
+		Response: RefreshTokenResponse{Envelope: exampleOK, AccessToken: &Token{Token: "eyJhbGciOiJIUzI1NiJ9.example", Expires: exampleNow.Add(15 * time.Minute).Unix()}},
+	},
+	{
+		ID:       "changePassword",
+		Method:   http.MethodPut,
+		Path:     "/users/:userID/password",
+		Summary:  "Change the current user's password.",
+		Auth:     true,
+		Params:   map[string]string{"userID": "me"},
+		Request:  ChangePasswordRequest{CurrentPassword: "a long and unguessable passphrase", NewPassword: "an even longer unguessable passphrase"},
+		Status:   http.StatusOK,
+		Response: ChangePasswordResponse{Envelope: exampleOK, PasswordErrors: []PasswordViolation{}},
+	},
+	{
+		ID:      "createEntry",
+		Method:  http.MethodPost,
+		Path:    "/entries",
+		Summary: "Send an entry. The recipient claims it with the secret, which is shared separately.",
+		Auth:    true,
+		Request: CreateEntryRequest{
+			Name:        exampleEntry.Name,
+			SendToEmail: exampleEntry.SentToEmail,
+			Value:       exampleValue,
+			Secret:      "blue-otter-42",
+			Duration:    &EntryDuration{Duration: 24 * time.Hour},
+			Locale:      exampleEntry.Locale,
+			Type:        exampleEntry.Type,
+			Challenges:  []EntryChallenge{},
+		},
+		Status:   http.StatusOK,
+		Response: CreateEntryResponse{Envelope: exampleOK, Entry: &exampleEntry, ClaimURL: exampleClaimURL},
+	},
+	{
+		ID:       "generate",
+		Method:   http.MethodPost,
+		Path:     "/generate",
+		Summary:  "Generate a random password or passphrase, optionally sending it as an entry.",
+		Auth:     true,
+		Request:  GenerateRequest{Words: 4, Separator: "-"},
+		Status:   http.StatusOK,
+		Response: GenerateResponse{Envelope: exampleOK, Value: exampleValue},
+	},
+	{
+		ID:       "findEntry",
+		Method:   http.MethodGet,
+		Path:     "/entries/:entryID",
+		Summary:  "Look up an entry by its ID and nonce from the claim link.",
+		Params:   map[string]string{"entryID": exampleEntryID.String()},
+		Query:    map[string]string{"nonce": exampleNonce},
+		Status:   http.StatusOK,
+		Response: exampleEntry,
+	},
+	{
+		ID:       "claimEntry",
+		Method:   http.MethodGet,
+		Path:     "/entries/:entryID/value",
+		Summary:  "Claim an entry's value with its secret. The entry is gone once it's claimed.",
+		Params:   map[string]string{"entryID": exampleEntryID.String()},
+		Query:    map[string]string{"nonce": exampleNonce, "secret": "blue-otter-42"},
+		Status:   http.StatusOK,
+		Response: ClaimEntryResponse{Envelope: exampleOK, Value: &exampleValue, Receipt: &exampleReceipt},
+	},
+	{
+		ID:      "deferClaim",
+		Method:  http.MethodPost,
+		Path:    "/entries/:entryID/defer",
+		Summary: "Put off claiming an entry and be reminded later.",
+		Params:  map[string]string{"entryID": exampleEntryID.String()},
+		Request: DeferClaimRequest{Nonce: exampleNonce, DelaySeconds: 3600},
+		Status:  http.StatusOK,
+		Response: DeferClaimResponse{Envelope: exampleOK, Deferral: &sendkey.EntryDeferral{
+			ID:            uuid.MustParse("0d9e4b7c-21a6-4f38-b5c2-e8a1f3d6c794"),
+			EntryID:       exampleEntryID,
+			DeferredAtUTC: exampleNow,
+			RemindAtUTC:   exampleNow.Add(time.Hour),
+		}},
+	},
+	{
+		ID:       "resendLink",
+		Method:   http.MethodPost,
+		Path:     "/entries/:entryID/links",
+		Summary:  "Issue a new claim link for the user's entry. The entry's earlier links stop working.",
+		Auth:     true,
+		Params:   map[string]string{"entryID": exampleEntryID.String()},
+		Request:  ResendLinkRequest{TTLSeconds: 86400},
+		Status:   http.StatusOK,
+		Response: ResendLinkResponse{Envelope: exampleOK, Link: &exampleClaimLink, ClaimURL: exampleClaimURL},
+	},
+	{
+		ID:       "delegateClaim",
+		Method:   http.MethodPost,
+		Path:     "/entries/:entryID/delegations",
+		Summary:  "Make a short-lived token a bot or service can claim the user's entry with, without the secret.",
+		Auth:     true,
+		Params:   map[string]string{"entryID": exampleEntryID.String()},
+		Request:  DelegateClaimRequest{Secret: "blue-otter-42", TTLSeconds: 300},
+		Status:   http.StatusOK,
+		Response: DelegateClaimResponse{Envelope: exampleOK, Token: exampleDelegationToken, ExpiresAtUTC: &exampleDelegationExpiry},
+	},
+	{
+		ID:       "claimDelegated",
+		Method:   http.MethodPost,
+		Path:     "/entries/:entryID/delegated-claim",
+		Summary:  "Claim an entry with a delegated claim token.",
+		Params:   map[string]string{"entryID": exampleEntryID.String()},
+		Request:  DelegatedClaimRequest{Token: exampleDelegationToken},
+		Status:   http.StatusOK,
+		Response: ClaimEntryResponse{Envelope: exampleOK, Value: &exampleValue, Receipt: &exampleReceipt},
+	},
+	{
+		ID:       "listEntries",
+		Method:   http.MethodGet,
+		Path:     "/users/:userID/entries",
+		Summary:  "List the entries the user has sent that haven't been claimed.",
+		Auth:     true,
+		Params:   map[string]string{"userID": "me"},
+		Status:   http.StatusOK,
+		Response: []sendkey.Entry{exampleEntry},
+	},
+	{
+		ID:       "usage",
+		Method:   http.MethodGet,
+		Path:     "/users/:userID/usage",
+		Summary:  "Show what the user has sent against their quota.",
+		Auth:     true,
+		Params:   map[string]string{"userID": "me"},
+		Status:   http.StatusOK,
+		Response: Usage{EntriesToday: 3, LiveEntries: 1, Quota: Quota{EntriesPerDay: 50, MaxLiveEntries: 20, MaxValueBytes: 65536}},
+	},
+	{
+		ID:       "verifyReceipt",
+		Method:   http.MethodPost,
+		Path:     "/receipts/verify",
+		Summary:  "Check that a claim receipt was issued by the server.",
+		Request:  exampleReceipt,
+		Status:   http.StatusOK,
+		Response: VerifyReceiptResponse{Valid: true},
+	},
+	{
+		ID:       "revokeEntries",
+		Method:   http.MethodPost,
+		Path:     "/admin/entries/revoke",
+		Summary:  "Revoke every active entry matching a filter. Admins only.",
+		Auth:     true,
+		Request:  RevokeEntriesRequest{SentByUserID: &exampleUserID},
+		Status:   http.StatusOK,
+		Response: RevokeEntriesResponse{Envelope: exampleOK, Revoked: 1, Notified: 1},
+	},
+	{
+		ID:       "releaseEscrow",
+		Method:   http.MethodPost,
+		Path:     "/admin/escrows/:entryID/release",
+		Summary:  "Release an entry's escrow, encrypted to the escrow key. Admins only.",
+		Auth:     true,
+		Params:   map[string]string{"entryID": exampleEntryID.String()},
+		Request:  ReleaseEscrowRequest{Reason: "INC-1234"},
+		Status:   http.StatusOK,
+		Response: ReleaseEscrowResponse{Envelope: exampleOK, Escrow: &exampleEscrow},
+	},
+	{
+		ID:       "listUsers",
+		Method:   http.MethodGet,
+		Path:     "/admin/users",
+		Summary:  "List users. Admins only.",
+		Auth:     true,
+		Query:    map[string]string{"limit": "50"},
+		Status:   http.StatusOK,
+		Response: ListUsersResponse{Users: []sendkey.User{exampleUser}},
+	},
+	{
+		ID:      "adminStats",
+		Method:  http.MethodGet,
+		Path:    "/admin/stats",
+		Summary: "Show how much storage the database takes, and the senders using the most. Admins only.",
+		Auth:    true,
+		Status:  http.StatusOK,
+		Response: AdminStatsResponse{Storage: StorageStats{
+			TotalBytes: 1 << 20,
+			ValueBytes: 2048,
+			Tables:     []TableSize{{Name: "entries", Rows: 8, DataBytes: 16384, IndexBytes: 16384}},
+			Senders:    []SenderSize{{UserID: exampleUserID, Entries: 8, ValueBytes: 2048}},
+			Warnings:   []string{},
+		}},
+	},
+}
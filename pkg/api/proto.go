@@ -0,0 +1,12 @@
+package api
+
+import _ "embed"
+
+// Proto is the gRPC API's protobuf definitions, for generating clients in
+// other languages. See sendkey.proto. The Go code generated from it is in
+// sendkeyv1.
+//
+//go:embed sendkey.proto
+var Proto []byte
+
+//go:generate protoc -I . -I $GOOGLEAPIS --go_out=. --go_opt=module=github.com/gavinwade12/sendkey/pkg/api --go-grpc_out=. --go-grpc_opt=module=github.com/gavinwade12/sendkey/pkg/api sendkey.proto
@@ -0,0 +1,492 @@
+// The gRPC API, for internal services that prefer it to the JSON API. Each
+// method is served by the JSON route in its google.api.http option, so the
+// two can't disagree: a request's fields are sent as the route's path
+// parameters, query parameters, or body, by their JSON names, and its response
+// is the route's. Responses with success and errors report validation
+// problems the way the JSON API's envelopes do; other failures are the call's
+// status, with the JSON API's error code in the sendkey-error-code trailer.
+//
+// Send the access token as "authorization: Bearer <token>" metadata. Other
+// metadata, like accept-language or idempotency-key, is sent as the route's
+// headers.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.4.0
+// - protoc             (unknown)
+// source: sendkey.proto
+
+package sendkeyv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.62.0 or later.
+const _ = grpc.SupportPackageIsVersion8
+
+const (
+	Users_CreateUser_FullMethodName = "/sendkey.v1.Users/CreateUser"
+)
+
+// UsersClient is the client API for Users service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type UsersClient interface {
+	CreateUser(ctx context.Context, in *CreateUserRequest, opts ...grpc.CallOption) (*CreateUserResponse, error)
+}
+
+type usersClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewUsersClient(cc grpc.ClientConnInterface) UsersClient {
+	return &usersClient{cc}
+}
+
+func (c *usersClient) CreateUser(ctx context.Context, in *CreateUserRequest, opts ...grpc.CallOption) (*CreateUserResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CreateUserResponse)
+	err := c.cc.Invoke(ctx, Users_CreateUser_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UsersServer is the server API for Users service.
+// All implementations must embed UnimplementedUsersServer
+// for forward compatibility
+type UsersServer interface {
+	CreateUser(context.Context, *CreateUserRequest) (*CreateUserResponse, error)
+	mustEmbedUnimplementedUsersServer()
+}
+
+// UnimplementedUsersServer must be embedded to have forward compatible implementations.
+type UnimplementedUsersServer struct {
+}
+
+func (UnimplementedUsersServer) CreateUser(context.Context, *CreateUserRequest) (*CreateUserResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateUser not implemented")
+}
+func (UnimplementedUsersServer) mustEmbedUnimplementedUsersServer() {}
+
+// UnsafeUsersServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to UsersServer will
+// result in compilation errors.
+type UnsafeUsersServer interface {
+	mustEmbedUnimplementedUsersServer()
+}
+
+func RegisterUsersServer(s grpc.ServiceRegistrar, srv UsersServer) {
+	s.RegisterService(&Users_ServiceDesc, srv)
+}
+
+func _Users_CreateUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UsersServer).CreateUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Users_CreateUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UsersServer).CreateUser(ctx, req.(*CreateUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Users_ServiceDesc is the grpc.ServiceDesc for Users service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Users_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "sendkey.v1.Users",
+	HandlerType: (*UsersServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateUser",
+			Handler:    _Users_CreateUser_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "sendkey.proto",
+}
+
+const (
+	Auth_Login_FullMethodName        = "/sendkey.v1.Auth/Login"
+	Auth_RefreshToken_FullMethodName = "/sendkey.v1.Auth/RefreshToken"
+)
+
+// AuthClient is the client API for Auth service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type AuthClient interface {
+	Login(ctx context.Context, in *LoginRequest, opts ...grpc.CallOption) (*LoginResponse, error)
+	RefreshToken(ctx context.Context, in *RefreshTokenRequest, opts ...grpc.CallOption) (*RefreshTokenResponse, error)
+}
+
+type authClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAuthClient(cc grpc.ClientConnInterface) AuthClient {
+	return &authClient{cc}
+}
+
+func (c *authClient) Login(ctx context.Context, in *LoginRequest, opts ...grpc.CallOption) (*LoginResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(LoginResponse)
+	err := c.cc.Invoke(ctx, Auth_Login_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authClient) RefreshToken(ctx context.Context, in *RefreshTokenRequest, opts ...grpc.CallOption) (*RefreshTokenResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RefreshTokenResponse)
+	err := c.cc.Invoke(ctx, Auth_RefreshToken_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AuthServer is the server API for Auth service.
+// All implementations must embed UnimplementedAuthServer
+// for forward compatibility
+type AuthServer interface {
+	Login(context.Context, *LoginRequest) (*LoginResponse, error)
+	RefreshToken(context.Context, *RefreshTokenRequest) (*RefreshTokenResponse, error)
+	mustEmbedUnimplementedAuthServer()
+}
+
+// UnimplementedAuthServer must be embedded to have forward compatible implementations.
+type UnimplementedAuthServer struct {
+}
+
+func (UnimplementedAuthServer) Login(context.Context, *LoginRequest) (*LoginResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Login not implemented")
+}
+func (UnimplementedAuthServer) RefreshToken(context.Context, *RefreshTokenRequest) (*RefreshTokenResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RefreshToken not implemented")
+}
+func (UnimplementedAuthServer) mustEmbedUnimplementedAuthServer() {}
+
+// UnsafeAuthServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AuthServer will
+// result in compilation errors.
+type UnsafeAuthServer interface {
+	mustEmbedUnimplementedAuthServer()
+}
+
+func RegisterAuthServer(s grpc.ServiceRegistrar, srv AuthServer) {
+	s.RegisterService(&Auth_ServiceDesc, srv)
+}
+
+func _Auth_Login_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LoginRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthServer).Login(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Auth_Login_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthServer).Login(ctx, req.(*LoginRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Auth_RefreshToken_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RefreshTokenRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthServer).RefreshToken(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Auth_RefreshToken_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthServer).RefreshToken(ctx, req.(*RefreshTokenRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Auth_ServiceDesc is the grpc.ServiceDesc for Auth service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Auth_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "sendkey.v1.Auth",
+	HandlerType: (*AuthServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Login",
+			Handler:    _Auth_Login_Handler,
+		},
+		{
+			MethodName: "RefreshToken",
+			Handler:    _Auth_RefreshToken_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "sendkey.proto",
+}
+
+const (
+	Entries_CreateEntry_FullMethodName      = "/sendkey.v1.Entries/CreateEntry"
+	Entries_FindEntry_FullMethodName        = "/sendkey.v1.Entries/FindEntry"
+	Entries_ClaimEntry_FullMethodName       = "/sendkey.v1.Entries/ClaimEntry"
+	Entries_StreamClaimEntry_FullMethodName = "/sendkey.v1.Entries/StreamClaimEntry"
+)
+
+// EntriesClient is the client API for Entries service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type EntriesClient interface {
+	CreateEntry(ctx context.Context, in *CreateEntryRequest, opts ...grpc.CallOption) (*CreateEntryResponse, error)
+	FindEntry(ctx context.Context, in *FindEntryRequest, opts ...grpc.CallOption) (*Entry, error)
+	ClaimEntry(ctx context.Context, in *ClaimEntryRequest, opts ...grpc.CallOption) (*ClaimEntryResponse, error)
+	// StreamClaimEntry claims the entry like ClaimEntry, but streams its value
+	// in chunks, so a large one doesn't need a client that accepts messages as
+	// large. The first message has the response without its value, and the
+	// rest have the value's data, in order.
+	StreamClaimEntry(ctx context.Context, in *ClaimEntryRequest, opts ...grpc.CallOption) (Entries_StreamClaimEntryClient, error)
+}
+
+type entriesClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewEntriesClient(cc grpc.ClientConnInterface) EntriesClient {
+	return &entriesClient{cc}
+}
+
+func (c *entriesClient) CreateEntry(ctx context.Context, in *CreateEntryRequest, opts ...grpc.CallOption) (*CreateEntryResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CreateEntryResponse)
+	err := c.cc.Invoke(ctx, Entries_CreateEntry_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *entriesClient) FindEntry(ctx context.Context, in *FindEntryRequest, opts ...grpc.CallOption) (*Entry, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Entry)
+	err := c.cc.Invoke(ctx, Entries_FindEntry_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *entriesClient) ClaimEntry(ctx context.Context, in *ClaimEntryRequest, opts ...grpc.CallOption) (*ClaimEntryResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ClaimEntryResponse)
+	err := c.cc.Invoke(ctx, Entries_ClaimEntry_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *entriesClient) StreamClaimEntry(ctx context.Context, in *ClaimEntryRequest, opts ...grpc.CallOption) (Entries_StreamClaimEntryClient, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Entries_ServiceDesc.Streams[0], Entries_StreamClaimEntry_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &entriesStreamClaimEntryClient{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Entries_StreamClaimEntryClient interface {
+	Recv() (*ClaimEntryChunk, error)
+	grpc.ClientStream
+}
+
+type entriesStreamClaimEntryClient struct {
+	grpc.ClientStream
+}
+
+func (x *entriesStreamClaimEntryClient) Recv() (*ClaimEntryChunk, error) {
+	m := new(ClaimEntryChunk)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// EntriesServer is the server API for Entries service.
+// All implementations must embed UnimplementedEntriesServer
+// for forward compatibility
+type EntriesServer interface {
+	CreateEntry(context.Context, *CreateEntryRequest) (*CreateEntryResponse, error)
+	FindEntry(context.Context, *FindEntryRequest) (*Entry, error)
+	ClaimEntry(context.Context, *ClaimEntryRequest) (*ClaimEntryResponse, error)
+	// StreamClaimEntry claims the entry like ClaimEntry, but streams its value
+	// in chunks, so a large one doesn't need a client that accepts messages as
+	// large. The first message has the response without its value, and the
+	// rest have the value's data, in order.
+	StreamClaimEntry(*ClaimEntryRequest, Entries_StreamClaimEntryServer) error
+	mustEmbedUnimplementedEntriesServer()
+}
+
+// UnimplementedEntriesServer must be embedded to have forward compatible implementations.
+type UnimplementedEntriesServer struct {
+}
+
+func (UnimplementedEntriesServer) CreateEntry(context.Context, *CreateEntryRequest) (*CreateEntryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateEntry not implemented")
+}
+func (UnimplementedEntriesServer) FindEntry(context.Context, *FindEntryRequest) (*Entry, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FindEntry not implemented")
+}
+func (UnimplementedEntriesServer) ClaimEntry(context.Context, *ClaimEntryRequest) (*ClaimEntryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ClaimEntry not implemented")
+}
+func (UnimplementedEntriesServer) StreamClaimEntry(*ClaimEntryRequest, Entries_StreamClaimEntryServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamClaimEntry not implemented")
+}
+func (UnimplementedEntriesServer) mustEmbedUnimplementedEntriesServer() {}
+
+// UnsafeEntriesServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to EntriesServer will
+// result in compilation errors.
+type UnsafeEntriesServer interface {
+	mustEmbedUnimplementedEntriesServer()
+}
+
+func RegisterEntriesServer(s grpc.ServiceRegistrar, srv EntriesServer) {
+	s.RegisterService(&Entries_ServiceDesc, srv)
+}
+
+func _Entries_CreateEntry_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateEntryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EntriesServer).CreateEntry(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Entries_CreateEntry_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EntriesServer).CreateEntry(ctx, req.(*CreateEntryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Entries_FindEntry_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FindEntryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EntriesServer).FindEntry(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Entries_FindEntry_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EntriesServer).FindEntry(ctx, req.(*FindEntryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Entries_ClaimEntry_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ClaimEntryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EntriesServer).ClaimEntry(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Entries_ClaimEntry_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EntriesServer).ClaimEntry(ctx, req.(*ClaimEntryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Entries_StreamClaimEntry_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ClaimEntryRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(EntriesServer).StreamClaimEntry(m, &entriesStreamClaimEntryServer{ServerStream: stream})
+}
+
+type Entries_StreamClaimEntryServer interface {
+	Send(*ClaimEntryChunk) error
+	grpc.ServerStream
+}
+
+type entriesStreamClaimEntryServer struct {
+	grpc.ServerStream
+}
+
+func (x *entriesStreamClaimEntryServer) Send(m *ClaimEntryChunk) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// Entries_ServiceDesc is the grpc.ServiceDesc for Entries service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Entries_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "sendkey.v1.Entries",
+	HandlerType: (*EntriesServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateEntry",
+			Handler:    _Entries_CreateEntry_Handler,
+		},
+		{
+			MethodName: "FindEntry",
+			Handler:    _Entries_FindEntry_Handler,
+		},
+		{
+			MethodName: "ClaimEntry",
+			Handler:    _Entries_ClaimEntry_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamClaimEntry",
+			Handler:       _Entries_StreamClaimEntry_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "sendkey.proto",
+}
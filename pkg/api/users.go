@@ -0,0 +1,119 @@
+package api
+
+import (
+	"github.com/gavinwade12/sendkey"
+	"github.com/google/uuid"
+)
+
+type CreateUserRequest struct {
+	Email     string `json:"email"`
+	Password  string `json:"password"`
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+}
+
+// LoginRequest logs in with a password. MFACode is only required if the user
+// has MFA enabled, and can be a TOTP code or a recovery code.
+// LinkExternalLogin is the ExternalLogin from a LoginResponse, to link the
+// identity the user logged in to the provider with to their account.
+type LoginRequest struct {
+	Email             string `json:"email"`
+	Password          string `json:"password"`
+	MFACode           string `json:"mfaCode"`
+	LinkExternalLogin string `json:"linkExternalLogin,omitempty"`
+}
+
+// ExternalLoginRequest finishes a login with an identity provider that
+// needed an MFA code, with the ExternalLogin from its LoginResponse.
+type ExternalLoginRequest struct {
+	ExternalLogin string `json:"externalLogin"`
+	MFACode       string `json:"mfaCode"`
+}
+
+type RefreshTokenRequest struct {
+	UserID       uuid.UUID `json:"userId"`
+	RefreshToken string    `json:"refreshToken"`
+}
+
+type MagicLinkRequest struct {
+	Email string `json:"email"`
+}
+
+// ChangePasswordRequest changes the current user's password. CurrentPassword
+// is only required if the user has a password.
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"currentPassword"`
+	NewPassword     string `json:"newPassword"`
+}
+
+type CreateUserResponse struct {
+	Envelope
+	PasswordErrors []PasswordViolation `json:"passwordErrors"`
+	User           *sendkey.User       `json:"user"`
+}
+
+// PasswordViolation is a password policy rule the password failed.
+type PasswordViolation struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// LoginResponse is returned by every way of logging in. The tokens are only
+// set when the login succeeded. ExternalLogin is set when a login with an
+// identity provider needs an MFA code, or a login to the account with the
+// identity's email to link it, and expires after 10 minutes.
+type LoginResponse struct {
+	Envelope
+	User          *sendkey.User `json:"user"`
+	AccessToken   *Token        `json:"accessToken"`
+	RefreshToken  *Token        `json:"refreshToken"`
+	ExternalLogin string        `json:"externalLogin,omitempty"`
+}
+
+type RefreshTokenResponse struct {
+	Envelope
+	AccessToken *Token `json:"accessToken"`
+}
+
+// RedeemMagicLinkRequest logs in with a magic link's code, which is in the
+// path. MFACode is only required if the user has MFA enabled. NewPassword
+// sets the user's password, which is required if an admin reset it.
+// LinkExternalLogin links an identity, as in LoginRequest.
+type RedeemMagicLinkRequest struct {
+	MFACode           string `json:"mfaCode"`
+	NewPassword       string `json:"newPassword,omitempty"`
+	LinkExternalLogin string `json:"linkExternalLogin,omitempty"`
+}
+
+type MagicLinkResponse struct {
+	Envelope
+}
+
+type ChangePasswordResponse struct {
+	Envelope
+	PasswordErrors []PasswordViolation `json:"passwordErrors"`
+}
+
+// EnableMFAResponse has a new TOTP secret for the user's authenticator. MFA
+// isn't enabled until a code from it is confirmed with a ConfirmMFARequest.
+type EnableMFAResponse struct {
+	Envelope
+	Secret string `json:"secret"`
+}
+
+// ConfirmMFARequest enables MFA with a code from the secret in the
+// EnableMFAResponse.
+type ConfirmMFARequest struct {
+	Code string `json:"code"`
+}
+
+// ConfirmMFAResponse has the user's recovery codes, which are only returned
+// here and when they're regenerated.
+type ConfirmMFAResponse struct {
+	Envelope
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WarningDeprecated is the code for a warning that the route is deprecated.
+const WarningDeprecated = "deprecated"
+
+// Warning tells the client about something it should change before it
+// breaks, like calling a deprecated route.
+type Warning struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// Sunset is when the route stops being served, if that's been decided.
+	Sunset *time.Time `json:"sunset,omitempty"`
+	// Successor is the path of the route to use instead, if there is one.
+	Successor string `json:"successor,omitempty"`
+}
+
+// warnAgent is the agent in the Warning headers the server sends.
+const warnAgent = "sendkey"
+
+// FormatWarning formats a message as a Warning header with the
+// miscellaneous persistent warning code, 299.
+func FormatWarning(message string) string {
+	return "299 " + warnAgent + " " + strconv.Quote(message)
+}
+
+// ParseWarnings returns the warnings in a response's headers: a deprecation
+// warning if it has a Deprecation header, with its Sunset and successor
+// Link, and one for each other Warning header with code 299.
+func ParseWarnings(h http.Header) []Warning {
+	var messages []string
+	for _, v := range h.Values("Warning") {
+		code, rest, _ := strings.Cut(v, " ")
+		_, text, _ := strings.Cut(rest, " ")
+		if code != "299" {
+			continue
+		}
+		if msg, err := strconv.Unquote(text); err == nil {
+			messages = append(messages, msg)
+		}
+	}
+
+	var warnings []Warning
+	if h.Get("Deprecation") != "" {
+		w := Warning{Code: WarningDeprecated, Message: "This route is deprecated.", Successor: successorLink(h)}
+		if len(messages) > 0 {
+			w.Message, messages = messages[0], messages[1:]
+		}
+		if t, err := http.ParseTime(h.Get("Sunset")); err == nil {
+			w.Sunset = &t
+		}
+		warnings = append(warnings, w)
+	}
+	for _, msg := range messages {
+		warnings = append(warnings, Warning{Code: "warning", Message: msg})
+	}
+	return warnings
+}
+
+// successorLink returns the target of the Link header with
+// rel="successor-version", if there is one.
+func successorLink(h http.Header) string {
+	for _, v := range h.Values("Link") {
+		for _, link := range strings.Split(v, ",") {
+			target, params, _ := strings.Cut(strings.TrimSpace(link), ";")
+			if strings.Contains(params, `rel="successor-version"`) {
+				return strings.Trim(strings.TrimSpace(target), "<>")
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,99 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gavinwade12/sendkey/pkg/api"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrClaimedByYou is returned by Claim when the entry was already
+	// claimed with the client's claim key: by another process sharing the
+	// session, or by an earlier attempt whose response was lost. The value
+	// isn't returned again.
+	ErrClaimedByYou = errors.New("the entry was already claimed by you")
+	// ErrClaimedBySomeoneElse is returned by Claim when the entry was
+	// already claimed with a different claim key.
+	ErrClaimedBySomeoneElse = errors.New("the entry was already claimed by someone else")
+)
+
+const (
+	retryAttempts = 4
+	retryBackoff  = 250 * time.Millisecond
+)
+
+// Claim claims the entry like ClaimEntry, but is safe to retry and to run
+// from several processes sharing a session. It sends the client's claim key
+// and retries failed requests and server errors a few times, backing off
+// between attempts, until ctx is done. The server only returns the value to
+// one claim, so a retry or a process that lost a race gets ErrClaimedByYou
+// instead, or ErrClaimedBySomeoneElse if it was claimed with another key.
+func (r *entriesResource) Claim(ctx context.Context, id uuid.UUID, nonce, secret string, answers ...string) (*api.ClaimEntryResponse, *api.Error, error) {
+	return r.ClaimWithOTP(ctx, id, nonce, secret, "", answers...)
+}
+
+// ClaimWithOTP is Claim for an entry that requires a one-time code. See
+// ClaimEntryWithOTP.
+func (r *entriesResource) ClaimWithOTP(ctx context.Context, id uuid.UUID, nonce, secret, otp string, answers ...string) (*api.ClaimEntryResponse, *api.Error, error) {
+	return r.retryClaim(ctx, id, nonce, secret, otp, false, answers)
+}
+
+// ClaimAcknowledged is ClaimWithOTP for an entry whose sender requires the
+// recipient to acknowledge its Acknowledgment text, and acknowledges it. The
+// text should be shown to the recipient first.
+func (r *entriesResource) ClaimAcknowledged(ctx context.Context, id uuid.UUID, nonce, secret, otp string, answers ...string) (*api.ClaimEntryResponse, *api.Error, error) {
+	return r.retryClaim(ctx, id, nonce, secret, otp, true, answers)
+}
+
+func (r *entriesResource) retryClaim(ctx context.Context, id uuid.UUID, nonce, secret, otp string, acknowledged bool, answers []string) (*api.ClaimEntryResponse, *api.Error, error) {
+	header := http.Header{"X-Claim-Key": {r.c.claimKey}}
+	backoff := retryBackoff
+	for attempt := 1; ; attempt++ {
+		res, e, err := r.claim(ctx, id, nonce, secret, otp, acknowledged, header, answers)
+		if attempt == retryAttempts || !retryable(ctx, e, err) {
+			if err == nil && res != nil {
+				err = claimedErr(res.ErrorCodes())
+			}
+			return res, e, err
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		}
+		backoff *= 2
+	}
+}
+
+// retryable reports whether a request failed in a way that's worth retrying:
+// a request that didn't get a response, or a server error. A retry of a
+// claim that succeeded gets ErrClaimedByYou, so it's never claimed twice.
+func retryable(ctx context.Context, e *api.Error, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if e != nil {
+		return e.StatusCode >= http.StatusInternalServerError || e.StatusCode == http.StatusTooManyRequests
+	}
+	// the HTTP client's errors, like a reset connection or a timeout
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
+
+func claimedErr(codes []string) error {
+	for _, code := range codes {
+		switch code {
+		case "entry_claimed_by_you":
+			return ErrClaimedByYou
+		case "entry_claimed":
+			return ErrClaimedBySomeoneElse
+		}
+	}
+	return nil
+}
@@ -2,23 +2,51 @@ package client
 
 import (
 	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/gavinwade12/sendkey/pkg/api"
 	"github.com/google/uuid"
 )
 
+// DefaultHTTPClient is used by clients without their own HTTP client, proxy,
+// or TLS config. It uses the proxy from the environment, including
+// ALL_PROXY.
 var DefaultHTTPClient = &http.Client{
-	Timeout: time.Second * 10,
+	Timeout:   time.Second * 10,
+	Transport: newTransport(proxyFromEnvironment, nil),
 }
 
 type Client struct {
 	baseURL        string
 	client         *http.Client
 	defaultHeaders map[string][]string
+	proxy          func(*http.Request) (*url.URL, error)
+	tlsConfig      *tls.Config
+	sandbox        bool
+	strictJSON     bool
+	// claimKey is sent with claims made through Claim; see WithClaimKey.
+	claimKey string
+	// onWarning is called with the warnings on responses; see
+	// WithWarningHandler.
+	onWarning func(api.Warning)
+	// onRateLimit is called with the rate limit on responses; see
+	// WithRateLimitHandler.
+	onRateLimit func(RateLimitInfo)
+
+	mu        sync.Mutex
+	rateLimit *RateLimitInfo
 
 	accessToken   string
 	refreshToken  string
@@ -50,6 +78,54 @@ var WithSession = func(userID uuid.UUID, refreshToken, accessToken string) Optio
 	}
 }
 
+// WithSandbox sends every request to the server's sandbox, where entries
+// expire faster and nothing is emailed, for testing integrations.
+var WithSandbox = func() Option {
+	return func(c *Client) {
+		c.sandbox = true
+	}
+}
+
+// WithStrictJSON asks the server to reject request bodies with fields it
+// doesn't know, so a client sending a misspelled field finds out instead of
+// having it ignored.
+var WithStrictJSON = func() Option {
+	return func(c *Client) {
+		c.strictJSON = true
+	}
+}
+
+// WithClaimKey sets the key the client's claims are made with, so a claim
+// retried or raced by another client with the same key is reported as
+// already claimed by you rather than by someone else. It defaults to one
+// derived from the session's refresh token, so processes sharing a session
+// share it, or a random key for clients without a session.
+var WithClaimKey = func(key string) Option {
+	return func(c *Client) {
+		c.claimKey = key
+	}
+}
+
+// WithWarningHandler calls f with each warning the server sends, like a
+// route being deprecated, so they can be shown to whoever can update the
+// client. Warnings are ignored otherwise.
+var WithWarningHandler = func(f func(api.Warning)) Option {
+	return func(c *Client) {
+		c.onWarning = f
+	}
+}
+
+// WithRateLimitHandler calls f with the rate limit on each response that
+// has one, so callers can pace themselves; see RateLimitInfo. The latest is
+// also returned by Client.RateLimit.
+var WithRateLimitHandler = func(f func(RateLimitInfo)) Option {
+	return func(c *Client) {
+		c.onRateLimit = f
+	}
+}
+
+// NewClient returns a client for the API at baseURL, which includes the
+// version's path, e.g. "https://api.sendkey.me/v1"; see api.BasePath.
 func NewClient(baseURL string, opts ...Option) *Client {
 	client := &Client{
 		baseURL: baseURL,
@@ -58,9 +134,22 @@ func NewClient(baseURL string, opts ...Option) *Client {
 		opt(client)
 	}
 
+	if client.client == nil && (client.proxy != nil || client.tlsConfig != nil) {
+		proxy := client.proxy
+		if proxy == nil {
+			proxy = proxyFromEnvironment
+		}
+		client.client = &http.Client{
+			Timeout:   DefaultHTTPClient.Timeout,
+			Transport: newTransport(proxy, client.tlsConfig),
+		}
+	}
 	if client.client == nil {
 		client.client = DefaultHTTPClient
 	}
+	if client.claimKey == "" {
+		client.claimKey = defaultClaimKey(client.refreshToken)
+	}
 
 	client.Users = &usersResource{client}
 	client.Entries = &entriesResource{client}
@@ -68,8 +157,20 @@ func NewClient(baseURL string, opts ...Option) *Client {
 	return client
 }
 
+// Do sends a request to the path, relative to the base URL, with the
+// client's headers and session, for operations the client has no method for.
+// The body is JSON, if there is one. The caller closes the response's body.
+func (c *Client) Do(method, path string, body io.ReadSeeker) (*http.Response, error) {
+	return c.doRequest(method, path, body)
+}
+
 func (c *Client) doRequest(method, path string, body io.ReadSeeker) (*http.Response, error) {
-	req, err := http.NewRequest(method, c.baseURL+path, body)
+	return c.doRequestContext(context.Background(), method, path, body, nil)
+}
+
+// doRequestContext is doRequest with a context and extra headers.
+func (c *Client) doRequestContext(ctx context.Context, method, path string, body io.ReadSeeker, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
 	if err != nil {
 		return nil, err
 	}
@@ -83,16 +184,36 @@ func (c *Client) doRequest(method, path string, body io.ReadSeeker) (*http.Respo
 		}
 	}
 
+	if c.sandbox {
+		req.Header.Set("X-Sandbox", "true")
+	}
+	if c.strictJSON {
+		req.Header.Set("X-Strict-JSON", "true")
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
 	if c.accessToken != "" && path != "/token" && path != "/login" {
 		req.Header.Set("Authorization", "Bearer "+c.accessToken)
 	}
 
-	res, err := c.client.Do(req)
+	client := c.client
+	if req.Header.Get("Accept") == "text/event-stream" {
+		// a stream stays open until the caller's context is done, so the
+		// client's timeout, which covers reading the body, doesn't apply
+		streaming := *client
+		streaming.Timeout = 0
+		client = &streaming
+	}
+	res, err := client.Do(req)
 	if err != nil {
 		return nil, err
 	}
 	if res.StatusCode != http.StatusUnauthorized || c.refreshToken == "" ||
 		path == "/token" || path == "/login" {
+		c.warn(res)
+		c.noteRateLimit(res)
 		return res, nil
 	}
 
@@ -101,18 +222,36 @@ func (c *Client) doRequest(method, path string, body io.ReadSeeker) (*http.Respo
 		return nil, err
 	}
 	if e != nil {
-		return nil, fmt.Errorf("fetching access token: [%d]: %s", e.StatusCode, e.Message)
+		// wrapped, so callers can still branch on its code with errors.As
+		return nil, fmt.Errorf("fetching access token: [%d]: %w", e.StatusCode, *e)
 	}
 
-	return c.client.Do(req)
+	res, err = client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	c.warn(res)
+	c.noteRateLimit(res)
+	return res, nil
+}
+
+// warn passes the response's warnings to the warning handler, if there is
+// one.
+func (c *Client) warn(res *http.Response) {
+	if c.onWarning == nil {
+		return
+	}
+	for _, w := range api.ParseWarnings(res.Header) {
+		c.onWarning(w)
+	}
 }
 
-func (c *Client) refreshAccessToken() (*Error, error) {
+func (c *Client) refreshAccessToken() (*api.Error, error) {
 	const path = `/token`
 
-	jr, err := jsonReader(map[string]string{
-		"userId":       c.currentUserID.String(),
-		"refreshToken": c.refreshToken,
+	jr, err := jsonReader(api.RefreshTokenRequest{
+		UserID:       c.currentUserID,
+		RefreshToken: c.refreshToken,
 	})
 	if err != nil {
 		return nil, err
@@ -122,21 +261,49 @@ func (c *Client) refreshAccessToken() (*Error, error) {
 	if err != nil {
 		return nil, err
 	}
-	if res.StatusCode != http.StatusOK {
+	if res.StatusCode > http.StatusBadRequest {
 		return c.parseErrorResponse(res)
 	}
 	defer res.Body.Close()
 
-	var token Token
-	err = json.NewDecoder(res.Body).Decode(&token)
+	var response api.RefreshTokenResponse
+	err = json.NewDecoder(res.Body).Decode(&response)
 	if err != nil {
 		return nil, err
 	}
+	if !response.Success {
+		e := &api.Error{
+			UserID:     c.currentUserID,
+			StatusCode: res.StatusCode,
+			Message:    strings.Join(response.Messages(), " "),
+			RequestID:  res.Header.Get("X-Request-ID"),
+		}
+		if codes := response.ErrorCodes(); len(codes) > 0 {
+			e.Code = codes[0]
+		}
+		return e, nil
+	}
 
-	c.accessToken = token.Token
+	c.accessToken = response.AccessToken.Token
 	return nil, nil
 }
 
+// defaultClaimKey derives a claim key from the refresh token, or returns a
+// random one if there isn't a session. It's hashed so the token itself isn't
+// sent.
+func defaultClaimKey(refreshToken string) string {
+	if refreshToken != "" {
+		sum := sha256.Sum256([]byte("sendkey-claim-key|" + refreshToken))
+		return hex.EncodeToString(sum[:])
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
 func jsonReader(value interface{}) (io.ReadSeeker, error) {
 	b, err := json.Marshal(value)
 	if err != nil {
@@ -146,25 +313,19 @@ func jsonReader(value interface{}) (io.ReadSeeker, error) {
 	return bytes.NewReader(b), nil
 }
 
-type Error struct {
-	UserID     uuid.UUID `json:"userId"`
-	StatusCode int       `json:"statusCode"`
-	Message    string    `json:"message"`
-}
-
-func (c *Client) parseErrorResponse(res *http.Response) (*Error, error) {
+// parseErrorResponse decodes an error response. The request ID is taken from
+// the X-Request-ID header if the body doesn't have one, e.g. from a proxy.
+func (c *Client) parseErrorResponse(res *http.Response) (*api.Error, error) {
 	defer res.Body.Close()
 
-	var e Error
+	var e api.Error
 	err := json.NewDecoder(res.Body).Decode(&e)
 	if err != nil {
 		return nil, fmt.Errorf("decoding error response [status: %d]: %w ", res.StatusCode, err)
 	}
+	if e.RequestID == "" {
+		e.RequestID = res.Header.Get("X-Request-ID")
+	}
 
 	return &e, nil
 }
-
-type Token struct {
-	Token   string `json:"token"`
-	Expires int64  `json:"expires"`
-}
@@ -0,0 +1,56 @@
+package client
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+
+	"github.com/gavinwade12/sendkey"
+	"golang.org/x/crypto/argon2"
+)
+
+// digestArgon2idHMACSHA256 derives the digest key from the secret with the
+// same Argon2id cost as end-to-end entries and MACs the value with
+// HMAC-SHA256.
+const digestArgon2idHMACSHA256 = "argon2id-hmac-sha256"
+
+// ErrDigestMismatch is returned when a claimed value doesn't match the digest
+// its sender's client computed, meaning it was corrupted after it was sent.
+var ErrDigestMismatch = errors.New("the value doesn't match the sender's digest")
+
+// NewDigest returns a keyed digest of the value for the recipient's client to
+// check it against. The key is derived from the secret, so the server can't
+// forge it for end-to-end entries.
+func NewDigest(value, secret string) (*sendkey.ValueDigest, error) {
+	salt := make([]byte, e2eSaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	return &sendkey.ValueDigest{
+		Algorithm: digestArgon2idHMACSHA256,
+		Salt:      salt,
+		MAC:       digestMAC(value, secret, salt),
+	}, nil
+}
+
+// VerifyDigest returns ErrDigestMismatch if the value doesn't match the
+// digest.
+func VerifyDigest(d sendkey.ValueDigest, value, secret string) error {
+	if d.Algorithm != digestArgon2idHMACSHA256 {
+		return fmt.Errorf("unsupported digest algorithm %q", d.Algorithm)
+	}
+	if !hmac.Equal(digestMAC(value, secret, d.Salt), d.MAC) {
+		return ErrDigestMismatch
+	}
+	return nil
+}
+
+func digestMAC(value, secret string, salt []byte) []byte {
+	key := argon2.IDKey([]byte(secret), salt, 3, 64*1024, 4, e2eKeyLength)
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(value))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,99 @@
+package client
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"fmt"
+
+	"github.com/gavinwade12/sendkey"
+	"github.com/gavinwade12/sendkey/pkg/api"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// The ciphers end-to-end entries can be sealed with.
+const (
+	CipherAESGCM            = "aes-gcm"
+	CipherXChaCha20Poly1305 = "xchacha20-poly1305"
+)
+
+const (
+	e2eKDFArgon2id = "argon2id"
+	e2eSaltLength  = 16
+	e2eKeyLength   = 32
+)
+
+// Seal encrypts the value for an end-to-end entry with a key derived from the
+// secret using Argon2id. The server only ever sees the sealed value, never the
+// value or the secret. An empty cipherName uses XChaCha20-Poly1305.
+func Seal(value, secret, cipherName string) (*api.SealedValue, error) {
+	if cipherName == "" {
+		cipherName = CipherXChaCha20Poly1305
+	}
+
+	salt := make([]byte, e2eSaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	kdf := sendkey.EntryKDF{
+		Algorithm: e2eKDFArgon2id,
+		Salt:      salt,
+		Time:      3,
+		MemoryKiB: 64 * 1024,
+		Threads:   4,
+	}
+
+	aead, err := e2eAEAD(cipherName, secret, kdf)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return &api.SealedValue{
+		Ciphertext: aead.Seal(nil, nonce, []byte(value), nil),
+		Nonce:      nonce,
+		Cipher:     cipherName,
+		KDF:        kdf,
+	}, nil
+}
+
+// Open decrypts a sealed value with the secret it was sealed with.
+func Open(v api.SealedValue, secret string) (string, error) {
+	aead, err := e2eAEAD(v.Cipher, secret, v.KDF)
+	if err != nil {
+		return "", err
+	}
+	if len(v.Nonce) != aead.NonceSize() {
+		return "", fmt.Errorf("invalid nonce size")
+	}
+
+	value, err := aead.Open(nil, v.Nonce, v.Ciphertext, nil)
+	if err != nil {
+		return "", fmt.Errorf("invalid secret")
+	}
+	return string(value), nil
+}
+
+func e2eAEAD(cipherName, secret string, kdf sendkey.EntryKDF) (cipher.AEAD, error) {
+	if kdf.Algorithm != e2eKDFArgon2id {
+		return nil, fmt.Errorf("unsupported kdf %q", kdf.Algorithm)
+	}
+	key := argon2.IDKey([]byte(secret), kdf.Salt, kdf.Time, kdf.MemoryKiB, kdf.Threads, e2eKeyLength)
+
+	switch cipherName {
+	case CipherAESGCM:
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		return cipher.NewGCM(block)
+	case CipherXChaCha20Poly1305:
+		return chacha20poly1305.NewX(key)
+	default:
+		return nil, fmt.Errorf("unsupported cipher %q", cipherName)
+	}
+}
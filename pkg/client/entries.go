@@ -1,11 +1,15 @@
 package client
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
+	"time"
 
 	"github.com/gavinwade12/sendkey"
+	"github.com/gavinwade12/sendkey/pkg/api"
 	"github.com/google/uuid"
 )
 
@@ -13,22 +17,68 @@ type entriesResource struct {
 	c *Client
 }
 
-type CreateEntryRequest struct {
-	Name            string    `json:"name"`
-	SenderID        uuid.UUID `json:"senderId"`
-	SendToEmail     string    `json:"sendToEmail"`
-	Value           string    `json:"value"`
-	Secret          string    `json:"secret"`
-	DurationMinutes int       `json:"duration"`
+// CreateEntry creates the entry. A digest of the value is sent along with it
+// unless the model already has one, so the recipient's client can check the
+// value when it's claimed. End-to-end entries need the digest set by the
+// caller since the value isn't in the model.
+func (r *entriesResource) CreateEntry(model api.CreateEntryRequest) (*api.CreateEntryResponse, *api.Error, error) {
+	model, err := withDigest(model)
+	if err != nil {
+		return nil, nil, err
+	}
+	return r.create(context.Background(), model, nil)
+}
+
+// Create creates the entry like CreateEntry, but is safe to retry. It sends
+// the key as the Idempotency-Key and retries failed requests and server
+// errors a few times, backing off between attempts, until ctx is done. The
+// server answers a retry of a request that created the entry with the same
+// response, so it's only created once. An empty key uses a random one. To
+// call Create again with the same key after it returns an error, set the
+// model's Digest first, or its salt will make it a different request.
+func (r *entriesResource) Create(ctx context.Context, model api.CreateEntryRequest, key string) (*api.CreateEntryResponse, *api.Error, error) {
+	if key == "" {
+		key = uuid.New().String()
+	}
+	// the digest is salted, so it's computed once for every attempt to send
+	// the same request
+	model, err := withDigest(model)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	header := http.Header{"Idempotency-Key": {key}}
+	backoff := retryBackoff
+	for attempt := 1; ; attempt++ {
+		res, e, err := r.create(ctx, model, header)
+		inUse := e != nil && e.Code == "idempotency_key_in_use"
+		if attempt == retryAttempts || !(inUse || retryable(ctx, e, err)) {
+			return res, e, err
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		}
+		backoff *= 2
+	}
 }
 
-type CreateEntryResponse struct {
-	Success bool           `json:"success"`
-	Errors  []string       `json:"errors"`
-	Entry   *sendkey.Entry `json:"entry"`
+// withDigest adds a digest of the value to the model unless it already has
+// one or can't.
+func withDigest(model api.CreateEntryRequest) (api.CreateEntryRequest, error) {
+	if model.Digest == nil && model.EndToEnd == nil && model.Value != "" && model.Secret != "" {
+		digest, err := NewDigest(model.Value, model.Secret)
+		if err != nil {
+			return model, fmt.Errorf("computing digest: %w", err)
+		}
+		model.Digest = digest
+	}
+	return model, nil
 }
 
-func (r *entriesResource) CreateEntry(model CreateEntryRequest) (*CreateEntryResponse, *Error, error) {
+func (r *entriesResource) create(ctx context.Context, model api.CreateEntryRequest, header http.Header) (*api.CreateEntryResponse, *api.Error, error) {
 	const path = `/entries`
 
 	jr, err := jsonReader(model)
@@ -36,6 +86,35 @@ func (r *entriesResource) CreateEntry(model CreateEntryRequest) (*CreateEntryRes
 		return nil, nil, err
 	}
 
+	res, err := r.c.doRequestContext(ctx, http.MethodPost, path, jr, header)
+	if err != nil {
+		return nil, nil, err
+	}
+	if res.StatusCode > http.StatusBadRequest {
+		e, err := r.c.parseErrorResponse(res)
+		return nil, e, err
+	}
+	defer res.Body.Close()
+
+	var response api.CreateEntryResponse
+	err = json.NewDecoder(res.Body).Decode(&response)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &response, nil, nil
+}
+
+// Generate generates a random password or passphrase on the server, creating
+// an entry with it if the request has one.
+func (r *entriesResource) Generate(model api.GenerateRequest) (*api.GenerateResponse, *api.Error, error) {
+	const path = `/generate`
+
+	jr, err := jsonReader(model)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	res, err := r.c.doRequest(http.MethodPost, path, jr)
 	if err != nil {
 		return nil, nil, err
@@ -46,16 +125,43 @@ func (r *entriesResource) CreateEntry(model CreateEntryRequest) (*CreateEntryRes
 	}
 	defer res.Body.Close()
 
-	var response CreateEntryResponse
+	var response api.GenerateResponse
 	err = json.NewDecoder(res.Body).Decode(&response)
+	if err != nil {
+		return nil, nil, fmt.Errorf("decoding response: %w", err)
+	}
+
+	return &response, nil, nil
+}
+
+// FindEntry returns the entry, or nil if it doesn't exist or the nonce is wrong.
+func (r *entriesResource) FindEntry(id uuid.UUID, nonce string) (*sendkey.Entry, *api.Error, error) {
+	path := fmt.Sprintf("/entries/%s?%s", id.String(), url.Values{"nonce": {nonce}}.Encode())
+
+	res, err := r.c.doRequest(http.MethodGet, path, nil)
 	if err != nil {
 		return nil, nil, err
 	}
+	if res.StatusCode == http.StatusNotFound {
+		res.Body.Close()
+		return nil, nil, nil
+	}
+	if res.StatusCode >= http.StatusBadRequest {
+		e, err := r.c.parseErrorResponse(res)
+		return nil, e, err
+	}
+	defer res.Body.Close()
+
+	var response sendkey.Entry
+	err = json.NewDecoder(res.Body).Decode(&response)
+	if err != nil {
+		return nil, nil, fmt.Errorf("decoding response: %w", err)
+	}
 
 	return &response, nil, nil
 }
 
-func (r *entriesResource) ListEntries() ([]sendkey.Entry, *Error, error) {
+func (r *entriesResource) ListEntries() ([]sendkey.Entry, *api.Error, error) {
 	path := fmt.Sprintf("/users/%s/entries", r.c.currentUserID.String())
 
 	res, err := r.c.doRequest(http.MethodGet, path, nil)
@@ -76,3 +182,239 @@ func (r *entriesResource) ListEntries() ([]sendkey.Entry, *Error, error) {
 
 	return response, nil, nil
 }
+
+// SetPinned pins one of the current user's entries to the top of their
+// listings, or unpins it.
+func (r *entriesResource) SetPinned(id uuid.UUID, pinned bool) (*api.EntryFlagsResponse, *api.Error, error) {
+	return r.setFlag(id, "pin", pinned)
+}
+
+// SetFavorite marks one of the current user's entries as a favorite, listed
+// after the pinned entries, or unmarks it.
+func (r *entriesResource) SetFavorite(id uuid.UUID, favorite bool) (*api.EntryFlagsResponse, *api.Error, error) {
+	return r.setFlag(id, "favorite", favorite)
+}
+
+func (r *entriesResource) setFlag(id uuid.UUID, flag string, on bool) (*api.EntryFlagsResponse, *api.Error, error) {
+	path := fmt.Sprintf("/entries/%s/%s", id.String(), flag)
+	method := http.MethodPut
+	if !on {
+		method = http.MethodDelete
+	}
+
+	res, err := r.c.doRequest(method, path, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if res.StatusCode > http.StatusBadRequest {
+		e, err := r.c.parseErrorResponse(res)
+		return nil, e, err
+	}
+	defer res.Body.Close()
+
+	var response api.EntryFlagsResponse
+	err = json.NewDecoder(res.Body).Decode(&response)
+	if err != nil {
+		return nil, nil, fmt.Errorf("decoding response: %w", err)
+	}
+
+	return &response, nil, nil
+}
+
+// Usage returns what the current user has sent against their quota.
+func (r *entriesResource) Usage() (*api.Usage, *api.Error, error) {
+	const path = `/users/me/usage`
+
+	res, err := r.c.doRequest(http.MethodGet, path, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if res.StatusCode >= http.StatusBadRequest {
+		e, err := r.c.parseErrorResponse(res)
+		return nil, e, err
+	}
+	defer res.Body.Close()
+
+	var response api.Usage
+	err = json.NewDecoder(res.Body).Decode(&response)
+	if err != nil {
+		return nil, nil, fmt.Errorf("decoding response: %w", err)
+	}
+
+	return &response, nil, nil
+}
+
+// ClaimEntry claims the entry. If the sender set any challenge questions, an
+// answer must be given for each, in order.
+//
+// End-to-end entries are claimed with an empty secret so it's never sent to
+// the server; the response's Sealed value is opened with the secret instead.
+func (r *entriesResource) ClaimEntry(id uuid.UUID, nonce, secret string, answers ...string) (*api.ClaimEntryResponse, *api.Error, error) {
+	return r.ClaimEntryWithOTP(id, nonce, secret, "", answers...)
+}
+
+// ClaimEntryWithOTP claims an entry that requires a one-time code. Claiming
+// without the code emails it to the recipient and sets OTPSent.
+//
+// If the sender sent a digest, the value is checked against it. The entry has
+// been claimed either way, so the response is returned along with
+// ErrDigestMismatch if it doesn't match. Sealed values are checked by the
+// caller after opening them; see VerifyDigest.
+func (r *entriesResource) ClaimEntryWithOTP(id uuid.UUID, nonce, secret, otp string, answers ...string) (*api.ClaimEntryResponse, *api.Error, error) {
+	return r.claim(context.Background(), id, nonce, secret, otp, false, nil, answers)
+}
+
+func (r *entriesResource) claim(ctx context.Context, id uuid.UUID, nonce, secret, otp string, acknowledged bool, header http.Header, answers []string) (*api.ClaimEntryResponse, *api.Error, error) {
+	q := url.Values{}
+	q.Set("nonce", nonce)
+	if secret != "" {
+		q.Set("secret", secret)
+	}
+	if otp != "" {
+		q.Set("otp", otp)
+	}
+	if acknowledged {
+		q.Set("acknowledged", "true")
+	}
+	for _, a := range answers {
+		q.Add("answer", a)
+	}
+	path := fmt.Sprintf("/entries/%s/value?%s", id.String(), q.Encode())
+
+	res, err := r.c.doRequestContext(ctx, http.MethodGet, path, nil, header)
+	if err != nil {
+		return nil, nil, err
+	}
+	if res.StatusCode > http.StatusBadRequest {
+		e, err := r.c.parseErrorResponse(res)
+		return nil, e, err
+	}
+	defer res.Body.Close()
+
+	var response api.ClaimEntryResponse
+	err = json.NewDecoder(res.Body).Decode(&response)
+	if err != nil {
+		return nil, nil, fmt.Errorf("decoding response: %w", err)
+	}
+	if response.Value != nil && response.Digest != nil && secret != "" {
+		if err = VerifyDigest(*response.Digest, *response.Value, secret); err != nil {
+			return &response, nil, err
+		}
+	}
+
+	return &response, nil, nil
+}
+
+// ResendLink issues a new claim link for one of the user's entries, which
+// stops its earlier links from working. A ttl of 0 uses the server's default.
+func (r *entriesResource) ResendLink(id uuid.UUID, ttl time.Duration) (*api.ResendLinkResponse, *api.Error, error) {
+	path := fmt.Sprintf("/entries/%s/links", id.String())
+
+	jr, err := jsonReader(api.ResendLinkRequest{TTLSeconds: int(ttl / time.Second)})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	res, err := r.c.doRequest(http.MethodPost, path, jr)
+	if err != nil {
+		return nil, nil, err
+	}
+	if res.StatusCode > http.StatusBadRequest {
+		e, err := r.c.parseErrorResponse(res)
+		return nil, e, err
+	}
+	defer res.Body.Close()
+
+	var response api.ResendLinkResponse
+	err = json.NewDecoder(res.Body).Decode(&response)
+	if err != nil {
+		return nil, nil, fmt.Errorf("decoding response: %w", err)
+	}
+
+	return &response, nil, nil
+}
+
+// DelegateClaim makes a token for one of the user's entries that a bot or
+// service can claim it with using ClaimDelegated, without the secret. A ttl
+// of 0 uses the server's default.
+func (r *entriesResource) DelegateClaim(id uuid.UUID, secret string, ttl time.Duration) (*api.DelegateClaimResponse, *api.Error, error) {
+	path := fmt.Sprintf("/entries/%s/delegations", id.String())
+
+	jr, err := jsonReader(api.DelegateClaimRequest{Secret: secret, TTLSeconds: int(ttl / time.Second)})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	res, err := r.c.doRequest(http.MethodPost, path, jr)
+	if err != nil {
+		return nil, nil, err
+	}
+	if res.StatusCode > http.StatusBadRequest {
+		e, err := r.c.parseErrorResponse(res)
+		return nil, e, err
+	}
+	defer res.Body.Close()
+
+	var response api.DelegateClaimResponse
+	err = json.NewDecoder(res.Body).Decode(&response)
+	if err != nil {
+		return nil, nil, fmt.Errorf("decoding response: %w", err)
+	}
+
+	return &response, nil, nil
+}
+
+// ClaimDelegated claims an entry with a token from DelegateClaim.
+func (r *entriesResource) ClaimDelegated(id uuid.UUID, token string) (*api.ClaimEntryResponse, *api.Error, error) {
+	path := fmt.Sprintf("/entries/%s/delegated-claim", id.String())
+
+	jr, err := jsonReader(api.DelegatedClaimRequest{Token: token})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	res, err := r.c.doRequest(http.MethodPost, path, jr)
+	if err != nil {
+		return nil, nil, err
+	}
+	if res.StatusCode > http.StatusBadRequest {
+		e, err := r.c.parseErrorResponse(res)
+		return nil, e, err
+	}
+	defer res.Body.Close()
+
+	var response api.ClaimEntryResponse
+	err = json.NewDecoder(res.Body).Decode(&response)
+	if err != nil {
+		return nil, nil, fmt.Errorf("decoding response: %w", err)
+	}
+
+	return &response, nil, nil
+}
+
+func (r *entriesResource) DeferClaim(id uuid.UUID, model api.DeferClaimRequest) (*api.DeferClaimResponse, *api.Error, error) {
+	path := fmt.Sprintf("/entries/%s/defer", id.String())
+
+	jr, err := jsonReader(model)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	res, err := r.c.doRequest(http.MethodPost, path, jr)
+	if err != nil {
+		return nil, nil, err
+	}
+	if res.StatusCode > http.StatusBadRequest {
+		e, err := r.c.parseErrorResponse(res)
+		return nil, e, err
+	}
+	defer res.Body.Close()
+
+	var response api.DeferClaimResponse
+	err = json.NewDecoder(res.Body).Decode(&response)
+	if err != nil {
+		return nil, nil, fmt.Errorf("decoding response: %w", err)
+	}
+
+	return &response, nil, nil
+}
@@ -0,0 +1,96 @@
+package client
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gavinwade12/sendkey"
+	"github.com/gavinwade12/sendkey/pkg/api"
+	"github.com/google/uuid"
+)
+
+// ReleaseEscrow releases an entry's escrow for the break-glass procedure,
+// recording the reason in the audit log. It's for admins, and what's
+// released is still encrypted to the org's escrow key; see OpenEscrow.
+func (r *entriesResource) ReleaseEscrow(id uuid.UUID, reason string) (*api.ReleaseEscrowResponse, *api.Error, error) {
+	path := fmt.Sprintf("/admin/escrows/%s/release", id.String())
+
+	jr, err := jsonReader(api.ReleaseEscrowRequest{Reason: reason})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	res, err := r.c.doRequest(http.MethodPost, path, jr)
+	if err != nil {
+		return nil, nil, err
+	}
+	if res.StatusCode > http.StatusBadRequest {
+		e, err := r.c.parseErrorResponse(res)
+		return nil, e, err
+	}
+	defer res.Body.Close()
+
+	var response api.ReleaseEscrowResponse
+	err = json.NewDecoder(res.Body).Decode(&response)
+	if err != nil {
+		return nil, nil, fmt.Errorf("decoding response: %w", err)
+	}
+
+	return &response, nil, nil
+}
+
+// ParseEscrowPrivateKey parses the org's PEM-encoded RSA escrow private key,
+// in PKCS #8 or PKCS #1 form.
+func ParseEscrowPrivateKey(b []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, errors.New("escrow key isn't PEM-encoded")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("escrow key isn't an RSA key")
+	}
+	return rsaKey, nil
+}
+
+// OpenEscrow decrypts an escrowed value with the org's escrow private key.
+// It's meant to be done offline, wherever the key's kept.
+func OpenEscrow(e sendkey.EntryEscrow, key *rsa.PrivateKey) (string, error) {
+	valueKey, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, key, e.WrappedKey, nil)
+	if err != nil {
+		return "", fmt.Errorf("unwrapping the escrow's key; is it for key %s?", e.KeyID)
+	}
+	block, err := aes.NewCipher(valueKey)
+	if err != nil {
+		return "", err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	if len(e.Nonce) != aead.NonceSize() {
+		return "", fmt.Errorf("invalid nonce size")
+	}
+
+	value, err := aead.Open(nil, e.Nonce, e.Ciphertext, e.EntryID[:])
+	if err != nil {
+		return "", fmt.Errorf("the escrow is corrupt or isn't for entry %s", e.EntryID)
+	}
+	return string(value), nil
+}
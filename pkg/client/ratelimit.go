@@ -0,0 +1,62 @@
+package client
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RateLimitInfo is the server's rate limit on the route a response came from,
+// read from its RateLimit headers, so callers can slow down before they're
+// refused with 429.
+type RateLimitInfo struct {
+	// Limit is how many requests can be made at once.
+	Limit int
+	// Remaining is how many more can be made now.
+	Remaining int
+	// Reset is when the limit has fully replenished.
+	Reset time.Time
+}
+
+// ParseRateLimit returns the rate limit in the response's headers, or nil if
+// it doesn't have one, like when the server doesn't limit the route.
+func ParseRateLimit(res *http.Response) *RateLimitInfo {
+	limit, err := strconv.Atoi(res.Header.Get("RateLimit-Limit"))
+	if err != nil {
+		return nil
+	}
+	remaining, err := strconv.Atoi(res.Header.Get("RateLimit-Remaining"))
+	if err != nil {
+		return nil
+	}
+	// the reset is in seconds from when the response was sent
+	reset, _ := strconv.Atoi(res.Header.Get("RateLimit-Reset"))
+	return &RateLimitInfo{
+		Limit:     limit,
+		Remaining: remaining,
+		Reset:     time.Now().Add(time.Duration(reset) * time.Second),
+	}
+}
+
+// RateLimit returns the rate limit on the latest response that had one, or
+// nil if none has.
+func (c *Client) RateLimit() *RateLimitInfo {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.rateLimit
+}
+
+// noteRateLimit keeps the response's rate limit, if it has one, and passes
+// it to the rate limit handler.
+func (c *Client) noteRateLimit(res *http.Response) {
+	info := ParseRateLimit(res)
+	if info == nil {
+		return
+	}
+	c.mu.Lock()
+	c.rateLimit = info
+	c.mu.Unlock()
+	if c.onRateLimit != nil {
+		c.onRateLimit(*info)
+	}
+}
@@ -0,0 +1,86 @@
+package client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+// TLSOptions configures how the client verifies self-hosted servers, such as
+// ones with certificates from a private CA.
+type TLSOptions struct {
+	// CAFile is a PEM bundle of CA certificates trusted in addition to the
+	// system's.
+	CAFile string
+	// CertFile and KeyFile are a PEM client certificate and key for servers
+	// that require mutual TLS.
+	CertFile string
+	KeyFile  string
+	// MinVersion is the lowest TLS version allowed: "1.0", "1.1", "1.2", or
+	// "1.3". It defaults to 1.2.
+	MinVersion string
+	// InsecureSkipVerify doesn't verify the server's certificate, which lets
+	// anyone on the network read and change the requests, including the
+	// secrets in them. Only use it for testing.
+	InsecureSkipVerify bool
+}
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// NewTLSConfig returns the TLS config for the options.
+func NewTLSConfig(o TLSOptions) (*tls.Config, error) {
+	cfg := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: o.InsecureSkipVerify,
+	}
+
+	if o.MinVersion != "" {
+		v, ok := tlsVersions[o.MinVersion]
+		if !ok {
+			return nil, fmt.Errorf("unsupported TLS version %q", o.MinVersion)
+		}
+		cfg.MinVersion = v
+	}
+
+	if o.CAFile != "" {
+		pem, err := os.ReadFile(o.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading CA bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", o.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+
+	if o.CertFile != "" || o.KeyFile != "" {
+		if o.CertFile == "" || o.KeyFile == "" {
+			return nil, fmt.Errorf("a client certificate and key must be used together")
+		}
+		cert, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return cfg, nil
+}
+
+// WithTLSConfig makes the client's requests with the TLS config, e.g. from
+// NewTLSConfig. It's ignored if a client is set with WithHTTPClient.
+var WithTLSConfig = func(cfg *tls.Config) Option {
+	return func(c *Client) {
+		c.tlsConfig = cfg
+	}
+}
@@ -0,0 +1,96 @@
+package client
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// WithProxy sends the client's requests through the proxy instead of the one
+// from the environment. The scheme can be http, https, or socks5.
+var WithProxy = func(proxyURL *url.URL) Option {
+	return func(c *Client) {
+		c.proxy = http.ProxyURL(proxyURL)
+	}
+}
+
+// ParseProxy parses a proxy URL for WithProxy, checking its scheme.
+func ParseProxy(proxyURL string) (*url.URL, error) {
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %w", err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q; use http, https, or socks5", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("the proxy URL %q has no host", proxyURL)
+	}
+	return u, nil
+}
+
+// newTransport returns a transport using the proxy and TLS config, either of
+// which can be nil.
+func newTransport(proxy func(*http.Request) (*url.URL, error), tlsConfig *tls.Config) *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	if proxy != nil {
+		t.Proxy = proxy
+	}
+	if tlsConfig != nil {
+		t.TLSClientConfig = tlsConfig
+	}
+	return t
+}
+
+// proxyFromEnvironment is http.ProxyFromEnvironment, falling back to
+// ALL_PROXY, which curl and other tools honor but net/http doesn't. Hosts in
+// NO_PROXY aren't sent through ALL_PROXY either.
+func proxyFromEnvironment(req *http.Request) (*url.URL, error) {
+	u, err := http.ProxyFromEnvironment(req)
+	if u != nil || err != nil {
+		return u, err
+	}
+	if os.Getenv("HTTPS_PROXY") != "" || os.Getenv("https_proxy") != "" ||
+		os.Getenv("HTTP_PROXY") != "" || os.Getenv("http_proxy") != "" {
+		// the request was excluded by NO_PROXY
+		return nil, nil
+	}
+
+	all := os.Getenv("ALL_PROXY")
+	if all == "" {
+		all = os.Getenv("all_proxy")
+	}
+	if all == "" || noProxy(req.URL.Hostname()) {
+		return nil, nil
+	}
+	return ParseProxy(all)
+}
+
+// noProxy reports whether the host matches NO_PROXY, as a domain or one of its
+// subdomains.
+func noProxy(host string) bool {
+	list := os.Getenv("NO_PROXY")
+	if list == "" {
+		list = os.Getenv("no_proxy")
+	}
+	if ip := net.ParseIP(host); host == "localhost" || ip != nil && ip.IsLoopback() {
+		return true
+	}
+
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(entry), "."))
+		if entry == "*" {
+			return true
+		}
+		if entry != "" && (strings.EqualFold(host, entry) || strings.HasSuffix(strings.ToLower(host), "."+entry)) {
+			return true
+		}
+	}
+	return false
+}
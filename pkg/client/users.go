@@ -3,28 +3,16 @@ package client
 import (
 	"encoding/json"
 	"net/http"
+	"net/url"
 
-	"github.com/gavinwade12/sendkey"
+	"github.com/gavinwade12/sendkey/pkg/api"
 )
 
 type usersResource struct {
 	c *Client
 }
 
-type CreateUserRequest struct {
-	Email     string `json:"email"`
-	Password  string `json:"password"`
-	FirstName string `json:"firstName"`
-	LastName  string `json:"lastName"`
-}
-
-type CreateUserResponse struct {
-	Success bool          `json:"success"`
-	Errors  []string      `json:"errors"`
-	User    *sendkey.User `json:"user"`
-}
-
-func (r *usersResource) CreateUser(model CreateUserRequest) (*CreateUserResponse, *Error, error) {
+func (r *usersResource) CreateUser(model api.CreateUserRequest) (*api.CreateUserResponse, *api.Error, error) {
 	const path = `/users`
 
 	jr, err := jsonReader(model)
@@ -42,7 +30,7 @@ func (r *usersResource) CreateUser(model CreateUserRequest) (*CreateUserResponse
 	}
 	defer res.Body.Close()
 
-	var response CreateUserResponse
+	var response api.CreateUserResponse
 	err = json.NewDecoder(res.Body).Decode(&response)
 	if err != nil {
 		return nil, nil, err
@@ -51,25 +39,45 @@ func (r *usersResource) CreateUser(model CreateUserRequest) (*CreateUserResponse
 	return &response, nil, nil
 }
 
-type LoginResponseModel struct {
-	Success      bool          `json:"success"`
-	Errors       []string      `json:"errors"`
-	User         *sendkey.User `json:"user"`
-	AccessToken  *Token        `json:"accessToken"`
-	RefreshToken *Token        `json:"refreshToken"`
+func (r *usersResource) Login(email, password string) (*api.LoginResponse, *api.Error, error) {
+	return r.LoginWithMFA(email, password, "")
 }
 
-func (r *usersResource) Login(email, password string) (*LoginResponseModel, *Error, error) {
+// LoginWithMFA logs in a user that has MFA enabled. The mfaCode can be either
+// a TOTP code or one of the user's recovery codes.
+func (r *usersResource) LoginWithMFA(email, password, mfaCode string) (*api.LoginResponse, *api.Error, error) {
 	const path = `/login`
 
-	jr, err := jsonReader(map[string]string{
-		"email":    email,
-		"password": password,
+	jr, err := jsonReader(api.LoginRequest{
+		Email:    email,
+		Password: password,
+		MFACode:  mfaCode,
 	})
 	if err != nil {
 		return nil, nil, err
 	}
 
+	res, err := r.c.doRequest(http.MethodPost, path, jr)
+	if err != nil {
+		return nil, nil, err
+	}
+	if res.StatusCode > http.StatusBadRequest {
+		e, err := r.c.parseErrorResponse(res)
+		return nil, e, err
+	}
+
+	return r.decodeLoginResponse(res)
+}
+
+// RequestMagicLink emails a single-use login link to the user.
+func (r *usersResource) RequestMagicLink(email string) (*api.MagicLinkResponse, *api.Error, error) {
+	const path = `/login/magic`
+
+	jr, err := jsonReader(api.MagicLinkRequest{Email: email})
+	if err != nil {
+		return nil, nil, err
+	}
+
 	res, err := r.c.doRequest(http.MethodPost, path, jr)
 	if err != nil {
 		return nil, nil, err
@@ -80,12 +88,48 @@ func (r *usersResource) Login(email, password string) (*LoginResponseModel, *Err
 	}
 	defer res.Body.Close()
 
-	var response LoginResponseModel
+	var response api.MagicLinkResponse
 	err = json.NewDecoder(res.Body).Decode(&response)
 	if err != nil {
 		return nil, nil, err
 	}
 
+	return &response, nil, nil
+}
+
+// RedeemMagicLink logs in using the code from a magic link. The mfaCode is
+// only required if the user has MFA enabled, and the newPassword if an admin
+// reset the user's password.
+func (r *usersResource) RedeemMagicLink(code, mfaCode, newPassword string) (*api.LoginResponse, *api.Error, error) {
+	path := "/login/magic/" + url.PathEscape(code)
+	jr, err := jsonReader(api.RedeemMagicLinkRequest{MFACode: mfaCode, NewPassword: newPassword})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	res, err := r.c.doRequest(http.MethodPost, path, jr)
+	if err != nil {
+		return nil, nil, err
+	}
+	if res.StatusCode > http.StatusBadRequest {
+		e, err := r.c.parseErrorResponse(res)
+		return nil, e, err
+	}
+
+	return r.decodeLoginResponse(res)
+}
+
+// decodeLoginResponse decodes the response and, if the login succeeded,
+// switches the client's session to the logged in user.
+func (r *usersResource) decodeLoginResponse(res *http.Response) (*api.LoginResponse, *api.Error, error) {
+	defer res.Body.Close()
+
+	var response api.LoginResponse
+	err := json.NewDecoder(res.Body).Decode(&response)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	if response.Success {
 		r.c.refreshToken = response.RefreshToken.Token
 		r.c.accessToken = response.AccessToken.Token
@@ -0,0 +1,70 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gavinwade12/sendkey/pkg/api"
+)
+
+// ErrStreamEnded is returned by Watch when the server ends the stream, e.g.
+// because the client fell behind or its access token is about to expire.
+// Watching again picks up from there, though events published in between are
+// missed.
+var ErrStreamEnded = errors.New("the event stream ended")
+
+// Watch calls f with each event about the current user's entries as they're
+// claimed, expire, or are revoked or replaced, until ctx is done, f returns
+// an error, or the server ends the stream. It returns f's error, or
+// ErrStreamEnded. Events that happened before Watch was called aren't sent.
+func (r *entriesResource) Watch(ctx context.Context, f func(api.EntryEvent) error) (*api.Error, error) {
+	const path = `/events`
+
+	header := http.Header{"Accept": {"text/event-stream"}}
+	res, err := r.c.doRequestContext(ctx, http.MethodGet, path, nil, header)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode >= http.StatusBadRequest {
+		return r.c.parseErrorResponse(res)
+	}
+	defer res.Body.Close()
+
+	// each event is a block of "field: value" lines ended by a blank line;
+	// lines starting with a colon are comments, like the heartbeats
+	var data strings.Builder
+	scanner := bufio.NewScanner(res.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line != "" {
+			if field, value, _ := strings.Cut(line, ":"); field == "data" {
+				data.WriteString(strings.TrimPrefix(value, " "))
+			}
+			continue
+		}
+		if data.Len() == 0 {
+			continue
+		}
+
+		var e api.EntryEvent
+		if err := json.Unmarshal([]byte(data.String()), &e); err != nil {
+			return nil, fmt.Errorf("decoding event: %w", err)
+		}
+		data.Reset()
+		if err := f(e); err != nil {
+			return nil, err
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, ErrStreamEnded
+}
@@ -0,0 +1,394 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gavinwade12/sendkey"
+	"github.com/gavinwade12/sendkey/internal/app"
+	"github.com/gavinwade12/sendkey/pkg/api"
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// adminConfig bootstraps admins, who can use the /admin endpoints for
+// incident response and manage other users.
+type adminConfig struct {
+	// Emails are users who are admins whatever their role, as long as their
+	// emails are verified, e.g. to set up the first admin.
+	Emails []string
+}
+
+type AdminController struct {
+	baseController
+	entries       *app.EntryService
+	users         *app.UserService
+	refreshTokens RefreshTokenRepository
+	mailer        app.Mailer
+	storage       *storageMonitor
+}
+
+// RevokeEntries expires every active entry matching the filters, such as all
+// the entries sent by a compromised account, and notifies their recipients.
+func (c *AdminController) RevokeEntries(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	var req api.RevokeEntriesRequest
+	if err := decodeJSON(r, &req); err != nil {
+		return respond(w, http.StatusBadRequest, api.RevokeEntriesResponse{Envelope: invalidBody(r, err)})
+	}
+
+	resp, err := c.entries.RevokeEntries(sendkey.EntryFilter(req), c.mailer)
+	if err != nil {
+		return err
+	}
+	if resp.Success {
+		requestLogger(r).Info("admin: revoked entries", "revoked", resp.Revoked, "notified", resp.Notified, "notifyFailed", resp.NotifyFailed, "optedOut", resp.OptedOut)
+	}
+
+	model := api.RevokeEntriesResponse{
+		Envelope:     envelope(r, resp.Success, resp.Errors),
+		Revoked:      resp.Revoked,
+		Notified:     resp.Notified,
+		NotifyFailed: resp.NotifyFailed,
+		OptedOut:     resp.OptedOut,
+	}
+	return respond(w, envelopeStatus(model.Envelope), model)
+}
+
+// FindEntry shows any active entry, without its value.
+func (c *AdminController) FindEntry(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	userID, err := c.GetCurrentUserID(r)
+	if err != nil {
+		return err
+	}
+
+	entry, err := c.entries.FindAnyEntry(idParam(r, "entryID"))
+	if err != nil {
+		return err
+	}
+	if entry == nil {
+		return api.Error{UserID: userID, StatusCode: http.StatusNotFound, Code: "entry_not_found", Message: "The entry doesn't exist."}
+	}
+
+	return respond(w, http.StatusOK, entry)
+}
+
+// FindAnonymousSender shows who a guest entry is attributed to and how many
+// entries they've created.
+func (c *AdminController) FindAnonymousSender(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	userID, err := c.GetCurrentUserID(r)
+	if err != nil {
+		return err
+	}
+
+	sender, err := c.entries.FindAnonymousSender(idParam(r, "senderID"))
+	if err != nil {
+		return err
+	}
+	if sender == nil {
+		return api.Error{UserID: userID, StatusCode: http.StatusNotFound, Code: "sender_not_found", Message: "The anonymous sender doesn't exist."}
+	}
+
+	return respond(w, http.StatusOK, sender)
+}
+
+// ExpireEntry expires any active entry so it can't be claimed.
+func (c *AdminController) ExpireEntry(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	userID, err := c.GetCurrentUserID(r)
+	if err != nil {
+		return err
+	}
+
+	entryID := idParam(r, "entryID")
+	ee, err := c.entries.ExpireEntry(entryID)
+	if err != nil {
+		return err
+	}
+	if ee == nil {
+		return api.Error{UserID: userID, StatusCode: http.StatusNotFound, Code: "entry_not_found", Message: "The entry doesn't exist."}
+	}
+	requestLogger(r).Info("admin: expired an entry", "entryId", entryID)
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// OptOutRecipient opts the recipient out of receiving entries for them, like
+// when they asked by replying to an email.
+func (c *AdminController) OptOutRecipient(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	if err := c.entries.OptOut(p.ByName("email")); err != nil {
+		return err
+	}
+	requestLogger(r).Info("admin: opted out a recipient")
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// OptInRecipient lets a recipient who opted out receive entries again.
+func (c *AdminController) OptInRecipient(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	userID, err := c.GetCurrentUserID(r)
+	if err != nil {
+		return err
+	}
+
+	ok, err := c.entries.OptIn(p.ByName("email"))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return api.Error{UserID: userID, StatusCode: http.StatusNotFound, Code: "opt_out_not_found", Message: "The recipient hasn't opted out."}
+	}
+	requestLogger(r).Info("admin: opted in a recipient")
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// ReleaseEscrow hands over an entry's escrow, still encrypted to the org's
+// escrow key, for the break-glass procedure. The release is audited with the
+// admin and their reason.
+func (c *AdminController) ReleaseEscrow(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	userID, err := c.GetCurrentUserID(r)
+	if err != nil {
+		return err
+	}
+
+	var req api.ReleaseEscrowRequest
+	if err := decodeJSON(r, &req); err != nil {
+		return respond(w, http.StatusBadRequest, api.ReleaseEscrowResponse{Envelope: invalidBody(r, err)})
+	}
+
+	entryID := idParam(r, "entryID")
+	resp, err := requestEntries(r, c.entries).ReleaseEscrow(app.ReleaseEscrowRequest{
+		EntryID: entryID,
+		AdminID: userID,
+		Reason:  req.Reason,
+	})
+	if err != nil {
+		return err
+	}
+	if resp.Success {
+		requestLogger(r).Warn("admin: released an entry's escrow", "entryId", entryID, "reason", req.Reason)
+	}
+
+	model := api.ReleaseEscrowResponse{Envelope: envelope(r, resp.Success, resp.Errors), Escrow: resp.Escrow}
+	return respond(w, envelopeStatus(model.Envelope), model)
+}
+
+// SetUserRole changes a user's role.
+func (c *AdminController) SetUserRole(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	var req api.SetUserRoleRequest
+	if err := decodeJSON(r, &req); err != nil {
+		return respond(w, http.StatusBadRequest, api.SetUserRoleResponse{Envelope: invalidBody(r, err)})
+	}
+
+	targetID := idParam(r, "userID")
+	resp, err := c.users.SetUserRole(targetID, req.Role)
+	if err != nil {
+		return err
+	}
+	if resp.Success {
+		requestLogger(r).Info("admin: set a user's role", "targetUserId", targetID, "role", req.Role)
+	}
+
+	model := api.SetUserRoleResponse{
+		Envelope: envelope(r, resp.Success, resp.Errors),
+		User:     resp.User,
+	}
+	return respond(w, envelopeStatus(model.Envelope), model)
+}
+
+// ListUsers pages through the users, optionally searching by email or name
+// with the search query parameter. The after query parameter is the previous
+// page's next cursor.
+func (c *AdminController) ListUsers(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	userID, err := c.GetCurrentUserID(r)
+	if err != nil {
+		return err
+	}
+
+	req := app.ListUsersRequest{
+		Search: r.URL.Query().Get("search"),
+		After:  r.URL.Query().Get("after"),
+	}
+	if req.Limit, err = limitParam(r, userID); err != nil {
+		return err
+	}
+
+	resp, err := c.users.ListUsers(req)
+	if err != nil {
+		return err
+	}
+
+	return respond(w, http.StatusOK, api.ListUsersResponse{Users: resp.Users, Next: resp.Next})
+}
+
+// DisableUser stops a user from logging in and ends their sessions.
+func (c *AdminController) DisableUser(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	return c.updateUser(w, r, "disabled", func(id uuid.UUID) (*sendkey.User, error) {
+		user, err := c.users.DisableUser(id)
+		if err != nil || user == nil {
+			return user, err
+		}
+		return user, c.refreshTokens.DeleteByUserID(id)
+	})
+}
+
+// EnableUser lets a disabled user log in again.
+func (c *AdminController) EnableUser(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	return c.updateUser(w, r, "enabled", c.users.EnableUser)
+}
+
+// ResetPassword removes a user's password and ends their sessions, so they
+// have to set a new one when they redeem a magic link.
+func (c *AdminController) ResetPassword(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	return c.updateUser(w, r, "reset the password of", func(id uuid.UUID) (*sendkey.User, error) {
+		user, err := c.users.ForcePasswordReset(id)
+		if err != nil || user == nil {
+			return user, err
+		}
+		return user, c.refreshTokens.DeleteByUserID(id)
+	})
+}
+
+// DeleteUser permanently deletes a user and the entries they've sent.
+func (c *AdminController) DeleteUser(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	userID, targetID, err := c.otherUser(r)
+	if err != nil {
+		return err
+	}
+
+	found, err := c.users.DeleteUser(targetID)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return api.Error{UserID: userID, StatusCode: http.StatusNotFound, Code: "user_not_found", Message: "The user doesn't exist."}
+	}
+	requestLogger(r).Info("admin: deleted a user", "targetUserId", targetID)
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// updateUser applies the update to the user in the route and responds with
+// the updated user.
+func (c *AdminController) updateUser(w http.ResponseWriter, r *http.Request, verb string, update func(uuid.UUID) (*sendkey.User, error)) error {
+	userID, targetID, err := c.otherUser(r)
+	if err != nil {
+		return err
+	}
+
+	user, err := update(targetID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return api.Error{UserID: userID, StatusCode: http.StatusNotFound, Code: "user_not_found", Message: "The user doesn't exist."}
+	}
+	requestLogger(r).Info("admin: "+verb+" a user", "targetUserId", targetID)
+
+	return respond(w, http.StatusOK, user)
+}
+
+// otherUser returns the current user's ID and the ID of the user in the
+// route, refusing requests where they're the same so admins can't lock
+// themselves out.
+func (c *AdminController) otherUser(r *http.Request) (uuid.UUID, uuid.UUID, error) {
+	userID, err := c.GetCurrentUserID(r)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, err
+	}
+
+	targetID := idParam(r, "userID")
+	if targetID == userID {
+		return uuid.Nil, uuid.Nil, api.Error{UserID: userID, StatusCode: http.StatusBadRequest, Code: "own_account", Message: "Admins can't do this to their own account."}
+	}
+	return userID, targetID, nil
+}
+
+// ListEntries pages through every sender's live entries, without their
+// values. The after query parameter is the previous page's next ID.
+func (c *AdminController) ListEntries(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	userID, err := c.GetCurrentUserID(r)
+	if err != nil {
+		return err
+	}
+
+	limit, err := limitParam(r, userID)
+	if err != nil {
+		return err
+	}
+	after := uuid.Nil
+	if v := r.URL.Query().Get("after"); v != "" {
+		if after, err = sendkey.ParseID(v); err != nil {
+			return api.Error{UserID: userID, StatusCode: http.StatusBadRequest, Code: "invalid_after", Message: "Invalid after ID."}
+		}
+	}
+
+	resp, err := c.entries.ListLiveEntries(after, limit)
+	if err != nil {
+		return err
+	}
+
+	return respond(w, http.StatusOK, api.ListEntriesResponse{Entries: resp.Entries, Next: resp.Next})
+}
+
+// EntryHistory pages through the claimed and expired entries across every
+// sender, newest first. They can be filtered by the sentBy user ID, the sentTo
+// email, and the since time in RFC 3339 format.
+func (c *AdminController) EntryHistory(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	userID, err := c.GetCurrentUserID(r)
+	if err != nil {
+		return err
+	}
+
+	q := r.URL.Query()
+	req := app.EntryHistoryRequest{Cursor: q.Get("cursor")}
+	if req.Limit, err = limitParam(r, userID); err != nil {
+		return err
+	}
+	req.Filter.SentToEmail = q.Get("sentTo")
+	if v := q.Get("sentBy"); v != "" {
+		sentBy, err := uuid.Parse(v)
+		if err != nil {
+			return api.Error{UserID: userID, StatusCode: http.StatusBadRequest, Code: "invalid_sent_by", Message: "Invalid sentBy user ID."}
+		}
+		req.Filter.SentByUserID = &sentBy
+	}
+	if v := q.Get("since"); v != "" {
+		since, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return api.Error{UserID: userID, StatusCode: http.StatusBadRequest, Code: "invalid_since", Message: "The since time must be in RFC 3339 format."}
+		}
+		since = since.UTC()
+		req.Filter.SinceUTC = &since
+	}
+
+	resp, err := c.entries.EntryHistory(req)
+	if err != nil {
+		return err
+	}
+
+	model := api.EntryHistoryResponse{
+		Envelope: envelope(r, resp.Success, resp.Errors),
+		Outcomes: resp.Outcomes,
+		Next:     resp.Next,
+	}
+	return respond(w, envelopeStatus(model.Envelope), model)
+}
+
+// limitParam returns the limit query parameter, or zero for the default.
+func limitParam(r *http.Request, userID uuid.UUID) (int, error) {
+	v := r.URL.Query().Get("limit")
+	if v == "" {
+		return 0, nil
+	}
+
+	limit, err := strconv.Atoi(v)
+	if err != nil || limit <= 0 {
+		return 0, api.Error{UserID: userID, StatusCode: http.StatusBadRequest, Code: "invalid_limit", Message: "The limit must be a positive number."}
+	}
+	return limit, nil
+}
@@ -0,0 +1,110 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/gavinwade12/sendkey/internal/app"
+	"github.com/gavinwade12/sendkey/internal/audit"
+	"github.com/gavinwade12/sendkey/internal/mysql"
+)
+
+// auditConfig records entry events in a hash-chained log. Every
+// AnchorIntervalMins, the latest hash is signed with Auth.SigningKey; run with
+// -verify-audit to check the log. Zero anchors hourly.
+type auditConfig struct {
+	AnchorIntervalMins int
+	// Sink is where events are written: "mysql" (the default), "file" to
+	// append them as JSON lines to FilePath, "loki" to push them to the
+	// Loki server at LokiURL with LokiLabels, "kafka" to produce them to
+	// KafkaTopic through the Kafka REST proxy at KafkaProxyURL, or "s3" to
+	// write each as an object in S3.Bucket. Only the chain's head and
+	// anchors are kept in the database for the other sinks. Events pushed to
+	// Loki or Kafka can't be checked with -verify-audit.
+	Sink          string
+	FilePath      string
+	LokiURL       string
+	LokiLabels    map[string]string
+	KafkaProxyURL string
+	KafkaTopic    string
+	S3            struct {
+		// Endpoint is the URL of storage with S3's API other than AWS, like
+		// MinIO. Region defaults to us-east-1.
+		Endpoint string
+		Region   string
+		Bucket   string
+		Prefix   string
+	}
+}
+
+// sink returns the configured sink, and the source to read its events back
+// from, which is nil if they can't be read back.
+func (c auditConfig) sink(db *mysql.DB) (app.AuditSink, app.AuditSource, error) {
+	switch c.Sink {
+	case "", "mysql":
+		return db.AuditEvents, db.AuditEvents, nil
+	case "file":
+		if c.FilePath == "" {
+			return nil, nil, errors.New("audit: FilePath is required for the file sink")
+		}
+		f, err := audit.NewFile(c.FilePath)
+		if err != nil {
+			return nil, nil, err
+		}
+		return f, f, nil
+	case "loki":
+		if c.LokiURL == "" {
+			return nil, nil, errors.New("audit: LokiURL is required for the loki sink")
+		}
+		return audit.NewLoki(c.LokiURL, c.LokiLabels), nil, nil
+	case "kafka":
+		if c.KafkaProxyURL == "" || c.KafkaTopic == "" {
+			return nil, nil, errors.New("audit: KafkaProxyURL and KafkaTopic are required for the kafka sink")
+		}
+		return audit.NewKafka(c.KafkaProxyURL, c.KafkaTopic), nil, nil
+	case "s3":
+		if c.S3.Bucket == "" {
+			return nil, nil, errors.New("audit: S3.Bucket is required for the s3 sink")
+		}
+		region := c.S3.Region
+		if region == "" {
+			region = "us-east-1"
+		}
+		s := audit.NewS3(c.S3.Endpoint, region, c.S3.Bucket, c.S3.Prefix)
+		return s, s, nil
+	default:
+		return nil, nil, fmt.Errorf("audit: unknown sink %q", c.Sink)
+	}
+}
+
+// verifyAuditLog checks the audit log's chain and anchors.
+func verifyAuditLog(l *app.AuditLog, events app.AuditSource) (*app.AuditVerification, error) {
+	if events == nil {
+		return nil, errors.New("audit: the configured sink's events can't be read back to verify")
+	}
+	return l.Verify(events, 1000)
+}
+
+// anchorAudit signs the audit log's latest hash every interval until done is
+// closed.
+func anchorAudit(l *app.AuditLog, interval time.Duration, done <-chan struct{}) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-t.C:
+		case <-done:
+			return
+		}
+
+		a, err := l.Anchor()
+		if err != nil {
+			slog.Error("audit: anchoring the log", "error", err)
+		} else if a != nil {
+			slog.Info("audit: anchored the log", "seq", a.Seq)
+		}
+	}
+}
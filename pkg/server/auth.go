@@ -0,0 +1,162 @@
+package server
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/gavinwade12/sendkey"
+	"github.com/gavinwade12/sendkey/pkg/api"
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+func init() {
+	rand.Seed(time.Now().UnixNano())
+}
+
+// TokenProvider defines the methods necessary for providing access tokens
+type TokenProvider interface {
+	AccessToken(userID uuid.UUID) (*api.Token, error)
+	RefreshToken() api.Token
+}
+
+// AccessTokenVerifier defines the methods necessary for verifying auth tokens
+type AccessTokenVerifier interface {
+	Verify(string) (uuid.UUID, time.Time, error) // Verify should return the UserID from the token and when it was issued if it's valid, otherwise it should return an error
+}
+
+type tokenManager struct {
+	privateKey           []byte
+	accessTokenLifetime  time.Duration
+	refreshTokenLifetime time.Duration
+}
+
+var _ TokenProvider = (*tokenManager)(nil)
+var _ AccessTokenVerifier = (*tokenManager)(nil)
+
+func newAuthTokenManager(privateKey []byte, accessTokenLifetime, refreshTokenLifetime time.Duration) *tokenManager {
+	return &tokenManager{privateKey, accessTokenLifetime, refreshTokenLifetime}
+}
+
+func (m *tokenManager) AccessToken(userID uuid.UUID) (*api.Token, error) {
+	now := time.Now()
+	expires := now.Add(m.accessTokenLifetime).Unix()
+	claims := &jwt.StandardClaims{
+		ExpiresAt: expires,
+		Id:        userID.String(),
+		IssuedAt:  now.Unix(),
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(m.privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.Token{
+		Token:   token,
+		Expires: expires,
+	}, nil
+}
+
+func (m *tokenManager) RefreshToken() api.Token {
+	b := make([]byte, 25)
+	rand.Read(b)
+
+	return api.Token{
+		Token:   hex.EncodeToString(b),
+		Expires: time.Now().UTC().Add(m.refreshTokenLifetime).Unix(),
+	}
+}
+
+func (m *tokenManager) Verify(token string) (uuid.UUID, time.Time, error) {
+	if token == "" {
+		return uuid.Nil, time.Time{}, api.Error{StatusCode: http.StatusUnauthorized, Code: "invalid_token", Message: "no token provided"}
+	}
+
+	t, err := jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, api.Error{StatusCode: http.StatusUnauthorized, Code: "invalid_token", Message: fmt.Sprintf("unexpected signing method: %v", token.Header["alg"])}
+		}
+		return m.privateKey, nil
+	})
+	if err != nil {
+		if _, ok := err.(*jwt.ValidationError); ok {
+			return uuid.Nil, time.Time{}, api.Error{StatusCode: http.StatusUnauthorized, Code: "invalid_token", Message: err.Error()}
+		}
+
+		return uuid.Nil, time.Time{}, err
+	}
+
+	claims, ok := t.Claims.(jwt.MapClaims)
+	if !ok || !t.Valid {
+		return uuid.Nil, time.Time{}, api.Error{StatusCode: http.StatusUnauthorized, Code: "invalid_token", Message: "token invalid or failed to parse token claims"}
+	}
+
+	idClaim, ok := claims["jti"].(string)
+	if !ok {
+		return uuid.Nil, time.Time{}, api.Error{StatusCode: http.StatusUnauthorized, Code: "invalid_token", Message: "invalid token claims"}
+	}
+
+	id, err := uuid.Parse(idClaim)
+	if err != nil {
+		return uuid.Nil, time.Time{}, api.Error{StatusCode: http.StatusUnauthorized, Code: "invalid_token", Message: "invalid token claims"}
+	}
+
+	issuedAt, _ := claims["iat"].(float64)
+	return id, time.Unix(int64(issuedAt), 0), nil
+}
+
+// authRealm is the realm in WWW-Authenticate challenges.
+const authRealm = "sendkey"
+
+// requireUser refuses anonymous requests with authRequired, and users who
+// are deactivated or disabled. Use an authorizer instead for routes that also
+// need a role.
+func requireUser(a action) action {
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+		user, _ := r.Context().Value(userCtxKeyValue).(*sendkey.User)
+		if user == nil {
+			return authRequired(w)
+		}
+		if inactive(user) {
+			return accountInactive(user.ID)
+		}
+		return a(w, r, p)
+	}
+}
+
+// inactive reports whether the user deactivated their account or an admin
+// disabled it.
+func inactive(user *sendkey.User) bool {
+	return user.DeactivatedAtUTC != nil || user.DisabledAtUTC != nil
+}
+
+// accountInactive is the error for a request from an inactive user.
+func accountInactive(userID uuid.UUID) error {
+	return api.Error{UserID: userID, StatusCode: http.StatusForbidden, Code: "account_inactive", Message: "The account is deactivated or disabled."}
+}
+
+// authRequired is the error for a request without an access token to a route
+// that needs one. It challenges the client for a bearer token (RFC 6750).
+func authRequired(w http.ResponseWriter) error {
+	w.Header().Set("WWW-Authenticate", fmt.Sprintf("Bearer realm=%q", authRealm))
+	return api.Error{
+		StatusCode: http.StatusUnauthorized,
+		Code:       "auth_required",
+		Message:    "Log in and send the access token in the Authorization header.",
+	}
+}
+
+// invalidToken is the error for a request with an access token that can't be
+// verified, e.g. because it expired. Clients should refresh it and retry.
+func invalidToken(w http.ResponseWriter, err error) error {
+	w.Header().Set("WWW-Authenticate", fmt.Sprintf("Bearer realm=%q, error=\"invalid_token\"", authRealm))
+	return api.Error{
+		StatusCode: http.StatusUnauthorized,
+		Code:       "invalid_token",
+		Message:    err.Error(),
+	}
+}
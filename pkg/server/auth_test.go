@@ -0,0 +1,91 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gavinwade12/sendkey"
+	"github.com/gavinwade12/sendkey/internal/app"
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// memoryUsers keeps users in memory.
+type memoryUsers map[uuid.UUID]sendkey.User
+
+func (m memoryUsers) Find(id uuid.UUID) (*sendkey.User, error) {
+	u, ok := m[id]
+	if !ok {
+		return nil, nil
+	}
+	return &u, nil
+}
+
+func (m memoryUsers) FindByEmail(string) (*sendkey.User, error)          { return nil, nil }
+func (m memoryUsers) Create(u sendkey.User) error                        { m[u.ID] = u; return nil }
+func (m memoryUsers) Update(u sendkey.User) error                        { m[u.ID] = u; return nil }
+func (m memoryUsers) Delete(id uuid.UUID) error                          { delete(m, id); return nil }
+func (m memoryUsers) DeleteDeactivatedBefore(time.Time) (int64, error)   { return 0, nil }
+func (m memoryUsers) AdvanceMFAStep(uuid.UUID, int64) (bool, error)      { return true, nil }
+func (m memoryUsers) Search(string, string, int) ([]sendkey.User, error) { return nil, nil }
+
+func TestRequireUser(t *testing.T) {
+	atm := newAuthTokenManager([]byte("signing key"), time.Hour, time.Hour)
+	long := time.Now().UTC().Add(-time.Hour)
+	soon := time.Now().UTC().Add(time.Minute)
+
+	tests := []struct {
+		name string
+		user *sendkey.User
+		code string
+	}{
+		{"active user", &sendkey.User{}, ""},
+		{"no token", nil, "auth_required"},
+		{"deleted user", &sendkey.User{ID: uuid.New()}, "invalid_token"},
+		{"disabled user", &sendkey.User{DisabledAtUTC: &long}, "account_inactive"},
+		{"deactivated user", &sendkey.User{DeactivatedAtUTC: &long}, "account_inactive"},
+		{"password reset before the token", &sendkey.User{PasswordResetAtUTC: &long}, ""},
+		{"password reset after the token", &sendkey.User{PasswordResetAtUTC: &soon}, "invalid_token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := memoryUsers{}
+			r := httptest.NewRequest(http.MethodPost, "/generate", nil)
+			if tt.user != nil {
+				id := uuid.New()
+				if tt.user.ID == uuid.Nil {
+					tt.user.ID = id
+					users[id] = *tt.user
+				}
+				token, err := atm.AccessToken(tt.user.ID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				r.Header.Set("Authorization", "Bearer "+token.Token)
+			}
+
+			svc := app.NewUserService(users, nil, nil, app.PasswordPolicy{}, app.PasswordHashers{})
+			a := setUserID(atm, svc)(requireUser(func(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+				w.WriteHeader(http.StatusNoContent)
+				return nil
+			}))
+			w := httptest.NewRecorder()
+			cleanOutput(a)(w, r, nil)
+
+			if tt.code == "" {
+				if w.Code != http.StatusNoContent {
+					t.Errorf("got %d %s, want the action to run", w.Code, w.Body)
+				}
+				return
+			}
+			var e struct{ Code string }
+			json.Unmarshal(w.Body.Bytes(), &e)
+			if e.Code != tt.code {
+				t.Errorf("got %d %s, want %s", w.Code, w.Body, tt.code)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gavinwade12/sendkey"
+	"github.com/gavinwade12/sendkey/internal/app"
+	"github.com/gavinwade12/sendkey/pkg/api"
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// requirement is something a route requires of the current user, checked by
+// an authorizer before the route's action runs.
+type requirement func(r *http.Request, user *sendkey.User) bool
+
+// admin requires the user to be an admin.
+func admin(r *http.Request, user *sendkey.User) bool {
+	return user.Role == sendkey.RoleAdmin
+}
+
+// self requires the user to be the one in the userID route param.
+func self(r *http.Request, user *sendkey.User) bool {
+	return idParam(r, "userID") == user.ID
+}
+
+// authorizer checks routes' requirements against the current user.
+type authorizer struct {
+	users *app.UserService
+	// adminEmails are treated as admins regardless of their role, if
+	// they're verified, so the first admin can be set up from config.
+	adminEmails []string
+}
+
+// require returns a wrapper for actions only active users meeting one of the
+// requirements can use. Without requirements, any active user can.
+func (z authorizer) require(reqs ...requirement) func(action) action {
+	return func(a action) action {
+		return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+			userID, err := baseController{}.GetCurrentUserID(r)
+			if err != nil || userID == uuid.Nil {
+				return authRequired(w)
+			}
+
+			user, err := z.users.FindUser(userID)
+			if err != nil {
+				return err
+			}
+			if user == nil || inactive(user) {
+				return accountInactive(userID)
+			}
+			if user.EmailVerified && containsFold(z.adminEmails, user.Email) {
+				user.Role = sendkey.RoleAdmin
+			}
+
+			if len(reqs) == 0 {
+				return a(w, r, p)
+			}
+			for _, req := range reqs {
+				if req(r, user) {
+					return a(w, r, p)
+				}
+			}
+			return api.Error{UserID: userID, StatusCode: http.StatusForbidden, Code: "forbidden", Message: "You don't have permission to do this."}
+		}
+	}
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(strings.TrimSpace(v), s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,380 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gavinwade12/sendkey"
+	"github.com/gavinwade12/sendkey/internal/app"
+	"github.com/gavinwade12/sendkey/internal/i18n"
+	"github.com/gavinwade12/sendkey/internal/ratelimit"
+	"github.com/gavinwade12/sendkey/pkg/api"
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+const claimPageTokenLifetime = 10 * time.Minute
+
+// ClaimPageController serves a server-rendered claim page that works without
+// JavaScript. The form carries a short-lived page token so a rendered page
+// can't be replayed indefinitely.
+type ClaimPageController struct {
+	service  *app.EntryService
+	key      []byte
+	previews *ratelimit.Limiter
+	links    *app.ClaimLinks
+}
+
+type claimPageModel struct {
+	Lang      string
+	T         func(string) string
+	EntryID   string
+	Name      string
+	Nonce     string
+	Token     string
+	Questions []string
+	Errors    []app.Problem
+	Value     string
+	Metadata  sendkey.EntryMetadata
+	Claimed   bool
+	NotFound  bool
+	EndToEnd  bool
+	// LoginRequired is set for entries that require the recipient to log
+	// in, which the page can't do.
+	LoginRequired bool
+	Preview       bool
+	// OTPSent shows the field for the one-time code emailed to the
+	// recipient.
+	OTPSent bool
+	// Acknowledgment is the text the recipient has to acknowledge to claim
+	// the entry, if the sender set one.
+	Acknowledgment string
+
+	// Deferred confirms when the recipient will be reminded.
+	Deferred *app.Problem
+}
+
+// Show renders the claim form for a signed claim link. Unsigned links, with
+// the entry ID in the path and the nonce in the query, are still accepted.
+func (c *ClaimPageController) Show(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	entryID, nonce, ok := c.link(r, p.ByName("token"))
+	if !ok {
+		return c.render(w, r, http.StatusNotFound, claimPageModel{NotFound: true}, "")
+	}
+
+	if isLinkPreview(r) {
+		return c.preview(w, r, entryID, nonce)
+	}
+
+	entry, err := requestEntries(r, c.service).FindEntry(entryID, nonce)
+	if p, ok := notAvailable(err); ok {
+		return c.render(w, r, http.StatusForbidden, claimPageModel{Errors: []app.Problem{p}}, "")
+	}
+	if err != nil {
+		return err
+	}
+	if entry == nil {
+		return c.render(w, r, http.StatusNotFound, claimPageModel{NotFound: true}, "")
+	}
+
+	return c.render(w, r, http.StatusOK, c.formModel(entry, nonce), entry.Locale)
+}
+
+// link returns the entry ID and nonce the claim link points at, or false if
+// its signature is invalid.
+func (c *ClaimPageController) link(r *http.Request, token string) (uuid.UUID, string, bool) {
+	if id, err := sendkey.ParseID(token); err == nil {
+		return id, r.URL.Query().Get("nonce"), true
+	}
+	return c.links.Verify(token)
+}
+
+// preview renders a page without the form or the entry's name for link
+// previews, so fetching the link can't lead to a claim.
+func (c *ClaimPageController) preview(w http.ResponseWriter, r *http.Request, entryID uuid.UUID, nonce string) error {
+	limited, err := previewLimited(w, r, c.previews)
+	if err != nil {
+		return err
+	}
+	if limited {
+		return c.render(w, r, http.StatusTooManyRequests, claimPageModel{Errors: []app.Problem{{Code: "too_many_requests"}}}, "")
+	}
+
+	// an entry that isn't available yet still exists, so it gets the same
+	// preview
+	entry, err := requestEntries(r, c.service).FindEntry(entryID, nonce)
+	if _, ok := notAvailable(err); ok {
+		return c.render(w, r, http.StatusOK, claimPageModel{Preview: true}, "")
+	}
+	if err != nil {
+		return err
+	}
+	if entry == nil {
+		return c.render(w, r, http.StatusNotFound, claimPageModel{NotFound: true}, "")
+	}
+
+	return c.render(w, r, http.StatusOK, claimPageModel{Preview: true}, entry.Locale)
+}
+
+func (c *ClaimPageController) Claim(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	entryID, err := sendkey.ParseID(p.ByName("entryID"))
+	if err != nil {
+		return c.render(w, r, http.StatusNotFound, claimPageModel{NotFound: true}, "")
+	}
+	if err = r.ParseForm(); err != nil {
+		return c.render(w, r, http.StatusBadRequest, claimPageModel{Errors: []app.Problem{{Code: "form_unreadable"}}}, "")
+	}
+
+	if !c.validPageToken(entryID, r.PostForm.Get("token")) {
+		return c.render(w, r, http.StatusBadRequest, claimPageModel{
+			Errors: []app.Problem{{Code: "page_expired"}},
+		}, "")
+	}
+
+	// end-to-end entries can't be decrypted here, and the recipient can't
+	// log in here, so don't let the form claim those entries
+	nonce := r.PostForm.Get("nonce")
+	entry, err := requestEntries(r, c.service).FindEntry(entryID, nonce)
+	if p, ok := notAvailable(err); ok {
+		return c.render(w, r, http.StatusForbidden, claimPageModel{Errors: []app.Problem{p}}, "")
+	}
+	if err != nil {
+		return err
+	}
+	if entry == nil {
+		return c.render(w, r, http.StatusNotFound, claimPageModel{NotFound: true}, "")
+	}
+	if entry.EndToEnd || entry.RequireLogin {
+		return c.render(w, r, http.StatusBadRequest, c.formModel(entry, nonce), entry.Locale)
+	}
+
+	acknowledged, _ := strconv.ParseBool(r.PostForm.Get("acknowledged"))
+	resp, err := requestEntries(r, c.service).DecryptEntry(app.DecryptEntryRequest{
+		ID:           entryID,
+		Nonce:        nonce,
+		Secret:       r.PostForm.Get("secret"),
+		Answers:      r.PostForm["answer"],
+		OTP:          r.PostForm.Get("otp"),
+		Acknowledged: acknowledged,
+	})
+	if err != nil {
+		return err
+	}
+
+	if resp.Success {
+		return c.render(w, r, http.StatusOK, claimPageModel{
+			Name:     resp.Entry.Name,
+			Value:    string(resp.Entry.Value),
+			Metadata: resp.Entry.Metadata,
+			Claimed:  true,
+		}, resp.Entry.Locale)
+	}
+	if resp.Expired {
+		return c.render(w, r, http.StatusGone, claimPageModel{NotFound: true, Errors: resp.Errors}, "")
+	}
+
+	// re-render the form so the recipient can try again
+	entry, err = requestEntries(r, c.service).FindEntry(entryID, nonce)
+	if err != nil {
+		return err
+	}
+	if entry == nil {
+		return c.render(w, r, http.StatusNotFound, claimPageModel{NotFound: true}, "")
+	}
+
+	model := c.formModel(entry, nonce)
+	model.Errors = resp.Errors
+	model.OTPSent = resp.OTPSent || r.PostForm.Get("otp") != ""
+	return c.render(w, r, http.StatusBadRequest, model, entry.Locale)
+}
+
+// Defer puts off claiming the entry for a day. The recipient is emailed a
+// reminder with a new link.
+func (c *ClaimPageController) Defer(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	entryID, err := sendkey.ParseID(p.ByName("entryID"))
+	if err != nil {
+		return c.render(w, r, http.StatusNotFound, claimPageModel{NotFound: true}, "")
+	}
+	if err = r.ParseForm(); err != nil {
+		return c.render(w, r, http.StatusBadRequest, claimPageModel{Errors: []app.Problem{{Code: "form_unreadable"}}}, "")
+	}
+
+	if !c.validPageToken(entryID, r.PostForm.Get("token")) {
+		return c.render(w, r, http.StatusBadRequest, claimPageModel{
+			Errors: []app.Problem{{Code: "page_expired"}},
+		}, "")
+	}
+
+	resp, err := requestEntries(r, c.service).DeferClaim(app.DeferClaimRequest{ID: entryID, Nonce: r.PostForm.Get("nonce")})
+	if err != nil {
+		return err
+	}
+	if !resp.Success {
+		return c.render(w, r, http.StatusBadRequest, claimPageModel{Errors: resp.Errors}, "")
+	}
+
+	deferred := app.Problem{Code: "claim_deferred", Args: []interface{}{resp.Deferral.RemindAtUTC.Format(time.RFC1123)}}
+	return c.render(w, r, http.StatusOK, claimPageModel{Deferred: &deferred}, "")
+}
+
+func (c *ClaimPageController) formModel(entry *sendkey.Entry, nonce string) claimPageModel {
+	model := claimPageModel{
+		EntryID:       sendkey.FormatID(entry.ID),
+		Name:          entry.Name,
+		Nonce:         nonce,
+		Token:         c.pageToken(entry.ID, time.Now().Add(claimPageTokenLifetime)),
+		EndToEnd:      entry.EndToEnd,
+		LoginRequired: entry.RequireLogin,
+
+		Acknowledgment: entry.Acknowledgment,
+	}
+	for _, ch := range entry.Challenges {
+		model.Questions = append(model.Questions, ch.Question)
+	}
+
+	return model
+}
+
+// render writes the page in the recipient's preferred language, falling back
+// to the sender's locale for the entry and then English.
+func (c *ClaimPageController) render(w http.ResponseWriter, r *http.Request, status int, model claimPageModel, locale string) error {
+	model.Lang = i18n.Negotiate(r.Header.Get("Accept-Language"))
+	if model.Lang == "" && i18n.Supported(locale) {
+		model.Lang = i18n.Base(locale)
+	}
+	if model.Lang == "" {
+		model.Lang = i18n.DefaultLanguage
+	}
+	model.T = i18n.Translator(model.Lang)
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; form-action 'self'")
+	w.WriteHeader(status)
+	return claimPageTemplate.Execute(w, model)
+}
+
+// pageToken returns a token for the entry's claim form that's valid until expires.
+func (c *ClaimPageController) pageToken(entryID uuid.UUID, expires time.Time) string {
+	exp := strconv.FormatInt(expires.Unix(), 10)
+	return exp + "." + c.pageTokenMAC(entryID, exp)
+}
+
+func (c *ClaimPageController) validPageToken(entryID uuid.UUID, token string) bool {
+	parts := strings.SplitN(token, ".", 2)
+	if len(parts) != 2 {
+		return false
+	}
+
+	exp, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || time.Now().Unix() > exp {
+		return false
+	}
+
+	return hmac.Equal([]byte(parts[1]), []byte(c.pageTokenMAC(entryID, parts[0])))
+}
+
+func (c *ClaimPageController) pageTokenMAC(entryID uuid.UUID, exp string) string {
+	mac := hmac.New(sha256.New, c.key)
+	fmt.Fprintf(mac, "claim-page|%s|%s", entryID, exp)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// htmlPage adapts an action to a handler that renders a plain error page
+// instead of the API's JSON errors.
+func htmlPage(a action) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		err := a(w, r, p)
+		if e, ok := err.(api.Error); ok {
+			http.Error(w, e.Message, e.StatusCode)
+			return
+		}
+		if e, ok := dbUnavailable(w, r, err); ok {
+			http.Error(w, e.Message, e.StatusCode)
+			return
+		}
+		if err != nil {
+			requestLogger(r).Error("claim page", "error", err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
+	}
+}
+
+var claimPageTemplate = template.Must(template.New("claim").Parse(`<!DOCTYPE html>
+<html lang="{{.Lang}}">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex, nofollow">
+<title>{{if .Name}}{{.Name}} - {{end}}sendkey</title>
+<style>
+body { font-family: sans-serif; max-width: 40em; margin: 2em auto; padding: 0 1em; line-height: 1.5; }
+label { display: block; margin-top: 1em; font-weight: bold; }
+input { font-size: 1em; padding: .4em; width: 100%; box-sizing: border-box; }
+input[type=checkbox] { width: auto; margin-right: .5em; }
+button { font-size: 1em; margin-top: 1em; padding: .5em 1em; }
+pre { white-space: pre-wrap; word-break: break-all; padding: 1em; border: 2px solid; }
+:focus { outline: 3px solid #1a5fb4; outline-offset: 2px; }
+</style>
+</head>
+<body>
+<main>
+<h1>{{if .Name}}{{.Name}}{{else}}sendkey{{end}}</h1>
+{{if .Errors}}
+<div role="alert">
+<h2>{{call $.T "There was a problem"}}</h2>
+<ul>{{range .Errors}}<li>{{.Message $.Lang}}</li>{{end}}</ul>
+</div>
+{{end}}
+{{if .Claimed}}
+<p>{{call .T "This value is shown only once. Copy it now; it can't be viewed again."}}</p>
+{{with .Metadata.Username}}<p>{{call $.T "Username"}}: {{.}}</p>{{end}}
+{{with .Metadata.Hostname}}<p>{{call $.T "Hostname"}}: {{.}}</p>{{end}}
+<pre aria-label="{{call .T "Entry value"}}">{{.Value}}</pre>
+{{else if .Deferred}}
+<p>{{.Deferred.Message $.Lang}}</p>
+{{else if .Preview}}
+<p>{{call .T "Open this link in a browser to view the entry."}}</p>
+{{else if .NotFound}}
+<p>{{call .T "This entry doesn't exist, has expired, or has already been claimed."}}</p>
+{{else if .LoginRequired}}
+<p>{{call .T "This entry requires logging in as the recipient and can only be claimed with the sendkey CLI."}}</p>
+{{else if .EndToEnd}}
+<p>{{call .T "This entry is end-to-end encrypted and can only be claimed with the sendkey CLI."}}</p>
+{{else if .EntryID}}
+<form method="post" action="/claim/{{.EntryID}}" autocomplete="off">
+<input type="hidden" name="nonce" value="{{.Nonce}}">
+<input type="hidden" name="token" value="{{.Token}}">
+{{range $i, $q := .Questions}}
+<label for="answer-{{$i}}">{{$q}}</label>
+<input type="text" id="answer-{{$i}}" name="answer" required>
+{{end}}
+<label for="secret">{{call .T "Secret"}}</label>
+<input type="password" id="secret" name="secret" required>
+{{if .OTPSent}}
+<label for="otp">{{call .T "Code from your email"}}</label>
+<input type="text" id="otp" name="otp" inputmode="numeric" autocomplete="one-time-code" required>
+{{end}}
+{{with .Acknowledgment}}
+<p>{{call $.T "The sender asks you to acknowledge this before the value is shown:"}}</p>
+<label><input type="checkbox" name="acknowledged" value="true" required>{{.}}</label>
+{{end}}
+<button type="submit">{{call .T "Show value"}}</button>
+</form>
+<form method="post" action="/claim/{{.EntryID}}/defer">
+<input type="hidden" name="nonce" value="{{.Nonce}}">
+<input type="hidden" name="token" value="{{.Token}}">
+<button type="submit">{{call .T "Remind me tomorrow"}}</button>
+</form>
+{{end}}
+</main>
+</body>
+</html>
+`))
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// trustedProxies are the networks of the reverse proxies and load balancers
+// in front of the API. Their X-Forwarded-For and X-Real-IP headers are
+// believed; anyone else's are ignored, since a client can send any headers.
+type trustedProxies []*net.IPNet
+
+// parseTrustedProxies parses the CIDRs, like "10.0.0.0/8". A bare IP is
+// trusted on its own.
+func parseTrustedProxies(cidrs []string) (trustedProxies, error) {
+	proxies := make(trustedProxies, 0, len(cidrs))
+	for _, c := range cidrs {
+		if !strings.Contains(c, "/") {
+			if ip := net.ParseIP(c); ip != nil && ip.To4() != nil {
+				c += "/32"
+			} else {
+				c += "/128"
+			}
+		}
+		_, n, err := net.ParseCIDR(c)
+		if err != nil {
+			return nil, fmt.Errorf("trusted proxies: %w", err)
+		}
+		proxies = append(proxies, n)
+	}
+	return proxies, nil
+}
+
+func (t trustedProxies) trusted(ip net.IP) bool {
+	for _, n := range t {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIPOf resolves the IP of the client that made the request. If it
+// came through trusted proxies, it's the last address in X-Forwarded-For
+// that isn't a trusted proxy's, since each proxy appends the address it got
+// the request from and anything before that could have been sent by the
+// client. X-Real-IP is used if there's no X-Forwarded-For.
+func (t trustedProxies) clientIPOf(r *http.Request) string {
+	remote := parseIP(r.RemoteAddr)
+	if remote == nil {
+		return r.RemoteAddr
+	}
+	if !t.trusted(remote) {
+		return remote.String()
+	}
+
+	var hops []string
+	for _, v := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(v, ",")...)
+	}
+	client := remote
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := parseIP(hops[i])
+		if ip == nil {
+			// a malformed hop can't be followed any further
+			break
+		}
+		client = ip
+		if !t.trusted(ip) {
+			break
+		}
+	}
+	if len(hops) == 0 {
+		if ip := parseIP(r.Header.Get("X-Real-IP")); ip != nil {
+			client = ip
+		}
+	}
+	return client.String()
+}
+
+// parseIP parses an IP address, with or without a port.
+func parseIP(s string) net.IP {
+	s = strings.TrimSpace(s)
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+	return net.ParseIP(strings.Trim(s, "[]"))
+}
+
+type clientIPCtxKey string
+
+const clientIPCtxKeyValue = clientIPCtxKey("clientIP")
+
+// resolveClientIP resolves each request's client IP before it's handled, for
+// clientIP.
+func resolveClientIP(t trustedProxies, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := t.clientIPOf(r)
+		h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientIPCtxKeyValue, ip)))
+	})
+}
+
+// clientIP returns the IP address of the client that made the request,
+// looking through trusted proxies.
+func clientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPCtxKeyValue).(string); ok {
+		return ip
+	}
+	return trustedProxies(nil).clientIPOf(r)
+}
@@ -0,0 +1,249 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/gavinwade12/sendkey"
+	"github.com/gavinwade12/sendkey/internal/chaos"
+)
+
+type chaosConfig struct {
+	LatencyMs   int
+	LatencyRate float64
+	ErrorRate   float64
+}
+
+func (c chaosConfig) faults() chaos.Faults {
+	return chaos.Faults{
+		Latency:     time.Millisecond * time.Duration(c.LatencyMs),
+		LatencyRate: c.LatencyRate,
+		ErrorRate:   c.ErrorRate,
+	}
+}
+
+// Config configures the server. It's usually read from a JSON file with
+// ReadConfig; see cmd/api's config.example.json.
+type Config struct {
+	// Key is the legacy entry key, for entries from before key versioning.
+	// It's plaintext and can be any length.
+	Key string
+	// Keys are the versioned entry keys, wrapped by the KeyProvider if one is
+	// set, and KeyVersion is the one used for new entries. After adding a key,
+	// run with -rotate-keys to re-encrypt existing entries so older keys can
+	// be removed.
+	Keys        map[int]string
+	KeyVersion  int
+	KeyProvider keyProviderConfig
+	// Cipher is the AEAD new entries are encrypted with, either "aes-gcm"
+	// (the default) or "xchacha20-poly1305" for hardware without AES-NI.
+	Cipher             string
+	MaxInvalidAttempts int
+	// DuplicateEntries detects a sender creating the same entry for the
+	// same recipient within WindowSecs. Duplicates get a warning, or are
+	// rejected if Suppress is set. A zero window turns detection off.
+	DuplicateEntries struct {
+		WindowSecs int
+		Suppress   bool
+	}
+	// MaxEntryValueBytes limits the size of entry values. Zero uses the
+	// default of 64 KiB.
+	MaxEntryValueBytes int
+	// ClaimLinkTTLHours makes new entries' claim links expire after that
+	// many hours, even if the entry lasts longer; the sender can resend the
+	// entry for a new link. Zero makes links last as long as their entries.
+	ClaimLinkTTLHours int
+	// EntryKDF is the Argon2id cost for deriving new entries' keys from their
+	// secrets. Zero values use the defaults.
+	EntryKDF struct {
+		Time      uint32
+		MemoryKiB uint32
+		Threads   uint8
+	}
+	// PublicURL is where the API is reached, for the links it sends.
+	PublicURL string
+	// TrustedProxies are the CIDRs of the reverse proxies and load
+	// balancers in front of the API, whose X-Forwarded-For and X-Real-IP
+	// headers give the client's IP for rate limits, logs, and the audit log.
+	// Empty trusts none, using the connection's address.
+	TrustedProxies []string
+	// StrictJSON rejects request bodies with unknown fields, which catches
+	// typos in development. Clients can ask for either with the
+	// X-Strict-JSON header.
+	StrictJSON bool
+	// ValidateRequests checks request bodies against the OpenAPI document
+	// served at /openapi.json before they're handled, reporting every field
+	// with the wrong type or format at once.
+	ValidateRequests bool
+	// Requests limits requests' bodies and how long they can take.
+	Requests requestLimitsConfig
+	Cors     corsConfig
+	Auth     struct {
+		SigningKey                string
+		AccessTokenDurationMins   int
+		RefreshTokenDurationHours int
+		MagicLinkDurationMins     int
+		PasswordPolicy            struct {
+			MinLength     int
+			RequireUpper  bool
+			RequireLower  bool
+			RequireDigit  bool
+			RequireSymbol bool
+			CheckPwned    bool
+		}
+		// PasswordHashing configures the Argon2id parameters for new hashes.
+		// Zero values use the defaults. Existing hashes with weaker parameters
+		// are re-hashed on login.
+		PasswordHashing struct {
+			Argon2idTime      uint32
+			Argon2idMemoryKiB uint32
+			Argon2idThreads   uint8
+		}
+		LoginThrottle struct {
+			BaseDelaySecs    int
+			MaxDelaySecs     int
+			LockoutThreshold int
+			LockoutMins      int
+		}
+		// LinkPreviews limits how many times per window each IP can check
+		// whether an entry exists through a link preview or HEAD request.
+		// Zero values use 30 per minute.
+		LinkPreviews struct {
+			Limit      int
+			WindowSecs int
+		}
+		OIDC []oidcProviderConfig
+		SAML samlConfig
+		// SSOOrgs require members of the orgs, identified by their email
+		// domains, to log in through OIDC or SAML. Password login is refused
+		// for them, except for the ExemptEmails kept as a way in when the
+		// identity provider is down.
+		SSOOrgs []struct {
+			Name         string
+			Domains      []string
+			ExemptEmails []string
+		}
+	}
+	MySQL struct {
+		DSN           string
+		MigrationsDir string
+	}
+	Redis struct {
+		Addr     string
+		Password string
+		DB       int
+	}
+	// RateLimits limit requests by IP and by user with token buckets, kept
+	// in Redis if it's configured so every instance shares them. The routes
+	// that check passwords and entries' secrets have stricter limits by
+	// default.
+	RateLimits rateLimitsConfig
+	// Idempotency keeps the responses to requests that create entries with
+	// an Idempotency-Key header, for retries.
+	Idempotency idempotencyConfig
+	// Metrics records per-route, per-operation store, and mailer stats,
+	// entry lifecycle events, connection pool gauges, and how long each
+	// phase of startup took, as "startup." operations. They're served in
+	// the Prometheus format from /metrics and as JSON from /debug/vars,
+	// along with resource usage from /debug/stats. Restrict access to them,
+	// or serve them from Ops.Addr, if it's enabled.
+	Metrics struct {
+		Enabled bool
+	}
+	Ops opsConfig
+	IDs idsConfig
+	// Chaos injects latency and errors for resilience testing. Never enable
+	// it in production.
+	Chaos struct {
+		Enabled bool
+		HTTP    chaosConfig
+		Stores  chaosConfig
+	}
+	Replication replicationConfig
+	Retention   struct {
+		UserGracePeriodDays int
+		SweepIntervalMins   int
+		// HistoryDays is how long claimed and expired entries are kept in
+		// the entry history. Zero keeps them forever. With an Archive,
+		// they're written to cold storage before they're purged.
+		HistoryDays int
+		Archive     archiveConfig
+	}
+	// Escrow keeps a copy of each new entry's value encrypted to the org's
+	// escrow public key, a PEM file, for RetentionDays after it's claimed.
+	// Escrows past their retention are purged by the retention sweep. See
+	// app.Escrow for the break-glass procedure.
+	// Storage warns as the database grows.
+	Storage storageConfig
+	Escrow  struct {
+		PublicKeyFile string
+		RetentionDays int
+	}
+	RobotsTxt   string
+	SecurityTxt securityTxtConfig
+	SMTP        struct {
+		Host     string
+		Port     string
+		Username string
+		Password string
+		From     string
+		// LogOnly logs the recipients and subjects of mail instead of
+		// sending it when Host is empty, for local development. Without
+		// either, magic links, OTP challenges, reminders, and revocation
+		// notices are disabled.
+		LogOnly bool
+		// Queue holds mail waiting to be sent. Overflow is "drop" to drop
+		// new mail with an audit log entry when the queue is full, or
+		// "block" to wait up to BlockTimeoutSecs for room. AlertDepth logs
+		// an alert when the backlog reaches it. Zero values use a depth of
+		// 1000 with 2 workers and the drop policy.
+		Queue struct {
+			Depth            int
+			Workers          int
+			Overflow         string
+			BlockTimeoutSecs int
+			AlertDepth       int
+		}
+	}
+	Admin        adminConfig
+	Audit        auditConfig
+	GuestEntries guestConfig
+	Events       eventsConfig
+	Quotas       quotaConfig
+	Telemetry    telemetryConfig
+	Logging      loggingConfig
+	Sandbox      sandboxConfig
+	Tracing      tracingConfig
+}
+
+// ReadConfig reads the config from the JSON file at path.
+func ReadConfig(path string) (*Config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening config file: %w", err)
+	}
+	defer f.Close()
+
+	cfg := &Config{}
+	if err = json.NewDecoder(f).Decode(cfg); err != nil {
+		return nil, fmt.Errorf("decoding config file: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// Logger returns the logger the config's Logging describes, writing to w.
+func (c Config) Logger(w io.Writer) (*slog.Logger, error) {
+	return c.Logging.logger(w)
+}
+
+// IDGenerators returns the generators of records' and entries' IDs the
+// config's IDs choose. A MySQL database opened for the server needs the
+// records' one; see mysql.GenerateIDs.
+func (c Config) IDGenerators() (records, entries sendkey.IDGenerator, err error) {
+	return c.IDs.generators()
+}
@@ -0,0 +1,132 @@
+package server
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/rs/cors"
+)
+
+type corsConfig struct {
+	// AllowedOrigins are exact origins like "https://app.example.com", "*"
+	// for any origin, or a wildcard subdomain like "https://*.example.com".
+	// A wildcard subdomain doesn't match the bare domain.
+	AllowedOrigins []string
+	// AllowedOriginPatterns are regular expressions matched against the whole
+	// origin.
+	AllowedOriginPatterns []string
+	AllowedMethods        []string
+	AllowedHeaders        []string
+	// ExposedHeaders are response headers browser clients can read, on top
+	// of the ones the API always exposes.
+	ExposedHeaders   []string
+	AllowCredentials bool
+	MaxAgeSecs       int
+}
+
+// options validates the config and returns the equivalent cors options.
+func (c corsConfig) options() (cors.Options, error) {
+	m, err := newOriginMatcher(c.AllowedOrigins, c.AllowedOriginPatterns)
+	if err != nil {
+		return cors.Options{}, err
+	}
+	if m.any && c.AllowCredentials {
+		return cors.Options{}, fmt.Errorf(`cors: credentials can't be allowed for any origin ("*")`)
+	}
+
+	return cors.Options{
+		AllowOriginFunc:  m.match,
+		AllowedMethods:   c.AllowedMethods,
+		AllowedHeaders:   c.AllowedHeaders,
+		ExposedHeaders:   append(append([]string{}, exposedHeaders...), c.ExposedHeaders...),
+		AllowCredentials: c.AllowCredentials,
+		MaxAge:           c.MaxAgeSecs,
+	}, nil
+}
+
+// exposedHeaders are always readable by browser clients: the request ID to
+// quote in support requests, the auth challenge, deprecation warnings, and
+// the rate limits, so clients can slow down before they're refused.
+var exposedHeaders = []string{
+	"X-Request-ID", "WWW-Authenticate", "Deprecation", "Sunset", "Link", "Warning",
+	"RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Retry-After",
+}
+
+type originMatcher struct {
+	any       bool
+	exact     map[string]bool
+	wildcards []wildcardOrigin
+	patterns  []*regexp.Regexp
+}
+
+// wildcardOrigin matches any subdomain of a host, e.g. "https://*.example.com"
+// has the prefix "https://" and suffix ".example.com".
+type wildcardOrigin struct {
+	prefix, suffix string
+}
+
+func newOriginMatcher(origins, patterns []string) (*originMatcher, error) {
+	m := &originMatcher{exact: map[string]bool{}}
+
+	for _, o := range origins {
+		o = strings.ToLower(strings.TrimSpace(o))
+		if o == "*" {
+			m.any = true
+			continue
+		}
+
+		u, err := url.Parse(strings.Replace(o, "*.", "wildcard.", 1))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" ||
+			(u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.User != nil {
+			return nil, fmt.Errorf("cors: invalid origin %q; origins are a scheme and host, e.g. https://app.example.com", o)
+		}
+		o = strings.TrimSuffix(o, "/")
+
+		switch n := strings.Count(o, "*"); {
+		case n == 0:
+			m.exact[o] = true
+		case n == 1 && strings.HasPrefix(o, u.Scheme+"://*."):
+			m.wildcards = append(m.wildcards, wildcardOrigin{u.Scheme + "://", o[len(u.Scheme)+4:]})
+		default:
+			return nil, fmt.Errorf("cors: invalid origin %q; only the leftmost subdomain can be a wildcard", o)
+		}
+	}
+
+	for _, p := range patterns {
+		// the whole origin must match so a pattern can't be satisfied by an
+		// attacker's domain that merely contains it
+		re, err := regexp.Compile("^(?:" + strings.TrimSuffix(strings.TrimPrefix(p, "^"), "$") + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("cors: invalid origin pattern %q: %w", p, err)
+		}
+		m.patterns = append(m.patterns, re)
+	}
+
+	return m, nil
+}
+
+func (m *originMatcher) match(origin string) bool {
+	if m.any {
+		return true
+	}
+
+	origin = strings.ToLower(origin)
+	if m.exact[origin] {
+		return true
+	}
+	for _, w := range m.wildcards {
+		if len(origin) > len(w.prefix)+len(w.suffix) &&
+			strings.HasPrefix(origin, w.prefix) && strings.HasSuffix(origin, w.suffix) {
+			return true
+		}
+	}
+	for _, re := range m.patterns {
+		if re.MatchString(origin) {
+			return true
+		}
+	}
+
+	return false
+}
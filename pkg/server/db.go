@@ -0,0 +1,34 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gavinwade12/sendkey/internal/mysql"
+	"github.com/gavinwade12/sendkey/internal/redact"
+	"github.com/gavinwade12/sendkey/pkg/api"
+)
+
+// dbRetryAfter is the Retry-After, in seconds, for a request that failed
+// because the database was unavailable. A failover usually finishes within
+// a few seconds.
+const dbRetryAfter = 5
+
+// dbUnavailable returns the error for a request that failed because the
+// database was unavailable, like during a failover, and whether it did. A
+// write may or may not have been made, so clients should check before
+// retrying one that isn't idempotent.
+func dbUnavailable(w http.ResponseWriter, r *http.Request, err error) (api.Error, bool) {
+	if !mysql.Unavailable(err) {
+		return api.Error{}, false
+	}
+
+	requestLogger(r).Warn("database unavailable", "error", redact.String(err.Error()))
+	w.Header().Set("Retry-After", strconv.Itoa(dbRetryAfter))
+	return api.Error{
+		StatusCode: http.StatusServiceUnavailable,
+		Code:       "db_unavailable",
+		Message:    "The service is briefly unavailable. Try again in a few seconds.",
+		RequestID:  requestID(r),
+	}, true
+}
@@ -0,0 +1,52 @@
+package server
+
+import (
+	"net/http"
+	"runtime"
+
+	"github.com/gavinwade12/sendkey/internal/metrics"
+	"github.com/gavinwade12/sendkey/internal/mysql"
+	"github.com/julienschmidt/httprouter"
+)
+
+type debugStats struct {
+	Goroutines int              `json:"goroutines"`
+	HeapBytes  uint64           `json:"heapBytes"`
+	DB         debugDBStats     `json:"db"`
+	Gauges     map[string]int64 `json:"gauges"`
+}
+
+type debugDBStats struct {
+	MaxOpenConnections int   `json:"maxOpenConnections"`
+	OpenConnections    int   `json:"openConnections"`
+	InUse              int   `json:"inUse"`
+	Idle               int   `json:"idle"`
+	WaitCount          int64 `json:"waitCount"`
+	WaitDurationMs     int64 `json:"waitDurationMs"`
+}
+
+// statsHandler reports resources that grow when something leaks: goroutines,
+// DB connections, and any gauges registered by components like the mailer.
+func statsHandler(db *mysql.DB, reg *metrics.Registry) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		var mem runtime.MemStats
+		runtime.ReadMemStats(&mem)
+		dbs := db.Stats()
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		writeJSON(w, debugStats{
+			Goroutines: runtime.NumGoroutine(),
+			HeapBytes:  mem.HeapAlloc,
+			DB: debugDBStats{
+				MaxOpenConnections: dbs.MaxOpenConnections,
+				OpenConnections:    dbs.OpenConnections,
+				InUse:              dbs.InUse,
+				Idle:               dbs.Idle,
+				WaitCount:          dbs.WaitCount,
+				WaitDurationMs:     dbs.WaitDuration.Milliseconds(),
+			},
+			Gauges: reg.Gauges(),
+		})
+	}
+}
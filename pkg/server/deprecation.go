@@ -0,0 +1,94 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gavinwade12/sendkey/pkg/api"
+	"github.com/julienschmidt/httprouter"
+)
+
+// deprecation describes a route that's being replaced, so clients are told
+// before it's removed rather than finding out when it breaks.
+type deprecation struct {
+	// Since is when the route was deprecated.
+	Since time.Time
+	// Sunset is when the route stops being served, if that's been decided.
+	// After it, the route responds with 410 Gone.
+	Sunset time.Time
+	// Successor is the path of the route to use instead, if there is one.
+	Successor string
+	// SuccessorPrefix is prepended to the request's path for the route to
+	// use instead, for routes replaced by the same route in an API version.
+	SuccessorPrefix string
+	// Message tells the client what to do, e.g. which route replaces it.
+	Message string
+}
+
+// deprecations are the deprecated routes, keyed by method and path as they're
+// registered, e.g. "GET /v1/entries/:entryID/value". The router marks them as
+// they're registered, so the routes themselves don't change.
+var deprecations = map[string]deprecation{}
+
+// successor returns the path of the route to use instead of the request's.
+func (d deprecation) successor(r *http.Request) string {
+	if d.SuccessorPrefix != "" {
+		return d.SuccessorPrefix + r.URL.Path
+	}
+	return d.Successor
+}
+
+// warning is the structured warning for the deprecation, added to envelopes.
+func (d deprecation) warning(successor string) api.Warning {
+	w := api.Warning{Code: api.WarningDeprecated, Message: d.Message, Successor: successor}
+	if !d.Sunset.IsZero() {
+		sunset := d.Sunset.UTC()
+		w.Sunset = &sunset
+	}
+	return w
+}
+
+// deprecate sets the Deprecation (RFC 9745) and Sunset (RFC 8594) headers on
+// the route's responses, with a Warning header clients can show, and adds the
+// warning to the response's envelope.
+func deprecate(d deprecation, h httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		header := w.Header()
+		header.Set("Deprecation", "@"+strconv.FormatInt(d.Since.Unix(), 10))
+		if !d.Sunset.IsZero() {
+			header.Set("Sunset", d.Sunset.UTC().Format(http.TimeFormat))
+		}
+		successor := d.successor(r)
+		if successor != "" {
+			header.Add("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, successor))
+		}
+		header.Add("Warning", api.FormatWarning(d.Message))
+
+		if !d.Sunset.IsZero() && !time.Now().Before(d.Sunset) {
+			header.Set("Content-Type", "application/json")
+			respond(w, http.StatusGone, api.Error{
+				StatusCode: http.StatusGone,
+				Code:       "route_sunset",
+				Message:    strings.TrimSpace("This route is no longer served. " + d.Message),
+				RequestID:  requestID(r),
+			})
+			return
+		}
+
+		h(w, r.WithContext(context.WithValue(r.Context(), warningsCtxKeyValue, []api.Warning{d.warning(successor)})), p)
+	}
+}
+
+type warningsCtxKey string
+
+const warningsCtxKeyValue = warningsCtxKey("warnings")
+
+// requestWarnings returns the warnings for the request's envelope.
+func requestWarnings(r *http.Request) []api.Warning {
+	warnings, _ := r.Context().Value(warningsCtxKeyValue).([]api.Warning)
+	return warnings
+}
@@ -0,0 +1,638 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gavinwade12/sendkey"
+	"github.com/gavinwade12/sendkey/internal/app"
+	"github.com/gavinwade12/sendkey/internal/captcha"
+	"github.com/gavinwade12/sendkey/internal/ratelimit"
+	"github.com/gavinwade12/sendkey/pkg/api"
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+	"github.com/skip2/go-qrcode"
+)
+
+type EntriesController struct {
+	baseController
+
+	service *app.EntryService
+	// maxBody limits the size of a request to create an entry
+	maxBody int64
+	// previews limits how often link previews can check for an entry
+	previews *ratelimit.Limiter
+	links    *app.ClaimLinks
+	// users finds the logged-in claimer for entries that require login
+	users *app.UserService
+	// guests limits entries created without logging in. It's nil unless
+	// guest entries are enabled.
+	guests *ratelimit.Limiter
+	// sandbox serves requests with the X-Sandbox header. It's nil unless the
+	// sandbox is enabled.
+	sandbox *app.EntryService
+	// captcha verifies guests' CAPTCHAs. It's nil unless they're required.
+	captcha *captcha.Verifier
+}
+
+// entryBodyLimit is the request body limit for entries with values up to
+// maxValue bytes. It leaves room for JSON escaping and base64 encoding, so the
+// service's check on the value itself gives the precise error.
+func entryBodyLimit(maxValue int) int64 {
+	return 2*int64(maxValue) + 64<<10
+}
+
+func (s *EntriesController) CreateEntry(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	userID, _ := s.GetCurrentUserID(r)
+	if userID == uuid.Nil {
+		// guests can only create entries if they're enabled
+		if s.guests == nil {
+			return authRequired(w)
+		}
+		limited, err := guestLimited(w, r, s.guests)
+		if err != nil {
+			return err
+		}
+		if limited {
+			return api.Error{StatusCode: http.StatusTooManyRequests, Code: "rate_limited", Message: "Too many requests. Try again later."}
+		}
+	}
+
+	var req api.CreateEntryRequest
+	r.Body = http.MaxBytesReader(w, r.Body, s.maxBody)
+	if err := decodeJSON(r, &req); err != nil {
+		var mbe *http.MaxBytesError
+		if errors.As(err, &mbe) {
+			return api.Error{
+				UserID:     userID,
+				StatusCode: http.StatusRequestEntityTooLarge,
+				Code:       "body_too_large",
+				Message:    fmt.Sprintf("The request body can't be larger than %d bytes.", s.maxBody),
+			}
+		}
+		return respond(w, http.StatusBadRequest, api.CreateEntryResponse{Envelope: invalidBody(r, err)})
+	}
+
+	if userID == uuid.Nil && s.captcha != nil {
+		if err := checkCaptcha(r, s.captcha, req.CaptchaToken); err != nil {
+			return err
+		}
+	}
+
+	entryReq, problems := createEntryRequest(w, userID, req)
+	if len(problems) > 0 {
+		return respond(w, http.StatusBadRequest, api.CreateEntryResponse{Envelope: envelope(r, false, problems)})
+	}
+	if userID == uuid.Nil {
+		entryReq.SenderAddress = clientIP(r)
+	}
+
+	model, err := s.createEntry(r, entryReq)
+	if err != nil {
+		return err
+	}
+	return respond(w, envelopeStatus(model.Envelope), model)
+}
+
+// createEntry creates the entry with the request's service and returns the
+// API's response.
+func (s *EntriesController) createEntry(r *http.Request, req app.CreateEntryRequest) (*api.CreateEntryResponse, error) {
+	service, err := s.entries(r)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := service.CreateEntry(req)
+	if err != nil {
+		return nil, err
+	}
+
+	model := &api.CreateEntryResponse{
+		Envelope: envelope(r, resp.Success, resp.Errors),
+		Warnings: messages(r, resp.Warnings),
+		Entry:    resp.Entry,
+		Link:     resp.Link,
+	}
+	if resp.Entry != nil {
+		model.ClaimURL = s.claimURL(*resp.Entry, resp.Link)
+	}
+	return model, nil
+}
+
+// Generate generates a random password or passphrase, and creates an entry
+// with it if one was requested.
+func (s *EntriesController) Generate(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	userID, err := s.GetCurrentUserID(r)
+	if err != nil {
+		return err
+	}
+
+	var req api.GenerateRequest
+	if err := decodeJSON(r, &req); err != nil {
+		return respond(w, http.StatusBadRequest, api.GenerateResponse{Envelope: invalidBody(r, err)})
+	}
+
+	genReq := app.GenerateSecretRequest{
+		GeneratePolicy: app.GeneratePolicy{
+			Length:    req.Length,
+			Charset:   req.Charset,
+			Words:     req.Words,
+			Separator: req.Separator,
+		},
+	}
+	if req.Entry != nil {
+		entryReq, problems := createEntryRequest(w, userID, *req.Entry)
+		if len(problems) > 0 {
+			return respond(w, http.StatusBadRequest, api.GenerateResponse{Envelope: envelope(r, false, problems)})
+		}
+		genReq.Entry = &entryReq
+	}
+
+	service, err := s.entries(r)
+	if err != nil {
+		return err
+	}
+	resp, err := service.GenerateSecret(genReq)
+	if err != nil {
+		return err
+	}
+
+	model := api.GenerateResponse{
+		Envelope: envelope(r, resp.Success, resp.Errors),
+		Value:    resp.Value,
+		Entry:    resp.Entry,
+		Link:     resp.Link,
+	}
+	if resp.Entry != nil {
+		model.ClaimURL = s.claimURL(*resp.Entry, resp.Link)
+	}
+	return respond(w, envelopeStatus(model.Envelope), model)
+}
+
+// failureSendLog records entries in a ratelimit.FailureStore, so duplicates
+// are detected across API instances when the store is shared.
+type failureSendLog struct {
+	store ratelimit.FailureStore
+}
+
+func (l failureSendLog) Record(fingerprint string, window time.Duration) (int, error) {
+	count, _, err := l.store.RecordFailure("sent:"+fingerprint, window)
+	return count, err
+}
+
+// createEntryRequest converts the API's request to create an entry into the
+// service's.
+func createEntryRequest(w http.ResponseWriter, userID uuid.UUID, req api.CreateEntryRequest) (app.CreateEntryRequest, []app.Problem) {
+	var duration time.Duration
+	switch {
+	case req.Duration != nil && req.DurationSeconds != 0:
+		return app.CreateEntryRequest{}, []app.Problem{{Field: "duration", Code: "duration_conflict"}}
+	case req.Duration != nil:
+		duration = req.Duration.Duration
+		if req.Duration.Minutes {
+			w.Header().Set("Deprecation", "true")
+			w.Header().Set("Warning", `299 - "A bare number of minutes for duration is deprecated; use units like \"45m\" or durationSeconds."`)
+		}
+	default:
+		duration = time.Duration(req.DurationSeconds) * time.Second
+	}
+
+	challenges := make([]app.ChallengeRequest, len(req.Challenges))
+	for i, c := range req.Challenges {
+		challenges[i] = app.ChallengeRequest(c)
+	}
+
+	return app.CreateEntryRequest{
+		Name:           req.Name,
+		SenderID:       userID,
+		SendToEmail:    req.SendToEmail,
+		Value:          req.Value,
+		Secret:         req.Secret,
+		Duration:       duration,
+		AvailableAtUTC: req.AvailableAtUTC,
+		Locale:         req.Locale,
+		Type:           req.Type,
+		Metadata:       req.Metadata,
+		EndToEnd:       (*app.SealedValue)(req.EndToEnd),
+		Generate:       (*app.GeneratePolicy)(req.Generate),
+		RequireLogin:   req.RequireLogin,
+		RequireOTP:     req.RequireOTP,
+		Acknowledgment: req.Acknowledgment,
+		Digest:         req.Digest,
+		Challenges:     challenges,
+
+		ReplacesEntryID: req.ReplacesEntryID,
+	}, nil
+}
+
+func (c *EntriesController) FindEntry(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	userID, err := c.GetCurrentUserID(r)
+	if err != nil {
+		return err
+	}
+
+	entryID := idParam(r, "entryID")
+
+	nonce := r.URL.Query().Get("nonce")
+	if nonce == "" {
+		return api.Error{UserID: userID, StatusCode: http.StatusBadRequest, Code: "nonce_required", Message: "A nonce is required."}
+	}
+
+	service, err := c.entries(r)
+	if err != nil {
+		return err
+	}
+	entry, err := service.FindEntry(entryID, nonce)
+	if p, ok := notAvailable(err); ok {
+		return api.Error{UserID: userID, StatusCode: http.StatusForbidden, Code: p.Code, Message: p.Message(language(r))}
+	}
+	if err != nil {
+		return err
+	}
+	if entry == nil {
+		return api.Error{UserID: userID, StatusCode: http.StatusNotFound, Code: "entry_not_found", Message: "The entry doesn't exist."}
+	}
+
+	return respond(w, http.StatusOK, entry)
+}
+
+const (
+	defaultQRSize = 256
+	maxQRSize     = 1024
+)
+
+// ClaimQRCode renders the entry's claim link as a PNG QR code for the sender
+// to show to a recipient's phone. The size query parameter sets its width in
+// pixels.
+func (c *EntriesController) ClaimQRCode(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	userID, err := c.GetCurrentUserID(r)
+	if err != nil {
+		return err
+	}
+
+	size := defaultQRSize
+	if s := r.URL.Query().Get("size"); s != "" {
+		if size, err = strconv.Atoi(s); err != nil || size < 64 || size > maxQRSize {
+			return api.Error{
+				UserID:     userID,
+				StatusCode: http.StatusBadRequest,
+				Code:       "invalid_size",
+				Message:    fmt.Sprintf("The size must be between 64 and %d.", maxQRSize),
+			}
+		}
+	}
+
+	service, err := c.entries(r)
+	if err != nil {
+		return err
+	}
+	entry, err := service.FindSentEntry(idParam(r, "entryID"), userID)
+	if err != nil {
+		return err
+	}
+	if entry == nil {
+		return api.Error{UserID: userID, StatusCode: http.StatusNotFound, Code: "entry_not_found", Message: "The entry doesn't exist."}
+	}
+
+	link, err := service.ActiveClaimLink(*entry)
+	if err != nil {
+		return err
+	}
+
+	png, err := qrcode.Encode(c.claimURL(*entry, link), qrcode.Medium, size)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(png)
+	return err
+}
+
+func (c *EntriesController) FindUserEntries(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	service, err := c.entries(r)
+	if err != nil {
+		return err
+	}
+	entries, err := service.FindByUserID(idParam(r, "userID"))
+	if err != nil {
+		return err
+	}
+
+	return respond(w, http.StatusOK, entries)
+}
+
+func (c *EntriesController) EntryValue(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	userID, err := c.GetCurrentUserID(r)
+	if err != nil {
+		return err
+	}
+
+	entryID := idParam(r, "entryID")
+
+	nonce := r.URL.Query().Get("nonce")
+	if nonce == "" {
+		return api.Error{UserID: userID, StatusCode: http.StatusBadRequest, Code: "nonce_required", Message: "A nonce is required."}
+	}
+	if isLinkPreview(r) {
+		return c.previewEntry(w, r, userID, entryID, nonce)
+	}
+
+	claimer, err := c.claimer(userID)
+	if err != nil {
+		return err
+	}
+	service, err := c.entries(r)
+	if err != nil {
+		return err
+	}
+	acknowledged, _ := strconv.ParseBool(r.URL.Query().Get("acknowledged"))
+	resp, err := service.DecryptEntry(app.DecryptEntryRequest{
+		ID:      entryID,
+		Nonce:   nonce,
+		Secret:  r.URL.Query().Get("secret"),
+		Answers: r.URL.Query()["answer"],
+		Claimer: claimer,
+		OTP:     r.URL.Query().Get("otp"),
+		// the client's key for its claims, so retries and concurrent
+		// claims from the same client can be told apart from others'
+		ClaimKey:     r.Header.Get("X-Claim-Key"),
+		Acknowledged: acknowledged,
+	})
+	if err != nil {
+		return err
+	}
+
+	model := api.ClaimEntryResponse{
+		Envelope: envelope(r, resp.Success, resp.Errors),
+		Receipt:  resp.Receipt,
+		OTPSent:  resp.OTPSent,
+		Digest:   resp.Digest,
+	}
+	if resp.Sealed != nil {
+		sealed := api.SealedValue(*resp.Sealed)
+		model.Sealed = &sealed
+	} else if resp.Entry != nil {
+		v := string(resp.Entry.Value)
+		model.Value = &v
+	}
+
+	return respond(w, http.StatusOK, model)
+}
+
+// claimer returns the logged-in user claiming an entry, or nil if there isn't
+// one.
+func (c *EntriesController) claimer(userID uuid.UUID) (*sendkey.User, error) {
+	if userID == uuid.Nil {
+		return nil, nil
+	}
+
+	user, err := c.users.FindUser(userID)
+	if err != nil || user == nil || user.DeactivatedAtUTC != nil || user.DisabledAtUTC != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// previewEntry tells a link preview whether the entry exists without
+// consuming a claim attempt.
+func (c *EntriesController) previewEntry(w http.ResponseWriter, r *http.Request, userID, entryID uuid.UUID, nonce string) error {
+	limited, err := previewLimited(w, r, c.previews)
+	if err != nil {
+		return err
+	}
+	if limited {
+		return api.Error{UserID: userID, StatusCode: http.StatusTooManyRequests, Code: "rate_limited", Message: "Too many requests. Try again later."}
+	}
+
+	service, err := c.entries(r)
+	if err != nil {
+		return err
+	}
+	entry, err := service.FindEntry(entryID, nonce)
+	if _, ok := notAvailable(err); ok {
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if entry == nil {
+		return api.Error{UserID: userID, StatusCode: http.StatusNotFound, Code: "entry_not_found", Message: "The entry doesn't exist."}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// DeferClaim lets the recipient put off claiming the entry. They're emailed a
+// reminder before it expires.
+func (c *EntriesController) DeferClaim(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	var req api.DeferClaimRequest
+	if err := decodeJSON(r, &req); err != nil {
+		return respond(w, http.StatusBadRequest, api.DeferClaimResponse{Envelope: invalidBody(r, err)})
+	}
+
+	service, err := c.entries(r)
+	if err != nil {
+		return err
+	}
+	resp, err := service.DeferClaim(app.DeferClaimRequest{
+		ID:    idParam(r, "entryID"),
+		Nonce: req.Nonce,
+		Delay: time.Duration(req.DelaySeconds) * time.Second,
+	})
+	if err != nil {
+		return err
+	}
+
+	model := api.DeferClaimResponse{
+		Envelope: envelope(r, resp.Success, resp.Errors),
+		Deferral: resp.Deferral,
+	}
+	return respond(w, envelopeStatus(model.Envelope), model)
+}
+
+// ResendLink issues a new claim link for one of the user's entries, which
+// stops the entry's earlier links from working.
+func (c *EntriesController) ResendLink(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	var req api.ResendLinkRequest
+	if err := decodeJSON(r, &req); err != nil {
+		return respond(w, http.StatusBadRequest, api.ResendLinkResponse{Envelope: invalidBody(r, err)})
+	}
+
+	userID, err := c.GetCurrentUserID(r)
+	if err != nil {
+		return err
+	}
+	service, err := c.entries(r)
+	if err != nil {
+		return err
+	}
+	resp, err := service.ResendClaimLink(app.ResendClaimLinkRequest{
+		EntryID:  idParam(r, "entryID"),
+		SenderID: userID,
+		TTL:      time.Duration(req.TTLSeconds) * time.Second,
+	})
+	if err != nil {
+		return err
+	}
+
+	model := api.ResendLinkResponse{
+		Envelope: envelope(r, resp.Success, resp.Errors),
+		Link:     resp.Link,
+	}
+	if resp.Success {
+		model.ClaimURL = c.claimURL(*resp.Entry, resp.Link)
+	}
+	return respond(w, envelopeStatus(model.Envelope), model)
+}
+
+// ClaimLinks lists the links issued for one of the user's entries with their
+// statuses, newest first.
+func (c *EntriesController) ClaimLinks(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	userID, err := c.GetCurrentUserID(r)
+	if err != nil {
+		return err
+	}
+	service, err := c.entries(r)
+	if err != nil {
+		return err
+	}
+
+	entry, err := service.FindSentEntry(idParam(r, "entryID"), userID)
+	if err != nil {
+		return err
+	}
+	if entry == nil {
+		return api.Error{UserID: userID, StatusCode: http.StatusNotFound, Code: "entry_not_found", Message: "The entry doesn't exist."}
+	}
+	links, err := service.FindClaimLinks(*entry)
+	if err != nil {
+		return err
+	}
+
+	if links == nil {
+		links = []sendkey.ClaimLink{}
+	}
+	return respond(w, http.StatusOK, links)
+}
+
+// claimURL returns the URL the recipient claims the entry with: the link
+// issued for it, or its original link if it hasn't had one.
+func (c *EntriesController) claimURL(e sendkey.Entry, link *sendkey.ClaimLink) string {
+	if link != nil {
+		return c.links.LinkURL(*link)
+	}
+	return c.links.URL(e)
+}
+
+// DelegateClaim makes a token for one of the user's entries that a bot or
+// service can claim it with, without the secret.
+func (c *EntriesController) DelegateClaim(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	var req api.DelegateClaimRequest
+	if err := decodeJSON(r, &req); err != nil {
+		return respond(w, http.StatusBadRequest, api.DelegateClaimResponse{Envelope: invalidBody(r, err)})
+	}
+
+	userID, err := c.GetCurrentUserID(r)
+	if err != nil {
+		return err
+	}
+	service, err := c.entries(r)
+	if err != nil {
+		return err
+	}
+	resp, err := service.DelegateClaim(app.DelegateClaimRequest{
+		EntryID:  idParam(r, "entryID"),
+		SenderID: userID,
+		Secret:   req.Secret,
+		TTL:      time.Duration(req.TTLSeconds) * time.Second,
+	})
+	if err != nil {
+		return err
+	}
+
+	model := api.DelegateClaimResponse{
+		Envelope: envelope(r, resp.Success, resp.Errors),
+		Token:    resp.Token,
+	}
+	if resp.Success {
+		model.ExpiresAtUTC = &resp.ExpiresAtUTC
+	}
+	return respond(w, envelopeStatus(model.Envelope), model)
+}
+
+// ClaimDelegated claims an entry with a delegated claim token. Like
+// EntryValue, it responds with 200 and the envelope either way.
+func (c *EntriesController) ClaimDelegated(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	var req api.DelegatedClaimRequest
+	if err := decodeJSON(r, &req); err != nil {
+		return respond(w, http.StatusBadRequest, api.ClaimEntryResponse{Envelope: invalidBody(r, err)})
+	}
+
+	service, err := c.entries(r)
+	if err != nil {
+		return err
+	}
+	resp, err := service.ClaimDelegated(idParam(r, "entryID"), req.Token)
+	if err != nil {
+		return err
+	}
+
+	model := api.ClaimEntryResponse{
+		Envelope: envelope(r, resp.Success, resp.Errors),
+		Receipt:  resp.Receipt,
+		Digest:   resp.Digest,
+	}
+	if resp.Entry != nil {
+		v := string(resp.Entry.Value)
+		model.Value = &v
+	}
+	return respond(w, http.StatusOK, model)
+}
+
+// SetFlag returns an action that sets one of the user's flags on an entry:
+// pinned if pin is set, and favorite otherwise.
+func (c *EntriesController) SetFlag(pin, on bool) action {
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+		userID, err := c.GetCurrentUserID(r)
+		if err != nil {
+			return err
+		}
+		service, err := c.entries(r)
+		if err != nil {
+			return err
+		}
+
+		req := app.SetEntryFlagsRequest{EntryID: idParam(r, "entryID"), UserID: userID}
+		if pin {
+			req.Pinned = &on
+		} else {
+			req.Favorite = &on
+		}
+		resp, err := service.SetEntryFlags(req)
+		if err != nil {
+			return err
+		}
+
+		model := api.EntryFlagsResponse{
+			Envelope: envelope(r, resp.Success, resp.Errors),
+			Flags:    resp.Flags,
+		}
+		return respond(w, envelopeStatus(model.Envelope), model)
+	}
+}
+
+func (c *EntriesController) VerifyReceipt(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	var receipt sendkey.ClaimReceipt
+	if err := decodeJSON(r, &receipt); err != nil {
+		return api.Error{StatusCode: http.StatusBadRequest, Code: "invalid_body", Message: err.Error()}
+	}
+
+	return respond(w, http.StatusOK, api.VerifyReceiptResponse{Valid: c.service.VerifyReceipt(receipt)})
+}
@@ -0,0 +1,27 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestCreateEntryBodyTooLarge(t *testing.T) {
+	c := &EntriesController{maxBody: 16}
+	r := httptest.NewRequest(http.MethodPost, "/v1/entries", strings.NewReader(`{"name":"`+strings.Repeat("a", 32)+`"}`))
+	r = r.WithContext(context.WithValue(r.Context(), userIDCtxKeyValue, uuid.New()))
+
+	w := httptest.NewRecorder()
+	cleanOutput(c.CreateEntry)(w, r, nil)
+
+	var e struct{ Code string }
+	json.Unmarshal(w.Body.Bytes(), &e)
+	if w.Code != http.StatusRequestEntityTooLarge || e.Code != "body_too_large" {
+		t.Errorf("got %d %s, want body_too_large", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,138 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gavinwade12/sendkey/internal/app"
+	"github.com/gavinwade12/sendkey/internal/events"
+	"github.com/gavinwade12/sendkey/pkg/api"
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// eventStreamPath is the event stream's route, which limitRequests leaves
+// open past the handler timeout.
+const eventStreamPath = "/events"
+
+const (
+	// eventStreamBuffer is how many events a stream holds for a slow client
+	// before it's closed for falling behind.
+	eventStreamBuffer = 64
+	// eventStreamHeartbeat is how often an idle stream sends a comment, so
+	// proxies don't close it.
+	eventStreamHeartbeat = 30 * time.Second
+)
+
+// streamedEvents are the events sent on the event stream.
+var streamedEvents = map[string]bool{
+	"entry.claimed":  true,
+	"entry.expired":  true,
+	"entry.revoked":  true,
+	"entry.replaced": true,
+}
+
+// EventStreamController streams the events about the user's entries to them
+// as server-sent events, so a web UI or CLI can show their status as it
+// changes instead of polling. The stream's authenticated like any request,
+// with a bearer token, so browsers read it with fetch rather than
+// EventSource.
+//
+// Events come from this instance's event bus, so behind a load balancer a
+// stream only has the events of the requests its instance served. Events
+// published while a client is disconnected aren't replayed when it
+// reconnects; it should refresh its entries instead.
+type EventStreamController struct {
+	baseController
+
+	bus *events.Bus
+	// maxAge closes streams once their access token could have expired, so a
+	// client whose session is revoked stops getting events.
+	maxAge time.Duration
+}
+
+// Stream sends the user's entry events until the client disconnects. Each is
+// an api.EntryEvent, named for its type and with its ID as the event's.
+func (c *EventStreamController) Stream(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	userID, err := c.GetCurrentUserID(r)
+	if err != nil {
+		return err
+	}
+
+	sub := c.bus.Subscribe(eventStreamBuffer, func(e app.Event) bool {
+		return streamedEvents[e.Type] && e.Data["sentBy"] == userID.String()
+	})
+	defer sub.Close()
+
+	rc := http.NewResponseController(w)
+	// the server's write timeout would cut the stream off
+	rc.SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, ": connected\n\n")
+	if err := rc.Flush(); err != nil {
+		requestLogger(r).Error("events: streaming isn't supported", "error", err)
+		return nil
+	}
+
+	heartbeat := time.NewTicker(eventStreamHeartbeat)
+	defer heartbeat.Stop()
+	expired := time.NewTimer(c.maxAge)
+	defer expired.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return nil
+		case <-expired.C:
+			return nil
+		case e, ok := <-sub.Events():
+			if !ok {
+				// the client fell behind, and reconnects
+				return nil
+			}
+			if err := writeEntryEvent(w, e); err != nil {
+				return nil
+			}
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+		}
+		if err := rc.Flush(); err != nil {
+			return nil
+		}
+	}
+}
+
+// writeEntryEvent writes the event as a server-sent event. The claimed
+// value's hash is left out; it's for downstream systems holding its key.
+func writeEntryEvent(w http.ResponseWriter, e app.Event) error {
+	entryID, _ := uuid.Parse(e.Data["entryId"])
+	ee := api.EntryEvent{ID: e.ID, Type: e.Type, EntryID: entryID, AtUTC: e.AtUTC}
+	for k, v := range e.Data {
+		switch k {
+		case "entryId", "sentBy", "valueHash", "valueHashAlgorithm":
+			continue
+		}
+		if ee.Data == nil {
+			ee.Data = make(map[string]string)
+		}
+		ee.Data[k] = v
+	}
+
+	b, err := json.Marshal(ee)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", e.ID, e.Type, b)
+	return err
+}
+
+// isEventStream reports whether the request is for the event stream, in any
+// version of the API.
+func isEventStream(r *http.Request) bool {
+	return r.Method == http.MethodGet &&
+		(r.URL.Path == api.BasePath+eventStreamPath || r.URL.Path == eventStreamPath)
+}
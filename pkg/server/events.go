@@ -0,0 +1,82 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"github.com/gavinwade12/sendkey/internal/app"
+	"github.com/gavinwade12/sendkey/internal/events"
+)
+
+// eventsConfig publishes domain events (entry.created, entry.claimed,
+// entry.expired, entry.revoked, and user.created) for downstream pipelines.
+type eventsConfig struct {
+	// Exporter is "kafka" to post events to KafkaTopic through the Kafka
+	// REST proxy at KafkaProxyURL, or "nats" to publish them to the server
+	// at NATSURL under NATSSubjectPrefix, e.g. "sendkey.entry.created".
+	// Empty doesn't publish events.
+	Exporter          string
+	KafkaProxyURL     string
+	KafkaTopic        string
+	NATSURL           string
+	NATSSubjectPrefix string
+	// NATSToken is the NATS server's auth token. NATSCAFile is a PEM bundle
+	// of CA certificates trusted in addition to the system's for a "tls://"
+	// NATSURL, or a server that requires TLS.
+	NATSToken  string
+	NATSCAFile string
+	// QueueDepth is how many events wait to be published before new ones
+	// are dropped. Zero uses 1000.
+	QueueDepth int
+	// ClaimedValueHashKey, if set, adds an HMAC-SHA256 of the claimed
+	// value keyed with it to entry.claimed events, so systems given the key
+	// can reconcile which credential was handed over. Keep it as secret as
+	// the values; anyone with it can test guesses against the hashes.
+	ClaimedValueHashKey string
+}
+
+// queue returns a queue publishing to the configured exporter, or nil if
+// there isn't one.
+func (c eventsConfig) queue() (*events.Queue, error) {
+	var p app.EventPublisher
+	switch c.Exporter {
+	case "":
+		return nil, nil
+	case "kafka":
+		if c.KafkaProxyURL == "" || c.KafkaTopic == "" {
+			return nil, fmt.Errorf("events: KafkaProxyURL and KafkaTopic are required for the kafka exporter")
+		}
+		p = events.NewKafka(c.KafkaProxyURL, c.KafkaTopic)
+	case "nats":
+		o := events.NATSOptions{Token: c.NATSToken}
+		if c.NATSCAFile != "" {
+			pem, err := os.ReadFile(c.NATSCAFile)
+			if err != nil {
+				return nil, fmt.Errorf("events: reading NATSCAFile: %w", err)
+			}
+			pool, err := x509.SystemCertPool()
+			if err != nil {
+				pool = x509.NewCertPool()
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("events: no certificates found in %s", c.NATSCAFile)
+			}
+			o.TLS = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+		}
+		n, err := events.NewNATS(c.NATSURL, c.NATSSubjectPrefix, o)
+		if err != nil {
+			return nil, fmt.Errorf("events: %w", err)
+		}
+		p = n
+	default:
+		return nil, fmt.Errorf("events: unknown exporter %q", c.Exporter)
+	}
+
+	depth := c.QueueDepth
+	if depth <= 0 {
+		depth = 1000
+	}
+	return events.NewQueue(p, depth), nil
+}
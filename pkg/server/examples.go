@@ -0,0 +1,186 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/gavinwade12/sendkey/pkg/api"
+	"github.com/julienschmidt/httprouter"
+)
+
+type exampleSummary struct {
+	ID      string `json:"id"`
+	Method  string `json:"method"`
+	Path    string `json:"path"`
+	Summary string `json:"summary"`
+}
+
+// ListExamples lists the operations that have examples.
+func ListExamples(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	summaries := make([]exampleSummary, len(api.Operations))
+	for i, o := range api.Operations {
+		summaries[i] = exampleSummary{o.ID, o.Method, o.Path, o.Summary}
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].ID < summaries[j].ID })
+
+	return respond(w, http.StatusOK, summaries)
+}
+
+// Example returns an operation's example request and response. With
+// ?format=har it's a HAR log instead, which Postman and browsers' dev tools
+// can import and replay against this server.
+func Example(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	o, ok := api.FindOperation(p.ByName("operation"))
+	if !ok {
+		return api.Error{StatusCode: http.StatusNotFound, Code: "unknown_operation", Message: "There's no example for the operation."}
+	}
+
+	switch r.URL.Query().Get("format") {
+	case "":
+		return respond(w, http.StatusOK, o)
+	case "har":
+		h, err := exampleHAR(r, o)
+		if err != nil {
+			return err
+		}
+		return respond(w, http.StatusOK, h)
+	default:
+		return api.Error{StatusCode: http.StatusBadRequest, Code: "invalid_format", Message: `The format must be "har" or empty.`}
+	}
+}
+
+// The subset of HAR 1.2 needed to replay a request.
+type (
+	harFile struct {
+		Log harLog `json:"log"`
+	}
+	harLog struct {
+		Version string     `json:"version"`
+		Creator harCreator `json:"creator"`
+		Entries []harEntry `json:"entries"`
+	}
+	harCreator struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	}
+	harEntry struct {
+		StartedDateTime string      `json:"startedDateTime"`
+		Time            int         `json:"time"`
+		Request         harRequest  `json:"request"`
+		Response        harResponse `json:"response"`
+		Cache           struct{}    `json:"cache"`
+		Timings         harTimings  `json:"timings"`
+	}
+	harRequest struct {
+		Method      string         `json:"method"`
+		URL         string         `json:"url"`
+		HTTPVersion string         `json:"httpVersion"`
+		Headers     []harNameValue `json:"headers"`
+		QueryString []harNameValue `json:"queryString"`
+		Cookies     []harNameValue `json:"cookies"`
+		PostData    *harContent    `json:"postData,omitempty"`
+		HeadersSize int            `json:"headersSize"`
+		BodySize    int            `json:"bodySize"`
+	}
+	harResponse struct {
+		Status      int            `json:"status"`
+		StatusText  string         `json:"statusText"`
+		HTTPVersion string         `json:"httpVersion"`
+		Headers     []harNameValue `json:"headers"`
+		Cookies     []harNameValue `json:"cookies"`
+		Content     harContent     `json:"content"`
+		RedirectURL string         `json:"redirectURL"`
+		HeadersSize int            `json:"headersSize"`
+		BodySize    int            `json:"bodySize"`
+	}
+	harNameValue struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	}
+	harContent struct {
+		Size     int    `json:"size,omitempty"`
+		MimeType string `json:"mimeType"`
+		Text     string `json:"text"`
+	}
+	harTimings struct {
+		Send    int `json:"send"`
+		Wait    int `json:"wait"`
+		Receive int `json:"receive"`
+	}
+)
+
+// exampleHAR builds a HAR log with the example's request against this
+// server's current API version. Path parameters are filled with the example's values, and
+// operations that need auth have a placeholder bearer token to replace.
+func exampleHAR(r *http.Request, o api.Operation) (*harFile, error) {
+	path, err := o.Expand(o.Params)
+	if err != nil {
+		return nil, err
+	}
+	u := url.URL{Scheme: requestScheme(r), Host: r.Host, Path: api.BasePath + path}
+
+	req := harRequest{
+		Method:      o.Method,
+		HTTPVersion: "HTTP/1.1",
+		Headers:     []harNameValue{{"Accept", "application/json"}},
+		QueryString: []harNameValue{},
+		Cookies:     []harNameValue{},
+		HeadersSize: -1,
+		BodySize:    0,
+	}
+	if o.Auth {
+		req.Headers = append(req.Headers, harNameValue{"Authorization", "Bearer <access token>"})
+	}
+	q := url.Values{}
+	for name, v := range o.Query {
+		q.Set(name, v)
+		req.QueryString = append(req.QueryString, harNameValue{name, v})
+	}
+	sort.Slice(req.QueryString, func(i, j int) bool { return req.QueryString[i].Name < req.QueryString[j].Name })
+	u.RawQuery = q.Encode()
+	req.URL = u.String()
+	if o.Request != nil {
+		body, err := json.Marshal(o.Request)
+		if err != nil {
+			return nil, err
+		}
+		req.Headers = append(req.Headers, harNameValue{"Content-Type", "application/json"})
+		req.PostData = &harContent{MimeType: "application/json", Text: string(body)}
+		req.BodySize = len(body)
+	}
+
+	body, err := json.Marshal(o.Response)
+	if err != nil {
+		return nil, err
+	}
+	return &harFile{Log: harLog{
+		Version: "1.2",
+		Creator: harCreator{"sendkey", "1"},
+		Entries: []harEntry{{
+			StartedDateTime: "2022-03-01T12:00:00.000Z",
+			Request:         req,
+			Response: harResponse{
+				Status:      o.Status,
+				StatusText:  http.StatusText(o.Status),
+				HTTPVersion: "HTTP/1.1",
+				Headers:     []harNameValue{{"Content-Type", "application/json"}},
+				Cookies:     []harNameValue{},
+				Content:     harContent{Size: len(body), MimeType: "application/json", Text: string(body)},
+				HeadersSize: -1,
+				BodySize:    len(body),
+			},
+		}},
+	}}, nil
+}
+
+// requestScheme returns the scheme the client used to reach the server, which
+// is https if a proxy in front of it terminated TLS.
+func requestScheme(r *http.Request) string {
+	if r.TLS == nil && !strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https") {
+		return "http"
+	}
+	return "https"
+}
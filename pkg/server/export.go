@@ -0,0 +1,42 @@
+package server
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/gavinwade12/sendkey/internal/app"
+)
+
+// exportEntries writes the unexpired entries to a new file at path. The file
+// only holds wrapped values, but it should still be handled like a backup.
+func exportEntries(entries *app.EntryService, path string) (int, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := entries.ExportEntries(f, 100)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return n, fmt.Errorf("exporting entries: %w", err)
+	}
+	return n, nil
+}
+
+// importEntries imports the entries in the file at path. It can be run again
+// after a failure; entries that were already imported are skipped.
+func importEntries(entries *app.EntryService, path string) (*app.ImportEntriesResponse, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	resp, err := entries.ImportEntries(f)
+	if err != nil {
+		return resp, fmt.Errorf("importing entries: %w", err)
+	}
+	return resp, nil
+}
@@ -0,0 +1,110 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/gavinwade12/sendkey/internal/app"
+	"github.com/gavinwade12/sendkey/pkg/api"
+	"github.com/julienschmidt/httprouter"
+)
+
+// externalLoginLifetime is how long the user has to finish an external login
+// that needs an MFA code, or a login to the account to link the identity.
+const externalLoginLifetime = 10 * time.Minute
+
+// purposeKey derives a key for one purpose from the signing key, so a token
+// signed for one purpose, like an access token, can't be passed off as
+// another, like an OIDC state.
+func purposeKey(key []byte, purpose string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
+
+type externalLoginClaims struct {
+	Identity app.ExternalLoginRequest `json:"identity"`
+	jwt.StandardClaims
+}
+
+// pendingExternalLogin signs the identity from an external login that
+// couldn't finish yet, for the client to send back once it can.
+func (c *UsersController) pendingExternalLogin(identity app.ExternalLoginRequest) (string, error) {
+	identity.MFACode = ""
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, externalLoginClaims{
+		Identity: identity,
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: time.Now().Add(externalLoginLifetime).Unix(),
+		},
+	}).SignedString(c.externalKey)
+}
+
+// verifyExternalLogin returns the identity from a token pendingExternalLogin
+// signed, if it's valid.
+func (c *UsersController) verifyExternalLogin(token string) (*app.ExternalLoginRequest, bool) {
+	var claims externalLoginClaims
+	_, err := jwt.ParseWithClaims(token, &claims, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+		return c.externalKey, nil
+	})
+	if err != nil {
+		return nil, false
+	}
+	return &claims.Identity, true
+}
+
+// writeExternalLoginResponse writes the response to an external login. A
+// login that needs an MFA code, or a login to the account with the
+// identity's email, returns the identity as a token to finish it with.
+func (c *UsersController) writeExternalLoginResponse(w http.ResponseWriter, r *http.Request, identity app.ExternalLoginRequest, resp *app.UserLoginResponse) error {
+	if resp.Success || !(mfaCodeMissing(resp.Errors) || linkRequired(resp.Errors)) {
+		return c.writeLoginResponse(w, r, resp)
+	}
+
+	token, err := c.pendingExternalLogin(identity)
+	if err != nil {
+		return err
+	}
+	return respond(w, http.StatusBadRequest, api.LoginResponse{Envelope: envelope(r, false, resp.Errors), ExternalLogin: token})
+}
+
+// linkRequired reports whether an external login failed because its
+// identity has the email of a user it isn't linked to.
+func linkRequired(problems []app.Problem) bool {
+	return len(problems) == 1 && problems[0].Code == "identity_link_required"
+}
+
+// CompleteExternalLogin finishes an external login that needed an MFA code.
+func (c *UsersController) CompleteExternalLogin(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	var req api.ExternalLoginRequest
+	if err := decodeJSON(r, &req); err != nil {
+		return respond(w, http.StatusBadRequest, api.LoginResponse{Envelope: invalidBody(r, err)})
+	}
+
+	identity, ok := c.verifyExternalLogin(req.ExternalLogin)
+	if !ok {
+		return respond(w, http.StatusBadRequest, api.LoginResponse{Envelope: envelope(r, false, []app.Problem{{Field: "externalLogin", Code: "external_login_invalid"}})})
+	}
+
+	keys := []string{"login:ip:" + clientIP(r), "login:external:" + identity.Provider + ":" + identity.Subject}
+	if err := c.attemptLogin(w, keys); err != nil {
+		return err
+	}
+
+	identity.MFACode = req.MFACode
+	resp, err := tracedUsers(r, c.service).ExternalLogin(*identity)
+	if err != nil {
+		return err
+	}
+	if err = c.recordLogin(keys, resp); err != nil {
+		return err
+	}
+
+	return c.writeExternalLoginResponse(w, r, *identity, resp)
+}
@@ -0,0 +1,253 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gavinwade12/sendkey/internal/app"
+	"github.com/gavinwade12/sendkey/internal/redact"
+	"github.com/gavinwade12/sendkey/pkg/api"
+	"github.com/google/uuid"
+	graphql "github.com/graph-gophers/graphql-go"
+	gqlerrors "github.com/graph-gophers/graphql-go/errors"
+	"github.com/julienschmidt/httprouter"
+)
+
+// graphQLMaxDepth limits how deeply a query's fields can be nested, which
+// leaves plenty of room for the dashboard's queries. How many fields a query
+// can select is bounded by the request body's size.
+const graphQLMaxDepth = 10
+
+// GraphQLController serves the web dashboard's queries over GraphQL, so a view
+// showing the user and their entries loads in one round trip. Its fields are
+// resolved with the same services and response models as the JSON routes;
+// graphQLSchema has the schema.
+//
+// Expired entries are the ones that left without being claimed, whether they
+// expired or were revoked; their outcome says which. The history's after
+// argument is the previous page's next cursor.
+type GraphQLController struct {
+	baseController
+
+	entries *EntriesController
+	users   *app.UserService
+	// frozen refuses mutations while writes are frozen; see writeGuard.
+	frozen bool
+	schema *graphql.Schema
+}
+
+func newGraphQLController(bc baseController, entries *EntriesController, users *app.UserService, frozen bool) *GraphQLController {
+	c := &GraphQLController{baseController: bc, entries: entries, users: users, frozen: frozen}
+	// the resolvers share the request's ResponseWriter, e.g. to set
+	// Retry-After, so they're run one at a time
+	c.schema = graphql.MustParseSchema(graphQLSchema, c,
+		graphql.UseFieldResolvers(),
+		graphql.MaxDepth(graphQLMaxDepth),
+		graphql.MaxParallelism(1),
+	)
+	return c
+}
+
+// graphQLRequest is a GraphQL request as clients post it.
+type graphQLRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+	// Extensions are for clients' extensions to the protocol, like persisted
+	// queries. They're ignored.
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+// graphQLCall is the HTTP request a GraphQL request came in, which the
+// resolvers get from their context.
+type graphQLCall struct {
+	w      http.ResponseWriter
+	r      *http.Request
+	userID uuid.UUID
+}
+
+type graphQLCallCtxKey struct{}
+
+func callFrom(ctx context.Context) graphQLCall {
+	call, _ := ctx.Value(graphQLCallCtxKey{}).(graphQLCall)
+	return call
+}
+
+// Query executes a GraphQL request for the current user. A request the
+// fields fail for still succeeds, with the fields' errors in the response,
+// which have the JSON API's error codes in their extensions.
+func (c *GraphQLController) Query(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	var req graphQLRequest
+	r.Body = http.MaxBytesReader(w, r.Body, c.entries.maxBody)
+	if err := decodeJSON(r, &req); err != nil {
+		return respond(w, http.StatusBadRequest, graphql.Response{Errors: []*gqlerrors.QueryError{{
+			Message:    messages(r, []app.Problem{{Code: "invalid_body", Args: []interface{}{err.Error()}}})[0],
+			Extensions: map[string]interface{}{"code": "invalid_body"},
+		}}})
+	}
+
+	userID, err := c.GetCurrentUserID(r)
+	if err != nil {
+		return err
+	}
+	ctx := context.WithValue(r.Context(), graphQLCallCtxKey{}, graphQLCall{w, r, userID})
+	return respond(w, http.StatusOK, c.schema.Exec(ctx, req.Query, req.OperationName, req.Variables))
+}
+
+// graphQLError is a resolver's error, with its code and the request's ID in
+// its extensions.
+type graphQLError struct {
+	e api.Error
+}
+
+func (e graphQLError) Error() string {
+	return e.e.Message
+}
+
+func (e graphQLError) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": e.e.Code, "requestId": e.e.RequestID}
+}
+
+// fail converts a resolver's error to a GraphQL error, the way cleanOutput
+// converts an action's: unexpected errors are only logged.
+func (call graphQLCall) fail(err error) error {
+	e, ok := dbUnavailable(call.w, call.r, err)
+	if !ok {
+		if e, ok = err.(api.Error); !ok {
+			requestLogger(call.r).Error("graphql: resolver failed", "error", redact.String(err.Error()))
+			e = internalError(call.r)
+		}
+	}
+	e.RequestID = requestID(call.r)
+	return graphQLError{e}
+}
+
+func (c *GraphQLController) Me(ctx context.Context) (*userResolver, error) {
+	call := callFrom(ctx)
+	u, err := tracedUsers(call.r, c.users).FindUser(call.userID)
+	if err != nil {
+		return nil, call.fail(err)
+	}
+	if u == nil {
+		return nil, nil
+	}
+	return &userResolver{*u}, nil
+}
+
+func (c *GraphQLController) LiveEntries(ctx context.Context) (*[]entryResolver, error) {
+	call := callFrom(ctx)
+	service, err := c.entries.entries(call.r)
+	if err != nil {
+		return nil, call.fail(err)
+	}
+	entries, err := service.FindByUserID(call.userID)
+	if err != nil {
+		return nil, call.fail(err)
+	}
+	return entryResolvers(entries), nil
+}
+
+type historyArgs struct {
+	Limit *int32
+	After *string
+}
+
+func (c *GraphQLController) ClaimedEntries(ctx context.Context, args historyArgs) (*historyResolver, error) {
+	return c.history(callFrom(ctx), true, args)
+}
+
+func (c *GraphQLController) ExpiredEntries(ctx context.Context, args historyArgs) (*historyResolver, error) {
+	return c.history(callFrom(ctx), false, args)
+}
+
+func (c *GraphQLController) history(call graphQLCall, claimed bool, args historyArgs) (*historyResolver, error) {
+	req := app.EntryHistoryRequest{}
+	if args.Limit != nil {
+		if *args.Limit < 0 {
+			return nil, call.fail(api.Error{UserID: call.userID, StatusCode: http.StatusBadRequest, Code: "invalid_limit", Message: "The limit must be a positive number."})
+		}
+		req.Limit = int(*args.Limit)
+	}
+	if args.After != nil {
+		req.Cursor = *args.After
+	}
+
+	service, err := c.entries.entries(call.r)
+	if err != nil {
+		return nil, call.fail(err)
+	}
+	req.Filter.SentByUserID = &call.userID
+	req.Filter.Claimed = &claimed
+	resp, err := service.EntryHistory(req)
+	if err != nil {
+		return nil, call.fail(err)
+	}
+
+	return &historyResolver{api.EntryHistoryResponse{
+		Envelope: envelope(call.r, resp.Success, resp.Errors),
+		Outcomes: resp.Outcomes,
+		Next:     resp.Next,
+	}}, nil
+}
+
+func (c *GraphQLController) CreateEntry(ctx context.Context, args struct{ Input createEntryInput }) (*createEntryResolver, error) {
+	call := callFrom(ctx)
+	if c.frozen {
+		return nil, call.fail(readOnly(call.w))
+	}
+
+	// the input has the JSON request's fields, so it's decoded the same way
+	var input api.CreateEntryRequest
+	b, err := json.Marshal(args.Input)
+	if err == nil {
+		err = json.Unmarshal(b, &input)
+	}
+	if err != nil {
+		return nil, call.fail(api.Error{UserID: call.userID, StatusCode: http.StatusBadRequest, Code: "invalid_arguments", Message: "The arguments are invalid: " + err.Error()})
+	}
+
+	entryReq, problems := createEntryRequest(call.w, call.userID, input)
+	if len(problems) > 0 {
+		return &createEntryResolver{api.CreateEntryResponse{Envelope: envelope(call.r, false, problems)}}, nil
+	}
+	resp, err := c.entries.createEntry(call.r, entryReq)
+	if err != nil {
+		return nil, call.fail(err)
+	}
+	return &createEntryResolver{*resp}, nil
+}
+
+// RevokeEntry revokes one of the user's entries so it can't be claimed. Like
+// an admin expiring an entry, the recipient isn't notified.
+func (c *GraphQLController) RevokeEntry(ctx context.Context, args struct{ ID graphql.ID }) (*expiredEntryResolver, error) {
+	call := callFrom(ctx)
+	if c.frozen {
+		return nil, call.fail(readOnly(call.w))
+	}
+	notFound := api.Error{UserID: call.userID, StatusCode: http.StatusNotFound, Code: "entry_not_found", Message: "The entry doesn't exist."}
+	id, err := uuid.Parse(string(args.ID))
+	if err != nil {
+		return nil, call.fail(notFound)
+	}
+
+	service, err := c.entries.entries(call.r)
+	if err != nil {
+		return nil, call.fail(err)
+	}
+	entry, err := service.FindSentEntry(id, call.userID)
+	if err != nil {
+		return nil, call.fail(err)
+	}
+	if entry == nil {
+		return nil, call.fail(notFound)
+	}
+	ee, err := service.ExpireEntry(entry.ID)
+	if err != nil {
+		return nil, call.fail(err)
+	}
+	if ee == nil {
+		return nil, call.fail(notFound)
+	}
+	return &expiredEntryResolver{*ee}, nil
+}
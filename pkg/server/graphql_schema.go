@@ -0,0 +1,444 @@
+package server
+
+import (
+	"time"
+
+	"github.com/gavinwade12/sendkey"
+	"github.com/gavinwade12/sendkey/pkg/api"
+	"github.com/google/uuid"
+	graphql "github.com/graph-gophers/graphql-go"
+)
+
+// graphQLSchema is the dashboard's GraphQL schema. Its types have the fields
+// of the JSON models they're resolved from, by the same names; bytes are
+// base64 strings, as they are in JSON.
+const graphQLSchema = `
+schema {
+	query: Query
+	mutation: Mutation
+}
+
+scalar Time
+
+type Query {
+	me: User
+	liveEntries: [Entry!]
+	claimedEntries(limit: Int, after: String): EntryHistoryResponse
+	expiredEntries(limit: Int, after: String): EntryHistoryResponse
+}
+
+type Mutation {
+	createEntry(input: CreateEntryRequest!): CreateEntryResponse
+	revokeEntry(id: ID!): ExpiredEntry
+}
+
+type User {
+	id: ID!
+	email: String!
+	emailVerified: Boolean!
+	firstName: String!
+	lastName: String!
+	mfaEnabled: Boolean!
+	createdAtUtc: Time!
+	role: String!
+	deactivatedAtUtc: Time
+	disabledAtUtc: Time
+	passwordResetAtUtc: Time
+}
+
+type Entry {
+	id: ID!
+	name: String!
+	sentByUserId: ID!
+	sentToEmail: String!
+	invalidAttempts: Int!
+	locale: String!
+	endToEnd: Boolean!
+	type: String!
+	metadata: EntryMetadata!
+	createdAtUtc: Time!
+	availableAtUtc: Time
+	expiresAtUtc: Time!
+	requireLogin: Boolean!
+	requireOtp: Boolean!
+	acknowledgment: String!
+	sandbox: Boolean!
+	anonymousSenderId: ID
+	replacesEntryId: ID
+	challenges: [EntryChallenge!]!
+	deferrals: [EntryDeferral!]!
+	flags: EntryFlags!
+}
+
+type EntryMetadata {
+	username: String!
+	hostname: String!
+}
+
+type EntryChallenge {
+	question: String!
+}
+
+type EntryDeferral {
+	id: ID!
+	entryId: ID!
+	deferredAtUtc: Time!
+	remindAtUtc: Time!
+	remindedAtUtc: Time
+}
+
+type EntryFlags {
+	pinned: Boolean!
+	favorite: Boolean!
+}
+
+type ExpiredEntry {
+	entryId: ID!
+	name: String!
+	sentByUserId: ID!
+	sentToEmail: String!
+	tooManyAttempts: Boolean!
+	revoked: Boolean!
+	expiredAtUtc: Time!
+	replacedByEntryId: ID
+}
+
+type EntryOutcome {
+	entryId: ID!
+	name: String!
+	sentByUserId: ID!
+	sentToEmail: String!
+	outcome: String!
+	atUtc: Time!
+	replacesEntryId: ID
+	replacedByEntryId: ID
+	acknowledgment: String!
+	acknowledgedAtUtc: Time
+}
+
+type ClaimLink {
+	id: ID!
+	entryId: ID!
+	status: String!
+	createdAtUtc: Time!
+	expiresAtUtc: Time!
+	supersededAtUtc: Time
+}
+
+type FieldError {
+	field: String!
+	code: String!
+	message: String!
+}
+
+type Warning {
+	code: String!
+	message: String!
+	sunset: Time
+	successor: String!
+}
+
+type EntryHistoryResponse {
+	success: Boolean!
+	errors: [FieldError!]!
+	warnings: [Warning!]!
+	outcomes: [EntryOutcome!]!
+	next: String!
+}
+
+type CreateEntryResponse {
+	success: Boolean!
+	errors: [FieldError!]!
+	warnings: [String!]!
+	entry: Entry
+	claimUrl: String!
+	link: ClaimLink
+}
+
+input CreateEntryRequest {
+	name: String
+	sendToEmail: String
+	value: String
+	secret: String
+	duration: String
+	durationSeconds: Int
+	availableAtUtc: Time
+	locale: String
+	endToEnd: SealedValue
+	generate: GeneratePolicy
+	type: String
+	metadata: EntryMetadataInput
+	requireLogin: Boolean
+	requireOtp: Boolean
+	acknowledgment: String
+	digest: ValueDigest
+	replacesEntryId: ID
+	captchaToken: String
+	challenges: [EntryChallengeInput!]
+}
+
+input SealedValue {
+	ciphertext: String!
+	nonce: String!
+	cipher: String!
+	kdf: EntryKDF!
+}
+
+input EntryKDF {
+	algorithm: String!
+	salt: String!
+	time: Int!
+	memoryKiB: Int!
+	threads: Int!
+}
+
+input GeneratePolicy {
+	length: Int
+	charset: String
+	words: Int
+	separator: String
+}
+
+input EntryMetadataInput {
+	username: String
+	hostname: String
+}
+
+input ValueDigest {
+	algorithm: String!
+	salt: String!
+	mac: String!
+}
+
+input EntryChallengeInput {
+	question: String!
+	answer: String!
+}
+`
+
+// The resolvers below embed the models the schema's types are resolved
+// from. Fields the schema has the same type for are resolved from the
+// model's fields; the methods convert the rest.
+
+type userResolver struct{ sendkey.User }
+
+func (u userResolver) ID() graphql.ID                  { return graphQLID(u.User.ID) }
+func (u userResolver) CreatedAtUTC() graphql.Time      { return graphql.Time{Time: u.User.CreatedAtUTC} }
+func (u userResolver) DeactivatedAtUTC() *graphql.Time { return graphQLTime(u.User.DeactivatedAtUTC) }
+func (u userResolver) DisabledAtUTC() *graphql.Time    { return graphQLTime(u.User.DisabledAtUTC) }
+func (u userResolver) PasswordResetAtUTC() *graphql.Time {
+	return graphQLTime(u.User.PasswordResetAtUTC)
+}
+
+type entryResolver struct{ sendkey.Entry }
+
+func entryResolvers(entries []sendkey.Entry) *[]entryResolver {
+	rs := make([]entryResolver, len(entries))
+	for i, e := range entries {
+		rs[i] = entryResolver{e}
+	}
+	return &rs
+}
+
+func (e entryResolver) ID() graphql.ID                { return graphQLID(e.Entry.ID) }
+func (e entryResolver) SentByUserID() graphql.ID      { return graphQLID(e.Entry.SentByUserID) }
+func (e entryResolver) InvalidAttempts() int32        { return int32(e.Entry.InvalidAttempts) }
+func (e entryResolver) CreatedAtUTC() graphql.Time    { return graphql.Time{Time: e.Entry.CreatedAtUTC} }
+func (e entryResolver) AvailableAtUTC() *graphql.Time { return graphQLTime(e.Entry.AvailableAtUTC) }
+func (e entryResolver) ExpiresAtUTC() graphql.Time    { return graphql.Time{Time: e.Entry.ExpiresAtUTC} }
+func (e entryResolver) AnonymousSenderID() *graphql.ID {
+	return graphQLOptionalID(e.Entry.AnonymousSenderID)
+}
+func (e entryResolver) ReplacesEntryID() *graphql.ID {
+	return graphQLOptionalID(e.Entry.ReplacesEntryID)
+}
+
+func (e entryResolver) Deferrals() []deferralResolver {
+	rs := make([]deferralResolver, len(e.Entry.Deferrals))
+	for i, d := range e.Entry.Deferrals {
+		rs[i] = deferralResolver{d}
+	}
+	return rs
+}
+
+type deferralResolver struct{ sendkey.EntryDeferral }
+
+func (d deferralResolver) ID() graphql.ID      { return graphQLID(d.EntryDeferral.ID) }
+func (d deferralResolver) EntryID() graphql.ID { return graphQLID(d.EntryDeferral.EntryID) }
+func (d deferralResolver) DeferredAtUTC() graphql.Time {
+	return graphql.Time{Time: d.EntryDeferral.DeferredAtUTC}
+}
+func (d deferralResolver) RemindAtUTC() graphql.Time {
+	return graphql.Time{Time: d.EntryDeferral.RemindAtUTC}
+}
+func (d deferralResolver) RemindedAtUTC() *graphql.Time {
+	return graphQLTime(d.EntryDeferral.RemindedAtUTC)
+}
+
+type expiredEntryResolver struct{ sendkey.ExpiredEntry }
+
+func (e expiredEntryResolver) EntryID() graphql.ID { return graphQLID(e.ExpiredEntry.EntryID) }
+func (e expiredEntryResolver) SentByUserID() graphql.ID {
+	return graphQLID(e.ExpiredEntry.SentByUserID)
+}
+func (e expiredEntryResolver) ExpiredAtUTC() graphql.Time {
+	return graphql.Time{Time: e.ExpiredEntry.ExpiredAtUTC}
+}
+func (e expiredEntryResolver) ReplacedByEntryID() *graphql.ID {
+	return graphQLOptionalID(e.ExpiredEntry.ReplacedByEntryID)
+}
+
+type outcomeResolver struct{ sendkey.EntryOutcome }
+
+func (o outcomeResolver) EntryID() graphql.ID      { return graphQLID(o.EntryOutcome.EntryID) }
+func (o outcomeResolver) SentByUserID() graphql.ID { return graphQLID(o.EntryOutcome.SentByUserID) }
+func (o outcomeResolver) AtUTC() graphql.Time      { return graphql.Time{Time: o.EntryOutcome.AtUTC} }
+func (o outcomeResolver) ReplacesEntryID() *graphql.ID {
+	return graphQLOptionalID(o.EntryOutcome.ReplacesEntryID)
+}
+func (o outcomeResolver) ReplacedByEntryID() *graphql.ID {
+	return graphQLOptionalID(o.EntryOutcome.ReplacedByEntryID)
+}
+func (o outcomeResolver) AcknowledgedAtUTC() *graphql.Time {
+	return graphQLTime(o.EntryOutcome.AcknowledgedAtUTC)
+}
+
+type claimLinkResolver struct{ sendkey.ClaimLink }
+
+func (l claimLinkResolver) ID() graphql.ID      { return graphQLID(l.ClaimLink.ID) }
+func (l claimLinkResolver) EntryID() graphql.ID { return graphQLID(l.ClaimLink.EntryID) }
+func (l claimLinkResolver) CreatedAtUTC() graphql.Time {
+	return graphql.Time{Time: l.ClaimLink.CreatedAtUTC}
+}
+func (l claimLinkResolver) ExpiresAtUTC() graphql.Time {
+	return graphql.Time{Time: l.ClaimLink.ExpiresAtUTC}
+}
+func (l claimLinkResolver) SupersededAtUTC() *graphql.Time {
+	return graphQLTime(l.ClaimLink.SupersededAtUTC)
+}
+
+type warningResolver struct{ api.Warning }
+
+func (w warningResolver) Sunset() *graphql.Time { return graphQLTime(w.Warning.Sunset) }
+
+func warningResolvers(warnings []api.Warning) []warningResolver {
+	rs := make([]warningResolver, len(warnings))
+	for i, w := range warnings {
+		rs[i] = warningResolver{w}
+	}
+	return rs
+}
+
+type historyResolver struct{ api.EntryHistoryResponse }
+
+func (h historyResolver) Warnings() []warningResolver {
+	return warningResolvers(h.EntryHistoryResponse.Warnings)
+}
+
+func (h historyResolver) Outcomes() []outcomeResolver {
+	rs := make([]outcomeResolver, len(h.EntryHistoryResponse.Outcomes))
+	for i, o := range h.EntryHistoryResponse.Outcomes {
+		rs[i] = outcomeResolver{o}
+	}
+	return rs
+}
+
+type createEntryResolver struct{ api.CreateEntryResponse }
+
+// Warnings are the response's own, which replace the envelope's in JSON.
+func (c createEntryResolver) Warnings() []string { return c.CreateEntryResponse.Warnings }
+
+func (c createEntryResolver) Entry() *entryResolver {
+	if c.CreateEntryResponse.Entry == nil {
+		return nil
+	}
+	return &entryResolver{*c.CreateEntryResponse.Entry}
+}
+
+func (c createEntryResolver) Link() *claimLinkResolver {
+	if c.CreateEntryResponse.Link == nil {
+		return nil
+	}
+	return &claimLinkResolver{*c.CreateEntryResponse.Link}
+}
+
+func graphQLID(id uuid.UUID) graphql.ID {
+	return graphql.ID(id.String())
+}
+
+func graphQLOptionalID(id *uuid.UUID) *graphql.ID {
+	if id == nil {
+		return nil
+	}
+	gid := graphQLID(*id)
+	return &gid
+}
+
+func graphQLTime(t *time.Time) *graphql.Time {
+	if t == nil {
+		return nil
+	}
+	return &graphql.Time{Time: *t}
+}
+
+// createEntryInput is the createEntry mutation's input. Its JSON is the
+// JSON API's request, which it's decoded into.
+type createEntryInput struct {
+	Name            *string                `json:"name,omitempty"`
+	SendToEmail     *string                `json:"sendToEmail,omitempty"`
+	Value           *string                `json:"value,omitempty"`
+	Secret          *string                `json:"secret,omitempty"`
+	Duration        *string                `json:"duration,omitempty"`
+	DurationSeconds *int32                 `json:"durationSeconds,omitempty"`
+	AvailableAtUTC  *graphql.Time          `json:"availableAtUtc,omitempty"`
+	Locale          *string                `json:"locale,omitempty"`
+	EndToEnd        *sealedValueInput      `json:"endToEnd,omitempty"`
+	Generate        *generatePolicyInput   `json:"generate,omitempty"`
+	Type            *string                `json:"type,omitempty"`
+	Metadata        *entryMetadataInput    `json:"metadata,omitempty"`
+	RequireLogin    *bool                  `json:"requireLogin,omitempty"`
+	RequireOTP      *bool                  `json:"requireOtp,omitempty"`
+	Acknowledgment  *string                `json:"acknowledgment,omitempty"`
+	Digest          *valueDigestInput      `json:"digest,omitempty"`
+	ReplacesEntryID *graphql.ID            `json:"replacesEntryId,omitempty"`
+	CaptchaToken    *string                `json:"captchaToken,omitempty"`
+	Challenges      *[]entryChallengeInput `json:"challenges,omitempty"`
+}
+
+type sealedValueInput struct {
+	Ciphertext string        `json:"ciphertext"`
+	Nonce      string        `json:"nonce"`
+	Cipher     string        `json:"cipher"`
+	KDF        entryKDFInput `json:"kdf"`
+}
+
+type entryKDFInput struct {
+	Algorithm string `json:"algorithm"`
+	Salt      string `json:"salt"`
+	Time      int32  `json:"time"`
+	MemoryKiB int32  `json:"memoryKiB"`
+	Threads   int32  `json:"threads"`
+}
+
+type generatePolicyInput struct {
+	Length    *int32  `json:"length,omitempty"`
+	Charset   *string `json:"charset,omitempty"`
+	Words     *int32  `json:"words,omitempty"`
+	Separator *string `json:"separator,omitempty"`
+}
+
+type entryMetadataInput struct {
+	Username *string `json:"username,omitempty"`
+	Hostname *string `json:"hostname,omitempty"`
+}
+
+type valueDigestInput struct {
+	Algorithm string `json:"algorithm"`
+	Salt      string `json:"salt"`
+	MAC       string `json:"mac"`
+}
+
+type entryChallengeInput struct {
+	Question string `json:"question"`
+	Answer   string `json:"answer"`
+}
@@ -0,0 +1,93 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gavinwade12/sendkey"
+	"github.com/gavinwade12/sendkey/internal/app"
+	"github.com/google/uuid"
+)
+
+// serveGraphQL posts the query to the GraphQL route as the user, returning
+// the response.
+func serveGraphQL(t *testing.T, user sendkey.User, query string) *httptest.ResponseRecorder {
+	t.Helper()
+	atm := newAuthTokenManager([]byte("signing key"), time.Hour, time.Hour)
+	users := memoryUsers{user.ID: user}
+	svc := app.NewUserService(users, nil, nil, app.PasswordPolicy{}, app.PasswordHashers{})
+	c := newGraphQLController(baseController{}, &EntriesController{maxBody: 1 << 20}, svc, false)
+
+	body, _ := json.Marshal(map[string]string{"query": query})
+	r := httptest.NewRequest(http.MethodPost, "/v1/graphql", strings.NewReader(string(body)))
+	token, err := atm.AccessToken(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Header.Set("Authorization", "Bearer "+token.Token)
+
+	w := httptest.NewRecorder()
+	cleanOutput(setUserID(atm, svc)(authorizer{users: svc}.require()(c.Query)))(w, r, nil)
+	return w
+}
+
+func TestGraphQLMe(t *testing.T) {
+	created := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	user := sendkey.User{ID: uuid.New(), Email: "ada@example.com", FirstName: "Ada", MFAEnabled: true, CreatedAtUTC: created, Role: sendkey.RoleUser}
+	w := serveGraphQL(t, user, `{ me { id email firstName mfaEnabled createdAtUtc disabledAtUtc } }`)
+
+	var resp struct {
+		Data struct {
+			Me map[string]interface{}
+		}
+		Errors []interface{}
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || w.Code != http.StatusOK || len(resp.Errors) > 0 {
+		t.Fatalf("got %d %s", w.Code, w.Body)
+	}
+	want := map[string]interface{}{
+		"id":            user.ID.String(),
+		"email":         "ada@example.com",
+		"firstName":     "Ada",
+		"mfaEnabled":    true,
+		"createdAtUtc":  "2026-10-15T12:00:00Z",
+		"disabledAtUtc": nil,
+	}
+	for k, v := range want {
+		if resp.Data.Me[k] != v {
+			t.Errorf("got %s %v, want %v", k, resp.Data.Me[k], v)
+		}
+	}
+	if len(resp.Data.Me) != len(want) {
+		t.Errorf("got the fields %v, want only the ones selected", resp.Data.Me)
+	}
+}
+
+func TestGraphQLRefusesDeepQueries(t *testing.T) {
+	query := "{ me " + strings.Repeat("{ me ", graphQLMaxDepth) + "{ id }" + strings.Repeat(" }", graphQLMaxDepth) + " }"
+	w := serveGraphQL(t, sendkey.User{ID: uuid.New()}, query)
+
+	var resp struct {
+		Data   interface{}
+		Errors []struct{ Message string }
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.Data != nil || len(resp.Errors) == 0 || !strings.Contains(resp.Errors[0].Message, "depth") {
+		t.Errorf("got %d %s, want the query refused", w.Code, w.Body)
+	}
+}
+
+func TestGraphQLRefusesInactiveUsers(t *testing.T) {
+	disabled := time.Now().UTC().Add(-time.Hour)
+	w := serveGraphQL(t, sendkey.User{ID: uuid.New(), DisabledAtUTC: &disabled}, `{ me { id } }`)
+
+	var e struct{ Code string }
+	json.Unmarshal(w.Body.Bytes(), &e)
+	if w.Code != http.StatusForbidden || e.Code != "account_inactive" {
+		t.Errorf("got %d %s, want account_inactive", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,100 @@
+package server
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gavinwade12/sendkey/internal/captcha"
+	"github.com/gavinwade12/sendkey/internal/ratelimit"
+	"github.com/gavinwade12/sendkey/pkg/api"
+)
+
+// guestConfig lets people create entries without registering, for one-off
+// secrets. Each guest entry is attributed to an anonymous sender for the
+// client's IP, which is stored hashed.
+type guestConfig struct {
+	Enabled bool
+	// MaxDurationMins limits how long guest entries last. Zero uses an hour.
+	MaxDurationMins int
+	// Limit and WindowSecs limit how many guest entries each IP can create.
+	// Zero values use 5 per hour.
+	Limit      int
+	WindowSecs int
+	// Captcha, if it has a Provider, makes guests solve a CAPTCHA for each
+	// entry and send its response as the request's captchaToken.
+	Captcha struct {
+		// Provider is "hcaptcha", "turnstile", or "recaptcha".
+		Provider  string
+		SecretKey string
+		// VerifyURL overrides the provider's siteverify URL, e.g. for a
+		// compatible self-hosted service.
+		VerifyURL string
+	}
+}
+
+func (c guestConfig) maxDuration() time.Duration {
+	if c.MaxDurationMins > 0 {
+		return time.Minute * time.Duration(c.MaxDurationMins)
+	}
+	return time.Hour
+}
+
+func (c guestConfig) limiter(store ratelimit.FailureStore) *ratelimit.Limiter {
+	l := ratelimit.NewLimiter(store, 5, time.Hour)
+	if c.Limit > 0 && c.WindowSecs > 0 {
+		l.Limit = c.Limit
+		l.Window = time.Second * time.Duration(c.WindowSecs)
+	}
+	return l
+}
+
+// captcha returns the verifier for guests' CAPTCHAs, or nil if they don't
+// need to solve one.
+func (c guestConfig) captcha() (*captcha.Verifier, error) {
+	cc := c.Captcha
+	if cc.Provider == "" {
+		return nil, nil
+	}
+	verifyURL, ok := captcha.Providers[cc.Provider]
+	if !ok {
+		return nil, fmt.Errorf("guest entries: unknown captcha provider %q", cc.Provider)
+	}
+	if cc.SecretKey == "" {
+		return nil, fmt.Errorf("guest entries: a captcha SecretKey is required")
+	}
+	if cc.VerifyURL != "" {
+		verifyURL = cc.VerifyURL
+	}
+	return captcha.NewVerifier(verifyURL, cc.SecretKey), nil
+}
+
+// checkCaptcha returns an error if the guest's CAPTCHA response isn't valid.
+func checkCaptcha(r *http.Request, v *captcha.Verifier, token string) error {
+	ok, err := v.Verify(token, clientIP(r))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return api.Error{
+			StatusCode: http.StatusBadRequest,
+			Code:       "captcha_failed",
+			Message:    "Solve the CAPTCHA to send an entry without logging in.",
+		}
+	}
+	return nil
+}
+
+// guestLimited records a guest entry from the client's IP and reports whether
+// it's over the limit, setting Retry-After if it is.
+func guestLimited(w http.ResponseWriter, r *http.Request, l *ratelimit.Limiter) (bool, error) {
+	wait, err := l.Allow("guest:ip:" + clientIP(r))
+	if err != nil || wait == 0 {
+		return false, err
+	}
+
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	return true, nil
+}
@@ -0,0 +1,211 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gavinwade12/sendkey"
+	"github.com/gavinwade12/sendkey/pkg/api"
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// maxIdempotencyKeyLength is the longest Idempotency-Key accepted. Clients
+// usually send a UUID.
+const maxIdempotencyKeyLength = 255
+
+type IdempotencyRepository interface {
+	// Reserve stores the record for a request that's starting, unless its
+	// key is already stored and hasn't expired, returning whether it was
+	// stored.
+	Reserve(sendkey.IdempotencyRecord) (bool, error)
+	Find(keyHash string) (*sendkey.IdempotencyRecord, error)
+	// Complete stores the response to the key's request.
+	Complete(keyHash string, statusCode int, response []byte) error
+	Delete(keyHash string) error
+	// Purge deletes the records that expired before the time.
+	Purge(before time.Time) (int64, error)
+}
+
+type idempotencyConfig struct {
+	// WindowHours is how long a response is kept for retries with the same
+	// Idempotency-Key. Zero keeps it for a day.
+	WindowHours int
+}
+
+func (c idempotencyConfig) window() time.Duration {
+	if c.WindowHours <= 0 {
+		return 24 * time.Hour
+	}
+	return time.Hour * time.Duration(c.WindowHours)
+}
+
+// idempotency makes retries of a request with the same Idempotency-Key
+// header get the first request's response instead of repeating it, so
+// automation on a flaky network doesn't create an entry twice. Keys are
+// scoped to the user, or to the client's IP for guests.
+type idempotency struct {
+	bc     baseController
+	store  IdempotencyRepository
+	window time.Duration
+}
+
+func (i *idempotency) wrap(a action) action {
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+		key := r.Header.Get("Idempotency-Key")
+		if key == "" {
+			return a(w, r, p)
+		}
+
+		userID, err := i.bc.GetCurrentUserID(r)
+		if err != nil {
+			return err
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			return api.Error{
+				UserID:     userID,
+				StatusCode: http.StatusBadRequest,
+				Code:       "invalid_idempotency_key",
+				Message:    "The Idempotency-Key header can't be longer than 255 characters.",
+			}
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			return respond(w, http.StatusBadRequest, invalidBody(r, err))
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		now := time.Now().UTC()
+		rec := sendkey.IdempotencyRecord{
+			KeyHash:      idempotencyKeyHash(userID, clientIP(r), key),
+			RequestHash:  idempotencyRequestHash(r, body),
+			CreatedAtUTC: now,
+			ExpiresAtUTC: now.Add(i.window),
+		}
+		reserved, err := i.store.Reserve(rec)
+		if err != nil {
+			return err
+		}
+		if !reserved {
+			return i.replay(w, r, userID, rec)
+		}
+
+		rw := &recordingWriter{ResponseWriter: w}
+		err = a(rw, r, p)
+		// a request that failed on the server didn't happen, as far as the
+		// client can tell, so its key is freed for the retry
+		if err != nil || rw.status == 0 || rw.status >= http.StatusInternalServerError {
+			if delErr := i.store.Delete(rec.KeyHash); delErr != nil {
+				requestLogger(r).Error("freeing idempotency key", "error", delErr)
+			}
+			return err
+		}
+		if err = i.store.Complete(rec.KeyHash, rw.status, rw.body.Bytes()); err != nil {
+			requestLogger(r).Error("storing idempotent response", "error", err)
+		}
+		return nil
+	}
+}
+
+// replay responds to a retry with the response to the key's first request.
+func (i *idempotency) replay(w http.ResponseWriter, r *http.Request, userID uuid.UUID, rec sendkey.IdempotencyRecord) error {
+	first, err := i.store.Find(rec.KeyHash)
+	if err != nil {
+		return err
+	}
+	switch {
+	case first != nil && first.RequestHash != rec.RequestHash:
+		return api.Error{
+			UserID:     userID,
+			StatusCode: http.StatusUnprocessableEntity,
+			Code:       "idempotency_key_reused",
+			Message:    "The Idempotency-Key was already used for a different request.",
+		}
+	// a first request that failed since the key was reserved is gone, so
+	// the retry is told to try again
+	case first == nil || first.StatusCode == 0:
+		return api.Error{
+			UserID:     userID,
+			StatusCode: http.StatusConflict,
+			Code:       "idempotency_key_in_use",
+			Message:    "A request with this Idempotency-Key is in progress. Try again shortly.",
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(first.StatusCode)
+	_, err = w.Write(first.Response)
+	return err
+}
+
+func idempotencyKeyHash(userID uuid.UUID, ip, key string) string {
+	scope := userID.String()
+	if userID == uuid.Nil {
+		scope = "ip:" + ip
+	}
+	sum := sha256.Sum256([]byte(scope + "\x00" + key))
+	return hex.EncodeToString(sum[:])
+}
+
+// idempotencyRequestHash hashes what makes a request the same request: its
+// route, whether it's for the sandbox, and its body. The route's version
+// prefix is left out, so a retry through the deprecated route still matches.
+func idempotencyRequestHash(r *http.Request, body []byte) string {
+	h := sha256.New()
+	path := strings.TrimPrefix(r.URL.Path, api.BasePath)
+	io.WriteString(h, r.Method+" "+path+"\x00"+r.Header.Get("X-Sandbox")+"\x00")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// recordingWriter keeps a copy of the response it writes.
+type recordingWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *recordingWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+// purgeIdempotencyKeys deletes the expired idempotency records, checking
+// every interval until done is closed.
+func purgeIdempotencyKeys(store IdempotencyRepository, interval time.Duration, done <-chan struct{}) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		n, err := store.Purge(time.Now().UTC())
+		if err != nil {
+			slog.Error("idempotency: purging expired keys", "error", err)
+		} else if n > 0 {
+			slog.Info("idempotency: purged expired keys", "purged", n)
+		}
+
+		select {
+		case <-t.C:
+		case <-done:
+			return
+		}
+	}
+}
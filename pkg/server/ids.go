@@ -0,0 +1,45 @@
+package server
+
+import (
+	"fmt"
+
+	"github.com/gavinwade12/sendkey"
+)
+
+// idsConfig chooses how new records' IDs are generated. Every generator's IDs
+// are UUIDs, so a deployment can switch from the default random ones without
+// migrating anything: the IDs it already has stay valid alongside the new ones.
+type idsConfig struct {
+	// Generator is "random" (the default) for version 4 UUIDs, or
+	// "time-ordered" for version 7 UUIDs, which MySQL inserts into large
+	// tables faster.
+	Generator string
+	// Entries is the generator of entries' IDs: either of Generator's, or
+	// "short" for IDs with an 11 character form that the API accepts in its
+	// paths and the CLI shows. Empty uses Generator.
+	Entries string
+}
+
+var idGenerators = map[string]sendkey.IDGenerator{
+	"":             sendkey.RandomIDs,
+	"random":       sendkey.RandomIDs,
+	"time-ordered": sendkey.TimeOrderedIDs,
+}
+
+// generators returns the generators of records' and entries' IDs.
+func (c idsConfig) generators() (records, entries sendkey.IDGenerator, err error) {
+	records, ok := idGenerators[c.Generator]
+	if !ok {
+		return nil, nil, fmt.Errorf("IDs: unknown generator %q", c.Generator)
+	}
+	switch c.Entries {
+	case "":
+		return records, records, nil
+	case "short":
+		return records, sendkey.ShortIDs, nil
+	}
+	if entries, ok = idGenerators[c.Entries]; !ok {
+		return nil, nil, fmt.Errorf("IDs: unknown entry ID generator %q", c.Entries)
+	}
+	return records, entries, nil
+}
@@ -0,0 +1,98 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/gavinwade12/sendkey/pkg/api"
+	"github.com/julienschmidt/httprouter"
+)
+
+// maxPooledJSONBuffer keeps an occasional huge response from pinning its
+// buffer in the pool.
+const maxPooledJSONBuffer = 64 << 10
+
+// jsonEncoder is a buffer with an encoder bound to it. Encoding into the
+// buffer first means the response is written with a single Write, and a
+// failed encode doesn't leave a partial body behind.
+type jsonEncoder struct {
+	buf bytes.Buffer
+	enc *json.Encoder
+}
+
+var jsonEncoders = sync.Pool{
+	New: func() interface{} {
+		e := &jsonEncoder{}
+		e.enc = json.NewEncoder(&e.buf)
+		return e
+	},
+}
+
+// writeJSON writes v to w as JSON followed by a newline, the same output as
+// json.NewEncoder(w).Encode(v), reusing encoders and buffers across calls.
+func writeJSON(w io.Writer, v interface{}) error {
+	e := jsonEncoders.Get().(*jsonEncoder)
+	defer func() {
+		if e.buf.Cap() <= maxPooledJSONBuffer {
+			e.buf.Reset()
+			jsonEncoders.Put(e)
+		}
+	}()
+
+	if err := e.enc.Encode(v); err != nil {
+		return err
+	}
+
+	_, err := w.Write(e.buf.Bytes())
+	return err
+}
+
+// respond writes the payload as the response body with the status code.
+func respond(w http.ResponseWriter, status int, payload interface{}) error {
+	w.WriteHeader(status)
+	return writeJSON(w, payload)
+}
+
+// envelopeStatus is the status code for a response with the envelope. Failed
+// responses are a bad request since their errors are the client's to fix.
+func envelopeStatus(e api.Envelope) int {
+	if !e.Success {
+		return http.StatusBadRequest
+	}
+	return http.StatusOK
+}
+
+type strictJSONCtxKey string
+
+const strictJSONCtxKeyValue = strictJSONCtxKey("strictJSON")
+
+// jsonDecoding sets whether decodeJSON rejects request bodies with fields
+// the request doesn't have, so a typo like "durration" fails instead of
+// being ignored. A request's X-Strict-JSON header overrides strict, so a
+// client can opt in while it's being developed against a lenient server.
+func jsonDecoding(strict bool) func(action) action {
+	return func(a action) action {
+		return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+			s := strict
+			if v, err := strconv.ParseBool(r.Header.Get("X-Strict-JSON")); err == nil {
+				s = v
+			}
+			return a(w, r.WithContext(context.WithValue(r.Context(), strictJSONCtxKeyValue, s)), p)
+		}
+	}
+}
+
+// decodeJSON decodes the request's body into v, strictly if the request
+// asks for it; see jsonDecoding.
+func decodeJSON(r *http.Request, v interface{}) error {
+	d := json.NewDecoder(r.Body)
+	if strict, _ := r.Context().Value(strictJSONCtxKeyValue).(bool); strict {
+		d.DisallowUnknownFields()
+	}
+	return d.Decode(v)
+}
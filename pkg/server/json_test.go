@@ -0,0 +1,118 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gavinwade12/sendkey"
+	"github.com/gavinwade12/sendkey/pkg/api"
+	"github.com/google/uuid"
+)
+
+// listPage is a page of entries as the admin list endpoint returns it, which
+// is where encoding showed up in profiles.
+func listPage() api.ListEntriesResponse {
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	page := api.ListEntriesResponse{}
+	for i := 0; i < 100; i++ {
+		page.Entries = append(page.Entries, sendkey.Entry{
+			ID:           uuid.New(),
+			Name:         "wifi password",
+			SentToEmail:  "ada@example.com",
+			CreatedAtUTC: now,
+			ExpiresAtUTC: now.Add(24 * time.Hour),
+		})
+	}
+	return page
+}
+
+func TestWriteJSONMatchesEncoder(t *testing.T) {
+	page := listPage()
+	var want bytes.Buffer
+	if err := json.NewEncoder(&want).Encode(page); err != nil {
+		t.Fatal(err)
+	}
+
+	// twice, so the second write reuses the pooled buffer
+	for i := 0; i < 2; i++ {
+		var got bytes.Buffer
+		if err := writeJSON(&got, page); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got.Bytes(), want.Bytes()) {
+			t.Fatalf("write %d: got %s, want %s", i, got.Bytes(), want.Bytes())
+		}
+	}
+}
+
+// discardResponse is a ResponseWriter that throws the body away, so the
+// benchmarks measure encoding rather than a recorder's buffer.
+type discardResponse struct{ header http.Header }
+
+func (w discardResponse) Header() http.Header         { return w.header }
+func (w discardResponse) Write(b []byte) (int, error) { return len(b), nil }
+func (w discardResponse) WriteHeader(int)             {}
+
+func BenchmarkRespond(b *testing.B) {
+	page := listPage()
+	w := discardResponse{http.Header{}}
+	b.Run("json.NewEncoder", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			w.WriteHeader(http.StatusOK)
+			if err := json.NewEncoder(w).Encode(page); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("pooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if err := respond(w, http.StatusOK, page); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkDecodeJSON(b *testing.B) {
+	body := `{"name":"wifi password","sendToEmail":"ada@example.com","value":"correct horse battery staple","secret":"a shared secret","durationSeconds":3600,"locale":"en"}`
+	request := func(strict bool) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/entries", nil)
+		return r.WithContext(context.WithValue(r.Context(), strictJSONCtxKeyValue, strict))
+	}
+
+	b.Run("json.NewDecoder", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var req api.CreateEntryRequest
+			if err := json.NewDecoder(strings.NewReader(body)).Decode(&req); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	for _, strict := range []bool{false, true} {
+		name := "lenient"
+		if strict {
+			name = "strict"
+		}
+		b.Run(name, func(b *testing.B) {
+			r := request(strict)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				r.Body = io.NopCloser(strings.NewReader(body))
+				var req api.CreateEntryRequest
+				if err := decodeJSON(r, &req); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/gavinwade12/sendkey/internal/app"
+	"github.com/gavinwade12/sendkey/internal/kms"
+)
+
+// keyProviderConfig selects how the keys in the config are unwrapped. With no
+// provider, they're used as plaintext. Otherwise they're ciphertexts from the
+// provider: base64 blobs for AWS and GCP, and transit ciphertexts for Vault.
+type keyProviderConfig struct {
+	Provider string // "", "aws-kms", "gcp-kms", or "vault"
+
+	AWSRegion string
+	AWSKeyID  string
+
+	GCPKeyName string
+
+	VaultAddr string
+	// VaultToken defaults to the VAULT_TOKEN environment variable.
+	VaultToken        string
+	VaultTransitMount string
+	VaultTransitKey   string
+}
+
+func (c keyProviderConfig) provider() (app.KeyProvider, error) {
+	switch c.Provider {
+	case "":
+		return kms.Plaintext{}, nil
+	case "aws-kms":
+		return kms.NewAWS(c.AWSRegion, c.AWSKeyID), nil
+	case "gcp-kms":
+		return kms.NewGCP(c.GCPKeyName), nil
+	case "vault":
+		return kms.NewVault(c.VaultAddr, c.VaultToken, c.VaultTransitMount, c.VaultTransitKey), nil
+	default:
+		return nil, fmt.Errorf("unknown key provider %q", c.Provider)
+	}
+}
+
+// keyRing loads the entry key ring from the config. Key is the legacy key
+// for entries from before key versioning. Without any versioned Keys,
+// version 1 is derived from it so existing configs keep working, whatever
+// its length. Neither is unwrapped by the KeyProvider, since Key was always
+// plaintext.
+func keyRing(cfg Config) (*app.KeyRing, error) {
+	legacy := []byte(cfg.Key)
+	if len(cfg.Keys) == 0 {
+		if cfg.Key == "" {
+			return nil, fmt.Errorf("no entry keys: set Keys, or the legacy Key")
+		}
+		// the label keeps the derived key apart from the legacy entries'
+		// keys, which hash the legacy key with each entry's secret
+		v1 := sha256.Sum256([]byte("sendkey key version 1:" + cfg.Key))
+		return app.NewKeyRing(1, map[int][]byte{app.LegacyKeyVersion: legacy, 1: v1[:]})
+	}
+
+	p, err := cfg.KeyProvider.provider()
+	if err != nil {
+		return nil, err
+	}
+	return app.LoadKeyRing(p, cfg.KeyVersion, cfg.Keys, legacy)
+}
@@ -0,0 +1,115 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gavinwade12/sendkey/pkg/api"
+)
+
+// requestLimitsConfig keeps a single slow or giant request from tying up the
+// server. Zero values use the defaults.
+type requestLimitsConfig struct {
+	// MaxBodyBytes caps every request's body. It's raised to fit the
+	// largest entry MaxEntryValueBytes allows. Zero uses 1 MiB.
+	MaxBodyBytes int64
+	// ReadTimeoutSecs limits reading a whole request, including its body.
+	// Zero uses 30 seconds.
+	ReadTimeoutSecs int
+	// WriteTimeoutSecs limits the time from the end of reading a request's
+	// headers to the end of writing its response. Zero uses 60 seconds.
+	WriteTimeoutSecs int
+	// HandlerTimeoutSecs limits how long a request is handled before it's
+	// answered with 503. It should be shorter than the write timeout, or
+	// the connection's closed before the 503 can be sent. Zero uses 30
+	// seconds.
+	HandlerTimeoutSecs int
+	// IdleTimeoutSecs limits how long a kept-alive connection waits for
+	// the next request. Zero uses 120 seconds.
+	IdleTimeoutSecs int
+}
+
+const defaultMaxBodyBytes = 1 << 20
+
+func secsOr(secs, def int) time.Duration {
+	if secs <= 0 {
+		secs = def
+	}
+	return time.Second * time.Duration(secs)
+}
+
+// server returns the API's server on addr with the timeouts.
+func (c requestLimitsConfig) server(addr string, h http.Handler) *http.Server {
+	read := secsOr(c.ReadTimeoutSecs, 30)
+	return &http.Server{
+		Addr:              addr,
+		Handler:           h,
+		ReadHeaderTimeout: read,
+		ReadTimeout:       read,
+		WriteTimeout:      secsOr(c.WriteTimeoutSecs, 60),
+		IdleTimeout:       secsOr(c.IdleTimeoutSecs, 120),
+	}
+}
+
+// limitRequests caps requests' bodies at maxBody bytes and answers requests
+// that take longer than the handler timeout with 503, except the event
+// stream, which stays open until the client leaves. A body with a
+// Content-Length over the cap is refused with 413 before it's read; one sent
+// without a length fails to decode once it passes the cap.
+func (c requestLimitsConfig) limitRequests(maxBody int64, h http.Handler) http.Handler {
+	timeout := secsOr(c.HandlerTimeoutSecs, 30)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > maxBody {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Connection", "close")
+			respond(w, http.StatusRequestEntityTooLarge, api.Error{
+				StatusCode: http.StatusRequestEntityTooLarge,
+				Code:       "body_too_large",
+				Message:    fmt.Sprintf("The request body can't be larger than %d bytes.", maxBody),
+				RequestID:  requestID(r),
+			})
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, maxBody)
+		if isEventStream(r) {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		msg, _ := json.Marshal(api.Error{
+			StatusCode: http.StatusServiceUnavailable,
+			Code:       "timeout",
+			Message:    "The request took too long. Try again later.",
+			RequestID:  requestID(r),
+		})
+		http.TimeoutHandler(h, timeout, string(msg)).ServeHTTP(timeoutWriter{w}, r)
+	})
+}
+
+// maxBody returns the body cap, raised to fit entries' bodies of up to
+// entryBody bytes.
+func (c requestLimitsConfig) maxBody(entryBody int64) int64 {
+	max := c.MaxBodyBytes
+	if max <= 0 {
+		max = defaultMaxBodyBytes
+	}
+	if max < entryBody {
+		max = entryBody
+	}
+	return max
+}
+
+// timeoutWriter labels http.TimeoutHandler's 503, which it writes without a
+// Content-Type, as the JSON it is.
+type timeoutWriter struct {
+	http.ResponseWriter
+}
+
+func (w timeoutWriter) WriteHeader(status int) {
+	if status == http.StatusServiceUnavailable && w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
@@ -0,0 +1,147 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gavinwade12/sendkey/internal/redact"
+	"github.com/google/uuid"
+)
+
+type loggingConfig struct {
+	// Level is "debug", "info", "warn", or "error". Empty uses info.
+	Level string
+	// Format is "json" or "text". Empty uses json.
+	Format string
+	// Unredacted stops masking entry values, secrets, nonces, passwords,
+	// and tokens in the logs, so the links written by the log mailer can be
+	// followed in local development. Never enable it in production.
+	Unredacted bool
+}
+
+// logger returns a logger writing to w as configured.
+func (c loggingConfig) logger(w io.Writer) (*slog.Logger, error) {
+	var level slog.Level
+	if c.Level != "" {
+		if err := level.UnmarshalText([]byte(c.Level)); err != nil {
+			return nil, fmt.Errorf("logging: invalid level %q", c.Level)
+		}
+	}
+	opts := &slog.HandlerOptions{Level: level}
+	if !c.Unredacted {
+		// attributes are masked by name, and the written lines are scanned
+		// for anything that slipped into a message or a nested value
+		opts.ReplaceAttr = redact.ReplaceAttr
+		w = redact.NewWriter(w)
+	}
+	switch strings.ToLower(c.Format) {
+	case "", "json":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	case "text":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	default:
+		return nil, fmt.Errorf("logging: unknown format %q", c.Format)
+	}
+}
+
+type requestLogCtxKey string
+
+const requestLogCtxKeyValue = requestLogCtxKey("requestLog")
+
+// requestLog holds a request's logger, which gains the user's ID once the
+// request is authenticated.
+type requestLog struct {
+	id     string
+	logger *slog.Logger
+	userID uuid.UUID
+}
+
+// requestIDs are the request IDs accepted from an X-Request-ID header, so a
+// proxy's IDs can be carried through without letting clients write anything
+// into the logs.
+var requestIDs = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// logRequests logs every request once it's served, with its ID, method,
+// path, status, and latency. Actions log with the same fields through
+// requestLogger.
+func logRequests(l *slog.Logger, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		id := r.Header.Get("X-Request-ID")
+		if !requestIDs.MatchString(id) {
+			id = uuid.NewString()
+		}
+		w.Header().Set("X-Request-ID", id)
+
+		// the query is left out since it can hold an entry's nonce and secret
+		rl := &requestLog{id: id, logger: l.With("requestId", id, "method", r.Method, "path", r.URL.Path, "clientIp", clientIP(r))}
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		h.ServeHTTP(sw, r.WithContext(context.WithValue(r.Context(), requestLogCtxKeyValue, rl)))
+
+		rl.logger.Info("request", "status", sw.status, "latencyMs", time.Since(start).Milliseconds())
+	})
+}
+
+// requestLogger returns the request's logger.
+func requestLogger(r *http.Request) *slog.Logger {
+	if rl, ok := r.Context().Value(requestLogCtxKeyValue).(*requestLog); ok {
+		return rl.logger
+	}
+	return slog.Default()
+}
+
+// requestID returns the request's ID, which is also sent back in the
+// X-Request-ID header.
+func requestID(r *http.Request) string {
+	if rl, ok := r.Context().Value(requestLogCtxKeyValue).(*requestLog); ok {
+		return rl.id
+	}
+	return ""
+}
+
+// setRequestUser adds the authenticated user's ID to the request's logger.
+func setRequestUser(r *http.Request, userID uuid.UUID) {
+	if rl, ok := r.Context().Value(requestLogCtxKeyValue).(*requestLog); ok && rl.userID == uuid.Nil {
+		rl.userID = userID
+		rl.logger = rl.logger.With("userId", userID)
+	}
+}
+
+// setRequestTrace adds the request's trace ID to its logger, so its logs can
+// be found from its trace.
+func setRequestTrace(r *http.Request, traceID string) {
+	if rl, ok := r.Context().Value(requestLogCtxKeyValue).(*requestLog); ok && traceID != "" {
+		rl.logger = rl.logger.With("traceId", traceID)
+	}
+}
+
+// statusWriter records the status written to a response.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	wrote  bool
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if !w.wrote {
+		w.status, w.wrote = status, true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	w.wrote = true
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the wrapped writer, so an http.ResponseController can
+// flush it.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gavinwade12/sendkey/internal/redact"
+)
+
+// secret is a value that must never reach the logs or a response.
+const secret = "hunter2-s3cr3t"
+
+// assertRedacted fails the test if out has the secret, or doesn't have the
+// mask where it was.
+func assertRedacted(t *testing.T, what, out string) {
+	t.Helper()
+	if strings.Contains(out, secret) {
+		t.Errorf("%s has the secret: %s", what, out)
+	}
+	if !strings.Contains(out, redact.Mask) {
+		t.Errorf("%s has no %s: %s", what, redact.Mask, out)
+	}
+}
+
+func TestLoggerRedacts(t *testing.T) {
+	logs := []struct {
+		name string
+		log  func(*slog.Logger)
+	}{
+		{"sensitive attribute", func(l *slog.Logger) { l.Info("login", "password", secret) }},
+		{"sensitive attribute in a group", func(l *slog.Logger) { l.Info("login", slog.Group("request", "mfaCode", secret)) }},
+		{"sensitive bytes", func(l *slog.Logger) { l.Info("entry", "key", []byte(secret)) }},
+		{"error wrapping a secret", func(l *slog.Logger) { l.Error("claim", "error", errors.New("decrypting: nonce="+secret)) }},
+		{"message with a secret", func(l *slog.Logger) { l.Warn("refreshing failed for refreshToken=" + secret) }},
+		{"struct with a secret", func(l *slog.Logger) {
+			l.Info("request", "body", struct{ ID, Secret string }{"1", secret})
+		}},
+		{"link with a nonce", func(l *slog.Logger) { l.Info("mail", "body", "Open https://sendkey.example/claim/"+secret) }},
+	}
+	for _, format := range []string{"json", "text"} {
+		for _, tt := range logs {
+			t.Run(format+"/"+tt.name, func(t *testing.T) {
+				var buf bytes.Buffer
+				l, err := loggingConfig{Format: format}.logger(&buf)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				tt.log(l)
+				assertRedacted(t, "the log", buf.String())
+			})
+		}
+	}
+}
+
+func TestLoggerUnredacted(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := loggingConfig{Unredacted: true}.logger(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l.Info("login", "password", secret)
+	if !strings.Contains(buf.String(), secret) {
+		t.Errorf("the unredacted log masked the secret: %s", buf.String())
+	}
+}
+
+func TestRequestLogRedactsPath(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := loggingConfig{}.logger(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := logRequests(l, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestLogger(r).Info("showing the claim page")
+	}))
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/claim/"+secret+"?secret="+secret, nil))
+	assertRedacted(t, "the request log", buf.String())
+}
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"html/template"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/gavinwade12/sendkey/internal/app"
+	"github.com/gavinwade12/sendkey/internal/i18n"
+	"github.com/gavinwade12/sendkey/pkg/api"
+	"github.com/julienschmidt/httprouter"
+)
+
+// MagicLinkPageController serves the page emailed login links open. Links
+// are single use, so opening one only shows a form, and mail scanners and
+// prefetchers that follow links can't use it up. Posting the form logs in.
+type MagicLinkPageController struct {
+	links *app.MagicLinkService
+}
+
+type magicLinkPageModel struct {
+	Lang    string
+	T       func(string) string
+	Code    string
+	Invalid bool
+}
+
+// Show renders the form confirming the login.
+func (c *MagicLinkPageController) Show(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	code := p.ByName("code")
+	if !c.links.Valid(code) {
+		return c.render(w, r, http.StatusNotFound, magicLinkPageModel{Invalid: true})
+	}
+	return c.render(w, r, http.StatusOK, magicLinkPageModel{Code: code})
+}
+
+func (c *MagicLinkPageController) render(w http.ResponseWriter, r *http.Request, status int, model magicLinkPageModel) error {
+	model.Lang = i18n.Negotiate(r.Header.Get("Accept-Language"))
+	if model.Lang == "" {
+		model.Lang = i18n.DefaultLanguage
+	}
+	model.T = i18n.Translator(model.Lang)
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; form-action 'self'")
+	w.WriteHeader(status)
+	return magicLinkPageTemplate.Execute(w, model)
+}
+
+// magicLinkForm turns the page's form post into the JSON request
+// RedeemMagicLink takes, so it gets through acceptJSON like the API's.
+func magicLinkForm(h httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		if ct, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); ct == "application/x-www-form-urlencoded" {
+			if err := r.ParseForm(); err != nil {
+				http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+				return
+			}
+			b, _ := json.Marshal(api.RedeemMagicLinkRequest{MFACode: r.PostForm.Get("mfaCode"), NewPassword: r.PostForm.Get("newPassword")})
+			r.Body = io.NopCloser(bytes.NewReader(b))
+			r.ContentLength = int64(len(b))
+			r.Header.Set("Content-Type", "application/json")
+		}
+		h(w, r, p)
+	}
+}
+
+var magicLinkPageTemplate = template.Must(template.New("magic-link").Parse(`<!DOCTYPE html>
+<html lang="{{.Lang}}">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex, nofollow">
+<title>sendkey</title>
+<style>
+body { font-family: sans-serif; max-width: 40em; margin: 2em auto; padding: 0 1em; line-height: 1.5; }
+label { display: block; margin-top: 1em; font-weight: bold; }
+input { font-size: 1em; padding: .4em; width: 100%; box-sizing: border-box; }
+button { font-size: 1em; margin-top: 1em; padding: .5em 1em; }
+:focus { outline: 3px solid #1a5fb4; outline-offset: 2px; }
+</style>
+</head>
+<body>
+<main>
+<h1>{{call .T "Log in to sendkey"}}</h1>
+{{if .Invalid}}
+<p>{{call .T "This link is invalid."}}</p>
+{{else}}
+<p>{{call .T "Log in with this link? It can only be used once."}}</p>
+<form method="post" action="/login/magic/{{.Code}}">
+<label for="mfaCode">{{call .T "MFA code, if you've turned on MFA"}}</label>
+<input id="mfaCode" name="mfaCode" autocomplete="one-time-code" inputmode="numeric">
+<label for="newPassword">{{call .T "New password, if you were asked to choose one"}}</label>
+<input id="newPassword" name="newPassword" type="password" autocomplete="new-password">
+<button type="submit">{{call .T "Log in"}}</button>
+</form>
+{{end}}
+</main>
+</body>
+</html>
+`))
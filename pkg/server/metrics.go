@@ -0,0 +1,109 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gavinwade12/sendkey/internal/app"
+	"github.com/gavinwade12/sendkey/internal/metrics"
+	"github.com/gavinwade12/sendkey/internal/mysql"
+	"github.com/gavinwade12/sendkey/internal/tracing"
+	"github.com/julienschmidt/httprouter"
+)
+
+// router registers the API's routes. When metrics are enabled, each route's
+// requests are timed by its pattern, e.g. "GET /entries/:entryID", so IDs
+// don't make a metric per request. When tracing is enabled, each request
+// gets a span named the same way.
+type router struct {
+	*httprouter.Router
+	reg    *metrics.Registry
+	tracer *tracing.Tracer
+	limits *rateLimits
+	// validation checks request bodies against the OpenAPI document if
+	// it's enabled.
+	validation *requestValidation
+}
+
+func (rt *router) Handle(method, path string, h httprouter.Handle) {
+	rt.handle(method, path, method+" "+path, nil, h)
+}
+
+// handle registers the route at path. Its rate limits are looked up by
+// route, the method and path within its API version, so a route shares its
+// limits with its unversioned alias. It's marked deprecated by d, or its
+// entry in deprecations if d is nil.
+func (rt *router) handle(method, path, route string, d *deprecation, h httprouter.Handle) {
+	if rt.validation != nil {
+		h = rt.validation.route(route, h)
+	}
+	if rt.limits != nil {
+		h = rt.limits.route(route, h)
+	}
+	if rt.reg != nil {
+		h = observeRoute(rt.reg, metrics.HTTPPrefix+method+" "+path, h)
+	}
+	if d == nil {
+		if dep, ok := deprecations[method+" "+path]; ok {
+			d = &dep
+		}
+	}
+	if d != nil {
+		h = deprecate(*d, h)
+	}
+	if rt.tracer != nil {
+		h = traceRoute(rt.tracer, method+" "+path, h)
+	}
+	rt.Router.Handle(method, path, h)
+}
+
+func (rt *router) GET(path string, h httprouter.Handle) { rt.Handle(http.MethodGet, path, h) }
+
+func (rt *router) HEAD(path string, h httprouter.Handle) { rt.Handle(http.MethodHead, path, h) }
+
+func (rt *router) POST(path string, h httprouter.Handle) { rt.Handle(http.MethodPost, path, h) }
+
+func (rt *router) PUT(path string, h httprouter.Handle) { rt.Handle(http.MethodPut, path, h) }
+
+func (rt *router) DELETE(path string, h httprouter.Handle) { rt.Handle(http.MethodDelete, path, h) }
+
+// errServerStatus is observed for routes that respond with a 5xx status.
+type errServerStatus int
+
+func (e errServerStatus) Error() string {
+	return http.StatusText(int(e))
+}
+
+func observeRoute(reg *metrics.Registry, op string, h httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		h(sw, r, p)
+
+		var err error
+		if sw.status >= http.StatusInternalServerError {
+			err = errServerStatus(sw.status)
+		}
+		reg.Observe(op, time.Since(start), err)
+	}
+}
+
+// watchDB reports the database's connection pool as gauges.
+func watchDB(db *mysql.DB, reg *metrics.Registry) {
+	reg.Gauge("db.max_open_connections", func() int64 { return int64(db.Stats().MaxOpenConnections) })
+	reg.Gauge("db.open_connections", func() int64 { return int64(db.Stats().OpenConnections) })
+	reg.Gauge("db.in_use", func() int64 { return int64(db.Stats().InUse) })
+	reg.Gauge("db.idle", func() int64 { return int64(db.Stats().Idle) })
+	reg.Gauge("db.wait_count", func() int64 { return db.Stats().WaitCount })
+	reg.Gauge("db.wait_duration_ms", func() int64 { return db.Stats().WaitDuration.Milliseconds() })
+}
+
+// usageCounters counts with each of the counters, e.g. both the metrics and
+// the anonymous usage statistics.
+type usageCounters []app.UsageCounter
+
+func (cs usageCounters) Count(name string) {
+	for _, c := range cs {
+		c.Count(name)
+	}
+}
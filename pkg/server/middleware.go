@@ -0,0 +1,192 @@
+package server
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// Middleware wraps a handler, e.g. to check a header a corporate gateway
+// sets or to log requests somewhere else too. It can refuse a request by
+// writing a response and not calling the handler.
+type Middleware func(http.Handler) http.Handler
+
+// Chain is middleware applied in order, the first outermost.
+type Chain []Middleware
+
+// Then returns h wrapped in the chain's middleware.
+func (c Chain) Then(h http.Handler) http.Handler {
+	for i := len(c) - 1; i >= 0; i-- {
+		h = c[i](h)
+	}
+	return h
+}
+
+// The stages of the pipeline every JSON API route runs through before its
+// controller, outermost first. Custom middleware can be inserted before or
+// after any of them with Before and After. The HTML pages opened from emails
+// (the claim, opt-out, and magic link pages) and the SAML ACS, which the IdP
+// posts a form to, don't run the pipeline, so only Use's middleware wraps
+// them.
+const (
+	// StageAcceptJSON refuses request bodies that aren't JSON, and sets the
+	// response's Content-Type.
+	StageAcceptJSON = "acceptJSON"
+	// StageCleanOutput turns the route's errors, and panics, into JSON error
+	// responses. Middleware after it can't change those.
+	StageCleanOutput = "cleanOutput"
+	// StageSetUserID authenticates the access token in the Authorization
+	// header, if there is one. Middleware after it can read the user with
+	// UserID.
+	StageSetUserID = "setUserID"
+	// StageValidateIDs refuses malformed IDs in the path.
+	StageValidateIDs = "validateIDParams"
+	// StageDecodeJSON sets how request bodies are decoded.
+	StageDecodeJSON = "decoding"
+)
+
+var pipelineStages = []string{StageAcceptJSON, StageCleanOutput, StageSetUserID, StageValidateIDs, StageDecodeJSON}
+
+// Use wraps everything the API serves with mw, the first outermost. It runs
+// once the request's ID and client IP are known, and inside CORS, so
+// preflight requests don't reach it. The operational endpoints are left out.
+func Use(mw ...Middleware) Option {
+	return func(s *Server) {
+		s.middleware.around = append(s.middleware.around, mw...)
+	}
+}
+
+// Before inserts mw into every JSON route's pipeline just before the stage,
+// the first outermost. It isn't run for the HTML pages or the SAML ACS; use
+// Use for middleware that has to see every request. New fails if there's no
+// such stage.
+func Before(stage string, mw ...Middleware) Option {
+	return func(s *Server) {
+		s.middleware.insert(stage, true, mw)
+	}
+}
+
+// After inserts mw into every JSON route's pipeline just after the stage,
+// the first outermost. Like Before, it isn't run for the HTML pages or the
+// SAML ACS. New fails if there's no such stage.
+func After(stage string, mw ...Middleware) Option {
+	return func(s *Server) {
+		s.middleware.insert(stage, false, mw)
+	}
+}
+
+// middlewareHooks are the custom middleware the server's options insert.
+type middlewareHooks struct {
+	around        Chain
+	before, after map[string]Chain
+	// unknown are the stages inserted around that don't exist.
+	unknown []string
+}
+
+func (m *middlewareHooks) insert(stage string, before bool, mw []Middleware) {
+	known := false
+	for _, s := range pipelineStages {
+		if s == stage {
+			known = true
+			break
+		}
+	}
+	if !known {
+		m.unknown = append(m.unknown, stage)
+		return
+	}
+
+	hooks := &m.after
+	if before {
+		hooks = &m.before
+	}
+	if *hooks == nil {
+		*hooks = map[string]Chain{}
+	}
+	(*hooks)[stage] = append((*hooks)[stage], mw...)
+}
+
+func (m *middlewareHooks) err() error {
+	if len(m.unknown) > 0 {
+		return fmt.Errorf("middleware: unknown pipeline stage %q", m.unknown[0])
+	}
+	return nil
+}
+
+// pipeline returns the pipeline of the stages, with the custom middleware
+// inserted around them.
+func (m *middlewareHooks) pipeline(setUserID, decoding func(action) action) func(action) httprouter.Handle {
+	stages := []struct {
+		name string
+		wrap func(action) action
+	}{
+		{StageSetUserID, setUserID},
+		{StageValidateIDs, validateIDParams},
+		{StageDecodeJSON, decoding},
+	}
+
+	return func(a action) httprouter.Handle {
+		for i := len(stages) - 1; i >= 0; i-- {
+			st := stages[i]
+			a = m.after[st.name].action(a)
+			a = st.wrap(a)
+			a = m.before[st.name].action(a)
+		}
+		a = m.after[StageCleanOutput].action(a)
+		h := cleanOutput(a)
+		h = m.before[StageCleanOutput].handle(h)
+		h = m.after[StageAcceptJSON].handle(h)
+		h = acceptJSON(h)
+		return m.before[StageAcceptJSON].handle(h)
+	}
+}
+
+// handle returns h wrapped in the chain's middleware.
+func (c Chain) handle(h httprouter.Handle) httprouter.Handle {
+	if len(c) == 0 {
+		return h
+	}
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		c.Then(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h(w, r, p)
+		})).ServeHTTP(w, r)
+	}
+}
+
+// action returns a wrapped in the chain's middleware. The action's error is
+// passed back out to be handled by cleanOutput, as though the middleware
+// weren't there.
+func (c Chain) action(a action) action {
+	if len(c) == 0 {
+		return a
+	}
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+		var err error
+		c.Then(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			err = a(w, r, p)
+		})).ServeHTTP(w, r)
+		return err
+	}
+}
+
+// UserID returns the ID of the user the request's access token authenticated,
+// or uuid.Nil if it's anonymous or hasn't been through StageSetUserID yet.
+func UserID(r *http.Request) uuid.UUID {
+	id, _ := r.Context().Value(userIDCtxKeyValue).(uuid.UUID)
+	return id
+}
+
+// RequestID returns the request's ID, which is logged with it and sent back
+// in the X-Request-ID header.
+func RequestID(r *http.Request) string {
+	return requestID(r)
+}
+
+// RequestLogger returns the request's logger, which logs with its ID, and
+// its user once it's authenticated.
+func RequestLogger(r *http.Request) *slog.Logger {
+	return requestLogger(r)
+}
@@ -0,0 +1,298 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/gavinwade12/sendkey/internal/app"
+	"github.com/gavinwade12/sendkey/internal/redact"
+	"github.com/gavinwade12/sendkey/pkg/api"
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	oidcStateLifetime = 10 * time.Minute
+	// oidcStateCookie binds a login to the browser that started it, so a
+	// callback URL from someone else's login can't be used to log the
+	// browser in as them.
+	oidcStateCookie = "sendkey_oidc_state"
+)
+
+type oidcProviderConfig struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+
+	// TrustEmail lets users sign up with the email claim even if the
+	// provider doesn't send email_verified, e.g. Azure AD. The email still
+	// isn't trusted to link the identity to an existing user, who has to log
+	// in to their account to link it.
+	TrustEmail bool
+}
+
+// oidcProvider is an OpenID Connect provider using the authorization code flow.
+// Google, Azure AD, and any other issuer that publishes a discovery document
+// are all configured the same way.
+type oidcProvider struct {
+	oidcProviderConfig
+
+	mu        sync.Mutex
+	discovery *oidcDiscovery
+}
+
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+}
+
+func (p *oidcProvider) discover() (*oidcDiscovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	res, err := http.Get(strings.TrimSuffix(p.Issuer, "/") + "/.well-known/openid-configuration")
+	if err != nil {
+		return nil, fmt.Errorf("fetching oidc discovery document: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching oidc discovery document: unexpected status %d", res.StatusCode)
+	}
+
+	var d oidcDiscovery
+	if err = json.NewDecoder(res.Body).Decode(&d); err != nil {
+		return nil, fmt.Errorf("decoding oidc discovery document: %w", err)
+	}
+
+	p.discovery = &d
+	return p.discovery, nil
+}
+
+// exchange trades the authorization code for an ID token and returns its claims.
+// The token comes directly from the provider's token endpoint over TLS, so its
+// signature isn't checked (OIDC Core 3.1.3.7), but its claims still are.
+func (p *oidcProvider) exchange(code, nonce string) (jwt.MapClaims, error) {
+	d, err := p.discover()
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := http.PostForm(d.TokenEndpoint, url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.RedirectURL},
+		"client_id":     {p.ClientID},
+		"client_secret": {p.ClientSecret},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("exchanging authorization code: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("exchanging authorization code: unexpected status %d", res.StatusCode)
+	}
+
+	var tokens struct {
+		IDToken string `json:"id_token"`
+	}
+	if err = json.NewDecoder(res.Body).Decode(&tokens); err != nil {
+		return nil, fmt.Errorf("decoding token response: %w", err)
+	}
+
+	claims := jwt.MapClaims{}
+	if _, _, err = new(jwt.Parser).ParseUnverified(tokens.IDToken, claims); err != nil {
+		return nil, fmt.Errorf("parsing id token: %w", err)
+	}
+
+	if err = claims.Valid(); err != nil {
+		return nil, err
+	}
+	if !claims.VerifyIssuer(d.Issuer, true) {
+		return nil, fmt.Errorf("unexpected id token issuer")
+	}
+	if !claims.VerifyAudience(p.ClientID, true) {
+		return nil, fmt.Errorf("unexpected id token audience")
+	}
+	if n, _ := claims["nonce"].(string); n != nonce {
+		return nil, fmt.Errorf("id token nonce mismatch")
+	}
+
+	return claims, nil
+}
+
+type OIDCController struct {
+	*UsersController
+
+	providers map[string]*oidcProvider
+	stateKey  []byte
+}
+
+func newOIDCController(uc *UsersController, stateKey []byte, providers []oidcProviderConfig) *OIDCController {
+	c := &OIDCController{
+		UsersController: uc,
+		providers:       map[string]*oidcProvider{},
+		stateKey:        stateKey,
+	}
+	for _, p := range providers {
+		c.providers[p.Name] = &oidcProvider{oidcProviderConfig: p}
+	}
+
+	return c
+}
+
+// oidcStateClaims are kept in the state cookie. Only the state itself is
+// sent to the provider.
+type oidcStateClaims struct {
+	Provider string `json:"provider"`
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	jwt.StandardClaims
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// stateCookie returns the state cookie for the provider's callback. A
+// negative maxAge deletes it.
+func (p *oidcProvider) stateCookie(value string, maxAge int) *http.Cookie {
+	c := &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		// Lax, since the provider redirects back with a top-level GET
+		SameSite: http.SameSiteLaxMode,
+	}
+	if u, err := url.Parse(p.RedirectURL); err == nil {
+		if u.Path != "" {
+			c.Path = u.Path
+		}
+		c.Secure = u.Scheme == "https"
+	}
+	return c
+}
+
+func (c *OIDCController) Start(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	provider, ok := c.providers[r.URL.Query().Get("provider")]
+	if !ok {
+		return api.Error{StatusCode: http.StatusBadRequest, Code: "unknown_provider", Message: "Unknown identity provider."}
+	}
+
+	d, err := provider.discover()
+	if err != nil {
+		return err
+	}
+
+	state, err := randomHex(16)
+	if err != nil {
+		return err
+	}
+	nonce, err := randomHex(16)
+	if err != nil {
+		return err
+	}
+
+	cookie, err := jwt.NewWithClaims(jwt.SigningMethodHS256, oidcStateClaims{
+		Provider: provider.Name,
+		State:    state,
+		Nonce:    nonce,
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: time.Now().Add(oidcStateLifetime).Unix(),
+		},
+	}).SignedString(c.stateKey)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, provider.stateCookie(cookie, int(oidcStateLifetime.Seconds())))
+
+	scopes := provider.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"openid", "email", "profile"}
+	}
+
+	q := url.Values{
+		"response_type": {"code"},
+		"client_id":     {provider.ClientID},
+		"redirect_uri":  {provider.RedirectURL},
+		"scope":         {strings.Join(scopes, " ")},
+		"state":         {state},
+		"nonce":         {nonce},
+	}
+	http.Redirect(w, r, d.AuthorizationEndpoint+"?"+q.Encode(), http.StatusFound)
+	return nil
+}
+
+func (c *OIDCController) Callback(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	q := r.URL.Query()
+	if e := q.Get("error"); e != "" {
+		return api.Error{StatusCode: http.StatusUnauthorized, Code: "provider_error", Message: e + ": " + q.Get("error_description")}
+	}
+
+	invalidState := api.Error{StatusCode: http.StatusBadRequest, Code: "invalid_state", Message: "Invalid state."}
+	cookie, err := r.Cookie(oidcStateCookie)
+	if err != nil {
+		return invalidState
+	}
+	var state oidcStateClaims
+	_, err = jwt.ParseWithClaims(cookie.Value, &state, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+		return c.stateKey, nil
+	})
+	if err != nil || subtle.ConstantTimeCompare([]byte(state.State), []byte(q.Get("state"))) != 1 {
+		return invalidState
+	}
+
+	provider, ok := c.providers[state.Provider]
+	if !ok {
+		return api.Error{StatusCode: http.StatusBadRequest, Code: "unknown_provider", Message: "Unknown identity provider."}
+	}
+	// the state is single use
+	http.SetCookie(w, provider.stateCookie("", -1))
+
+	claims, err := provider.exchange(q.Get("code"), state.Nonce)
+	if err != nil {
+		// the provider's error can quote the code or tokens, so it's only
+		// logged
+		requestLogger(r).Warn("oidc: exchanging the code", "provider", provider.Name, "error", redact.String(err.Error()))
+		return api.Error{StatusCode: http.StatusUnauthorized, Code: "external_login_failed", Message: "Logging in with the identity provider failed."}
+	}
+
+	req := app.ExternalLoginRequest{Provider: provider.Name}
+	req.Subject, _ = claims["sub"].(string)
+	req.Email, _ = claims["email"].(string)
+	req.EmailVerified, _ = claims["email_verified"].(bool)
+	req.TrustEmail = provider.TrustEmail
+	req.FirstName, _ = claims["given_name"].(string)
+	req.LastName, _ = claims["family_name"].(string)
+
+	resp, err := c.service.ExternalLogin(req)
+	if err != nil {
+		return err
+	}
+
+	return c.writeExternalLoginResponse(w, r, req, resp)
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gavinwade12/sendkey/pkg/api"
+)
+
+func TestOIDCStateIsBoundToTheBrowser(t *testing.T) {
+	idp := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(oidcDiscovery{AuthorizationEndpoint: "https://idp.example/authorize"})
+	}))
+	defer idp.Close()
+
+	c := newOIDCController(&UsersController{}, []byte("state key"), []oidcProviderConfig{
+		{Name: "google", Issuer: idp.URL, ClientID: "c1", RedirectURL: "https://sendkey.example/auth/oidc/callback"},
+	})
+
+	w := httptest.NewRecorder()
+	if err := c.Start(w, httptest.NewRequest(http.MethodGet, "/auth/oidc/start?provider=google", nil), nil); err != nil {
+		t.Fatal(err)
+	}
+	location, err := url.Parse(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := location.Query().Get("state")
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != oidcStateCookie {
+		t.Fatalf("got the cookies %v", cookies)
+	}
+	cookie := cookies[0]
+	if !cookie.HttpOnly || !cookie.Secure || cookie.Path != "/auth/oidc/callback" {
+		t.Errorf("got the cookie %+v", cookie)
+	}
+	if state == "" || state == cookie.Value || location.Query().Get("nonce") == "" {
+		t.Errorf("got the state %q and the nonce %q, want only the state's random part sent", state, location.Query().Get("nonce"))
+	}
+
+	tests := []struct {
+		name   string
+		state  string
+		cookie *http.Cookie
+	}{
+		{"no cookie", state, nil},
+		{"another login's state", "0123456789abcdef0123456789abcdef", cookie},
+		{"cookie signed with another key", state, &http.Cookie{Name: oidcStateCookie, Value: "eyJhbGciOiJIUzI1NiJ9.e30.ZRrHA1JJJW8opsbCGfG_HACGpVUMN_a9IV7pAx_Zmeo"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/auth/oidc/callback?code=c&state="+tt.state, nil)
+			if tt.cookie != nil {
+				r.AddCookie(tt.cookie)
+			}
+			err := c.Callback(httptest.NewRecorder(), r, nil)
+			if e, ok := err.(api.Error); !ok || e.Code != "invalid_state" {
+				t.Errorf("Callback() = %v, want invalid_state", err)
+			}
+		})
+	}
+}
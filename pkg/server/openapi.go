@@ -0,0 +1,185 @@
+package server
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gavinwade12/sendkey/internal/app"
+	"github.com/gavinwade12/sendkey/pkg/api"
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// OpenAPI serves the OpenAPI document describing the operations, with this
+// server's current API version as its server, for generating clients in
+// other languages.
+func OpenAPI(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	u := url.URL{Scheme: requestScheme(r), Host: r.Host, Path: api.BasePath}
+	return respond(w, http.StatusOK, api.NewOpenAPIDocument(u.String()))
+}
+
+// requestValidation checks request bodies against the OpenAPI document's
+// schemas before they reach the route, responding with every field that has
+// the wrong type or format at once. Fields the document doesn't describe are
+// left for strict decoding to refuse, and bodies that aren't JSON for the
+// route to.
+type requestValidation struct {
+	doc *api.OpenAPIDocument
+}
+
+// route validates the bodies of requests to the route, the method and path
+// within its API version, if the document describes its body.
+func (v *requestValidation) route(route string, h httprouter.Handle) httprouter.Handle {
+	method, path, _ := strings.Cut(route, " ")
+	schema := v.doc.RequestSchema(method, path)
+	if schema == nil {
+		return h
+	}
+
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			respond(w, http.StatusBadRequest, invalidBody(r, err))
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		var value interface{}
+		d := json.NewDecoder(bytes.NewReader(body))
+		d.UseNumber()
+		if err := d.Decode(&value); err != nil {
+			h(w, r, p)
+			return
+		}
+		problems := v.check(schema, value, "")
+		if len(problems) == 0 {
+			h(w, r, p)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		respond(w, http.StatusBadRequest, envelope(r, false, problems))
+	}
+}
+
+// check returns the problems with the decoded JSON value at field. A null is
+// always valid, since decoding leaves the field's zero value.
+func (v *requestValidation) check(s *api.Schema, value interface{}, field string) []app.Problem {
+	s = v.doc.Resolve(s)
+	if s == nil || value == nil {
+		return nil
+	}
+	for _, sub := range s.AllOf {
+		if problems := v.check(sub, value, field); len(problems) > 0 {
+			return problems
+		}
+	}
+	if len(s.OneOf) > 0 {
+		var types []string
+		for _, sub := range s.OneOf {
+			if len(v.check(sub, value, field)) == 0 {
+				return nil
+			}
+			types = append(types, v.doc.Resolve(sub).Type)
+		}
+		return []app.Problem{{Field: field, Code: "field_type_invalid", Args: []interface{}{strings.Join(types, ", ")}}}
+	}
+
+	typeInvalid := []app.Problem{{Field: field, Code: "field_type_invalid", Args: []interface{}{s.Type}}}
+	switch s.Type {
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return typeInvalid
+		}
+		names := make([]string, 0, len(obj))
+		for name := range obj {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		var problems []app.Problem
+		for _, name := range names {
+			if sub := property(s, name); sub != nil {
+				problems = append(problems, v.check(sub, obj[name], joinField(field, name))...)
+			}
+		}
+		return problems
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return typeInvalid
+		}
+		var problems []app.Problem
+		for i, item := range items {
+			problems = append(problems, v.check(s.Items, item, field+"["+strconv.Itoa(i)+"]")...)
+		}
+		return problems
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return typeInvalid
+		}
+		if s.Format != "" && !validFormat(s.Format, str) {
+			return []app.Problem{{Field: field, Code: "field_format_invalid", Args: []interface{}{s.Format}}}
+		}
+	case "integer":
+		n, ok := value.(json.Number)
+		if !ok {
+			return typeInvalid
+		}
+		if _, err := n.Int64(); err != nil {
+			return typeInvalid
+		}
+	case "number":
+		if _, ok := value.(json.Number); !ok {
+			return typeInvalid
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return typeInvalid
+		}
+	}
+	return nil
+}
+
+// property returns the schema of the object's property, matched the way
+// decoding matches fields: exactly, or else ignoring case.
+func property(s *api.Schema, name string) *api.Schema {
+	if sub, ok := s.Properties[name]; ok {
+		return sub
+	}
+	for n, sub := range s.Properties {
+		if strings.EqualFold(n, name) {
+			return sub
+		}
+	}
+	return s.AdditionalProperties
+}
+
+func joinField(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
+
+func validFormat(format, s string) bool {
+	var err error
+	switch format {
+	case "uuid":
+		_, err = uuid.Parse(s)
+	case "date-time":
+		_, err = time.Parse(time.RFC3339, s)
+	case "byte":
+		_, err = base64.StdEncoding.DecodeString(s)
+	}
+	return err == nil
+}
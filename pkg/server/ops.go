@@ -0,0 +1,102 @@
+package server
+
+import (
+	"expvar"
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+
+	"github.com/gavinwade12/sendkey/internal/metrics"
+	"github.com/gavinwade12/sendkey/internal/mysql"
+	"github.com/julienschmidt/httprouter"
+)
+
+// opsConfig serves the operational endpoints: /healthz, the metrics, the
+// replication status, /debug/vars and /debug/stats, and pprof. They skip the
+// API's middleware, so scrapers and load balancers don't need JSON headers,
+// a token, or an allowed origin, and chaos faults aren't injected into them.
+type opsConfig struct {
+	// Addr is the host:port of a separate listener for the endpoints, e.g.
+	// "127.0.0.1:9090", so they can be kept off the public network, where
+	// Server.Ops is served. Empty serves the health check and the metrics
+	// along with the API, and leaves the rest out.
+	Addr string
+	// Pprof serves the runtime profiler from /debug/pprof/. It needs Addr.
+	Pprof bool
+}
+
+// check returns an error if the config would put the profiler or the
+// replication status on the API's listener.
+func (c opsConfig) check(replication replicationConfig) error {
+	if c.Pprof && c.Addr == "" {
+		return fmt.Errorf("ops: Pprof needs a separate Addr to serve the profiler on")
+	}
+	if replication.StatusEnabled && c.Addr == "" {
+		return fmt.Errorf("ops: Replication.StatusEnabled needs a separate Addr to serve the status on")
+	}
+	return nil
+}
+
+// publicOpsMux returns the mux for the operational endpoints that can be
+// served along with the API.
+func publicOpsMux(db *mysql.DB, reg *metrics.Registry) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", healthHandler(db))
+	if reg != nil {
+		mux.Handle("/metrics", reg.PrometheusHandler())
+	}
+	return mux
+}
+
+// opsMux returns the mux for every operational endpoint. The metrics and
+// replication status are only served if they're enabled.
+func opsMux(cfg opsConfig, db *mysql.DB, reg *metrics.Registry, replication replicationConfig) *http.ServeMux {
+	mux := publicOpsMux(db, reg)
+	if reg != nil {
+		mux.Handle("/debug/vars", expvar.Handler())
+		mux.Handle("/debug/stats", handle(statsHandler(db, reg)))
+	}
+	if replication.StatusEnabled {
+		mux.Handle("/admin/replication", handle(replicationHandler(db, replication)))
+	}
+	if cfg.Pprof {
+		mux.HandleFunc("/debug/pprof/", pprof.Index)
+		mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	}
+	return mux
+}
+
+// withOps sends requests for the operational endpoints to the mux before
+// they reach the API's handler and its middleware.
+func withOps(mux *http.ServeMux, api http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, pattern := mux.Handler(r); pattern != "" {
+			mux.ServeHTTP(w, r)
+			return
+		}
+		api.ServeHTTP(w, r)
+	})
+}
+
+// healthHandler reports whether the server can reach the database.
+func healthHandler(db *mysql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		if err := db.Ping(); err != nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte("database unavailable\n"))
+			return
+		}
+		w.Write([]byte("ok\n"))
+	}
+}
+
+func handle(h httprouter.Handle) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h(w, r, nil)
+	})
+}
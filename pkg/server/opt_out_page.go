@@ -0,0 +1,97 @@
+package server
+
+import (
+	"html/template"
+	"net/http"
+
+	"github.com/gavinwade12/sendkey/internal/app"
+	"github.com/gavinwade12/sendkey/internal/i18n"
+	"github.com/julienschmidt/httprouter"
+)
+
+// OptOutPageController serves the page the link at the bottom of notification
+// emails opens, where recipients opt out of receiving entries. Opening the
+// link only shows a form, so mail scanners that follow links can't opt
+// anyone out.
+type OptOutPageController struct {
+	service *app.EntryService
+	links   *app.OptOutLinks
+}
+
+type optOutPageModel struct {
+	Lang     string
+	T        func(string) string
+	Token    string
+	Email    string
+	OptedOut bool
+	Invalid  bool
+}
+
+// Show renders the form confirming the opt-out.
+func (c *OptOutPageController) Show(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	token := p.ByName("token")
+	email, ok := c.links.Verify(token)
+	if !ok {
+		return c.render(w, r, http.StatusNotFound, optOutPageModel{Invalid: true})
+	}
+	return c.render(w, r, http.StatusOK, optOutPageModel{Token: token, Email: email})
+}
+
+// OptOut opts the link's address out of receiving entries.
+func (c *OptOutPageController) OptOut(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	email, ok := c.links.Verify(p.ByName("token"))
+	if !ok {
+		return c.render(w, r, http.StatusNotFound, optOutPageModel{Invalid: true})
+	}
+	if err := c.service.OptOut(email); err != nil {
+		return err
+	}
+	return c.render(w, r, http.StatusOK, optOutPageModel{Email: email, OptedOut: true})
+}
+
+func (c *OptOutPageController) render(w http.ResponseWriter, r *http.Request, status int, model optOutPageModel) error {
+	model.Lang = i18n.Negotiate(r.Header.Get("Accept-Language"))
+	if model.Lang == "" {
+		model.Lang = i18n.DefaultLanguage
+	}
+	model.T = i18n.Translator(model.Lang)
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; form-action 'self'")
+	w.WriteHeader(status)
+	return optOutPageTemplate.Execute(w, model)
+}
+
+var optOutPageTemplate = template.Must(template.New("opt-out").Parse(`<!DOCTYPE html>
+<html lang="{{.Lang}}">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex, nofollow">
+<title>sendkey</title>
+<style>
+body { font-family: sans-serif; max-width: 40em; margin: 2em auto; padding: 0 1em; line-height: 1.5; }
+button { font-size: 1em; margin-top: 1em; padding: .5em 1em; }
+:focus { outline: 3px solid #1a5fb4; outline-offset: 2px; }
+</style>
+</head>
+<body>
+<main>
+<h1>{{call .T "Stop receiving entries"}}</h1>
+{{if .Invalid}}
+<p>{{call .T "This link is invalid."}}</p>
+{{else if .OptedOut}}
+<p><strong>{{.Email}}</strong></p>
+<p>{{call .T "You've opted out. Entries can no longer be sent to this address with sendkey."}}</p>
+{{else}}
+<p><strong>{{.Email}}</strong></p>
+<p>{{call .T "Opt this address out of receiving entries sent with sendkey? Senders will be told it can't receive them."}}</p>
+<form method="post" action="/unsubscribe/{{.Token}}">
+<button type="submit">{{call .T "Opt out"}}</button>
+</form>
+{{end}}
+</main>
+</body>
+</html>
+`))
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/gavinwade12/sendkey"
+	"github.com/gavinwade12/sendkey/pkg/api"
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+type idParamsCtxKey string
+
+const idParamsCtxKeyValue = idParamsCtxKey("idParams")
+
+// validateIDParams parses every path parameter named like "entryID" or
+// "userID" as a UUID, in either form sendkey.FormatID writes, before the
+// action runs. An invalid ID is a 400 with a code like "invalid_entry_id";
+// valid IDs are available from idParam.
+func validateIDParams(a action) action {
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+		var ids map[string]uuid.UUID
+		for _, param := range p {
+			if !strings.HasSuffix(param.Key, "ID") {
+				continue
+			}
+
+			// "me" is the current user, e.g. /users/me/usage
+			if param.Key == "userID" && param.Value == "me" {
+				id, _ := baseController{}.GetCurrentUserID(r)
+				ids = setIDParam(ids, param.Key, id)
+				continue
+			}
+
+			id, err := sendkey.ParseID(param.Value)
+			if err != nil {
+				userID, _ := baseController{}.GetCurrentUserID(r)
+				return api.Error{
+					UserID:     userID,
+					StatusCode: http.StatusBadRequest,
+					Code:       "invalid_" + snakeCase(param.Key),
+					Message:    "Invalid " + param.Key + ".",
+				}
+			}
+
+			ids = setIDParam(ids, param.Key, id)
+		}
+
+		if ids != nil {
+			r = r.WithContext(context.WithValue(r.Context(), idParamsCtxKeyValue, ids))
+		}
+		return a(w, r, p)
+	}
+}
+
+func setIDParam(ids map[string]uuid.UUID, name string, id uuid.UUID) map[string]uuid.UUID {
+	if ids == nil {
+		ids = map[string]uuid.UUID{}
+	}
+	ids[name] = id
+	return ids
+}
+
+// idParam returns the ID path parameter parsed by validateIDParams.
+func idParam(r *http.Request, name string) uuid.UUID {
+	ids, _ := r.Context().Value(idParamsCtxKeyValue).(map[string]uuid.UUID)
+	return ids[name]
+}
+
+// snakeCase converts a parameter name like "entryID" to "entry_id".
+func snakeCase(s string) string {
+	var b strings.Builder
+	for i, r := range s {
+		if r >= 'A' && r <= 'Z' {
+			if i > 0 && !(s[i-1] >= 'A' && s[i-1] <= 'Z') {
+				b.WriteByte('_')
+			}
+			r += 'a' - 'A'
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
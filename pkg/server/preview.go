@@ -0,0 +1,70 @@
+package server
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gavinwade12/sendkey/internal/ratelimit"
+)
+
+// linkPreviewAgents are user agent fragments of crawlers, chat apps, and mail
+// scanners that fetch links to build previews or check them for malware.
+var linkPreviewAgents = []string{
+	"bot",
+	"crawler",
+	"spider",
+	"preview",
+	"facebookexternalhit",
+	"whatsapp",
+	"skypeuripreview",
+	"microsoft office",
+	"ms-office",
+	"outlook",
+	"google-safety",
+	"barracuda",
+	"mimecast",
+	"proofpoint",
+}
+
+// isLinkPreview reports whether the request looks like a prefetch or a link
+// preview rather than a person opening the link. Requests without a user
+// agent are treated as previews too. Previews must never consume claim
+// attempts.
+func isLinkPreview(r *http.Request) bool {
+	if r.Method == http.MethodHead {
+		return true
+	}
+	for _, h := range []string{"Purpose", "Sec-Purpose", "X-Purpose", "X-Moz"} {
+		v := strings.ToLower(r.Header.Get(h))
+		if strings.Contains(v, "prefetch") || strings.Contains(v, "preview") {
+			return true
+		}
+	}
+
+	ua := strings.ToLower(r.UserAgent())
+	if ua == "" {
+		return true
+	}
+	for _, a := range linkPreviewAgents {
+		if strings.Contains(ua, a) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// previewLimited records a preview from the client's IP and reports whether
+// it's over the limit, setting Retry-After if it is. The limit keeps previews
+// from being used to probe for entries.
+func previewLimited(w http.ResponseWriter, r *http.Request, l *ratelimit.Limiter) (bool, error) {
+	wait, err := l.Allow("preview:ip:" + clientIP(r))
+	if err != nil || wait == 0 {
+		return false, err
+	}
+
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	return true, nil
+}
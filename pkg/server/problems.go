@@ -0,0 +1,68 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gavinwade12/sendkey/internal/app"
+	"github.com/gavinwade12/sendkey/internal/i18n"
+	"github.com/gavinwade12/sendkey/pkg/api"
+)
+
+// language returns the supported language the request's Accept-Language
+// prefers, or the default language.
+func language(r *http.Request) string {
+	if lang := i18n.Negotiate(r.Header.Get("Accept-Language")); lang != "" {
+		return lang
+	}
+	return i18n.DefaultLanguage
+}
+
+// envelope returns a response envelope with the problems' messages in the
+// request's language, in the error format the request gets.
+func envelope(r *http.Request, success bool, problems []app.Problem) api.Envelope {
+	e := api.Envelope{Success: success, Warnings: requestWarnings(r)}
+	lang, legacy := language(r), legacyErrors(r)
+	for _, p := range problems {
+		e.Errors = append(e.Errors, api.FieldError{Field: p.Field, Code: p.Code, Message: p.Message(lang), Legacy: legacy})
+		if legacy {
+			e.Codes = append(e.Codes, p.Code)
+		}
+	}
+	return e
+}
+
+// legacyErrors reports whether the request gets its envelope's errors in the
+// legacy format. Routes without a version keep the format they always had.
+func legacyErrors(r *http.Request) bool {
+	if !strings.HasPrefix(r.URL.Path, api.BasePath+"/") {
+		return true
+	}
+	return strings.EqualFold(r.Header.Get(api.ErrorFormatHeader), api.ErrorFormatLegacy)
+}
+
+// messages returns the problems' messages in the request's language.
+func messages(r *http.Request, problems []app.Problem) []string {
+	lang := language(r)
+	var result []string
+	for _, p := range problems {
+		result = append(result, p.Message(lang))
+	}
+	return result
+}
+
+// invalidBody is the envelope for a request body that couldn't be decoded.
+func invalidBody(r *http.Request, err error) api.Envelope {
+	return envelope(r, false, []app.Problem{{Code: "invalid_body", Args: []interface{}{err.Error()}}})
+}
+
+// notAvailable returns the problem for an entry that can't be claimed yet if
+// err is an app.NotAvailableError.
+func notAvailable(err error) (app.Problem, bool) {
+	var na *app.NotAvailableError
+	if !errors.As(err, &na) {
+		return app.Problem{}, false
+	}
+	return na.Problem(), true
+}
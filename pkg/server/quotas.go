@@ -0,0 +1,40 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/gavinwade12/sendkey/internal/app"
+	"github.com/gavinwade12/sendkey/pkg/api"
+	"github.com/julienschmidt/httprouter"
+)
+
+// quotaConfig limits what each registered user can send, so registration can
+// be opened to the public. Zero values are unlimited.
+type quotaConfig struct {
+	EntriesPerDay  int
+	MaxLiveEntries int
+	// MaxValueBytes can only lower MaxEntryValueBytes.
+	MaxValueBytes int
+}
+
+func (c quotaConfig) quota() app.Quota {
+	return app.Quota(c)
+}
+
+// Usage returns what the user has sent against their quota.
+func (c *EntriesController) Usage(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	userID := idParam(r, "userID")
+	usage, err := requestEntries(r, c.service).Usage(userID)
+	if err != nil {
+		return err
+	}
+	if usage == nil {
+		return api.Error{UserID: userID, StatusCode: http.StatusNotFound, Code: "user_not_found", Message: "The user doesn't exist."}
+	}
+
+	return respond(w, http.StatusOK, api.Usage{
+		EntriesToday: usage.EntriesToday,
+		LiveEntries:  usage.LiveEntries,
+		Quota:        api.Quota(usage.Quota),
+	})
+}
@@ -0,0 +1,163 @@
+package server
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gavinwade12/sendkey/internal/ratelimit"
+	"github.com/gavinwade12/sendkey/pkg/api"
+	"github.com/julienschmidt/httprouter"
+)
+
+// rateConfig is a token bucket's rate. A zero PerMinute doesn't limit.
+type rateConfig struct {
+	PerMinute float64
+	// Burst is how many requests can be made at once before the rate
+	// applies. Zero allows a minute's worth.
+	Burst int
+}
+
+func (c rateConfig) bucket(store ratelimit.BucketStore) *ratelimit.TokenBucket {
+	if c.PerMinute <= 0 {
+		return nil
+	}
+	burst := c.Burst
+	if burst <= 0 {
+		burst = int(math.Ceil(c.PerMinute))
+	}
+	return ratelimit.NewTokenBucket(store, c.PerMinute/60, burst)
+}
+
+// routeRateConfig limits requests from each IP and each signed in user.
+type routeRateConfig struct {
+	IP   rateConfig
+	User rateConfig
+}
+
+type rateLimitsConfig struct {
+	Enabled bool
+	// Global limits the routes without their own limits.
+	Global routeRateConfig
+	// Routes override the limits for routes, keyed by method and path
+	// without the version prefix, like "POST /login", which also limits the
+	// deprecated unversioned route. They're added to defaultRouteLimits.
+	Routes map[string]routeRateConfig
+}
+
+// defaultRouteLimits are stricter limits for the routes that check a
+// password, an MFA code, or an entry's secret, which are the ones worth guessing at.
+var defaultRouteLimits = map[string]routeRateConfig{
+	"POST /login":                            {IP: rateConfig{PerMinute: 10, Burst: 5}},
+	"POST /login/magic":                      {IP: rateConfig{PerMinute: 5, Burst: 3}},
+	"POST /auth/external":                    {IP: rateConfig{PerMinute: 10, Burst: 5}},
+	"GET /entries/:entryID/value":            {IP: rateConfig{PerMinute: 20, Burst: 5}, User: rateConfig{PerMinute: 20, Burst: 5}},
+	"POST /entries/:entryID/delegated-claim": {IP: rateConfig{PerMinute: 20, Burst: 5}},
+	"POST /claim/:entryID":                   {IP: rateConfig{PerMinute: 20, Burst: 5}},
+}
+
+type routeBuckets struct {
+	ip, user *ratelimit.TokenBucket
+}
+
+// rateLimits limits requests to each route by IP and by user, with the
+// buckets kept in a store shared by the API's instances if it's Redis.
+type rateLimits struct {
+	global routeBuckets
+	routes map[string]routeBuckets
+	atv    AccessTokenVerifier
+}
+
+func newRateLimits(cfg rateLimitsConfig, store ratelimit.BucketStore, atv AccessTokenVerifier) *rateLimits {
+	l := &rateLimits{
+		global: routeBuckets{cfg.Global.IP.bucket(store), cfg.Global.User.bucket(store)},
+		routes: map[string]routeBuckets{},
+		atv:    atv,
+	}
+	for route, c := range defaultRouteLimits {
+		l.routes[route] = routeBuckets{c.IP.bucket(store), c.User.bucket(store)}
+	}
+	for route, c := range cfg.Routes {
+		l.routes[route] = routeBuckets{c.IP.bucket(store), c.User.bucket(store)}
+	}
+	return l
+}
+
+// route limits requests to the route, responding with 429 and Retry-After
+// once the client's IP or user is out of requests. Routes without their own
+// limits share the global buckets. Every response has RateLimit headers with
+// the client's quota, so it can slow down before it's refused.
+func (l *rateLimits) route(route string, h httprouter.Handle) httprouter.Handle {
+	b, prefix := l.global, "rate:"
+	if rb, ok := l.routes[route]; ok {
+		b, prefix = rb, "rate:"+route+":"
+	}
+	if b.ip == nil && b.user == nil {
+		return h
+	}
+
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		quota, err := l.allow(r, b, prefix)
+		if err != nil {
+			// a limit that can't be checked, like with Redis down, lets
+			// the request through rather than taking the API down with it
+			requestLogger(r).Error("checking rate limits", "route", route, "error", err)
+		}
+		if quota == nil {
+			h(w, r, p)
+			return
+		}
+		w.Header().Set("RateLimit-Limit", strconv.Itoa(quota.Limit))
+		w.Header().Set("RateLimit-Remaining", strconv.Itoa(quota.Remaining))
+		w.Header().Set("RateLimit-Reset", strconv.Itoa(int(math.Ceil(quota.Reset.Seconds()))))
+		if quota.Wait == 0 {
+			h(w, r, p)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(quota.Wait.Seconds()))))
+		respond(w, http.StatusTooManyRequests, api.Error{
+			StatusCode: http.StatusTooManyRequests,
+			Code:       "rate_limited",
+			Message:    "Too many requests. Try again later.",
+			RequestID:  requestID(r),
+		})
+	}
+}
+
+// allow takes a token for the request's IP and, if it has a valid access
+// token, its user, returning the quota of whichever is closer to its limit,
+// or the first that's out. It's nil if neither was checked. A token that's
+// invalid is left for setUserID to refuse.
+func (l *rateLimits) allow(r *http.Request, b routeBuckets, prefix string) (*ratelimit.Quota, error) {
+	var quota *ratelimit.Quota
+	if b.ip != nil {
+		q, err := b.ip.Allow(prefix + "ip:" + clientIP(r))
+		if err != nil {
+			return nil, err
+		}
+		if quota = &q; q.Wait > 0 {
+			return quota, nil
+		}
+	}
+	if b.user != nil {
+		token := r.Header.Get("Authorization")
+		if token == "" {
+			return quota, nil
+		}
+		userID, _, err := l.atv.Verify(strings.TrimPrefix(token, "Bearer "))
+		if err != nil {
+			return quota, nil
+		}
+		q, err := b.user.Allow(prefix + "user:" + userID.String())
+		if err != nil {
+			return quota, err
+		}
+		if quota == nil || q.Wait > 0 || q.Remaining < quota.Remaining {
+			quota = &q
+		}
+	}
+	return quota, nil
+}
@@ -0,0 +1,30 @@
+package server
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/gavinwade12/sendkey/internal/app"
+)
+
+// sendReminders emails the reminders for deferred claims as they come due,
+// checking every interval until done is closed.
+func sendReminders(entries *app.EntryService, mailer app.Mailer, links *app.ClaimLinks, interval time.Duration, done <-chan struct{}) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		n, err := entries.SendDueReminders(mailer, links, 100)
+		if err != nil {
+			slog.Error("reminders: sending claim reminders", "error", err)
+		} else if n > 0 {
+			slog.Info("reminders: sent claim reminders", "sent", n)
+		}
+
+		select {
+		case <-t.C:
+		case <-done:
+			return
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gavinwade12/sendkey/internal/mysql"
+	"github.com/gavinwade12/sendkey/pkg/api"
+	"github.com/julienschmidt/httprouter"
+)
+
+// replicationConfig supports running a warm standby against a replica.
+type replicationConfig struct {
+	// StatusEnabled serves the database's replication status and lag from
+	// /admin/replication. It has no authentication, so it's only served
+	// from Ops.Addr, which it needs.
+	StatusEnabled bool
+	// MaxLagSecs makes the status endpoint respond with a 503 when the
+	// replica is further behind, or not replicating, for health checks.
+	// Zero doesn't check the lag.
+	MaxLagSecs int
+	// ReadOnly freezes writes so a standby can show entries and claim pages
+	// from a read-only replica. Requests that would write get a 503, and
+	// migrations aren't run.
+	ReadOnly bool
+}
+
+type replicationStatus struct {
+	// Role is "primary" or "replica".
+	Role string `json:"role"`
+	// ReadOnly is set if writes are frozen by the config, and DBReadOnly if
+	// the database rejects them.
+	ReadOnly   bool   `json:"readOnly"`
+	DBReadOnly bool   `json:"dbReadOnly"`
+	SourceHost string `json:"sourceHost,omitempty"`
+	IORunning  bool   `json:"ioRunning"`
+	SQLRunning bool   `json:"sqlRunning"`
+	// LagSecs is how far the replica is behind, or null if it's unknown.
+	LagSecs *int64 `json:"lagSecs"`
+	Healthy bool   `json:"healthy"`
+}
+
+// replicationHandler reports the database's replication status.
+func replicationHandler(db *mysql.DB, cfg replicationConfig) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		rs, err := db.ReplicationStatus()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		status := replicationStatus{
+			Role:       "primary",
+			ReadOnly:   cfg.ReadOnly,
+			DBReadOnly: rs.ReadOnly,
+			Healthy:    true,
+		}
+		if rs.Replica {
+			status.Role = "replica"
+			status.SourceHost = rs.SourceHost
+			status.IORunning = rs.IORunning
+			status.SQLRunning = rs.SQLRunning
+			status.Healthy = rs.IORunning && rs.SQLRunning
+			if rs.Lag != nil {
+				secs := int64(*rs.Lag / time.Second)
+				status.LagSecs = &secs
+			}
+			if cfg.MaxLagSecs > 0 && (status.LagSecs == nil || *status.LagSecs > int64(cfg.MaxLagSecs)) {
+				status.Healthy = false
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		if !status.Healthy {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		writeJSON(w, status)
+	}
+}
+
+// writeGuard returns a wrapper for actions that write to the database. While
+// writes are frozen, the wrapped actions are refused with a 503.
+func writeGuard(frozen bool) func(action) action {
+	return func(a action) action {
+		if !frozen {
+			return a
+		}
+		return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+			return readOnly(w)
+		}
+	}
+}
+
+// readOnly is the error for a write while writes are frozen.
+func readOnly(w http.ResponseWriter) error {
+	w.Header().Set("Retry-After", "60")
+	return api.Error{
+		StatusCode: http.StatusServiceUnavailable,
+		Code:       "read_only",
+		Message:    "This server is read-only. Try again later.",
+	}
+}
@@ -0,0 +1,110 @@
+package server
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/gavinwade12/sendkey/internal/app"
+	"github.com/gavinwade12/sendkey/internal/archive"
+)
+
+// sweepRetention permanently removes data that's past its retention period,
+// checking every interval until done is closed.
+func sweepRetention(users *app.UserService, entries *app.EntryService, userGracePeriod, interval time.Duration, done <-chan struct{}) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		n, err := users.PurgeDeactivatedUsers(userGracePeriod)
+		if err != nil {
+			slog.Error("retention sweep: purging deactivated users", "error", err)
+		} else if n > 0 {
+			slog.Info("retention sweep: purged deactivated users", "purged", n)
+		}
+		n, err = entries.PurgeEscrows()
+		if err != nil {
+			slog.Error("retention sweep: purging escrows", "error", err)
+		} else if n > 0 {
+			slog.Info("retention sweep: purged escrows", "purged", n)
+		}
+		n, err = entries.PurgeHistory()
+		if err != nil {
+			slog.Error("retention sweep: purging entry history", "purged", n, "error", err)
+		} else if n > 0 {
+			slog.Info("retention sweep: purged entry history", "purged", n)
+		}
+
+		select {
+		case <-t.C:
+		case <-done:
+			return
+		}
+	}
+}
+
+// escrowValues turns on escrow for new entries with the public key in the
+// file. Escrows have to be kept for some time after claim, or there'd be no
+// point to them.
+func escrowValues(entries *app.EntryService, keyFile string, retentionDays int) error {
+	if retentionDays <= 0 {
+		return fmt.Errorf("escrow: RetentionDays must be positive")
+	}
+	b, err := os.ReadFile(keyFile)
+	if err != nil {
+		return fmt.Errorf("escrow: %w", err)
+	}
+	key, err := app.ParseEscrowKey(b)
+	if err != nil {
+		return fmt.Errorf("escrow: %w", err)
+	}
+	return entries.EscrowValues(app.Escrow{Key: key, Retention: 24 * time.Hour * time.Duration(retentionDays)})
+}
+
+// archiveConfig is where entry history is archived before it's purged: a
+// directory, or an S3 bucket. Neither purges it without archiving it.
+type archiveConfig struct {
+	Dir string
+	S3  struct {
+		// Endpoint is the URL of storage with S3's API other than AWS, like
+		// MinIO. Region defaults to us-east-1.
+		Endpoint string
+		Region   string
+		Bucket   string
+		Prefix   string
+	}
+}
+
+func (c archiveConfig) archiver() (app.Archiver, error) {
+	switch {
+	case c.Dir != "" && c.S3.Bucket != "":
+		return nil, fmt.Errorf("archive: use either Dir or S3, not both")
+	case c.Dir != "":
+		return archive.NewDir(c.Dir), nil
+	case c.S3.Bucket != "":
+		region := c.S3.Region
+		if region == "" {
+			region = "us-east-1"
+		}
+		return archive.NewS3(c.S3.Endpoint, region, c.S3.Bucket, c.S3.Prefix), nil
+	}
+	return nil, nil
+}
+
+// retainHistory purges entry history older than historyDays, archiving it
+// first if an archive is configured.
+func retainHistory(entries *app.EntryService, historyDays int, c archiveConfig) error {
+	a, err := c.archiver()
+	if err != nil {
+		return err
+	}
+	if historyDays <= 0 {
+		if a != nil {
+			return fmt.Errorf("archive: HistoryDays must be set for history to be archived")
+		}
+		return nil
+	}
+	entries.RetainHistory(app.HistoryRetention{Keep: 24 * time.Hour * time.Duration(historyDays), Archive: a})
+	return nil
+}
@@ -0,0 +1,164 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gavinwade12/sendkey/pkg/api"
+	"github.com/julienschmidt/httprouter"
+)
+
+// legacyRoutes deprecates the routes served at the root, as they were before
+// the API was versioned, in favor of the same routes under api.BasePath.
+var legacyRoutes = deprecation{
+	Since:           time.Date(2022, time.April, 6, 0, 0, 0, 0, time.UTC),
+	SuccessorPrefix: api.BasePath,
+	Message:         "Routes without a version are deprecated; use the same route under " + api.BasePath + ".",
+}
+
+// apiVersion registers a version of the API's routes under its prefix, e.g.
+// "/v1". A new version is mounted alongside the old ones with its own mount
+// func, so clients of the old versions keep working while they move over.
+type apiVersion struct {
+	rt     *router
+	prefix string
+	// legacy also serves the routes at the root, marked deprecated, for
+	// clients from before the API was versioned.
+	legacy *deprecation
+}
+
+func (v *apiVersion) Handle(method, path string, h httprouter.Handle) {
+	v.rt.handle(method, v.prefix+path, method+" "+path, nil, h)
+	if v.legacy != nil {
+		v.rt.handle(method, path, method+" "+path, v.legacy, h)
+	}
+}
+
+// HandleStable registers the route under the prefix and, without marking it
+// deprecated, at the root. It's for routes whose URLs are sent in emails or
+// registered with identity providers, which can't be updated once they're out.
+func (v *apiVersion) HandleStable(method, path string, h httprouter.Handle) {
+	v.rt.handle(method, v.prefix+path, method+" "+path, nil, h)
+	v.rt.Handle(method, path, h)
+}
+
+func (v *apiVersion) GET(path string, h httprouter.Handle) { v.Handle(http.MethodGet, path, h) }
+
+func (v *apiVersion) HEAD(path string, h httprouter.Handle) { v.Handle(http.MethodHead, path, h) }
+
+func (v *apiVersion) POST(path string, h httprouter.Handle) { v.Handle(http.MethodPost, path, h) }
+
+func (v *apiVersion) PUT(path string, h httprouter.Handle) { v.Handle(http.MethodPut, path, h) }
+
+func (v *apiVersion) DELETE(path string, h httprouter.Handle) { v.Handle(http.MethodDelete, path, h) }
+
+// controllers are the API's controllers, shared by its versions. A version
+// that changes what a route does gets its own controller method, leaving the
+// one the older versions route to as it was.
+type controllers struct {
+	users      *UsersController
+	oidc       *OIDCController
+	saml       *SAMLController
+	entries    *EntriesController
+	claimPages *ClaimPageController
+	optOuts    *OptOutPageController
+	magicLinks *MagicLinkPageController
+	admin      *AdminController
+	graphql    *GraphQLController
+	stream     *EventStreamController
+	authz      authorizer
+	pipeline   func(action) httprouter.Handle
+	write      func(action) action
+	// idempotent replays the response to a retried request; see
+	// idempotency.
+	idempotent func(action) action
+}
+
+// mountV1 registers version 1 of the API.
+func mountV1(v *apiVersion, c controllers) {
+	pipeline, write, authz := c.pipeline, c.write, c.authz
+	uc, ec, ac := c.users, c.entries, c.admin
+
+	v.POST("/users", pipeline(write(uc.CreateUser)))
+	v.POST("/login", pipeline(write(uc.Login)))
+	v.POST("/login/magic", pipeline(write(uc.SendMagicLink)))
+	v.HandleStable(http.MethodGet, "/login/magic/:code", noIndex(htmlPage(c.magicLinks.Show)))
+	v.HandleStable(http.MethodPost, "/login/magic/:code", noIndex(magicLinkForm(pipeline(write(uc.RedeemMagicLink)))))
+	v.POST("/token", pipeline(write(uc.RefreshToken)))
+	v.POST("/auth/external", pipeline(write(uc.CompleteExternalLogin)))
+	v.GET("/auth/oidc/start", pipeline(c.oidc.Start))
+	v.HandleStable(http.MethodGet, "/auth/oidc/callback", pipeline(write(c.oidc.Callback)))
+	if c.saml != nil {
+		v.GET("/auth/saml/start", pipeline(c.saml.Start))
+	}
+	v.DELETE("/users/:userID", pipeline(write(authz.require(self, admin)(uc.DeleteUser))))
+	v.PUT("/users/:userID/password", pipeline(write(authz.require(self)(uc.ChangePassword))))
+	v.POST("/users/:userID/mfa", pipeline(write(authz.require(self)(uc.EnableMFA))))
+	v.POST("/users/:userID/mfa/confirm", pipeline(write(authz.require(self)(uc.ConfirmMFA))))
+	v.POST("/users/:userID/mfa/recovery-codes", pipeline(write(authz.require(self)(uc.RegenerateRecoveryCodes))))
+
+	v.POST("/entries", pipeline(write(c.idempotent(ec.CreateEntry))))
+	v.POST("/generate", pipeline(write(requireUser(ec.Generate))))
+	v.GET("/entries/:entryID", noIndex(pipeline(ec.FindEntry)))
+	v.GET("/entries/:entryID/value", noIndex(pipeline(write(ec.EntryValue))))
+	v.GET("/entries/:entryID/qr", pipeline(requireUser(ec.ClaimQRCode)))
+	v.POST("/entries/:entryID/defer", pipeline(write(ec.DeferClaim)))
+	v.POST("/entries/:entryID/delegations", pipeline(write(requireUser(ec.DelegateClaim))))
+	v.GET("/entries/:entryID/links", pipeline(requireUser(ec.ClaimLinks)))
+	v.POST("/entries/:entryID/links", pipeline(write(requireUser(ec.ResendLink))))
+	v.POST("/entries/:entryID/delegated-claim", noIndex(pipeline(write(ec.ClaimDelegated))))
+	v.PUT("/entries/:entryID/pin", pipeline(write(requireUser(ec.SetFlag(true, true)))))
+	v.DELETE("/entries/:entryID/pin", pipeline(write(requireUser(ec.SetFlag(true, false)))))
+	v.PUT("/entries/:entryID/favorite", pipeline(write(requireUser(ec.SetFlag(false, true)))))
+	v.DELETE("/entries/:entryID/favorite", pipeline(write(requireUser(ec.SetFlag(false, false)))))
+	v.HEAD("/entries/:entryID/value", noIndex(pipeline(write(ec.EntryValue))))
+	v.GET("/users/:userID/entries", pipeline(authz.require(self, admin)(ec.FindUserEntries)))
+	v.GET("/users/:userID/usage", pipeline(authz.require(self, admin)(ec.Usage)))
+	v.POST("/receipts/verify", pipeline(ec.VerifyReceipt))
+	v.GET("/examples", pipeline(ListExamples))
+	v.GET("/examples/:operation", pipeline(Example))
+	v.HandleStable(http.MethodGet, "/openapi.json", pipeline(OpenAPI))
+	// mutations are refused while writes are frozen, but queries aren't, so
+	// the route isn't wrapped in write
+	v.POST("/graphql", pipeline(authz.require()(c.graphql.Query)))
+	v.GET(eventStreamPath, pipeline(requireUser(c.stream.Stream)))
+
+	adminOnly := authz.require(admin)
+	v.GET("/admin/entries", pipeline(adminOnly(ac.ListEntries)))
+	v.POST("/admin/entries/revoke", pipeline(write(adminOnly(ac.RevokeEntries))))
+	v.GET("/admin/history/entries", pipeline(adminOnly(ac.EntryHistory)))
+	v.GET("/admin/entries/:entryID", pipeline(adminOnly(ac.FindEntry)))
+	v.GET("/admin/anonymous-senders/:senderID", pipeline(adminOnly(ac.FindAnonymousSender)))
+	v.DELETE("/admin/entries/:entryID", pipeline(write(adminOnly(ac.ExpireEntry))))
+	v.POST("/admin/escrows/:entryID/release", pipeline(write(adminOnly(ac.ReleaseEscrow))))
+	v.PUT("/admin/opt-outs/:email", pipeline(write(adminOnly(ac.OptOutRecipient))))
+	v.DELETE("/admin/opt-outs/:email", pipeline(write(adminOnly(ac.OptInRecipient))))
+	v.GET("/admin/stats", pipeline(adminOnly(ac.Stats)))
+	v.GET("/admin/users", pipeline(adminOnly(ac.ListUsers)))
+	v.DELETE("/admin/users/:userID", pipeline(write(adminOnly(ac.DeleteUser))))
+	v.PUT("/admin/users/:userID/role", pipeline(write(adminOnly(ac.SetUserRole))))
+	v.POST("/admin/users/:userID/disable", pipeline(write(adminOnly(ac.DisableUser))))
+	v.POST("/admin/users/:userID/enable", pipeline(write(adminOnly(ac.EnableUser))))
+	v.POST("/admin/users/:userID/password-reset", pipeline(write(adminOnly(ac.ResetPassword))))
+}
+
+// mountUnversioned registers the routes that aren't part of an API version:
+// the claim and opt-out pages people open from their emails, and the SAML
+// endpoints registered with the IdP. The pages and the ACS don't take JSON,
+// so they skip the pipeline and the middleware Before and After insert into
+// it; Use's middleware still wraps them.
+func mountUnversioned(r *router, c controllers) {
+	cp := c.claimPages
+	r.GET("/claim/:token", noIndex(htmlPage(cp.Show)))
+	r.HEAD("/claim/:token", noIndex(htmlPage(cp.Show)))
+	r.POST("/claim/:entryID", noIndex(htmlPage(c.write(cp.Claim))))
+	r.POST("/claim/:entryID/defer", noIndex(htmlPage(c.write(cp.Defer))))
+	r.GET("/unsubscribe/:token", noIndex(htmlPage(c.optOuts.Show)))
+	r.POST("/unsubscribe/:token", noIndex(htmlPage(c.write(c.optOuts.OptOut))))
+
+	if c.saml != nil {
+		r.GET("/auth/saml/metadata", c.pipeline(c.saml.Metadata))
+		// the IdP posts a form to the ACS, so it can't go through acceptJSON
+		r.POST("/auth/saml/acs", cleanOutput(c.write(c.saml.ACS)))
+	}
+}
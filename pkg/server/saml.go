@@ -0,0 +1,225 @@
+package server
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/crewjam/saml"
+	"github.com/crewjam/saml/samlsp"
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/gavinwade12/sendkey/internal/app"
+	"github.com/gavinwade12/sendkey/pkg/api"
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	samlProviderName       = "saml"
+	samlRelayStateLifetime = 10 * time.Minute
+)
+
+type samlConfig struct {
+	Enabled bool
+
+	// RootURL is the externally reachable base URL of the API. The metadata
+	// and ACS URLs are derived from it.
+	RootURL         string
+	EntityID        string
+	CertFile        string
+	KeyFile         string
+	IDPMetadataURL  string
+	IDPMetadataFile string
+
+	// AllowIDPInitiated accepts assertions that weren't requested through
+	// /auth/saml/start.
+	AllowIDPInitiated bool
+
+	// Attributes maps sendkey user fields to SAML attribute names. The NameID
+	// is used as the email if no email attribute is configured.
+	Attributes struct {
+		Email     string
+		FirstName string
+		LastName  string
+	}
+}
+
+type SAMLController struct {
+	*UsersController
+
+	cfg      samlConfig
+	sp       *saml.ServiceProvider
+	stateKey []byte
+}
+
+func newSAMLController(uc *UsersController, stateKey []byte, cfg samlConfig) (*SAMLController, error) {
+	root, err := url.Parse(cfg.RootURL)
+	if err != nil {
+		return nil, fmt.Errorf("parsing root url: %w", err)
+	}
+
+	keyPair, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("loading sp key pair: %w", err)
+	}
+	key, ok := keyPair.PrivateKey.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("sp key must be an RSA key")
+	}
+	cert, err := x509.ParseCertificate(keyPair.Certificate[0])
+	if err != nil {
+		return nil, fmt.Errorf("parsing sp certificate: %w", err)
+	}
+
+	var idp *saml.EntityDescriptor
+	if cfg.IDPMetadataFile != "" {
+		b, err := os.ReadFile(cfg.IDPMetadataFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading idp metadata: %w", err)
+		}
+		idp, err = samlsp.ParseMetadata(b)
+		if err != nil {
+			return nil, fmt.Errorf("parsing idp metadata: %w", err)
+		}
+	} else {
+		u, err := url.Parse(cfg.IDPMetadataURL)
+		if err != nil {
+			return nil, fmt.Errorf("parsing idp metadata url: %w", err)
+		}
+		idp, err = samlsp.FetchMetadata(context.Background(), http.DefaultClient, *u)
+		if err != nil {
+			return nil, fmt.Errorf("fetching idp metadata: %w", err)
+		}
+	}
+
+	sp := &saml.ServiceProvider{
+		EntityID:          cfg.EntityID,
+		Key:               key,
+		Certificate:       cert,
+		MetadataURL:       *root.ResolveReference(&url.URL{Path: "/auth/saml/metadata"}),
+		AcsURL:            *root.ResolveReference(&url.URL{Path: "/auth/saml/acs"}),
+		IDPMetadata:       idp,
+		AllowIDPInitiated: cfg.AllowIDPInitiated,
+	}
+	if sp.EntityID == "" {
+		sp.EntityID = sp.MetadataURL.String()
+	}
+
+	return &SAMLController{uc, cfg, sp, stateKey}, nil
+}
+
+func (c *SAMLController) Metadata(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	b, err := xml.MarshalIndent(c.sp.Metadata(), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/samlmetadata+xml")
+	_, err = w.Write(b)
+	return err
+}
+
+func (c *SAMLController) Start(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	req, err := c.sp.MakeAuthenticationRequest(c.sp.GetSSOBindingLocation(saml.HTTPRedirectBinding),
+		saml.HTTPRedirectBinding, saml.HTTPPostBinding)
+	if err != nil {
+		return err
+	}
+
+	// the relay state carries the request ID so the ACS can validate
+	// InResponseTo without keeping any server-side state
+	relayState, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.StandardClaims{
+		Id:        req.ID,
+		Audience:  samlProviderName,
+		ExpiresAt: time.Now().Add(samlRelayStateLifetime).Unix(),
+	}).SignedString(c.stateKey)
+	if err != nil {
+		return err
+	}
+
+	u, err := req.Redirect(relayState, c.sp)
+	if err != nil {
+		return err
+	}
+
+	http.Redirect(w, r, u.String(), http.StatusFound)
+	return nil
+}
+
+func (c *SAMLController) ACS(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	if err := r.ParseForm(); err != nil {
+		return api.Error{StatusCode: http.StatusBadRequest, Code: "form_unreadable", Message: err.Error()}
+	}
+
+	var requestIDs []string
+	if rs := r.PostForm.Get("RelayState"); rs != "" {
+		var claims jwt.StandardClaims
+		_, err := jwt.ParseWithClaims(rs, &claims, func(t *jwt.Token) (interface{}, error) {
+			if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+			}
+			return c.stateKey, nil
+		})
+		if err != nil || !claims.VerifyAudience(samlProviderName, true) {
+			return api.Error{StatusCode: http.StatusBadRequest, Code: "invalid_relay_state", Message: "Invalid relay state."}
+		}
+		requestIDs = append(requestIDs, claims.Id)
+	} else if !c.cfg.AllowIDPInitiated {
+		return api.Error{StatusCode: http.StatusBadRequest, Code: "relay_state_required", Message: "A relay state is required."}
+	}
+
+	assertion, err := c.sp.ParseResponse(r, requestIDs)
+	if err != nil {
+		if ire, ok := err.(*saml.InvalidResponseError); ok {
+			err = ire.PrivateErr
+		}
+		return api.Error{StatusCode: http.StatusUnauthorized, Code: "external_login_failed", Message: err.Error()}
+	}
+	if assertion.Subject == nil || assertion.Subject.NameID == nil {
+		return api.Error{StatusCode: http.StatusUnauthorized, Code: "external_login_failed", Message: "The assertion has no subject."}
+	}
+
+	req := app.ExternalLoginRequest{
+		Provider:      samlProviderName,
+		Subject:       assertion.Subject.NameID.Value,
+		Email:         assertion.Subject.NameID.Value,
+		EmailVerified: true,
+	}
+	if c.cfg.Attributes.Email != "" {
+		req.Email = samlAttribute(assertion, c.cfg.Attributes.Email)
+	}
+	req.FirstName = samlAttribute(assertion, c.cfg.Attributes.FirstName)
+	req.LastName = samlAttribute(assertion, c.cfg.Attributes.LastName)
+
+	resp, err := c.service.ExternalLogin(req)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	return c.writeExternalLoginResponse(w, r, req, resp)
+}
+
+// samlAttribute returns the first value of the attribute with the given name
+// or friendly name.
+func samlAttribute(a *saml.Assertion, name string) string {
+	if name == "" {
+		return ""
+	}
+
+	for _, s := range a.AttributeStatements {
+		for _, attr := range s.Attributes {
+			if (attr.Name == name || attr.FriendlyName == name) && len(attr.Values) > 0 {
+				return attr.Values[0].Value
+			}
+		}
+	}
+
+	return ""
+}
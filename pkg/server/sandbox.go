@@ -0,0 +1,49 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gavinwade12/sendkey/internal/app"
+	"github.com/gavinwade12/sendkey/pkg/api"
+)
+
+// sandboxConfig lets integrators test against the API without sending real
+// entries. Requests with the X-Sandbox: true header create and claim
+// entries in an isolated namespace, and nothing is emailed for them.
+type sandboxConfig struct {
+	Enabled bool
+	// TimeScale divides sandbox entries' durations and delays, so expiry
+	// and reminders can be tested quickly. Zero uses 60, making an hour a
+	// minute.
+	TimeScale int
+}
+
+func (c sandboxConfig) service(s *app.EntryService) *app.EntryService {
+	if !c.Enabled {
+		return nil
+	}
+	scale := c.TimeScale
+	if scale <= 0 {
+		scale = app.DefaultSandboxTimeScale
+	}
+	return s.Sandbox(scale)
+}
+
+// entries returns the service for the request: the sandbox's if the request
+// asks for it with the X-Sandbox header. It's traced under the request's
+// span.
+func (c *EntriesController) entries(r *http.Request) (*app.EntryService, error) {
+	sandbox, _ := strconv.ParseBool(r.Header.Get("X-Sandbox"))
+	if !sandbox {
+		return requestEntries(r, c.service), nil
+	}
+	if c.sandbox == nil {
+		return nil, api.Error{
+			StatusCode: http.StatusBadRequest,
+			Code:       "sandbox_disabled",
+			Message:    "The sandbox isn't enabled on this server.",
+		}
+	}
+	return requestEntries(r, c.sandbox), nil
+}
@@ -0,0 +1,807 @@
+// Package server is the sendkey API: its routes, middleware, and
+// controllers, and the services behind them. cmd/api serves it on its own,
+// and other Go programs can mount it in their servers:
+//
+//	cfg, err := server.ReadConfig("sendkey.json")
+//	...
+//	stores, err := server.OpenStores(*cfg)
+//	...
+//	srv, err := server.New(*cfg, stores)
+//	...
+//	srv.Start()
+//	defer srv.Close()
+//	mux.Handle("/", srv)
+//
+// Custom middleware, like a check for a header a corporate gateway sets, can
+// wrap the whole API with Use, or go into each route's pipeline around one
+// of its stages with Before and After:
+//
+//	srv, err := server.New(*cfg, stores,
+//		server.Use(requireGatewayHeader),
+//		server.After(server.StageSetUserID, logUsers))
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gavinwade12/sendkey"
+	"github.com/gavinwade12/sendkey/internal/app"
+	"github.com/gavinwade12/sendkey/internal/captcha"
+	"github.com/gavinwade12/sendkey/internal/chaos"
+	"github.com/gavinwade12/sendkey/internal/events"
+	"github.com/gavinwade12/sendkey/internal/hibp"
+	"github.com/gavinwade12/sendkey/internal/mail"
+	"github.com/gavinwade12/sendkey/internal/metrics"
+	"github.com/gavinwade12/sendkey/internal/mysql"
+	"github.com/gavinwade12/sendkey/internal/ratelimit"
+	"github.com/gavinwade12/sendkey/internal/redact"
+	"github.com/gavinwade12/sendkey/internal/telemetry"
+	"github.com/gavinwade12/sendkey/internal/tracing"
+	"github.com/gavinwade12/sendkey/pkg/api"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+	"github.com/rs/cors"
+)
+
+// Stores are the repositories the server keeps its data in.
+type Stores struct {
+	Users         app.UserRepository
+	Identities    app.UserIdentityRepository
+	RecoveryCodes app.RecoveryCodeRepository
+	MagicLinks    app.MagicLinkRepository
+	Entries       app.EntryRepository
+	RefreshTokens RefreshTokenRepository
+	Audit         app.AuditRepository
+	Usage         app.UsageRepository
+	Anonymous     app.AnonymousSenderRepository
+	Idempotency   IdempotencyRepository
+	// DB is the MySQL database the repositories are in. It's also behind
+	// the health check, the replication status, the connection pool's
+	// metrics, and the default audit sink.
+	DB *mysql.DB
+}
+
+// MySQLStores returns the stores in the database.
+func MySQLStores(db *mysql.DB) Stores {
+	return Stores{
+		Users:         db.Users,
+		Identities:    db.Identities,
+		RecoveryCodes: db.RecoveryCodes,
+		MagicLinks:    db.MagicLinks,
+		Entries:       db.Entries,
+		RefreshTokens: db.RefreshTokens,
+		Audit:         db.Audit,
+		Usage:         db.Usage,
+		Anonymous:     db.Anonymous,
+		Idempotency:   db.Idempotency,
+		DB:            db,
+	}
+}
+
+// OpenStores opens the MySQL database the config names and returns the
+// stores in it. Unless writes are frozen, the database is created if it
+// doesn't exist and migrated with the config's migrations. Close the
+// database when the server's done with it.
+func OpenStores(cfg Config) (Stores, error) {
+	records, _, err := cfg.IDGenerators()
+	if err != nil {
+		return Stores{}, err
+	}
+	opts := []mysql.Option{mysql.GenerateIDs(records)}
+	if !cfg.Replication.ReadOnly {
+		opts = append(opts, mysql.AutoCreateDB())
+		if cfg.MySQL.MigrationsDir != "" {
+			opts = append(opts, mysql.WithMigrations(cfg.MySQL.MigrationsDir))
+		}
+	}
+	db, err := mysql.NewDB(cfg.MySQL.DSN, opts...)
+	if err != nil {
+		return Stores{}, err
+	}
+	return MySQLStores(db), nil
+}
+
+// Option configures a server.
+type Option func(*Server)
+
+// WithLogger logs with l rather than a logger made from the config's
+// Logging.
+func WithLogger(l *slog.Logger) Option {
+	return func(s *Server) {
+		s.logger = l
+	}
+}
+
+// ObservePhases calls f with how long each phase of setting the server up
+// took, like "keys" for loading the entry keys.
+func ObservePhases(f func(phase string, d time.Duration, err error)) Option {
+	return func(s *Server) {
+		s.observe = f
+	}
+}
+
+// Server is the sendkey API, with its middleware, ready to be served or
+// mounted in another program's server. Its background jobs run once it's
+// started.
+type Server struct {
+	cfg     Config
+	handler http.Handler
+	ops     *http.ServeMux
+	maxBody int64
+	logger  *slog.Logger
+	observe func(phase string, d time.Duration, err error)
+	reg     *metrics.Registry
+	tracer  *tracing.Tracer
+
+	users       *app.UserService
+	entries     *app.EntryService
+	keys        *app.KeyRing
+	mailer      app.Mailer
+	links       *app.ClaimLinks
+	idempotent  IdempotencyRepository
+	auditLog    *app.AuditLog
+	auditSource app.AuditSource
+	reporter    *telemetry.Reporter
+	storage     *storageMonitor
+
+	// middleware is the custom middleware from Use, Before, and After.
+	middleware middlewareHooks
+
+	// closers are closed in reverse order by Close.
+	closers []func()
+	done    chan struct{}
+}
+
+// New returns the server the config describes, keeping its data in the
+// stores.
+func New(cfg Config, stores Stores, opts ...Option) (*Server, error) {
+	s := &Server{cfg: cfg}
+	for _, o := range opts {
+		o(s)
+	}
+	if err := s.middleware.err(); err != nil {
+		return nil, err
+	}
+	if s.logger == nil {
+		logger, err := cfg.Logger(os.Stderr)
+		if err != nil {
+			return nil, err
+		}
+		s.logger = logger
+	}
+	if err := s.build(stores); err != nil {
+		s.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// build sets the server up, adding what needs closing to its closers as it
+// goes, so a failure part way through can be closed.
+func (s *Server) build(stores Stores) error {
+	cfg := &s.cfg
+	logger := s.logger
+	recordIDs, entryIDs, err := cfg.IDGenerators()
+	if err != nil {
+		return err
+	}
+	if err = cfg.Ops.check(cfg.Replication); err != nil {
+		return err
+	}
+
+	// TODO: create a transaction for each request? allow services to request a transaction?
+
+	accessTokenLifetime := time.Minute * time.Duration(cfg.Auth.AccessTokenDurationMins)
+	refreshTokenLifetime := time.Hour * time.Duration(cfg.Auth.RefreshTokenDurationHours)
+	atm := newAuthTokenManager([]byte(cfg.Auth.SigningKey), accessTokenLifetime, refreshTokenLifetime)
+
+	if s.tracer, err = cfg.Tracing.tracer(logger); err != nil {
+		return err
+	}
+	s.closers = append(s.closers, s.tracer.Shutdown)
+
+	r := &router{Router: httprouter.New(), tracer: s.tracer}
+	if cfg.ValidateRequests {
+		r.validation = &requestValidation{doc: api.NewOpenAPIDocument("")}
+	}
+	bc := baseController{}
+
+	pp := cfg.Auth.PasswordPolicy
+	policy := app.PasswordPolicy{
+		MinLength:     pp.MinLength,
+		RequireUpper:  pp.RequireUpper,
+		RequireLower:  pp.RequireLower,
+		RequireDigit:  pp.RequireDigit,
+		RequireSymbol: pp.RequireSymbol,
+	}
+	if pp.CheckPwned {
+		policy.Breaches = hibp.NewClient()
+	}
+
+	hashers := app.DefaultPasswordHashers()
+	argon := app.DefaultArgon2idHasher()
+	ph := cfg.Auth.PasswordHashing
+	if ph.Argon2idTime > 0 {
+		argon.Time = ph.Argon2idTime
+	}
+	if ph.Argon2idMemoryKiB > 0 {
+		argon.MemoryKiB = ph.Argon2idMemoryKiB
+	}
+	if ph.Argon2idThreads > 0 {
+		argon.Threads = ph.Argon2idThreads
+	}
+	hashers.Default = argon
+
+	var (
+		users         = stores.Users
+		identities    = stores.Identities
+		recoveryCodes = stores.RecoveryCodes
+		magicLinks    = stores.MagicLinks
+		entries       = stores.Entries
+		refreshTokens = stores.RefreshTokens
+		audit         = stores.Audit
+		usage         = stores.Usage
+		anonymous     = stores.Anonymous
+		idempotent    = stores.Idempotency
+	)
+	if f := cfg.Chaos.Stores.faults(); cfg.Chaos.Enabled && f.Enabled() {
+		logger.Warn("chaos: injecting store faults", "latency", f.Latency, "latencyRate", f.LatencyRate, "errorRate", f.ErrorRate)
+		users = chaos.NewUserStore(users, f)
+		identities = chaos.NewUserIdentityStore(identities, f)
+		recoveryCodes = chaos.NewRecoveryCodeStore(recoveryCodes, f)
+		magicLinks = chaos.NewMagicLinkStore(magicLinks, f)
+		entries = chaos.NewEntryStore(entries, f)
+		refreshTokens = chaos.NewRefreshTokenStore(refreshTokens, f)
+		audit = chaos.NewAuditStore(audit, f)
+		usage = chaos.NewUsageStore(usage, f)
+		anonymous = chaos.NewAnonymousSenderStore(anonymous, f)
+		idempotent = chaos.NewIdempotencyStore(idempotent, f)
+	}
+	var reg *metrics.Registry
+	if cfg.Metrics.Enabled {
+		reg = metrics.NewRegistry()
+		reg.Publish("stores")
+		users = metrics.NewUserStore(users, reg)
+		identities = metrics.NewUserIdentityStore(identities, reg)
+		recoveryCodes = metrics.NewRecoveryCodeStore(recoveryCodes, reg)
+		magicLinks = metrics.NewMagicLinkStore(magicLinks, reg)
+		entries = metrics.NewEntryStore(entries, reg)
+		refreshTokens = metrics.NewRefreshTokenStore(refreshTokens, reg)
+		audit = metrics.NewAuditStore(audit, reg)
+		usage = metrics.NewUsageStore(usage, reg)
+		anonymous = metrics.NewAnonymousSenderStore(anonymous, reg)
+		idempotent = metrics.NewIdempotencyStore(idempotent, reg)
+		watchDB(stores.DB, reg)
+		r.reg = reg
+	}
+	s.reg, s.idempotent = reg, idempotent
+
+	userSvc := app.NewUserService(users, recoveryCodes, identities, policy, hashers)
+	userSvc.GenerateIDs(recordIDs)
+	userSvc.LogTo(logger)
+	for _, org := range cfg.Auth.SSOOrgs {
+		userSvc.RequireSSO(app.SSOOrg{Name: org.Name, Domains: org.Domains, Exempt: org.ExemptEmails})
+	}
+	var mailer app.Mailer
+	switch {
+	case cfg.SMTP.Host != "":
+		mailer = mail.NewSMTPMailer(cfg.SMTP.Host, cfg.SMTP.Port, cfg.SMTP.Username, cfg.SMTP.Password, cfg.SMTP.From)
+	case cfg.SMTP.LogOnly:
+		mailer = mail.LogMailer{Logger: logger}
+	default:
+		logger.Warn("smtp: no mail server is configured, so magic links, OTP challenges, reminders, and revocation notices are disabled")
+	}
+	if mailer != nil {
+		if reg != nil {
+			mailer = metrics.NewMailer(mailer, reg)
+		}
+		mq := cfg.SMTP.Queue
+		if mq.Depth <= 0 {
+			mq.Depth = 1000
+		}
+		if mq.Workers <= 0 {
+			mq.Workers = 2
+		}
+		if mq.Overflow == "" {
+			mq.Overflow = mail.OverflowDrop
+		}
+		queue, err := mail.NewQueue(mailer, mq.Depth, mq.Workers, mq.Overflow)
+		if err != nil {
+			return err
+		}
+		s.closers = append(s.closers, queue.Close)
+		if mq.BlockTimeoutSecs > 0 {
+			queue.BlockTimeout = time.Second * time.Duration(mq.BlockTimeoutSecs)
+		}
+		queue.AlertDepth = mq.AlertDepth
+		if reg != nil {
+			metrics.WatchMailQueue(queue, reg)
+		}
+		mailer = queue
+	}
+
+	magicLinkLifetime := time.Minute * time.Duration(cfg.Auth.MagicLinkDurationMins)
+	magicLinkSvc := app.NewMagicLinkService(userSvc, magicLinks, mailer, []byte(cfg.Auth.SigningKey), cfg.PublicURL, magicLinkLifetime)
+	memoryStore := ratelimit.NewMemoryStore()
+	var (
+		failures ratelimit.FailureStore = memoryStore
+		buckets  ratelimit.BucketStore  = memoryStore
+	)
+	if cfg.Redis.Addr != "" {
+		rc := redis.NewClient(&redis.Options{Addr: cfg.Redis.Addr, Password: cfg.Redis.Password, DB: cfg.Redis.DB})
+		s.closers = append(s.closers, func() { rc.Close() })
+		redisStore := ratelimit.NewRedisStore(rc, "sendkey:")
+		failures, buckets = redisStore, redisStore
+	}
+	if cfg.RateLimits.Enabled {
+		r.limits = newRateLimits(cfg.RateLimits, buckets, atm)
+	}
+	loginThrottle := ratelimit.NewThrottler(failures)
+	if t := cfg.Auth.LoginThrottle; t.LockoutThreshold > 0 {
+		loginThrottle.BaseDelay = time.Second * time.Duration(t.BaseDelaySecs)
+		loginThrottle.MaxDelay = time.Second * time.Duration(t.MaxDelaySecs)
+		loginThrottle.LockoutThreshold = t.LockoutThreshold
+		loginThrottle.LockoutDuration = time.Minute * time.Duration(t.LockoutMins)
+	}
+
+	uc := &UsersController{bc, userSvc, atm, refreshTokens, magicLinkSvc, loginThrottle, recordIDs, purposeKey([]byte(cfg.Auth.SigningKey), "external-login")}
+	previews := ratelimit.NewLimiter(failures, 30, time.Minute)
+	if l := cfg.Auth.LinkPreviews; l.Limit > 0 && l.WindowSecs > 0 {
+		previews.Limit = l.Limit
+		previews.Window = time.Second * time.Duration(l.WindowSecs)
+	}
+
+	oc := newOIDCController(uc, purposeKey([]byte(cfg.Auth.SigningKey), "oidc-state"), cfg.Auth.OIDC)
+
+	kdf := app.DefaultEntryKeyDerivation()
+	if cfg.EntryKDF.Time > 0 {
+		kdf.Time = cfg.EntryKDF.Time
+	}
+	if cfg.EntryKDF.MemoryKiB > 0 {
+		kdf.MemoryKiB = cfg.EntryKDF.MemoryKiB
+	}
+	if cfg.EntryKDF.Threads > 0 {
+		kdf.Threads = cfg.EntryKDF.Threads
+	}
+	endKeys := s.phase("keys")
+	keys, err := keyRing(*cfg)
+	if err != nil {
+		endKeys(err)
+		return err
+	}
+	if cfg.Cipher == "" {
+		cfg.Cipher = app.CipherAESGCM
+	}
+	if !app.SupportedCipher(cfg.Cipher) {
+		err = fmt.Errorf("unsupported cipher %q", cfg.Cipher)
+		endKeys(err)
+		return err
+	}
+	if err = keys.CheckCipher(cfg.Cipher); err != nil {
+		endKeys(err)
+		return err
+	}
+	endKeys(nil)
+	s.keys = keys
+	if cfg.MaxEntryValueBytes <= 0 {
+		cfg.MaxEntryValueBytes = app.DefaultMaxValueBytes
+	}
+	entrySvc := app.NewEntryService(entries, keys, cfg.MaxInvalidAttempts, kdf, cfg.Cipher, cfg.MaxEntryValueBytes)
+	entrySvc.GenerateIDs(entryIDs, recordIDs)
+	entrySvc.LogTo(logger)
+	if cfg.ClaimLinkTTLHours > 0 {
+		entrySvc.ExpireClaimLinks(time.Hour * time.Duration(cfg.ClaimLinkTTLHours))
+	}
+	if d := cfg.DuplicateEntries; d.WindowSecs > 0 {
+		entrySvc.DetectDuplicates(app.DuplicateDetection{
+			Log:      failureSendLog{failures},
+			Window:   time.Second * time.Duration(d.WindowSecs),
+			Suppress: d.Suppress,
+		})
+	}
+	if mailer != nil {
+		entrySvc.SendOTPs(mailer)
+	}
+	optOutLinks := app.NewOptOutLinks([]byte(cfg.Auth.SigningKey), cfg.PublicURL)
+	entrySvc.HonorOptOuts(optOutLinks)
+	if e := cfg.Escrow; e.PublicKeyFile != "" {
+		if err = escrowValues(entrySvc, e.PublicKeyFile, e.RetentionDays); err != nil {
+			return err
+		}
+	}
+	if err = retainHistory(entrySvc, cfg.Retention.HistoryDays, cfg.Retention.Archive); err != nil {
+		return err
+	}
+	entrySvc.EnforceQuota(cfg.Quotas.quota(), usage)
+	var (
+		guests        *ratelimit.Limiter
+		guestCaptchas *captcha.Verifier
+	)
+	if cfg.GuestEntries.Enabled {
+		entrySvc.AllowGuests(cfg.GuestEntries.maxDuration(), anonymous, []byte(cfg.Auth.SigningKey))
+		guests = cfg.GuestEntries.limiter(failures)
+		if guestCaptchas, err = cfg.GuestEntries.captcha(); err != nil {
+			return err
+		}
+	}
+	eventQueue, err := cfg.Events.queue()
+	if err != nil {
+		return err
+	}
+	// entry events always go through the bus, for the event streams, and
+	// are exported after it if an exporter's configured
+	var exported app.EventPublisher
+	if eventQueue != nil {
+		s.closers = append(s.closers, eventQueue.Close)
+		exported = eventQueue
+		userSvc.PublishEvents(eventQueue)
+		if cfg.Events.ClaimedValueHashKey != "" {
+			entrySvc.HashClaimedValues([]byte(cfg.Events.ClaimedValueHashKey))
+		}
+	}
+	bus := events.NewBus(exported)
+	entrySvc.PublishEvents(bus)
+	if s.reporter, err = cfg.Telemetry.reporter(); err != nil {
+		return err
+	}
+	var counters usageCounters
+	if s.reporter != nil {
+		counters = append(counters, s.reporter)
+	}
+	if reg != nil {
+		counters = append(counters, reg)
+	}
+	if len(counters) > 0 {
+		entrySvc.CountUsage(counters)
+	}
+	if cfg.Replication.ReadOnly {
+		logger.Info("replication: read-only, writes are frozen")
+		entrySvc.FreezeWrites()
+	}
+	auditSink, auditSource, err := cfg.Audit.sink(stores.DB)
+	if err != nil {
+		return err
+	}
+	if f := cfg.Chaos.Stores.faults(); cfg.Chaos.Enabled && f.Enabled() {
+		auditSink = chaos.NewAuditSink(auditSink, f)
+	}
+	if reg != nil {
+		auditSink = metrics.NewAuditSink(auditSink, reg)
+	}
+	auditLog := app.NewAuditLog(audit, auditSink, []byte(cfg.Auth.SigningKey))
+	if !cfg.Replication.ReadOnly {
+		entrySvc.RecordAudit(auditLog)
+	}
+	s.users, s.entries, s.mailer = userSvc, entrySvc, mailer
+	s.auditLog, s.auditSource = auditLog, auditSource
+	s.storage = newStorageMonitor(cfg.Storage, stores.DB, logger)
+	if reg != nil && cfg.Storage.enabled() {
+		s.storage.gauges(reg)
+	}
+
+	s.links = app.NewClaimLinks([]byte(cfg.Auth.SigningKey), cfg.PublicURL)
+	ec := &EntriesController{bc, entrySvc, entryBodyLimit(cfg.MaxEntryValueBytes), previews, s.links, userSvc, guests, cfg.Sandbox.service(entrySvc), guestCaptchas}
+	cp := &ClaimPageController{entrySvc, []byte(cfg.Auth.SigningKey), previews, s.links}
+
+	setUserID := setUserID(atm, userSvc)
+	decoding := jsonDecoding(cfg.StrictJSON)
+	pipeline := s.middleware.pipeline(setUserID, decoding)
+
+	ctrl := controllers{
+		users:      uc,
+		oidc:       oc,
+		entries:    ec,
+		claimPages: cp,
+		optOuts:    &OptOutPageController{entrySvc, optOutLinks},
+		magicLinks: &MagicLinkPageController{magicLinkSvc},
+		admin:      &AdminController{bc, entrySvc, userSvc, refreshTokens, mailer, s.storage},
+		graphql:    newGraphQLController(bc, ec, userSvc, cfg.Replication.ReadOnly),
+		stream:     &EventStreamController{bc, bus, accessTokenLifetime},
+		authz:      authorizer{userSvc, cfg.Admin.Emails},
+		pipeline:   pipeline,
+		write:      writeGuard(cfg.Replication.ReadOnly),
+		idempotent: (&idempotency{bc, idempotent, cfg.Idempotency.window()}).wrap,
+	}
+	if cfg.Auth.SAML.Enabled {
+		if ctrl.saml, err = newSAMLController(uc, purposeKey([]byte(cfg.Auth.SigningKey), "saml-relay-state"), cfg.Auth.SAML); err != nil {
+			return err
+		}
+	}
+	mountV1(&apiVersion{rt: r, prefix: api.BasePath, legacy: &legacyRoutes}, ctrl)
+	mountUnversioned(r, ctrl)
+	mountWellKnown(r.Router, cfg.RobotsTxt, cfg.SecurityTxt)
+
+	corsOpts, err := cfg.Cors.options()
+	if err != nil {
+		return err
+	}
+	c := cors.New(corsOpts)
+	proxies, err := parseTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		return err
+	}
+
+	var h http.Handler = r
+	if f := cfg.Chaos.HTTP.faults(); cfg.Chaos.Enabled && f.Enabled() {
+		logger.Warn("chaos: injecting http faults", "latency", f.Latency, "latencyRate", f.LatencyRate, "errorRate", f.ErrorRate)
+		h = chaos.Middleware(h, f)
+	}
+	s.ops = opsMux(cfg.Ops, stores.DB, reg, cfg.Replication)
+	s.maxBody = cfg.Requests.maxBody(entryBodyLimit(cfg.MaxEntryValueBytes))
+	h = cfg.Requests.limitRequests(s.maxBody, h)
+	h = resolveClientIP(proxies, logRequests(logger, c.Handler(s.middleware.around.Then(h))))
+	if cfg.Ops.Addr == "" {
+		h = withOps(publicOpsMux(stores.DB, reg), h)
+	}
+	s.handler = h
+	return nil
+}
+
+// phase starts timing a phase of setting the server up, returning the func
+// that ends it with the phase's error.
+func (s *Server) phase(name string) func(error) {
+	start := time.Now()
+	return func(err error) {
+		if s.observe != nil {
+			s.observe(name, time.Since(start), err)
+		}
+	}
+}
+
+// ServeHTTP serves the API, and the operational endpoints that can be public
+// unless the config has a separate Ops.Addr for them.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.handler.ServeHTTP(w, r)
+}
+
+// Ops returns the handler for the operational endpoints, for serving them
+// separately from the API; see Config.Ops.
+func (s *Server) Ops() http.Handler {
+	return s.ops
+}
+
+// Metrics returns the server's metrics, or nil if they aren't enabled.
+func (s *Server) Metrics() *metrics.Registry {
+	return s.reg
+}
+
+// MaxBodyBytes returns the largest request body the server accepts.
+func (s *Server) MaxBodyBytes() int64 {
+	return s.maxBody
+}
+
+// HTTPServer returns an http.Server serving the server at addr with the
+// config's timeouts.
+func (s *Server) HTTPServer(addr string) *http.Server {
+	return s.cfg.Requests.server(addr, s)
+}
+
+// Start starts the background jobs: sending reminders, purging old
+// idempotency keys, anchoring the audit log, sweeping data past its
+// retention, checking storage, and reporting telemetry. They don't run while writes are
+// frozen. Close stops them.
+func (s *Server) Start() {
+	if s.cfg.Replication.ReadOnly || s.done != nil {
+		return
+	}
+	s.done = make(chan struct{})
+	if s.mailer != nil {
+		go sendReminders(s.entries, s.mailer, s.links, time.Minute, s.done)
+	}
+	go purgeIdempotencyKeys(s.idempotent, time.Hour, s.done)
+
+	anchorInterval := time.Hour
+	if s.cfg.Audit.AnchorIntervalMins > 0 {
+		anchorInterval = time.Minute * time.Duration(s.cfg.Audit.AnchorIntervalMins)
+	}
+	go anchorAudit(s.auditLog, anchorInterval, s.done)
+	if s.reporter != nil {
+		go reportTelemetry(s.reporter, s.cfg.Telemetry.interval(), s.done)
+	}
+	if s.cfg.Retention.SweepIntervalMins > 0 {
+		userGracePeriod := 24 * time.Hour * time.Duration(s.cfg.Retention.UserGracePeriodDays)
+		sweepInterval := time.Minute * time.Duration(s.cfg.Retention.SweepIntervalMins)
+		go sweepRetention(s.users, s.entries, userGracePeriod, sweepInterval, s.done)
+	}
+	if s.cfg.Storage.enabled() {
+		go watchStorage(s.storage, s.cfg.Storage.interval(), s.done)
+	}
+}
+
+// Close stops the background jobs and flushes the queued mail and events.
+// The stores are left open.
+func (s *Server) Close() {
+	if s.done != nil {
+		close(s.done)
+		s.done = nil
+	}
+	for i := len(s.closers) - 1; i >= 0; i-- {
+		s.closers[i]()
+	}
+	s.closers = nil
+}
+
+// RotateKeys re-encrypts the entries with the current key version, returning
+// how many were.
+func (s *Server) RotateKeys() (*app.ReencryptEntriesResponse, error) {
+	return s.entries.ReencryptEntries(100)
+}
+
+// ExportEntries writes the unexpired entries, still encrypted, to a new file
+// at path, returning how many were written.
+func (s *Server) ExportEntries(path string) (int, error) {
+	return exportEntries(s.entries, path)
+}
+
+// ImportEntries imports the entries in a file written by ExportEntries. The
+// counts are returned with any error, for what was imported before it.
+func (s *Server) ImportEntries(path string) (*app.ImportEntriesResponse, error) {
+	return importEntries(s.entries, path)
+}
+
+// VerifyAudit checks the audit log for rewritten history, returning how much
+// of it was verified.
+func (s *Server) VerifyAudit() (*app.AuditVerification, error) {
+	return verifyAuditLog(s.auditLog, s.auditSource)
+}
+
+func acceptJSON(h httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		ct := r.Header.Get("Content-Type")
+		if ct != "" && ct != "application/json" {
+			w.Header().Set("Content-Type", "application/json")
+			respond(w, http.StatusBadRequest, api.Error{
+				StatusCode: http.StatusBadRequest,
+				Code:       "invalid_content_type",
+				Message:    "The request body must be JSON, with a Content-Type of application/json.",
+				RequestID:  requestID(r),
+			})
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+
+		h(w, r, p)
+	}
+}
+
+type action func(http.ResponseWriter, *http.Request, httprouter.Params) error
+
+func cleanOutput(a action) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				err, ok := rec.(error)
+				if !ok {
+					err = fmt.Errorf("%v", rec)
+				}
+
+				requestLogger(r).Error("panic recovery", "error", redact.String(err.Error()))
+				e := internalError(r)
+				respond(w, e.StatusCode, e)
+			}
+		}()
+
+		err := a(w, r, p)
+		if err == nil {
+			return
+		}
+
+		if e, ok := dbUnavailable(w, r, err); ok {
+			respond(w, e.StatusCode, e)
+			return
+		}
+		e, ok := err.(api.Error)
+		if !ok {
+			// unexpected errors can wrap anything, like a driver error
+			// quoting a query's arguments, so they're only logged, and
+			// the client gets the request ID to quote instead
+			requestLogger(r).Error("request failed", "status", http.StatusInternalServerError, "error", redact.String(err.Error()))
+			e = internalError(r)
+			respond(w, e.StatusCode, e)
+			return
+		}
+		e.RequestID = requestID(r)
+
+		respond(w, e.StatusCode, e)
+		logError(r, e)
+	}
+}
+
+// internalError is the response to an unexpected error. Its details are
+// logged rather than sent.
+func internalError(r *http.Request) api.Error {
+	return api.Error{
+		StatusCode: http.StatusInternalServerError,
+		Code:       "internal_error",
+		Message:    "Something went wrong. Quote the request ID if you contact support.",
+		RequestID:  requestID(r),
+	}
+}
+
+// logError logs an action's error. Server errors are logged as errors; the
+// rest, like a bad request, only as info.
+func logError(r *http.Request, e api.Error) {
+	level := slog.LevelInfo
+	if e.StatusCode >= http.StatusInternalServerError {
+		level = slog.LevelError
+	}
+	requestLogger(r).Log(r.Context(), level, "request failed", "status", e.StatusCode, "code", e.Code, "error", e.Message)
+}
+
+type userIDCtxKey string
+
+const userIDCtxKeyValue = userIDCtxKey("userID")
+
+// userCtxKeyValue holds the user setUserID found for the access token.
+const userCtxKeyValue = userIDCtxKey("user")
+
+// setUserID verifies the request's access token and sets its user. Tokens
+// issued before an admin reset the user's password are refused, as are ones
+// for users that no longer exist.
+func setUserID(atv AccessTokenVerifier, users *app.UserService) func(a action) action {
+	return func(a action) action {
+		return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+			// anonymous requests have no user, which routes that need
+			// one refuse with requireUser
+			userID := uuid.Nil
+			var user *sendkey.User
+			if token := r.Header.Get("Authorization"); token != "" {
+				var issuedAt time.Time
+				var err error
+				userID, issuedAt, err = atv.Verify(strings.TrimPrefix(token, "Bearer "))
+				if err != nil {
+					return invalidToken(w, err)
+				}
+				if user, err = users.FindUser(userID); err != nil {
+					return err
+				}
+				if user == nil {
+					return invalidToken(w, errors.New("The token's user doesn't exist."))
+				}
+				// tokens only have the second they were issued, so one from
+				// the same second as the reset is refused too
+				if reset := user.PasswordResetAtUTC; reset != nil && !issuedAt.After(*reset) {
+					return invalidToken(w, errors.New("The token was revoked. Log in again."))
+				}
+				setRequestUser(r, userID)
+			}
+
+			ctx := r.Context()
+			ctx = context.WithValue(ctx, userIDCtxKeyValue, userID)
+			ctx = context.WithValue(ctx, userCtxKeyValue, user)
+			r = r.WithContext(ctx)
+
+			return a(w, r, p)
+		}
+	}
+}
+
+type baseController struct {
+}
+
+func (c baseController) GetCurrentUserID(r *http.Request) (uuid.UUID, error) {
+	userID := r.Context().Value(userIDCtxKeyValue)
+	if userID == nil {
+		return uuid.Nil, fmt.Errorf("unable to get current user id")
+	}
+
+	return userID.(uuid.UUID), nil
+}
+
+func (c baseController) GetCurrentUser(r *http.Request, us *app.UserService) (*sendkey.User, error) {
+	id, err := c.GetCurrentUserID(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return us.FindUser(id)
+}
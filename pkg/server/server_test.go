@@ -0,0 +1,102 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gavinwade12/sendkey/pkg/api"
+	"github.com/julienschmidt/httprouter"
+)
+
+// serveAction serves a request with a through cleanOutput and the request
+// log, returning the response and what was logged.
+func serveAction(t *testing.T, a action) (*httptest.ResponseRecorder, string) {
+	t.Helper()
+	var buf bytes.Buffer
+	l, err := loggingConfig{}.logger(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := logRequests(l, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cleanOutput(a)(w, r, nil)
+	}))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/entries", nil))
+	return w, buf.String()
+}
+
+func TestErrorResponsesRedacted(t *testing.T) {
+	actions := []struct {
+		name string
+		a    action
+	}{
+		{"error", func(http.ResponseWriter, *http.Request, httprouter.Params) error {
+			return fmt.Errorf("creating the entry: secret=%s: duplicate key", secret)
+		}},
+		{"error quoting a struct", func(http.ResponseWriter, *http.Request, httprouter.Params) error {
+			return fmt.Errorf("storing %+v", struct{ ID, Value string }{"1", secret})
+		}},
+		{"panic", func(http.ResponseWriter, *http.Request, httprouter.Params) error {
+			panic("decrypting with nonce=" + secret)
+		}},
+	}
+	for _, tt := range actions {
+		t.Run(tt.name, func(t *testing.T) {
+			w, logged := serveAction(t, tt.a)
+			if w.Code != http.StatusInternalServerError {
+				t.Errorf("status = %d, want %d", w.Code, http.StatusInternalServerError)
+			}
+			if bytes.Contains(w.Body.Bytes(), []byte(secret)) {
+				t.Errorf("the response has the secret: %s", w.Body)
+			}
+			assertRedacted(t, "the log", logged)
+		})
+	}
+}
+
+func TestInternalErrorsHidden(t *testing.T) {
+	const detail = "dial tcp 10.0.3.7:3306: connection refused"
+	actions := []struct {
+		name string
+		a    action
+	}{
+		{"error", func(http.ResponseWriter, *http.Request, httprouter.Params) error {
+			return fmt.Errorf("finding the entry: %s", detail)
+		}},
+		{"panic", func(http.ResponseWriter, *http.Request, httprouter.Params) error {
+			panic(detail)
+		}},
+	}
+	for _, tt := range actions {
+		t.Run(tt.name, func(t *testing.T) {
+			w, logged := serveAction(t, tt.a)
+			if w.Code != http.StatusInternalServerError {
+				t.Errorf("status = %d, want %d", w.Code, http.StatusInternalServerError)
+			}
+
+			var e api.Error
+			if err := json.Unmarshal(w.Body.Bytes(), &e); err != nil {
+				t.Fatalf("decoding the response %s: %v", w.Body, err)
+			}
+			if e.Code != "internal_error" {
+				t.Errorf("code = %q, want internal_error", e.Code)
+			}
+			if strings.Contains(w.Body.String(), "10.0.3.7") {
+				t.Errorf("the response has the error's details: %s", w.Body)
+			}
+			// the client quotes the request ID to find the logged details
+			if id := w.Header().Get("X-Request-ID"); id == "" || e.RequestID != id {
+				t.Errorf("the response's request ID is %q, want the header's %q", e.RequestID, id)
+			}
+			if !strings.Contains(logged, detail) || !strings.Contains(logged, e.RequestID) {
+				t.Errorf("the log doesn't have the error with the request ID: %s", logged)
+			}
+		})
+	}
+}
@@ -0,0 +1,229 @@
+package server
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gavinwade12/sendkey/internal/alerts"
+	"github.com/gavinwade12/sendkey/internal/metrics"
+	"github.com/gavinwade12/sendkey/internal/mysql"
+	"github.com/gavinwade12/sendkey/pkg/api"
+	"github.com/julienschmidt/httprouter"
+)
+
+// storageTopSenders is how many of the senders using the most storage are
+// reported and checked against WarnSenderBytes.
+const storageTopSenders = 20
+
+// storageConfig warns operators as the database grows, before it runs out of
+// disk. The thresholds are soft: nothing's refused past them. An alert is
+// logged, and posted to AlertWebhookURL if there is one, when a threshold is
+// passed, and again when it's back under. Zero thresholds aren't checked.
+// The sizes are reported by the admin stats either way.
+type storageConfig struct {
+	// CheckIntervalMins is how often the sizes are checked. Zero uses 15.
+	CheckIntervalMins int
+	// WarnTotalBytes is the size of the database's tables together.
+	WarnTotalBytes int64
+	// WarnTableBytes is the size of any one table.
+	WarnTableBytes int64
+	// WarnSenderBytes is the size of the values of one sender's live
+	// entries, a soft quota for spotting a sender filling the database.
+	WarnSenderBytes int64
+	// AlertWebhookURL is posted each alert as JSON, e.g. a chat or paging
+	// service's incoming webhook.
+	AlertWebhookURL string
+}
+
+func (c storageConfig) enabled() bool {
+	return c.WarnTotalBytes > 0 || c.WarnTableBytes > 0 || c.WarnSenderBytes > 0
+}
+
+func (c storageConfig) interval() time.Duration {
+	if c.CheckIntervalMins <= 0 {
+		return 15 * time.Minute
+	}
+	return time.Minute * time.Duration(c.CheckIntervalMins)
+}
+
+// storageWarning is a storage threshold that's been passed.
+type storageWarning struct {
+	// key identifies the threshold and what passed it, so the alert can be
+	// resolved once it's back under.
+	key    string
+	name   string
+	msg    string
+	fields map[string]string
+}
+
+// warnings returns the thresholds the stats are past.
+func (c storageConfig) warnings(s *mysql.StorageStats) []storageWarning {
+	var warnings []storageWarning
+	if total := s.TotalBytes(); c.WarnTotalBytes > 0 && total >= c.WarnTotalBytes {
+		warnings = append(warnings, storageWarning{
+			key:    "total",
+			name:   "storage.total",
+			msg:    fmt.Sprintf("the database takes %d bytes, past the warning threshold of %d", total, c.WarnTotalBytes),
+			fields: map[string]string{"bytes": strconv.FormatInt(total, 10)},
+		})
+	}
+	for _, t := range s.Tables {
+		if size := t.DataBytes + t.IndexBytes; c.WarnTableBytes > 0 && size >= c.WarnTableBytes {
+			warnings = append(warnings, storageWarning{
+				key:    "table:" + t.Name,
+				name:   "storage.table",
+				msg:    fmt.Sprintf("the %s table takes %d bytes, past the warning threshold of %d", t.Name, size, c.WarnTableBytes),
+				fields: map[string]string{"table": t.Name, "bytes": strconv.FormatInt(size, 10)},
+			})
+		}
+	}
+	for _, ss := range s.Senders {
+		if c.WarnSenderBytes > 0 && ss.ValueBytes >= c.WarnSenderBytes {
+			warnings = append(warnings, storageWarning{
+				key:    "sender:" + ss.UserID.String(),
+				name:   "storage.sender",
+				msg:    fmt.Sprintf("sender %s's live entries take %d bytes, past the warning threshold of %d", ss.UserID, ss.ValueBytes, c.WarnSenderBytes),
+				fields: map[string]string{"userId": ss.UserID.String(), "bytes": strconv.FormatInt(ss.ValueBytes, 10)},
+			})
+		}
+	}
+	return warnings
+}
+
+// storageMonitor checks the database's storage against the thresholds,
+// alerting when they're passed and when they're back under.
+type storageMonitor struct {
+	cfg    storageConfig
+	db     *mysql.DB
+	notify alerts.Notifier
+	logger *slog.Logger
+
+	mu sync.Mutex
+	// active are the warnings that have been alerted and not resolved.
+	active map[string]storageWarning
+	// last is the last check's stats, for the gauges.
+	last *mysql.StorageStats
+}
+
+func newStorageMonitor(cfg storageConfig, db *mysql.DB, logger *slog.Logger) *storageMonitor {
+	notify := alerts.Notifiers{alerts.Log{Logger: logger}}
+	if cfg.AlertWebhookURL != "" {
+		notify = append(notify, alerts.NewWebhook(cfg.AlertWebhookURL))
+	}
+	return &storageMonitor{cfg: cfg, db: db, notify: notify, logger: logger, active: map[string]storageWarning{}}
+}
+
+// gauges reports the sizes from the last check as gauges. They're cached
+// since reading them on every scrape would be too slow.
+func (m *storageMonitor) gauges(reg *metrics.Registry) {
+	last := func(f func(*mysql.StorageStats) int64) func() int64 {
+		return func() int64 {
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			if m.last == nil {
+				return 0
+			}
+			return f(m.last)
+		}
+	}
+	reg.Gauge("storage.total_bytes", last((*mysql.StorageStats).TotalBytes))
+	reg.Gauge("storage.value_bytes", last(func(s *mysql.StorageStats) int64 { return s.ValueBytes }))
+}
+
+// check checks the storage, alerting about thresholds that have been passed
+// or are back under since the last check.
+func (m *storageMonitor) check() error {
+	stats, err := m.db.StorageStats(storageTopSenders)
+	if err != nil {
+		return err
+	}
+
+	var pending []alerts.Alert
+	now := time.Now().UTC()
+	current := map[string]bool{}
+	m.mu.Lock()
+	m.last = stats
+	for _, w := range m.cfg.warnings(stats) {
+		current[w.key] = true
+		if _, ok := m.active[w.key]; ok {
+			continue
+		}
+		m.active[w.key] = w
+		pending = append(pending, alerts.Alert{Name: w.name, Message: w.msg, AtUTC: now, Fields: w.fields})
+	}
+	for key, w := range m.active {
+		if current[key] {
+			continue
+		}
+		delete(m.active, key)
+		pending = append(pending, alerts.Alert{Name: w.name, Message: "resolved: " + w.msg, AtUTC: now, Fields: w.fields, Resolved: true})
+	}
+	m.mu.Unlock()
+
+	// the alerts are logged even if the webhook fails, so its failure is only
+	// logged too
+	for _, a := range pending {
+		if err := m.notify.Notify(a); err != nil {
+			m.logger.Error("storage: sending an alert", "name", a.Name, "error", err)
+		}
+	}
+	return nil
+}
+
+// watchStorage checks the storage every interval until done is closed.
+func watchStorage(m *storageMonitor, interval time.Duration, done <-chan struct{}) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		if err := m.check(); err != nil {
+			m.logger.Error("storage: checking sizes", "error", err)
+		}
+		select {
+		case <-t.C:
+		case <-done:
+			return
+		}
+	}
+}
+
+// stats returns the storage stats as the admin stats report them.
+func (m *storageMonitor) stats() (*api.StorageStats, error) {
+	s, err := m.db.StorageStats(storageTopSenders)
+	if err != nil {
+		return nil, err
+	}
+
+	model := &api.StorageStats{
+		TotalBytes: s.TotalBytes(),
+		ValueBytes: s.ValueBytes,
+		Tables:     make([]api.TableSize, 0, len(s.Tables)),
+		Senders:    make([]api.SenderSize, 0, len(s.Senders)),
+		Warnings:   []string{},
+	}
+	for _, t := range s.Tables {
+		model.Tables = append(model.Tables, api.TableSize{Name: t.Name, Rows: t.Rows, DataBytes: t.DataBytes, IndexBytes: t.IndexBytes})
+	}
+	for _, ss := range s.Senders {
+		model.Senders = append(model.Senders, api.SenderSize{UserID: ss.UserID, Entries: ss.Entries, ValueBytes: ss.ValueBytes})
+	}
+	for _, w := range m.cfg.warnings(s) {
+		model.Warnings = append(model.Warnings, w.msg)
+	}
+	return model, nil
+}
+
+// Stats reports the numbers operators keep an eye on, like how much disk the
+// database takes and which senders use the most of it.
+func (c *AdminController) Stats(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	storage, err := c.storage.stats()
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	return respond(w, http.StatusOK, api.AdminStatsResponse{Storage: *storage})
+}
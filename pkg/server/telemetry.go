@@ -0,0 +1,68 @@
+package server
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/gavinwade12/sendkey/internal/telemetry"
+)
+
+// telemetryConfig opts in to reporting anonymous usage statistics: counts of
+// entries created, claimed, expired, and revoked, and of the features new
+// entries use. Nothing identifying users or entries is sent, and each count
+// has noise added before it's reported.
+type telemetryConfig struct {
+	Enabled  bool
+	Endpoint string
+	// IntervalHours is how often counts are reported. Zero uses a day.
+	IntervalHours int
+	// Epsilon is the differential privacy budget for each count. Smaller
+	// values add more noise. Zero uses 1.
+	Epsilon float64
+}
+
+// reporter returns the configured reporter, or nil if telemetry isn't
+// enabled.
+func (c telemetryConfig) reporter() (*telemetry.Reporter, error) {
+	if !c.Enabled {
+		return nil, nil
+	}
+	if c.Endpoint == "" {
+		return nil, fmt.Errorf("telemetry: an Endpoint is required")
+	}
+	if c.Epsilon < 0 {
+		return nil, fmt.Errorf("telemetry: Epsilon can't be negative")
+	}
+
+	epsilon := c.Epsilon
+	if epsilon == 0 {
+		epsilon = 1
+	}
+	return telemetry.NewReporter(c.Endpoint, epsilon), nil
+}
+
+func (c telemetryConfig) interval() time.Duration {
+	if c.IntervalHours > 0 {
+		return time.Hour * time.Duration(c.IntervalHours)
+	}
+	return 24 * time.Hour
+}
+
+// reportTelemetry reports usage every interval until done is closed.
+func reportTelemetry(r *telemetry.Reporter, interval time.Duration, done <-chan struct{}) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-t.C:
+		case <-done:
+			return
+		}
+
+		if err := r.Report(); err != nil {
+			slog.Error("telemetry: reporting usage", "error", err)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package server
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gavinwade12/sendkey/internal/app"
+	"github.com/gavinwade12/sendkey/internal/tracing"
+	"github.com/julienschmidt/httprouter"
+)
+
+// tracingConfig exports traces of requests to an OpenTelemetry collector
+// over OTLP/HTTP. Each request's span has children for the store calls and
+// emails made while serving it.
+type tracingConfig struct {
+	Enabled bool
+	// Endpoint is the collector's base URL, e.g. "http://localhost:4318".
+	// Spans are posted to its /v1/traces.
+	Endpoint string
+	// Headers are sent with every export, e.g. for the collector's auth.
+	Headers map[string]string
+	// ServiceName identifies the deployment in traces. Empty uses
+	// "sendkey".
+	ServiceName string
+	// SampleRatio is the share of new traces that are exported, between 0
+	// and 1. Zero uses 1. Requests continuing a caller's trace keep the
+	// caller's decision.
+	SampleRatio float64
+	// IntervalSecs is how often spans are exported. Zero uses 5 seconds.
+	IntervalSecs int
+}
+
+// tracer returns the configured tracer, or nil if tracing isn't enabled.
+func (c tracingConfig) tracer(logger *slog.Logger) (*tracing.Tracer, error) {
+	if !c.Enabled {
+		return nil, nil
+	}
+	if c.Endpoint == "" {
+		return nil, fmt.Errorf("tracing: an Endpoint is required")
+	}
+	if c.SampleRatio < 0 || c.SampleRatio > 1 {
+		return nil, fmt.Errorf("tracing: SampleRatio must be between 0 and 1")
+	}
+
+	name := c.ServiceName
+	if name == "" {
+		name = "sendkey"
+	}
+	ratio := c.SampleRatio
+	if ratio == 0 {
+		ratio = 1
+	}
+	interval := 5 * time.Second
+	if c.IntervalSecs > 0 {
+		interval = time.Second * time.Duration(c.IntervalSecs)
+	}
+	return tracing.NewTracer(strings.TrimSuffix(c.Endpoint, "/"), c.Headers, name, ratio, interval, logger)
+}
+
+// traceRoute starts a span for each of the route's requests, continuing the
+// caller's trace if the request has a traceparent header.
+func traceRoute(t *tracing.Tracer, name string, h httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		sp := t.Start(name, tracing.KindServer, r.Header)
+		sp.SetAttr("http.method", r.Method)
+		sp.SetAttr("http.route", name[len(r.Method)+1:])
+		setRequestTrace(r, sp.TraceID())
+
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		h(sw, r.WithContext(tracing.NewContext(r.Context(), sp)), p)
+
+		sp.SetAttr("http.status_code", strconv.Itoa(sw.status))
+		var err error
+		if sw.status >= http.StatusInternalServerError {
+			err = errServerStatus(sw.status)
+		}
+		sp.End(err)
+	}
+}
+
+// requestEntries returns a copy of the service for serving the request. It
+// records the client's IP in the audit log, and traces its calls under the
+// request's span if the request is traced.
+func requestEntries(r *http.Request, s *app.EntryService) *app.EntryService {
+	s = s.FromClient(clientIP(r))
+	if sp := tracing.FromContext(r.Context()); sp != nil {
+		return s.Traced(sp)
+	}
+	return s
+}
+
+// tracedUsers returns a copy of the service tracing its calls under the
+// request's span, or the service itself if the request isn't traced.
+func tracedUsers(r *http.Request, s *app.UserService) *app.UserService {
+	if sp := tracing.FromContext(r.Context()); sp != nil {
+		return s.Traced(sp)
+	}
+	return s
+}
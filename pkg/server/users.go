@@ -0,0 +1,364 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gavinwade12/sendkey"
+	"github.com/gavinwade12/sendkey/internal/app"
+	"github.com/gavinwade12/sendkey/internal/ratelimit"
+	"github.com/gavinwade12/sendkey/pkg/api"
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+type UsersController struct {
+	baseController
+
+	service *app.UserService
+
+	tokenProvider TokenProvider
+	refreshTokens RefreshTokenRepository
+	magicLinks    *app.MagicLinkService
+	loginThrottle *ratelimit.Throttler
+	// ids generates refresh tokens' IDs
+	ids sendkey.IDGenerator
+	// externalKey signs external logins that are waiting on an MFA code or
+	// a login to link them; see writeExternalLoginResponse.
+	externalKey []byte
+}
+
+type RefreshTokenRepository interface {
+	Create(sendkey.RefreshToken) error
+	FindByTokenAndUser(token string, userID uuid.UUID) (*sendkey.RefreshToken, error)
+	Delete(uuid.UUID) error
+	DeleteByUserID(uuid.UUID) error
+}
+
+func (c *UsersController) CreateUser(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	var req api.CreateUserRequest
+	if err := decodeJSON(r, &req); err != nil {
+		return respond(w, http.StatusBadRequest, api.CreateUserResponse{Envelope: invalidBody(r, err)})
+	}
+
+	resp, err := tracedUsers(r, c.service).CreateUser(app.CreateUserRequest(req))
+	if err != nil {
+		return err
+	}
+
+	model := api.CreateUserResponse{
+		Envelope:       envelope(r, resp.Success, resp.Errors),
+		PasswordErrors: passwordViolations(r, resp.PasswordErrors),
+		User:           resp.User,
+	}
+	return respond(w, envelopeStatus(model.Envelope), model)
+}
+
+func (c *UsersController) Login(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	var req api.LoginRequest
+	if err := decodeJSON(r, &req); err != nil {
+		return respond(w, http.StatusBadRequest, api.LoginResponse{Envelope: invalidBody(r, err)})
+	}
+
+	keys := []string{"login:ip:" + clientIP(r)}
+	if email := strings.ToLower(strings.TrimSpace(req.Email)); email != "" {
+		keys = append(keys, "login:email:"+email)
+	}
+
+	if err := c.attemptLogin(w, keys); err != nil {
+		return err
+	}
+
+	login := app.UserLoginRequest{Email: req.Email, Password: req.Password, MFACode: req.MFACode}
+	if req.LinkExternalLogin != "" {
+		var ok bool
+		if login.Link, ok = c.verifyExternalLogin(req.LinkExternalLogin); !ok {
+			return respond(w, http.StatusBadRequest, api.LoginResponse{Envelope: envelope(r, false, []app.Problem{{Field: "linkExternalLogin", Code: "external_login_invalid"}})})
+		}
+	}
+
+	resp, err := tracedUsers(r, c.service).Login(login)
+	if err != nil {
+		return err
+	}
+	if err = c.recordLogin(keys, resp); err != nil {
+		return err
+	}
+
+	return c.writeLoginResponse(w, r, resp)
+}
+
+// attemptLogin reserves a login attempt for its keys, refusing it if any of
+// them, like the client's IP, has failed too many logins recently. The first
+// key is the client's IP, and the rest identify the account.
+func (c *UsersController) attemptLogin(w http.ResponseWriter, keys []string) error {
+	wait, err := c.loginThrottle.Attempt(keys...)
+	if err != nil {
+		return err
+	}
+	if wait > 0 {
+		secs := int(math.Ceil(wait.Seconds()))
+		w.Header().Set("Retry-After", strconv.Itoa(secs))
+		return api.Error{
+			StatusCode: http.StatusTooManyRequests,
+			Code:       "too_many_attempts",
+			Message:    fmt.Sprintf("Too many failed login attempts. Try again in %d seconds.", secs),
+		}
+	}
+	return nil
+}
+
+// recordLogin settles the attempt attemptLogin reserved, which is left as a
+// failure unless the login succeeded or only needed an MFA code.
+func (c *UsersController) recordLogin(keys []string, resp *app.UserLoginResponse) error {
+	switch {
+	case resp.Success:
+		// the account's failures are forgiven, but not the IP's: one
+		// account an attacker controls shouldn't clear the failures of
+		// the others they're guessing at from the same IP. Only this
+		// attempt is taken back, so users sharing an IP don't add up to
+		// a lockout.
+		if err := c.loginThrottle.Release(keys[0]); err != nil {
+			return err
+		}
+		return c.loginThrottle.Succeed(keys[1:]...)
+	case mfaCodeMissing(resp.Errors):
+		// the password was right, and clients that ask for the MFA code
+		// only after it shouldn't be delayed for it
+		return c.loginThrottle.Release(keys...)
+	default:
+		return nil
+	}
+}
+
+// mfaCodeMissing reports whether a login failed only because it needed an
+// MFA code, which is checked after the password.
+func mfaCodeMissing(problems []app.Problem) bool {
+	return len(problems) == 1 && problems[0].Code == "mfa_code_required"
+}
+
+func (c *UsersController) SendMagicLink(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	var req api.MagicLinkRequest
+	if err := decodeJSON(r, &req); err != nil {
+		return respond(w, http.StatusBadRequest, api.MagicLinkResponse{Envelope: invalidBody(r, err)})
+	}
+
+	resp, err := c.magicLinks.Send(req.Email)
+	if err != nil {
+		return err
+	}
+
+	model := api.MagicLinkResponse{Envelope: envelope(r, resp.Success, resp.Errors)}
+	return respond(w, envelopeStatus(model.Envelope), model)
+}
+
+// RedeemMagicLink logs in with a magic link. It's a POST, from the page the
+// link opens or from clients, since a GET would let link scanners use it up.
+// The body is optional.
+func (c *UsersController) RedeemMagicLink(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	var model api.RedeemMagicLinkRequest
+	if err := decodeJSON(r, &model); err != nil && err != io.EOF {
+		return respond(w, http.StatusBadRequest, api.LoginResponse{Envelope: invalidBody(r, err)})
+	}
+
+	req := app.RedeemMagicLinkRequest{Code: p.ByName("code"), MFACode: model.MFACode, NewPassword: model.NewPassword}
+	if model.LinkExternalLogin != "" {
+		var ok bool
+		if req.Link, ok = c.verifyExternalLogin(model.LinkExternalLogin); !ok {
+			return respond(w, http.StatusBadRequest, api.LoginResponse{Envelope: envelope(r, false, []app.Problem{{Field: "linkExternalLogin", Code: "external_login_invalid"}})})
+		}
+	}
+
+	resp, err := c.magicLinks.Redeem(req)
+	if err != nil {
+		return err
+	}
+
+	return c.writeLoginResponse(w, r, resp)
+}
+
+// writeLoginResponse writes the login response along with a new
+// access/refresh token pair if the login was successful.
+func (c *UsersController) writeLoginResponse(w http.ResponseWriter, r *http.Request, resp *app.UserLoginResponse) error {
+	model := api.LoginResponse{
+		Envelope: envelope(r, resp.Success, resp.Errors),
+		User:     resp.User,
+	}
+	if !resp.Success {
+		return respond(w, http.StatusBadRequest, model)
+	}
+
+	srt, rt := c.refreshToken(r, model.User.ID)
+	err := c.refreshTokens.Create(srt)
+	if err != nil {
+		return err
+	}
+	model.RefreshToken = &rt
+
+	model.AccessToken, err = c.tokenProvider.AccessToken(model.User.ID)
+	if err != nil {
+		return err
+	}
+
+	return respond(w, http.StatusOK, model)
+}
+
+func (c *UsersController) RefreshToken(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	var model api.RefreshTokenRequest
+	if err := decodeJSON(r, &model); err != nil {
+		return respond(w, http.StatusBadRequest, api.RefreshTokenResponse{Envelope: invalidBody(r, err)})
+	}
+
+	var v app.Validator
+	v.Check(model.UserID != uuid.Nil, "userId", "invalid_user_id")
+	v.Require("refreshToken", model.RefreshToken, "refresh_token_required")
+	if !v.Valid() {
+		return respond(w, http.StatusBadRequest, api.RefreshTokenResponse{Envelope: envelope(r, false, v.Problems())})
+	}
+
+	invalid := api.RefreshTokenResponse{Envelope: envelope(r, false, []app.Problem{{Field: "refreshToken", Code: "refresh_token_invalid"}})}
+
+	rt, err := c.refreshTokens.FindByTokenAndUser(model.RefreshToken, model.UserID)
+	if err != nil {
+		return err
+	}
+	if rt == nil {
+		return respond(w, http.StatusBadRequest, invalid)
+	}
+
+	user, err := tracedUsers(r, c.service).FindUser(rt.UserID)
+	if err != nil {
+		return err
+	}
+	if user == nil || user.DeactivatedAtUTC != nil || user.DisabledAtUTC != nil {
+		return respond(w, http.StatusBadRequest, invalid)
+	}
+
+	response := api.RefreshTokenResponse{Envelope: api.Envelope{Success: true}}
+	response.AccessToken, err = c.tokenProvider.AccessToken(rt.UserID)
+	if err != nil {
+		return err
+	}
+
+	return respond(w, http.StatusOK, response)
+}
+
+func (c *UsersController) ChangePassword(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	userID := idParam(r, "userID")
+
+	var req api.ChangePasswordRequest
+	if err := decodeJSON(r, &req); err != nil {
+		return respond(w, http.StatusBadRequest, api.ChangePasswordResponse{Envelope: invalidBody(r, err)})
+	}
+
+	resp, err := tracedUsers(r, c.service).ChangePassword(app.ChangePasswordRequest{
+		UserID:          userID,
+		CurrentPassword: req.CurrentPassword,
+		NewPassword:     req.NewPassword,
+	})
+	if err != nil {
+		return err
+	}
+
+	model := api.ChangePasswordResponse{
+		Envelope:       envelope(r, resp.Success, resp.Errors),
+		PasswordErrors: passwordViolations(r, resp.PasswordErrors),
+	}
+	return respond(w, envelopeStatus(model.Envelope), model)
+}
+
+// DeleteUser deactivates the user's account. It's permanently deleted once
+// the grace period passes. Admins can delete other users' accounts.
+func (c *UsersController) DeleteUser(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	userID := idParam(r, "userID")
+
+	if err := tracedUsers(r, c.service).DeactivateUser(userID); err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func (c *UsersController) EnableMFA(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	userID := idParam(r, "userID")
+
+	resp, err := tracedUsers(r, c.service).EnableMFA(userID)
+	if err != nil {
+		return err
+	}
+
+	model := api.EnableMFAResponse{
+		Envelope: envelope(r, resp.Success, resp.Errors),
+		Secret:   resp.Secret,
+	}
+	return respond(w, envelopeStatus(model.Envelope), model)
+}
+
+func (c *UsersController) ConfirmMFA(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	var req api.ConfirmMFARequest
+	if err := decodeJSON(r, &req); err != nil {
+		return respond(w, http.StatusBadRequest, api.ConfirmMFAResponse{Envelope: invalidBody(r, err)})
+	}
+
+	resp, err := tracedUsers(r, c.service).ConfirmMFA(app.ConfirmMFARequest{UserID: idParam(r, "userID"), Code: req.Code})
+	if err != nil {
+		return err
+	}
+
+	model := api.ConfirmMFAResponse{
+		Envelope:      envelope(r, resp.Success, resp.Errors),
+		RecoveryCodes: resp.RecoveryCodes,
+	}
+	return respond(w, envelopeStatus(model.Envelope), model)
+}
+
+func (c *UsersController) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	userID := idParam(r, "userID")
+
+	user, err := tracedUsers(r, c.service).FindUser(userID)
+	if err != nil {
+		return err
+	}
+	if user == nil || !user.MFAEnabled {
+		return api.Error{UserID: userID, StatusCode: http.StatusBadRequest, Code: "mfa_not_enabled", Message: "MFA is not enabled."}
+	}
+
+	codes, err := tracedUsers(r, c.service).RegenerateRecoveryCodes(userID)
+	if err != nil {
+		return err
+	}
+
+	return respond(w, http.StatusOK, api.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+func (c *UsersController) refreshToken(r *http.Request, userID uuid.UUID) (sendkey.RefreshToken, api.Token) {
+	rt := c.tokenProvider.RefreshToken()
+
+	return sendkey.RefreshToken{
+		ID:           c.ids.NewID(),
+		UserID:       userID,
+		Token:        rt.Token,
+		CreatedAtUTC: time.Now().UTC(),
+		ExpiresAtUTC: time.Unix(rt.Expires, 0),
+		ClientIP:     clientIP(r),
+	}, rt
+}
+
+func passwordViolations(r *http.Request, vs []app.PasswordViolation) []api.PasswordViolation {
+	if vs == nil {
+		return nil
+	}
+
+	lang := language(r)
+	result := make([]api.PasswordViolation, len(vs))
+	for i, v := range vs {
+		result[i] = api.PasswordViolation{Code: v.Code, Message: v.Problem.Message(lang)}
+	}
+	return result
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+const defaultRobotsTxt = "User-agent: *\nDisallow: /\n"
+
+// securityTxtConfig holds the RFC 9116 security.txt fields. Contact and
+// Expires are required by the RFC; the file isn't served without them.
+type securityTxtConfig struct {
+	Contact            []string
+	Expires            string
+	Encryption         []string
+	Acknowledgments    []string
+	PreferredLanguages string
+	Canonical          []string
+	Policy             []string
+	Hiring             []string
+}
+
+func (c securityTxtConfig) String() string {
+	var b strings.Builder
+	write := func(field string, values ...string) {
+		for _, v := range values {
+			if v != "" {
+				fmt.Fprintf(&b, "%s: %s\n", field, v)
+			}
+		}
+	}
+
+	write("Contact", c.Contact...)
+	write("Expires", c.Expires)
+	write("Encryption", c.Encryption...)
+	write("Acknowledgments", c.Acknowledgments...)
+	write("Preferred-Languages", c.PreferredLanguages)
+	write("Canonical", c.Canonical...)
+	write("Policy", c.Policy...)
+	write("Hiring", c.Hiring...)
+
+	return b.String()
+}
+
+func textFile(contents string) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte(contents))
+	}
+}
+
+// mountWellKnown serves robots.txt and, if configured, security.txt. An empty
+// robots config disallows everything.
+func mountWellKnown(r *httprouter.Router, robots string, security securityTxtConfig) {
+	if robots == "" {
+		robots = defaultRobotsTxt
+	}
+	r.GET("/robots.txt", textFile(robots))
+
+	if len(security.Contact) > 0 && security.Expires != "" {
+		r.GET("/.well-known/security.txt", textFile(security.String()))
+	}
+}
+
+// noIndex tells crawlers not to index or follow the response. It's used for
+// any URL that contains a secret, like claim links.
+func noIndex(h httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		w.Header().Set("X-Robots-Tag", "noindex, nofollow, noarchive")
+		h(w, r, p)
+	}
+}
@@ -13,19 +13,133 @@ type User struct {
 	FirstName     string    `json:"firstName"`
 	LastName      string    `json:"lastName"`
 	Password      string    `json:"-"`
+	MFAEnabled    bool      `json:"mfaEnabled"`
+	MFASecret     string    `json:"-"`
 	CreatedAtUTC  time.Time `json:"createdAtUtc"`
+	// Role is RoleUser or RoleAdmin. Admins can view and expire any entry
+	// and manage other users.
+	Role string `json:"role"`
+
+	// DeactivatedAtUTC is set when the user deletes their account. The
+	// account is only removed once the deletion grace period has passed.
+	DeactivatedAtUTC *time.Time `json:"deactivatedAtUtc,omitempty"`
+	// DisabledAtUTC is set when an admin disables the account. The user
+	// can't log in until an admin enables it again.
+	DisabledAtUTC *time.Time `json:"disabledAtUtc,omitempty"`
+	// PasswordResetAtUTC is when an admin last forced the user to reset
+	// their password. Access tokens issued before it are refused, and until
+	// the user has a password again, they can only set one with a magic link.
+	PasswordResetAtUTC *time.Time `json:"passwordResetAtUtc,omitempty"`
 }
 
+// The roles a user can have.
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
 type Entry struct {
-	ID              uuid.UUID `json:"id"`
-	Name            string    `json:"name"`
-	SentByUserID    uuid.UUID `json:"sentByUserId"`
-	SentToEmail     string    `json:"sentToEmail"`
-	Nonce           []byte    `json:"-"`
-	Value           []byte    `json:"-"`
-	InvalidAttempts int       `json:"invalidAttempts"`
-	CreatedAtUTC    time.Time `json:"createdAtUtc"`
-	ExpiresAtUTC    time.Time `json:"expiresAtUtc"`
+	ID              uuid.UUID     `json:"id"`
+	Name            string        `json:"name"`
+	SentByUserID    uuid.UUID     `json:"sentByUserId"`
+	SentToEmail     string        `json:"sentToEmail"`
+	Nonce           []byte        `json:"-"`
+	Value           []byte        `json:"-"`
+	InvalidAttempts int           `json:"invalidAttempts"`
+	Locale          string        `json:"locale"`
+	KDF             EntryKDF      `json:"-"`
+	KeyVersion      int           `json:"-"`
+	Cipher          string        `json:"-"`
+	EndToEnd        bool          `json:"endToEnd"`
+	Type            string        `json:"type"`
+	Metadata        EntryMetadata `json:"metadata"`
+	CreatedAtUTC    time.Time     `json:"createdAtUtc"`
+	// AvailableAtUTC, if set, is the earliest the entry can be claimed.
+	// Together with ExpiresAtUTC, it makes a window for claiming it.
+	AvailableAtUTC *time.Time `json:"availableAtUtc,omitempty"`
+	ExpiresAtUTC   time.Time  `json:"expiresAtUtc"`
+	// RequireLogin makes the recipient log in as a user with a verified
+	// email matching SentToEmail to claim the entry.
+	RequireLogin bool `json:"requireLogin"`
+	// RequireOTP emails the recipient a one-time code when they claim the
+	// entry, which they must enter along with the secret.
+	RequireOTP bool `json:"requireOtp"`
+	// Acknowledgment is text the recipient must acknowledge, like "I'll
+	// store this in the team vault", before the value is decrypted.
+	Acknowledgment string `json:"acknowledgment,omitempty"`
+	// Sandbox entries are created by integrators testing against the
+	// sandbox. They're kept apart from real entries and never emailed.
+	Sandbox bool `json:"sandbox,omitempty"`
+	// AnonymousSenderID is who a guest entry is attributed to. It's nil for
+	// entries with a sender.
+	AnonymousSenderID *uuid.UUID `json:"anonymousSenderId,omitempty"`
+	// Digest is the sender's client's digest of the value, if it sent one.
+	// It's only given to the recipient when they claim the entry.
+	Digest *ValueDigest `json:"-"`
+	// ReplacesEntryID is the entry this one rotates, like the previous
+	// password for an account. Claiming this one revokes it.
+	ReplacesEntryID *uuid.UUID `json:"replacesEntryId,omitempty"`
+
+	Challenges []EntryChallenge `json:"challenges"`
+	// Deferrals are set for the sender, to show when the recipient put off
+	// claiming the entry.
+	Deferrals []EntryDeferral `json:"deferrals,omitempty"`
+	// Flags are set for the user listing their entries.
+	Flags EntryFlags `json:"flags"`
+}
+
+// EntryFlags are a user's own marks on an entry, to find it quickly in their
+// listings. Pinned entries are listed first, then favorites.
+type EntryFlags struct {
+	Pinned   bool `json:"pinned"`
+	Favorite bool `json:"favorite"`
+}
+
+// The kinds of value an entry can hold. Entries created before types were
+// added have an empty Type and are treated as notes.
+const (
+	EntryTypePassword = "password"
+	EntryTypeNote     = "note"
+	EntryTypeSSHKey   = "ssh-key"
+	EntryTypeEnvFile  = "env-file"
+)
+
+// EntryMetadata describes an entry's value so it can be shown appropriately.
+// It isn't encrypted, so it must not hold anything secret.
+type EntryMetadata struct {
+	// Username is the account a password is for.
+	Username string `json:"username,omitempty"`
+	// Hostname is the host an SSH key is for.
+	Hostname string `json:"hostname,omitempty"`
+}
+
+// EntryKDF holds the parameters used to derive an entry's encryption key from
+// its secret. Entries created before key derivation was added have an empty
+// Algorithm.
+type EntryKDF struct {
+	Algorithm string `json:"algorithm"`
+	Salt      []byte `json:"salt"`
+	Time      uint32 `json:"time"`
+	MemoryKiB uint32 `json:"memoryKiB"`
+	Threads   uint8  `json:"threads"`
+}
+
+// ValueDigest is a keyed digest of an entry's value, computed by the sender's
+// client so the recipient's client can check the value wasn't corrupted in
+// transit or storage. The key is derived from the entry's secret, so only
+// someone with the secret can compute or check it.
+type ValueDigest struct {
+	// Algorithm names the key derivation and MAC, including their costs.
+	Algorithm string `json:"algorithm"`
+	Salt      []byte `json:"salt"`
+	MAC       []byte `json:"mac"`
+}
+
+// EntryChallenge is a question set by the sender that the recipient must
+// answer before any decryption attempt is made.
+type EntryChallenge struct {
+	Question   string `json:"question"`
+	AnswerHash string `json:"-"`
 }
 
 type ClaimedEntry struct {
@@ -34,6 +148,16 @@ type ClaimedEntry struct {
 	SentByUserID uuid.UUID `json:"sentByUserId"`
 	SentToEmail  string    `json:"sentToEmail"`
 	ClaimedAtUTC time.Time `json:"claimedAtUtc"`
+	// ClaimKeyHash is the SHA-256 of the key the claimer's client sent, if
+	// it sent one, so a retried or concurrent claim from the same client
+	// can be told apart from someone else's.
+	ClaimKeyHash string `json:"-"`
+	// ReplacesEntryID is the entry the claimed one rotated.
+	ReplacesEntryID *uuid.UUID `json:"replacesEntryId,omitempty"`
+	// Acknowledgment is the entry's acknowledgment text the recipient
+	// acknowledged when they claimed it, and AcknowledgedAtUTC when.
+	Acknowledgment    string     `json:"acknowledgment,omitempty"`
+	AcknowledgedAtUTC *time.Time `json:"acknowledgedAtUtc,omitempty"`
 }
 
 type ExpiredEntry struct {
@@ -42,7 +166,60 @@ type ExpiredEntry struct {
 	SentByUserID    uuid.UUID `json:"sentByUserId"`
 	SentToEmail     string    `json:"sentToEmail"`
 	TooManyAttempts bool      `json:"tooManyAttempts"`
+	Revoked         bool      `json:"revoked"`
 	ExpiredAtUTC    time.Time `json:"expiredAtUtc"`
+	// ReplacedByEntryID is the entry whose claim revoked this one.
+	ReplacedByEntryID *uuid.UUID `json:"replacedByEntryId,omitempty"`
+}
+
+// EntryFilter selects active entries by who sent them, what they hold, when
+// they were created, and who they were sent to. Zero fields match any entry.
+type EntryFilter struct {
+	SentByUserID    *uuid.UUID `json:"sentByUserId,omitempty"`
+	Type            string     `json:"type,omitempty"`
+	CreatedAfterUTC *time.Time `json:"createdAfterUtc,omitempty"`
+	// RecipientDomain matches entries sent to emails at the domain.
+	RecipientDomain string `json:"recipientDomain,omitempty"`
+}
+
+// The ways an entry can leave the active entries.
+const (
+	OutcomeClaimed         = "claimed"
+	OutcomeExpired         = "expired"
+	OutcomeTooManyAttempts = "too_many_attempts"
+	OutcomeRevoked         = "revoked"
+	// OutcomeReplaced is an entry revoked when the entry replacing it was
+	// claimed.
+	OutcomeReplaced = "replaced"
+)
+
+// EntryOutcome is a claimed or expired entry in the history of entries.
+type EntryOutcome struct {
+	EntryID      uuid.UUID `json:"entryId"`
+	Name         string    `json:"name"`
+	SentByUserID uuid.UUID `json:"sentByUserId"`
+	SentToEmail  string    `json:"sentToEmail"`
+	Outcome      string    `json:"outcome"`
+	AtUTC        time.Time `json:"atUtc"`
+	// ReplacesEntryID and ReplacedByEntryID link the outcomes of entries
+	// rotated one after another, so the history shows the whole chain.
+	ReplacesEntryID   *uuid.UUID `json:"replacesEntryId,omitempty"`
+	ReplacedByEntryID *uuid.UUID `json:"replacedByEntryId,omitempty"`
+	// Acknowledgment and AcknowledgedAtUTC are what the recipient of a
+	// claimed entry acknowledged, and when, if the sender required it.
+	Acknowledgment    string     `json:"acknowledgment,omitempty"`
+	AcknowledgedAtUTC *time.Time `json:"acknowledgedAtUtc,omitempty"`
+}
+
+// OutcomeFilter selects entry outcomes by who sent the entries, who they were
+// sent to, and when. Zero fields match any outcome.
+type OutcomeFilter struct {
+	SentByUserID *uuid.UUID
+	SentToEmail  string
+	SinceUTC     *time.Time
+	// Claimed, if set, selects only the claimed entries, or only the ones
+	// that left without being claimed.
+	Claimed *bool
 }
 
 type RefreshToken struct {
@@ -51,4 +228,171 @@ type RefreshToken struct {
 	Token        string    `json:"token"`
 	CreatedAtUTC time.Time `json:"createdAtUtc"`
 	ExpiresAtUTC time.Time `json:"expiresAtUtc"`
+	// ClientIP is the IP of the client that logged in, to tell sessions
+	// apart.
+	ClientIP string `json:"clientIp,omitempty"`
+}
+
+// IdempotencyRecord is a request made with an Idempotency-Key and the
+// response it got, kept so a retry with the same key gets the same response
+// instead of making the request again. A zero StatusCode means the request
+// is still in progress.
+type IdempotencyRecord struct {
+	// KeyHash is the hex SHA-256 of the key and who sent it, and
+	// RequestHash the hex SHA-256 of the request, so a key reused for a
+	// different request can be refused.
+	KeyHash      string
+	RequestHash  string
+	StatusCode   int
+	Response     []byte
+	CreatedAtUTC time.Time
+	ExpiresAtUTC time.Time
+}
+
+type RecoveryCode struct {
+	ID           uuid.UUID `json:"id"`
+	UserID       uuid.UUID `json:"userId"`
+	CodeHash     string    `json:"-"`
+	CreatedAtUTC time.Time `json:"createdAtUtc"`
+}
+
+// EntryDeferral records a recipient putting off claiming an entry. They're
+// sent a reminder at RemindAtUTC.
+type EntryDeferral struct {
+	ID            uuid.UUID  `json:"id"`
+	EntryID       uuid.UUID  `json:"entryId"`
+	DeferredAtUTC time.Time  `json:"deferredAtUtc"`
+	RemindAtUTC   time.Time  `json:"remindAtUtc"`
+	RemindedAtUTC *time.Time `json:"remindedAtUtc,omitempty"`
+}
+
+// EntryOTP is a one-time code emailed to an entry's recipient to claim it.
+type EntryOTP struct {
+	EntryID      uuid.UUID `json:"entryId"`
+	CodeHash     string    `json:"-"`
+	CreatedAtUTC time.Time `json:"createdAtUtc"`
+	ExpiresAtUTC time.Time `json:"expiresAtUtc"`
+}
+
+// EntryDelegation lets a service the sender trusts, like a deploy bot, claim
+// an entry with a short-lived token instead of its secret. The entry's key is
+// stored encrypted with the token, which only the service has.
+type EntryDelegation struct {
+	ID              uuid.UUID `json:"id"`
+	EntryID         uuid.UUID `json:"entryId"`
+	CreatedByUserID uuid.UUID `json:"createdByUserId"`
+	WrappedKey      []byte    `json:"-"`
+	KeyNonce        []byte    `json:"-"`
+	CreatedAtUTC    time.Time `json:"createdAtUtc"`
+	ExpiresAtUTC    time.Time `json:"expiresAtUtc"`
+}
+
+// The statuses of a claim link. Only an entry's latest link is active; issuing
+// a new one supersedes the others. An active link past its expiration is
+// reported as expired.
+const (
+	ClaimLinkActive     = "active"
+	ClaimLinkSuperseded = "superseded"
+	ClaimLinkExpired    = "expired"
+)
+
+// ClaimLink is a link to claim an entry that can expire before the entry
+// does. Each link has its own nonce, so a link that's expired or superseded
+// can't be used even though the entry still can.
+type ClaimLink struct {
+	ID              uuid.UUID  `json:"id"`
+	EntryID         uuid.UUID  `json:"entryId"`
+	Nonce           []byte     `json:"-"`
+	Status          string     `json:"status"`
+	CreatedAtUTC    time.Time  `json:"createdAtUtc"`
+	ExpiresAtUTC    time.Time  `json:"expiresAtUtc"`
+	SupersededAtUTC *time.Time `json:"supersededAtUtc,omitempty"`
+}
+
+// EntryEscrow is a copy of an entry's value encrypted to an org's escrow
+// public key when the entry was created, kept for a while after it's claimed
+// so what was sent can be recovered with the escrow private key. The server
+// never has the private key, so it can't decrypt the copy itself.
+type EntryEscrow struct {
+	EntryID      uuid.UUID `json:"entryId"`
+	SentByUserID uuid.UUID `json:"sentByUserId"`
+	SentToEmail  string    `json:"sentToEmail"`
+	// KeyID is the hex SHA-256 of the escrow public key's DER encoding, so
+	// the right private key can be found after the key's rotated.
+	KeyID string `json:"keyId"`
+	// WrappedKey is the AES-256-GCM key the value's sealed with, encrypted
+	// to the escrow key with RSA-OAEP and SHA-256. The entry's ID is the
+	// value's additional data.
+	WrappedKey   []byte     `json:"wrappedKey"`
+	Nonce        []byte     `json:"nonce"`
+	Ciphertext   []byte     `json:"ciphertext"`
+	CreatedAtUTC time.Time  `json:"createdAtUtc"`
+	ClaimedAtUTC *time.Time `json:"claimedAtUtc"`
+	// PurgeAtUTC is when the escrow is deleted: when the entry expires, or
+	// the retention period after it's claimed.
+	PurgeAtUTC time.Time `json:"purgeAtUtc"`
+}
+
+type ClaimReceipt struct {
+	EntryID uuid.UUID `json:"entryId"`
+	// ValueHash is an HMAC-SHA256 of the claimed value under the server's
+	// key, so only the server can tell which value it's for.
+	ValueHash    string    `json:"valueHash"`
+	Signature    string    `json:"signature"`
+	ClaimedAtUTC time.Time `json:"claimedAtUtc"`
+}
+
+// UserIdentity links a user to an account at an external identity provider.
+type UserIdentity struct {
+	Provider     string    `json:"provider"`
+	Subject      string    `json:"subject"`
+	UserID       uuid.UUID `json:"userId"`
+	CreatedAtUTC time.Time `json:"createdAtUtc"`
+}
+
+// AnonymousSender is who guest entries are attributed to: one record per
+// client address, which is kept as a keyed hash rather than stored. It lets
+// abuse from one source be counted and traced without accounts.
+type AnonymousSender struct {
+	ID             uuid.UUID `json:"id"`
+	AddressHash    string    `json:"addressHash"`
+	EntriesCreated int       `json:"entriesCreated"`
+	FirstSeenAtUTC time.Time `json:"firstSeenAtUtc"`
+	LastSeenAtUTC  time.Time `json:"lastSeenAtUtc"`
+}
+
+// MagicLink is a single-use, passwordless login link.
+type MagicLink struct {
+	ID           uuid.UUID `json:"id"`
+	UserID       uuid.UUID `json:"userId"`
+	CreatedAtUTC time.Time `json:"createdAtUtc"`
+	ExpiresAtUTC time.Time `json:"expiresAtUtc"`
+}
+
+// AuditEvent is an event in the audit log. Its Hash covers its fields and the
+// previous event's hash, so rewriting an event breaks the chain after it.
+type AuditEvent struct {
+	Seq  int64  `json:"seq"`
+	Type string `json:"type"`
+	// Data is a JSON object of the event's details.
+	Data     string    `json:"data"`
+	AtUTC    time.Time `json:"atUtc"`
+	PrevHash string    `json:"prevHash"`
+	Hash     string    `json:"hash"`
+}
+
+// AuditAnchor is the audit log's hash as of an event, signed with the server
+// key. History up to it can't be rewritten without the key.
+type AuditAnchor struct {
+	Seq          int64     `json:"seq"`
+	Hash         string    `json:"hash"`
+	Signature    string    `json:"signature"`
+	CreatedAtUTC time.Time `json:"createdAtUtc"`
+}
+
+// AuditHead is the latest event in the audit log's chain. Its Seq is zero
+// before the first event.
+type AuditHead struct {
+	Seq  int64  `json:"seq"`
+	Hash string `json:"hash"`
 }